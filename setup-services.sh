@@ -183,6 +183,17 @@ else
         --topic sms_events 2>/dev/null
     echo -e "  ${GREEN}✓ Topic 'sms_events' created (1 partition)${NC}"
 fi
+# Delay-tier topics for provider retries (see sms.retry.delays)
+for topic in sms_retry_1m sms_retry_5m sms_retry_30m; do
+    if ! bin/kafka-topics.sh --list --bootstrap-server localhost:9092 2>/dev/null | grep -q "^$topic\$"; then
+        bin/kafka-topics.sh --create \
+            --bootstrap-server localhost:9092 \
+            --replication-factor 1 \
+            --partitions 1 \
+            --topic "$topic" 2>/dev/null
+    fi
+done
+echo -e "  ${GREEN}✓ Retry topics ready${NC}"
 echo ""
 
 # =============================================================================
@@ -0,0 +1,23 @@
+package com.example.demo.config;
+
+import com.example.demo.service.AbuseDetectionService;
+import org.springframework.boot.CommandLineRunner;
+import org.springframework.context.annotation.Bean;
+import org.springframework.context.annotation.Configuration;
+
+@Configuration
+public class AbusePrefixInitializer {
+
+    @Bean
+    CommandLineRunner initBlockedPrefixes(AbuseDetectionService abuseDetection, AbuseProperties properties) {
+        return args -> {
+            // Each configured range is added the first time it is configured; admin changes are kept
+            properties.getPremiumPrefixes().stream().filter(prefix -> !prefix.trim().isEmpty())
+                    .forEach(prefix -> abuseDetection.seedPrefix(prefix.trim(), "premium_rate"));
+            properties.getFraudPrefixes().stream().filter(prefix -> !prefix.trim().isEmpty())
+                    .forEach(prefix -> abuseDetection.seedPrefix(prefix.trim(), "fraud"));
+            System.out.println("✓ Blocked prefixes initialized - "
+                    + (properties.getPremiumPrefixes().size() + properties.getFraudPrefixes().size()) + " configured");
+        };
+    }
+}
@@ -0,0 +1,70 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.List;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Abuse detection thresholds, bound from sms.abuse.* properties.
+ *
+ * A number that receives more than volumeThreshold promotional sends within
+ * volumeWindow is blocked for blockDuration, or until an admin unblocks it.
+ * Transactional sends (e.g. OTPs) never count. The prefix lists seed the
+ * blocked prefix collection the first time each prefix is configured;
+ * prefixes can also be managed at runtime through the admin API.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.abuse")
+public class AbuseProperties {
+    // 0 (the default) disables volume-based blocking
+    private int volumeThreshold = 0;
+    private Duration volumeWindow = Duration.ofHours(1);
+    private Duration blockDuration = Duration.ofHours(24);
+    // Premium-rate ranges, e.g. +1900
+    private List<String> premiumPrefixes = new ArrayList<>(Arrays.asList("+1900", "+1976", "+44909", "+44871"));
+    // Ranges known to be used for toll fraud
+    private List<String> fraudPrefixes = new ArrayList<>();
+
+    public int getVolumeThreshold() {
+        return volumeThreshold;
+    }
+
+    public void setVolumeThreshold(int volumeThreshold) {
+        this.volumeThreshold = volumeThreshold;
+    }
+
+    public Duration getVolumeWindow() {
+        return volumeWindow;
+    }
+
+    public void setVolumeWindow(Duration volumeWindow) {
+        this.volumeWindow = volumeWindow;
+    }
+
+    public Duration getBlockDuration() {
+        return blockDuration;
+    }
+
+    public void setBlockDuration(Duration blockDuration) {
+        this.blockDuration = blockDuration;
+    }
+
+    public List<String> getPremiumPrefixes() {
+        return premiumPrefixes;
+    }
+
+    public void setPremiumPrefixes(List<String> premiumPrefixes) {
+        this.premiumPrefixes = premiumPrefixes;
+    }
+
+    public List<String> getFraudPrefixes() {
+        return fraudPrefixes;
+    }
+
+    public void setFraudPrefixes(List<String> fraudPrefixes) {
+        this.fraudPrefixes = fraudPrefixes;
+    }
+}
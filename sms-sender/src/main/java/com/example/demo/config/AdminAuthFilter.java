@@ -0,0 +1,125 @@
+package com.example.demo.config;
+
+import java.io.IOException;
+import java.net.InetAddress;
+import java.net.UnknownHostException;
+import java.nio.charset.StandardCharsets;
+import java.security.MessageDigest;
+import java.util.ArrayList;
+import java.util.List;
+import javax.servlet.FilterChain;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.HttpHeaders;
+import org.springframework.http.HttpStatus;
+import org.springframework.http.MediaType;
+import org.springframework.stereotype.Component;
+import org.springframework.web.filter.OncePerRequestFilter;
+
+/**
+ * Guards /v1/admin with the admin allowlist, then the admin bearer token, the
+ * same way the storage service guards its admin API.
+ */
+@Component
+public class AdminAuthFilter extends OncePerRequestFilter {
+    private static final String ADMIN_PATH = "/v1/admin/";
+
+    private final AdminProperties properties;
+    private final List<Cidr> allowed = new ArrayList<>();
+
+    @Autowired
+    public AdminAuthFilter(AdminProperties properties) {
+        this.properties = properties;
+        for (String cidr : properties.getAllowedCidrs()) {
+            if (!cidr.trim().isEmpty()) {
+                allowed.add(Cidr.parse(cidr.trim()));
+            }
+        }
+    }
+
+    @Override
+    protected boolean shouldNotFilter(HttpServletRequest request) {
+        return !request.getRequestURI().startsWith(ADMIN_PATH);
+    }
+
+    @Override
+    protected void doFilterInternal(HttpServletRequest request, HttpServletResponse response, FilterChain chain)
+            throws ServletException, IOException {
+        if (!allowed.isEmpty() && !isAllowed(request.getRemoteAddr())) {
+            reject(response, HttpStatus.FORBIDDEN, "Client address is not allowed");
+            return;
+        }
+        String token = properties.getApiToken();
+        if (token != null && !token.isEmpty()) {
+            String header = request.getHeader(HttpHeaders.AUTHORIZATION);
+            String presented = header != null && header.startsWith("Bearer ") ? header.substring(7) : "";
+            if (!MessageDigest.isEqual(presented.getBytes(StandardCharsets.UTF_8), token.getBytes(StandardCharsets.UTF_8))) {
+                response.setHeader(HttpHeaders.WWW_AUTHENTICATE, "Bearer realm=\"sms-sender-admin\"");
+                reject(response, HttpStatus.UNAUTHORIZED, "Admin credentials required");
+                return;
+            }
+        }
+        chain.doFilter(request, response);
+    }
+
+    private boolean isAllowed(String remoteAddr) {
+        byte[] address;
+        try {
+            address = InetAddress.getByName(remoteAddr).getAddress();
+        } catch (UnknownHostException e) {
+            return false;
+        }
+        for (Cidr cidr : allowed) {
+            if (cidr.contains(address)) {
+                return true;
+            }
+        }
+        return false;
+    }
+
+    private static void reject(HttpServletResponse response, HttpStatus status, String message) throws IOException {
+        response.setStatus(status.value());
+        response.setContentType(MediaType.APPLICATION_JSON_VALUE);
+        response.getWriter().write("{\"result\":\"Failed: " + message + "\"}");
+    }
+
+    private static class Cidr {
+        final byte[] network;
+        final int bits;
+
+        Cidr(byte[] network, int bits) {
+            this.network = network;
+            this.bits = bits;
+        }
+
+        // Accepts "10.0.0.0/8" and bare addresses, IPv4 or IPv6
+        static Cidr parse(String cidr) {
+            String[] parts = cidr.split("/", 2);
+            try {
+                byte[] network = InetAddress.getByName(parts[0]).getAddress();
+                int bits = parts.length == 2 ? Integer.parseInt(parts[1]) : network.length * 8;
+                if (bits < 0 || bits > network.length * 8) {
+                    throw new IllegalArgumentException("Invalid admin allowed CIDR: " + cidr);
+                }
+                return new Cidr(network, bits);
+            } catch (UnknownHostException | NumberFormatException e) {
+                throw new IllegalArgumentException("Invalid admin allowed CIDR: " + cidr, e);
+            }
+        }
+
+        boolean contains(byte[] address) {
+            if (address.length != network.length) {
+                return false;
+            }
+            for (int i = 0; i < bits; i++) {
+                int mask = 0x80 >> (i % 8);
+                if ((address[i / 8] & mask) != (network[i / 8] & mask)) {
+                    return false;
+                }
+            }
+            return true;
+        }
+    }
+}
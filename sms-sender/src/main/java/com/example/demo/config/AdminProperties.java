@@ -0,0 +1,37 @@
+package com.example.demo.config;
+
+import java.util.ArrayList;
+import java.util.List;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Access to the /v1/admin API, bound from sms.admin.* properties. Mirrors the
+ * storage service's ADMIN_API_TOKEN and ADMIN_ALLOWED_CIDRS: an empty token
+ * leaves the API open, which is only appropriate behind a private network,
+ * and an empty allowlist admits every address.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.admin")
+public class AdminProperties {
+    // Bearer token required on every admin request
+    private String apiToken = "";
+    // CIDRs, e.g. 10.0.0.0/8, admin requests may come from
+    private List<String> allowedCidrs = new ArrayList<>();
+
+    public String getApiToken() {
+        return apiToken;
+    }
+
+    public void setApiToken(String apiToken) {
+        this.apiToken = apiToken;
+    }
+
+    public List<String> getAllowedCidrs() {
+        return allowedCidrs;
+    }
+
+    public void setAllowedCidrs(List<String> allowedCidrs) {
+        this.allowedCidrs = allowedCidrs;
+    }
+}
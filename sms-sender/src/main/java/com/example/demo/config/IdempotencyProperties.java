@@ -0,0 +1,35 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Idempotency-Key handling for POST /v1/sms/send, bound from
+ * sms.send.idempotency.* properties. A completed send's response is replayed
+ * for ttl; a key whose send is still running is held for at most
+ * inProgressTimeout, so a replica dying mid-send doesn't block it for ttl.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.send.idempotency")
+public class IdempotencyProperties {
+    private Duration ttl = Duration.ofHours(24);
+    // Longer than sms.send.wait.timeout, so waiting sends keep their key
+    private Duration inProgressTimeout = Duration.ofMinutes(2);
+
+    public Duration getTtl() {
+        return ttl;
+    }
+
+    public void setTtl(Duration ttl) {
+        this.ttl = ttl;
+    }
+
+    public Duration getInProgressTimeout() {
+        return inProgressTimeout;
+    }
+
+    public void setInProgressTimeout(Duration inProgressTimeout) {
+        this.inProgressTimeout = inProgressTimeout;
+    }
+}
@@ -0,0 +1,52 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Link shortening and click tracking, bound from sms.links.* properties. When
+ * enabled, URLs in outgoing messages are replaced by baseUrl plus a code that
+ * GET /r/{code} redirects from for ttl.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.links")
+public class LinkProperties {
+    private boolean enabled = false;
+    // Public address of the redirect handler, ending in "/r/"
+    private String baseUrl = "http://localhost:8080/r/";
+    private Duration ttl = Duration.ofDays(30);
+    private int codeLength = 7;
+
+    public boolean isEnabled() {
+        return enabled;
+    }
+
+    public void setEnabled(boolean enabled) {
+        this.enabled = enabled;
+    }
+
+    public String getBaseUrl() {
+        return baseUrl;
+    }
+
+    public void setBaseUrl(String baseUrl) {
+        this.baseUrl = baseUrl;
+    }
+
+    public Duration getTtl() {
+        return ttl;
+    }
+
+    public void setTtl(Duration ttl) {
+        this.ttl = ttl;
+    }
+
+    public int getCodeLength() {
+        return codeLength;
+    }
+
+    public void setCodeLength(int codeLength) {
+        this.codeLength = codeLength;
+    }
+}
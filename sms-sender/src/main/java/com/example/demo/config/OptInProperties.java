@@ -0,0 +1,32 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Double opt-in, bound from sms.opt-in.* properties. Confirmation SMS ask the
+ * recipient to reply with keyword and a code that stays valid for codeTtl.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.opt-in")
+public class OptInProperties {
+    private String keyword = "YES";
+    private Duration codeTtl = Duration.ofHours(24);
+
+    public String getKeyword() {
+        return keyword;
+    }
+
+    public void setKeyword(String keyword) {
+        this.keyword = keyword;
+    }
+
+    public Duration getCodeTtl() {
+        return codeTtl;
+    }
+
+    public void setCodeTtl(Duration codeTtl) {
+        this.codeTtl = codeTtl;
+    }
+}
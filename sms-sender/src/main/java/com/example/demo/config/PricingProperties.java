@@ -0,0 +1,53 @@
+package com.example.demo.config;
+
+import java.math.BigDecimal;
+import java.util.HashMap;
+import java.util.Map;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Per-segment prices used for cost estimates, bound from sms.pricing.*
+ * properties. Prices can be overridden per destination country, e.g.
+ * sms.pricing.countries.IN=0.0025
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.pricing")
+public class PricingProperties {
+    private String currency = "USD";
+    // Applies to requests without a countryCode or an override
+    private BigDecimal perSegment = new BigDecimal("0.0079");
+    private Map<String, BigDecimal> countries = new HashMap<>();
+
+    public String getCurrency() {
+        return currency;
+    }
+
+    public void setCurrency(String currency) {
+        this.currency = currency;
+    }
+
+    public BigDecimal getPerSegment() {
+        return perSegment;
+    }
+
+    public void setPerSegment(BigDecimal perSegment) {
+        this.perSegment = perSegment;
+    }
+
+    public Map<String, BigDecimal> getCountries() {
+        return countries;
+    }
+
+    public void setCountries(Map<String, BigDecimal> countries) {
+        this.countries = countries;
+    }
+
+    /**
+     * Returns the per-segment price for a country, falling back to perSegment.
+     */
+    public BigDecimal priceForCountry(String countryCode) {
+        BigDecimal override = countryCode == null ? null : countries.get(countryCode);
+        return override != null ? override : perSegment;
+    }
+}
@@ -0,0 +1,95 @@
+package com.example.demo.config;
+
+import java.util.HashMap;
+import java.util.Map;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Send quotas, bound from sms.quota.* properties. A limit of 0 means unlimited.
+ *
+ * Per-tenant limits can be overridden, e.g.
+ * sms.quota.tenants.acme.daily=50000
+ *
+ * When Redis is unreachable sends are refused (fail closed) unless failOpen
+ * is set, in which case they go out uncounted.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.quota")
+public class QuotaProperties {
+    // Applies to every phone number
+    private Limits phone = new Limits();
+    // Applies to every tenant without an override
+    private Limits tenant = new Limits();
+    private Map<String, Limits> tenants = new HashMap<>();
+    private boolean failOpen;
+
+    public Limits getPhone() {
+        return phone;
+    }
+
+    public void setPhone(Limits phone) {
+        this.phone = phone;
+    }
+
+    public Limits getTenant() {
+        return tenant;
+    }
+
+    public void setTenant(Limits tenant) {
+        this.tenant = tenant;
+    }
+
+    public Map<String, Limits> getTenants() {
+        return tenants;
+    }
+
+    public void setTenants(Map<String, Limits> tenants) {
+        this.tenants = tenants;
+    }
+
+    public boolean isFailOpen() {
+        return failOpen;
+    }
+
+    public void setFailOpen(boolean failOpen) {
+        this.failOpen = failOpen;
+    }
+
+    /**
+     * Returns the limits for a tenant, falling back to the tenant defaults.
+     */
+    public Limits limitsForTenant(String tenantId) {
+        Limits override = tenants.get(tenantId);
+        return override != null ? override : tenant;
+    }
+
+    public static class Limits {
+        private long daily;
+        private long monthly;
+
+        public Limits() {
+        }
+
+        public Limits(long daily, long monthly) {
+            this.daily = daily;
+            this.monthly = monthly;
+        }
+
+        public long getDaily() {
+            return daily;
+        }
+
+        public void setDaily(long daily) {
+            this.daily = daily;
+        }
+
+        public long getMonthly() {
+            return monthly;
+        }
+
+        public void setMonthly(long monthly) {
+            this.monthly = monthly;
+        }
+    }
+}
@@ -0,0 +1,90 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.List;
+import java.util.stream.Stream;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Provider retry policy, bound from sms.retry.* properties. Each delay tier has
+ * its own topic (e.g. sms_retry_5m), so every record in a topic becomes due in
+ * the order it was written. Retry n waits delays[n-1]; the last tier repeats
+ * if maxAttempts exceeds the number of tiers. The storage service publishes
+ * resends of failed sends, already due, to resendTopic.
+ */
+@Component("retryProperties")
+@ConfigurationProperties(prefix = "sms.retry")
+public class RetryProperties {
+    // Total provider attempts, including the first
+    private int maxAttempts = 4;
+    private List<Duration> delays = new ArrayList<>(Arrays.asList(
+            Duration.ofMinutes(1), Duration.ofMinutes(5), Duration.ofMinutes(30)));
+    private String topicPrefix = "sms_retry_";
+    private String resendTopic = "sms_resend";
+
+    public int getMaxAttempts() {
+        return maxAttempts;
+    }
+
+    public void setMaxAttempts(int maxAttempts) {
+        this.maxAttempts = maxAttempts;
+    }
+
+    public List<Duration> getDelays() {
+        return delays;
+    }
+
+    public void setDelays(List<Duration> delays) {
+        this.delays = delays;
+    }
+
+    public String getTopicPrefix() {
+        return topicPrefix;
+    }
+
+    public void setTopicPrefix(String topicPrefix) {
+        this.topicPrefix = topicPrefix;
+    }
+
+    public String getResendTopic() {
+        return resendTopic;
+    }
+
+    public void setResendTopic(String resendTopic) {
+        this.resendTopic = resendTopic;
+    }
+
+    /**
+     * Returns the delay before the given retry (2 = first retry).
+     */
+    public Duration delayBefore(int attempt) {
+        int tier = Math.min(attempt - 2, delays.size() - 1);
+        return delays.get(Math.max(tier, 0));
+    }
+
+    /**
+     * Returns the topic holding retries with the given delay, e.g. sms_retry_30m.
+     */
+    public String topicFor(Duration delay) {
+        long seconds = delay.getSeconds();
+        if (seconds % 3600 == 0) {
+            return topicPrefix + (seconds / 3600) + "h";
+        }
+        if (seconds % 60 == 0) {
+            return topicPrefix + (seconds / 60) + "m";
+        }
+        return topicPrefix + seconds + "s";
+    }
+
+    /**
+     * Returns every tier topic and the resend topic, for the retry listener to
+     * subscribe to.
+     */
+    public String[] topics() {
+        return Stream.concat(delays.stream().map(this::topicFor), Stream.of(resendTopic))
+                .distinct().toArray(String[]::new);
+    }
+}
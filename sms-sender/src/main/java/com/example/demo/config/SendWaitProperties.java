@@ -0,0 +1,24 @@
+package com.example.demo.config;
+
+import java.time.Duration;
+import org.springframework.boot.context.properties.ConfigurationProperties;
+import org.springframework.stereotype.Component;
+
+/**
+ * Synchronous sends (POST /v1/sms/send?wait=true), bound from sms.send.wait.*
+ * properties. A waiting request gives up after timeout and answers 202 with
+ * the send still pending.
+ */
+@Component
+@ConfigurationProperties(prefix = "sms.send.wait")
+public class SendWaitProperties {
+    private Duration timeout = Duration.ofSeconds(30);
+
+    public Duration getTimeout() {
+        return timeout;
+    }
+
+    public void setTimeout(Duration timeout) {
+        this.timeout = timeout;
+    }
+}
@@ -0,0 +1,65 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.BlockedNumber;
+import com.example.demo.model.BlockedPrefix;
+import com.example.demo.service.AbuseDetectionService;
+import java.util.List;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Review and unblock numbers blocked by abuse detection, and manage blocked
+ * prefix ranges. Phone numbers and prefixes are in E.164 form (URL-encode the +).
+ */
+@RestController
+@RequestMapping("v1/admin/abuse")
+public class AbuseControllerV1 {
+    private static final String PREFIX_PATTERN = "^\\+\\d{1,14}$";
+
+    private final AbuseDetectionService abuseDetection;
+
+    @Autowired
+    public AbuseControllerV1(AbuseDetectionService abuseDetection) {
+        this.abuseDetection = abuseDetection;
+    }
+
+    @GetMapping("/blocked")
+    public List<BlockedNumber> listBlocked() {
+        return abuseDetection.listBlocked();
+    }
+
+    @DeleteMapping("/blocked/{phoneNumber}")
+    public ResponseEntity<Void> unblock(@PathVariable String phoneNumber) {
+        return abuseDetection.unblock(phoneNumber)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+
+    @GetMapping("/prefixes")
+    public List<BlockedPrefix> listPrefixes() {
+        return abuseDetection.listPrefixes();
+    }
+
+    @PutMapping("/prefixes/{prefix}")
+    public ResponseEntity<BlockedPrefix> savePrefix(@PathVariable String prefix, @Valid @RequestBody BlockedPrefix body) {
+        if (!prefix.matches(PREFIX_PATTERN)) {
+            return ResponseEntity.badRequest().build();
+        }
+        return ResponseEntity.ok(abuseDetection.savePrefix(prefix, body.getCategory()));
+    }
+
+    @DeleteMapping("/prefixes/{prefix}")
+    public ResponseEntity<Void> deletePrefix(@PathVariable String prefix) {
+        return abuseDetection.deletePrefix(prefix)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
@@ -0,0 +1,49 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.CampaignExperiment;
+import com.example.demo.service.CampaignExperimentService;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/campaigns/{campaignId}/experiment")
+public class CampaignExperimentControllerV1 {
+    private final CampaignExperimentService experimentService;
+
+    @Autowired
+    public CampaignExperimentControllerV1(CampaignExperimentService experimentService) {
+        this.experimentService = experimentService;
+    }
+
+    @GetMapping
+    public ResponseEntity<CampaignExperiment> getExperiment(@PathVariable String campaignId) {
+        CampaignExperiment experiment = experimentService.getExperiment(campaignId);
+        return experiment == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(experiment);
+    }
+
+    @PutMapping
+    public ResponseEntity<CampaignExperiment> saveExperiment(@PathVariable String campaignId,
+            @Valid @RequestBody CampaignExperiment experiment) {
+        try {
+            return ResponseEntity.ok(experimentService.saveExperiment(campaignId, experiment));
+        } catch (IllegalArgumentException e) {
+            // Duplicate variant names
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping
+    public ResponseEntity<Void> deleteExperiment(@PathVariable String campaignId) {
+        return experimentService.deleteExperiment(campaignId)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
@@ -0,0 +1,50 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.CampaignSendWindow;
+import com.example.demo.service.CampaignSendWindowService;
+import java.time.DateTimeException;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/campaigns/{campaignId}/send-window")
+public class CampaignSendWindowControllerV1 {
+    private final CampaignSendWindowService sendWindowService;
+
+    @Autowired
+    public CampaignSendWindowControllerV1(CampaignSendWindowService sendWindowService) {
+        this.sendWindowService = sendWindowService;
+    }
+
+    @GetMapping
+    public ResponseEntity<CampaignSendWindow> getWindow(@PathVariable String campaignId) {
+        CampaignSendWindow window = sendWindowService.getWindow(campaignId);
+        return window == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(window);
+    }
+
+    @PutMapping
+    public ResponseEntity<CampaignSendWindow> saveWindow(@PathVariable String campaignId,
+            @Valid @RequestBody CampaignSendWindow window) {
+        try {
+            return ResponseEntity.ok(sendWindowService.saveWindow(campaignId, window));
+        } catch (DateTimeException e) {
+            // Unknown timezone
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping
+    public ResponseEntity<Void> deleteWindow(@PathVariable String campaignId) {
+        return sendWindowService.deleteWindow(campaignId)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
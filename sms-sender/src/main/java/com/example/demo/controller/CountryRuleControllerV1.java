@@ -0,0 +1,58 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.service.CountryRuleService;
+import java.time.DateTimeException;
+import java.util.List;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/country-rules")
+public class CountryRuleControllerV1 {
+    private final CountryRuleService ruleService;
+
+    @Autowired
+    public CountryRuleControllerV1(CountryRuleService ruleService) {
+        this.ruleService = ruleService;
+    }
+
+    @GetMapping
+    public List<CountryRule> listRules() {
+        return ruleService.listRules();
+    }
+
+    @GetMapping("/{countryCode}")
+    public ResponseEntity<CountryRule> getRule(@PathVariable String countryCode) {
+        CountryRule rule = ruleService.getRule(countryCode.toUpperCase());
+        return rule == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(rule);
+    }
+
+    @PutMapping("/{countryCode}")
+    public ResponseEntity<CountryRule> saveRule(@PathVariable String countryCode, @Valid @RequestBody CountryRule rule) {
+        if (!countryCode.matches("^[A-Za-z]{2}$")) {
+            return ResponseEntity.badRequest().build();
+        }
+        try {
+            return ResponseEntity.ok(ruleService.saveRule(countryCode.toUpperCase(), rule));
+        } catch (DateTimeException e) {
+            // Unknown timezone
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping("/{countryCode}")
+    public ResponseEntity<Void> deleteRule(@PathVariable String countryCode) {
+        return ruleService.deleteRule(countryCode.toUpperCase())
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
@@ -0,0 +1,49 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.InboundMessage;
+import com.example.demo.model.InboundMessageResult;
+import com.example.demo.model.KeywordRule;
+import com.example.demo.model.OptIn;
+import com.example.demo.service.DoubleOptInService;
+import com.example.demo.service.KeywordRuleService;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.PostMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestHeader;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Receives messages recipients send us from the provider, configured per
+ * tenant number with the tenant's X-Tenant-ID. A reply confirming a double
+ * opt-in is handled as such; otherwise the tenant's keyword rules apply.
+ * Messages doing neither are acknowledged with 204.
+ */
+@RestController
+@RequestMapping("v1/webhooks/inbound")
+public class InboundMessageWebhookControllerV1 {
+    private final DoubleOptInService optInService;
+    private final KeywordRuleService keywordRules;
+
+    @Autowired
+    public InboundMessageWebhookControllerV1(DoubleOptInService optInService, KeywordRuleService keywordRules) {
+        this.optInService = optInService;
+        this.keywordRules = keywordRules;
+    }
+
+    @PostMapping
+    public ResponseEntity<InboundMessageResult> receive(@Valid @RequestBody InboundMessage message,
+            @RequestHeader(value = SmsControllerV1.TENANT_HEADER, defaultValue = SmsControllerV1.DEFAULT_TENANT) String tenantId) {
+        OptIn confirmed = optInService.handleReply(message);
+        if (confirmed != null) {
+            return ResponseEntity.ok(new InboundMessageResult(confirmed, null));
+        }
+        KeywordRule rule = keywordRules.handle(message, tenantId);
+        if (rule != null) {
+            return ResponseEntity.ok(new InboundMessageResult(null, rule));
+        }
+        return ResponseEntity.noContent().build();
+    }
+}
@@ -0,0 +1,55 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.KeywordRule;
+import com.example.demo.service.KeywordRuleService;
+import java.util.List;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/tenants/{tenantId}/keywords")
+public class KeywordRuleControllerV1 {
+    private final KeywordRuleService ruleService;
+
+    @Autowired
+    public KeywordRuleControllerV1(KeywordRuleService ruleService) {
+        this.ruleService = ruleService;
+    }
+
+    @GetMapping
+    public List<KeywordRule> listRules(@PathVariable String tenantId) {
+        return ruleService.listRules(tenantId);
+    }
+
+    @GetMapping("/{keyword}")
+    public ResponseEntity<KeywordRule> getRule(@PathVariable String tenantId, @PathVariable String keyword) {
+        KeywordRule rule = ruleService.getRule(tenantId, keyword);
+        return rule == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(rule);
+    }
+
+    @PutMapping("/{keyword}")
+    public ResponseEntity<KeywordRule> saveRule(@PathVariable String tenantId, @PathVariable String keyword,
+            @Valid @RequestBody KeywordRule rule) {
+        try {
+            return ResponseEntity.ok(ruleService.saveRule(tenantId, keyword, rule));
+        } catch (IllegalArgumentException e) {
+            // Malformed keyword
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping("/{keyword}")
+    public ResponseEntity<Void> deleteRule(@PathVariable String tenantId, @PathVariable String keyword) {
+        return ruleService.deleteRule(tenantId, keyword)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
@@ -0,0 +1,44 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.LinkClick;
+import com.example.demo.service.LinkTrackingService;
+import java.time.Instant;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.HttpStatus;
+import org.springframework.http.ResponseEntity;
+import org.springframework.kafka.KafkaException;
+import org.springframework.web.bind.annotation.PostMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Receives link clicks from the click-tracking provider and publishes them as
+ * "clicked" events, which the storage service records on the message that was
+ * clicked, the way it records read receipts. They feed the per-variant click
+ * counts of campaign A/B tests.
+ */
+@RestController
+@RequestMapping("v1/webhooks/clicks")
+public class LinkClickWebhookControllerV1 {
+    private final LinkTrackingService linkService;
+
+    @Autowired
+    public LinkClickWebhookControllerV1(LinkTrackingService linkService) {
+        this.linkService = linkService;
+    }
+
+    @PostMapping
+    public ResponseEntity<Void> recordClick(@Valid @RequestBody LinkClick click) {
+        Instant clickedAt = click.getClickedAt() != null ? click.getClickedAt() : Instant.now();
+        try {
+            linkService.publishClick(click.getPhoneNumber(), click.getProviderMessageId(), clickedAt);
+        } catch (KafkaException e) {
+            // Providers retry webhooks that fail
+            System.err.println("Failed to publish click to Kafka: " + e.getMessage());
+            return ResponseEntity.status(HttpStatus.SERVICE_UNAVAILABLE).build();
+        }
+        return ResponseEntity.accepted().build();
+    }
+}
@@ -0,0 +1,27 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.ShortLink;
+import com.example.demo.service.LinkTrackingService;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/links")
+public class LinkControllerV1 {
+    private final LinkTrackingService linkService;
+
+    @Autowired
+    public LinkControllerV1(LinkTrackingService linkService) {
+        this.linkService = linkService;
+    }
+
+    @GetMapping("{code}")
+    public ResponseEntity<ShortLink> getLink(@PathVariable String code) {
+        ShortLink link = linkService.getLink(code);
+        return link == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(link);
+    }
+}
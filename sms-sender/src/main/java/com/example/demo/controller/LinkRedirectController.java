@@ -0,0 +1,36 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.ShortLink;
+import com.example.demo.service.LinkTrackingService;
+import java.net.URI;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.HttpStatus;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Redirects short links in sent messages (sms.links.enabled) to their URLs,
+ * counting each click. Unversioned, as the links are printed in messages.
+ */
+@RestController
+@RequestMapping("r")
+public class LinkRedirectController {
+    private final LinkTrackingService linkService;
+
+    @Autowired
+    public LinkRedirectController(LinkTrackingService linkService) {
+        this.linkService = linkService;
+    }
+
+    @GetMapping("{code}")
+    public ResponseEntity<Void> redirect(@PathVariable String code) {
+        ShortLink link = linkService.recordClick(code);
+        if (link == null) {
+            return ResponseEntity.notFound().build();
+        }
+        return ResponseEntity.status(HttpStatus.FOUND).location(URI.create(link.getUrl())).build();
+    }
+}
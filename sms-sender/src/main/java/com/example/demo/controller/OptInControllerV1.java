@@ -0,0 +1,61 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.OptIn;
+import com.example.demo.model.SmsResponse;
+import com.example.demo.service.DoubleOptInService;
+import com.example.demo.service.QuotaExceededException;
+import com.example.demo.service.QuotaUnavailableException;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.HttpStatus;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PostMapping;
+import org.springframework.web.bind.annotation.RequestHeader;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Double opt-in of a user (phone number) to a category: POST sends the
+ * confirmation SMS, GET shows where the opt-in stands.
+ */
+@RestController
+@RequestMapping("v1/user/{userId}/opt-ins/{category}")
+public class OptInControllerV1 {
+    private final DoubleOptInService optInService;
+
+    @Autowired
+    public OptInControllerV1(DoubleOptInService optInService) {
+        this.optInService = optInService;
+    }
+
+    @GetMapping
+    public ResponseEntity<OptIn> getOptIn(@PathVariable String userId, @PathVariable String category) {
+        OptIn optIn = optInService.getOptIn(userId, category);
+        return optIn == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(optIn);
+    }
+
+    @PostMapping
+    public ResponseEntity<?> requestOptIn(@PathVariable String userId, @PathVariable String category,
+            @RequestHeader(value = SmsControllerV1.TENANT_HEADER, defaultValue = SmsControllerV1.DEFAULT_TENANT) String tenantId) {
+        try {
+            OptIn optIn = optInService.requestOptIn(userId, category, tenantId);
+            return OptIn.OPTED_IN.equals(optIn.getStatus())
+                    ? ResponseEntity.ok(optIn)
+                    : ResponseEntity.accepted().body(optIn);
+        } catch (IllegalArgumentException e) {
+            return ResponseEntity.badRequest().body(new SmsResponse("Failed: " + e.getMessage()));
+        } catch (QuotaExceededException e) {
+            return ResponseEntity.status(HttpStatus.TOO_MANY_REQUESTS)
+                    .header("Retry-After", String.valueOf(e.getRetryAfterSeconds()))
+                    .body(new SmsResponse("Failed: " + e.getMessage()));
+        } catch (QuotaUnavailableException e) {
+            return ResponseEntity.status(HttpStatus.SERVICE_UNAVAILABLE)
+                    .header("Retry-After", String.valueOf(e.getRetryAfterSeconds()))
+                    .body(new SmsResponse("Failed: " + e.getMessage()));
+        } catch (IllegalStateException e) {
+            // The confirmation SMS was blocked, rejected or failed
+            return ResponseEntity.unprocessableEntity().body(new SmsResponse(e.getMessage()));
+        }
+    }
+}
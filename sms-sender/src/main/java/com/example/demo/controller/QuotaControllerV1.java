@@ -0,0 +1,30 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.QuotaUsage;
+import com.example.demo.service.QuotaService;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.RequestHeader;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RequestParam;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/sms/quota")
+public class QuotaControllerV1 {
+    private final QuotaService quotaService;
+
+    @Autowired
+    public QuotaControllerV1(QuotaService quotaService) {
+        this.quotaService = quotaService;
+    }
+
+    // Usage for the calling tenant, plus a phone number's usage when requested
+    @GetMapping
+    public ResponseEntity<QuotaUsage> getUsage(
+            @RequestHeader(value = SmsControllerV1.TENANT_HEADER, defaultValue = SmsControllerV1.DEFAULT_TENANT) String tenantId,
+            @RequestParam(value = "phoneNumber", required = false) String phoneNumber) {
+        return ResponseEntity.ok(quotaService.usage(tenantId, phoneNumber));
+    }
+}
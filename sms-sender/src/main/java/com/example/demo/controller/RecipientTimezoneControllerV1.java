@@ -0,0 +1,50 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.RecipientTimezone;
+import com.example.demo.service.RecipientTimezoneService;
+import java.time.DateTimeException;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/recipients/{phoneNumber}/timezone")
+public class RecipientTimezoneControllerV1 {
+    private final RecipientTimezoneService timezoneService;
+
+    @Autowired
+    public RecipientTimezoneControllerV1(RecipientTimezoneService timezoneService) {
+        this.timezoneService = timezoneService;
+    }
+
+    @GetMapping
+    public ResponseEntity<RecipientTimezone> getTimezone(@PathVariable String phoneNumber) {
+        RecipientTimezone timezone = timezoneService.getTimezone(phoneNumber);
+        return timezone == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(timezone);
+    }
+
+    @PutMapping
+    public ResponseEntity<RecipientTimezone> saveTimezone(@PathVariable String phoneNumber,
+            @Valid @RequestBody RecipientTimezone timezone) {
+        try {
+            return ResponseEntity.ok(timezoneService.saveTimezone(phoneNumber, timezone.getTimezone()));
+        } catch (DateTimeException e) {
+            // Unknown timezone
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping
+    public ResponseEntity<Void> deleteTimezone(@PathVariable String phoneNumber) {
+        return timezoneService.deleteTimezone(phoneNumber)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
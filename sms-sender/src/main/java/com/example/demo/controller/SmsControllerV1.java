@@ -4,32 +4,189 @@ import org.springframework.beans.factory.annotation.Autowired;
 import org.springframework.http.ResponseEntity;
 import org.springframework.web.bind.annotation.PostMapping;
 import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestHeader;
 import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RequestParam;
 import org.springframework.web.bind.annotation.RestController;
 import org.springframework.http.HttpStatus;
+import com.example.demo.config.SendWaitProperties;
+import com.example.demo.model.IdempotencyRecord;
+import com.example.demo.model.SmsEvent;
 import com.example.demo.model.SmsResponse;
+import com.example.demo.service.IdempotencyService;
+import com.example.demo.service.QuotaExceededException;
+import com.example.demo.service.QuotaUnavailableException;
+import com.example.demo.service.SendOutcomeRegistry;
 import com.example.demo.service.SmsService;
 import com.example.demo.model.SmsRequest;
+import java.util.UUID;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
 import javax.validation.Valid;
 
 @RestController
 @RequestMapping("v1/sms/send")
 public class SmsControllerV1 {
+    static final String TENANT_HEADER = "X-Tenant-ID";
+    static final String DEFAULT_TENANT = "default";
+    // Status reported when a wait times out before the send has an outcome
+    static final String PENDING_STATUS = "pending";
+    static final String IDEMPOTENCY_KEY_HEADER = "Idempotency-Key";
+    // Set on responses replayed for a repeated Idempotency-Key
+    static final String IDEMPOTENT_REPLAYED_HEADER = "Idempotent-Replayed";
+
     private final SmsService service;
+    private final SendOutcomeRegistry outcomes;
+    private final SendWaitProperties waitProperties;
+    private final IdempotencyService idempotency;
 
     @Autowired // used to inject SmsService
-    public SmsControllerV1(SmsService service) {
+    public SmsControllerV1(SmsService service, SendOutcomeRegistry outcomes, SendWaitProperties waitProperties,
+            IdempotencyService idempotency) {
         this.service = service;
+        this.outcomes = outcomes;
+        this.waitProperties = waitProperties;
+        this.idempotency = idempotency;
     }
 
+    /**
+     * Sends a message. With wait=true the response is held until the send's
+     * outcome arrives on the status pipeline, so it reflects provider retries;
+     * if none arrives within sms.send.wait.timeout it answers 202 as pending.
+     * With an Idempotency-Key header, repeats of a completed request get its
+     * original response instead of sending again; see sendIdempotently.
+     */
     @PostMapping
-    public ResponseEntity<SmsResponse> sendSmsRequest(@Valid @RequestBody SmsRequest request) {
-        String result = null;
+    public ResponseEntity<SmsResponse> sendSmsRequest(@Valid @RequestBody SmsRequest request,
+            @RequestHeader(value = TENANT_HEADER, defaultValue = DEFAULT_TENANT) String tenantId,
+            @RequestHeader(value = IDEMPOTENCY_KEY_HEADER, required = false) String idempotencyKey,
+            @RequestParam(value = "wait", defaultValue = "false") boolean wait) {
+        request.setTenantId(tenantId);
+        if (idempotencyKey == null) {
+            return send(request, wait);
+        }
+        return sendIdempotently(request, idempotencyKey.trim(), wait);
+    }
+
+    /**
+     * Sends under an idempotency key. Only 2xx responses are kept for replay
+     * (with an Idempotent-Replayed header); after a rejected or failed send
+     * the key is released so the client can retry it. A repeat with another
+     * body answers 422, and one arriving while the first is still sending 409.
+     */
+    private ResponseEntity<SmsResponse> sendIdempotently(SmsRequest request, String key, boolean wait) {
+        if (key.isEmpty() || key.length() > IdempotencyService.MAX_KEY_LENGTH) {
+            return ResponseEntity.badRequest().body(new SmsResponse(
+                    "Failed: " + IDEMPOTENCY_KEY_HEADER + " must be 1 to " + IdempotencyService.MAX_KEY_LENGTH + " characters"));
+        }
+        String tenantId = request.getTenantId();
+        String fingerprint;
+        IdempotencyRecord existing;
         try {
-            result = service.sendSms(request);
+            fingerprint = idempotency.fingerprint(request);
+            existing = idempotency.claim(tenantId, key, fingerprint);
         } catch (Exception e) {
+            // Sending without the key could duplicate a send the client is retrying
             return ResponseEntity.status(HttpStatus.INTERNAL_SERVER_ERROR).body(new SmsResponse("Server error kindly try again later"));
         }
-        return ResponseEntity.ok(new SmsResponse(result));
+        if (existing != null) {
+            if (!fingerprint.equals(existing.getFingerprint())) {
+                return ResponseEntity.unprocessableEntity().body(new SmsResponse(
+                        "Failed: " + IDEMPOTENCY_KEY_HEADER + " was already used for a different request"));
+            }
+            if (existing.isInProgress()) {
+                return ResponseEntity.status(HttpStatus.CONFLICT).body(new SmsResponse(
+                        "Failed: a request with this " + IDEMPOTENCY_KEY_HEADER + " is still in progress"));
+            }
+            return ResponseEntity.status(existing.getStatus())
+                    .header(IDEMPOTENT_REPLAYED_HEADER, "true")
+                    .body(existing.getResponse());
+        }
+
+        ResponseEntity<SmsResponse> response = null;
+        try {
+            response = send(request, wait);
+        } finally {
+            try {
+                if (response != null && response.getStatusCode().is2xxSuccessful()) {
+                    idempotency.complete(tenantId, key, fingerprint, response.getStatusCodeValue(), response.getBody());
+                } else {
+                    idempotency.release(tenantId, key);
+                }
+            } catch (Exception e) {
+                // The claim expires on its own; the send itself has happened
+                System.err.println("Failed to record idempotency key " + key + ": " + e.getMessage());
+            }
+        }
+        return response;
+    }
+
+    private ResponseEntity<SmsResponse> send(SmsRequest request, boolean wait) {
+        String sendId = UUID.randomUUID().toString();
+        // Registered before sending so an outcome published straight away isn't missed
+        CompletableFuture<SmsEvent> outcome = wait ? outcomes.register(sendId) : null;
+        try {
+            String result = null;
+            try {
+                result = service.sendSms(request, sendId);
+            } catch (QuotaExceededException e) {
+                return ResponseEntity.status(HttpStatus.TOO_MANY_REQUESTS)
+                        .header("Retry-After", String.valueOf(e.getRetryAfterSeconds()))
+                        .body(new SmsResponse("Failed: " + e.getMessage()));
+            } catch (QuotaUnavailableException e) {
+                return ResponseEntity.status(HttpStatus.SERVICE_UNAVAILABLE)
+                        .header("Retry-After", String.valueOf(e.getRetryAfterSeconds()))
+                        .body(new SmsResponse("Failed: " + e.getMessage()));
+            } catch (Exception e) {
+                return ResponseEntity.status(HttpStatus.INTERNAL_SERVER_ERROR).body(new SmsResponse("Server error kindly try again later"));
+            }
+            if (outcome == null) {
+                return ResponseEntity.ok(new SmsResponse(result));
+            }
+            return awaitOutcome(outcome, result);
+        } finally {
+            if (outcome != null) {
+                outcomes.cancel(sendId);
+            }
+        }
+    }
+
+    private ResponseEntity<SmsResponse> awaitOutcome(CompletableFuture<SmsEvent> outcome, String result) {
+        SmsEvent event;
+        try {
+            event = outcome.get(waitProperties.getTimeout().toMillis(), TimeUnit.MILLISECONDS);
+        } catch (TimeoutException | ExecutionException e) {
+            return pending(result);
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+            return pending(result);
+        }
+
+        SmsResponse response = new SmsResponse(outcomeResult(event, result));
+        response.setStatus(event.getStatus());
+        if ("deferred".equals(event.getStatus())) {
+            return ResponseEntity.status(HttpStatus.ACCEPTED).body(response);
+        }
+        return ResponseEntity.ok(response);
+    }
+
+    private static ResponseEntity<SmsResponse> pending(String result) {
+        SmsResponse response = new SmsResponse(result);
+        response.setStatus(PENDING_STATUS);
+        return ResponseEntity.status(HttpStatus.ACCEPTED).body(response);
+    }
+
+    // The immediate result describes the first attempt; a retried send ends
+    // with the outcome of its last attempt instead
+    private static String outcomeResult(SmsEvent event, String result) {
+        if ("successful".equals(event.getStatus())) {
+            return "SMS sent to " + event.getPhoneNumber();
+        }
+        if (event.getAttempt() != null) {
+            return "Failed to send SMS after " + event.getAttempt() + " attempts";
+        }
+        return result;
     }
 }
@@ -0,0 +1,39 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.SmsPreview;
+import com.example.demo.model.SmsPreviewRequest;
+import com.example.demo.service.SmsPreviewService;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.PostMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestHeader;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * Dry runs of POST /v1/sms/send, so client teams can check an integration
+ * without sending anything or using up quota.
+ */
+@RestController
+@RequestMapping("v1/sms/preview")
+public class SmsPreviewControllerV1 {
+    private final SmsPreviewService service;
+
+    @Autowired
+    public SmsPreviewControllerV1(SmsPreviewService service) {
+        this.service = service;
+    }
+
+    /**
+     * Renders the message with params and reports its encoding, segments and
+     * estimated cost, and whether a send now would go out, be held or fail.
+     */
+    @PostMapping
+    public ResponseEntity<SmsPreview> previewSmsRequest(@Valid @RequestBody SmsPreviewRequest request,
+            @RequestHeader(value = SmsControllerV1.TENANT_HEADER, defaultValue = SmsControllerV1.DEFAULT_TENANT) String tenantId) {
+        request.setTenantId(tenantId);
+        return ResponseEntity.ok(service.preview(request));
+    }
+}
@@ -0,0 +1,49 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.QuietHoursWindow;
+import com.example.demo.service.TenantQuietHoursService;
+import java.time.DateTimeException;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.ResponseEntity;
+import org.springframework.web.bind.annotation.DeleteMapping;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+@RestController
+@RequestMapping("v1/admin/tenants/{tenantId}/quiet-hours")
+public class TenantQuietHoursControllerV1 {
+    private final TenantQuietHoursService quietHoursService;
+
+    @Autowired
+    public TenantQuietHoursControllerV1(TenantQuietHoursService quietHoursService) {
+        this.quietHoursService = quietHoursService;
+    }
+
+    @GetMapping
+    public ResponseEntity<QuietHoursWindow> getWindow(@PathVariable String tenantId) {
+        QuietHoursWindow window = quietHoursService.getWindow(tenantId);
+        return window == null ? ResponseEntity.notFound().build() : ResponseEntity.ok(window);
+    }
+
+    @PutMapping
+    public ResponseEntity<QuietHoursWindow> saveWindow(@PathVariable String tenantId, @Valid @RequestBody QuietHoursWindow window) {
+        try {
+            return ResponseEntity.ok(quietHoursService.saveWindow(tenantId, window));
+        } catch (DateTimeException e) {
+            // Unknown timezone
+            return ResponseEntity.badRequest().build();
+        }
+    }
+
+    @DeleteMapping
+    public ResponseEntity<Void> deleteWindow(@PathVariable String tenantId) {
+        return quietHoursService.deleteWindow(tenantId)
+                ? ResponseEntity.noContent().build()
+                : ResponseEntity.notFound().build();
+    }
+}
@@ -0,0 +1,50 @@
+package com.example.demo.controller;
+
+import com.example.demo.model.UserPreferences;
+import com.example.demo.service.UserPreferenceService;
+import javax.validation.Valid;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.http.HttpStatus;
+import org.springframework.http.ResponseEntity;
+import org.springframework.kafka.KafkaException;
+import org.springframework.web.bind.annotation.GetMapping;
+import org.springframework.web.bind.annotation.PathVariable;
+import org.springframework.web.bind.annotation.PutMapping;
+import org.springframework.web.bind.annotation.RequestBody;
+import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RestController;
+
+/**
+ * The preference center: users' per-channel and per-category consent, keyed
+ * by phone number.
+ */
+@RestController
+@RequestMapping("v1/user/{userId}/preferences")
+public class UserPreferencesControllerV1 {
+    private final UserPreferenceService preferenceService;
+
+    @Autowired
+    public UserPreferencesControllerV1(UserPreferenceService preferenceService) {
+        this.preferenceService = preferenceService;
+    }
+
+    @GetMapping
+    public ResponseEntity<UserPreferences> getPreferences(@PathVariable String userId) {
+        return ResponseEntity.ok(preferenceService.getPreferences(userId));
+    }
+
+    @PutMapping
+    public ResponseEntity<UserPreferences> savePreferences(@PathVariable String userId,
+            @Valid @RequestBody UserPreferences preferences) {
+        try {
+            return ResponseEntity.ok(preferenceService.savePreferences(userId, preferences));
+        } catch (IllegalArgumentException e) {
+            // Malformed channel or unknown category
+            return ResponseEntity.badRequest().build();
+        } catch (KafkaException e) {
+            // Stored, but downstream systems haven't heard; retrying publishes it
+            System.err.println("Failed to publish preference change to Kafka: " + e.getMessage());
+            return ResponseEntity.status(HttpStatus.SERVICE_UNAVAILABLE).build();
+        }
+    }
+}
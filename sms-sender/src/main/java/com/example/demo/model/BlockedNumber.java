@@ -0,0 +1,53 @@
+package com.example.demo.model;
+
+/**
+ * A destination blocked by abuse detection, kept for admin review.
+ */
+public class BlockedNumber {
+    private String phoneNumber;
+    private String reason;
+    private long blockedAtEpochMs;
+    // When the block lifts by itself; 0 for blocks recorded before blocks expired
+    private long expiresAtEpochMs;
+
+    public BlockedNumber() {
+    }
+
+    public BlockedNumber(String phoneNumber, String reason, long blockedAtEpochMs) {
+        this.phoneNumber = phoneNumber;
+        this.reason = reason;
+        this.blockedAtEpochMs = blockedAtEpochMs;
+    }
+
+    public String getPhoneNumber() {
+        return phoneNumber;
+    }
+
+    public void setPhoneNumber(String phoneNumber) {
+        this.phoneNumber = phoneNumber;
+    }
+
+    public String getReason() {
+        return reason;
+    }
+
+    public void setReason(String reason) {
+        this.reason = reason;
+    }
+
+    public long getBlockedAtEpochMs() {
+        return blockedAtEpochMs;
+    }
+
+    public void setBlockedAtEpochMs(long blockedAtEpochMs) {
+        this.blockedAtEpochMs = blockedAtEpochMs;
+    }
+
+    public long getExpiresAtEpochMs() {
+        return expiresAtEpochMs;
+    }
+
+    public void setExpiresAtEpochMs(long expiresAtEpochMs) {
+        this.expiresAtEpochMs = expiresAtEpochMs;
+    }
+}
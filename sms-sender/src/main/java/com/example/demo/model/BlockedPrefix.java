@@ -0,0 +1,39 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.NotBlank;
+import javax.validation.constraints.Pattern;
+
+/**
+ * A destination range no message may be sent to, e.g. a premium-rate prefix.
+ */
+public class BlockedPrefix {
+    private String prefix;
+    // "premium_rate", "fraud" or any label an admin chooses
+    @NotBlank(message = "Category is mandatory")
+    @Pattern(regexp = "^[a-z_]{1,32}$", message = "Category must be lowercase letters and underscores")
+    private String category;
+
+    public BlockedPrefix() {
+    }
+
+    public BlockedPrefix(String prefix, String category) {
+        this.prefix = prefix;
+        this.category = category;
+    }
+
+    public String getPrefix() {
+        return prefix;
+    }
+
+    public void setPrefix(String prefix) {
+        this.prefix = prefix;
+    }
+
+    public String getCategory() {
+        return category;
+    }
+
+    public void setCategory(String category) {
+        this.category = category;
+    }
+}
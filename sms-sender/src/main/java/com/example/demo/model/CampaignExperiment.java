@@ -0,0 +1,31 @@
+package com.example.demo.model;
+
+import java.util.List;
+import javax.validation.Valid;
+import javax.validation.constraints.NotEmpty;
+import javax.validation.constraints.Size;
+
+/**
+ * A campaign's template A/B test: the variants its sends are split between.
+ */
+public class CampaignExperiment {
+    @NotEmpty(message = "At least one variant is required")
+    @Size(max = 10, message = "At most 10 variants are allowed")
+    @Valid
+    private List<TemplateVariant> variants;
+
+    public CampaignExperiment() {
+    }
+
+    public CampaignExperiment(List<TemplateVariant> variants) {
+        this.variants = variants;
+    }
+
+    public List<TemplateVariant> getVariants() {
+        return variants;
+    }
+
+    public void setVariants(List<TemplateVariant> variants) {
+        this.variants = variants;
+    }
+}
@@ -0,0 +1,54 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.NotBlank;
+import javax.validation.constraints.Pattern;
+
+/**
+ * The hours of the day a campaign's messages may go out, in each recipient's
+ * local time: "send at 10:00 recipient local time" is a window from 10:00.
+ * Sends outside it are deferred to its next start in the recipient's timezone.
+ */
+public class CampaignSendWindow {
+    @NotBlank(message = "Start is mandatory")
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "Start must be HH:mm")
+    private String start;
+    @NotBlank(message = "End is mandatory")
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "End must be HH:mm")
+    private String end;
+    // Used when the recipient's timezone can't be inferred
+    @NotBlank(message = "Default timezone is mandatory")
+    private String defaultTimezone;
+
+    public CampaignSendWindow() {
+    }
+
+    public CampaignSendWindow(String start, String end, String defaultTimezone) {
+        this.start = start;
+        this.end = end;
+        this.defaultTimezone = defaultTimezone;
+    }
+
+    public String getStart() {
+        return start;
+    }
+
+    public void setStart(String start) {
+        this.start = start;
+    }
+
+    public String getEnd() {
+        return end;
+    }
+
+    public void setEnd(String end) {
+        this.end = end;
+    }
+
+    public String getDefaultTimezone() {
+        return defaultTimezone;
+    }
+
+    public void setDefaultTimezone(String defaultTimezone) {
+        this.defaultTimezone = defaultTimezone;
+    }
+}
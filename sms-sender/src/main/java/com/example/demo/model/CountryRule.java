@@ -0,0 +1,88 @@
+package com.example.demo.model;
+
+import java.util.ArrayList;
+import java.util.List;
+import javax.validation.constraints.NotBlank;
+import javax.validation.constraints.Pattern;
+
+/**
+ * Compliance rules for sending to one destination country.
+ */
+public class CountryRule {
+    private String countryCode;
+    // IANA zone used to evaluate quiet hours, e.g. Asia/Kolkata
+    @NotBlank(message = "Timezone is mandatory")
+    private String timezone;
+    // Promotional sends between start and end (recipient local time, HH:mm) are
+    // deferred or rejected. The window may cross midnight. Null disables it.
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "Quiet hours must be HH:mm")
+    private String quietHoursStart;
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "Quiet hours must be HH:mm")
+    private String quietHoursEnd;
+    // "defer" (default) holds the message until quiet hours end; "reject" drops it
+    @Pattern(regexp = "^(defer|reject)$", message = "Quiet hours action must be defer or reject")
+    private String quietHoursAction = "defer";
+    // Sender IDs allowed for this country; empty allows any
+    private List<String> allowedSenderIds = new ArrayList<>();
+    // Rejects all promotional messages to this country
+    private boolean promotionalBanned;
+
+    public CountryRule() {
+    }
+
+    public String getCountryCode() {
+        return countryCode;
+    }
+
+    public void setCountryCode(String countryCode) {
+        this.countryCode = countryCode;
+    }
+
+    public String getTimezone() {
+        return timezone;
+    }
+
+    public void setTimezone(String timezone) {
+        this.timezone = timezone;
+    }
+
+    public String getQuietHoursStart() {
+        return quietHoursStart;
+    }
+
+    public void setQuietHoursStart(String quietHoursStart) {
+        this.quietHoursStart = quietHoursStart;
+    }
+
+    public String getQuietHoursEnd() {
+        return quietHoursEnd;
+    }
+
+    public void setQuietHoursEnd(String quietHoursEnd) {
+        this.quietHoursEnd = quietHoursEnd;
+    }
+
+    public String getQuietHoursAction() {
+        return quietHoursAction;
+    }
+
+    public void setQuietHoursAction(String quietHoursAction) {
+        this.quietHoursAction = quietHoursAction;
+    }
+
+    public List<String> getAllowedSenderIds() {
+        return allowedSenderIds;
+    }
+
+    public void setAllowedSenderIds(List<String> allowedSenderIds) {
+        this.allowedSenderIds = allowedSenderIds;
+    }
+
+    public boolean isPromotionalBanned() {
+        return promotionalBanned;
+    }
+
+    public void setPromotionalBanned(boolean promotionalBanned) {
+        this.promotionalBanned = promotionalBanned;
+    }
+}
@@ -0,0 +1,64 @@
+package com.example.demo.model;
+
+/**
+ * A send request held back until it may be delivered.
+ */
+public class DeferredSms {
+    private String id;
+    // Kept separately because SmsRequest never serializes its tenant
+    private String tenantId;
+    private SmsRequest request;
+    private long releaseAtEpochMs;
+    // Failed release attempts so far
+    private int attempts;
+
+    public DeferredSms() {
+    }
+
+    public DeferredSms(String id, String tenantId, SmsRequest request, long releaseAtEpochMs) {
+        this.id = id;
+        this.tenantId = tenantId;
+        this.request = request;
+        this.releaseAtEpochMs = releaseAtEpochMs;
+    }
+
+    public String getId() {
+        return id;
+    }
+
+    public void setId(String id) {
+        this.id = id;
+    }
+
+    public String getTenantId() {
+        return tenantId;
+    }
+
+    public void setTenantId(String tenantId) {
+        this.tenantId = tenantId;
+    }
+
+    public SmsRequest getRequest() {
+        return request;
+    }
+
+    public void setRequest(SmsRequest request) {
+        this.request = request;
+    }
+
+    public long getReleaseAtEpochMs() {
+        return releaseAtEpochMs;
+    }
+
+    public void setReleaseAtEpochMs(long releaseAtEpochMs) {
+        this.releaseAtEpochMs = releaseAtEpochMs;
+    }
+
+    public int getAttempts() {
+        return attempts;
+    }
+
+    public void setAttempts(int attempts) {
+        this.attempts = attempts;
+    }
+}
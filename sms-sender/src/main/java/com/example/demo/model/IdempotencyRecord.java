@@ -0,0 +1,52 @@
+package com.example.demo.model;
+
+import com.fasterxml.jackson.annotation.JsonIgnore;
+
+/**
+ * What is kept under an Idempotency-Key: a fingerprint of the request that
+ * claimed it and, once that send finished, its response.
+ */
+public class IdempotencyRecord {
+    private String fingerprint;
+    // HTTP status of the original response; 0 while the send is in progress
+    private int status;
+    private SmsResponse response;
+
+    public IdempotencyRecord() {
+    }
+
+    public IdempotencyRecord(String fingerprint, int status, SmsResponse response) {
+        this.fingerprint = fingerprint;
+        this.status = status;
+        this.response = response;
+    }
+
+    public String getFingerprint() {
+        return fingerprint;
+    }
+
+    public void setFingerprint(String fingerprint) {
+        this.fingerprint = fingerprint;
+    }
+
+    public int getStatus() {
+        return status;
+    }
+
+    public void setStatus(int status) {
+        this.status = status;
+    }
+
+    public SmsResponse getResponse() {
+        return response;
+    }
+
+    public void setResponse(SmsResponse response) {
+        this.response = response;
+    }
+
+    @JsonIgnore
+    public boolean isInProgress() {
+        return status == 0;
+    }
+}
@@ -0,0 +1,40 @@
+package com.example.demo.model;
+
+import java.time.Instant;
+import javax.validation.constraints.NotBlank;
+
+/**
+ * A message a recipient sent us, as reported by the provider's inbound webhook.
+ */
+public class InboundMessage {
+    @NotBlank(message = "Phone number is mandatory")
+    private String phoneNumber;
+    @NotBlank(message = "Message is mandatory")
+    private String message;
+    // When the provider received it; null means when the webhook arrived
+    private Instant receivedAt;
+
+    public String getPhoneNumber() {
+        return phoneNumber;
+    }
+
+    public void setPhoneNumber(String phoneNumber) {
+        this.phoneNumber = phoneNumber;
+    }
+
+    public String getMessage() {
+        return message;
+    }
+
+    public void setMessage(String message) {
+        this.message = message;
+    }
+
+    public Instant getReceivedAt() {
+        return receivedAt;
+    }
+
+    public void setReceivedAt(Instant receivedAt) {
+        this.receivedAt = receivedAt;
+    }
+}
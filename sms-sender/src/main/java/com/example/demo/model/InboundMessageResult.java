@@ -0,0 +1,34 @@
+package com.example.demo.model;
+
+/**
+ * What an inbound message did: confirmed a double opt-in, or matched a
+ * keyword rule.
+ */
+public class InboundMessageResult {
+    private OptIn optIn;
+    private KeywordRule keywordRule;
+
+    public InboundMessageResult() {
+    }
+
+    public InboundMessageResult(OptIn optIn, KeywordRule keywordRule) {
+        this.optIn = optIn;
+        this.keywordRule = keywordRule;
+    }
+
+    public OptIn getOptIn() {
+        return optIn;
+    }
+
+    public void setOptIn(OptIn optIn) {
+        this.optIn = optIn;
+    }
+
+    public KeywordRule getKeywordRule() {
+        return keywordRule;
+    }
+
+    public void setKeywordRule(KeywordRule keywordRule) {
+        this.keywordRule = keywordRule;
+    }
+}
@@ -0,0 +1,64 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.Pattern;
+
+/**
+ * What an inbound message starting with a keyword does: the reply sent back,
+ * if any, and the action taken on the sender's preferences. opt_out turns
+ * SMS off for the rule's category, or for every category when it has none;
+ * opt_in turns it back on.
+ */
+public class KeywordRule {
+    public static final String ACTION_NONE = "none";
+    public static final String ACTION_OPT_OUT = "opt_out";
+    public static final String ACTION_OPT_IN = "opt_in";
+
+    private String keyword;
+    // Sent back as a transactional SMS; null sends nothing
+    private String reply;
+    @Pattern(regexp = "^(none|opt_out|opt_in)$", message = "Action must be none, opt_out or opt_in")
+    private String action = ACTION_NONE;
+    @Pattern(regexp = "^(transactional|promotional)$", message = "Category must be transactional or promotional")
+    private String category;
+
+    public KeywordRule() {
+    }
+
+    public KeywordRule(String keyword, String reply, String action) {
+        this.keyword = keyword;
+        this.reply = reply;
+        this.action = action;
+    }
+
+    public String getKeyword() {
+        return keyword;
+    }
+
+    public void setKeyword(String keyword) {
+        this.keyword = keyword;
+    }
+
+    public String getReply() {
+        return reply;
+    }
+
+    public void setReply(String reply) {
+        this.reply = reply;
+    }
+
+    public String getAction() {
+        return action;
+    }
+
+    public void setAction(String action) {
+        this.action = action;
+    }
+
+    public String getCategory() {
+        return category;
+    }
+
+    public void setCategory(String category) {
+        this.category = category;
+    }
+}
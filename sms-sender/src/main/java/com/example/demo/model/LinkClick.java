@@ -0,0 +1,41 @@
+package com.example.demo.model;
+
+import java.time.Instant;
+import javax.validation.constraints.NotBlank;
+
+/**
+ * A link click reported by the click-tracking provider: the recipient opened a
+ * link in the message the provider knows by providerMessageId.
+ */
+public class LinkClick {
+    @NotBlank(message = "Phone number is mandatory")
+    private String phoneNumber;
+    @NotBlank(message = "Provider message ID is mandatory")
+    private String providerMessageId;
+    // When the link was opened; null means when the webhook arrived
+    private Instant clickedAt;
+
+    public String getPhoneNumber() {
+        return phoneNumber;
+    }
+
+    public void setPhoneNumber(String phoneNumber) {
+        this.phoneNumber = phoneNumber;
+    }
+
+    public String getProviderMessageId() {
+        return providerMessageId;
+    }
+
+    public void setProviderMessageId(String providerMessageId) {
+        this.providerMessageId = providerMessageId;
+    }
+
+    public Instant getClickedAt() {
+        return clickedAt;
+    }
+
+    public void setClickedAt(Instant clickedAt) {
+        this.clickedAt = clickedAt;
+    }
+}
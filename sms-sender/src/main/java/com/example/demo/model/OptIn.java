@@ -0,0 +1,75 @@
+package com.example.demo.model;
+
+/**
+ * A user's double opt-in to a category: PENDING_CONFIRMATION once the
+ * confirmation SMS went out, OPTED_IN once they replied with its code.
+ */
+public class OptIn {
+    public static final String PENDING_CONFIRMATION = "PENDING_CONFIRMATION";
+    public static final String OPTED_IN = "OPTED_IN";
+
+    private String phoneNumber;
+    private String category;
+    private String status;
+    private String requestedAt;
+    // Until when the pending code can be confirmed; a new request issues another
+    private String expiresAt;
+    private String confirmedAt;
+
+    public OptIn() {
+    }
+
+    public OptIn(String phoneNumber, String category, String status) {
+        this.phoneNumber = phoneNumber;
+        this.category = category;
+        this.status = status;
+    }
+
+    public String getPhoneNumber() {
+        return phoneNumber;
+    }
+
+    public void setPhoneNumber(String phoneNumber) {
+        this.phoneNumber = phoneNumber;
+    }
+
+    public String getCategory() {
+        return category;
+    }
+
+    public void setCategory(String category) {
+        this.category = category;
+    }
+
+    public String getStatus() {
+        return status;
+    }
+
+    public void setStatus(String status) {
+        this.status = status;
+    }
+
+    public String getRequestedAt() {
+        return requestedAt;
+    }
+
+    public void setRequestedAt(String requestedAt) {
+        this.requestedAt = requestedAt;
+    }
+
+    public String getExpiresAt() {
+        return expiresAt;
+    }
+
+    public void setExpiresAt(String expiresAt) {
+        this.expiresAt = expiresAt;
+    }
+
+    public String getConfirmedAt() {
+        return confirmedAt;
+    }
+
+    public void setConfirmedAt(String confirmedAt) {
+        this.confirmedAt = confirmedAt;
+    }
+}
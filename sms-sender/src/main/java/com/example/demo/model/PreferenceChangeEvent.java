@@ -0,0 +1,65 @@
+package com.example.demo.model;
+
+import java.util.Map;
+
+/**
+ * Published to the sms_preference_changes topic, keyed by user, whenever a
+ * user's preferences change, so downstream systems (CRM, email, push) can
+ * honour the same consent.
+ */
+public class PreferenceChangeEvent {
+    private String userId;
+    // Consent before and after the change; previous is empty for a user's first preferences
+    private Map<String, Map<String, Boolean>> previous;
+    private Map<String, Map<String, Boolean>> consent;
+    private String changedAt;
+
+    public PreferenceChangeEvent() {
+    }
+
+    public PreferenceChangeEvent(String userId, Map<String, Map<String, Boolean>> previous,
+            Map<String, Map<String, Boolean>> consent, String changedAt) {
+        this.userId = userId;
+        this.previous = previous;
+        this.consent = consent;
+        this.changedAt = changedAt;
+    }
+
+    public String getUserId() {
+        return userId;
+    }
+
+    public void setUserId(String userId) {
+        this.userId = userId;
+    }
+
+    public Map<String, Map<String, Boolean>> getPrevious() {
+        return previous;
+    }
+
+    public void setPrevious(Map<String, Map<String, Boolean>> previous) {
+        this.previous = previous;
+    }
+
+    public Map<String, Map<String, Boolean>> getConsent() {
+        return consent;
+    }
+
+    public void setConsent(Map<String, Map<String, Boolean>> consent) {
+        this.consent = consent;
+    }
+
+    public String getChangedAt() {
+        return changedAt;
+    }
+
+    public void setChangedAt(String changedAt) {
+        this.changedAt = changedAt;
+    }
+
+    @Override
+    public String toString() {
+        return "PreferenceChangeEvent{userId='" + userId + "', previous=" + previous + ", consent=" + consent
+                + ", changedAt='" + changedAt + "'}";
+    }
+}
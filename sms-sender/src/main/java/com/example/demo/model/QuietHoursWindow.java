@@ -0,0 +1,53 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.NotBlank;
+import javax.validation.constraints.Pattern;
+
+/**
+ * A tenant's quiet-hour window in recipient local time. Promotional messages
+ * sent inside it are deferred until it ends.
+ */
+public class QuietHoursWindow {
+    @NotBlank(message = "Start is mandatory")
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "Start must be HH:mm")
+    private String start;
+    @NotBlank(message = "End is mandatory")
+    @Pattern(regexp = "^([01]\\d|2[0-3]):[0-5]\\d$", message = "End must be HH:mm")
+    private String end;
+    // Used when the recipient's timezone can't be derived from a country rule
+    @NotBlank(message = "Default timezone is mandatory")
+    private String defaultTimezone;
+
+    public QuietHoursWindow() {
+    }
+
+    public QuietHoursWindow(String start, String end, String defaultTimezone) {
+        this.start = start;
+        this.end = end;
+        this.defaultTimezone = defaultTimezone;
+    }
+
+    public String getStart() {
+        return start;
+    }
+
+    public void setStart(String start) {
+        this.start = start;
+    }
+
+    public String getEnd() {
+        return end;
+    }
+
+    public void setEnd(String end) {
+        this.end = end;
+    }
+
+    public String getDefaultTimezone() {
+        return defaultTimezone;
+    }
+
+    public void setDefaultTimezone(String defaultTimezone) {
+        this.defaultTimezone = defaultTimezone;
+    }
+}
@@ -0,0 +1,103 @@
+package com.example.demo.model;
+
+/**
+ * Current quota consumption. A limit of 0 means unlimited.
+ */
+public class QuotaUsage {
+    private String tenantId;
+    private String phoneNumber;
+    private Window tenantDaily;
+    private Window tenantMonthly;
+    private Window phoneDaily;
+    private Window phoneMonthly;
+
+    public QuotaUsage() {
+    }
+
+    public String getTenantId() {
+        return tenantId;
+    }
+
+    public void setTenantId(String tenantId) {
+        this.tenantId = tenantId;
+    }
+
+    public String getPhoneNumber() {
+        return phoneNumber;
+    }
+
+    public void setPhoneNumber(String phoneNumber) {
+        this.phoneNumber = phoneNumber;
+    }
+
+    public Window getTenantDaily() {
+        return tenantDaily;
+    }
+
+    public void setTenantDaily(Window tenantDaily) {
+        this.tenantDaily = tenantDaily;
+    }
+
+    public Window getTenantMonthly() {
+        return tenantMonthly;
+    }
+
+    public void setTenantMonthly(Window tenantMonthly) {
+        this.tenantMonthly = tenantMonthly;
+    }
+
+    public Window getPhoneDaily() {
+        return phoneDaily;
+    }
+
+    public void setPhoneDaily(Window phoneDaily) {
+        this.phoneDaily = phoneDaily;
+    }
+
+    public Window getPhoneMonthly() {
+        return phoneMonthly;
+    }
+
+    public void setPhoneMonthly(Window phoneMonthly) {
+        this.phoneMonthly = phoneMonthly;
+    }
+
+    public static class Window {
+        private long used;
+        private long limit;
+        private long resetsInSeconds;
+
+        public Window() {
+        }
+
+        public Window(long used, long limit, long resetsInSeconds) {
+            this.used = used;
+            this.limit = limit;
+            this.resetsInSeconds = resetsInSeconds;
+        }
+
+        public long getUsed() {
+            return used;
+        }
+
+        public void setUsed(long used) {
+            this.used = used;
+        }
+
+        public long getLimit() {
+            return limit;
+        }
+
+        public void setLimit(long limit) {
+            this.limit = limit;
+        }
+
+        public long getResetsInSeconds() {
+            return resetsInSeconds;
+        }
+
+        public void setResetsInSeconds(long resetsInSeconds) {
+            this.resetsInSeconds = resetsInSeconds;
+        }
+    }
+}
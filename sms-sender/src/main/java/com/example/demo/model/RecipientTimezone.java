@@ -0,0 +1,42 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.NotBlank;
+
+/**
+ * A recipient's timezone, as an IANA zone ID, and where it came from:
+ * "manual" when set through the admin API, "country" from the country rule of
+ * their country, "number" from their calling code.
+ */
+public class RecipientTimezone {
+    public static final String SOURCE_MANUAL = "manual";
+    public static final String SOURCE_COUNTRY = "country";
+    public static final String SOURCE_NUMBER = "number";
+
+    @NotBlank(message = "Timezone is mandatory")
+    private String timezone;
+    private String source;
+
+    public RecipientTimezone() {
+    }
+
+    public RecipientTimezone(String timezone, String source) {
+        this.timezone = timezone;
+        this.source = source;
+    }
+
+    public String getTimezone() {
+        return timezone;
+    }
+
+    public void setTimezone(String timezone) {
+        this.timezone = timezone;
+    }
+
+    public String getSource() {
+        return source;
+    }
+
+    public void setSource(String source) {
+        this.source = source;
+    }
+}
@@ -1,10 +1,42 @@
 package com.example.demo.model;
 
+import java.util.Map;
+
 public class SmsEvent {
+    // Event schema version this class writes; the storage service upcasts older ones
+    public static final int SCHEMA_VERSION = 3;
+    // Sender ID callers use for the default sender; events leave it out
+    public static final String DEFAULT_SENDER_ID = "default";
+
+    private int schemaVersion = SCHEMA_VERSION;
     private String phoneNumber;
     private String message;
     private String status;
     private String eventId;
+    // Stable across every status and retry attempt of one send, so a caller
+    // waiting on the send can match its outcome
+    private String sendId;
+    // Delivery metadata; null when unknown (e.g. blocked before reaching a provider)
+    private String provider;
+    private String providerMessageId;
+    private String campaignId;
+    private String templateId;
+    // Template variant of the campaign's A/B test the recipient was assigned
+    private String variant;
+    private String countryCode;
+    private Map<String, String> metadata;
+    // Tenant that requested the send (X-Tenant-ID); null for untenanted callers
+    private String tenantId;
+    // "transactional" or "promotional"; lets downstream consumers filter by category
+    private String category;
+    // Sender ID or shortcode the message goes out under; null for the default sender
+    private String senderId;
+    // How long the provider took to accept or reject the send
+    private Long providerLatencyMs;
+    // Provider send attempt this event reports; null for the first attempt
+    private Integer attempt;
+    // When the recipient clicked a link in the message; set on "clicked" events only
+    private String clickedAt;
     public SmsEvent() {
     }
     public SmsEvent(String phoneNumber, String message, String status) {
@@ -12,6 +44,12 @@ public class SmsEvent {
         this.message = message;
         this.status = status;
     }
+    public int getSchemaVersion() {
+        return schemaVersion;
+    }
+    public void setSchemaVersion(int schemaVersion) {
+        this.schemaVersion = schemaVersion;
+    }
     public String getPhoneNumber() {
         return phoneNumber;
     }
@@ -36,4 +74,88 @@ public class SmsEvent {
     public void setEventId(String eventId) {
         this.eventId = eventId;
     }
+    public String getSendId() {
+        return sendId;
+    }
+    public void setSendId(String sendId) {
+        this.sendId = sendId;
+    }
+    public String getProvider() {
+        return provider;
+    }
+    public void setProvider(String provider) {
+        this.provider = provider;
+    }
+    public String getProviderMessageId() {
+        return providerMessageId;
+    }
+    public void setProviderMessageId(String providerMessageId) {
+        this.providerMessageId = providerMessageId;
+    }
+    public String getCampaignId() {
+        return campaignId;
+    }
+    public void setCampaignId(String campaignId) {
+        this.campaignId = campaignId;
+    }
+    public String getTemplateId() {
+        return templateId;
+    }
+    public void setTemplateId(String templateId) {
+        this.templateId = templateId;
+    }
+    public String getVariant() {
+        return variant;
+    }
+    public void setVariant(String variant) {
+        this.variant = variant;
+    }
+    public String getCountryCode() {
+        return countryCode;
+    }
+    public void setCountryCode(String countryCode) {
+        this.countryCode = countryCode;
+    }
+    public Map<String, String> getMetadata() {
+        return metadata;
+    }
+    public void setMetadata(Map<String, String> metadata) {
+        this.metadata = metadata;
+    }
+    public String getTenantId() {
+        return tenantId;
+    }
+    public void setTenantId(String tenantId) {
+        this.tenantId = tenantId;
+    }
+    public String getCategory() {
+        return category;
+    }
+    public void setCategory(String category) {
+        this.category = category;
+    }
+    public String getSenderId() {
+        return senderId;
+    }
+    public void setSenderId(String senderId) {
+        this.senderId = senderId;
+    }
+    public Long getProviderLatencyMs() {
+        return providerLatencyMs;
+    }
+    public void setProviderLatencyMs(Long providerLatencyMs) {
+        this.providerLatencyMs = providerLatencyMs;
+    }
+    public Integer getAttempt() {
+        return attempt;
+    }
+    public void setAttempt(Integer attempt) {
+        this.attempt = attempt;
+    }
+    public String getClickedAt() {
+        return clickedAt;
+    }
+    public void setClickedAt(String clickedAt) {
+        this.clickedAt = clickedAt;
+    }
 }
@@ -0,0 +1,129 @@
+package com.example.demo.model;
+
+import java.math.BigDecimal;
+import java.time.Instant;
+import java.util.List;
+
+/**
+ * What POST /v1/sms/send would do with a request, as worked out by
+ * POST /v1/sms/preview without sending anything.
+ */
+public class SmsPreview {
+    // The message after placeholders are filled in
+    private String renderedMessage;
+    // Placeholders with no param; they would be sent as written
+    private List<String> missingParams;
+    // "GSM-7" or "UCS-2"
+    private String encoding;
+    // Characters as the provider counts them: GSM-7 extension characters count
+    // twice, UCS-2 counts UTF-16 code units
+    private int characters;
+    private int segments;
+    private BigDecimal estimatedCost;
+    private String currency;
+    private String provider;
+    // "send", "blocked", "rejected", "deferred" or "quota_exceeded"
+    private String outcome;
+    // Why the send would not go out now; null for "send"
+    private String reason;
+    // When a deferred send would be released; null otherwise
+    private Instant releaseAt;
+    // The campaign A/B test variant the recipient is assigned; null without one
+    private String variant;
+
+    public String getRenderedMessage() {
+        return renderedMessage;
+    }
+
+    public void setRenderedMessage(String renderedMessage) {
+        this.renderedMessage = renderedMessage;
+    }
+
+    public List<String> getMissingParams() {
+        return missingParams;
+    }
+
+    public void setMissingParams(List<String> missingParams) {
+        this.missingParams = missingParams;
+    }
+
+    public String getEncoding() {
+        return encoding;
+    }
+
+    public void setEncoding(String encoding) {
+        this.encoding = encoding;
+    }
+
+    public int getCharacters() {
+        return characters;
+    }
+
+    public void setCharacters(int characters) {
+        this.characters = characters;
+    }
+
+    public int getSegments() {
+        return segments;
+    }
+
+    public void setSegments(int segments) {
+        this.segments = segments;
+    }
+
+    public BigDecimal getEstimatedCost() {
+        return estimatedCost;
+    }
+
+    public void setEstimatedCost(BigDecimal estimatedCost) {
+        this.estimatedCost = estimatedCost;
+    }
+
+    public String getCurrency() {
+        return currency;
+    }
+
+    public void setCurrency(String currency) {
+        this.currency = currency;
+    }
+
+    public String getProvider() {
+        return provider;
+    }
+
+    public void setProvider(String provider) {
+        this.provider = provider;
+    }
+
+    public String getOutcome() {
+        return outcome;
+    }
+
+    public void setOutcome(String outcome) {
+        this.outcome = outcome;
+    }
+
+    public String getReason() {
+        return reason;
+    }
+
+    public void setReason(String reason) {
+        this.reason = reason;
+    }
+
+    public Instant getReleaseAt() {
+        return releaseAt;
+    }
+
+    public void setReleaseAt(Instant releaseAt) {
+        this.releaseAt = releaseAt;
+    }
+
+    public String getVariant() {
+        return variant;
+    }
+
+    public void setVariant(String variant) {
+        this.variant = variant;
+    }
+}
@@ -0,0 +1,19 @@
+package com.example.demo.model;
+
+import java.util.Map;
+
+/**
+ * A send to preview: the message may be a template whose {{name}}
+ * placeholders are filled in from params.
+ */
+public class SmsPreviewRequest extends SmsRequest {
+    private Map<String, String> params;
+
+    public Map<String, String> getParams() {
+        return params;
+    }
+
+    public void setParams(Map<String, String> params) {
+        this.params = params;
+    }
+}
@@ -1,7 +1,10 @@
 package com.example.demo.model;
 
+import com.fasterxml.jackson.annotation.JsonIgnore;
+import java.util.Map;
 import javax.validation.constraints.NotBlank;
 import javax.validation.constraints.Pattern;
+import javax.validation.constraints.Size;
 
 public class SmsRequest {
     @NotBlank(message = "Phone number is mandatory")
@@ -9,6 +12,25 @@ public class SmsRequest {
     private String phoneNumber;
     @NotBlank(message = "Message is mandatory")
     private String message;
+    // Optional attribution carried through to the stored message
+    private String campaignId;
+    private String templateId;
+    // Set from the campaign's A/B test when it has one; a caller's value is replaced
+    private String variant;
+    @Pattern(regexp = "^[A-Z]{2}$", message = "Country code must be an ISO 3166-1 alpha-2 code")
+    private String countryCode;
+    // Free-form references (order_id, merchant_id, ...) stored with the message and searchable
+    @Size(max = 20, message = "At most 20 metadata entries are allowed")
+    private Map<String, String> metadata;
+    // Optional sender ID; some countries only allow registered ones
+    private String senderId;
+    // "transactional" (default) or "promotional". Only promotional messages are held by
+    // country or tenant quiet hours; transactional ones (OTPs, alerts) always go out
+    @Pattern(regexp = "^(transactional|promotional)$", message = "Category must be transactional or promotional")
+    private String category;
+    // Set from the X-Tenant-ID header, never from the body
+    @JsonIgnore
+    private String tenantId;
 
     public SmsRequest() {
     }
@@ -33,4 +55,73 @@ public class SmsRequest {
     public void setMessage(String message) {
         this.message = message;
     }
-}
\ No newline at end of file
+
+    public String getCampaignId() {
+        return campaignId;
+    }
+
+    public void setCampaignId(String campaignId) {
+        this.campaignId = campaignId;
+    }
+
+    public String getTemplateId() {
+        return templateId;
+    }
+
+    public void setTemplateId(String templateId) {
+        this.templateId = templateId;
+    }
+
+    public String getVariant() {
+        return variant;
+    }
+
+    public void setVariant(String variant) {
+        this.variant = variant;
+    }
+
+    public String getCountryCode() {
+        return countryCode;
+    }
+
+    public void setCountryCode(String countryCode) {
+        this.countryCode = countryCode;
+    }
+
+    public String getTenantId() {
+        return tenantId;
+    }
+
+    public void setTenantId(String tenantId) {
+        this.tenantId = tenantId;
+    }
+
+    public Map<String, String> getMetadata() {
+        return metadata;
+    }
+
+    public void setMetadata(Map<String, String> metadata) {
+        this.metadata = metadata;
+    }
+
+    public String getSenderId() {
+        return senderId;
+    }
+
+    public void setSenderId(String senderId) {
+        this.senderId = senderId;
+    }
+
+    public String getCategory() {
+        return category;
+    }
+
+    public void setCategory(String category) {
+        this.category = category;
+    }
+
+    @JsonIgnore
+    public boolean isPromotional() {
+        return "promotional".equals(category);
+    }
+}
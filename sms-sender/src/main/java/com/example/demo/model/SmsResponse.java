@@ -3,6 +3,9 @@ package com.example.demo.model;
 public class SmsResponse {
     private String result;
     private String messageId;
+    // Outcome status of a synchronous send (wait=true), or "pending" if the wait
+    // timed out; null otherwise
+    private String status;
 
     public SmsResponse() {
     }
@@ -25,4 +28,12 @@ public class SmsResponse {
     public void setMessageId(String messageId) {
         this.messageId = messageId;
     }
+
+    public String getStatus() {
+        return status;
+    }
+
+    public void setStatus(String status) {
+        this.status = status;
+    }
 }
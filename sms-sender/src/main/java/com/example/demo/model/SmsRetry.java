@@ -0,0 +1,77 @@
+package com.example.demo.model;
+
+/**
+ * A send waiting on a retry topic after a transient provider failure, or a
+ * failed send the storage service resends under its tenant's retry policy.
+ */
+public class SmsRetry {
+    // Stable across attempts, so the attempts of one send can be correlated
+    private String id;
+    // Kept separately because SmsRequest never serializes its tenant
+    private String tenantId;
+    private SmsRequest request;
+    // The attempt this retry will make (2 = first retry)
+    private int attempt;
+    private long dueAtEpochMs;
+    // Provider a tenant retry policy asked for; null for the default provider
+    private String provider;
+
+    public SmsRetry() {
+    }
+
+    public SmsRetry(String id, String tenantId, SmsRequest request, int attempt, long dueAtEpochMs) {
+        this.id = id;
+        this.tenantId = tenantId;
+        this.request = request;
+        this.attempt = attempt;
+        this.dueAtEpochMs = dueAtEpochMs;
+    }
+
+    public String getId() {
+        return id;
+    }
+
+    public void setId(String id) {
+        this.id = id;
+    }
+
+    public String getTenantId() {
+        return tenantId;
+    }
+
+    public void setTenantId(String tenantId) {
+        this.tenantId = tenantId;
+    }
+
+    public SmsRequest getRequest() {
+        return request;
+    }
+
+    public void setRequest(SmsRequest request) {
+        this.request = request;
+    }
+
+    public int getAttempt() {
+        return attempt;
+    }
+
+    public void setAttempt(int attempt) {
+        this.attempt = attempt;
+    }
+
+    public long getDueAtEpochMs() {
+        return dueAtEpochMs;
+    }
+
+    public void setDueAtEpochMs(long dueAtEpochMs) {
+        this.dueAtEpochMs = dueAtEpochMs;
+    }
+
+    public String getProvider() {
+        return provider;
+    }
+
+    public void setProvider(String provider) {
+        this.provider = provider;
+    }
+}
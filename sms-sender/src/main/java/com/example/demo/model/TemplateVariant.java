@@ -0,0 +1,63 @@
+package com.example.demo.model;
+
+import javax.validation.constraints.Min;
+import javax.validation.constraints.NotBlank;
+import javax.validation.constraints.Pattern;
+
+/**
+ * One arm of a campaign's template A/B test. Recipients are split between
+ * variants in proportion to their weights.
+ */
+public class TemplateVariant {
+    @NotBlank(message = "Variant name is mandatory")
+    @Pattern(regexp = "^[A-Za-z0-9_-]{1,32}$", message = "Variant name must be 1-32 letters, digits, '_' or '-'")
+    private String name;
+    // Replaces the request's templateId; null keeps it
+    private String templateId;
+    // Replaces the request's message; null keeps it, e.g. for a control arm
+    private String message;
+    @Min(value = 1, message = "Weight must be at least 1")
+    private int weight = 1;
+
+    public TemplateVariant() {
+    }
+
+    public TemplateVariant(String name, String templateId, String message, int weight) {
+        this.name = name;
+        this.templateId = templateId;
+        this.message = message;
+        this.weight = weight;
+    }
+
+    public String getName() {
+        return name;
+    }
+
+    public void setName(String name) {
+        this.name = name;
+    }
+
+    public String getTemplateId() {
+        return templateId;
+    }
+
+    public void setTemplateId(String templateId) {
+        this.templateId = templateId;
+    }
+
+    public String getMessage() {
+        return message;
+    }
+
+    public void setMessage(String message) {
+        this.message = message;
+    }
+
+    public int getWeight() {
+        return weight;
+    }
+
+    public void setWeight(int weight) {
+        this.weight = weight;
+    }
+}
@@ -0,0 +1,52 @@
+package com.example.demo.model;
+
+import java.util.HashMap;
+import java.util.Map;
+import javax.validation.constraints.NotNull;
+
+/**
+ * A user's consent per channel and category: consent.sms.promotional = false
+ * turns promotional SMS off. Channels and categories without an entry are
+ * consented to, so a user who never set any preferences gets everything.
+ */
+public class UserPreferences {
+    public static final String CHANNEL_SMS = "sms";
+
+    @NotNull(message = "Consent is mandatory")
+    private Map<String, Map<String, Boolean>> consent = new HashMap<>();
+    // When the preferences were last changed; null until they are first set
+    private String updatedAt;
+
+    public UserPreferences() {
+    }
+
+    public UserPreferences(Map<String, Map<String, Boolean>> consent, String updatedAt) {
+        this.consent = consent;
+        this.updatedAt = updatedAt;
+    }
+
+    /**
+     * Returns whether the user consents to messages of the category on the channel.
+     */
+    public boolean allows(String channel, String category) {
+        Map<String, Boolean> categories = consent == null ? null : consent.get(channel);
+        Boolean allowed = categories == null ? null : categories.get(category);
+        return allowed == null || allowed;
+    }
+
+    public Map<String, Map<String, Boolean>> getConsent() {
+        return consent;
+    }
+
+    public void setConsent(Map<String, Map<String, Boolean>> consent) {
+        this.consent = consent;
+    }
+
+    public String getUpdatedAt() {
+        return updatedAt;
+    }
+
+    public void setUpdatedAt(String updatedAt) {
+        this.updatedAt = updatedAt;
+    }
+}
@@ -0,0 +1,217 @@
+package com.example.demo.service;
+
+import com.example.demo.config.AbuseProperties;
+import com.example.demo.model.BlockedNumber;
+import com.example.demo.model.BlockedPrefix;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Detects suspicious destinations before a send reaches the provider: numbers
+ * in blocked prefix ranges (premium-rate, known fraud) and numbers receiving an
+ * unusual volume of promotional messages. Volume offenders are blacklisted for
+ * blockDuration and recorded in a Redis hash so admins can review and unblock
+ * them sooner.
+ */
+@Service
+public class AbuseDetectionService {
+    private static final String PREFIXES_KEY = "blocked_prefixes";
+    // Configured prefixes seeded so far, so ones an admin deleted stay deleted
+    private static final String SEEDED_PREFIXES_KEY = "blocked_prefixes:seeded";
+    private static final String BLOCKED_KEY = "abuse_blocked";
+    private static final String VOLUME_PREFIX = "abuse:volume:";
+    // Prefixes are checked on every send; admin changes reach other replicas within this long
+    private static final long PREFIX_CACHE_TTL_MS = 30000;
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final BlacklistCache blacklist;
+    private final AbuseProperties properties;
+    private final Clock clock;
+
+    private volatile List<BlockedPrefix> cachedPrefixes;
+    private volatile long prefixesLoadedAtMs;
+
+    @Autowired
+    public AbuseDetectionService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            BlacklistCache blacklist, AbuseProperties properties) {
+        this(redisTemplate, objectMapper, blacklist, properties, Clock.systemUTC());
+    }
+
+    public AbuseDetectionService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            BlacklistCache blacklist, AbuseProperties properties, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.blacklist = blacklist;
+        this.properties = properties;
+        this.clock = clock;
+    }
+
+    /**
+     * Returns null when the destination looks legitimate, otherwise why the
+     * send must be blocked. Counts nothing: see recordSend.
+     */
+    public String check(String phoneNumber) {
+        BlockedPrefix prefix = matchingPrefix(phoneNumber);
+        if (prefix != null) {
+            return "Destination " + prefix.getPrefix() + " is blocked (" + prefix.getCategory() + ")";
+        }
+        return null;
+    }
+
+    /**
+     * Counts a promotional send about to go out towards the volume threshold.
+     * Returns null while the number is within it, otherwise blocks the number
+     * and returns why.
+     */
+    public String recordSend(String phoneNumber) {
+        int threshold = properties.getVolumeThreshold();
+        if (threshold <= 0) {
+            return null;
+        }
+        Duration window = properties.getVolumeWindow();
+        long windowIndex = clock.millis() / window.toMillis();
+        String key = VOLUME_PREFIX + phoneNumber + ":" + windowIndex;
+        Long sends = redisTemplate.opsForValue().increment(key);
+        if (sends != null && sends == 1L) {
+            redisTemplate.expire(key, window);
+        }
+        if (sends == null || sends <= threshold) {
+            return null;
+        }
+        String reason = "More than " + threshold + " messages within " + window.toMinutes() + " minutes";
+        block(phoneNumber, reason);
+        return "Destination blocked: " + reason;
+    }
+
+    /**
+     * Like recordSend, but neither counts the send nor blocks the number:
+     * returns why a promotional send now would be blocked, or null.
+     */
+    public String peekSend(String phoneNumber) {
+        int threshold = properties.getVolumeThreshold();
+        if (threshold <= 0) {
+            return null;
+        }
+        Duration window = properties.getVolumeWindow();
+        long windowIndex = clock.millis() / window.toMillis();
+        String sends = redisTemplate.opsForValue().get(VOLUME_PREFIX + phoneNumber + ":" + windowIndex);
+        if (sends == null || Long.parseLong(sends) + 1 <= threshold) {
+            return null;
+        }
+        return "Destination blocked: More than " + threshold + " messages within " + window.toMinutes() + " minutes";
+    }
+
+    /**
+     * Lists the numbers currently blocked by abuse detection. Records of
+     * blocks that have expired are removed on the way.
+     */
+    public List<BlockedNumber> listBlocked() {
+        List<BlockedNumber> blocked = new ArrayList<>();
+        long now = clock.millis();
+        for (Object json : redisTemplate.opsForHash().values(BLOCKED_KEY)) {
+            BlockedNumber number = fromJson(json.toString());
+            if (number.getExpiresAtEpochMs() > 0 && number.getExpiresAtEpochMs() <= now) {
+                redisTemplate.opsForHash().delete(BLOCKED_KEY, number.getPhoneNumber());
+                continue;
+            }
+            blocked.add(number);
+        }
+        return blocked;
+    }
+
+    /**
+     * Lifts an automatic block. Returns false if the number wasn't blocked by
+     * abuse detection.
+     */
+    public boolean unblock(String phoneNumber) {
+        Long removed = redisTemplate.opsForHash().delete(BLOCKED_KEY, phoneNumber);
+        if (removed == null || removed == 0) {
+            return false;
+        }
+        blacklist.removeFromBlacklist(phoneNumber);
+        // Start counting afresh, or the next send would block it again
+        long windowIndex = clock.millis() / properties.getVolumeWindow().toMillis();
+        redisTemplate.delete(VOLUME_PREFIX + phoneNumber + ":" + windowIndex);
+        return true;
+    }
+
+    public List<BlockedPrefix> listPrefixes() {
+        List<BlockedPrefix> prefixes = new ArrayList<>();
+        for (Map.Entry<Object, Object> entry : redisTemplate.opsForHash().entries(PREFIXES_KEY).entrySet()) {
+            prefixes.add(new BlockedPrefix(entry.getKey().toString(), entry.getValue().toString()));
+        }
+        return prefixes;
+    }
+
+    public BlockedPrefix savePrefix(String prefix, String category) {
+        redisTemplate.opsForHash().put(PREFIXES_KEY, prefix, category);
+        cachedPrefixes = null;
+        return new BlockedPrefix(prefix, category);
+    }
+
+    /**
+     * Adds a configured prefix the first time it is configured. Prefixes seeded
+     * before are left alone, so admin changes and deletions survive restarts.
+     */
+    public void seedPrefix(String prefix, String category) {
+        Long added = redisTemplate.opsForSet().add(SEEDED_PREFIXES_KEY, prefix);
+        if (added != null && added > 0) {
+            redisTemplate.opsForHash().putIfAbsent(PREFIXES_KEY, prefix, category);
+            cachedPrefixes = null;
+        }
+    }
+
+    public boolean deletePrefix(String prefix) {
+        Long removed = redisTemplate.opsForHash().delete(PREFIXES_KEY, prefix);
+        cachedPrefixes = null;
+        return removed != null && removed > 0;
+    }
+
+    // Longest blocked prefix of the number, or null
+    private BlockedPrefix matchingPrefix(String phoneNumber) {
+        List<BlockedPrefix> prefixes = cachedPrefixes;
+        if (prefixes == null || clock.millis() - prefixesLoadedAtMs >= PREFIX_CACHE_TTL_MS) {
+            prefixes = listPrefixes();
+            prefixesLoadedAtMs = clock.millis();
+            cachedPrefixes = prefixes;
+        }
+        BlockedPrefix longest = null;
+        for (BlockedPrefix prefix : prefixes) {
+            if (phoneNumber.startsWith(prefix.getPrefix())
+                    && (longest == null || prefix.getPrefix().length() > longest.getPrefix().length())) {
+                longest = prefix;
+            }
+        }
+        return longest;
+    }
+
+    private void block(String phoneNumber, String reason) {
+        Duration duration = properties.getBlockDuration();
+        blacklist.addToBlacklist(phoneNumber, duration);
+        BlockedNumber blocked = new BlockedNumber(phoneNumber, reason, clock.millis());
+        blocked.setExpiresAtEpochMs(clock.millis() + duration.toMillis());
+        try {
+            redisTemplate.opsForHash().put(BLOCKED_KEY, phoneNumber, objectMapper.writeValueAsString(blocked));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode blocked number", e);
+        }
+        System.out.println("Blocked suspicious destination " + phoneNumber + ": " + reason);
+    }
+
+    private BlockedNumber fromJson(String json) {
+        try {
+            return objectMapper.readValue(json, BlockedNumber.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode blocked number", e);
+        }
+    }
+}
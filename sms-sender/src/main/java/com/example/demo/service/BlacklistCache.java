@@ -1,5 +1,6 @@
 package com.example.demo.service;
 
+import java.time.Duration;
 import org.springframework.beans.factory.annotation.Autowired;
 import org.springframework.data.redis.core.StringRedisTemplate;
 import org.springframework.stereotype.Service;
@@ -22,6 +23,13 @@ public class BlacklistCache {
         redisTemplate.opsForValue().set(BLACKLIST_PREFIX + phoneNumber, "1");
     }
 
+    /**
+     * Blacklists a number until ttl has passed.
+     */
+    public void addToBlacklist(String phoneNumber, Duration ttl) {
+        redisTemplate.opsForValue().set(BLACKLIST_PREFIX + phoneNumber, "1", ttl);
+    }
+
     public void removeFromBlacklist(String phoneNumber) {
         redisTemplate.delete(BLACKLIST_PREFIX + phoneNumber);
     }
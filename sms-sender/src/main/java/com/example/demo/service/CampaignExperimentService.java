@@ -0,0 +1,129 @@
+package com.example.demo.service;
+
+import com.example.demo.model.CampaignExperiment;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.TemplateVariant;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.nio.charset.StandardCharsets;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import java.util.HashSet;
+import java.util.Set;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Template A/B tests per campaign, kept in Redis. Each recipient of a
+ * campaign is assigned a variant from a hash of the campaign and phone
+ * number, so every replica, retry and later send of the campaign to them
+ * picks the same one. Changing the variants or their weights reshuffles
+ * recipients.
+ */
+@Service
+public class CampaignExperimentService {
+    private static final String EXPERIMENTS_KEY = "campaign_experiments";
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+
+    @Autowired
+    public CampaignExperimentService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+    }
+
+    public CampaignExperiment getExperiment(String campaignId) {
+        Object json = redisTemplate.opsForHash().get(EXPERIMENTS_KEY, campaignId);
+        if (json == null) {
+            return null;
+        }
+        try {
+            return objectMapper.readValue(json.toString(), CampaignExperiment.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode campaign experiment", e);
+        }
+    }
+
+    /**
+     * Stores a campaign's variants, replacing any before. Throws
+     * IllegalArgumentException if two variants share a name.
+     */
+    public CampaignExperiment saveExperiment(String campaignId, CampaignExperiment experiment) {
+        Set<String> names = new HashSet<>();
+        for (TemplateVariant variant : experiment.getVariants()) {
+            if (!names.add(variant.getName())) {
+                throw new IllegalArgumentException("Duplicate variant " + variant.getName());
+            }
+        }
+        try {
+            redisTemplate.opsForHash().put(EXPERIMENTS_KEY, campaignId, objectMapper.writeValueAsString(experiment));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode campaign experiment", e);
+        }
+        return experiment;
+    }
+
+    public boolean deleteExperiment(String campaignId) {
+        Long removed = redisTemplate.opsForHash().delete(EXPERIMENTS_KEY, campaignId);
+        return removed != null && removed > 0;
+    }
+
+    /**
+     * Applies the variant assigned to the request's recipient, if its
+     * campaign has an A/B test: the request takes the variant's name and its
+     * template and message where set. Returns the variant, or null.
+     */
+    public TemplateVariant assign(SmsRequest request) {
+        if (request.getCampaignId() == null) {
+            return null;
+        }
+        CampaignExperiment experiment = getExperiment(request.getCampaignId());
+        if (experiment == null || experiment.getVariants() == null || experiment.getVariants().isEmpty()) {
+            return null;
+        }
+        TemplateVariant variant = variantFor(request.getCampaignId(), request.getPhoneNumber(), experiment);
+        request.setVariant(variant.getName());
+        if (variant.getTemplateId() != null) {
+            request.setTemplateId(variant.getTemplateId());
+        }
+        if (variant.getMessage() != null) {
+            request.setMessage(variant.getMessage());
+        }
+        return variant;
+    }
+
+    /**
+     * Picks the recipient's variant: their bucket in [0, total weight) falls
+     * in the range of exactly one variant, in list order.
+     */
+    public static TemplateVariant variantFor(String campaignId, String phoneNumber, CampaignExperiment experiment) {
+        long total = 0;
+        for (TemplateVariant variant : experiment.getVariants()) {
+            total += variant.getWeight();
+        }
+        long bucket = Math.floorMod(bucketHash(campaignId + ":" + phoneNumber), total);
+        for (TemplateVariant variant : experiment.getVariants()) {
+            bucket -= variant.getWeight();
+            if (bucket < 0) {
+                return variant;
+            }
+        }
+        return experiment.getVariants().get(experiment.getVariants().size() - 1);
+    }
+
+    // First 8 bytes of the key's SHA-256, so buckets are uniform and stable across JVMs
+    private static long bucketHash(String key) {
+        try {
+            byte[] digest = MessageDigest.getInstance("SHA-256").digest(key.getBytes(StandardCharsets.UTF_8));
+            long hash = 0;
+            for (int i = 0; i < 8; i++) {
+                hash = (hash << 8) | (digest[i] & 0xff);
+            }
+            return hash;
+        } catch (NoSuchAlgorithmException e) {
+            throw new IllegalStateException("SHA-256 is not available", e);
+        }
+    }
+}
@@ -0,0 +1,41 @@
+package com.example.demo.service;
+
+import java.time.Instant;
+
+/**
+ * Outcome of a compliance check that did not allow the send as-is.
+ */
+public class ComplianceDecision {
+    public enum Action { REJECT, DEFER }
+
+    private final Action action;
+    private final String reason;
+    // When a deferred message may be sent; null for rejections
+    private final Instant releaseAt;
+
+    private ComplianceDecision(Action action, String reason, Instant releaseAt) {
+        this.action = action;
+        this.reason = reason;
+        this.releaseAt = releaseAt;
+    }
+
+    public static ComplianceDecision reject(String reason) {
+        return new ComplianceDecision(Action.REJECT, reason, null);
+    }
+
+    public static ComplianceDecision defer(String reason, Instant releaseAt) {
+        return new ComplianceDecision(Action.DEFER, reason, releaseAt);
+    }
+
+    public Action getAction() {
+        return action;
+    }
+
+    public String getReason() {
+        return reason;
+    }
+
+    public Instant getReleaseAt() {
+        return releaseAt;
+    }
+}
@@ -0,0 +1,125 @@
+package com.example.demo.service;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.SmsRequest;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.LocalTime;
+import java.time.ZoneId;
+import java.time.ZonedDateTime;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Stores per-country compliance rules in Redis and evaluates sends against them.
+ * Rules only apply to requests that carry a countryCode.
+ */
+@Service
+public class CountryRuleService {
+    private static final String RULES_KEY = "country_rules";
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final Clock clock;
+
+    @Autowired
+    public CountryRuleService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper) {
+        this(redisTemplate, objectMapper, Clock.systemUTC());
+    }
+
+    public CountryRuleService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.clock = clock;
+    }
+
+    public CountryRule getRule(String countryCode) {
+        Object json = redisTemplate.opsForHash().get(RULES_KEY, countryCode);
+        return json == null ? null : fromJson(json.toString());
+    }
+
+    public List<CountryRule> listRules() {
+        List<CountryRule> rules = new ArrayList<>();
+        for (Map.Entry<Object, Object> entry : redisTemplate.opsForHash().entries(RULES_KEY).entrySet()) {
+            rules.add(fromJson(entry.getValue().toString()));
+        }
+        return rules;
+    }
+
+    public CountryRule saveRule(String countryCode, CountryRule rule) {
+        ZoneId.of(rule.getTimezone()); // throws DateTimeException for unknown zones
+        rule.setCountryCode(countryCode);
+        try {
+            redisTemplate.opsForHash().put(RULES_KEY, countryCode, objectMapper.writeValueAsString(rule));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode country rule", e);
+        }
+        return rule;
+    }
+
+    public boolean deleteRule(String countryCode) {
+        Long removed = redisTemplate.opsForHash().delete(RULES_KEY, countryCode);
+        return removed != null && removed > 0;
+    }
+
+    /**
+     * Returns null when the request may be sent now, otherwise whether to
+     * reject or defer it and why.
+     */
+    public ComplianceDecision evaluate(SmsRequest request) {
+        if (request.getCountryCode() == null) {
+            return null;
+        }
+        CountryRule rule = getRule(request.getCountryCode());
+        if (rule == null) {
+            return null;
+        }
+
+        List<String> allowed = rule.getAllowedSenderIds();
+        if (allowed != null && !allowed.isEmpty() && !allowed.contains(request.getSenderId())) {
+            return ComplianceDecision.reject("Sender ID not allowed for " + rule.getCountryCode());
+        }
+        if (!request.isPromotional()) {
+            return null;
+        }
+        if (rule.isPromotionalBanned()) {
+            return ComplianceDecision.reject("Promotional messages are not allowed for " + rule.getCountryCode());
+        }
+
+        Instant quietEnd = quietHoursEnd(rule, clock.instant());
+        if (quietEnd == null) {
+            return null;
+        }
+        String reason = "Quiet hours in effect for " + rule.getCountryCode();
+        if ("reject".equals(rule.getQuietHoursAction())) {
+            return ComplianceDecision.reject(reason);
+        }
+        return ComplianceDecision.defer(reason, quietEnd);
+    }
+
+    /**
+     * Returns when the quiet-hours window containing now ends, or null if now
+     * is outside quiet hours (or the rule has none).
+     */
+    static Instant quietHoursEnd(CountryRule rule, Instant now) {
+        if (rule.getQuietHoursStart() == null || rule.getQuietHoursEnd() == null) {
+            return null;
+        }
+        return QuietHours.endOfWindow(LocalTime.parse(rule.getQuietHoursStart()),
+                LocalTime.parse(rule.getQuietHoursEnd()), ZonedDateTime.ofInstant(now, ZoneId.of(rule.getTimezone())));
+    }
+
+    private CountryRule fromJson(String json) {
+        try {
+            return objectMapper.readValue(json, CountryRule.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode country rule", e);
+        }
+    }
+}
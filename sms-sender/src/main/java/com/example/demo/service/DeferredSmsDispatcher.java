@@ -0,0 +1,61 @@
+package com.example.demo.service;
+
+import com.example.demo.model.DeferredSms;
+import java.time.Duration;
+import java.time.Instant;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.scheduling.annotation.Scheduled;
+import org.springframework.stereotype.Component;
+
+/**
+ * Periodically sends deferred messages whose release time has passed. They go
+ * through the normal send path again, so rules and quotas are re-checked.
+ * Releases that fail are retried with exponential backoff, then dead-lettered.
+ */
+@Component
+public class DeferredSmsDispatcher {
+    private static final int BATCH_SIZE = 100;
+    private static final int MAX_ATTEMPTS = 5;
+    private static final Duration INITIAL_BACKOFF = Duration.ofMinutes(1);
+
+    private final DeferredSmsQueue queue;
+    private final SmsService smsService;
+
+    @Autowired
+    public DeferredSmsDispatcher(DeferredSmsQueue queue, SmsService smsService) {
+        this.queue = queue;
+        this.smsService = smsService;
+    }
+
+    @Scheduled(fixedDelayString = "${sms.deferred.poll-interval-ms:10000}")
+    public void releaseDue() {
+        for (DeferredSms deferred : queue.claimDue(Instant.now(), BATCH_SIZE)) {
+            try {
+                String result = smsService.sendSms(deferred.getRequest());
+                System.out.println("Released deferred SMS " + deferred.getId() + ": " + result);
+            } catch (QuotaExceededException e) {
+                // Try again once the quota window resets
+                queue.defer(deferred.getRequest(), Instant.now().plusSeconds(e.getRetryAfterSeconds()));
+            } catch (QuotaUnavailableException e) {
+                // Try again once Redis is back
+                queue.defer(deferred.getRequest(), Instant.now().plusSeconds(e.getRetryAfterSeconds()));
+            } catch (Exception e) {
+                retryOrDeadLetter(deferred, e);
+            }
+        }
+    }
+
+    // Backs off 1m, 2m, 4m, ... between attempts
+    private void retryOrDeadLetter(DeferredSms deferred, Exception e) {
+        if (deferred.getAttempts() + 1 >= MAX_ATTEMPTS) {
+            System.err.println("Dead-lettering deferred SMS " + deferred.getId() + " after "
+                    + MAX_ATTEMPTS + " attempts: " + e.getMessage());
+            queue.deadLetter(deferred);
+            return;
+        }
+        Duration backoff = INITIAL_BACKOFF.multipliedBy(1L << deferred.getAttempts());
+        System.err.println("Failed to release deferred SMS " + deferred.getId() + ", retrying in "
+                + backoff.toMinutes() + "m: " + e.getMessage());
+        queue.retry(deferred, Instant.now().plus(backoff));
+    }
+}
@@ -0,0 +1,96 @@
+package com.example.demo.service;
+
+import com.example.demo.model.DeferredSms;
+import com.example.demo.model.SmsRequest;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Instant;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Set;
+import java.util.UUID;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Redis sorted set of deferred sends, scored by release time. Claiming removes
+ * the entry first, so with several replicas each entry is released once.
+ * Entries that keep failing to release end up in a dead-letter list.
+ */
+@Service
+public class DeferredSmsQueue {
+    private static final String QUEUE_KEY = "deferred_sms";
+    private static final String DEAD_LETTER_KEY = "deferred_sms:dead";
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+
+    @Autowired
+    public DeferredSmsQueue(StringRedisTemplate redisTemplate, ObjectMapper objectMapper) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+    }
+
+    public DeferredSms defer(SmsRequest request, Instant releaseAt) {
+        DeferredSms deferred = new DeferredSms(UUID.randomUUID().toString(), request.getTenantId(), request, releaseAt.toEpochMilli());
+        redisTemplate.opsForZSet().add(QUEUE_KEY, toJson(deferred), deferred.getReleaseAtEpochMs());
+        return deferred;
+    }
+
+    /**
+     * Puts a claimed entry whose release failed back in the queue, keeping its
+     * id and counting the failed attempt.
+     */
+    public void retry(DeferredSms deferred, Instant releaseAt) {
+        deferred.setAttempts(deferred.getAttempts() + 1);
+        deferred.setReleaseAtEpochMs(releaseAt.toEpochMilli());
+        redisTemplate.opsForZSet().add(QUEUE_KEY, toJson(deferred), deferred.getReleaseAtEpochMs());
+    }
+
+    /**
+     * Moves a claimed entry that cannot be released to the dead-letter list,
+     * where it is kept for inspection instead of being dropped.
+     */
+    public void deadLetter(DeferredSms deferred) {
+        redisTemplate.opsForList().rightPush(DEAD_LETTER_KEY, toJson(deferred));
+    }
+
+    /**
+     * Removes and returns up to limit entries due at or before now.
+     */
+    public List<DeferredSms> claimDue(Instant now, int limit) {
+        List<DeferredSms> claimed = new ArrayList<>();
+        Set<String> due = redisTemplate.opsForZSet().rangeByScore(QUEUE_KEY, 0, now.toEpochMilli(), 0, limit);
+        if (due == null) {
+            return claimed;
+        }
+        for (String json : due) {
+            Long removed = redisTemplate.opsForZSet().remove(QUEUE_KEY, json);
+            if (removed == null || removed == 0) {
+                continue; // another replica claimed it
+            }
+            try {
+                DeferredSms deferred = objectMapper.readValue(json, DeferredSms.class);
+                deferred.getRequest().setTenantId(deferred.getTenantId());
+                claimed.add(deferred);
+            } catch (JsonProcessingException e) {
+                System.err.println("Dropping undecodable deferred SMS: " + e.getMessage());
+            }
+        }
+        return claimed;
+    }
+
+    public long size() {
+        Long size = redisTemplate.opsForZSet().zCard(QUEUE_KEY);
+        return size == null ? 0 : size;
+    }
+
+    private String toJson(DeferredSms deferred) {
+        try {
+            return objectMapper.writeValueAsString(deferred);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode deferred SMS", e);
+        }
+    }
+}
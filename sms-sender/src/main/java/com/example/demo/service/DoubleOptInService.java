@@ -0,0 +1,186 @@
+package com.example.demo.service;
+
+import com.example.demo.config.OptInProperties;
+import com.example.demo.model.InboundMessage;
+import com.example.demo.model.OptIn;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.UserPreferences;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.security.SecureRandom;
+import java.time.Clock;
+import java.time.Instant;
+import java.util.Locale;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.kafka.KafkaException;
+import org.springframework.stereotype.Service;
+
+/**
+ * Double opt-in, per phone number and category. Requesting one sends a
+ * confirmation SMS with a code and leaves it PENDING_CONFIRMATION; the
+ * recipient's reply with the code, reported by the inbound webhook, makes it
+ * OPTED_IN and turns the category on in their preferences. Codes are kept
+ * apart from the opt-ins, under a TTL, so they never show up in the API.
+ */
+@Service
+public class DoubleOptInService {
+    private static final String OPT_INS_KEY = "opt_ins";
+    private static final String CODE_PREFIX = "opt_in_code:";
+    private static final String[] CATEGORIES = {"promotional", "transactional"};
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final SmsService smsService;
+    private final UserPreferenceService preferences;
+    private final OptInProperties properties;
+    private final Clock clock;
+    private final SecureRandom random = new SecureRandom();
+
+    @Autowired
+    public DoubleOptInService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, SmsService smsService,
+            UserPreferenceService preferences, OptInProperties properties) {
+        this(redisTemplate, objectMapper, smsService, preferences, properties, Clock.systemUTC());
+    }
+
+    public DoubleOptInService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, SmsService smsService,
+            UserPreferenceService preferences, OptInProperties properties, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.smsService = smsService;
+        this.preferences = preferences;
+        this.properties = properties;
+        this.clock = clock;
+    }
+
+    public OptIn getOptIn(String phoneNumber, String category) {
+        Object json = redisTemplate.opsForHash().get(OPT_INS_KEY, field(phoneNumber, category));
+        if (json == null) {
+            return null;
+        }
+        try {
+            return objectMapper.readValue(json.toString(), OptIn.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode opt-in", e);
+        }
+    }
+
+    /**
+     * Starts a double opt-in and sends its confirmation SMS, replacing any
+     * pending code. Returns the opt-in as is if the recipient already opted in.
+     * Throws IllegalArgumentException for unknown categories, IllegalStateException
+     * if the confirmation SMS was not sent, and QuotaExceededException or
+     * QuotaUnavailableException.
+     */
+    public OptIn requestOptIn(String phoneNumber, String category, String tenantId) {
+        if (!isCategory(category)) {
+            throw new IllegalArgumentException("Category must be transactional or promotional: " + category);
+        }
+        OptIn existing = getOptIn(phoneNumber, category);
+        if (existing != null && OptIn.OPTED_IN.equals(existing.getStatus())) {
+            return existing;
+        }
+        String code = newCode();
+        Instant now = clock.instant();
+        OptIn optIn = new OptIn(phoneNumber, category, OptIn.PENDING_CONFIRMATION);
+        optIn.setRequestedAt(now.toString());
+        optIn.setExpiresAt(now.plus(properties.getCodeTtl()).toString());
+        // Stored before the SMS goes out, so a quick reply finds it
+        String codeKey = CODE_PREFIX + field(phoneNumber, category);
+        redisTemplate.opsForValue().set(codeKey, code, properties.getCodeTtl());
+        store(optIn);
+
+        // The confirmation answers the recipient's own request, so it isn't promotional
+        SmsRequest request = new SmsRequest();
+        request.setPhoneNumber(phoneNumber);
+        request.setMessage("Reply " + properties.getKeyword() + " " + code + " to confirm you want " + category
+                + " messages from us.");
+        request.setCategory("transactional");
+        request.setTenantId(tenantId);
+        String result;
+        try {
+            result = smsService.sendSms(request);
+        } catch (RuntimeException e) {
+            rollBack(codeKey, existing, phoneNumber, category);
+            throw e;
+        }
+        if (result.startsWith("Failed")) {
+            rollBack(codeKey, existing, phoneNumber, category);
+            throw new IllegalStateException(result);
+        }
+        return optIn;
+    }
+
+    // Puts back the opt-in as it was before a request whose SMS was not sent
+    private void rollBack(String codeKey, OptIn previous, String phoneNumber, String category) {
+        redisTemplate.delete(codeKey);
+        if (previous == null) {
+            redisTemplate.opsForHash().delete(OPT_INS_KEY, field(phoneNumber, category));
+        } else {
+            store(previous);
+        }
+    }
+
+    /**
+     * Confirms the sender's pending opt-in whose code the reply carries, as
+     * "YES 123456" or just "123456". Returns the confirmed opt-in, or null if
+     * the reply confirms nothing.
+     */
+    public OptIn handleReply(InboundMessage reply) {
+        String[] words = reply.getMessage().trim().split("\\s+");
+        String code = words[words.length - 1];
+        if (words.length > 2 || (words.length == 2 && !words[0].toUpperCase(Locale.ROOT).equals(properties.getKeyword().toUpperCase(Locale.ROOT)))) {
+            return null;
+        }
+        for (String category : CATEGORIES) {
+            String codeKey = CODE_PREFIX + field(reply.getPhoneNumber(), category);
+            if (!code.equals(redisTemplate.opsForValue().get(codeKey))) {
+                continue;
+            }
+            OptIn optIn = getOptIn(reply.getPhoneNumber(), category);
+            if (optIn == null) {
+                continue;
+            }
+            Instant confirmedAt = reply.getReceivedAt() != null ? reply.getReceivedAt() : clock.instant();
+            optIn.setStatus(OptIn.OPTED_IN);
+            optIn.setConfirmedAt(confirmedAt.toString());
+            optIn.setExpiresAt(null);
+            store(optIn);
+            redisTemplate.delete(codeKey);
+            try {
+                preferences.setConsent(reply.getPhoneNumber(), UserPreferences.CHANNEL_SMS, category, true);
+            } catch (KafkaException e) {
+                // The consent is stored; only downstream systems missed the change
+                System.err.println("Failed to publish preference change to Kafka: " + e.getMessage());
+            }
+            return optIn;
+        }
+        return null;
+    }
+
+    private static boolean isCategory(String category) {
+        for (String known : CATEGORIES) {
+            if (known.equals(category)) {
+                return true;
+            }
+        }
+        return false;
+    }
+
+    private String newCode() {
+        return String.format("%06d", random.nextInt(1000000));
+    }
+
+    private static String field(String phoneNumber, String category) {
+        return phoneNumber + ":" + category;
+    }
+
+    private void store(OptIn optIn) {
+        try {
+            redisTemplate.opsForHash().put(OPT_INS_KEY, field(optIn.getPhoneNumber(), optIn.getCategory()),
+                    objectMapper.writeValueAsString(optIn));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode opt-in", e);
+        }
+    }
+}
@@ -0,0 +1,114 @@
+package com.example.demo.service;
+
+import com.example.demo.config.IdempotencyProperties;
+import com.example.demo.model.IdempotencyRecord;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.SmsResponse;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import com.fasterxml.jackson.databind.SerializationFeature;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Idempotency keys for the send API, kept in Redis per tenant. The first
+ * request with a key claims it; repeats get the original response once the
+ * send has finished, and a repeat with a different body is a conflict. Keys
+ * are shared by all replicas, so a client retry landing elsewhere is caught too.
+ */
+@Service
+public class IdempotencyService {
+    // Keys are client-chosen; anything longer is rejected rather than stored
+    public static final int MAX_KEY_LENGTH = 255;
+    private static final String KEY_PREFIX = "idempotency:";
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final IdempotencyProperties properties;
+
+    @Autowired
+    public IdempotencyService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, IdempotencyProperties properties) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.properties = properties;
+    }
+
+    /**
+     * Claims key for a request with the given fingerprint. Returns null if the
+     * caller now owns the key and must complete or release it, otherwise the
+     * record already stored under it.
+     */
+    public IdempotencyRecord claim(String tenantId, String key, String fingerprint) {
+        String redisKey = redisKey(tenantId, key);
+        String claimed = encode(new IdempotencyRecord(fingerprint, 0, null));
+        // Twice, in case the existing record expires between the two calls
+        for (int attempt = 0; attempt < 2; attempt++) {
+            Boolean set = redisTemplate.opsForValue().setIfAbsent(redisKey, claimed, properties.getInProgressTimeout());
+            if (Boolean.TRUE.equals(set)) {
+                return null;
+            }
+            String existing = redisTemplate.opsForValue().get(redisKey);
+            if (existing != null) {
+                return decode(existing);
+            }
+        }
+        return new IdempotencyRecord(fingerprint, 0, null);
+    }
+
+    /**
+     * Stores the response of a claimed key's send for replay.
+     */
+    public void complete(String tenantId, String key, String fingerprint, int status, SmsResponse response) {
+        redisTemplate.opsForValue().set(redisKey(tenantId, key),
+                encode(new IdempotencyRecord(fingerprint, status, response)), properties.getTtl());
+    }
+
+    /**
+     * Gives up a claimed key whose send didn't go ahead, so a retry can.
+     */
+    public void release(String tenantId, String key) {
+        redisTemplate.delete(redisKey(tenantId, key));
+    }
+
+    /**
+     * Hex SHA-256 of the request as JSON, with map entries sorted so equal
+     * metadata always gives the same fingerprint. The tenant is never part
+     * of the JSON; keys are scoped by tenant instead.
+     */
+    public String fingerprint(SmsRequest request) {
+        try {
+            byte[] json = objectMapper.writer().with(SerializationFeature.ORDER_MAP_ENTRIES_BY_KEYS).writeValueAsBytes(request);
+            byte[] digest = MessageDigest.getInstance("SHA-256").digest(json);
+            StringBuilder hex = new StringBuilder(digest.length * 2);
+            for (byte b : digest) {
+                hex.append(String.format("%02x", b));
+            }
+            return hex.toString();
+        } catch (JsonProcessingException | NoSuchAlgorithmException e) {
+            throw new IllegalStateException("Failed to fingerprint SMS request", e);
+        }
+    }
+
+    private static String redisKey(String tenantId, String key) {
+        return KEY_PREFIX + tenantId + ":" + key;
+    }
+
+    private String encode(IdempotencyRecord record) {
+        try {
+            return objectMapper.writeValueAsString(record);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode idempotency record", e);
+        }
+    }
+
+    private IdempotencyRecord decode(String json) {
+        try {
+            return objectMapper.readValue(json, IdempotencyRecord.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode idempotency record", e);
+        }
+    }
+}
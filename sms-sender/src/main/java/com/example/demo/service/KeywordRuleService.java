@@ -0,0 +1,175 @@
+package com.example.demo.service;
+
+import com.example.demo.model.InboundMessage;
+import com.example.demo.model.KeywordRule;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.UserPreferences;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.util.ArrayList;
+import java.util.Comparator;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.kafka.KafkaException;
+import org.springframework.stereotype.Service;
+
+/**
+ * Keyword auto-responder for inbound messages. STOP, HELP and START are
+ * built in; tenants can override them and add their own keywords, kept in
+ * Redis per tenant. An inbound message whose first word is a keyword gets the
+ * rule's reply, and opt_out/opt_in rules update the sender's preferences, so
+ * the send path honours them.
+ */
+@Service
+public class KeywordRuleService {
+    private static final String RULES_PREFIX = "keyword_rules:";
+    private static final String KEYWORD = "^[A-Z0-9]{1,20}$";
+    private static final String[] CATEGORIES = {"promotional", "transactional"};
+    private static final Map<String, KeywordRule> BUILT_IN = new LinkedHashMap<>();
+
+    static {
+        BUILT_IN.put("STOP", new KeywordRule("STOP",
+                "You are unsubscribed and will receive no more messages. Reply START to resubscribe.",
+                KeywordRule.ACTION_OPT_OUT));
+        BUILT_IN.put("HELP", new KeywordRule("HELP",
+                "Reply STOP to unsubscribe or START to resubscribe. Msg&data rates may apply.",
+                KeywordRule.ACTION_NONE));
+        BUILT_IN.put("START", new KeywordRule("START",
+                "You are resubscribed. Reply STOP to unsubscribe.",
+                KeywordRule.ACTION_OPT_IN));
+    }
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final SmsService smsService;
+    private final UserPreferenceService preferences;
+
+    @Autowired
+    public KeywordRuleService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, SmsService smsService,
+            UserPreferenceService preferences) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.smsService = smsService;
+        this.preferences = preferences;
+    }
+
+    /**
+     * Returns the tenant's rules: its own, and the built-in ones it didn't override.
+     */
+    public List<KeywordRule> listRules(String tenantId) {
+        Map<String, KeywordRule> rules = new LinkedHashMap<>(BUILT_IN);
+        for (Map.Entry<Object, Object> entry : redisTemplate.opsForHash().entries(RULES_PREFIX + tenantId).entrySet()) {
+            rules.put(entry.getKey().toString(), fromJson(entry.getValue().toString()));
+        }
+        List<KeywordRule> sorted = new ArrayList<>(rules.values());
+        sorted.sort(Comparator.comparing(KeywordRule::getKeyword));
+        return sorted;
+    }
+
+    /**
+     * Returns the tenant's rule for the keyword, else the built-in one, or null.
+     */
+    public KeywordRule getRule(String tenantId, String keyword) {
+        String normalized = keyword.toUpperCase(Locale.ROOT);
+        Object json = redisTemplate.opsForHash().get(RULES_PREFIX + tenantId, normalized);
+        return json == null ? BUILT_IN.get(normalized) : fromJson(json.toString());
+    }
+
+    /**
+     * Saves the tenant's rule for a keyword, overriding a built-in one. Throws
+     * IllegalArgumentException for keywords other than 1 to 20 letters and digits.
+     */
+    public KeywordRule saveRule(String tenantId, String keyword, KeywordRule rule) {
+        String normalized = keyword.toUpperCase(Locale.ROOT);
+        if (!normalized.matches(KEYWORD)) {
+            throw new IllegalArgumentException("Keyword must be 1 to 20 letters and digits: " + keyword);
+        }
+        rule.setKeyword(normalized);
+        try {
+            redisTemplate.opsForHash().put(RULES_PREFIX + tenantId, normalized, objectMapper.writeValueAsString(rule));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode keyword rule", e);
+        }
+        return rule;
+    }
+
+    /**
+     * Deletes the tenant's rule for a keyword; a built-in one it overrode applies again.
+     */
+    public boolean deleteRule(String tenantId, String keyword) {
+        Long removed = redisTemplate.opsForHash().delete(RULES_PREFIX + tenantId, keyword.toUpperCase(Locale.ROOT));
+        return removed != null && removed > 0;
+    }
+
+    /**
+     * Applies the rule of the message's first word, if it is a keyword of the
+     * tenant, and returns the rule, or null.
+     */
+    public KeywordRule handle(InboundMessage message, String tenantId) {
+        String firstWord = message.getMessage().trim().split("\\s+")[0];
+        if (firstWord.isEmpty()) {
+            return null;
+        }
+        KeywordRule rule = getRule(tenantId, firstWord);
+        if (rule == null) {
+            return null;
+        }
+        String phoneNumber = message.getPhoneNumber();
+        if (KeywordRule.ACTION_OPT_OUT.equals(rule.getAction())) {
+            // Confirmed before opting out, which would reject the reply
+            reply(phoneNumber, rule, tenantId);
+            setConsent(phoneNumber, rule.getCategory(), false);
+        } else if (KeywordRule.ACTION_OPT_IN.equals(rule.getAction())) {
+            setConsent(phoneNumber, rule.getCategory(), true);
+            reply(phoneNumber, rule, tenantId);
+        } else {
+            reply(phoneNumber, rule, tenantId);
+        }
+        return rule;
+    }
+
+    // A null category means every category
+    private void setConsent(String phoneNumber, String category, boolean allowed) {
+        try {
+            for (String each : CATEGORIES) {
+                if (category == null || category.equals(each)) {
+                    preferences.setConsent(phoneNumber, UserPreferences.CHANNEL_SMS, each, allowed);
+                }
+            }
+        } catch (KafkaException e) {
+            // The consent is stored; only downstream systems missed the change
+            System.err.println("Failed to publish preference change to Kafka: " + e.getMessage());
+        }
+    }
+
+    private void reply(String phoneNumber, KeywordRule rule, String tenantId) {
+        if (rule.getReply() == null || rule.getReply().isEmpty()) {
+            return;
+        }
+        SmsRequest request = new SmsRequest();
+        request.setPhoneNumber(phoneNumber);
+        request.setMessage(rule.getReply());
+        request.setCategory("transactional");
+        request.setTenantId(tenantId);
+        try {
+            String result = smsService.sendSms(request);
+            if (result.startsWith("Failed")) {
+                System.err.println("Auto-reply to " + rule.getKeyword() + " from " + phoneNumber + " not sent: " + result);
+            }
+        } catch (QuotaExceededException | QuotaUnavailableException e) {
+            System.err.println("Auto-reply to " + rule.getKeyword() + " from " + phoneNumber + " not sent: " + e.getMessage());
+        }
+    }
+
+    private KeywordRule fromJson(String json) {
+        try {
+            return objectMapper.readValue(json, KeywordRule.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode keyword rule", e);
+        }
+    }
+}
@@ -0,0 +1,185 @@
+package com.example.demo.service;
+
+import com.example.demo.config.LinkProperties;
+import com.example.demo.model.ShortLink;
+import com.example.demo.model.SmsEvent;
+import com.example.demo.model.SmsRequest;
+import java.security.SecureRandom;
+import java.time.Instant;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.Set;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.kafka.KafkaException;
+import org.springframework.stereotype.Service;
+
+/**
+ * Short tracked links, kept in Redis. Before a send reaches the provider its
+ * URLs are replaced by short links; once the provider accepts it, the links
+ * learn its provider message ID, so a click on one can be reported as a
+ * "clicked" receipt on the message, which the storage service records and
+ * counts in its analytics.
+ */
+@Service
+public class LinkTrackingService {
+    public static final String STATUS_CLICKED = "clicked";
+
+    private static final String LINK_PREFIX = "link:";
+    // Codes of each send's links, so they can be given its provider message ID
+    private static final String SEND_PREFIX = "link_send:";
+    private static final String CODE_ALPHABET = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789";
+    // Stops before whitespace and trailing punctuation that usually ends a sentence
+    private static final Pattern URL = Pattern.compile("https?://[^\\s]*[^\\s.,;:!?)'\"]");
+
+    private final StringRedisTemplate redisTemplate;
+    private final SmsEventProducer eventProducer;
+    private final LinkProperties properties;
+    private final SecureRandom random = new SecureRandom();
+
+    @Autowired
+    public LinkTrackingService(StringRedisTemplate redisTemplate, SmsEventProducer eventProducer, LinkProperties properties) {
+        this.redisTemplate = redisTemplate;
+        this.eventProducer = eventProducer;
+        this.properties = properties;
+    }
+
+    /**
+     * Replaces the URLs in the request's message with short links of the send,
+     * when link tracking is enabled. Links already pointing at the redirect
+     * handler are left alone, so a send shortened once isn't shortened again.
+     */
+    public void shorten(SmsRequest request, String sendId) {
+        if (!properties.isEnabled() || request.getMessage() == null) {
+            return;
+        }
+        Matcher matcher = URL.matcher(request.getMessage());
+        StringBuffer shortened = new StringBuffer();
+        boolean changed = false;
+        while (matcher.find()) {
+            String url = matcher.group();
+            String replacement = url;
+            if (!url.startsWith(properties.getBaseUrl())) {
+                replacement = properties.getBaseUrl() + create(url, request, sendId);
+                changed = true;
+            }
+            matcher.appendReplacement(shortened, Matcher.quoteReplacement(replacement));
+        }
+        matcher.appendTail(shortened);
+        if (changed) {
+            request.setMessage(shortened.toString());
+        }
+    }
+
+    // Stores a link under a fresh code and returns the code
+    private String create(String url, SmsRequest request, String sendId) {
+        Map<String, String> fields = new HashMap<>();
+        fields.put("url", url);
+        fields.put("phoneNumber", request.getPhoneNumber());
+        fields.put("sendId", sendId);
+        if (request.getCampaignId() != null) {
+            fields.put("campaignId", request.getCampaignId());
+        }
+        if (request.getVariant() != null) {
+            fields.put("variant", request.getVariant());
+        }
+        while (true) {
+            String code = newCode();
+            String key = LINK_PREFIX + code;
+            // Claims the code; a collision with a live link picks another
+            if (!Boolean.TRUE.equals(redisTemplate.opsForHash().putIfAbsent(key, "url", url))) {
+                continue;
+            }
+            redisTemplate.opsForHash().putAll(key, fields);
+            redisTemplate.expire(key, properties.getTtl());
+            redisTemplate.opsForSet().add(SEND_PREFIX + sendId, code);
+            redisTemplate.expire(SEND_PREFIX + sendId, properties.getTtl());
+            return code;
+        }
+    }
+
+    private String newCode() {
+        StringBuilder code = new StringBuilder(properties.getCodeLength());
+        for (int i = 0; i < properties.getCodeLength(); i++) {
+            code.append(CODE_ALPHABET.charAt(random.nextInt(CODE_ALPHABET.length())));
+        }
+        return code.toString();
+    }
+
+    /**
+     * Records the provider message ID of a send on its links, if it has any.
+     */
+    public void attach(String sendId, String providerMessageId) {
+        if (!properties.isEnabled() || providerMessageId == null) {
+            return;
+        }
+        Set<String> codes = redisTemplate.opsForSet().members(SEND_PREFIX + sendId);
+        if (codes == null) {
+            return;
+        }
+        for (String code : codes) {
+            // Expired links must not be recreated without their URL
+            if (Boolean.TRUE.equals(redisTemplate.hasKey(LINK_PREFIX + code))) {
+                redisTemplate.opsForHash().put(LINK_PREFIX + code, "providerMessageId", providerMessageId);
+            }
+        }
+    }
+
+    /**
+     * Returns the link with the given code, or null if there is none or it
+     * has expired.
+     */
+    public ShortLink getLink(String code) {
+        Map<Object, Object> fields = redisTemplate.opsForHash().entries(LINK_PREFIX + code);
+        if (fields == null || !fields.containsKey("url")) {
+            return null;
+        }
+        ShortLink link = new ShortLink();
+        link.setCode(code);
+        link.setUrl((String) fields.get("url"));
+        link.setPhoneNumber((String) fields.get("phoneNumber"));
+        link.setSendId((String) fields.get("sendId"));
+        link.setProviderMessageId((String) fields.get("providerMessageId"));
+        link.setCampaignId((String) fields.get("campaignId"));
+        link.setVariant((String) fields.get("variant"));
+        Object clicks = fields.get("clicks");
+        link.setClicks(clicks == null ? 0 : Long.parseLong(clicks.toString()));
+        return link;
+    }
+
+    /**
+     * Counts a click on a link and returns the link, or null if there is none.
+     * The first click is reported on the message it was sent in; later ones
+     * only count towards the link's clicks.
+     */
+    public ShortLink recordClick(String code) {
+        ShortLink link = getLink(code);
+        if (link == null) {
+            return null;
+        }
+        Long clicks = redisTemplate.opsForHash().increment(LINK_PREFIX + code, "clicks", 1);
+        link.setClicks(clicks == null ? link.getClicks() + 1 : clicks);
+        if (link.getClicks() == 1 && link.getProviderMessageId() != null) {
+            try {
+                publishClick(link.getPhoneNumber(), link.getProviderMessageId(), Instant.now());
+            } catch (KafkaException e) {
+                // The redirect matters more to the recipient than the receipt
+                System.err.println("Failed to publish click to Kafka: " + e.getMessage());
+            }
+        }
+        return link;
+    }
+
+    /**
+     * Publishes a "clicked" receipt for the message the provider knows by
+     * providerMessageId.
+     */
+    public void publishClick(String phoneNumber, String providerMessageId, Instant clickedAt) throws KafkaException {
+        SmsEvent event = new SmsEvent(phoneNumber, "", STATUS_CLICKED);
+        event.setProviderMessageId(providerMessageId);
+        event.setClickedAt(clickedAt.toString());
+        eventProducer.sendSmsEvent(event);
+    }
+}
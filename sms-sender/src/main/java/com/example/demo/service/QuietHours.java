@@ -0,0 +1,37 @@
+package com.example.demo.service;
+
+import java.time.Instant;
+import java.time.LocalTime;
+import java.time.ZonedDateTime;
+
+/**
+ * Quiet-hour window arithmetic shared by the compliance checks.
+ */
+public final class QuietHours {
+    private QuietHours() {
+    }
+
+    /**
+     * Returns when the [start, end) window containing localNow ends, or null if
+     * localNow is outside it. A window with start after end crosses midnight.
+     */
+    public static Instant endOfWindow(LocalTime start, LocalTime end, ZonedDateTime localNow) {
+        if (start.equals(end)) {
+            return null;
+        }
+        LocalTime time = localNow.toLocalTime();
+        boolean crossesMidnight = start.isAfter(end);
+        boolean inside = crossesMidnight
+                ? !time.isBefore(start) || time.isBefore(end)
+                : !time.isBefore(start) && time.isBefore(end);
+        if (!inside) {
+            return null;
+        }
+        ZonedDateTime release = localNow.with(end).withSecond(0).withNano(0);
+        if (!release.isAfter(localNow)) {
+            // e.g. 23:00 in a 21:00-09:00 window ends tomorrow
+            release = release.plusDays(1);
+        }
+        return release.toInstant();
+    }
+}
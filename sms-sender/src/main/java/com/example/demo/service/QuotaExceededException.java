@@ -0,0 +1,36 @@
+package com.example.demo.service;
+
+/**
+ * Thrown when a send would exceed a tenant or phone-number quota.
+ */
+public class QuotaExceededException extends RuntimeException {
+    private final String scope;
+    private final String period;
+    private final long limit;
+    private final long retryAfterSeconds;
+
+    public QuotaExceededException(String scope, String period, long limit, long retryAfterSeconds) {
+        super("Quota exceeded: " + period + " " + scope + " limit of " + limit + " messages");
+        this.scope = scope;
+        this.period = period;
+        this.limit = limit;
+        this.retryAfterSeconds = retryAfterSeconds;
+    }
+
+    public String getScope() {
+        return scope;
+    }
+
+    public String getPeriod() {
+        return period;
+    }
+
+    public long getLimit() {
+        return limit;
+    }
+
+    // Seconds until the exhausted quota window resets
+    public long getRetryAfterSeconds() {
+        return retryAfterSeconds;
+    }
+}
@@ -0,0 +1,155 @@
+package com.example.demo.service;
+
+import com.example.demo.config.QuotaProperties;
+import com.example.demo.model.QuotaUsage;
+import java.time.Clock;
+import java.time.Duration;
+import java.time.LocalDate;
+import java.time.ZoneOffset;
+import java.time.ZonedDateTime;
+import java.time.format.DateTimeFormatter;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.List;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.dao.DataAccessException;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Enforces daily and monthly send quotas per tenant and per phone number using
+ * Redis counters. Windows are calendar days and months in UTC; counters expire
+ * on their own once the window is over.
+ */
+@Service
+public class QuotaService {
+    private static final String QUOTA_PREFIX = "quota:";
+    private static final DateTimeFormatter DAY = DateTimeFormatter.ofPattern("yyyyMMdd");
+    private static final DateTimeFormatter MONTH = DateTimeFormatter.ofPattern("yyyyMM");
+
+    private final StringRedisTemplate redisTemplate;
+    private final QuotaProperties properties;
+    private final Clock clock;
+
+    @Autowired
+    public QuotaService(StringRedisTemplate redisTemplate, QuotaProperties properties) {
+        this(redisTemplate, properties, Clock.systemUTC());
+    }
+
+    public QuotaService(StringRedisTemplate redisTemplate, QuotaProperties properties, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.properties = properties;
+        this.clock = clock;
+    }
+
+    /**
+     * Counts one send against every applicable quota. If any quota would be
+     * exceeded nothing is counted and QuotaExceededException is thrown. If
+     * Redis is unreachable QuotaUnavailableException is thrown, unless
+     * quotas fail open.
+     */
+    public void consume(String tenantId, String phoneNumber) {
+        try {
+            increment(tenantId, phoneNumber);
+        } catch (DataAccessException e) {
+            if (!properties.isFailOpen()) {
+                throw new QuotaUnavailableException(e);
+            }
+            System.err.println("Quotas unavailable, sending uncounted: " + e.getMessage());
+        }
+    }
+
+    private void increment(String tenantId, String phoneNumber) {
+        List<String> incremented = new ArrayList<>();
+        for (Counter counter : counters(tenantId, phoneNumber)) {
+            if (counter.limit <= 0) {
+                continue;
+            }
+            Long used = redisTemplate.opsForValue().increment(counter.key);
+            incremented.add(counter.key);
+            if (used != null && used == 1L) {
+                // First send in this window: let the counter clean itself up
+                redisTemplate.expire(counter.key, Duration.ofSeconds(counter.resetsInSeconds + 3600));
+            }
+            if (used != null && used > counter.limit) {
+                // Roll back so rejected sends don't eat into the quota
+                for (String key : incremented) {
+                    redisTemplate.opsForValue().decrement(key);
+                }
+                throw new QuotaExceededException(counter.scope, counter.period, counter.limit, counter.resetsInSeconds);
+            }
+        }
+    }
+
+    /**
+     * Throws the QuotaExceededException consume would throw for one more send,
+     * without counting anything.
+     */
+    public void check(String tenantId, String phoneNumber) {
+        for (Counter counter : counters(tenantId, phoneNumber)) {
+            if (counter.limit > 0 && window(counter).getUsed() + 1 > counter.limit) {
+                throw new QuotaExceededException(counter.scope, counter.period, counter.limit, counter.resetsInSeconds);
+            }
+        }
+    }
+
+    /**
+     * Returns current usage for a tenant and, if given, a phone number.
+     */
+    public QuotaUsage usage(String tenantId, String phoneNumber) {
+        List<Counter> counters = counters(tenantId, phoneNumber);
+        QuotaUsage usage = new QuotaUsage();
+        usage.setTenantId(tenantId);
+        usage.setPhoneNumber(phoneNumber);
+        usage.setTenantDaily(window(counters.get(0)));
+        usage.setTenantMonthly(window(counters.get(1)));
+        if (phoneNumber != null) {
+            usage.setPhoneDaily(window(counters.get(2)));
+            usage.setPhoneMonthly(window(counters.get(3)));
+        }
+        return usage;
+    }
+
+    private QuotaUsage.Window window(Counter counter) {
+        String value = redisTemplate.opsForValue().get(counter.key);
+        long used = value == null ? 0 : Long.parseLong(value);
+        return new QuotaUsage.Window(used, counter.limit, counter.resetsInSeconds);
+    }
+
+    // Tenant counters come first, then phone counters when a number is given
+    private List<Counter> counters(String tenantId, String phoneNumber) {
+        ZonedDateTime now = ZonedDateTime.now(clock.withZone(ZoneOffset.UTC));
+        LocalDate today = now.toLocalDate();
+        String day = today.format(DAY);
+        String month = today.format(MONTH);
+        long untilTomorrow = Duration.between(now, today.plusDays(1).atStartOfDay(ZoneOffset.UTC)).getSeconds();
+        long untilNextMonth = Duration.between(now, today.withDayOfMonth(1).plusMonths(1).atStartOfDay(ZoneOffset.UTC)).getSeconds();
+
+        QuotaProperties.Limits tenantLimits = properties.limitsForTenant(tenantId);
+        List<Counter> counters = new ArrayList<>(Arrays.asList(
+                new Counter("tenant", "daily", QUOTA_PREFIX + "tenant:" + tenantId + ":day:" + day, tenantLimits.getDaily(), untilTomorrow),
+                new Counter("tenant", "monthly", QUOTA_PREFIX + "tenant:" + tenantId + ":month:" + month, tenantLimits.getMonthly(), untilNextMonth)));
+        if (phoneNumber != null) {
+            QuotaProperties.Limits phoneLimits = properties.getPhone();
+            counters.add(new Counter("phone", "daily", QUOTA_PREFIX + "phone:" + phoneNumber + ":day:" + day, phoneLimits.getDaily(), untilTomorrow));
+            counters.add(new Counter("phone", "monthly", QUOTA_PREFIX + "phone:" + phoneNumber + ":month:" + month, phoneLimits.getMonthly(), untilNextMonth));
+        }
+        return counters;
+    }
+
+    private static class Counter {
+        final String scope;
+        final String period;
+        final String key;
+        final long limit;
+        final long resetsInSeconds;
+
+        Counter(String scope, String period, String key, long limit, long resetsInSeconds) {
+            this.scope = scope;
+            this.period = period;
+            this.key = key;
+            this.limit = limit;
+            this.resetsInSeconds = resetsInSeconds;
+        }
+    }
+}
@@ -0,0 +1,19 @@
+package com.example.demo.service;
+
+/**
+ * Thrown when quotas cannot be checked because Redis is unreachable and
+ * sms.quota.fail-open is off, so the send is refused rather than risk
+ * exceeding a quota.
+ */
+public class QuotaUnavailableException extends RuntimeException {
+    // Long enough for a Redis failover
+    private static final long RETRY_AFTER_SECONDS = 30;
+
+    public QuotaUnavailableException(Throwable cause) {
+        super("Quotas are temporarily unavailable", cause);
+    }
+
+    public long getRetryAfterSeconds() {
+        return RETRY_AFTER_SECONDS;
+    }
+}
@@ -0,0 +1,164 @@
+package com.example.demo.service;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.RecipientTimezone;
+import com.example.demo.model.SmsRequest;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.ZoneId;
+import java.util.HashMap;
+import java.util.Map;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Recipient timezones, kept in Redis per phone number. A recipient without
+ * one gets it inferred on their first send that needs it: from the country
+ * rule of the request's countryCode (or of the country their calling code
+ * belongs to), else from the calling code itself when its country has a
+ * single timezone. Inferred timezones are kept, so later sends and replicas
+ * agree; deleting one has it inferred again.
+ */
+@Service
+public class RecipientTimezoneService {
+    private static final String TIMEZONES_KEY = "recipient_timezones";
+
+    // Calling codes of single-timezone countries: code -> {country, zone}.
+    // Countries spanning several zones (+1, +7, +55, +61, +62, ...) can't be
+    // told apart by calling code and need a country rule or a manual timezone.
+    private static final Map<String, String[]> CALLING_CODES = new HashMap<>();
+
+    static {
+        CALLING_CODES.put("27", new String[] {"ZA", "Africa/Johannesburg"});
+        CALLING_CODES.put("31", new String[] {"NL", "Europe/Amsterdam"});
+        CALLING_CODES.put("33", new String[] {"FR", "Europe/Paris"});
+        CALLING_CODES.put("34", new String[] {"ES", "Europe/Madrid"});
+        CALLING_CODES.put("39", new String[] {"IT", "Europe/Rome"});
+        CALLING_CODES.put("44", new String[] {"GB", "Europe/London"});
+        CALLING_CODES.put("49", new String[] {"DE", "Europe/Berlin"});
+        CALLING_CODES.put("60", new String[] {"MY", "Asia/Kuala_Lumpur"});
+        CALLING_CODES.put("63", new String[] {"PH", "Asia/Manila"});
+        CALLING_CODES.put("65", new String[] {"SG", "Asia/Singapore"});
+        CALLING_CODES.put("66", new String[] {"TH", "Asia/Bangkok"});
+        CALLING_CODES.put("81", new String[] {"JP", "Asia/Tokyo"});
+        CALLING_CODES.put("82", new String[] {"KR", "Asia/Seoul"});
+        CALLING_CODES.put("86", new String[] {"CN", "Asia/Shanghai"});
+        CALLING_CODES.put("91", new String[] {"IN", "Asia/Kolkata"});
+        CALLING_CODES.put("92", new String[] {"PK", "Asia/Karachi"});
+        CALLING_CODES.put("234", new String[] {"NG", "Africa/Lagos"});
+        CALLING_CODES.put("254", new String[] {"KE", "Africa/Nairobi"});
+        CALLING_CODES.put("880", new String[] {"BD", "Asia/Dhaka"});
+        CALLING_CODES.put("966", new String[] {"SA", "Asia/Riyadh"});
+        CALLING_CODES.put("971", new String[] {"AE", "Asia/Dubai"});
+    }
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final CountryRuleService countryRuleService;
+
+    @Autowired
+    public RecipientTimezoneService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            CountryRuleService countryRuleService) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.countryRuleService = countryRuleService;
+    }
+
+    public RecipientTimezone getTimezone(String phoneNumber) {
+        Object json = redisTemplate.opsForHash().get(TIMEZONES_KEY, phoneNumber);
+        if (json == null) {
+            return null;
+        }
+        try {
+            return objectMapper.readValue(json.toString(), RecipientTimezone.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode recipient timezone", e);
+        }
+    }
+
+    /**
+     * Sets a recipient's timezone, replacing any inferred one. Throws
+     * DateTimeException for unknown zones.
+     */
+    public RecipientTimezone saveTimezone(String phoneNumber, String timezone) {
+        ZoneId.of(timezone); // throws DateTimeException for unknown zones
+        RecipientTimezone stored = new RecipientTimezone(timezone, RecipientTimezone.SOURCE_MANUAL);
+        store(phoneNumber, stored);
+        return stored;
+    }
+
+    public boolean deleteTimezone(String phoneNumber) {
+        Long removed = redisTemplate.opsForHash().delete(TIMEZONES_KEY, phoneNumber);
+        return removed != null && removed > 0;
+    }
+
+    /**
+     * Returns the recipient's timezone, inferring and storing it if they have
+     * none yet, or null if it can't be inferred.
+     */
+    public ZoneId resolve(SmsRequest request) {
+        RecipientTimezone stored = getTimezone(request.getPhoneNumber());
+        if (stored != null) {
+            return ZoneId.of(stored.getTimezone());
+        }
+        RecipientTimezone inferred = infer(request);
+        if (inferred == null) {
+            return null;
+        }
+        store(request.getPhoneNumber(), inferred);
+        return ZoneId.of(inferred.getTimezone());
+    }
+
+    private RecipientTimezone infer(SmsRequest request) {
+        String[] calling = callingCode(request.getPhoneNumber());
+        String country = request.getCountryCode();
+        if (country == null && calling != null) {
+            country = calling[0];
+        }
+        if (country != null) {
+            CountryRule rule = countryRuleService.getRule(country);
+            if (rule != null && rule.getTimezone() != null) {
+                return new RecipientTimezone(rule.getTimezone(), RecipientTimezone.SOURCE_COUNTRY);
+            }
+        }
+        // A calling code of another country than the request's says nothing about it
+        if (calling != null && (request.getCountryCode() == null || request.getCountryCode().equals(calling[0]))) {
+            return new RecipientTimezone(calling[1], RecipientTimezone.SOURCE_NUMBER);
+        }
+        return null;
+    }
+
+    /**
+     * Returns the {country, zone} of a number in international format (+ or
+     * 00 prefix) whose calling code belongs to a single-timezone country, or
+     * null.
+     */
+    static String[] callingCode(String phoneNumber) {
+        String digits;
+        if (phoneNumber.startsWith("+")) {
+            digits = phoneNumber.substring(1);
+        } else if (phoneNumber.startsWith("00")) {
+            digits = phoneNumber.substring(2);
+        } else {
+            return null; // national format
+        }
+        for (int length = 3; length >= 1; length--) {
+            if (digits.length() > length) {
+                String[] match = CALLING_CODES.get(digits.substring(0, length));
+                if (match != null) {
+                    return match;
+                }
+            }
+        }
+        return null;
+    }
+
+    private void store(String phoneNumber, RecipientTimezone timezone) {
+        try {
+            redisTemplate.opsForHash().put(TIMEZONES_KEY, phoneNumber, objectMapper.writeValueAsString(timezone));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode recipient timezone", e);
+        }
+    }
+}
@@ -0,0 +1,55 @@
+package com.example.demo.service;
+
+import com.example.demo.model.SmsEvent;
+import java.util.Arrays;
+import java.util.HashSet;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ConcurrentHashMap;
+import org.springframework.stereotype.Service;
+
+/**
+ * Correlates sends with their outcome for callers waiting on a send. A waiter
+ * registers the send ID before sending; the first outcome status seen for
+ * that ID on the status pipeline completes it.
+ */
+@Service
+public class SendOutcomeRegistry {
+    // Statuses that end a wait; retrying is not one of them. Deferred sends are
+    // released (as a new send) much later, so waiting for them is pointless.
+    private static final Set<String> OUTCOME_STATUSES = new HashSet<>(Arrays.asList(
+            "successful", "unsuccessful", "blocked", "rejected", "deferred"));
+
+    private final Map<String, CompletableFuture<SmsEvent>> waiters = new ConcurrentHashMap<>();
+
+    /**
+     * Registers a waiter for sendId. The caller must cancel it once done,
+     * whether or not it completed.
+     */
+    public CompletableFuture<SmsEvent> register(String sendId) {
+        return waiters.computeIfAbsent(sendId, id -> new CompletableFuture<>());
+    }
+
+    public void cancel(String sendId) {
+        waiters.remove(sendId);
+    }
+
+    /**
+     * Completes the waiter for the event's send if the event is an outcome.
+     * Events for sends nobody waits on are ignored.
+     */
+    public void record(SmsEvent event) {
+        if (event.getSendId() == null || !isOutcome(event.getStatus())) {
+            return;
+        }
+        CompletableFuture<SmsEvent> waiter = waiters.remove(event.getSendId());
+        if (waiter != null) {
+            waiter.complete(event);
+        }
+    }
+
+    public static boolean isOutcome(String status) {
+        return status != null && OUTCOME_STATUSES.contains(status);
+    }
+}
@@ -0,0 +1,183 @@
+package com.example.demo.service;
+
+import com.example.demo.config.PricingProperties;
+import com.example.demo.model.SmsPreview;
+import com.example.demo.model.SmsPreviewRequest;
+import java.math.BigDecimal;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+import org.springframework.stereotype.Service;
+
+/**
+ * Works out what SmsService.sendSms would do with a request without sending it:
+ * renders the message, sizes it the way the provider bills it, and runs the
+ * same checks in the same order, without counting anything towards abuse
+ * thresholds or quotas.
+ */
+@Service
+public class SmsPreviewService {
+    static final String GSM_7 = "GSM-7";
+    static final String UCS_2 = "UCS-2";
+
+    private static final Pattern PLACEHOLDER = Pattern.compile("\\{\\{\\s*([A-Za-z0-9_.-]+)\\s*\\}\\}");
+    // GSM 03.38 basic character set; escaped as the sources aren't compiled as UTF-8 everywhere
+    private static final String GSM_BASIC = "@\u00A3$\u00A5\u00E8\u00E9\u00F9\u00EC\u00F2\u00C7\n\u00D8\u00F8\r\u00C5\u00E5"
+            + "\u0394_\u03A6\u0393\u039B\u03A9\u03A0\u03A8\u03A3\u0398\u039E\u00C6\u00E6\u00DF\u00C9"
+            + " !\"#\u00A4%&'()*+,-./0123456789:;<=>?"
+            + "\u00A1ABCDEFGHIJKLMNOPQRSTUVWXYZ\u00C4\u00D6\u00D1\u00DC\u00A7"
+            + "\u00BFabcdefghijklmnopqrstuvwxyz\u00E4\u00F6\u00F1\u00FC\u00E0";
+    // Extension table characters, sent as an escape plus the character
+    private static final String GSM_EXTENSION = "\f^{}\\[~]|\u20AC";
+
+    private final BlacklistCache cache;
+    private final AbuseDetectionService abuseDetection;
+    private final CountryRuleService countryRuleService;
+    private final TenantQuietHoursService quietHoursService;
+    private final QuotaService quotaService;
+    private final PricingProperties pricing;
+    private final CampaignExperimentService experiments;
+    private final CampaignSendWindowService sendWindows;
+    private final UserPreferenceService preferences;
+
+    public SmsPreviewService(BlacklistCache cache, AbuseDetectionService abuseDetection,
+            CountryRuleService countryRuleService, TenantQuietHoursService quietHoursService,
+            QuotaService quotaService, PricingProperties pricing, CampaignExperimentService experiments,
+            CampaignSendWindowService sendWindows, UserPreferenceService preferences) {
+        this.cache = cache;
+        this.abuseDetection = abuseDetection;
+        this.countryRuleService = countryRuleService;
+        this.quietHoursService = quietHoursService;
+        this.quotaService = quotaService;
+        this.pricing = pricing;
+        this.experiments = experiments;
+        this.sendWindows = sendWindows;
+        this.preferences = preferences;
+    }
+
+    public SmsPreview preview(SmsPreviewRequest request) {
+        SmsPreview preview = new SmsPreview();
+        // The recipient's variant decides the message that is rendered
+        experiments.assign(request);
+        preview.setVariant(request.getVariant());
+        List<String> missing = new ArrayList<>();
+        String rendered = render(request.getMessage(), request.getParams(), missing);
+        preview.setRenderedMessage(rendered);
+        preview.setMissingParams(missing);
+
+        boolean gsm = isGsm(rendered);
+        int characters = gsm ? gsmLength(rendered) : rendered.length();
+        int segments = segments(characters, gsm);
+        preview.setEncoding(gsm ? GSM_7 : UCS_2);
+        preview.setCharacters(characters);
+        preview.setSegments(segments);
+        preview.setEstimatedCost(pricing.priceForCountry(request.getCountryCode()).multiply(BigDecimal.valueOf(segments)));
+        preview.setCurrency(pricing.getCurrency());
+        preview.setProvider(TwillioService.PROVIDER_NAME);
+
+        // Compliance rules look at the request as it would be sent
+        request.setMessage(rendered);
+        decide(request, preview);
+        return preview;
+    }
+
+    // Mirrors the checks of SmsService.sendSms
+    private void decide(SmsPreviewRequest request, SmsPreview preview) {
+        String phoneNumber = request.getPhoneNumber();
+        if (cache.isBlacklisted(phoneNumber)) {
+            preview.setOutcome("blocked");
+            preview.setReason("Phone number is blacklisted");
+            return;
+        }
+        String abuse = abuseDetection.check(phoneNumber);
+        if (abuse != null) {
+            preview.setOutcome("blocked");
+            preview.setReason(abuse);
+            return;
+        }
+
+        ComplianceDecision decision = preferences.evaluate(request);
+        if (decision == null) {
+            decision = countryRuleService.evaluate(request);
+        }
+        if (decision == null) {
+            decision = quietHoursService.evaluate(request);
+        }
+        if (decision == null) {
+            decision = sendWindows.evaluate(request);
+        }
+        if (decision != null) {
+            preview.setOutcome(decision.getAction() == ComplianceDecision.Action.DEFER ? "deferred" : "rejected");
+            preview.setReason(decision.getReason());
+            preview.setReleaseAt(decision.getReleaseAt());
+            return;
+        }
+        if (request.isPromotional()) {
+            abuse = abuseDetection.peekSend(phoneNumber);
+            if (abuse != null) {
+                preview.setOutcome("blocked");
+                preview.setReason(abuse);
+                return;
+            }
+        }
+
+        try {
+            quotaService.check(request.getTenantId(), phoneNumber);
+        } catch (QuotaExceededException e) {
+            preview.setOutcome("quota_exceeded");
+            preview.setReason(e.getMessage());
+            return;
+        }
+        preview.setOutcome("send");
+    }
+
+    // Fills in {{name}} placeholders, leaving the ones without a param as they
+    // are and adding their names to missing
+    static String render(String template, Map<String, String> params, List<String> missing) {
+        Matcher matcher = PLACEHOLDER.matcher(template);
+        StringBuffer rendered = new StringBuffer();
+        while (matcher.find()) {
+            String name = matcher.group(1);
+            String value = params == null ? null : params.get(name);
+            if (value == null) {
+                if (!missing.contains(name)) {
+                    missing.add(name);
+                }
+                value = matcher.group();
+            }
+            matcher.appendReplacement(rendered, Matcher.quoteReplacement(value));
+        }
+        matcher.appendTail(rendered);
+        return rendered.toString();
+    }
+
+    static boolean isGsm(String message) {
+        for (int i = 0; i < message.length(); i++) {
+            char c = message.charAt(i);
+            if (GSM_BASIC.indexOf(c) < 0 && GSM_EXTENSION.indexOf(c) < 0) {
+                return false;
+            }
+        }
+        return true;
+    }
+
+    static int gsmLength(String message) {
+        int length = 0;
+        for (int i = 0; i < message.length(); i++) {
+            length += GSM_EXTENSION.indexOf(message.charAt(i)) >= 0 ? 2 : 1;
+        }
+        return length;
+    }
+
+    // Concatenated messages lose room in every segment to the header
+    static int segments(int characters, boolean gsm) {
+        int single = gsm ? 160 : 70;
+        int multipart = gsm ? 153 : 67;
+        if (characters <= single) {
+            return characters == 0 ? 0 : 1;
+        }
+        return (characters + multipart - 1) / multipart;
+    }
+}
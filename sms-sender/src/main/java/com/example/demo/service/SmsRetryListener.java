@@ -0,0 +1,52 @@
+package com.example.demo.service;
+
+import com.example.demo.model.SmsRetry;
+import java.time.Clock;
+import java.time.Duration;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.kafka.annotation.KafkaListener;
+import org.springframework.kafka.support.Acknowledgment;
+import org.springframework.stereotype.Component;
+
+/**
+ * Consumes the retry topics and re-attempts each send once it is due. A record
+ * that isn't due yet is nacked, which pauses its partition without blocking the
+ * consumer's poll loop; records behind it in the same tier are due later still.
+ */
+@Component
+public class SmsRetryListener {
+    // Upper bound on one pause, so a long tier doesn't hold the partition past a rebalance
+    private static final Duration MAX_PAUSE = Duration.ofSeconds(30);
+
+    private final SmsService smsService;
+    private final Clock clock;
+
+    @Autowired
+    public SmsRetryListener(SmsService smsService) {
+        this(smsService, Clock.systemUTC());
+    }
+
+    public SmsRetryListener(SmsService smsService, Clock clock) {
+        this.smsService = smsService;
+        this.clock = clock;
+    }
+
+    // The storage service's resends carry no type header, so the payload type is set here
+    @KafkaListener(topics = "#{@retryProperties.topics()}", groupId = "${sms.retry.group-id:sms-sender-retry}",
+            properties = "spring.json.value.default.type=com.example.demo.model.SmsRetry")
+    public void onRetry(SmsRetry retry, Acknowledgment ack) {
+        long wait = retry.getDueAtEpochMs() - clock.millis();
+        if (wait > 0) {
+            ack.nack(Duration.ofMillis(Math.min(wait, MAX_PAUSE.toMillis())));
+            return;
+        }
+        retry.getRequest().setTenantId(retry.getTenantId());
+        try {
+            String result = smsService.retrySms(retry);
+            System.out.println("Retried SMS " + retry.getId() + " (attempt " + retry.getAttempt() + "): " + result);
+        } catch (Exception e) {
+            System.err.println("Failed to retry SMS " + retry.getId() + ": " + e.getMessage());
+        }
+        ack.acknowledge();
+    }
+}
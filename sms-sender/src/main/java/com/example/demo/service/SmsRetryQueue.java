@@ -0,0 +1,63 @@
+package com.example.demo.service;
+
+import com.example.demo.config.RetryProperties;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.SmsRetry;
+import java.time.Clock;
+import java.time.Duration;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.kafka.core.KafkaTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Publishes sends that failed transiently onto the delay-tier retry topics.
+ * SmsRetryListener picks them up once due.
+ */
+@Service
+public class SmsRetryQueue {
+    private static final long SEND_TIMEOUT_SECONDS = 5;
+
+    private final KafkaTemplate<String, SmsRetry> kafkaTemplate;
+    private final RetryProperties properties;
+    private final Clock clock;
+
+    @Autowired
+    public SmsRetryQueue(KafkaTemplate<String, SmsRetry> kafkaTemplate, RetryProperties properties) {
+        this(kafkaTemplate, properties, Clock.systemUTC());
+    }
+
+    public SmsRetryQueue(KafkaTemplate<String, SmsRetry> kafkaTemplate, RetryProperties properties, Clock clock) {
+        this.kafkaTemplate = kafkaTemplate;
+        this.properties = properties;
+        this.clock = clock;
+    }
+
+    /**
+     * Schedules the attempt after failedAttempt. Returns null when the attempts
+     * are exhausted or the retry could not be published, in which case the send
+     * should be marked failed.
+     */
+    public SmsRetry scheduleRetry(String retryId, SmsRequest request, int failedAttempt) {
+        int attempt = failedAttempt + 1;
+        if (attempt > properties.getMaxAttempts()) {
+            return null;
+        }
+        Duration delay = properties.delayBefore(attempt);
+        SmsRetry retry = new SmsRetry(retryId, request.getTenantId(), request, attempt,
+                clock.millis() + delay.toMillis());
+        try {
+            kafkaTemplate.send(properties.topicFor(delay), request.getPhoneNumber(), retry)
+                    .get(SEND_TIMEOUT_SECONDS, TimeUnit.SECONDS);
+        } catch (ExecutionException | TimeoutException e) {
+            System.err.println("Failed to schedule retry " + attempt + " for " + request.getPhoneNumber() + ": " + e.getMessage());
+            return null;
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+            return null;
+        }
+        return retry;
+    }
+}
@@ -5,26 +5,64 @@ import org.springframework.stereotype.Service;
 import com.example.demo.service.BlacklistCache;
 import com.example.demo.model.SmsRequest;
 import com.example.demo.model.SmsEvent;
+import com.example.demo.model.SmsRetry;
+import java.util.UUID;
 
 @Service
 public class SmsService {
     private final BlacklistCache cache;
     private final SmsEventProducer eventProducer;
     private final TwillioService twillioService;
+    private final QuotaService quotaService;
+    private final CountryRuleService countryRuleService;
+    private final TenantQuietHoursService quietHoursService;
+    private final DeferredSmsQueue deferredQueue;
+    private final SmsRetryQueue retryQueue;
+    private final AbuseDetectionService abuseDetection;
+    private final CampaignExperimentService experiments;
+    private final LinkTrackingService links;
+    private final CampaignSendWindowService sendWindows;
+    private final UserPreferenceService preferences;
 
-    public SmsService(BlacklistCache cache, SmsEventProducer eventProducer, TwillioService twillioService) {
+    public SmsService(BlacklistCache cache, SmsEventProducer eventProducer, TwillioService twillioService,
+            QuotaService quotaService, CountryRuleService countryRuleService,
+            TenantQuietHoursService quietHoursService, DeferredSmsQueue deferredQueue,
+            SmsRetryQueue retryQueue, AbuseDetectionService abuseDetection,
+            CampaignExperimentService experiments, LinkTrackingService links,
+            CampaignSendWindowService sendWindows, UserPreferenceService preferences) {
         this.cache = cache;
         this.eventProducer = eventProducer;
         this.twillioService = twillioService;
+        this.quotaService = quotaService;
+        this.countryRuleService = countryRuleService;
+        this.quietHoursService = quietHoursService;
+        this.deferredQueue = deferredQueue;
+        this.retryQueue = retryQueue;
+        this.abuseDetection = abuseDetection;
+        this.experiments = experiments;
+        this.links = links;
+        this.sendWindows = sendWindows;
+        this.preferences = preferences;
     }
 
     public String sendSms(SmsRequest request) {
+        return sendSms(request, UUID.randomUUID().toString());
+    }
+
+    /**
+     * Sends under a caller-chosen send ID, which every event of the send
+     * carries, so the caller can wait for its outcome (see SendOutcomeRegistry).
+     */
+    public String sendSms(SmsRequest request, String sendId) {
+        // Before anything reads the template or message, and before the
+        // blacklist check so even blocked sends are tagged with their variant
+        experiments.assign(request);
         String phoneNumber = request.getPhoneNumber();
         String message = request.getMessage();
 
         // Check if phone number is blacklisted
         if (cache.isBlacklisted(phoneNumber)) {
-            SmsEvent event = new SmsEvent(phoneNumber, message, "blocked");
+            SmsEvent event = newEvent(request, sendId, "blocked");
             try {
                 eventProducer.sendSmsEvent(event);
             } catch (KafkaException e) {
@@ -34,27 +72,142 @@ public class SmsService {
             return "Failed: Phone number is blacklisted";
         }
 
+        // Premium-rate and fraud ranges
+        String abuse = abuseDetection.check(phoneNumber);
+        if (abuse != null) {
+            publishQuietly(newEvent(request, sendId, "blocked"));
+            return "Failed: " + abuse;
+        }
+
+        // User preferences, country rules, tenant quiet hours and campaign send
+        // windows are checked before quota so held messages don't count yet
+        ComplianceDecision decision = preferences.evaluate(request);
+        if (decision == null) {
+            decision = countryRuleService.evaluate(request);
+        }
+        if (decision == null) {
+            decision = quietHoursService.evaluate(request);
+        }
+        if (decision == null) {
+            decision = sendWindows.evaluate(request);
+        }
+        if (decision != null) {
+            if (decision.getAction() == ComplianceDecision.Action.DEFER) {
+                deferredQueue.defer(request, decision.getReleaseAt());
+                publishQuietly(newEvent(request, sendId, "deferred"));
+                return "Deferred until " + decision.getReleaseAt() + ": " + decision.getReason();
+            }
+            publishQuietly(newEvent(request, sendId, "rejected"));
+            return "Failed: " + decision.getReason();
+        }
+
+        // Volume spikes get the number blocked. Only promotional sends going out
+        // count, so held sends count once released and OTPs never do
+        if (request.isPromotional()) {
+            abuse = abuseDetection.recordSend(phoneNumber);
+            if (abuse != null) {
+                publishQuietly(newEvent(request, sendId, "blocked"));
+                return "Failed: " + abuse;
+            }
+        }
+
+        // Throws QuotaExceededException, or QuotaUnavailableException while Redis is
+        // down, before anything reaches the provider
+        quotaService.consume(request.getTenantId(), phoneNumber);
+
+        // Only sends going out get tracked links; retries reuse the shortened request
+        links.shorten(request, sendId);
+        return deliver(request, sendId, 1);
+    }
+
+    /**
+     * Re-attempts a send that failed transiently, or that the storage service
+     * resends under a tenant retry policy. Resends can run hours after the
+     * first attempt, so the blacklist and the recipient's preferences (which
+     * opt-outs update) are checked again; quota and the other compliance
+     * checks were settled on the first attempt. Twilio is the only provider,
+     * so a retry asking for another one still goes through it.
+     */
+    public String retrySms(SmsRetry retry) {
+        SmsRequest request = retry.getRequest();
+        if (cache.isBlacklisted(request.getPhoneNumber())) {
+            publishQuietly(newEvent(request, retry.getId(), "blocked"));
+            return "Failed: Phone number is blacklisted";
+        }
+        ComplianceDecision decision = preferences.evaluate(request);
+        if (decision != null) {
+            publishQuietly(newEvent(request, retry.getId(), "rejected"));
+            return "Failed: " + decision.getReason();
+        }
+
+        String provider = retry.getProvider();
+        if (provider != null && !TwillioService.PROVIDER_NAME.equalsIgnoreCase(provider)) {
+            System.err.println("Provider " + provider + " is not available, retrying " + retry.getId()
+                    + " through " + TwillioService.PROVIDER_NAME);
+        }
+        return deliver(request, retry.getId(), retry.getAttempt());
+    }
+
+    private String deliver(SmsRequest request, String sendId, int attempt) {
+        // Provider latency feeds the provider health score downstream
+        long started = System.currentTimeMillis();
         try {
-            twillioService.sendSms(phoneNumber, message);
+            String providerMessageId = twillioService.sendSms(request.getPhoneNumber(), request.getMessage());
             // SMS sent successfully - publish event (Kafka failures shouldn't affect success)
-            SmsEvent event = new SmsEvent(phoneNumber, message, "successful");
-            try {
-                eventProducer.sendSmsEvent(event);
-            } catch (KafkaException e) {
-                // Log Kafka failure but don't affect the response
-                System.err.println("Failed to publish event to Kafka: " + e.getMessage());
-            }
+            SmsEvent event = newProviderEvent(request, sendId, "successful", attempt, started);
+            event.setProviderMessageId(providerMessageId);
+            publishQuietly(event);
+            links.attach(sendId, providerMessageId);
             return "SMS sent to " + request.getPhoneNumber();
         } catch (Exception e) {
-            // SMS failed - publish event (Kafka failures shouldn't affect failure response)
-            SmsEvent event = new SmsEvent(phoneNumber, message, "unsuccessful");
-            try {
-                eventProducer.sendSmsEvent(event);
-            } catch (KafkaException kafkaException) {
-                // Log Kafka failure but don't affect the response
-                System.err.println("Failed to publish event to Kafka: " + kafkaException.getMessage());
+            // Transient failures go to a retry topic until attempts run out
+            if (e instanceof TransientProviderException) {
+                SmsRetry retry = retryQueue.scheduleRetry(sendId, request, attempt);
+                if (retry != null) {
+                    publishQuietly(newProviderEvent(request, sendId, "retrying", attempt, started));
+                    return "Failed to send SMS, retrying (attempt " + retry.getAttempt() + "): " + e.getMessage();
+                }
             }
+            // SMS failed - publish event (Kafka failures shouldn't affect failure response)
+            publishQuietly(newProviderEvent(request, sendId, "unsuccessful", attempt, started));
             return "Failed to send SMS: " + e.getMessage();
         }
     }
+
+    // Kafka failures are logged but never affect the response
+    private void publishQuietly(SmsEvent event) {
+        try {
+            eventProducer.sendSmsEvent(event);
+        } catch (KafkaException e) {
+            System.err.println("Failed to publish event to Kafka: " + e.getMessage());
+        }
+    }
+
+    // Builds an event for a provider attempt that started at startedMs
+    private SmsEvent newProviderEvent(SmsRequest request, String sendId, String status, int attempt, long startedMs) {
+        SmsEvent event = newEvent(request, sendId, status);
+        event.setProvider(TwillioService.PROVIDER_NAME);
+        event.setProviderLatencyMs(System.currentTimeMillis() - startedMs);
+        if (attempt > 1) {
+            event.setAttempt(attempt);
+        }
+        return event;
+    }
+
+    // Builds an event of the send carrying the request's campaign/template attribution
+    private SmsEvent newEvent(SmsRequest request, String sendId, String status) {
+        SmsEvent event = new SmsEvent(request.getPhoneNumber(), request.getMessage(), status);
+        event.setSendId(sendId);
+        event.setCampaignId(request.getCampaignId());
+        event.setTemplateId(request.getTemplateId());
+        event.setVariant(request.getVariant());
+        event.setCountryCode(request.getCountryCode());
+        event.setMetadata(request.getMetadata());
+        event.setTenantId(request.getTenantId());
+        event.setCategory(request.isPromotional() ? "promotional" : "transactional");
+        if (!SmsEvent.DEFAULT_SENDER_ID.equalsIgnoreCase(request.getSenderId())) {
+            event.setSenderId(request.getSenderId());
+        }
+        return event;
+    }
 }
@@ -0,0 +1,33 @@
+package com.example.demo.service;
+
+import com.example.demo.model.SmsEvent;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.kafka.annotation.KafkaListener;
+import org.springframework.stereotype.Component;
+
+/**
+ * Follows the status events on sms_events so synchronous sends learn their
+ * outcome even when a retry ran on another replica. Every replica reads every
+ * partition: each instance joins a consumer group of its own, so the group
+ * coordinator assigns it the whole topic, partitions added later included. It
+ * starts from the latest offset each time and commits no offsets, so the
+ * group is dropped by the broker once the instance leaves.
+ */
+@Component
+public class SmsStatusListener {
+    private final SendOutcomeRegistry outcomes;
+
+    @Autowired
+    public SmsStatusListener(SendOutcomeRegistry outcomes) {
+        this.outcomes = outcomes;
+    }
+
+    // The storage service's canary and loadgen write events without a type header
+    @KafkaListener(topics = "sms_events",
+            groupId = "${sms.send.wait.group-id-prefix:sms-sender-status}-#{T(java.util.UUID).randomUUID()}",
+            properties = {"auto.offset.reset=latest", "enable.auto.commit=false",
+                    "spring.json.value.default.type=com.example.demo.model.SmsEvent"})
+    public void onStatus(SmsEvent event) {
+        outcomes.record(event);
+    }
+}
@@ -0,0 +1,101 @@
+package com.example.demo.service;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.QuietHoursWindow;
+import com.example.demo.model.SmsRequest;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.LocalTime;
+import java.time.ZoneId;
+import java.time.ZonedDateTime;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * Per-tenant quiet hours, evaluated in the recipient's local time. The
+ * recipient's timezone comes from the country rule for the request's
+ * countryCode, falling back to the window's default timezone.
+ * Transactional messages are never deferred.
+ */
+@Service
+public class TenantQuietHoursService {
+    private static final String WINDOWS_KEY = "tenant_quiet_hours";
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final CountryRuleService countryRuleService;
+    private final Clock clock;
+
+    @Autowired
+    public TenantQuietHoursService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper, CountryRuleService countryRuleService) {
+        this(redisTemplate, objectMapper, countryRuleService, Clock.systemUTC());
+    }
+
+    public TenantQuietHoursService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            CountryRuleService countryRuleService, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.countryRuleService = countryRuleService;
+        this.clock = clock;
+    }
+
+    public QuietHoursWindow getWindow(String tenantId) {
+        Object json = redisTemplate.opsForHash().get(WINDOWS_KEY, tenantId);
+        if (json == null) {
+            return null;
+        }
+        try {
+            return objectMapper.readValue(json.toString(), QuietHoursWindow.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode quiet hours", e);
+        }
+    }
+
+    public QuietHoursWindow saveWindow(String tenantId, QuietHoursWindow window) {
+        ZoneId.of(window.getDefaultTimezone()); // throws DateTimeException for unknown zones
+        try {
+            redisTemplate.opsForHash().put(WINDOWS_KEY, tenantId, objectMapper.writeValueAsString(window));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode quiet hours", e);
+        }
+        return window;
+    }
+
+    public boolean deleteWindow(String tenantId) {
+        Long removed = redisTemplate.opsForHash().delete(WINDOWS_KEY, tenantId);
+        return removed != null && removed > 0;
+    }
+
+    /**
+     * Returns a deferral to the end of the tenant's quiet hours, or null if the
+     * request may be sent now.
+     */
+    public ComplianceDecision evaluate(SmsRequest request) {
+        if (!request.isPromotional() || request.getTenantId() == null) {
+            return null;
+        }
+        QuietHoursWindow window = getWindow(request.getTenantId());
+        if (window == null) {
+            return null;
+        }
+        ZonedDateTime localNow = ZonedDateTime.ofInstant(clock.instant(), recipientZone(request, window));
+        Instant releaseAt = QuietHours.endOfWindow(LocalTime.parse(window.getStart()), LocalTime.parse(window.getEnd()), localNow);
+        if (releaseAt == null) {
+            return null;
+        }
+        return ComplianceDecision.defer("Quiet hours in effect for tenant " + request.getTenantId(), releaseAt);
+    }
+
+    private ZoneId recipientZone(SmsRequest request, QuietHoursWindow window) {
+        if (request.getCountryCode() != null) {
+            CountryRule rule = countryRuleService.getRule(request.getCountryCode());
+            if (rule != null && rule.getTimezone() != null) {
+                return ZoneId.of(rule.getTimezone());
+            }
+        }
+        return ZoneId.of(window.getDefaultTimezone());
+    }
+}
@@ -0,0 +1,15 @@
+package com.example.demo.service;
+
+/**
+ * Thrown by a provider client when a send failed in a way that may succeed if
+ * retried later (5xx responses, timeouts). Other provider errors are final.
+ */
+public class TransientProviderException extends RuntimeException {
+    public TransientProviderException(String message) {
+        super(message);
+    }
+
+    public TransientProviderException(String message, Throwable cause) {
+        super(message, cause);
+    }
+}
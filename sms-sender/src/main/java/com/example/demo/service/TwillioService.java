@@ -1,12 +1,20 @@
 package com.example.demo.service;
+import java.util.UUID;
 import org.springframework.stereotype.Service;
 
 @Service
 public class TwillioService{
+    public static final String PROVIDER_NAME = "twilio";
+
     public TwillioService(){}
-    public void sendSms(String phoneNumber, String message){
+    /**
+     * Sends an SMS and returns the provider's message ID (Twilio message SID).
+     * Throws TransientProviderException for failures worth retrying (5xx, timeouts).
+     */
+    public String sendSms(String phoneNumber, String message){
         // Logic to send SMS via Twilio API would go here
         //currently it deterministically sends a succesful message
         System.out.println("Sending SMS to " + phoneNumber + ": " + message);
+        return "SM" + UUID.randomUUID().toString().replace("-", "");
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,142 @@
+package com.example.demo.service;
+
+import com.example.demo.model.PreferenceChangeEvent;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.UserPreferences;
+import com.fasterxml.jackson.core.JsonProcessingException;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import java.util.regex.Pattern;
+import org.springframework.beans.factory.annotation.Autowired;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.kafka.KafkaException;
+import org.springframework.kafka.core.KafkaTemplate;
+import org.springframework.stereotype.Service;
+
+/**
+ * User preferences, kept in Redis per user ID (the phone number, as in the
+ * storage service's /v1/user/{id} routes). Sends of a category the user
+ * turned off for SMS are rejected, and every change is published to
+ * sms_preference_changes for downstream systems.
+ */
+@Service
+public class UserPreferenceService {
+    public static final String TOPIC = "sms_preference_changes";
+
+    private static final String PREFERENCES_KEY = "user_preferences";
+    private static final long SEND_TIMEOUT_SECONDS = 5;
+    private static final Pattern CHANNEL = Pattern.compile("^[a-z_]{1,32}$");
+
+    private final StringRedisTemplate redisTemplate;
+    private final ObjectMapper objectMapper;
+    private final KafkaTemplate<String, PreferenceChangeEvent> kafkaTemplate;
+    private final Clock clock;
+
+    @Autowired
+    public UserPreferenceService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            KafkaTemplate<String, PreferenceChangeEvent> kafkaTemplate) {
+        this(redisTemplate, objectMapper, kafkaTemplate, Clock.systemUTC());
+    }
+
+    public UserPreferenceService(StringRedisTemplate redisTemplate, ObjectMapper objectMapper,
+            KafkaTemplate<String, PreferenceChangeEvent> kafkaTemplate, Clock clock) {
+        this.redisTemplate = redisTemplate;
+        this.objectMapper = objectMapper;
+        this.kafkaTemplate = kafkaTemplate;
+        this.clock = clock;
+    }
+
+    /**
+     * Returns the user's preferences; a user without any consents to everything.
+     */
+    public UserPreferences getPreferences(String userId) {
+        Object json = redisTemplate.opsForHash().get(PREFERENCES_KEY, userId);
+        if (json == null) {
+            return new UserPreferences();
+        }
+        try {
+            return objectMapper.readValue(json.toString(), UserPreferences.class);
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to decode user preferences", e);
+        }
+    }
+
+    /**
+     * Replaces the user's preferences and publishes the change. Throws
+     * IllegalArgumentException for malformed channels or unknown categories,
+     * and KafkaException if the change was stored but could not be published,
+     * in which case saving it again publishes it.
+     */
+    public UserPreferences savePreferences(String userId, UserPreferences preferences) throws KafkaException {
+        validate(preferences.getConsent());
+        UserPreferences previous = getPreferences(userId);
+        UserPreferences stored = new UserPreferences(preferences.getConsent(), clock.instant().toString());
+        try {
+            redisTemplate.opsForHash().put(PREFERENCES_KEY, userId, objectMapper.writeValueAsString(stored));
+        } catch (JsonProcessingException e) {
+            throw new IllegalStateException("Failed to encode user preferences", e);
+        }
+        publish(new PreferenceChangeEvent(userId, previous.getConsent(), stored.getConsent(), stored.getUpdatedAt()));
+        return stored;
+    }
+
+    /**
+     * Sets the user's consent to one category on one channel, keeping the
+     * rest of their preferences, and publishes the change like savePreferences.
+     */
+    public UserPreferences setConsent(String userId, String channel, String category, boolean allowed) throws KafkaException {
+        Map<String, Map<String, Boolean>> consent = new HashMap<>(getPreferences(userId).getConsent());
+        Map<String, Boolean> categories = consent.get(channel) == null ? new HashMap<>() : new HashMap<>(consent.get(channel));
+        categories.put(category, allowed);
+        consent.put(channel, categories);
+        return savePreferences(userId, new UserPreferences(consent, null));
+    }
+
+    /**
+     * Returns a rejection if the user turned the request's category off for
+     * SMS, or null if they consent to it.
+     */
+    public ComplianceDecision evaluate(SmsRequest request) {
+        String category = request.isPromotional() ? "promotional" : "transactional";
+        if (getPreferences(request.getPhoneNumber()).allows(UserPreferences.CHANNEL_SMS, category)) {
+            return null;
+        }
+        return ComplianceDecision.reject("Recipient has opted out of " + category + " SMS");
+    }
+
+    private static void validate(Map<String, Map<String, Boolean>> consent) {
+        for (Map.Entry<String, Map<String, Boolean>> channel : consent.entrySet()) {
+            if (!CHANNEL.matcher(channel.getKey()).matches()) {
+                throw new IllegalArgumentException("Channel must be lowercase letters and underscores: " + channel.getKey());
+            }
+            Map<String, Boolean> categories = channel.getValue() == null ? new HashMap<>() : channel.getValue();
+            for (Map.Entry<String, Boolean> category : categories.entrySet()) {
+                if (!"transactional".equals(category.getKey()) && !"promotional".equals(category.getKey())) {
+                    throw new IllegalArgumentException("Category must be transactional or promotional: " + category.getKey());
+                }
+                if (category.getValue() == null) {
+                    throw new IllegalArgumentException("Consent must be true or false: " + channel.getKey() + "." + category.getKey());
+                }
+            }
+        }
+    }
+
+    // Keyed by user so each user's changes stay in order
+    private void publish(PreferenceChangeEvent event) throws KafkaException {
+        try {
+            kafkaTemplate.send(TOPIC, event.getUserId(), event).get(SEND_TIMEOUT_SECONDS, TimeUnit.SECONDS);
+        } catch (ExecutionException e) {
+            throw new KafkaException("Failed to publish preference change to Kafka", e.getCause());
+        } catch (TimeoutException e) {
+            throw new KafkaException("Timeout while publishing preference change to Kafka", e);
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+            throw new KafkaException("Interrupted while publishing preference change to Kafka", e);
+        }
+    }
+}
@@ -0,0 +1,231 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.times;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.AbuseProperties;
+import com.example.demo.model.BlockedNumber;
+import com.example.demo.service.AbuseDetectionService;
+import com.example.demo.service.BlacklistCache;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Duration;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.SetOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.data.redis.core.ValueOperations;
+
+/**
+ * Unit tests for AbuseDetectionService.
+ * 
+ * Testing Strategy:
+ * - Numbers in a blocked prefix range are rejected, longest prefix winning
+ * - Promotional sends are counted per number per window; crossing the threshold
+ *   blacklists the number for the block duration
+ * - Prefixes are cached between sends and seeded only the first time they are configured
+ * - Unblocking lifts the blacklist entry and resets the counter
+ * 
+ * The clock is fixed at 2024-03-15T12:00:00Z with a one-hour window, so the
+ * volume key for +1234567890 is "abuse:volume:+1234567890:475140".
+ */
+@ExtendWith(MockitoExtension.class)
+public class AbuseDetectionServiceTest {
+
+    private static final String VOLUME_KEY = "abuse:volume:+1234567890:475140";
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private ValueOperations<String, String> valueOps;
+
+    @Mock
+    private SetOperations<String, String> setOps;
+
+    @Mock
+    private BlacklistCache blacklist;
+
+    private final Map<Object, Object> prefixes = new HashMap<>();
+    private AbuseProperties properties;
+    private AbuseDetectionService abuseDetection;
+
+    @BeforeEach
+    public void setUp() {
+        properties = new AbuseProperties();
+        properties.setVolumeThreshold(3);
+        properties.setVolumeWindow(Duration.ofHours(1));
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T12:00:00Z"), ZoneOffset.UTC);
+        abuseDetection = new AbuseDetectionService(redisTemplate, new ObjectMapper(), blacklist, properties, clock);
+
+        // Not every test reaches the volume counter or the prefix hash
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        lenient().when(redisTemplate.opsForSet()).thenReturn(setOps);
+        lenient().when(hashOps.entries("blocked_prefixes")).thenReturn(prefixes);
+    }
+
+    /**
+     * Tests that a premium-rate number is rejected without being counted.
+     */
+    @Test
+    public void testCheck_BlockedPrefix() {
+        prefixes.put("+1900", "premium_rate");
+        prefixes.put("+19005", "fraud");
+
+        String reason = abuseDetection.check("+19005551234");
+
+        assertEquals("Destination +19005 is blocked (fraud)", reason);
+        verify(valueOps, never()).increment(anyString());
+    }
+
+    /**
+     * Tests that prefixes are read from Redis once, not on every send, and
+     * re-read after an admin change.
+     */
+    @Test
+    public void testCheck_PrefixesCached() {
+        prefixes.put("+1900", "premium_rate");
+
+        abuseDetection.check("+19005551234");
+        abuseDetection.check("+1234567890");
+        verify(hashOps, times(1)).entries("blocked_prefixes");
+
+        abuseDetection.deletePrefix("+1900");
+        abuseDetection.check("+19005551234");
+        verify(hashOps, times(2)).entries("blocked_prefixes");
+    }
+
+    /**
+     * Tests that sends within the threshold are allowed and the counter expires with the window.
+     */
+    @Test
+    public void testRecordSend_WithinThreshold() {
+        when(valueOps.increment(VOLUME_KEY)).thenReturn(1L);
+
+        assertNull(abuseDetection.recordSend("+1234567890"));
+
+        verify(redisTemplate).expire(VOLUME_KEY, Duration.ofHours(1));
+        verify(blacklist, never()).addToBlacklist(anyString(), any());
+    }
+
+    /**
+     * Tests that crossing the threshold blacklists the number for the block
+     * duration and records it for review.
+     */
+    @Test
+    public void testRecordSend_VolumeThresholdBlocksNumber() {
+        when(valueOps.increment(VOLUME_KEY)).thenReturn(4L);
+
+        String reason = abuseDetection.recordSend("+1234567890");
+
+        assertEquals("Destination blocked: More than 3 messages within 60 minutes", reason);
+        verify(blacklist).addToBlacklist("+1234567890", Duration.ofHours(24));
+        verify(blacklist, never()).addToBlacklist(anyString());
+        verify(hashOps).put(eq("abuse_blocked"), eq("+1234567890"), anyString());
+    }
+
+    /**
+     * Tests that a zero threshold disables volume tracking entirely.
+     */
+    @Test
+    public void testRecordSend_VolumeTrackingDisabled() {
+        properties.setVolumeThreshold(0);
+
+        assertNull(abuseDetection.recordSend("+1234567890"));
+
+        verify(valueOps, never()).increment(anyString());
+    }
+
+    /**
+     * Tests that peekSend reports a send that would cross the threshold without counting or blocking.
+     */
+    @Test
+    public void testPeek_DoesNotCountOrBlock() {
+        when(valueOps.get(VOLUME_KEY)).thenReturn("3");
+
+        String reason = abuseDetection.peekSend("+1234567890");
+
+        assertEquals("Destination blocked: More than 3 messages within 60 minutes", reason);
+        verify(valueOps, never()).increment(anyString());
+        verify(blacklist, never()).addToBlacklist(anyString(), any());
+    }
+
+    /**
+     * Tests that expired blocks are left out of the listing and their records removed.
+     */
+    @Test
+    public void testListBlocked_DropsExpiredBlocks() {
+        Map<Object, Object> blocked = new HashMap<>();
+        blocked.put("+1111111111", "{\"phoneNumber\":\"+1111111111\",\"expiresAtEpochMs\":1710500000000}");
+        blocked.put("+2222222222", "{\"phoneNumber\":\"+2222222222\",\"expiresAtEpochMs\":1710600000000}");
+        when(hashOps.values("abuse_blocked")).thenReturn(new ArrayList<>(blocked.values()));
+
+        List<BlockedNumber> listed = abuseDetection.listBlocked();
+
+        assertEquals(1, listed.size());
+        assertEquals("+2222222222", listed.get(0).getPhoneNumber());
+        verify(hashOps).delete("abuse_blocked", "+1111111111");
+    }
+
+    /**
+     * Tests that a configured prefix is seeded once, so one an admin deleted stays deleted.
+     */
+    @Test
+    public void testSeedPrefix_OnlyFirstTime() {
+        when(setOps.add("blocked_prefixes:seeded", "+1900")).thenReturn(1L).thenReturn(0L);
+
+        abuseDetection.seedPrefix("+1900", "premium_rate");
+        abuseDetection.seedPrefix("+1900", "premium_rate");
+
+        verify(hashOps, times(1)).putIfAbsent("blocked_prefixes", "+1900", "premium_rate");
+    }
+
+    /**
+     * Tests that unblocking removes the blacklist entry and resets the counter.
+     */
+    @Test
+    public void testUnblock() {
+        when(hashOps.delete("abuse_blocked", "+1234567890")).thenReturn(1L);
+
+        assertTrue(abuseDetection.unblock("+1234567890"));
+
+        verify(blacklist).removeFromBlacklist("+1234567890");
+        verify(redisTemplate).delete(VOLUME_KEY);
+    }
+
+    /**
+     * Tests that numbers not blocked by abuse detection (e.g. seeded blacklist entries) are left alone.
+     */
+    @Test
+    public void testUnblock_NotBlockedByAbuseDetection() {
+        when(hashOps.delete("abuse_blocked", "+1111111111")).thenReturn(0L);
+
+        assertFalse(abuseDetection.unblock("+1111111111"));
+
+        verify(blacklist, never()).removeFromBlacklist(anyString());
+    }
+}
@@ -0,0 +1,150 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertSame;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+
+import com.example.demo.model.CampaignExperiment;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.TemplateVariant;
+import com.example.demo.service.CampaignExperimentService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.util.Arrays;
+import java.util.HashMap;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for CampaignExperimentService.
+ *
+ * Testing Strategy:
+ * - A recipient always gets the same variant of a campaign
+ * - Recipients are split between variants in proportion to their weights
+ * - A variant replaces the template and message only where it sets them
+ * - Requests outside a campaign with an A/B test are left alone
+ *
+ * Campaign "spring-sale" tests a new message against the current one, 3:1.
+ */
+@ExtendWith(MockitoExtension.class)
+public class CampaignExperimentServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private CampaignExperimentService experimentService;
+    private CampaignExperiment experiment;
+
+    @BeforeEach
+    public void setUp() throws Exception {
+        experimentService = new CampaignExperimentService(redisTemplate, objectMapper);
+        experiment = new CampaignExperiment(Arrays.asList(
+                new TemplateVariant("new-copy", "tpl-2", "Spring sale: 20% off today", 3),
+                new TemplateVariant("control", null, null, 1)));
+
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(hashOps.get("campaign_experiments", "spring-sale"))
+                .thenReturn(objectMapper.writeValueAsString(experiment));
+    }
+
+    private static SmsRequest request(String phoneNumber, String campaignId) {
+        SmsRequest request = new SmsRequest();
+        request.setPhoneNumber(phoneNumber);
+        request.setMessage("Spring sale starts now");
+        request.setTenantId("default");
+        request.setCampaignId(campaignId);
+        request.setTemplateId("tpl-1");
+        return request;
+    }
+
+    @Test
+    public void testSameRecipientSameVariant() {
+        TemplateVariant first = CampaignExperimentService.variantFor("spring-sale", "+1234567890", experiment);
+
+        for (int i = 0; i < 5; i++) {
+            assertSame(first, CampaignExperimentService.variantFor("spring-sale", "+1234567890", experiment));
+        }
+    }
+
+    @Test
+    public void testRecipientsSplitByWeight() {
+        Map<String, Integer> counts = new HashMap<>();
+        for (int i = 0; i < 4000; i++) {
+            String name = CampaignExperimentService.variantFor("spring-sale", "+1555" + i, experiment).getName();
+            counts.merge(name, 1, Integer::sum);
+        }
+
+        // 3000 and 1000 expected; the bounds are several standard deviations wide
+        int newCopy = counts.getOrDefault("new-copy", 0);
+        assertEquals(4000, newCopy + counts.getOrDefault("control", 0));
+        assertTrue(newCopy > 2850 && newCopy < 3150, "new-copy got " + newCopy);
+    }
+
+    @Test
+    public void testAssignAppliesVariant() {
+        // Find a recipient of each variant, as assignment is by hash
+        SmsRequest newCopy = null;
+        SmsRequest control = null;
+        for (int i = 0; newCopy == null || control == null; i++) {
+            SmsRequest request = request("+1555" + i, "spring-sale");
+            String name = experimentService.assign(request).getName();
+            if ("new-copy".equals(name)) {
+                newCopy = request;
+            } else {
+                control = request;
+            }
+        }
+
+        assertEquals("new-copy", newCopy.getVariant());
+        assertEquals("tpl-2", newCopy.getTemplateId());
+        assertEquals("Spring sale: 20% off today", newCopy.getMessage());
+
+        // The control arm keeps the request's own template and message
+        assertEquals("control", control.getVariant());
+        assertEquals("tpl-1", control.getTemplateId());
+        assertEquals("Spring sale starts now", control.getMessage());
+    }
+
+    @Test
+    public void testNoExperimentLeavesRequestAlone() {
+        SmsRequest request = request("+1234567890", "winter-sale");
+
+        assertNull(experimentService.assign(request));
+        assertNull(request.getVariant());
+        assertEquals("tpl-1", request.getTemplateId());
+        assertEquals("Spring sale starts now", request.getMessage());
+    }
+
+    @Test
+    public void testNoCampaignSkipsLookup() {
+        SmsRequest request = request("+1234567890", null);
+
+        assertNull(experimentService.assign(request));
+        verify(hashOps, never()).get(anyString(), anyString());
+    }
+
+    @Test
+    public void testDuplicateVariantNamesRejected() {
+        CampaignExperiment duplicate = new CampaignExperiment(Arrays.asList(
+                new TemplateVariant("a", "tpl-1", null, 1),
+                new TemplateVariant("a", "tpl-2", null, 1)));
+
+        assertThrows(IllegalArgumentException.class, () -> experimentService.saveExperiment("spring-sale", duplicate));
+        verify(hashOps, never()).put(anyString(), anyString(), anyString());
+    }
+}
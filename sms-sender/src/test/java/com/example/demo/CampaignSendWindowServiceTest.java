@@ -0,0 +1,99 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.CampaignSendWindow;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.CampaignSendWindowService;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.RecipientTimezoneService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneId;
+import java.time.ZoneOffset;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for CampaignSendWindowService.
+ *
+ * Testing Strategy:
+ * - Sends inside the window in the recipient's local time go out now
+ * - Sends outside it are deferred to its next start in the recipient's timezone
+ * - The window's default timezone is used when the recipient's is unknown
+ *
+ * The clock is fixed at 2024-03-15T17:00:00Z: 22:30 in Asia/Kolkata, 21:00 in
+ * Asia/Dubai, 13:00 in America/New_York. Campaign "spring-sale" sends from
+ * 10:00 to 22:00.
+ */
+@ExtendWith(MockitoExtension.class)
+public class CampaignSendWindowServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private RecipientTimezoneService timezoneService;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private CampaignSendWindowService sendWindowService;
+
+    @BeforeEach
+    public void setUp() throws Exception {
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T17:00:00Z"), ZoneOffset.UTC);
+        sendWindowService = new CampaignSendWindowService(redisTemplate, objectMapper, timezoneService, clock);
+
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(hashOps.get("campaign_send_windows", "spring-sale"))
+                .thenReturn(objectMapper.writeValueAsString(new CampaignSendWindow("10:00", "22:00", "America/New_York")));
+    }
+
+    private static SmsRequest request(String phoneNumber) {
+        SmsRequest request = new SmsRequest(phoneNumber, "Spring sale starts now");
+        request.setCampaignId("spring-sale");
+        return request;
+    }
+
+    @Test
+    public void testOutsideWindowDeferredToLocalStart() {
+        SmsRequest request = request("+919876543210");
+        when(timezoneService.resolve(request)).thenReturn(ZoneId.of("Asia/Kolkata"));
+
+        ComplianceDecision decision = sendWindowService.evaluate(request);
+
+        // 22:30 IST is past the window; it opens again at 10:00 IST tomorrow
+        assertEquals(ComplianceDecision.Action.DEFER, decision.getAction());
+        assertEquals(Instant.parse("2024-03-16T04:30:00Z"), decision.getReleaseAt());
+    }
+
+    @Test
+    public void testInsideWindowSendsNow() {
+        SmsRequest request = request("+971501234567");
+        when(timezoneService.resolve(request)).thenReturn(ZoneId.of("Asia/Dubai"));
+
+        assertNull(sendWindowService.evaluate(request));
+    }
+
+    @Test
+    public void testFallsBackToDefaultTimezone() {
+        // 13:00 in New York is inside the window
+        assertNull(sendWindowService.evaluate(request("+13125550100")));
+    }
+
+    @Test
+    public void testNoCampaignNoWindow() {
+        assertNull(sendWindowService.evaluate(new SmsRequest("+919876543210", "Hi")));
+    }
+}
@@ -0,0 +1,148 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.CountryRuleService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.util.Arrays;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for CountryRuleService.
+ * 
+ * Testing Strategy:
+ * - Sender ID restrictions reject unregistered senders
+ * - Promotional bans reject promotional traffic only
+ * - Quiet hours (crossing midnight, in recipient local time) defer or reject promotional traffic
+ * - Requests without a country code, or countries without rules, are allowed
+ * 
+ * The clock is fixed at 2024-03-15T17:00:00Z, which is 22:30 in Asia/Kolkata.
+ * Rules are stored as JSON in the Redis hash "country_rules".
+ */
+@ExtendWith(MockitoExtension.class)
+public class CountryRuleServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private CountryRuleService ruleService;
+    private SmsRequest request;
+
+    @BeforeEach
+    public void setUp() {
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T17:00:00Z"), ZoneOffset.UTC);
+        ruleService = new CountryRuleService(redisTemplate, objectMapper, clock);
+        request = new SmsRequest("+919876543210", "Big sale today!");
+        request.setCountryCode("IN");
+    }
+
+    private void givenRule(CountryRule rule) throws Exception {
+        when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        when(hashOps.get("country_rules", "IN")).thenReturn(objectMapper.writeValueAsString(rule));
+    }
+
+    private CountryRule indiaRule() {
+        CountryRule rule = new CountryRule();
+        rule.setCountryCode("IN");
+        rule.setTimezone("Asia/Kolkata");
+        rule.setQuietHoursStart("21:00");
+        rule.setQuietHoursEnd("09:00");
+        return rule;
+    }
+
+    /**
+     * Tests that promotional messages during quiet hours are deferred to the window's end.
+     */
+    @Test
+    public void testEvaluate_QuietHoursDefersPromotional() throws Exception {
+        givenRule(indiaRule());
+        request.setCategory("promotional");
+
+        ComplianceDecision decision = ruleService.evaluate(request);
+
+        assertEquals(ComplianceDecision.Action.DEFER, decision.getAction());
+        // 09:00 IST the next morning
+        assertEquals(Instant.parse("2024-03-16T03:30:00Z"), decision.getReleaseAt());
+    }
+
+    /**
+     * Tests that transactional messages are not held by quiet hours.
+     */
+    @Test
+    public void testEvaluate_QuietHoursIgnoreTransactional() throws Exception {
+        givenRule(indiaRule());
+        request.setCategory("transactional");
+
+        assertNull(ruleService.evaluate(request));
+    }
+
+    /**
+     * Tests that a quiet-hours action of "reject" rejects instead of deferring.
+     */
+    @Test
+    public void testEvaluate_QuietHoursReject() throws Exception {
+        CountryRule rule = indiaRule();
+        rule.setQuietHoursAction("reject");
+        givenRule(rule);
+        request.setCategory("promotional");
+
+        assertEquals(ComplianceDecision.Action.REJECT, ruleService.evaluate(request).getAction());
+    }
+
+    /**
+     * Tests that only registered sender IDs may send to the country.
+     */
+    @Test
+    public void testEvaluate_SenderIdRestriction() throws Exception {
+        CountryRule rule = indiaRule();
+        rule.setAllowedSenderIds(Arrays.asList("MEESHO"));
+        givenRule(rule);
+        request.setSenderId("SPAMCO");
+
+        ComplianceDecision decision = ruleService.evaluate(request);
+
+        assertEquals(ComplianceDecision.Action.REJECT, decision.getAction());
+        assertEquals("Sender ID not allowed for IN", decision.getReason());
+    }
+
+    /**
+     * Tests that a promotional ban rejects promotional messages.
+     */
+    @Test
+    public void testEvaluate_PromotionalBan() throws Exception {
+        CountryRule rule = indiaRule();
+        rule.setPromotionalBanned(true);
+        givenRule(rule);
+        request.setCategory("promotional");
+
+        assertEquals(ComplianceDecision.Action.REJECT, ruleService.evaluate(request).getAction());
+    }
+
+    /**
+     * Tests that requests without a country code skip rule evaluation entirely.
+     */
+    @Test
+    public void testEvaluate_NoCountryCode() {
+        request.setCountryCode(null);
+
+        assertNull(ruleService.evaluate(request));
+    }
+}
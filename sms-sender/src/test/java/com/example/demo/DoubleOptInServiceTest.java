@@ -0,0 +1,144 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyBoolean;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.OptInProperties;
+import com.example.demo.model.InboundMessage;
+import com.example.demo.model.OptIn;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.DoubleOptInService;
+import com.example.demo.service.SmsService;
+import com.example.demo.service.UserPreferenceService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Duration;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.data.redis.core.ValueOperations;
+
+/**
+ * Unit tests for DoubleOptInService.
+ *
+ * Testing Strategy:
+ * - Requesting an opt-in stores it pending and sends a transactional
+ *   confirmation SMS carrying the code
+ * - An opt-in whose SMS was not sent is rolled back
+ * - A reply with the code confirms the opt-in and turns the category on in
+ *   the user's preferences; other replies confirm nothing
+ */
+@ExtendWith(MockitoExtension.class)
+public class DoubleOptInServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private ValueOperations<String, String> valueOps;
+
+    @Mock
+    private SmsService smsService;
+
+    @Mock
+    private UserPreferenceService preferences;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private DoubleOptInService optInService;
+
+    @BeforeEach
+    public void setUp() {
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T10:00:00Z"), ZoneOffset.UTC);
+        optInService = new DoubleOptInService(redisTemplate, objectMapper, smsService, preferences,
+                new OptInProperties(), clock);
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(redisTemplate.opsForValue()).thenReturn(valueOps);
+    }
+
+    private static InboundMessage reply(String message) {
+        InboundMessage reply = new InboundMessage();
+        reply.setPhoneNumber("+1234567890");
+        reply.setMessage(message);
+        return reply;
+    }
+
+    private String pending() throws Exception {
+        OptIn optIn = new OptIn("+1234567890", "promotional", OptIn.PENDING_CONFIRMATION);
+        return objectMapper.writeValueAsString(optIn);
+    }
+
+    @Test
+    public void testRequestSendsConfirmation() {
+        when(smsService.sendSms(any(SmsRequest.class))).thenReturn("SMS sent to +1234567890");
+
+        OptIn optIn = optInService.requestOptIn("+1234567890", "promotional", "default");
+
+        assertEquals(OptIn.PENDING_CONFIRMATION, optIn.getStatus());
+        assertEquals("2024-03-16T10:00:00Z", optIn.getExpiresAt());
+        ArgumentCaptor<String> code = ArgumentCaptor.forClass(String.class);
+        verify(valueOps).set(eq("opt_in_code:+1234567890:promotional"), code.capture(), eq(Duration.ofHours(24)));
+        ArgumentCaptor<SmsRequest> sms = ArgumentCaptor.forClass(SmsRequest.class);
+        verify(smsService).sendSms(sms.capture());
+        assertEquals("transactional", sms.getValue().getCategory());
+        assertTrue(sms.getValue().getMessage().startsWith("Reply YES " + code.getValue() + " "));
+    }
+
+    @Test
+    public void testRequestRolledBackWhenNotSent() {
+        when(smsService.sendSms(any(SmsRequest.class))).thenReturn("Failed: Phone number is blacklisted");
+
+        assertThrows(IllegalStateException.class,
+                () -> optInService.requestOptIn("+1234567890", "promotional", "default"));
+        verify(redisTemplate).delete("opt_in_code:+1234567890:promotional");
+        verify(hashOps).delete("opt_ins", "+1234567890:promotional");
+    }
+
+    @Test
+    public void testUnknownCategory() {
+        assertThrows(IllegalArgumentException.class,
+                () -> optInService.requestOptIn("+1234567890", "marketing", "default"));
+        verify(smsService, never()).sendSms(any(SmsRequest.class));
+    }
+
+    @Test
+    public void testReplyConfirms() throws Exception {
+        when(valueOps.get("opt_in_code:+1234567890:promotional")).thenReturn("482913");
+        when(hashOps.get("opt_ins", "+1234567890:promotional")).thenReturn(pending());
+
+        OptIn optIn = optInService.handleReply(reply(" yes 482913 "));
+
+        assertEquals(OptIn.OPTED_IN, optIn.getStatus());
+        assertEquals("2024-03-15T10:00:00Z", optIn.getConfirmedAt());
+        verify(redisTemplate).delete("opt_in_code:+1234567890:promotional");
+        verify(preferences).setConsent("+1234567890", "sms", "promotional", true);
+    }
+
+    @Test
+    public void testReplyWithWrongCode() {
+        when(valueOps.get(anyString())).thenReturn("482913");
+
+        assertNull(optInService.handleReply(reply("YES 111111")));
+        assertNull(optInService.handleReply(reply("NO 482913")));
+        verify(preferences, never()).setConsent(anyString(), anyString(), anyString(), anyBoolean());
+    }
+}
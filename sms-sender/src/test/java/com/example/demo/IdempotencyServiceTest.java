@@ -0,0 +1,169 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertNotEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.IdempotencyProperties;
+import com.example.demo.model.IdempotencyRecord;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.SmsResponse;
+import com.example.demo.service.IdempotencyService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Duration;
+import java.util.HashMap;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.data.redis.core.ValueOperations;
+
+/**
+ * Unit tests for IdempotencyService.
+ *
+ * Testing Strategy:
+ * - A free key is claimed with the short in-progress expiry
+ * - A taken key returns the stored record, in progress or completed
+ * - Completed responses are kept for the full TTL; released keys are deleted
+ * - Fingerprints ignore metadata order and the tenant, but not the body
+ *
+ * Redis Key Format: "idempotency:{tenant}:{key}"
+ */
+@ExtendWith(MockitoExtension.class)
+public class IdempotencyServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private ValueOperations<String, String> valueOps;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private IdempotencyProperties properties;
+    private IdempotencyService idempotency;
+
+    @BeforeEach
+    void setUp() {
+        properties = new IdempotencyProperties();
+        idempotency = new IdempotencyService(redisTemplate, objectMapper, properties);
+    }
+
+    /**
+     * Tests that the first request claims the key until the in-progress timeout.
+     */
+    @Test
+    void testClaim_FreeKey() {
+        when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        when(valueOps.setIfAbsent(eq("idempotency:acme:order-1"), anyString(), eq(Duration.ofMinutes(2)))).thenReturn(true);
+
+        assertNull(idempotency.claim("acme", "order-1", "abc"));
+    }
+
+    /**
+     * Tests that a repeat sees the completed record with its original response.
+     */
+    @Test
+    void testClaim_ReturnsCompletedRecord() throws Exception {
+        when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        when(valueOps.setIfAbsent(eq("idempotency:acme:order-1"), anyString(), eq(Duration.ofMinutes(2)))).thenReturn(false);
+        String stored = objectMapper.writeValueAsString(new IdempotencyRecord("abc", 200, new SmsResponse("SMS sent to +1234567890")));
+        when(valueOps.get("idempotency:acme:order-1")).thenReturn(stored);
+
+        IdempotencyRecord record = idempotency.claim("acme", "order-1", "abc");
+
+        assertFalse(record.isInProgress());
+        assertEquals(200, record.getStatus());
+        assertEquals("abc", record.getFingerprint());
+        assertEquals("SMS sent to +1234567890", record.getResponse().getResult());
+    }
+
+    /**
+     * Tests that a key claimed by a send still running is reported in progress.
+     */
+    @Test
+    void testClaim_InProgress() throws Exception {
+        when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        when(valueOps.setIfAbsent(eq("idempotency:acme:order-1"), anyString(), eq(Duration.ofMinutes(2)))).thenReturn(false);
+        when(valueOps.get("idempotency:acme:order-1"))
+                .thenReturn(objectMapper.writeValueAsString(new IdempotencyRecord("abc", 0, null)));
+
+        assertTrue(idempotency.claim("acme", "order-1", "abc").isInProgress());
+    }
+
+    /**
+     * Tests that a record expiring between the two calls lets the retry claim it.
+     */
+    @Test
+    void testClaim_RecordExpiredMeanwhile() {
+        when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        when(valueOps.setIfAbsent(eq("idempotency:acme:order-1"), anyString(), eq(Duration.ofMinutes(2))))
+                .thenReturn(false, true);
+        when(valueOps.get("idempotency:acme:order-1")).thenReturn(null);
+
+        assertNull(idempotency.claim("acme", "order-1", "abc"));
+    }
+
+    /**
+     * Tests that a completed response is stored for the full TTL.
+     */
+    @Test
+    void testComplete_StoresResponseForTtl() throws Exception {
+        when(redisTemplate.opsForValue()).thenReturn(valueOps);
+        properties.setTtl(Duration.ofHours(6));
+
+        idempotency.complete("acme", "order-1", "abc", 202, new SmsResponse("Deferred"));
+
+        ArgumentCaptor<String> json = ArgumentCaptor.forClass(String.class);
+        verify(valueOps).set(eq("idempotency:acme:order-1"), json.capture(), eq(Duration.ofHours(6)));
+        IdempotencyRecord record = objectMapper.readValue(json.getValue(), IdempotencyRecord.class);
+        assertEquals(202, record.getStatus());
+        assertEquals("Deferred", record.getResponse().getResult());
+    }
+
+    /**
+     * Tests that releasing a key deletes it.
+     */
+    @Test
+    void testRelease_DeletesKey() {
+        idempotency.release("acme", "order-1");
+
+        verify(redisTemplate).delete("idempotency:acme:order-1");
+    }
+
+    /**
+     * Tests that fingerprints depend on the body but not on metadata order or tenant.
+     */
+    @Test
+    void testFingerprint() {
+        Map<String, String> metadata = new LinkedHashMap<>();
+        metadata.put("order_id", "42");
+        metadata.put("merchant_id", "m-7");
+        SmsRequest first = new SmsRequest("+1234567890", "Hello");
+        first.setMetadata(metadata);
+        first.setTenantId("acme");
+
+        Map<String, String> reordered = new HashMap<>();
+        reordered.put("merchant_id", "m-7");
+        reordered.put("order_id", "42");
+        SmsRequest second = new SmsRequest("+1234567890", "Hello");
+        second.setMetadata(reordered);
+        second.setTenantId("globex");
+
+        assertEquals(idempotency.fingerprint(first), idempotency.fingerprint(second));
+        assertEquals(64, idempotency.fingerprint(first).length());
+
+        second.setMessage("Hello again");
+        assertNotEquals(idempotency.fingerprint(first), idempotency.fingerprint(second));
+    }
+}
@@ -0,0 +1,138 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyBoolean;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.Mockito.inOrder;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.InboundMessage;
+import com.example.demo.model.KeywordRule;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.KeywordRuleService;
+import com.example.demo.service.SmsService;
+import com.example.demo.service.UserPreferenceService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.InOrder;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for KeywordRuleService.
+ *
+ * Testing Strategy:
+ * - STOP replies before opting the sender out of every category; START opts
+ *   in before replying
+ * - Tenant rules override built-in ones and add custom keywords
+ * - Messages not starting with a keyword do nothing
+ */
+@ExtendWith(MockitoExtension.class)
+public class KeywordRuleServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private SmsService smsService;
+
+    @Mock
+    private UserPreferenceService preferences;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private KeywordRuleService ruleService;
+
+    @BeforeEach
+    public void setUp() {
+        ruleService = new KeywordRuleService(redisTemplate, objectMapper, smsService, preferences);
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(smsService.sendSms(any(SmsRequest.class))).thenReturn("SMS sent to +1234567890");
+    }
+
+    private static InboundMessage inbound(String message) {
+        InboundMessage inbound = new InboundMessage();
+        inbound.setPhoneNumber("+1234567890");
+        inbound.setMessage(message);
+        return inbound;
+    }
+
+    @Test
+    public void testStopRepliesThenOptsOut() {
+        KeywordRule rule = ruleService.handle(inbound("stop"), "acme");
+
+        assertEquals("STOP", rule.getKeyword());
+        InOrder order = inOrder(smsService, preferences);
+        order.verify(smsService).sendSms(any(SmsRequest.class));
+        order.verify(preferences).setConsent("+1234567890", "sms", "promotional", false);
+        order.verify(preferences).setConsent("+1234567890", "sms", "transactional", false);
+    }
+
+    @Test
+    public void testStartOptsInThenReplies() {
+        ruleService.handle(inbound("START"), "acme");
+
+        InOrder order = inOrder(smsService, preferences);
+        order.verify(preferences).setConsent("+1234567890", "sms", "promotional", true);
+        order.verify(preferences).setConsent("+1234567890", "sms", "transactional", true);
+        order.verify(smsService).sendSms(any(SmsRequest.class));
+    }
+
+    @Test
+    public void testTenantRuleOverridesBuiltIn() throws Exception {
+        KeywordRule custom = new KeywordRule("STOP", "Acme: unsubscribed from offers.", KeywordRule.ACTION_OPT_OUT);
+        custom.setCategory("promotional");
+        when(hashOps.get("keyword_rules:acme", "STOP")).thenReturn(objectMapper.writeValueAsString(custom));
+
+        ruleService.handle(inbound("STOP please"), "acme");
+
+        ArgumentCaptor<SmsRequest> reply = ArgumentCaptor.forClass(SmsRequest.class);
+        verify(smsService).sendSms(reply.capture());
+        assertEquals("Acme: unsubscribed from offers.", reply.getValue().getMessage());
+        assertEquals("transactional", reply.getValue().getCategory());
+        verify(preferences).setConsent("+1234567890", "sms", "promotional", false);
+        verify(preferences, never()).setConsent("+1234567890", "sms", "transactional", false);
+    }
+
+    @Test
+    public void testListMergesTenantRules() throws Exception {
+        Map<Object, Object> stored = new HashMap<>();
+        stored.put("PROMO", objectMapper.writeValueAsString(new KeywordRule("PROMO", "Today: 20% off", KeywordRule.ACTION_NONE)));
+        when(hashOps.entries("keyword_rules:acme")).thenReturn(stored);
+
+        List<KeywordRule> rules = ruleService.listRules("acme");
+
+        assertEquals(4, rules.size());
+        assertEquals("HELP", rules.get(0).getKeyword());
+        assertEquals("PROMO", rules.get(1).getKeyword());
+    }
+
+    @Test
+    public void testNoKeyword() {
+        assertNull(ruleService.handle(inbound("Thanks!"), "acme"));
+        verify(smsService, never()).sendSms(any(SmsRequest.class));
+        verify(preferences, never()).setConsent(anyString(), anyString(), anyString(), anyBoolean());
+    }
+
+    @Test
+    public void testMalformedKeyword() {
+        assertThrows(IllegalArgumentException.class,
+                () -> ruleService.saveRule("acme", "opt-out", new KeywordRule()));
+    }
+}
@@ -0,0 +1,160 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyLong;
+import static org.mockito.ArgumentMatchers.anyMap;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.times;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.LinkProperties;
+import com.example.demo.model.ShortLink;
+import com.example.demo.model.SmsEvent;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.LinkTrackingService;
+import com.example.demo.service.SmsEventProducer;
+import java.util.HashMap;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.SetOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for LinkTrackingService.
+ *
+ * Testing Strategy:
+ * - URLs in a message are replaced by short links under the base URL, leaving
+ *   trailing punctuation and existing short links alone
+ * - Nothing is rewritten while link tracking is disabled
+ * - The first click on a link is published as a "clicked" receipt, later ones
+ *   are only counted
+ */
+@ExtendWith(MockitoExtension.class)
+public class LinkTrackingServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private SetOperations<String, String> setOps;
+
+    @Mock
+    private SmsEventProducer eventProducer;
+
+    private LinkProperties properties;
+    private LinkTrackingService linkService;
+
+    @BeforeEach
+    public void setUp() {
+        properties = new LinkProperties();
+        properties.setEnabled(true);
+        properties.setBaseUrl("https://sms.example/r/");
+        linkService = new LinkTrackingService(redisTemplate, eventProducer, properties);
+
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(redisTemplate.opsForSet()).thenReturn(setOps);
+        lenient().when(hashOps.putIfAbsent(anyString(), eq("url"), anyString())).thenReturn(true);
+    }
+
+    private static SmsRequest request(String message) {
+        SmsRequest request = new SmsRequest();
+        request.setPhoneNumber("+1234567890");
+        request.setMessage(message);
+        request.setTenantId("default");
+        return request;
+    }
+
+    private static Map<Object, Object> storedLink(String providerMessageId) {
+        Map<Object, Object> fields = new HashMap<>();
+        fields.put("url", "https://shop.example/sale");
+        fields.put("phoneNumber", "+1234567890");
+        fields.put("sendId", "send-1");
+        if (providerMessageId != null) {
+            fields.put("providerMessageId", providerMessageId);
+        }
+        return fields;
+    }
+
+    @Test
+    public void testShortenReplacesUrls() {
+        SmsRequest request = request("Sale ends today: https://shop.example/sale?utm=sms. Details at http://shop.example/terms");
+
+        linkService.shorten(request, "send-1");
+
+        String message = request.getMessage();
+        assertTrue(message.matches("Sale ends today: https://sms\\.example/r/[A-Za-z0-9]{7}\\. "
+                + "Details at https://sms\\.example/r/[A-Za-z0-9]{7}"), message);
+        verify(hashOps).putIfAbsent(anyString(), eq("url"), eq("https://shop.example/sale?utm=sms"));
+        verify(hashOps).putIfAbsent(anyString(), eq("url"), eq("http://shop.example/terms"));
+        verify(setOps, times(2)).add(eq("link_send:send-1"), anyString());
+    }
+
+    @Test
+    public void testShortenKeepsShortLinks() {
+        SmsRequest request = request("Reminder: https://sms.example/r/AbC1234");
+
+        linkService.shorten(request, "send-1");
+
+        assertEquals("Reminder: https://sms.example/r/AbC1234", request.getMessage());
+        verify(hashOps, never()).putAll(anyString(), anyMap());
+    }
+
+    @Test
+    public void testShortenDisabled() {
+        properties.setEnabled(false);
+        SmsRequest request = request("Sale ends today: https://shop.example/sale");
+
+        linkService.shorten(request, "send-1");
+
+        assertEquals("Sale ends today: https://shop.example/sale", request.getMessage());
+    }
+
+    @Test
+    public void testFirstClickPublished() {
+        when(hashOps.entries("link:AbC1234")).thenReturn(storedLink("SM123"));
+        when(hashOps.increment("link:AbC1234", "clicks", 1)).thenReturn(1L);
+
+        ShortLink link = linkService.recordClick("AbC1234");
+
+        assertEquals("https://shop.example/sale", link.getUrl());
+        assertEquals(1, link.getClicks());
+        ArgumentCaptor<SmsEvent> event = ArgumentCaptor.forClass(SmsEvent.class);
+        verify(eventProducer).sendSmsEvent(event.capture());
+        assertEquals("clicked", event.getValue().getStatus());
+        assertEquals("SM123", event.getValue().getProviderMessageId());
+        assertEquals("+1234567890", event.getValue().getPhoneNumber());
+    }
+
+    @Test
+    public void testLaterClicksOnlyCounted() {
+        when(hashOps.entries("link:AbC1234")).thenReturn(storedLink("SM123"));
+        when(hashOps.increment("link:AbC1234", "clicks", 1)).thenReturn(2L);
+
+        assertEquals(2, linkService.recordClick("AbC1234").getClicks());
+        verify(eventProducer, never()).sendSmsEvent(any());
+    }
+
+    @Test
+    public void testUnknownCode() {
+        when(hashOps.entries("link:missing")).thenReturn(new HashMap<>());
+
+        assertNull(linkService.recordClick("missing"));
+        verify(hashOps, never()).increment(anyString(), any(), anyLong());
+    }
+}
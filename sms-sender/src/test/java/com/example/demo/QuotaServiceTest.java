@@ -0,0 +1,182 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.QuotaProperties;
+import com.example.demo.model.QuotaUsage;
+import com.example.demo.service.QuotaExceededException;
+import com.example.demo.service.QuotaService;
+import com.example.demo.service.QuotaUnavailableException;
+import java.time.Clock;
+import java.time.Duration;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.RedisConnectionFailureException;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.data.redis.core.ValueOperations;
+
+/**
+ * Unit tests for QuotaService.
+ * 
+ * Testing Strategy:
+ * - Within quota: counters are incremented and expiry set on first use
+ * - Over quota: exception thrown and increments rolled back
+ * - Unlimited (0) limits are not tracked at all
+ * - Per-tenant overrides take precedence over defaults
+ * - A Redis outage refuses the send unless quotas fail open
+ * 
+ * Redis Key Format: "quota:{tenant|phone}:{id}:{day|month}:{yyyyMMdd|yyyyMM}"
+ * The clock is fixed at 2024-03-15T12:00:00Z so keys and reset times are predictable.
+ */
+@ExtendWith(MockitoExtension.class)
+public class QuotaServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private ValueOperations<String, String> valueOps;
+
+    private QuotaProperties properties;
+    private QuotaService quotaService;
+
+    @BeforeEach
+    public void setUp() {
+        properties = new QuotaProperties();
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T12:00:00Z"), ZoneOffset.UTC);
+        quotaService = new QuotaService(redisTemplate, properties, clock);
+        // Lenient: tests with only unlimited quotas never read a counter
+        lenient().when(redisTemplate.opsForValue()).thenReturn(valueOps);
+    }
+
+    /**
+     * Tests that the first send of the day creates a counter with an expiry.
+     */
+    @Test
+    public void testConsume_WithinQuota() {
+        properties.setPhone(new QuotaProperties.Limits(10, 0));
+        when(valueOps.increment("quota:phone:+1234567890:day:20240315")).thenReturn(1L);
+
+        quotaService.consume("default", "+1234567890");
+
+        // 12 hours until midnight plus an hour of slack
+        verify(redisTemplate).expire("quota:phone:+1234567890:day:20240315", Duration.ofSeconds(13 * 3600));
+        verify(valueOps, never()).decrement(anyString());
+    }
+
+    /**
+     * Tests that exceeding any quota rejects the send and undoes every increment.
+     */
+    @Test
+    public void testConsume_OverQuotaRollsBack() {
+        properties.setTenant(new QuotaProperties.Limits(100, 0));
+        properties.setPhone(new QuotaProperties.Limits(5, 0));
+        when(valueOps.increment("quota:tenant:default:day:20240315")).thenReturn(40L);
+        when(valueOps.increment("quota:phone:+1234567890:day:20240315")).thenReturn(6L);
+
+        QuotaExceededException e = assertThrows(QuotaExceededException.class,
+                () -> quotaService.consume("default", "+1234567890"));
+
+        assertEquals("phone", e.getScope());
+        assertEquals("daily", e.getPeriod());
+        assertEquals(12 * 3600, e.getRetryAfterSeconds());
+        // Both counters touched by this send are decremented again
+        verify(valueOps).decrement("quota:tenant:default:day:20240315");
+        verify(valueOps).decrement("quota:phone:+1234567890:day:20240315");
+    }
+
+    /**
+     * Tests that unlimited quotas don't touch Redis.
+     */
+    @Test
+    public void testConsume_UnlimitedIsNotTracked() {
+        quotaService.consume("default", "+1234567890");
+
+        verify(valueOps, never()).increment(anyString());
+        verify(redisTemplate, never()).expire(anyString(), any(Duration.class));
+    }
+
+    /**
+     * Tests that a tenant override replaces the default tenant limits.
+     */
+    @Test
+    public void testConsume_TenantOverride() {
+        properties.setTenant(new QuotaProperties.Limits(0, 1000));
+        properties.getTenants().put("acme", new QuotaProperties.Limits(0, 10));
+        when(valueOps.increment("quota:tenant:acme:month:202403")).thenReturn(11L);
+
+        QuotaExceededException e = assertThrows(QuotaExceededException.class,
+                () -> quotaService.consume("acme", null));
+
+        assertEquals("tenant", e.getScope());
+        assertEquals(10, e.getLimit());
+    }
+
+    /**
+     * Tests that check reports a quota the next send would exceed without counting it.
+     */
+    @Test
+    public void testCheck_DoesNotCount() {
+        properties.setPhone(new QuotaProperties.Limits(5, 0));
+        when(valueOps.get("quota:phone:+1234567890:day:20240315")).thenReturn("5");
+
+        QuotaExceededException e = assertThrows(QuotaExceededException.class,
+                () -> quotaService.check("default", "+1234567890"));
+
+        assertEquals("phone", e.getScope());
+        verify(valueOps, never()).increment(anyString());
+    }
+
+    /**
+     * Tests that usage reports counters alongside their limits.
+     */
+    @Test
+    public void testUsage() {
+        properties.setPhone(new QuotaProperties.Limits(50, 500));
+        when(valueOps.get("quota:phone:+1234567890:day:20240315")).thenReturn("7");
+
+        QuotaUsage usage = quotaService.usage("default", "+1234567890");
+
+        assertEquals(7, usage.getPhoneDaily().getUsed());
+        assertEquals(50, usage.getPhoneDaily().getLimit());
+        assertEquals(0, usage.getPhoneMonthly().getUsed());
+        assertEquals(0, usage.getTenantDaily().getLimit());
+    }
+
+    /**
+     * Tests that a Redis outage refuses the send by default.
+     */
+    @Test
+    public void testConsume_RedisDownFailsClosed() {
+        properties.setPhone(new QuotaProperties.Limits(10, 0));
+        when(valueOps.increment("quota:phone:+1234567890:day:20240315"))
+                .thenThrow(new RedisConnectionFailureException("Connection refused"));
+
+        assertThrows(QuotaUnavailableException.class, () -> quotaService.consume("default", "+1234567890"));
+    }
+
+    /**
+     * Tests that with fail-open set a Redis outage lets the send through uncounted.
+     */
+    @Test
+    public void testConsume_RedisDownFailsOpen() {
+        properties.setPhone(new QuotaProperties.Limits(10, 0));
+        properties.setFailOpen(true);
+        when(valueOps.increment("quota:phone:+1234567890:day:20240315"))
+                .thenThrow(new RedisConnectionFailureException("Connection refused"));
+
+        quotaService.consume("default", "+1234567890");
+    }
+}
@@ -0,0 +1,102 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.contains;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.RecipientTimezone;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.CountryRuleService;
+import com.example.demo.service.RecipientTimezoneService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.ZoneId;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for RecipientTimezoneService.
+ *
+ * Testing Strategy:
+ * - A stored timezone wins over inference
+ * - The country rule's timezone is used, for the request's country or the
+ *   one its calling code belongs to
+ * - Without a rule, single-timezone calling codes give the zone; +1 doesn't
+ * - Inferred timezones are stored for later sends
+ */
+@ExtendWith(MockitoExtension.class)
+public class RecipientTimezoneServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private CountryRuleService countryRuleService;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private RecipientTimezoneService timezoneService;
+
+    @BeforeEach
+    public void setUp() {
+        timezoneService = new RecipientTimezoneService(redisTemplate, objectMapper, countryRuleService);
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+    }
+
+    @Test
+    public void testStoredTimezoneWins() throws Exception {
+        when(hashOps.get("recipient_timezones", "+919876543210"))
+                .thenReturn(objectMapper.writeValueAsString(new RecipientTimezone("Europe/London", RecipientTimezone.SOURCE_MANUAL)));
+
+        assertEquals(ZoneId.of("Europe/London"), timezoneService.resolve(new SmsRequest("+919876543210", "Hi")));
+        verify(countryRuleService, never()).getRule(anyString());
+    }
+
+    @Test
+    public void testCountryRuleTimezone() {
+        CountryRule us = new CountryRule();
+        us.setTimezone("America/Chicago");
+        when(countryRuleService.getRule("US")).thenReturn(us);
+        SmsRequest request = new SmsRequest("+13125550100", "Hi");
+        request.setCountryCode("US");
+
+        assertEquals(ZoneId.of("America/Chicago"), timezoneService.resolve(request));
+        verify(hashOps).put(eq("recipient_timezones"), eq("+13125550100"), contains("\"source\":\"country\""));
+    }
+
+    @Test
+    public void testCallingCodeCountryRule() {
+        CountryRule india = new CountryRule();
+        india.setTimezone("Asia/Kolkata");
+        when(countryRuleService.getRule("IN")).thenReturn(india);
+
+        assertEquals(ZoneId.of("Asia/Kolkata"), timezoneService.resolve(new SmsRequest("+919876543210", "Hi")));
+    }
+
+    @Test
+    public void testCallingCodeZone() {
+        assertEquals(ZoneId.of("Asia/Dubai"), timezoneService.resolve(new SmsRequest("+971501234567", "Hi")));
+        verify(hashOps).put(eq("recipient_timezones"), eq("+971501234567"), contains("\"source\":\"number\""));
+    }
+
+    @Test
+    public void testUnknownTimezone() {
+        // +1 spans several timezones, and national numbers carry no calling code
+        assertNull(timezoneService.resolve(new SmsRequest("+13125550100", "Hi")));
+        assertNull(timezoneService.resolve(new SmsRequest("9876543210", "Hi")));
+        verify(hashOps, never()).put(anyString(), anyString(), anyString());
+    }
+}
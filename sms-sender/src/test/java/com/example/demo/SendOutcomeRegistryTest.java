@@ -0,0 +1,94 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertSame;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+import com.example.demo.model.SmsEvent;
+import com.example.demo.service.SendOutcomeRegistry;
+import java.util.concurrent.CompletableFuture;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+/**
+ * Unit tests for SendOutcomeRegistry.
+ * 
+ * Testing Strategy:
+ * - Outcomes: Final statuses complete the waiter registered for their send ID
+ * - Retries: A "retrying" status keeps the waiter waiting
+ * - Isolation: Events of other sends, or without a send ID, are ignored
+ */
+public class SendOutcomeRegistryTest {
+
+    // The SendOutcomeRegistry instance under test
+    private SendOutcomeRegistry registry;
+
+    @BeforeEach
+    public void setUp() {
+        registry = new SendOutcomeRegistry();
+    }
+
+    private static SmsEvent event(String sendId, String status) {
+        SmsEvent event = new SmsEvent("+1234567890", "Test message", status);
+        event.setSendId(sendId);
+        return event;
+    }
+
+    /**
+     * Tests that an outcome completes the waiter of its send.
+     * 
+     * This test verifies:
+     * 1. A "retrying" event does not complete the waiter
+     * 2. The following "successful" event does, with that event
+     */
+    @Test
+    void testRecord_CompletesOnOutcome() throws Exception {
+        CompletableFuture<SmsEvent> waiter = registry.register("send-1");
+
+        registry.record(event("send-1", "retrying"));
+        assertFalse(waiter.isDone());
+
+        SmsEvent outcome = event("send-1", "successful");
+        registry.record(outcome);
+        assertSame(outcome, waiter.get());
+    }
+
+    /**
+     * Tests that events of other sends leave the waiter alone.
+     */
+    @Test
+    void testRecord_IgnoresOtherSends() {
+        CompletableFuture<SmsEvent> waiter = registry.register("send-1");
+
+        registry.record(event("send-2", "unsuccessful"));
+        registry.record(event(null, "unsuccessful"));
+
+        assertFalse(waiter.isDone());
+    }
+
+    /**
+     * Tests that a cancelled waiter is no longer completed.
+     */
+    @Test
+    void testCancel_StopsWaiting() {
+        CompletableFuture<SmsEvent> waiter = registry.register("send-1");
+
+        registry.cancel("send-1");
+        registry.record(event("send-1", "blocked"));
+
+        assertFalse(waiter.isDone());
+    }
+
+    /**
+     * Tests which statuses end a wait.
+     */
+    @Test
+    void testIsOutcome() {
+        assertTrue(SendOutcomeRegistry.isOutcome("successful"));
+        assertTrue(SendOutcomeRegistry.isOutcome("unsuccessful"));
+        assertTrue(SendOutcomeRegistry.isOutcome("blocked"));
+        assertTrue(SendOutcomeRegistry.isOutcome("deferred"));
+        assertFalse(SendOutcomeRegistry.isOutcome("retrying"));
+        assertFalse(SendOutcomeRegistry.isOutcome(null));
+    }
+}
@@ -0,0 +1,214 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.Mockito.doThrow;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.verifyNoInteractions;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.PricingProperties;
+import com.example.demo.model.SmsPreview;
+import com.example.demo.model.SmsPreviewRequest;
+import com.example.demo.service.AbuseDetectionService;
+import com.example.demo.service.BlacklistCache;
+import com.example.demo.service.CampaignExperimentService;
+import com.example.demo.service.CampaignSendWindowService;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.CountryRuleService;
+import com.example.demo.service.QuotaExceededException;
+import com.example.demo.service.QuotaService;
+import com.example.demo.service.SmsPreviewService;
+import com.example.demo.service.TenantQuietHoursService;
+import com.example.demo.service.UserPreferenceService;
+import java.math.BigDecimal;
+import java.time.Instant;
+import java.util.Collections;
+import java.util.HashMap;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+
+/**
+ * Unit tests for SmsPreviewService.
+ * 
+ * Testing Strategy:
+ * - Placeholders are filled from params; unknown ones are reported and left as written
+ * - GSM-7 and UCS-2 messages are sized and priced the way the provider bills them
+ * - Checks run in the order of SmsService.sendSms, using the non-counting
+ *   variants of the abuse and quota checks
+ * 
+ * The mocks allow every send by default, so the outcome is "send".
+ */
+@ExtendWith(MockitoExtension.class)
+public class SmsPreviewServiceTest {
+
+    @Mock
+    private BlacklistCache blacklistCache;
+
+    @Mock
+    private AbuseDetectionService abuseDetection;
+
+    @Mock
+    private CountryRuleService countryRuleService;
+
+    @Mock
+    private TenantQuietHoursService quietHoursService;
+
+    @Mock
+    private QuotaService quotaService;
+
+    @Mock
+    private CampaignExperimentService experiments;
+
+    @Mock
+    private CampaignSendWindowService sendWindows;
+
+    @Mock
+    private UserPreferenceService preferences;
+
+    private PricingProperties pricing;
+    private SmsPreviewService previewService;
+
+    @BeforeEach
+    public void setUp() {
+        pricing = new PricingProperties();
+        pricing.setPerSegment(new BigDecimal("0.01"));
+        previewService = new SmsPreviewService(blacklistCache, abuseDetection, countryRuleService,
+                quietHoursService, quotaService, pricing, experiments, sendWindows, preferences);
+    }
+
+    private static SmsPreviewRequest request(String message) {
+        SmsPreviewRequest request = new SmsPreviewRequest();
+        request.setPhoneNumber("+1234567890");
+        request.setMessage(message);
+        request.setTenantId("default");
+        return request;
+    }
+
+    /**
+     * Tests that params fill their placeholders and the rest are reported as missing.
+     */
+    @Test
+    public void testPreview_RendersParams() {
+        SmsPreviewRequest request = request("Hi {{name}}, your code is {{ code }}. {{footer}}");
+        Map<String, String> params = new HashMap<>();
+        params.put("name", "Asha");
+        params.put("code", "$42");
+        request.setParams(params);
+
+        SmsPreview preview = previewService.preview(request);
+
+        assertEquals("Hi Asha, your code is $42. {{footer}}", preview.getRenderedMessage());
+        assertEquals(Collections.singletonList("footer"), preview.getMissingParams());
+        assertEquals("send", preview.getOutcome());
+        assertNull(preview.getReason());
+    }
+
+    /**
+     * Tests GSM-7 sizing: extension characters count twice and long messages split into 153-character segments.
+     */
+    @Test
+    public void testPreview_GsmSegments() {
+        StringBuilder message = new StringBuilder();
+        for (int i = 0; i < 159; i++) {
+            message.append('a');
+        }
+        message.append('{');
+
+        SmsPreview preview = previewService.preview(request(message.toString()));
+
+        assertEquals("GSM-7", preview.getEncoding());
+        assertEquals(161, preview.getCharacters());
+        assertEquals(2, preview.getSegments());
+        assertEquals(0, new BigDecimal("0.02").compareTo(preview.getEstimatedCost()));
+        assertEquals("USD", preview.getCurrency());
+        assertEquals("twilio", preview.getProvider());
+    }
+
+    /**
+     * Tests that any character outside GSM-7 switches to UCS-2 with 70-character segments, priced per country.
+     */
+    @Test
+    public void testPreview_UnicodeSegments() {
+        pricing.getCountries().put("IN", new BigDecimal("0.002"));
+        StringBuilder message = new StringBuilder("\u0928\u092E\u0938\u094D\u0924\u0947");
+        while (message.length() < 71) {
+            message.append(' ');
+        }
+        SmsPreviewRequest request = request(message.toString());
+        request.setCountryCode("IN");
+
+        SmsPreview preview = previewService.preview(request);
+
+        assertEquals("UCS-2", preview.getEncoding());
+        assertEquals(71, preview.getCharacters());
+        assertEquals(2, preview.getSegments());
+        assertEquals(0, new BigDecimal("0.004").compareTo(preview.getEstimatedCost()));
+    }
+
+    /**
+     * Tests that a blacklisted number is reported blocked before any other check.
+     */
+    @Test
+    public void testPreview_Blacklisted() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(true);
+
+        SmsPreview preview = previewService.preview(request("Hello"));
+
+        assertEquals("blocked", preview.getOutcome());
+        verifyNoInteractions(abuseDetection, quotaService);
+    }
+
+    /**
+     * Tests that abuse detection is consulted without counting the send.
+     */
+    @Test
+    public void testPreview_AbuseUsesPeek() {
+        when(abuseDetection.peekSend("+1234567890")).thenReturn("Destination blocked: More than 3 messages within 60 minutes");
+        SmsPreviewRequest request = request("Sale now on");
+        request.setCategory("promotional");
+
+        SmsPreview preview = previewService.preview(request);
+
+        assertEquals("blocked", preview.getOutcome());
+        assertEquals("Destination blocked: More than 3 messages within 60 minutes", preview.getReason());
+        verify(abuseDetection, never()).recordSend(anyString());
+    }
+
+    /**
+     * Tests that a quiet-hours deferral is reported with its release time.
+     */
+    @Test
+    public void testPreview_Deferred() {
+        Instant releaseAt = Instant.parse("2024-03-16T08:00:00Z");
+        when(quietHoursService.evaluate(any())).thenReturn(ComplianceDecision.defer("Quiet hours in effect for tenant default", releaseAt));
+
+        SmsPreview preview = previewService.preview(request("Sale now on"));
+
+        assertEquals("deferred", preview.getOutcome());
+        assertEquals(releaseAt, preview.getReleaseAt());
+        verifyNoInteractions(quotaService);
+    }
+
+    /**
+     * Tests that an exhausted quota is reported without consuming it.
+     */
+    @Test
+    public void testPreview_QuotaExceeded() {
+        doThrow(new QuotaExceededException("phone", "daily", 50, 3600)).when(quotaService).check("default", "+1234567890");
+
+        SmsPreview preview = previewService.preview(request("Hello"));
+
+        assertEquals("quota_exceeded", preview.getOutcome());
+        assertTrue(preview.getReason().contains("daily phone limit of 50"));
+        verify(quotaService, never()).consume(anyString(), anyString());
+    }
+}
@@ -0,0 +1,137 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.mock;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.times;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.config.RetryProperties;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.SmsRetry;
+import com.example.demo.service.SmsRetryQueue;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.kafka.KafkaException;
+import org.springframework.kafka.core.KafkaTemplate;
+import org.springframework.kafka.support.SendResult;
+import org.springframework.util.concurrent.SettableListenableFuture;
+
+/**
+ * Unit tests for SmsRetryQueue.
+ * 
+ * Testing Strategy:
+ * - Each retry goes to the topic of its delay tier (1m, 5m, 30m by default)
+ * - The last tier repeats when max attempts exceed the number of tiers
+ * - Exhausted attempts and failed publishes return null so the send is marked failed
+ * - The listener's topics include the storage service's resend topic
+ * 
+ * The clock is fixed at 2024-03-15T12:00:00Z so due times are predictable.
+ */
+@ExtendWith(MockitoExtension.class)
+public class SmsRetryQueueTest {
+
+    private static final Instant NOW = Instant.parse("2024-03-15T12:00:00Z");
+
+    @Mock
+    private KafkaTemplate<String, SmsRetry> kafkaTemplate;
+
+    private RetryProperties properties;
+    private SmsRetryQueue retryQueue;
+    private SmsRequest request;
+
+    @BeforeEach
+    void setUp() {
+        properties = new RetryProperties();
+        retryQueue = new SmsRetryQueue(kafkaTemplate, properties, Clock.fixed(NOW, ZoneOffset.UTC));
+        request = new SmsRequest("+1234567890", "Test message");
+        request.setTenantId("acme");
+    }
+
+    @SuppressWarnings("unchecked")
+    private void kafkaAccepts() {
+        SettableListenableFuture<SendResult<String, SmsRetry>> future = new SettableListenableFuture<>();
+        future.set(mock(SendResult.class));
+        when(kafkaTemplate.send(anyString(), anyString(), any(SmsRetry.class))).thenReturn(future);
+    }
+
+    /**
+     * The first retry waits one minute on the 1m topic, keyed by phone number.
+     */
+    @Test
+    void testScheduleRetry_FirstFailureUsesFirstTier() {
+        kafkaAccepts();
+
+        SmsRetry retry = retryQueue.scheduleRetry("retry-1", request, 1);
+
+        assertNotNull(retry);
+        assertEquals(2, retry.getAttempt());
+        assertEquals("retry-1", retry.getId());
+        // The tenant travels separately because SmsRequest doesn't serialize it
+        assertEquals("acme", retry.getTenantId());
+        assertEquals(NOW.plusSeconds(60).toEpochMilli(), retry.getDueAtEpochMs());
+        verify(kafkaTemplate).send(eq("sms_retry_1m"), eq("+1234567890"), any(SmsRetry.class));
+    }
+
+    /**
+     * Later retries move up the tiers, and the last tier repeats.
+     */
+    @Test
+    void testScheduleRetry_LaterFailuresUseLongerTiers() {
+        kafkaAccepts();
+        properties.setMaxAttempts(6);
+
+        retryQueue.scheduleRetry("retry-1", request, 3);
+        retryQueue.scheduleRetry("retry-1", request, 5);
+
+        ArgumentCaptor<String> topics = ArgumentCaptor.forClass(String.class);
+        verify(kafkaTemplate, times(2)).send(topics.capture(), anyString(), any(SmsRetry.class));
+        assertEquals("sms_retry_30m", topics.getAllValues().get(0));
+        assertEquals("sms_retry_30m", topics.getAllValues().get(1));
+    }
+
+    /**
+     * Nothing is published once the last attempt has failed.
+     */
+    @Test
+    void testScheduleRetry_AttemptsExhausted() {
+        assertNull(retryQueue.scheduleRetry("retry-1", request, 4));
+        verify(kafkaTemplate, never()).send(anyString(), anyString(), any(SmsRetry.class));
+    }
+
+    /**
+     * A retry that can't be published is reported as not scheduled.
+     */
+    @Test
+    void testScheduleRetry_PublishFails() {
+        SettableListenableFuture<SendResult<String, SmsRetry>> future = new SettableListenableFuture<>();
+        future.setException(new KafkaException("Kafka connection failed"));
+        when(kafkaTemplate.send(anyString(), anyString(), any(SmsRetry.class))).thenReturn(future);
+
+        assertNull(retryQueue.scheduleRetry("retry-1", request, 1));
+    }
+
+    /**
+     * The retry listener subscribes to every tier topic and to the resend
+     * topic the storage service publishes retry-policy resends to.
+     */
+    @Test
+    void testTopics_IncludeResendTopic() {
+        assertArrayEquals(new String[] {"sms_retry_1m", "sms_retry_5m", "sms_retry_30m", "sms_resend"},
+                properties.topics());
+    }
+}
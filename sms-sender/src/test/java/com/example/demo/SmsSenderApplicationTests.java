@@ -1,6 +1,7 @@
 package com.example.demo;
 
 import com.example.demo.model.SmsRequest;
+import com.example.demo.service.QuotaExceededException;
 import com.example.demo.service.SmsService;
 import org.junit.jupiter.api.Test;
 import org.springframework.beans.factory.annotation.Autowired;
@@ -405,4 +406,31 @@ class SmsSenderApplicationTests {
 				.andExpect(status().isOk())
 				.andExpect(jsonPath("$.result").value("SMS sent to +9876543210"));
 	}
+
+	/**
+	 * Tests that an exhausted quota is reported as 429 Too Many Requests.
+	 * 
+	 * This test verifies:
+	 * 1. QuotaExceededException from the service maps to HTTP 429
+	 * 2. The Retry-After header tells the caller when the quota resets
+	 */
+	@Test
+	void testQuotaExceeded_Returns429() throws Exception {
+		// Arrange: Simulate the service rejecting the send for quota
+		when(smsService.sendSms(any(SmsRequest.class)))
+				.thenThrow(new QuotaExceededException("tenant", "daily", 1000, 120));
+
+		String request = "{\n" +
+				"    \"phoneNumber\": \"+1234567890\",\n" +
+				"    \"message\": \"Test\"\n" +
+				"}";
+
+		// Act & Assert: Verify status and Retry-After header
+		mockMvc.perform(post("/v1/sms/send")
+				.header("X-Tenant-ID", "acme")
+				.contentType(MediaType.APPLICATION_JSON)
+				.content(request))
+				.andExpect(status().isTooManyRequests())
+				.andExpect(header().string("Retry-After", "120"));
+	}
 }
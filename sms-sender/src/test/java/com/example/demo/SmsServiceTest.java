@@ -2,10 +2,26 @@ package com.example.demo;
 
 import com.example.demo.model.SmsEvent;
 import com.example.demo.model.SmsRequest;
+import com.example.demo.model.SmsRetry;
+import com.example.demo.service.AbuseDetectionService;
 import com.example.demo.service.BlacklistCache;
+import com.example.demo.service.CampaignExperimentService;
+import com.example.demo.service.CampaignSendWindowService;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.CountryRuleService;
+import com.example.demo.service.DeferredSmsQueue;
+import com.example.demo.service.LinkTrackingService;
+import com.example.demo.service.TenantQuietHoursService;
+import com.example.demo.service.QuotaExceededException;
+import com.example.demo.service.QuotaService;
 import com.example.demo.service.SmsEventProducer;
+import com.example.demo.service.SmsRetryQueue;
 import com.example.demo.service.SmsService;
+import com.example.demo.service.TransientProviderException;
 import com.example.demo.service.TwillioService;
+import com.example.demo.service.UserPreferenceService;
+import java.time.Instant;
+import java.util.Collections;
 import org.springframework.kafka.KafkaException;
 import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
@@ -18,6 +34,8 @@ import org.mockito.junit.jupiter.MockitoExtension;
 
 import static org.junit.jupiter.api.Assertions.*;
 import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyInt;
+import static org.mockito.ArgumentMatchers.anyString;
 import static org.mockito.ArgumentMatchers.eq;
 import static org.mockito.Mockito.*;
 
@@ -56,6 +74,46 @@ public class SmsServiceTest {
     @Mock
     private TwillioService twillioService;
 
+    // Mock the QuotaService dependency
+    // By default the mock does nothing, i.e. every send is within quota
+    @Mock
+    private QuotaService quotaService;
+
+    // Mock the compliance dependencies
+    // evaluate() returns null by default, meaning no country rule blocks the send
+    @Mock
+    private CountryRuleService countryRuleService;
+
+    @Mock
+    private TenantQuietHoursService quietHoursService;
+
+    @Mock
+    private DeferredSmsQueue deferredQueue;
+
+    // scheduleRetry() returns null by default, i.e. no retry is scheduled
+    @Mock
+    private SmsRetryQueue retryQueue;
+
+    // check() returns null by default, i.e. no destination looks suspicious
+    @Mock
+    private AbuseDetectionService abuseDetection;
+
+    // assign() leaves requests unchanged by default, i.e. no campaign has an A/B test
+    @Mock
+    private CampaignExperimentService experiments;
+
+    // shorten() leaves messages unchanged by default, i.e. link tracking is off
+    @Mock
+    private LinkTrackingService links;
+
+    // evaluate() returns null by default, i.e. no campaign has a send window
+    @Mock
+    private CampaignSendWindowService sendWindows;
+
+    // evaluate() returns null by default, i.e. every recipient consents to the send
+    @Mock
+    private UserPreferenceService preferences;
+
     // Inject the mocked dependencies into SmsService
     // Mockito will automatically inject the mocks above into SmsService constructor
     @InjectMocks
@@ -527,4 +585,388 @@ public class SmsServiceTest {
         // The service should attempt to log the blocked attempt
         verify(eventProducer, times(1)).sendSmsEvent(any(SmsEvent.class));
     }
+
+    /**
+     * Tests that delivery and campaign metadata are attached to the produced event.
+     * 
+     * This test verifies:
+     * 1. Campaign, template and country from the request are carried on the event
+     * 2. The provider name and the provider's message ID are recorded on success
+     */
+    @Test
+    void testSendSms_EventCarriesProviderAndCampaignMetadata() {
+        // Arrange: Attribute the request to a campaign and template
+        validRequest.setCampaignId("diwali-2024");
+        validRequest.setTemplateId("tmpl-42");
+        validRequest.setCountryCode("IN");
+        validRequest.setMetadata(Collections.singletonMap("order_id", "OD-1001"));
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        // Simulate the provider returning its message SID
+        when(twillioService.sendSms("+1234567890", "Test message")).thenReturn("SM123");
+
+        // Act: Send the SMS
+        smsService.sendSms(validRequest);
+
+        // Assert: Capture the event and verify the metadata fields
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        SmsEvent capturedEvent = smsEventCaptor.getValue();
+        assertEquals("twilio", capturedEvent.getProvider());
+        assertEquals("SM123", capturedEvent.getProviderMessageId());
+        assertEquals("diwali-2024", capturedEvent.getCampaignId());
+        assertEquals("tmpl-42", capturedEvent.getTemplateId());
+        assertEquals("IN", capturedEvent.getCountryCode());
+        assertEquals("OD-1001", capturedEvent.getMetadata().get("order_id"));
+        // Category defaults to transactional when the request doesn't set one
+        assertEquals("transactional", capturedEvent.getCategory());
+        // Latency is measured around the provider call
+        assertNotNull(capturedEvent.getProviderLatencyMs());
+    }
+
+    /**
+     * Tests that a send over quota never reaches the provider.
+     * 
+     * This test verifies:
+     * 1. QuotaExceededException propagates to the caller (the controller maps it to 429)
+     * 2. Twillio is not called, so no provider budget is spent
+     * 3. No event is produced for the rejected send
+     */
+    @Test
+    void testSendSms_QuotaExceeded() {
+        // Arrange: The number is allowed, but its daily quota is used up
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        validRequest.setTenantId("acme");
+        doThrow(new QuotaExceededException("phone", "daily", 50, 3600))
+                .when(quotaService).consume("acme", "+1234567890");
+
+        // Act & Assert: The exception surfaces with its retry hint
+        QuotaExceededException e = assertThrows(QuotaExceededException.class, () -> smsService.sendSms(validRequest));
+        assertEquals(3600, e.getRetryAfterSeconds());
+
+        // Verify nothing was sent or published
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(eventProducer, never()).sendSmsEvent(any(SmsEvent.class));
+    }
+
+    /**
+     * Tests that a country rule violation rejects the send with a distinct status.
+     * 
+     * This test verifies:
+     * 1. The provider is never called
+     * 2. A "rejected" event is recorded so the rejection is visible downstream
+     */
+    @Test
+    void testSendSms_RejectedByCountryRule() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(countryRuleService.evaluate(validRequest))
+                .thenReturn(ComplianceDecision.reject("Sender ID not allowed for IN"));
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Failed: Sender ID not allowed for IN", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(quotaService, never()).consume(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("rejected", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that a recipient who opted out of the category is never sent to.
+     * 
+     * This test verifies:
+     * 1. Neither the provider nor the later compliance checks are called
+     * 2. A "rejected" event is recorded
+     */
+    @Test
+    void testSendSms_RejectedByUserPreferences() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(preferences.evaluate(validRequest))
+                .thenReturn(ComplianceDecision.reject("Recipient has opted out of promotional SMS"));
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Failed: Recipient has opted out of promotional SMS", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(countryRuleService, never()).evaluate(any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("rejected", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that quiet hours defer the send instead of dropping it.
+     * 
+     * This test verifies:
+     * 1. The request is queued for release when quiet hours end
+     * 2. A "deferred" event is recorded and the provider is not called yet
+     */
+    @Test
+    void testSendSms_DeferredByQuietHours() {
+        Instant releaseAt = Instant.parse("2024-03-16T03:30:00Z");
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(countryRuleService.evaluate(validRequest))
+                .thenReturn(ComplianceDecision.defer("Quiet hours in effect for IN", releaseAt));
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Deferred until 2024-03-16T03:30:00Z: Quiet hours in effect for IN", result);
+        verify(deferredQueue, times(1)).defer(validRequest, releaseAt);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("deferred", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that a transient provider failure is queued for retry instead of failing.
+     * 
+     * This test verifies:
+     * 1. The retry is scheduled after attempt 1
+     * 2. A "retrying" event is recorded, not "unsuccessful"
+     */
+    @Test
+    void testSendSms_TransientFailureSchedulesRetry() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        doThrow(new TransientProviderException("Provider returned 503"))
+                .when(twillioService).sendSms("+1234567890", "Test message");
+        when(retryQueue.scheduleRetry(any(), eq(validRequest), eq(1)))
+                .thenReturn(new SmsRetry("retry-1", null, validRequest, 2, 0));
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Failed to send SMS, retrying (attempt 2): Provider returned 503", result);
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("retrying", smsEventCaptor.getValue().getStatus());
+        // The first attempt isn't labelled, only retries are
+        assertNull(smsEventCaptor.getValue().getAttempt());
+    }
+
+    /**
+     * Tests that the send is marked unsuccessful once retries are exhausted.
+     * 
+     * This test verifies:
+     * 1. A retried send doesn't re-check quota
+     * 2. The final failure records an "unsuccessful" event carrying the attempt number
+     */
+    @Test
+    void testRetrySms_AttemptsExhausted() {
+        doThrow(new TransientProviderException("Provider timed out"))
+                .when(twillioService).sendSms("+1234567890", "Test message");
+
+        String result = smsService.retrySms(new SmsRetry("retry-1", null, validRequest, 4, 0));
+
+        assertEquals("Failed to send SMS: Provider timed out", result);
+        verify(retryQueue, times(1)).scheduleRetry("retry-1", validRequest, 4);
+        verify(quotaService, never()).consume(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("unsuccessful", smsEventCaptor.getValue().getStatus());
+        assertEquals(Integer.valueOf(4), smsEventCaptor.getValue().getAttempt());
+    }
+
+    /**
+     * Tests that a resend asking for a provider that isn't integrated still
+     * goes out through Twilio, as the next attempt of the same send.
+     */
+    @Test
+    void testRetrySms_UnavailableProviderFallsBackToTwilio() {
+        SmsRetry resend = new SmsRetry("send-1", "acme", validRequest, 5, 0);
+        resend.setProvider("msg91");
+
+        String result = smsService.retrySms(resend);
+
+        assertEquals("SMS sent to +1234567890", result);
+        verify(twillioService, times(1)).sendSms("+1234567890", "Test message");
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("successful", smsEventCaptor.getValue().getStatus());
+        assertEquals("send-1", smsEventCaptor.getValue().getSendId());
+        assertEquals(Integer.valueOf(5), smsEventCaptor.getValue().getAttempt());
+    }
+
+    /**
+     * Tests that a resend to a recipient who opted out since the first attempt
+     * is rejected instead of reaching the provider.
+     */
+    @Test
+    void testRetrySms_RejectedByUserPreferences() {
+        when(preferences.evaluate(validRequest))
+                .thenReturn(ComplianceDecision.reject("Recipient has opted out of promotional SMS"));
+
+        String result = smsService.retrySms(new SmsRetry("send-1", "acme", validRequest, 2, 0));
+
+        assertEquals("Failed: Recipient has opted out of promotional SMS", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("rejected", smsEventCaptor.getValue().getStatus());
+        assertEquals("send-1", smsEventCaptor.getValue().getSendId());
+    }
+
+    /**
+     * Tests that a resend to a number blacklisted since the first attempt is blocked.
+     */
+    @Test
+    void testRetrySms_BlacklistedNumber() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(true);
+
+        String result = smsService.retrySms(new SmsRetry("send-1", "acme", validRequest, 2, 0));
+
+        assertEquals("Failed: Phone number is blacklisted", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(preferences, never()).evaluate(any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("blocked", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that non-transient provider errors are never retried.
+     */
+    @Test
+    void testSendSms_PermanentFailureIsNotRetried() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        doThrow(new RuntimeException("Invalid destination"))
+                .when(twillioService).sendSms("+1234567890", "Test message");
+
+        smsService.sendSms(validRequest);
+
+        verify(retryQueue, never()).scheduleRetry(any(), any(), anyInt());
+    }
+
+    /**
+     * Tests that a destination flagged by abuse detection is blocked before the provider.
+     * 
+     * This test verifies:
+     * 1. The provider and quota are never touched
+     * 2. A "blocked" event is recorded, as for blacklisted numbers
+     */
+    @Test
+    void testSendSms_BlockedByAbuseDetection() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(abuseDetection.check("+1234567890")).thenReturn("Destination +1234 is blocked (fraud)");
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Failed: Destination +1234 is blocked (fraud)", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(quotaService, never()).consume(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("blocked", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that a promotional send over the volume threshold is blocked once
+     * compliance has let it through.
+     * 
+     * This test verifies:
+     * 1. The send is counted only after the compliance checks
+     * 2. The provider and quota are never touched
+     */
+    @Test
+    void testSendSms_PromotionalBlockedByVolume() {
+        validRequest.setCategory("promotional");
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(abuseDetection.recordSend("+1234567890")).thenReturn("Destination blocked: More than 3 messages within 60 minutes");
+
+        String result = smsService.sendSms(validRequest);
+
+        assertEquals("Failed: Destination blocked: More than 3 messages within 60 minutes", result);
+        verify(twillioService, never()).sendSms(any(), any());
+        verify(quotaService, never()).consume(any(), any());
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("blocked", smsEventCaptor.getValue().getStatus());
+    }
+
+    /**
+     * Tests that transactional sends such as OTPs never count towards the volume threshold.
+     */
+    @Test
+    void testSendSms_TransactionalNotCountedForVolume() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        when(twillioService.sendSms("+1234567890", "Test message")).thenReturn("SM123");
+
+        smsService.sendSms(validRequest);
+
+        verify(abuseDetection, never()).recordSend(anyString());
+    }
+
+    /**
+     * Tests that events carry the tenant and category, which webhook
+     * subscriptions in the storage service filter on.
+     * 
+     * This test verifies:
+     * 1. The tenant from the X-Tenant-ID header is set on the event
+     * 2. A promotional request produces a promotional event
+     */
+    @Test
+    void testSendSms_EventCarriesTenantAndCategory() {
+        validRequest.setTenantId("acme");
+        validRequest.setCategory("promotional");
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+
+        smsService.sendSms(validRequest);
+
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("acme", smsEventCaptor.getValue().getTenantId());
+        assertEquals("promotional", smsEventCaptor.getValue().getCategory());
+    }
+
+    /**
+     * Tests that events carry the sender ID, which the storage service uses
+     * to group a user's inbox into conversations.
+     * 
+     * This test verifies:
+     * 1. The sender ID from the request is set on the event
+     */
+    @Test
+    void testSendSms_EventCarriesSenderId() {
+        validRequest.setSenderId("MEESHO");
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+
+        smsService.sendSms(validRequest);
+
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("MEESHO", smsEventCaptor.getValue().getSenderId());
+    }
+
+    /**
+     * Tests that events are stamped with the current schema version and leave
+     * out the placeholder sender ID of the default sender.
+     * 
+     * This test verifies:
+     * 1. The event carries SmsEvent.SCHEMA_VERSION
+     * 2. A "default" sender ID is not set on the event
+     */
+    @Test
+    void testSendSms_EventUsesCurrentSchema() {
+        validRequest.setSenderId("default");
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+
+        smsService.sendSms(validRequest);
+
+        verify(eventProducer, times(1)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals(SmsEvent.SCHEMA_VERSION, smsEventCaptor.getValue().getSchemaVersion());
+        assertNull(smsEventCaptor.getValue().getSenderId());
+    }
+
+    /**
+     * Tests that every event of a send carries its send ID, including the
+     * events of later retry attempts.
+     * 
+     * This test verifies:
+     * 1. The "retrying" event of the first attempt carries the caller's send ID
+     * 2. The retry is scheduled under the same ID
+     * 3. The outcome event of the retry carries the same ID
+     */
+    @Test
+    void testSendSms_EventsCarrySendId() {
+        when(blacklistCache.isBlacklisted("+1234567890")).thenReturn(false);
+        doThrow(new TransientProviderException("Provider timed out"))
+                .when(twillioService).sendSms("+1234567890", "Test message");
+        when(retryQueue.scheduleRetry("send-1", validRequest, 1))
+                .thenReturn(new SmsRetry("send-1", null, validRequest, 2, 0));
+
+        smsService.sendSms(validRequest, "send-1");
+        smsService.retrySms(new SmsRetry("send-1", null, validRequest, 4, 0));
+
+        verify(eventProducer, times(2)).sendSmsEvent(smsEventCaptor.capture());
+        assertEquals("retrying", smsEventCaptor.getAllValues().get(0).getStatus());
+        assertEquals("send-1", smsEventCaptor.getAllValues().get(0).getSendId());
+        assertEquals("unsuccessful", smsEventCaptor.getAllValues().get(1).getStatus());
+        assertEquals("send-1", smsEventCaptor.getAllValues().get(1).getSendId());
+    }
 }
@@ -0,0 +1,103 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.CountryRule;
+import com.example.demo.model.QuietHoursWindow;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.CountryRuleService;
+import com.example.demo.service.TenantQuietHoursService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+
+/**
+ * Unit tests for TenantQuietHoursService.
+ * 
+ * Testing Strategy:
+ * - Promotional messages inside the tenant's window are deferred to its end
+ * - Transactional messages are never deferred
+ * - The recipient's timezone comes from the country rule when there is one
+ * 
+ * The clock is fixed at 2024-03-15T17:00:00Z: 22:30 in Asia/Kolkata, 13:00 in America/New_York.
+ */
+@ExtendWith(MockitoExtension.class)
+public class TenantQuietHoursServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private CountryRuleService countryRuleService;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private TenantQuietHoursService quietHoursService;
+    private SmsRequest request;
+
+    @BeforeEach
+    public void setUp() throws Exception {
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T17:00:00Z"), ZoneOffset.UTC);
+        quietHoursService = new TenantQuietHoursService(redisTemplate, objectMapper, countryRuleService, clock);
+
+        // Tenant "acme" is quiet from 21:00 to 09:00, defaulting to New York time
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+        lenient().when(hashOps.get("tenant_quiet_hours", "acme"))
+                .thenReturn(objectMapper.writeValueAsString(new QuietHoursWindow("21:00", "09:00", "America/New_York")));
+
+        request = new SmsRequest("+919876543210", "Big sale today!");
+        request.setTenantId("acme");
+        request.setCategory("promotional");
+    }
+
+    /**
+     * Tests that the recipient's country timezone decides whether the window applies.
+     */
+    @Test
+    public void testEvaluate_UsesRecipientCountryTimezone() {
+        CountryRule india = new CountryRule();
+        india.setTimezone("Asia/Kolkata");
+        request.setCountryCode("IN");
+        when(countryRuleService.getRule("IN")).thenReturn(india);
+
+        ComplianceDecision decision = quietHoursService.evaluate(request);
+
+        // 22:30 IST is inside the window; it ends at 09:00 IST
+        assertEquals(ComplianceDecision.Action.DEFER, decision.getAction());
+        assertEquals(Instant.parse("2024-03-16T03:30:00Z"), decision.getReleaseAt());
+    }
+
+    /**
+     * Tests that the default timezone is used when the recipient's country is unknown.
+     */
+    @Test
+    public void testEvaluate_FallsBackToDefaultTimezone() {
+        // 13:00 in New York is outside the window
+        assertNull(quietHoursService.evaluate(request));
+    }
+
+    /**
+     * Tests that transactional messages bypass quiet hours.
+     */
+    @Test
+    public void testEvaluate_TransactionalOverride() {
+        request.setCategory("transactional");
+        request.setCountryCode("IN");
+
+        assertNull(quietHoursService.evaluate(request));
+    }
+}
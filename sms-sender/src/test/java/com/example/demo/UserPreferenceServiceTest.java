@@ -0,0 +1,139 @@
+package com.example.demo;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.mockito.ArgumentMatchers.any;
+import static org.mockito.ArgumentMatchers.anyString;
+import static org.mockito.ArgumentMatchers.eq;
+import static org.mockito.Mockito.lenient;
+import static org.mockito.Mockito.mock;
+import static org.mockito.Mockito.never;
+import static org.mockito.Mockito.verify;
+import static org.mockito.Mockito.when;
+
+import com.example.demo.model.PreferenceChangeEvent;
+import com.example.demo.model.SmsRequest;
+import com.example.demo.model.UserPreferences;
+import com.example.demo.service.ComplianceDecision;
+import com.example.demo.service.UserPreferenceService;
+import com.fasterxml.jackson.databind.ObjectMapper;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.util.HashMap;
+import java.util.Map;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.mockito.ArgumentCaptor;
+import org.mockito.Mock;
+import org.mockito.junit.jupiter.MockitoExtension;
+import org.springframework.data.redis.core.HashOperations;
+import org.springframework.data.redis.core.StringRedisTemplate;
+import org.springframework.kafka.core.KafkaTemplate;
+import org.springframework.kafka.support.SendResult;
+import org.springframework.util.concurrent.SettableListenableFuture;
+
+/**
+ * Unit tests for UserPreferenceService.
+ *
+ * Testing Strategy:
+ * - Users without preferences, or without an entry for a category, consent to it
+ * - A category turned off for SMS rejects sends of that category only
+ * - Saving publishes the change, with the previous consent, keyed by user
+ * - Unknown categories are refused before anything is stored
+ */
+@ExtendWith(MockitoExtension.class)
+public class UserPreferenceServiceTest {
+
+    @Mock
+    private StringRedisTemplate redisTemplate;
+
+    @Mock
+    private HashOperations<String, Object, Object> hashOps;
+
+    @Mock
+    private KafkaTemplate<String, PreferenceChangeEvent> kafkaTemplate;
+
+    private final ObjectMapper objectMapper = new ObjectMapper();
+    private UserPreferenceService preferenceService;
+
+    @BeforeEach
+    public void setUp() {
+        Clock clock = Clock.fixed(Instant.parse("2024-03-15T10:00:00Z"), ZoneOffset.UTC);
+        preferenceService = new UserPreferenceService(redisTemplate, objectMapper, kafkaTemplate, clock);
+        lenient().when(redisTemplate.opsForHash()).thenReturn(hashOps);
+    }
+
+    private static SmsRequest request(String category) {
+        SmsRequest request = new SmsRequest();
+        request.setPhoneNumber("+1234567890");
+        request.setMessage("Sale ends today");
+        request.setCategory(category);
+        return request;
+    }
+
+    private static UserPreferences promoOff() {
+        Map<String, Boolean> sms = new HashMap<>();
+        sms.put("promotional", false);
+        sms.put("transactional", true);
+        Map<String, Map<String, Boolean>> consent = new HashMap<>();
+        consent.put("sms", sms);
+        return new UserPreferences(consent, null);
+    }
+
+    @SuppressWarnings("unchecked")
+    private void kafkaAccepts() {
+        SettableListenableFuture<SendResult<String, PreferenceChangeEvent>> future = new SettableListenableFuture<>();
+        future.set(mock(SendResult.class));
+        when(kafkaTemplate.send(eq(UserPreferenceService.TOPIC), anyString(), any(PreferenceChangeEvent.class)))
+                .thenReturn(future);
+    }
+
+    @Test
+    public void testNoPreferencesAllowsEverything() {
+        when(hashOps.get("user_preferences", "+1234567890")).thenReturn(null);
+
+        assertNull(preferenceService.evaluate(request("promotional")));
+    }
+
+    @Test
+    public void testPromotionalOptOutRejectsPromotionalOnly() throws Exception {
+        when(hashOps.get("user_preferences", "+1234567890")).thenReturn(objectMapper.writeValueAsString(promoOff()));
+
+        ComplianceDecision decision = preferenceService.evaluate(request("promotional"));
+
+        assertEquals(ComplianceDecision.Action.REJECT, decision.getAction());
+        assertEquals("Recipient has opted out of promotional SMS", decision.getReason());
+        assertNull(preferenceService.evaluate(request("transactional")));
+    }
+
+    @Test
+    public void testSavePublishesChange() {
+        when(hashOps.get("user_preferences", "+1234567890")).thenReturn(null);
+        kafkaAccepts();
+
+        UserPreferences saved = preferenceService.savePreferences("+1234567890", promoOff());
+
+        assertEquals("2024-03-15T10:00:00Z", saved.getUpdatedAt());
+        verify(hashOps).put(eq("user_preferences"), eq("+1234567890"), anyString());
+        ArgumentCaptor<PreferenceChangeEvent> event = ArgumentCaptor.forClass(PreferenceChangeEvent.class);
+        verify(kafkaTemplate).send(eq(UserPreferenceService.TOPIC), eq("+1234567890"), event.capture());
+        assertTrue(event.getValue().getPrevious().isEmpty());
+        assertEquals(Boolean.FALSE, event.getValue().getConsent().get("sms").get("promotional"));
+        assertEquals("2024-03-15T10:00:00Z", event.getValue().getChangedAt());
+    }
+
+    @Test
+    public void testUnknownCategoryRefused() {
+        UserPreferences preferences = promoOff();
+        preferences.getConsent().get("sms").put("marketing", false);
+
+        assertThrows(IllegalArgumentException.class,
+                () -> preferenceService.savePreferences("+1234567890", preferences));
+        verify(hashOps, never()).put(anyString(), any(), any());
+        verify(kafkaTemplate, never()).send(anyString(), anyString(), any());
+    }
+}
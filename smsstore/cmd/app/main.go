@@ -6,10 +6,45 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"smsstore/internal/anomaly"
+	"smsstore/internal/breaker"
+	"smsstore/internal/canary"
+	"smsstore/internal/changeevents"
+	"smsstore/internal/changestream"
 	"smsstore/internal/config"
 	"smsstore/internal/consumer"
 	"smsstore/internal/db"
+	"smsstore/internal/deadletter"
+	"smsstore/internal/devmode"
+	"smsstore/internal/diagnostics"
+	"smsstore/internal/features"
+	"smsstore/internal/forwarding"
+	"smsstore/internal/handlers"
+	"smsstore/internal/integrity"
+	"smsstore/internal/jobs"
+	"smsstore/internal/logsample"
+	"smsstore/internal/maintenance"
+	"smsstore/internal/migrations"
+	"smsstore/internal/providerhealth"
+	"smsstore/internal/ratelimit"
+	"smsstore/internal/rbac"
+	"smsstore/internal/readiness"
+	"smsstore/internal/reconcile"
+	"smsstore/internal/repository"
+	"smsstore/internal/retention"
+	"smsstore/internal/retries"
 	"smsstore/internal/routes"
+	"smsstore/internal/shadow"
+	"smsstore/internal/statscache"
+	"smsstore/internal/statuscompaction"
+	"smsstore/internal/storagereport"
+	"smsstore/internal/tenants"
+	"smsstore/internal/tiering"
+	"smsstore/internal/tlsreload"
+	"smsstore/internal/usercache"
+	"smsstore/internal/watchdog"
+	"smsstore/internal/webhooks"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -26,53 +61,274 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize MongoDB connection
-	_, err = db.GetClient()
-	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB connection: %v", err)
+	// MongoDB clients connect lazily; everything reading or writing message
+	// data goes through this repository
+	clients := db.New(cfg)
+	repo := repository.New(cfg, clients)
+
+	providerhealth.Configure(cfg)
+	usercache.Configure(cfg)
+	statscache.Configure(cfg)
+	logsample.Configure(cfg)
+	webhooks.Configure(cfg, repo)
+	tenants.Configure(cfg, repo)
+	integrity.Configure(cfg)
+	ratelimit.Configure(cfg)
+	breaker.Configure(cfg)
+
+	// Initialize change-event publisher (no-op unless enabled)
+	changeevents.Init(cfg)
+
+	// Initialize dead-letter publisher for invalid events (no-op unless enabled)
+	deadletter.Init(cfg)
+
+	// Initialize forwarding of matching messages to downstream topics (no-op without rules)
+	forwarding.Init(cfg)
+
+	// Simulated provider and in-process bus (no-op unless DEV_MODE)
+	devmode.Init(cfg)
+
+	// Load the RBAC policy (no-op unless RBAC_POLICY_FILE is set)
+	if err := rbac.Init(cfg); err != nil {
+		log.Fatalf("Failed to initialize authorization: %v", err)
 	}
 
+	// Apply FEATURE_FLAGS and FEATURE_FLAGS_FILE; admin overrides load below
+	if err := features.Init(cfg, repo); err != nil {
+		log.Fatalf("Failed to initialize feature flags: %v", err)
+	}
+
+	// The consumer and the public API share one message store; API reads of
+	// user listings go through the in-process cache
+	var store repository.MessageStore = repo
+	if cfg.ShadowWrites {
+		// Mirror message writes to the backend being migrated to
+		store = shadow.Store{MessageStore: store, Shadow: repo}
+		log.Println("[SHADOW] Mirroring message writes to the shadow backend")
+	}
+	events := consumer.New(cfg, repo, store)
+	api := handlers.NewAPI(repo, usercache.Store{MessageStore: store, Repository: repo})
+
 	// Setup HTTP routes
-	router, err := routes.SetupRoutes()
+	router, err := routes.SetupRoutes(cfg, repo, api)
 	if err != nil {
 		log.Fatalf("Failed to setup routes: %v", err)
 	}
+	if cfg.AdminAPIToken == "" && !cfg.RBACEnabled() {
+		log.Println("[WARN] ADMIN_API_TOKEN is not set; admin routes and the message firehose are unauthenticated")
+	}
 
-	server := &http.Server{
-		Addr:         cfg.ServerPort,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	servers := []*http.Server{newServer(cfg.ServerPort, routes.WithProbes(router))}
+	if cfg.AdminServerEnabled() {
+		adminRouter, err := routes.SetupAdminRoutes(cfg, repo, api)
+		if err != nil {
+			log.Fatalf("Failed to setup admin routes: %v", err)
+		}
+		servers = append(servers, newServer(cfg.AdminPort, adminRouter))
 	}
 
-	go func() {
-		log.Printf("HTTP server listening on %s", cfg.ServerPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not listen on %s: %v\n", cfg.ServerPort, err)
+	// Background workers stop when this context is cancelled on shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var reloader *tlsreload.Reloader
+	if cfg.TLSEnabled() {
+		reloader, err = tlsreload.New(cfg)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
 		}
-	}()
+		for _, server := range servers {
+			server.TLSConfig = reloader.TLSConfig()
+		}
+		go reloader.Watch(workerCtx, cfg.TLSReloadInterval)
+	}
 
-	// Start Kafka consumer in goroutine
-	go consumer.StartKafkaConsumer(cfg)
+	if cfg.RBACEnabled() {
+		go rbac.Watch(workerCtx, cfg.RBACReloadInterval)
+	}
+
+	for _, server := range servers {
+		go serve(server, reloader != nil, cfg.TLSClientCAFile != "")
+	}
 
-	// Setup graceful shutdown
+	// Serve probes while waiting for dependencies that may start after us:
+	// the service reports not ready until they are reachable, and only gives
+	// up after STARTUP_MAX_WAIT
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	startCtx, cancelStart := context.WithCancel(workerCtx)
+	go func() {
+		select {
+		case <-quit:
+			log.Println("Shutdown requested during startup")
+			os.Exit(0)
+		case <-startCtx.Done():
+		}
+	}()
+	waitFor := func(name string, check func(ctx context.Context) error) {
+		if err := readiness.Wait(startCtx, name, cfg.StartupMaxWait, cfg.StartupRetryBackoff, check); err != nil {
+			log.Fatalf("Failed to start: %v", err)
+		}
+	}
+	for _, region := range clients.Regions() {
+		name := "mongodb"
+		if region != "" {
+			name += " (region " + region + ")"
+		}
+		waitFor(name, func(ctx context.Context) error {
+			_, err := clients.RegionClient(region)
+			return err
+		})
+	}
+	if !cfg.DevMode {
+		waitFor("kafka", func(ctx context.Context) error {
+			return consumer.CheckTopic(ctx, cfg)
+		})
+	}
+	cancelStart()
+
+	// Apply pending schema migrations before processing work, in every
+	// database holding message data
+	for _, scope := range repo.Scopes() {
+		database, err := repo.Database(scope)
+		if err != nil {
+			log.Fatalf("Failed to get database handle%s: %v", scope.Label(), err)
+		}
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 15*time.Minute)
+		err = migrations.Run(migrateCtx, database)
+		cancelMigrate()
+		if err != nil {
+			log.Fatalf("Failed to run schema migrations%s: %v", scope.Label(), err)
+		}
+	}
+
+	// Load admin feature flag overrides before processing work, then keep them current
+	features.Refresh(workerCtx)
+	go features.Watch(workerCtx, cfg.FeatureFlagsRefreshInterval)
+
+	// Start Kafka consumer in goroutine, or the in-process bus consumer in DEV_MODE
+	if cfg.DevMode {
+		go events.StartLocal(workerCtx, devmode.Bus())
+	} else {
+		go events.StartKafka(workerCtx)
+	}
+
+	// Start retention janitor in goroutine
+	go retention.StartJanitor(workerCtx, cfg, repo)
+	go retention.StartPurger(workerCtx, cfg, repo)
+	go retention.StartReporter(workerCtx, cfg, repo)
+
+	// Start hot/cold tier mover
+	go tiering.StartMover(workerCtx, cfg, repo)
+
+	// Start thinning out long status histories (no-op unless enabled)
+	go statuscompaction.Start(workerCtx, cfg, repo)
+
+	// Start resending failed messages under tenant retry policies
+	go retries.StartOrchestrator(workerCtx, cfg, repo)
+
+	// Start looking up sends whose delivery reports never arrived (no-op unless configured)
+	go reconcile.Start(workerCtx, cfg, repo)
+
+	// Start background job runner
+	maintenance.RegisterJobs(repo)
+	storagereport.RegisterJob(cfg, repo)
+	go jobs.Start(workerCtx, cfg, repo)
+
+	// Start building a storage usage report each day
+	go storagereport.Start(workerCtx, cfg, repo)
+
+	// Start change-stream watchers driving notification fan-out, one per database
+	for _, scope := range repo.Scopes() {
+		go changestream.Start(scope.Context(workerCtx), repo)
+	}
+
+	// Start ingest anomaly analyzer (no-op unless enabled)
+	go anomaly.Start(workerCtx, cfg)
+
+	// Compare the shadow backend with the primary storage (no-op unless shadow writes are on)
+	go shadow.StartComparer(workerCtx, cfg, repo)
+
+	// Watch for a stalled consumer and dead-letter growth, and probe the
+	// pipeline end to end (Kafka only)
+	if !cfg.DevMode {
+		go watchdog.Start(workerCtx, cfg)
+		go canary.Start(workerCtx, cfg, repo)
+	}
+
+	// kill -USR1 <pid> logs a goroutine dump without stopping the service
+	go diagnostics.DumpOnSignal(workerCtx)
+
+	readiness.SetReady()
+	log.Println("✓ Startup complete, ready to serve")
+
+	// Wait for graceful shutdown
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	// Shut the listeners down together so they share the drain deadline
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("Server on %s forced to shutdown: %v", server.Addr, err)
+			}
+		}(server)
 	}
+	wg.Wait()
 
-	if err := db.DisconnectMongo(); err != nil {
+	if err := changeevents.Close(); err != nil {
+		log.Println("Error closing change-event publisher:", err)
+	}
+
+	if err := deadletter.Close(); err != nil {
+		log.Println("Error closing dead-letter publisher:", err)
+	}
+
+	if err := forwarding.Close(); err != nil {
+		log.Println("Error closing forwarding publisher:", err)
+	}
+
+	if err := ratelimit.Close(); err != nil {
+		log.Println("Error closing Redis client:", err)
+	}
+
+	if err := clients.Disconnect(); err != nil {
 		log.Println("Error disconnecting MongoDB:", err)
 	}
 
 	log.Println("Server exited")
 }
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+}
+
+// serve runs one listener until Shutdown; any other error is fatal
+func serve(server *http.Server, useTLS, clientCerts bool) {
+	var err error
+	if useTLS {
+		log.Printf("HTTPS server listening on %s (client certificates required: %t)", server.Addr, clientCerts)
+		// Certificates come from TLSConfig, so no file arguments
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("HTTP server listening on %s", server.Addr)
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Could not listen on %s: %v\n", server.Addr, err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"smsstore/pkg/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generatorOptions shape the synthetic events.
+type generatorOptions struct {
+	users    int
+	statuses []weightedStatus
+	minBytes int
+	maxBytes int
+	senders  int
+	tenantID string
+	seed     int64
+}
+
+type weightedStatus struct {
+	status string
+	weight int
+}
+
+// parseWeights reads status=weight pairs, e.g. "successful=90,unsuccessful=10".
+func parseWeights(raw string) ([]weightedStatus, error) {
+	var weights []weightedStatus
+	for _, pair := range strings.Split(raw, ",") {
+		status, rawWeight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		weight, err := strconv.Atoi(rawWeight)
+		if !ok || status == "" || err != nil || weight < 0 {
+			return nil, fmt.Errorf("-statuses: invalid pair %q (want status=weight)", pair)
+		}
+		if weight > 0 {
+			weights = append(weights, weightedStatus{status: status, weight: weight})
+		}
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("-statuses: no status has a positive weight")
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i].status < weights[j].status })
+	return weights, nil
+}
+
+const bodyAlphabet = "abcdefghijklmnopqrstuvwxyz ABCDEFGHIJKLMNOPQRSTUVWXYZ 0123456789 .,"
+
+// generator builds events. It is not safe for concurrent use.
+type generator struct {
+	opts        generatorOptions
+	rand        *rand.Rand
+	runID       string
+	totalWeight int
+}
+
+func newGenerator(opts generatorOptions) *generator {
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	g := &generator{opts: opts, rand: rand.New(rand.NewSource(opts.seed))}
+	for _, status := range opts.statuses {
+		g.totalWeight += status.weight
+	}
+	g.runID = g.hex(4)
+	return g
+}
+
+// phoneNumber is the user with the given index; reads use the same numbering.
+func phoneNumber(index int) string {
+	return fmt.Sprintf("+1555%07d", index)
+}
+
+// next returns the JSON payload of a new event and its phone number, the
+// record key, so a user's events stay ordered on one partition.
+func (g *generator) next() ([]byte, string, error) {
+	event := models.SmsEvent{
+		SchemaVersion: models.SchemaVersion,
+		PhoneNumber:   phoneNumber(g.rand.Intn(g.opts.users)),
+		Message:       g.body(),
+		Status:        g.status(),
+		Provider:      "loadgen",
+		CampaignID:    "loadgen",
+		TenantID:      g.opts.tenantID,
+		// Lets a run's messages be found (metadata.loadgen_run) and cleaned up
+		Metadata: map[string]string{"loadgen_run": g.runID},
+	}
+	if event.Status == "successful" || event.Status == "delivered" {
+		event.ProviderMessageID = "LG" + g.hex(12)
+	}
+	if g.opts.senders > 0 {
+		event.SenderID = fmt.Sprintf("LOADGEN%d", g.rand.Intn(g.opts.senders))
+	}
+	payload, err := json.Marshal(event)
+	return payload, event.PhoneNumber, err
+}
+
+func (g *generator) status() string {
+	pick := g.rand.Intn(g.totalWeight)
+	for _, status := range g.opts.statuses {
+		if pick < status.weight {
+			return status.status
+		}
+		pick -= status.weight
+	}
+	return g.opts.statuses[len(g.opts.statuses)-1].status
+}
+
+func (g *generator) body() string {
+	size := g.opts.minBytes + g.rand.Intn(g.opts.maxBytes-g.opts.minBytes+1)
+	body := make([]byte, size)
+	for i := range body {
+		body[i] = bodyAlphabet[g.rand.Intn(len(bodyAlphabet))]
+	}
+	return string(body)
+}
+
+func (g *generator) hex(n int) string {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recorder collects the outcome of every operation of one kind.
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+	// skipped counts operations not started because the target was saturated
+	skipped int
+}
+
+func (r *recorder) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+func (r *recorder) skip() {
+	r.mu.Lock()
+	r.skipped++
+	r.mu.Unlock()
+}
+
+// summary formats the outcome counts and the latency percentiles of the
+// successful operations.
+func (r *recorder) summary() string {
+	r.mu.Lock()
+	latencies := append([]time.Duration(nil), r.latencies...)
+	counts := fmt.Sprintf("ok=%d errors=%d", len(latencies), r.errors)
+	if r.skipped > 0 {
+		counts += fmt.Sprintf(" skipped=%d", r.skipped)
+	}
+	r.mu.Unlock()
+
+	if len(latencies) == 0 {
+		return counts
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return fmt.Sprintf("%s p50=%s p90=%s p99=%s max=%s", counts,
+		percentile(latencies, 0.50), percentile(latencies, 0.90), percentile(latencies, 0.99),
+		latencies[len(latencies)-1].Round(time.Microsecond))
+}
+
+// percentile uses the nearest-rank method on sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank].Round(time.Microsecond)
+}
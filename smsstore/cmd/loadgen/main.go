@@ -0,0 +1,136 @@
+// Command loadgen produces synthetic SmsEvents to Kafka at a fixed rate and,
+// optionally, reads users' messages back through the API, then reports
+// throughput and latency percentiles. Use it to benchmark consumer and storage
+// changes against a disposable environment: it writes real messages.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options are the command-line settings of one load run.
+type options struct {
+	brokers   []string
+	topic     string
+	rate      float64
+	duration  time.Duration
+	batchSize int
+	// compression is the codec produced batches use, like a compressing producer
+	compression string
+
+	generator generatorOptions
+
+	readURL         string
+	readRate        float64
+	readConcurrency int
+	readToken       string
+}
+
+func parseFlags(args []string) (options, error) {
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	brokers := fs.String("brokers", getenv("KAFKA_BROKERS", "localhost:9092"), "comma-separated Kafka brokers")
+	topic := fs.String("topic", getenv("KAFKA_TOPIC", "sms_events"), "topic to produce to")
+	rate := fs.Float64("rate", 100, "events produced per second")
+	duration := fs.Duration("duration", time.Minute, "how long to run")
+	batchSize := fs.Int("batch-size", 500, "most events written per produce call")
+	compression := fs.String("compression", "none", "batch compression: none, gzip, snappy, lz4 or zstd")
+	users := fs.Int("users", 1000, "distinct phone numbers events are spread over")
+	statuses := fs.String("statuses", "successful=85,unsuccessful=5,retrying=5,blocked=5", "status=weight pairs")
+	minBytes := fs.Int("min-bytes", 20, "shortest message body")
+	maxBytes := fs.Int("max-bytes", 160, "longest message body")
+	senders := fs.Int("senders", 0, "distinct sender IDs; 0 sends without one")
+	tenant := fs.String("tenant", "", "tenant ID set on every event")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one")
+	readURL := fs.String("read-url", "", "SMS store base URL to read from (e.g. http://localhost:8081); empty disables reads")
+	readRate := fs.Float64("read-rate", 10, "message listings requested per second")
+	readConcurrency := fs.Int("read-concurrency", 8, "concurrent listing requests")
+	readToken := fs.String("read-token", "", "bearer token sent with reads")
+	fs.Parse(args)
+
+	weights, err := parseWeights(*statuses)
+	if err != nil {
+		return options{}, err
+	}
+	opts := options{
+		brokers:     strings.Split(*brokers, ","),
+		topic:       *topic,
+		rate:        *rate,
+		duration:    *duration,
+		batchSize:   *batchSize,
+		compression: strings.ToLower(*compression),
+		generator: generatorOptions{
+			users:    *users,
+			statuses: weights,
+			minBytes: *minBytes,
+			maxBytes: *maxBytes,
+			senders:  *senders,
+			tenantID: *tenant,
+			seed:     *seed,
+		},
+		readURL:         *readURL,
+		readRate:        *readRate,
+		readConcurrency: *readConcurrency,
+		readToken:       *readToken,
+	}
+	return opts, opts.validate()
+}
+
+func (o options) validate() error {
+	switch {
+	case o.rate <= 0:
+		return errors.New("-rate must be positive")
+	case o.duration <= 0:
+		return errors.New("-duration must be positive")
+	case o.batchSize < 1:
+		return errors.New("-batch-size must be at least 1")
+	case !slices.Contains([]string{"none", "gzip", "snappy", "lz4", "zstd"}, o.compression):
+		return errors.New("-compression must be none, gzip, snappy, lz4 or zstd")
+	case o.generator.users < 1:
+		return errors.New("-users must be at least 1")
+	case o.generator.minBytes < 1 || o.generator.maxBytes < o.generator.minBytes:
+		return errors.New("-min-bytes must be at least 1 and no more than -max-bytes")
+	case o.generator.senders < 0:
+		return errors.New("-senders cannot be negative")
+	case o.readURL != "" && o.readRate <= 0:
+		return errors.New("-read-rate must be positive")
+	case o.readURL != "" && o.readConcurrency < 1:
+		return errors.New("-read-concurrency must be at least 1")
+	}
+	return nil
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
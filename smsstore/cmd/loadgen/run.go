@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"smsstore/internal/kafkawriter"
+	"smsstore/pkg/client"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// progressInterval is how often a running total is logged.
+const progressInterval = 10 * time.Second
+
+// produceTick is how often the producer tops up to the target rate.
+const produceTick = 10 * time.Millisecond
+
+func run(ctx context.Context, opts options) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	gen := newGenerator(opts.generator)
+	log.Printf("[LOADGEN] Run %s: %.1f events/s to %s for %s over %d users (seed %d)",
+		gen.runID, opts.rate, opts.topic, opts.duration, opts.generator.users, gen.opts.seed)
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(opts.brokers...),
+		Topic:        opts.topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    opts.batchSize,
+		BatchTimeout: time.Millisecond,
+		Compression:  kafkawriter.Codec(opts.compression),
+	}
+	defer writer.Close()
+
+	var produced atomic.Int64
+	var produceStats, readStats recorder
+	var wg sync.WaitGroup
+	if opts.readURL != "" {
+		log.Printf("[LOADGEN] Reading from %s at %.1f listings/s with %d workers", opts.readURL, opts.readRate, opts.readConcurrency)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(ctx, opts, gen.opts.seed, &readStats)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		reportProgress(ctx, &produced, &produceStats, &readStats, opts.readURL != "")
+	}()
+
+	started := time.Now()
+	err := produce(ctx, writer, gen, opts, &produced, &produceStats)
+	elapsed := time.Since(started)
+	cancel()
+	wg.Wait()
+
+	fmt.Printf("run %s: produced %d events in %s (%.1f/s, target %.1f/s)\n",
+		gen.runID, produced.Load(), elapsed.Round(time.Millisecond), float64(produced.Load())/elapsed.Seconds(), opts.rate)
+	fmt.Printf("  produce batches: %s\n", produceStats.summary())
+	if opts.readURL != "" {
+		fmt.Printf("  reads:           %s\n", readStats.summary())
+	}
+	fmt.Printf("  find the run's messages with metadata.loadgen_run=%s\n", gen.runID)
+	return err
+}
+
+// produce writes events in batches, each tick topping up to where the target
+// rate says the run should be. When Kafka can't keep up the run falls behind
+// rather than bursting, and the achieved rate is reported.
+func produce(ctx context.Context, writer *kafka.Writer, gen *generator, opts options, produced *atomic.Int64, stats *recorder) error {
+	ticker := time.NewTicker(produceTick)
+	defer ticker.Stop()
+
+	started := time.Now()
+	batch := make([]kafka.Message, 0, opts.batchSize)
+	lastErr := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		due := int64(time.Since(started).Seconds()*opts.rate) - produced.Load()
+		for due > 0 && ctx.Err() == nil {
+			batch = batch[:0]
+			for int64(len(batch)) < due && len(batch) < opts.batchSize {
+				payload, key, err := gen.next()
+				if err != nil {
+					return err
+				}
+				batch = append(batch, kafka.Message{Key: []byte(key), Value: payload})
+			}
+
+			writeStarted := time.Now()
+			err := writer.WriteMessages(ctx, batch...)
+			if ctx.Err() != nil {
+				return nil
+			}
+			stats.record(time.Since(writeStarted), err)
+			if err != nil {
+				// Failures are counted in the summary; only log each new kind once
+				if err.Error() != lastErr {
+					log.Printf("[LOADGEN] Failed to produce %d events: %v", len(batch), err)
+					lastErr = err.Error()
+				}
+				break
+			}
+			produced.Add(int64(len(batch)))
+			due -= int64(len(batch))
+		}
+	}
+}
+
+// read lists random users' messages at the read rate until ctx is done.
+func read(ctx context.Context, opts options, seed int64, stats *recorder) {
+	clientOpts := []client.Option{
+		// Retries would hide the latency being measured
+		client.WithRetries(0, 0),
+		client.WithHTTPClient(&http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.readConcurrency},
+		}),
+	}
+	if opts.readToken != "" {
+		clientOpts = append(clientOpts, client.WithHeader("Authorization", "Bearer "+opts.readToken))
+	}
+	api := client.New(opts.readURL, clientOpts...)
+
+	requests := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.readConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range requests {
+				started := time.Now()
+				_, err := api.GetMessages(ctx, userID, &client.GetMessagesOptions{Descending: true})
+				if ctx.Err() != nil {
+					return
+				}
+				stats.record(time.Since(started), err)
+			}
+		}()
+	}
+
+	random := rand.New(rand.NewSource(seed + 1))
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.readRate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			close(requests)
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+		select {
+		case requests <- phoneNumber(random.Intn(opts.generator.users)):
+		default:
+			// Every worker is busy: the API is slower than the read rate allows
+			stats.skip()
+		}
+	}
+}
+
+func reportProgress(ctx context.Context, produced *atomic.Int64, produceStats, readStats *recorder, reading bool) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		line := fmt.Sprintf("[LOADGEN] produced=%d batches: %s", produced.Load(), produceStats.summary())
+		if reading {
+			line += " | reads: " + readStats.summary()
+		}
+		log.Print(line)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"smsstore/internal/config"
+	"smsstore/internal/consumer"
+	"smsstore/internal/db"
+	"smsstore/internal/importer"
+	"smsstore/internal/migrations"
+	"smsstore/internal/repository"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// connect returns the repository for cfg and a func disconnecting its
+// clients.
+func connect(cfg *config.Config) (*repository.Repository, func()) {
+	clients := db.New(cfg)
+	return repository.New(cfg, clients), func() {
+		if err := clients.Disconnect(); err != nil {
+			log.Printf("Error disconnecting MongoDB: %v", err)
+		}
+	}
+}
+
+func runBackfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	topic := fs.String("topic", "", "topic to read (defaults to KAFKA_TOPIC)")
+	partition := fs.Int("partition", 0, "partition to read")
+	startOffset := fs.Int64("start-offset", 0, "first offset to re-ingest (inclusive)")
+	endOffset := fs.Int64("end-offset", -1, "last offset to re-ingest (exclusive); required")
+	dryRun := fs.Bool("dry-run", false, "decode events without storing them")
+	fs.Parse(args)
+
+	if *endOffset <= *startOffset {
+		return errors.New("-end-offset is required and must be greater than -start-offset")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repo, disconnect := connect(cfg)
+	defer disconnect()
+	if *topic == "" {
+		*topic = cfg.KafkaTopic
+	}
+
+	// No GroupID: reading a fixed partition never commits offsets for the live consumer group
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   cfg.KafkaBrokers,
+		Topic:     *topic,
+		Partition: *partition,
+		MaxBytes:  cfg.KafkaMaxBytes,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(*startOffset); err != nil {
+		return fmt.Errorf("failed to seek to offset %d: %w", *startOffset, err)
+	}
+
+	ctx := context.Background()
+	events := consumer.New(cfg, repo, repo)
+	var stored, failed int
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		msg, err := reader.ReadMessage(readCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to read at offset %d (stored=%d failed=%d): %w", reader.Offset(), stored, failed, err)
+		}
+		if msg.Offset >= *endOffset {
+			break
+		}
+
+		if *dryRun {
+			var event map[string]interface{}
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				failed++
+				log.Printf("offset %d: invalid payload: %v", msg.Offset, err)
+				continue
+			}
+			stored++
+		} else if err := events.ProcessRecord(ctx, msg); err != nil {
+			failed++
+		} else {
+			stored++
+		}
+
+		if msg.Offset+1 >= *endOffset {
+			break
+		}
+	}
+
+	fmt.Printf("backfill %s[%d] offsets %d-%d: stored=%d failed=%d\n", *topic, *partition, *startOffset, *endOffset, stored, failed)
+	return nil
+}
+
+func runExport(args []string) error {
+	if len(args) != 2 || args[0] != "user" {
+		return errors.New("usage: smsctl export user <id>")
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repo, disconnect := connect(cfg)
+	defer disconnect()
+
+	messages, err := repo.GetUserMessages(context.Background(), args[1], repository.MessageQuery{})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"user_id":  args[1],
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	confirm := fs.Bool("yes", false, "confirm the deletion")
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if len(rest) != 2 || rest[0] != "user" {
+		return errors.New("usage: smsctl delete -yes user <id>")
+	}
+	if !*confirm {
+		return fmt.Errorf("refusing to delete user %s without -yes", rest[1])
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repo, disconnect := connect(cfg)
+	defer disconnect()
+
+	// The user's messages can be in any region's cluster or tenant database
+	deleted := false
+	for _, scope := range repo.Scopes() {
+		found, err := repo.DeleteUser(scope.Context(context.Background()), rest[1])
+		if err != nil {
+			return fmt.Errorf("deleting user %s%s: %w", rest[1], scope.Label(), err)
+		}
+		deleted = deleted || found
+	}
+	if !deleted {
+		return fmt.Errorf("user %s not found", rest[1])
+	}
+	fmt.Printf("deleted user %s\n", rest[1])
+	return nil
+}
+
+func runEnsureIndexes(args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repo, disconnect := connect(cfg)
+	defer disconnect()
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+	for _, scope := range repo.Scopes() {
+		database, err := repo.Database(scope)
+		if err != nil {
+			return err
+		}
+		if err := migrations.Run(ctx, database); err != nil {
+			return fmt.Errorf("database %s%s: %w", database.Name(), scope.Label(), err)
+		}
+	}
+	return nil
+}
+
+func runValidateConfig(args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("configuration is valid\n")
+	fmt.Printf("  KAFKA_BROKERS=%v KAFKA_TOPIC=%s KAFKA_GROUP_ID=%s\n", cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroupID)
+	fmt.Printf("  KAFKA_MIN_BYTES=%d KAFKA_MAX_BYTES=%d KAFKA_MAX_WAIT=%s KAFKA_QUEUE_CAPACITY=%d KAFKA_COMMIT_INTERVAL=%s KAFKA_START_OFFSET=%s\n",
+		cfg.KafkaMinBytes, cfg.KafkaMaxBytes, cfg.KafkaMaxWait, cfg.KafkaQueueCapacity, cfg.KafkaCommitInterval, cfg.KafkaStartOffset)
+	fmt.Printf("  KAFKA_PRODUCER_COMPRESSION=%s KAFKA_PRODUCER_BATCH_BYTES=%d\n", cfg.KafkaProducerCompression, cfg.KafkaProducerBatchBytes)
+	fmt.Printf("  DB_NAME=%s SERVER_PORT=%s ADMIN_PORT=%s MONGO_QUERY_TIMEOUT=%s\n", cfg.DBName, cfg.ServerPort, cfg.AdminPort, cfg.MongoQueryTimeout)
+	fmt.Printf("  TENANT_DATABASES=%v\n", cfg.TenantDatabases)
+	fmt.Printf("  MAX_INFLIGHT_REQUESTS=%d LOAD_SHED_RETRY_AFTER=%s REQUEST_DEADLINE_MARGIN=%s\n", cfg.MaxInflightRequests, cfg.LoadShedRetryAfter, cfg.RequestDeadlineMargin)
+	fmt.Printf("  STARTUP_MAX_WAIT=%s STARTUP_RETRY_BACKOFF=%s\n", cfg.StartupMaxWait, cfg.StartupRetryBackoff)
+	fmt.Printf("  MONGO_WRITE_CONCERN=%v MONGO_READ_PREFERENCE=%v MONGO_ANALYTICS_URI set=%t\n", cfg.MongoWriteConcerns, cfg.MongoReadPreferences, cfg.MongoAnalyticsURI != "")
+	// URIs can carry credentials, so only the regions are printed
+	regions := make([]string, 0, len(cfg.MongoRegionURIs))
+	for region := range cfg.MongoRegionURIs {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	fmt.Printf("  MONGO_REGION_URIS regions=%v KAFKA_REGION_HEADER=%s\n", regions, cfg.KafkaRegionHeader)
+	fmt.Printf("  COUNTRY_REGIONS=%v PHONE_DEFAULT_COUNTRY=%s\n", cfg.CountryRegions, cfg.PhoneDefaultCountry)
+	fmt.Printf("  ADMIN_API_TOKEN set=%t FIREHOSE_RATE_LIMIT=%.1f FIREHOSE_BURST=%d\n", cfg.AdminAPIToken != "", cfg.FirehoseRateLimit, cfg.FirehoseBurst)
+	fmt.Printf("  ADMIN_ALLOWED_CIDRS=%v\n", cfg.AdminAllowedCIDRs)
+	fmt.Printf("  RBAC_POLICY_FILE=%s RBAC_RELOAD_INTERVAL=%s RBAC_JWT_SECRET set=%t\n", cfg.RBACPolicyFile, cfg.RBACReloadInterval, cfg.RBACJWTSecret != "")
+	fmt.Printf("  RATE_LIMIT_BACKEND=%s REDIS_ADDR=%s REDIS_DB=%d REDIS_TIMEOUT=%s\n", cfg.RateLimitBackend, cfg.RedisAddr, cfg.RedisDB, cfg.RedisTimeout)
+	fmt.Printf("  TLS=%t TLS_CLIENT_CA_FILE=%s TLS_RELOAD_INTERVAL=%s\n", cfg.TLSEnabled(), cfg.TLSClientCAFile, cfg.TLSReloadInterval)
+	fmt.Printf("  KAFKA_CLIENT_ID=%s KAFKA_SESSION_TIMEOUT=%s KAFKA_REBALANCE_TIMEOUT=%s KAFKA_HEARTBEAT_INTERVAL=%s\n",
+		cfg.KafkaClientID, cfg.KafkaSessionTimeout, cfg.KafkaRebalanceTimeout, cfg.KafkaHeartbeatInterval)
+	fmt.Printf("  KAFKA_PRIORITY_TOPIC=%s KAFKA_PRIORITY_WORKERS=%d KAFKA_PRIORITY_MAX_WAIT=%s KAFKA_PRIORITY_HEADER=%s PRIORITY_LATENCY_TARGET=%s\n",
+		cfg.KafkaPriorityTopic, cfg.KafkaPriorityWorkers, cfg.KafkaPriorityMaxWait, cfg.KafkaPriorityHeader, cfg.PriorityLatencyTarget)
+	fmt.Printf("  PROMOTIONAL_RATE_LIMIT=%.1f PROMOTIONAL_BURST=%d PROMOTIONAL_QUEUE_CAPACITY=%d\n",
+		cfg.PromotionalRateLimit, cfg.PromotionalBurst, cfg.PromotionalQueueCapacity)
+	fmt.Printf("  DEDUP_WINDOW=%s MAX_MESSAGE_BYTES=%d MAX_EVENT_BYTES=%d STATUS_TRANSITION_MODE=%s\n", cfg.DedupWindow, cfg.MaxMessageBytes, cfg.MaxEventBytes, cfg.StatusTransitionMode)
+	fmt.Printf("  MESSAGE_BODY_ENCODING=%s MESSAGE_BODY_COMPRESSION_MIN_BYTES=%d\n", cfg.MessageBodyEncoding, cfg.MessageBodyCompressionMinBytes)
+	fmt.Printf("  KAFKA_HEADER_MAPPING=%v\n", cfg.KafkaHeaderMapping)
+	fmt.Printf("  LOG_SAMPLE_RATE=%d LOG_RATE_LIMIT=%.1f LOG_DEBUG=%t\n", cfg.LogSampleRate, cfg.LogRateLimit, cfg.LogDebug)
+	fmt.Printf("  USER_CACHE_SIZE=%d USER_CACHE_TTL=%s\n", cfg.UserCacheSize, cfg.UserCacheTTL)
+	fmt.Printf("  STATS_CACHE_SIZE=%d STATS_CACHE_TTL=%s STATS_CACHE_STALE_WHILE_REVALIDATE=%s\n", cfg.StatsCacheSize, cfg.StatsCacheTTL, cfg.StatsCacheStale)
+	fmt.Printf("  WEBHOOK_MAX_ATTEMPTS=%d WEBHOOK_RETRY_BACKOFF=%s WEBHOOK_TIMEOUT=%s WEBHOOK_SUBSCRIPTION_TTL=%s\n",
+		cfg.WebhookMaxAttempts, cfg.WebhookRetryBackoff, cfg.WebhookTimeout, cfg.WebhookSubscriptionTTL)
+	fmt.Printf("  TENANT_CONFIG_TTL=%s\n", cfg.TenantConfigTTL)
+	fmt.Printf("  RETRY_ORCHESTRATOR_INTERVAL=%s RESEND_TOPIC=%s\n", cfg.RetryOrchestratorInterval, cfg.ResendTopic)
+	// Status URLs may carry credentials, so only their providers are shown
+	reconciled := make([]string, 0, len(cfg.ReconcileStatusURLs))
+	for provider := range cfg.ReconcileStatusURLs {
+		reconciled = append(reconciled, provider)
+	}
+	sort.Strings(reconciled)
+	fmt.Printf("  RECONCILE_STATUS_URLS providers=%v RECONCILE_STALE_AFTER=%v RECONCILE_MAX_AGE=%s RECONCILE_INTERVAL=%s\n",
+		reconciled, cfg.ReconcileStaleAfter, cfg.ReconcileMaxAge, cfg.ReconcileInterval)
+	fmt.Printf("  MESSAGE_CHECKSUM_KEY set=%t\n", cfg.MessageChecksumKey != "")
+	fmt.Printf("  RETENTION_DAYS=%d RETENTION_INTERVAL=%s\n", cfg.RetentionDays, cfg.RetentionInterval)
+	fmt.Printf("  STORAGE_REPORT_INTERVAL=%s STORAGE_REPORT_TOP_USERS=%d\n", cfg.StorageReportInterval, cfg.StorageReportTopUsers)
+	fmt.Printf("  HOT_TIER_DAYS=%d TIERING_INTERVAL=%s\n", cfg.HotTierDays, cfg.TieringInterval)
+	fmt.Printf("  STATUS_COMPACTION_INTERVAL=%s STATUS_COMPACTION_MIN_STATUSES=%d STATUS_COMPACTION_ARCHIVE=%t\n",
+		cfg.StatusCompactionInterval, cfg.StatusCompactionMinStatuses, cfg.StatusCompactionArchive)
+	fmt.Printf("  USER_MESSAGE_LIMIT=%d USER_MESSAGE_LIMIT_ARCHIVE=%t\n", cfg.UserMessageLimit, cfg.UserMessageLimitArchive)
+	fmt.Printf("  JOB_WORKERS=%d JOB_POLL_INTERVAL=%s JOB_LEASE_DURATION=%s\n", cfg.JobWorkers, cfg.JobPollInterval, cfg.JobLeaseDuration)
+	fmt.Printf("  PROVIDER_HEALTH_WINDOW=%s PROVIDER_LATENCY_TARGET=%s PROVIDER_HEALTH_MIN_SAMPLES=%d\n",
+		cfg.ProviderHealthWindow, cfg.ProviderLatencyTarget, cfg.ProviderHealthMinSamples)
+	fmt.Printf("  DEV_MODE=%t DEV_PROVIDER_FAIL_RATE=%.2f DEV_PROVIDER_MAX_LATENCY=%s\n",
+		cfg.DevMode, cfg.DevProviderFailRate, cfg.DevProviderMaxLatency)
+	fmt.Printf("  CHANGE_EVENTS_ENABLED=%t CHANGE_EVENTS_TOPIC=%s\n", cfg.ChangeEventsEnabled, cfg.ChangeEventsTopic)
+	fmt.Printf("  DEAD_LETTER_ENABLED=%t DEAD_LETTER_TOPIC=%s\n", cfg.DeadLetterEnabled, cfg.DeadLetterTopic)
+	fmt.Printf("  SHADOW_WRITES=%t SHADOW_COMPARE_INTERVAL=%s SHADOW_COMPARE_SAMPLE=%d SHADOW_COMPARE_WINDOW=%s\n",
+		cfg.ShadowWrites, cfg.ShadowCompareInterval, cfg.ShadowCompareSample, cfg.ShadowCompareWindow)
+	fmt.Printf("  CANARY_INTERVAL=%s CANARY_TIMEOUT=%s CANARY_PHONE_NUMBER=%s\n", cfg.CanaryInterval, cfg.CanaryTimeout, cfg.CanaryPhoneNumber)
+	fmt.Printf("  WATCHDOG_INTERVAL=%s CONSUMER_STALL_AFTER=%s DEAD_LETTER_GROWTH_THRESHOLD=%d DEAD_LETTER_GROWTH_WINDOW=%s\n",
+		cfg.WatchdogInterval, cfg.ConsumerStallAfter, cfg.DeadLetterGrowthThreshold, cfg.DeadLetterGrowthWindow)
+	fmt.Printf("  ALERT_WEBHOOK_URLS=%d ALERT_SLACK_WEBHOOK_URL set=%t ALERT_PAGERDUTY_ROUTING_KEY set=%t ALERT_COOLDOWN=%s\n",
+		len(cfg.AlertWebhookURLs), cfg.AlertSlackWebhookURL != "", cfg.AlertPagerDutyRoutingKey != "", cfg.AlertCooldown)
+	fmt.Printf("  CONSUMER_PAUSE_ERROR_RATE=%.2f CONSUMER_PAUSE_WINDOW=%s CONSUMER_PAUSE_MIN_EVENTS=%d\n",
+		cfg.ConsumerPauseErrorRate, cfg.ConsumerPauseWindow, cfg.ConsumerPauseMinEvents)
+	fmt.Printf("  FEATURE_FLAGS_FILE=%s FEATURE_FLAGS_REFRESH_INTERVAL=%s\n", cfg.FeatureFlagsFile, cfg.FeatureFlagsRefreshInterval)
+	featureNames := make([]string, 0, len(cfg.FeatureFlags))
+	for name := range cfg.FeatureFlags {
+		featureNames = append(featureNames, name)
+	}
+	sort.Strings(featureNames)
+	for _, name := range featureNames {
+		setting := cfg.FeatureFlags[name]
+		if setting.Enabled != nil {
+			fmt.Printf("  FEATURE_FLAGS %s=%t\n", name, *setting.Enabled)
+		}
+		tenants := make([]string, 0, len(setting.Tenants))
+		for tenant := range setting.Tenants {
+			tenants = append(tenants, tenant)
+		}
+		sort.Strings(tenants)
+		for _, tenant := range tenants {
+			fmt.Printf("  FEATURE_FLAGS %s@%s=%t\n", name, tenant, setting.Tenants[tenant])
+		}
+	}
+	for _, rule := range cfg.ForwardingRules {
+		fmt.Printf("  FORWARDING_RULES topic=%s match=%v\n", rule.Topic, rule.Match)
+	}
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "input format, csv or jsonl (defaults to the file extension)")
+	errorsPath := fs.String("errors", "", "where to write rejected records as JSON lines (defaults to <file>.errors.jsonl)")
+	dryRun := fs.Bool("dry-run", false, "validate records without storing them")
+	region := fs.String("region", "", "region whose cluster receives the records (defaults to the default cluster)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: smsctl import [-format csv|jsonl] [-errors path] [-dry-run] [-region name] <file>")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = importer.FormatFromPath(path)
+	}
+	if *errorsPath == "" {
+		*errorsPath = path + ".errors.jsonl"
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repo, disconnect := connect(cfg)
+	defer disconnect()
+	*region = strings.ToUpper(*region)
+	if !repo.HasRegion(*region) {
+		return fmt.Errorf("unknown region %q", *region)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	report, err := os.Create(*errorsPath)
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	// Ctrl-C stops between records; re-running the same file resumes safely
+	ctx, stop := signal.NotifyContext(repository.WithRegion(context.Background(), *region), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	progress, err := importer.Run(ctx, consumer.New(cfg, repo, repo), file, importer.Options{
+		Format:           *format,
+		DryRun:           *dryRun,
+		Errors:           report,
+		ProgressInterval: 10 * time.Second,
+		OnProgress: func(p importer.Progress) {
+			percent := 100.0
+			if info.Size() > 0 {
+				percent = float64(p.BytesRead) * 100 / float64(info.Size())
+			}
+			rate := float64(p.Records) / math.Max(p.Elapsed.Seconds(), 0.001)
+			log.Printf("%.1f%% records=%d stored=%d duplicates=%d rejected=%d (%.0f/s)",
+				percent, p.Records, p.Stored, p.Duplicates, p.Rejected, rate)
+		},
+	})
+	mode := ""
+	if *dryRun {
+		mode = " (dry run, nothing stored)"
+	}
+	fmt.Printf("import %s: records=%d stored=%d duplicates=%d rejected=%d in %s%s\n",
+		path, progress.Records, progress.Stored, progress.Duplicates, progress.Rejected, progress.Elapsed.Round(time.Millisecond), mode)
+	if progress.Rejected > 0 {
+		fmt.Printf("rejected records written to %s\n", *errorsPath)
+	} else {
+		report.Close()
+		os.Remove(*errorsPath)
+	}
+	if err != nil {
+		return fmt.Errorf("import stopped (re-run to resume; stored records are skipped): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Command smsctl runs operational tasks against the SMS store using the same
+// configuration (environment variables) and internal packages as the service.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `Usage: smsctl <command> [arguments]
+
+Commands:
+  backfill         Re-ingest events from a Kafka partition offset range
+  export user <id> Print a user's stored messages as JSON
+  import <file>    Import historical messages from a CSV or JSONL file
+  delete user <id> Delete a user and all of their messages
+  ensure-indexes   Apply pending schema migrations (indexes included)
+  validate-config  Load and validate configuration from the environment
+
+Run 'smsctl <command> -h' for command flags.
+`
+
+func main() {
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "backfill":
+		err = runBackfill(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "delete":
+		err = runDelete(os.Args[2:])
+	case "ensure-indexes":
+		err = runEnsureIndexes(os.Args[2:])
+	case "validate-config":
+		err = runValidateConfig(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smsctl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
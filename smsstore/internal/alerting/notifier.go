@@ -0,0 +1,139 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"smsstore/internal/config"
+	"sync"
+	"time"
+)
+
+// Alert is the payload delivered to generic webhooks.
+type Alert struct {
+	Name     string            `json:"name"`
+	Severity string            `json:"severity"`
+	Summary  string            `json:"summary"`
+	Details  map[string]string `json:"details,omitempty"`
+	FiredAt  time.Time         `json:"fired_at"`
+}
+
+// Notifier delivers alerts to the configured webhooks, Slack and PagerDuty,
+// suppressing repeats of the same alert name within the cooldown window.
+type Notifier struct {
+	webhookURLs  []string
+	slackURL     string
+	pagerDutyKey string
+	pagerDutyURL string
+	cooldown     time.Duration
+	client       *http.Client
+
+	mu        sync.Mutex
+	lastFired map[string]time.Time
+}
+
+// NewNotifier builds a notifier from the shared alert configuration.
+func NewNotifier(cfg *config.Config) *Notifier {
+	return &Notifier{
+		webhookURLs:  cfg.AlertWebhookURLs,
+		slackURL:     cfg.AlertSlackWebhookURL,
+		pagerDutyKey: cfg.AlertPagerDutyRoutingKey,
+		pagerDutyURL: cfg.AlertPagerDutyURL,
+		cooldown:     cfg.AlertCooldown,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		lastFired:    map[string]time.Time{},
+	}
+}
+
+// Fire sends the alert to every destination unless it is cooling down.
+// Delivery errors are logged; alerts are best-effort.
+func (n *Notifier) Fire(ctx context.Context, alert Alert) {
+	if alert.FiredAt.IsZero() {
+		alert.FiredAt = time.Now().UTC()
+	}
+
+	n.mu.Lock()
+	if last, ok := n.lastFired[alert.Name]; ok && alert.FiredAt.Sub(last) < n.cooldown {
+		n.mu.Unlock()
+		return
+	}
+	n.lastFired[alert.Name] = alert.FiredAt
+	n.mu.Unlock()
+
+	log.Printf("[ALERT] %s (%s): %s", alert.Name, alert.Severity, alert.Summary)
+
+	for _, url := range n.webhookURLs {
+		if err := n.post(ctx, url, alert); err != nil {
+			log.Printf("[ALERT] Webhook delivery failed: %v", err)
+		}
+	}
+	if n.slackURL != "" {
+		text := fmt.Sprintf(":rotating_light: *%s* (%s)\n%s", alert.Name, alert.Severity, alert.Summary)
+		for key, value := range alert.Details {
+			text += fmt.Sprintf("\n• %s: %s", key, value)
+		}
+		if err := n.post(ctx, n.slackURL, map[string]string{"text": text}); err != nil {
+			log.Printf("[ALERT] Slack delivery failed: %v", err)
+		}
+	}
+	if n.pagerDutyKey != "" {
+		if err := n.post(ctx, n.pagerDutyURL, n.pagerDutyEvent(alert)); err != nil {
+			log.Printf("[ALERT] PagerDuty delivery failed: %v", err)
+		}
+	}
+}
+
+// pagerDutyEvent builds an Events API v2 trigger. The alert name is the dedup
+// key, so a condition that keeps firing updates one incident.
+func (n *Notifier) pagerDutyEvent(alert Alert) map[string]interface{} {
+	severity := alert.Severity
+	switch severity {
+	case "critical", "error", "warning", "info":
+	default:
+		severity = "error"
+	}
+	source, err := os.Hostname()
+	if err != nil {
+		source = "smsstore"
+	}
+	return map[string]interface{}{
+		"routing_key":  n.pagerDutyKey,
+		"event_action": "trigger",
+		"dedup_key":    alert.Name,
+		"payload": map[string]interface{}{
+			"summary":        alert.Summary,
+			"source":         source,
+			"severity":       severity,
+			"timestamp":      alert.FiredAt.Format(time.RFC3339),
+			"component":      "smsstore",
+			"class":          alert.Name,
+			"custom_details": alert.Details,
+		},
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"smsstore/internal/alerting"
+	"smsstore/internal/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// failureStatuses are event statuses counted towards the failure rate.
+var failureStatuses = map[string]bool{
+	"unsuccessful": true,
+	"failed":       true,
+	"undelivered":  true,
+}
+
+type bucket struct {
+	total    int64
+	failures int64
+}
+
+var (
+	mu      sync.Mutex
+	current bucket
+)
+
+// RecordEvent counts an ingested event in the current minute.
+func RecordEvent(status string) {
+	mu.Lock()
+	current.total++
+	if failureStatuses[strings.ToLower(status)] {
+		current.failures++
+	}
+	mu.Unlock()
+}
+
+// RecordError counts an event that could not be processed as a failure.
+func RecordError() {
+	mu.Lock()
+	current.total++
+	current.failures++
+	mu.Unlock()
+}
+
+// Start closes a bucket every minute and compares it with the rolling baseline,
+// firing alerts on volume spikes, drops and elevated failure rates.
+// Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config) {
+	if !cfg.AnomalyEnabled {
+		log.Println("[ANOMALY] Analyzer disabled")
+		return
+	}
+	log.Printf("[ANOMALY] Analyzer started: baseline=%dm zscore=%.1f failureRate=%.2f",
+		cfg.AnomalyBaselineMinutes, cfg.AnomalyZScoreThreshold, cfg.AnomalyFailureRateThreshold)
+
+	notifier := alerting.NewNotifier(cfg)
+	history := make([]bucket, 0, cfg.AnomalyBaselineMinutes)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[ANOMALY] Analyzer stopped")
+			return
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		closed := current
+		current = bucket{}
+		mu.Unlock()
+
+		// Wait for a reasonably full baseline before judging anything
+		if len(history) >= cfg.AnomalyBaselineMinutes/2 {
+			for _, alert := range evaluate(closed, history, cfg) {
+				notifier.Fire(ctx, alert)
+			}
+		}
+
+		if len(history) == cfg.AnomalyBaselineMinutes {
+			history = history[1:]
+		}
+		history = append(history, closed)
+	}
+}
+
+// evaluate returns the alerts raised by a closed minute bucket.
+func evaluate(closed bucket, history []bucket, cfg *config.Config) []alerting.Alert {
+	mean, stddev := volumeStats(history)
+	var alerts []alerting.Alert
+
+	if mean >= cfg.AnomalyMinBaselineVolume {
+		// Floor the deviation so a perfectly flat baseline doesn't alert on noise
+		deviation := math.Max(stddev, math.Sqrt(mean))
+		zscore := (float64(closed.total) - mean) / deviation
+		details := map[string]string{
+			"volume":        fmt.Sprintf("%d", closed.total),
+			"baseline_mean": fmt.Sprintf("%.1f", mean),
+			"zscore":        fmt.Sprintf("%.2f", zscore),
+		}
+		if zscore >= cfg.AnomalyZScoreThreshold {
+			alerts = append(alerts, alerting.Alert{
+				Name:     "ingest_volume_spike",
+				Severity: "warning",
+				Summary:  fmt.Sprintf("Ingest volume %d/min is far above baseline %.1f/min", closed.total, mean),
+				Details:  details,
+			})
+		} else if zscore <= -cfg.AnomalyZScoreThreshold {
+			alerts = append(alerts, alerting.Alert{
+				Name:     "ingest_volume_drop",
+				Severity: "critical",
+				Summary:  fmt.Sprintf("Ingest volume %d/min is far below baseline %.1f/min", closed.total, mean),
+				Details:  details,
+			})
+		}
+	}
+
+	if closed.total > 0 {
+		rate := float64(closed.failures) / float64(closed.total)
+		baselineRate := baselineFailureRate(history)
+		if rate >= cfg.AnomalyFailureRateThreshold && rate > 2*baselineRate {
+			alerts = append(alerts, alerting.Alert{
+				Name:     "failure_rate_elevated",
+				Severity: "critical",
+				Summary:  fmt.Sprintf("Failure rate %.1f%% exceeds threshold (baseline %.1f%%)", rate*100, baselineRate*100),
+				Details: map[string]string{
+					"failures": fmt.Sprintf("%d", closed.failures),
+					"total":    fmt.Sprintf("%d", closed.total),
+				},
+			})
+		}
+	}
+	return alerts
+}
+
+func volumeStats(history []bucket) (mean float64, stddev float64) {
+	if len(history) == 0 {
+		return 0, 0
+	}
+	for _, b := range history {
+		mean += float64(b.total)
+	}
+	mean /= float64(len(history))
+	for _, b := range history {
+		diff := float64(b.total) - mean
+		stddev += diff * diff
+	}
+	return mean, math.Sqrt(stddev / float64(len(history)))
+}
+
+func baselineFailureRate(history []bucket) float64 {
+	var total, failures int64
+	for _, b := range history {
+		total += b.total
+		failures += b.failures
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(failures) / float64(total)
+}
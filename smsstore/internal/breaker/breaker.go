@@ -0,0 +1,172 @@
+// Package breaker pauses the consumer when too many of the events it handles
+// fail, e.g. a backlog of poison pills, so they don't flood the dead-letter
+// topic and the logs. A paused consumer stops fetching until an admin resumes
+// it; the offsets it has not committed are redelivered then.
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"smsstore/internal/alerting"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+)
+
+// second counts the events handled within one second of the window.
+type second struct {
+	unix     int64
+	events   int
+	failures int
+}
+
+var (
+	mu        sync.Mutex
+	threshold float64
+	window    time.Duration
+	minEvents int
+	notifier  *alerting.Notifier
+	// seconds is a ring of per-second counts covering the window
+	seconds []second
+
+	paused   bool
+	pausedAt time.Time
+	reason   string
+	tripped  models.ConsumerPauseStatus
+	// resumed is closed when a paused consumer is resumed
+	resumed chan struct{}
+)
+
+// Configure sets the error-rate threshold from app config. Until it is called,
+// or with CONSUMER_PAUSE_ERROR_RATE at zero, the consumer never pauses.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	threshold = cfg.ConsumerPauseErrorRate
+	window = cfg.ConsumerPauseWindow
+	minEvents = cfg.ConsumerPauseMinEvents
+	if threshold <= 0 {
+		log.Println("[BREAKER] Consumer pausing disabled")
+		return
+	}
+	notifier = alerting.NewNotifier(cfg)
+	seconds = make([]second, int((window+time.Second-1)/time.Second))
+	log.Printf("[BREAKER] Consumer pauses above an error rate of %.2f over %s (at least %d events)", threshold, window, minEvents)
+}
+
+// Record counts an event the consumer handled, and pauses the consumer if the
+// failures within the window cross the threshold.
+func Record(ctx context.Context, failed bool) {
+	mu.Lock()
+	if threshold <= 0 || paused {
+		mu.Unlock()
+		return
+	}
+	now := time.Now()
+	slot := &seconds[now.Unix()%int64(len(seconds))]
+	if slot.unix != now.Unix() {
+		*slot = second{unix: now.Unix()}
+	}
+	slot.events++
+	if failed {
+		slot.failures++
+	}
+
+	events, failures := counts(now)
+	if events < minEvents || float64(failures)/float64(events) <= threshold {
+		mu.Unlock()
+		return
+	}
+	paused = true
+	pausedAt = now.UTC()
+	reason = fmt.Sprintf("%d of %d events failed within %s", failures, events, window)
+	tripped = models.ConsumerPauseStatus{Events: events, Failures: failures}
+	resumed = make(chan struct{})
+	alerts := notifier
+	mu.Unlock()
+
+	metrics.ConsumerPaused.Set(1)
+	log.Printf("[BREAKER] Consumer paused: %s. Resume it with POST /v1/admin/consumer/resume", reason)
+	alerts.Fire(ctx, alerting.Alert{
+		Name:     "consumer_paused",
+		Severity: "critical",
+		Summary:  fmt.Sprintf("Consumer paused: %s", reason),
+		Details: map[string]string{
+			"events":     fmt.Sprint(events),
+			"failures":   fmt.Sprint(failures),
+			"error_rate": fmt.Sprintf("%.2f", float64(failures)/float64(events)),
+			"threshold":  fmt.Sprintf("%.2f", threshold),
+		},
+	})
+}
+
+// counts sums the seconds still within the window. Callers hold mu.
+func counts(now time.Time) (events int, failures int) {
+	oldest := now.Unix() - int64(len(seconds)) + 1
+	for _, slot := range seconds {
+		if slot.unix >= oldest {
+			events += slot.events
+			failures += slot.failures
+		}
+	}
+	return events, failures
+}
+
+// Wait blocks while the consumer is paused. Returns ctx's error if it is
+// cancelled first.
+func Wait(ctx context.Context) error {
+	mu.Lock()
+	wait := resumed
+	isPaused := paused
+	mu.Unlock()
+	if !isPaused {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wait:
+		return nil
+	}
+}
+
+// Resume lets a paused consumer fetch again, with the window's counts cleared
+// so the failures that tripped it don't trip it again straight away. Returns
+// false if the consumer was not paused.
+func Resume() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if !paused {
+		return false
+	}
+	paused = false
+	reason = ""
+	clear(seconds)
+	close(resumed)
+	metrics.ConsumerPaused.Set(0)
+	log.Println("[BREAKER] Consumer resumed")
+	return true
+}
+
+// Status reports whether the consumer is paused and the counts in the window.
+func Status() models.ConsumerPauseStatus {
+	mu.Lock()
+	defer mu.Unlock()
+	status := models.ConsumerPauseStatus{
+		Paused:             paused,
+		ErrorRateThreshold: threshold,
+		Window:             window.String(),
+	}
+	if paused {
+		status.Events, status.Failures = tripped.Events, tripped.Failures
+		at := pausedAt
+		status.PausedAt = &at
+		status.Reason = reason
+	} else if threshold > 0 {
+		status.Events, status.Failures = counts(time.Now())
+	}
+	return status
+}
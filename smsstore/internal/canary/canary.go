@@ -0,0 +1,163 @@
+// Package canary checks the whole ingest path end to end. Every
+// CANARY_INTERVAL it publishes a synthetic event for CANARY_PHONE_NUMBER to the
+// events topic and waits for the consumer to store it, so a pipeline that
+// stopped storing events without failing anything (a stuck consumer, a
+// misrouted topic, a broken stage) shows up in metrics and /healthz. Canary
+// events carry consumer.CanaryHeader: they are stored like any other, but
+// skip rollups, webhooks, forwarding and change events, and each is soft
+// deleted once seen.
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/consumer"
+	"smsstore/internal/kafkawriter"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/events"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Canary states reported in models.CanaryStatus.
+const (
+	StatusDisabled = "disabled"
+	StatusPending  = "pending"
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+)
+
+// metadataKey tags a canary event with the ID it is looked up by.
+const metadataKey = "canary_id"
+
+// pollInterval is how often the repository is checked for the canary message.
+const pollInterval = 500 * time.Millisecond
+
+var (
+	mu     sync.RWMutex
+	status = models.CanaryStatus{Status: StatusDisabled}
+)
+
+// Status returns the outcome of the latest probe.
+func Status() models.CanaryStatus {
+	mu.RLock()
+	defer mu.RUnlock()
+	return status
+}
+
+// Start probes the pipeline every CANARY_INTERVAL, first straight away.
+// Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if cfg.CanaryInterval <= 0 {
+		log.Println("[CANARY] Disabled")
+		return
+	}
+	mu.Lock()
+	status.Status = StatusPending
+	mu.Unlock()
+	log.Printf("[CANARY] Started: interval=%s timeout=%s user=%s", cfg.CanaryInterval, cfg.CanaryTimeout, cfg.CanaryPhoneNumber)
+
+	writer := kafkawriter.New(cfg, cfg.KafkaTopic, 10*time.Millisecond)
+	defer writer.Close()
+
+	ticker := time.NewTicker(cfg.CanaryInterval)
+	defer ticker.Stop()
+	for {
+		probe(ctx, repo, writer, cfg)
+		select {
+		case <-ctx.Done():
+			log.Println("[CANARY] Stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe publishes one canary event and waits up to CANARY_TIMEOUT for it to
+// be stored.
+func probe(ctx context.Context, repo *repository.Repository, writer *kafka.Writer, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.CanaryTimeout)
+	defer cancel()
+
+	id := primitive.NewObjectID().Hex()
+	payload, err := json.Marshal(models.SmsEvent{
+		SchemaVersion: events.SchemaVersion,
+		PhoneNumber:   cfg.CanaryPhoneNumber,
+		Message:       "canary " + id,
+		Status:        "successful",
+		Category:      events.CategoryTransactional,
+		Metadata:      map[string]string{metadataKey: id},
+	})
+	if err != nil {
+		record("error", 0, err)
+		return
+	}
+
+	start := time.Now()
+	err = writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(cfg.CanaryPhoneNumber),
+		Value:   payload,
+		Headers: []kafka.Header{{Key: consumer.CanaryHeader, Value: []byte("true")}},
+	})
+	if err != nil {
+		if ctx.Err() != nil && ctx.Err() != context.DeadlineExceeded {
+			return
+		}
+		record("publish_failed", 0, fmt.Errorf("publish: %w", err))
+		return
+	}
+
+	filter := repository.MessageFilter{Metadata: map[string]string{metadataKey: id}}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		results, err := repo.SearchMessages(ctx, cfg.CanaryPhoneNumber, filter, 1)
+		if err == nil && len(results) > 0 {
+			record("success", time.Since(start), nil)
+			if _, err := repo.SoftDeleteMessage(ctx, cfg.CanaryPhoneNumber, results[0].Message.MessageID); err != nil {
+				log.Printf("[CANARY] Failed to delete canary message %s: %v", results[0].Message.MessageID, err)
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				record("timeout", 0, fmt.Errorf("not stored within %s", cfg.CanaryTimeout))
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record updates the status and metrics with a probe's outcome.
+func record(result string, latency time.Duration, err error) {
+	metrics.CanaryProbes.WithLabelValues(result).Inc()
+	now := time.Now().UTC()
+
+	mu.Lock()
+	defer mu.Unlock()
+	status.LastRun = &now
+	if err != nil {
+		status.Status = StatusFailing
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		log.Printf("[CANARY] Probe failed (%d in a row): %v", status.ConsecutiveFailures, err)
+		return
+	}
+	metrics.CanaryLatency.Observe(latency.Seconds())
+	metrics.CanaryLastSuccess.Set(float64(now.Unix()))
+	status.Status = StatusOK
+	status.LastSuccess = &now
+	status.LatencyMs = latency.Milliseconds()
+	status.ConsecutiveFailures = 0
+	status.LastError = ""
+}
@@ -0,0 +1,105 @@
+package changeevents
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/kafkawriter"
+	"smsstore/pkg/models"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// writer is nil when change events are disabled, turning Publish into a no-op.
+var writer *kafka.Writer
+
+// Init configures the shared change-event writer from app config.
+// Must be called before the consumer starts.
+func Init(cfg *config.Config) {
+	if !cfg.ChangeEventsEnabled {
+		log.Println("[CHANGE-EVENTS] Publisher disabled")
+		return
+	}
+	// Keyed by user so per-user ordering is preserved. Async, so publishing
+	// never holds up ingestion; failed batches are logged by Completion.
+	writer = kafkawriter.New(cfg, cfg.ChangeEventsTopic, 50*time.Millisecond)
+	writer.Async = true
+	writer.Completion = func(messages []kafka.Message, err error) {
+		if err != nil {
+			log.Printf("[CHANGE-EVENTS] Failed to publish %d events: %v", len(messages), err)
+		}
+	}
+	log.Printf("[CHANGE-EVENTS] Publishing to topic '%s'", cfg.ChangeEventsTopic)
+}
+
+// Enabled reports whether change events are published, so callers can skip
+// work only needed to build them.
+func Enabled() bool {
+	return writer != nil
+}
+
+// PublishMessageChange emits a change event for a single message.
+// Events are queued and written in batches in the background; failures are
+// logged and never propagated, so the write path is unaffected.
+func PublishMessageChange(ctx context.Context, op string, userID string, message *models.MessageWithStatus) {
+	if writer == nil {
+		return
+	}
+	publish(ctx, models.ChangeEvent{
+		EventID:    newEventID(),
+		Operation:  op,
+		Entity:     "message",
+		UserID:     userID,
+		MessageID:  message.MessageID,
+		After:      message,
+		OccurredAt: time.Now().UTC(),
+	})
+}
+
+// PublishMessageRemoval emits a delete event for a message permanently
+// removed, e.g. by retention or a purge.
+func PublishMessageRemoval(ctx context.Context, userID, messageID string) {
+	if writer == nil {
+		return
+	}
+	publish(ctx, models.ChangeEvent{
+		EventID:    newEventID(),
+		Operation:  models.ChangeOpDelete,
+		Entity:     "message",
+		UserID:     userID,
+		MessageID:  messageID,
+		OccurredAt: time.Now().UTC(),
+	})
+}
+
+func publish(ctx context.Context, event models.ChangeEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[CHANGE-EVENTS] Failed to encode event for %s: %v", event.UserID, err)
+		return
+	}
+	// Async writes return once queued, so this only fails on a closed writer
+	if err := writer.WriteMessages(ctx, kafka.Message{Key: []byte(event.UserID), Value: payload}); err != nil {
+		log.Printf("[CHANGE-EVENTS] Failed to publish %s event for %s: %v", event.Operation, event.UserID, err)
+	}
+}
+
+// Close flushes and closes the writer.
+func Close() error {
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package changestream
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/notify"
+	"smsstore/internal/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+type changeDocument struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	ClusterTime interface{} `bson:"clusterTime"`
+}
+
+// Start watches the messages collection and publishes every change to the notify hub,
+// so writes from other replicas and backfills reach local caches and stream subscribers.
+// Change streams require a replica set; on errors the watcher reconnects with backoff,
+// resuming after the last seen event. Watches repo's database for ctx's scope.
+// Blocks until ctx is cancelled.
+func Start(ctx context.Context, repo *repository.Repository) {
+	var resumeToken bson.Raw
+	backoff := minBackoff
+	label := repo.ScopeFrom(ctx).Label()
+
+	for {
+		stream, err := repo.WatchUserMessages(ctx, resumeToken)
+		if err == nil {
+			log.Printf("[CHANGE-STREAM] Watching messages collection%s", label)
+			backoff = minBackoff
+			resumeToken, err = consume(ctx, stream, resumeToken)
+		}
+		if ctx.Err() != nil {
+			log.Println("[CHANGE-STREAM] Watcher stopped")
+			return
+		}
+
+		log.Printf("[CHANGE-STREAM] Stream error%s, retrying in %s: %v", label, backoff, err)
+		select {
+		case <-ctx.Done():
+			log.Println("[CHANGE-STREAM] Watcher stopped")
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// consume reads events until the stream fails and returns the last resume token.
+func consume(ctx context.Context, stream *mongo.ChangeStream, resumeToken bson.Raw) (bson.Raw, error) {
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeDocument
+		if err := stream.Decode(&change); err != nil {
+			log.Printf("[CHANGE-STREAM] Failed to decode change event: %v", err)
+		} else if change.DocumentKey.ID != "" {
+			notify.Publish(notify.Event{
+				UserID:    change.DocumentKey.ID,
+				Operation: change.OperationType,
+				At:        time.Now().UTC(),
+			})
+		}
+		resumeToken = stream.ResumeToken()
+	}
+	return resumeToken, stream.Err()
+}
@@ -2,17 +2,379 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Config holds application settings loaded from environment variables.
+//
+// Kafka reader defaults: KAFKA_MIN_BYTES=1, KAFKA_MAX_BYTES=10000000, KAFKA_MAX_WAIT=500ms,
+// KAFKA_QUEUE_CAPACITY=100, KAFKA_COMMIT_INTERVAL=0 (synchronous), KAFKA_START_OFFSET=first.
 type Config struct {
-	MongoURI     string
-	DBName       string
+	MongoURI string
+	// DBName is the application database on every cluster. TenantDatabases
+	// gives tenants their own database (same cluster) for their messages.
+	DBName          string
+	TenantDatabases map[string]string
+
 	KafkaBrokers []string
 	KafkaTopic   string
 	KafkaGroupID string
 	ServerPort   string
+
+	// AdminPort is the internal listener for metrics, the firehose, /v1/admin and
+	// pprof, so SERVER_PORT can be exposed publicly. Empty serves them on ServerPort.
+	AdminPort string
+
+	// TLS is enabled when TLSCertFile and TLSKeyFile are set. Setting TLSClientCAFile
+	// additionally requires clients to present a certificate signed by that CA (mTLS)
+	// on every request but the /healthz and /readyz probes.
+	// The files are re-read every TLSReloadInterval so rotated certificates apply
+	// without a restart.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSReloadInterval time.Duration
+
+	// InstanceID identifies this replica (POD_NAME, else the hostname)
+	InstanceID string
+
+	// ResponseFormat is the default JSON shape: "flat" (legacy) or "envelope" ({data, meta, errors}).
+	// Clients can override it per request with the X-Response-Format header.
+	ResponseFormat string
+
+	// MaxInflightRequests caps concurrent HTTP requests; beyond it requests are shed with 503.
+	// Zero disables load shedding.
+	MaxInflightRequests int
+	LoadShedRetryAfter  time.Duration
+
+	// RequestDeadlineMargin is kept back from the deadline a caller sets with
+	// X-Request-Deadline or X-Request-Timeout, to write the response in.
+	RequestDeadlineMargin time.Duration
+
+	// AdminAPIToken is the bearer token required on /v1/admin and the firehose.
+	// Empty leaves them open, which is only appropriate behind a private network.
+	AdminAPIToken string
+	// RBACPolicyFile enables role-based authorization on every route: API keys
+	// and JWT claims map to roles, and the file's rules name the roles each
+	// route needs. It is re-read every RBACReloadInterval when changed. JWTs are
+	// verified with RBACJWTSecret (HS256); empty accepts API keys only.
+	RBACPolicyFile     string
+	RBACReloadInterval time.Duration
+	RBACJWTSecret      string
+	// AdminAllowedCIDRs restricts the admin routes (the whole ADMIN_PORT) to
+	// clients whose address falls in one of the ranges; empty allows any.
+	AdminAllowedCIDRs []netip.Prefix
+	// Firehose requests are rate limited per client to FirehoseRateLimit per
+	// second with bursts of FirehoseBurst.
+	FirehoseRateLimit float64
+	FirehoseBurst     int
+	// RateLimitBackend is "memory" (per replica) or "redis" (one budget per client
+	// shared by all replicas, falling back to per-replica limits while Redis is down).
+	RateLimitBackend   string
+	RateLimitKeyPrefix string
+
+	// Redis connection used by the redis rate-limit backend
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	RedisTimeout  time.Duration
+
+	// At startup MongoDB and Kafka are retried every StartupRetryBackoff
+	// (doubling, up to 30s) while the service reports not ready; the process
+	// exits only once StartupMaxWait has passed without them.
+	StartupMaxWait      time.Duration
+	StartupRetryBackoff time.Duration
+
+	// MongoQueryTimeout bounds each individual repository query
+	MongoQueryTimeout time.Duration
+	// MongoSlowQueryThreshold logs repository operations slower than this. Zero disables it.
+	MongoSlowQueryThreshold time.Duration
+	// MongoWriteConcerns and MongoReadPreferences override the connection
+	// defaults per repository operation class (critical, analytics, default),
+	// e.g. majority writes for message persistence and secondaryPreferred
+	// reads for stats and analytics. Classes not listed use the MONGO_URI defaults.
+	MongoWriteConcerns   map[string]string
+	MongoReadPreferences map[string]string
+	// MongoAnalyticsURI is an optional separate connection (e.g. to a hidden
+	// analytics member of the default cluster) for analytics-class reads, so
+	// stats and reporting aggregations neither queue on the main connection
+	// pool nor load the members serving ingest and user reads.
+	MongoAnalyticsURI string
+	// MongoRegionURIs maps data-residency regions (e.g. IN, EU) to the
+	// clusters holding their users' messages. Events name their region in the
+	// KafkaRegionHeader record header and API callers in X-Region; anything
+	// without a region stays on MONGO_URI.
+	MongoRegionURIs   map[string]string
+	KafkaRegionHeader string
+	// CountryRegions routes events without a region header by the country
+	// of their phone number (e.g. DE=EU), for markets whose producers don't
+	// set the header yet. API reads still need X-Region for those users.
+	CountryRegions map[string]string
+	// PhoneDefaultCountry is the country of phone numbers in national format
+	// (without a + or 00 calling code); empty leaves their country unknown.
+	// Events that give no countryCode get the one derived from the number.
+	PhoneDefaultCountry string
+
+	// Kafka reader tuning; see kafka.ReaderConfig for semantics
+	KafkaMinBytes       int
+	KafkaMaxBytes       int
+	KafkaMaxWait        time.Duration
+	KafkaQueueCapacity  int
+	KafkaCommitInterval time.Duration
+	// KafkaStartOffset is "first" or "last"; it only applies when the group has no committed offset
+	KafkaStartOffset string
+
+	// Producer settings shared by every topic the service writes to (change
+	// events, forwarding, dead letters, resends, canaries).
+	// KafkaProducerCompression is none, gzip, snappy, lz4 or zstd; the reader
+	// decompresses any of them, whatever producers choose. Batches are capped
+	// at KafkaProducerBatchBytes, which must fit the topics' max.message.bytes.
+	KafkaProducerCompression string
+	KafkaProducerBatchBytes  int
+
+	// Consumer group membership. KafkaClientID is the client id this replica's
+	// readers send, which identifies it in broker logs and group descriptions
+	// (it defaults to "smsstore-" + POD_NAME, then the hostname). kafka-go has
+	// no static membership, so members are always dynamic; the session and
+	// rebalance timeouts are what keep a quick restart from stalling the group.
+	KafkaClientID          string
+	KafkaSessionTimeout    time.Duration
+	KafkaRebalanceTimeout  time.Duration
+	KafkaHeartbeatInterval time.Duration
+	KafkaJoinGroupBackoff  time.Duration
+
+	// Priority lane. KafkaPriorityTopic (empty disables it) carries OTP and
+	// other transactional events; KafkaPriorityWorkers readers consume it in
+	// their own group (KAFKA_GROUP_ID + "-priority"), polling every
+	// KafkaPriorityMaxWait, so bulk traffic on the main topic never queues
+	// ahead of it. Events on the main topic whose KafkaPriorityHeader is
+	// "high" are handed to a pool of KafkaPriorityWorkers workers of their
+	// own, so they skip the main topic's standard and bulk queues but are
+	// still fetched in turn with them. Priority events stored later than
+	// PriorityLatencyTarget after their createdAt count as target misses.
+	KafkaPriorityTopic    string
+	KafkaPriorityWorkers  int
+	KafkaPriorityMaxWait  time.Duration
+	KafkaPriorityHeader   string
+	PriorityLatencyTarget time.Duration
+	// Promotional events outside the priority lane run on the bulk lane,
+	// throttled to PromotionalRateLimit events per second (zero disables the
+	// throttle) with bursts of PromotionalBurst, shared across replicas with
+	// the redis rate-limit backend. Up to PromotionalQueueCapacity bulk events
+	// wait for the throttle while the main topic is read on; past that the
+	// reader waits too.
+	PromotionalRateLimit     float64
+	PromotionalBurst         int
+	PromotionalQueueCapacity int
+
+	// RetentionDays is the global message retention. Zero (the default)
+	// disables the janitor, and with it tenant and per-user retention.
+	RetentionDays     int
+	RetentionInterval time.Duration
+	// SoftDeleteGracePeriod is how long soft-deleted messages remain restorable before purge.
+	SoftDeleteGracePeriod time.Duration
+
+	// The storage reporter stores a storage usage report once per UTC day,
+	// checking every StorageReportInterval; each tenant's entry lists its
+	// StorageReportTopUsers largest user documents.
+	StorageReportInterval time.Duration
+	StorageReportTopUsers int
+
+	// UserMessageLimit caps the messages in a user's hot-tier document; the
+	// oldest beyond it are dropped, or moved to the per-message collection with
+	// UserMessageLimitArchive. Zero is unlimited.
+	UserMessageLimit        int
+	UserMessageLimitArchive bool
+
+	// HotTierDays is how long messages stay in the indexed hot collection before the
+	// mover shifts them to the cold collection. Zero disables tiering.
+	HotTierDays     int
+	TieringInterval time.Duration
+
+	// Every StatusCompactionInterval (zero disables it) the status histories
+	// of users active since the previous run are compacted: sends with at
+	// least StatusCompactionMinStatuses statuses keep their first and last
+	// status and each phase change, and drop interim repeats, archived first
+	// with StatusCompactionArchive.
+	StatusCompactionInterval    time.Duration
+	StatusCompactionMinStatuses int
+	StatusCompactionArchive     bool
+
+	// KafkaHeaderMapping maps event fields (idempotency_key, tenant_id, trace_id)
+	// to the Kafka header that carries them, for producers that can't put them
+	// in the payload. Body values win over headers.
+	KafkaHeaderMapping map[string]string
+
+	// DedupWindow suppresses identical bodies for the same user within the window. Zero disables it.
+	DedupWindow time.Duration
+	// MaxMessageBytes caps stored message bodies; longer bodies are truncated and flagged.
+	// Zero (the default) stores bodies whole.
+	MaxMessageBytes int
+	// MaxEventBytes rejects (and dead-letters) whole event payloads larger
+	// than this before decoding. Zero disables it.
+	MaxEventBytes int
+	// MessageBodyEncoding is how bodies are stored: "bson" (plain strings) or
+	// "zstd", which stores bodies of at least MessageBodyCompressionMinBytes
+	// as compressed binary when that is smaller. Reads decompress either way,
+	// so switching back to "bson" leaves stored bodies readable.
+	MessageBodyEncoding            string
+	MessageBodyCompressionMinBytes int
+	// StatusTransitionMode decides what happens to a status event that can't
+	// follow the stored status of the same send: "reject" drops it (to the
+	// dead-letter topic when enabled), "flag" stores it marked out_of_order and
+	// "off" stores it unchecked.
+	StatusTransitionMode string
+
+	// UserCacheSize is how many message listings the in-process cache holds for
+	// hot users; UserCacheTTL bounds how stale a listing can be. Zero size disables it.
+	UserCacheSize int
+	UserCacheTTL  time.Duration
+
+	// StatsCacheSize is how many stats and analytics responses are cached.
+	// Responses are fresh for StatsCacheTTL, then served stale for up to
+	// StatsCacheStale more while a request refreshes them. Zero size disables it.
+	StatsCacheSize  int
+	StatsCacheTTL   time.Duration
+	StatsCacheStale time.Duration
+
+	// Webhook deliveries run on the job runner: each is tried up to
+	// WebhookMaxAttempts times, WebhookRetryBackoff doubling between attempts,
+	// with WebhookTimeout per request. Subscriptions are re-read from Mongo at
+	// most every WebhookSubscriptionTTL.
+	WebhookMaxAttempts     int
+	WebhookRetryBackoff    time.Duration
+	WebhookTimeout         time.Duration
+	WebhookSubscriptionTTL time.Duration
+
+	// TenantConfigTTL bounds how long a replica serves tenant configurations
+	// changed through another replica from its cached snapshot.
+	TenantConfigTTL time.Duration
+
+	// The retry orchestrator looks for failed messages due under their
+	// tenant's retry policy every RetryOrchestratorInterval and publishes
+	// resends to ResendTopic, which the sender's retry listener consumes.
+	RetryOrchestratorInterval time.Duration
+	ResendTopic               string
+
+	// Status reconciliation looks up sends that have been sent for longer
+	// than their provider's ReconcileStaleAfter (the "default" entry for
+	// providers without their own) without a delivery report in the
+	// provider's status API, every ReconcileInterval, up to ReconcileMaxAge
+	// after sending. Only providers with a ReconcileStatusURLs entry, a URL
+	// with an {id} placeholder for the provider message ID, are reconciled.
+	ReconcileStatusURLs map[string]string
+	ReconcileStaleAfter map[string]time.Duration
+	ReconcileMaxAge     time.Duration
+	ReconcileInterval   time.Duration
+
+	// MessageChecksumKey, when set, makes stored body checksums HMAC-SHA256
+	// rather than plain SHA-256. Changing it leaves older checksums
+	// unverifiable, so rotate it only together with re-checksumming.
+	MessageChecksumKey string
+
+	// Consumer-path logging: routine per-message lines are written for 1 in
+	// LogSampleRate messages, each level is capped at LogRateLimit lines per
+	// second (zero is unlimited), and LogDebug adds raw payloads and bodies.
+	// All three can be changed at runtime through /v1/admin/logging.
+	LogSampleRate int
+	LogRateLimit  float64
+	LogDebug      bool
+
+	// DevMode replaces Kafka with an in-process bus and serves a simulated
+	// provider at POST /v1/sms/send, so the full flow runs with only MongoDB.
+	DevMode               bool
+	DevProviderFailRate   float64
+	DevProviderMaxLatency time.Duration
+
+	// Change events are CDC-style records of stored/updated messages for the warehouse sink.
+	ChangeEventsEnabled bool
+	ChangeEventsTopic   string
+
+	// Events the consumer can never store (malformed, invalid or rejected
+	// status transitions) are copied to DeadLetterTopic before being committed.
+	DeadLetterEnabled bool
+	DeadLetterTopic   string
+
+	// The consumer pauses itself when more than ConsumerPauseErrorRate of the
+	// events it handled within ConsumerPauseWindow failed, once at least
+	// ConsumerPauseMinEvents were handled. Zero disables pausing.
+	ConsumerPauseErrorRate float64
+	ConsumerPauseWindow    time.Duration
+	ConsumerPauseMinEvents int
+
+	// FeatureFlags turn Features on or off, for everyone or per tenant, over
+	// their defaults. FeatureFlagsFile (JSON, re-read when changed) overrides
+	// them, and overrides set through the admin API override both; the file
+	// and the admin overrides are refreshed every FeatureFlagsRefreshInterval.
+	FeatureFlags                map[string]FeatureFlag
+	FeatureFlagsFile            string
+	FeatureFlagsRefreshInterval time.Duration
+
+	// ForwardingRules republish stored messages matching a rule to the rule's
+	// topic, so other teams can subscribe without running a consumer.
+	ForwardingRules []ForwardingRule
+
+	// Anomaly detection compares each minute's ingest volume and failure rate
+	// against a rolling baseline of the previous AnomalyBaselineMinutes.
+	AnomalyEnabled              bool
+	AnomalyBaselineMinutes      int
+	AnomalyZScoreThreshold      float64
+	AnomalyFailureRateThreshold float64
+	AnomalyMinBaselineVolume    float64
+
+	// Background job runner: JobWorkers jobs run concurrently per replica; a
+	// claimed job's lease is renewed while it runs so another replica can take
+	// it over if this one dies.
+	JobWorkers       int
+	JobPollInterval  time.Duration
+	JobLeaseDuration time.Duration
+
+	// Provider health scoring looks at delivery outcomes and provider latency
+	// over the last ProviderHealthWindow. Providers with fewer than
+	// ProviderHealthMinSamples events in the window are assumed healthy.
+	ProviderHealthWindow     time.Duration
+	ProviderLatencyTarget    time.Duration
+	ProviderHealthMinSamples int
+
+	// The watchdog checks the consumer group and dead-letter topic every
+	// WatchdogInterval. It alerts when the group's committed offsets haven't
+	// moved for ConsumerStallAfter while it has lag, and when more than
+	// DeadLetterGrowthThreshold events reached the dead-letter topic within
+	// DeadLetterGrowthWindow. Zero disables either check.
+	WatchdogInterval          time.Duration
+	ConsumerStallAfter        time.Duration
+	DeadLetterGrowthThreshold int
+	DeadLetterGrowthWindow    time.Duration
+
+	// ShadowWrites mirrors message writes to the shadow backend of a storage
+	// migration. Every ShadowCompareInterval (zero disables comparing) the
+	// messages of ShadowCompareSample recently active users created within
+	// ShadowCompareWindow are compared between the backends.
+	ShadowWrites          bool
+	ShadowCompareInterval time.Duration
+	ShadowCompareSample   int
+	ShadowCompareWindow   time.Duration
+
+	// The canary publishes a synthetic event for CanaryPhoneNumber every
+	// CanaryInterval and fails if it isn't stored within CanaryTimeout. Zero
+	// interval disables it.
+	CanaryInterval    time.Duration
+	CanaryTimeout     time.Duration
+	CanaryPhoneNumber string
+
+	// Alert destinations shared by background monitors
+	AlertWebhookURLs         []string
+	AlertSlackWebhookURL     string
+	AlertPagerDutyRoutingKey string
+	AlertPagerDutyURL        string
+	AlertCooldown            time.Duration
 }
 
 func getenv(key string, fallback string) string {
@@ -23,26 +385,636 @@ func getenv(key string, fallback string) string {
 	return val
 }
 
-// LoadConfig loads and validates application configuration from environment variables.
-// Returns an error if any required configuration is missing or invalid.
-func LoadConfig() (*Config, error) {
-	brokersStr := getenv("KAFKA_BROKERS", "localhost:9092")
-	brokers := strings.Split(brokersStr, ",")
-	// Filter out empty broker strings
-	var validBrokers []string
-	for _, broker := range brokers {
-		if trimmed := strings.TrimSpace(broker); trimmed != "" {
-			validBrokers = append(validBrokers, trimmed)
+func getenvInt(key string, fallback int) (int, error) {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getenvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(strings.TrimSpace(val))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration (e.g. 30s, 1h): %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getenvBool(key string, fallback bool) (bool, error) {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(val))
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getenvFloat(key string, fallback float64) (float64, error) {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// getenvList splits a comma-separated variable, dropping empty entries.
+func getenvList(key string, fallback string) []string {
+	var values []string
+	for _, item := range strings.Split(getenv(key, fallback), ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			values = append(values, trimmed)
 		}
 	}
+	return values
+}
 
+// getenvPrefixes parses a comma-separated list of CIDR ranges; a bare address
+// is a single-host range.
+func getenvPrefixes(key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range getenvList(key, "") {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q, want a CIDR range or address", key, item)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q, want a CIDR range or address", key, item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// HeaderMappedFields are the event fields KAFKA_HEADER_MAPPING may fill from headers.
+var HeaderMappedFields = map[string]bool{
+	"idempotency_key": true,
+	"tenant_id":       true,
+	"trace_id":        true,
+}
+
+// getenvMapping parses a comma-separated list of field=value pairs.
+func getenvMapping(key string, fallback string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, item := range getenvList(key, fallback) {
+		field, value, ok := strings.Cut(item, "=")
+		field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+		if !ok || field == "" || value == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, want field=value", key, item)
+		}
+		mapping[field] = value
+	}
+	return mapping, nil
+}
+
+// ForwardingRule forwards stored messages whose fields all equal Match to Topic.
+type ForwardingRule struct {
+	Topic string
+	Match map[string]string
+}
+
+// ForwardingFields are the message fields FORWARDING_RULES may match on.
+var ForwardingFields = map[string]bool{
+	"status":       true,
+	"category":     true,
+	"tenant_id":    true,
+	"provider":     true,
+	"country_code": true,
+	"campaign_id":  true,
+	"template_id":  true,
+	"sender_id":    true,
+}
+
+// getenvForwardingRules parses a semicolon-separated list of
+// topic:field=value[,field=value...] rules.
+func getenvForwardingRules(key string) ([]ForwardingRule, error) {
+	var rules []ForwardingRule
+	for _, item := range strings.Split(getenv(key, ""), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		topic, conditions, ok := strings.Cut(item, ":")
+		topic = strings.TrimSpace(topic)
+		if !ok || topic == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, want topic:field=value[,field=value...]", key, item)
+		}
+		rule := ForwardingRule{Topic: topic, Match: map[string]string{}}
+		for _, condition := range strings.Split(conditions, ",") {
+			field, value, ok := strings.Cut(condition, "=")
+			field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+			if !ok || field == "" || value == "" {
+				return nil, fmt.Errorf("invalid %s condition %q for topic %s, want field=value", key, condition, topic)
+			}
+			rule.Match[field] = value
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Features are the features that can be turned on and off at runtime, with
+// whether each is on by default. Features deployed dark start off.
+var Features = map[string]bool{
+	"user_cache": true, // API reads of user listings go through the in-process cache
+	"webhooks":   true, // stored messages are delivered to webhook subscriptions
+	"forwarding": true, // stored messages are republished by FORWARDING_RULES
+	"rollups":    true, // the consumer counts stored messages in dashboard rollups
+	"timeseries": true, // GET /v1/analytics/timeseries is served
+}
+
+// FeatureFlag turns a feature on or off for every tenant (Enabled, nil to
+// leave it as is) and for particular tenants.
+type FeatureFlag struct {
+	Enabled *bool           `json:"enabled,omitempty"`
+	Tenants map[string]bool `json:"tenants,omitempty"`
+}
+
+// getenvFeatureFlags parses a comma-separated list of feature=on|off and
+// feature@tenant=on|off settings.
+func getenvFeatureFlags(key string) (map[string]FeatureFlag, error) {
+	flags := map[string]FeatureFlag{}
+	for _, item := range strings.Split(getenv(key, ""), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || (value != "on" && value != "off") {
+			return nil, fmt.Errorf("invalid %s entry %q, want feature=on|off or feature@tenant=on|off", key, item)
+		}
+		enabled := value == "on"
+		name, tenant, perTenant := strings.Cut(name, "@")
+		flag := flags[name]
+		if perTenant {
+			if flag.Tenants == nil {
+				flag.Tenants = map[string]bool{}
+			}
+			flag.Tenants[tenant] = enabled
+		} else {
+			flag.Enabled = &enabled
+		}
+		flags[name] = flag
+	}
+	return flags, nil
+}
+
+// ValidateFeatureFlags checks that flags only name known features and tenants.
+func ValidateFeatureFlags(flags map[string]FeatureFlag) error {
+	for name, flag := range flags {
+		if _, ok := Features[name]; !ok {
+			return fmt.Errorf("unknown feature %q", name)
+		}
+		for tenant := range flag.Tenants {
+			if strings.TrimSpace(tenant) == "" {
+				return fmt.Errorf("feature %s: tenant ID cannot be empty", name)
+			}
+		}
+	}
+	return nil
+}
+
+// MongoOperationClasses are the repository operation classes that
+// MONGO_WRITE_CONCERN and MONGO_READ_PREFERENCE can configure.
+var MongoOperationClasses = map[string]bool{"critical": true, "analytics": true, "default": true}
+
+// databaseNamePattern matches MongoDB database names this service accepts.
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+// regionPattern matches data-residency region names after upper-casing.
+var regionPattern = regexp.MustCompile(`^[A-Z]{2,8}$`)
+
+// countryPattern matches ISO 3166-1 alpha-2 country codes after upper-casing.
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var mongoReadPreferences = map[string]bool{
+	"primary": true, "primaryPreferred": true, "secondary": true, "secondaryPreferred": true, "nearest": true,
+}
+
+// LoadConfig loads and validates application configuration from environment variables.
+// Returns an error if any required configuration is missing or invalid.
+func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		MongoURI:     getenv("MONGO_URI", "mongodb://localhost:27017"),
-		DBName:       getenv("DB_NAME", "sms_db"),
-		KafkaBrokers: validBrokers,
+		DBName:       getenv("DB_NAME", "smsstore"),
+		KafkaBrokers: getenvList("KAFKA_BROKERS", "localhost:9092"),
 		KafkaTopic:   getenv("KAFKA_TOPIC", "sms_events"),
 		KafkaGroupID: getenv("KAFKA_GROUP_ID", "sms-storage-group"),
 		ServerPort:   getenv("SERVER_PORT", ":8080"),
+		AdminPort:    getenv("ADMIN_PORT", ":8082"),
+
+		TLSCertFile:     getenv("TLS_CERT_FILE", ""),
+		TLSKeyFile:      getenv("TLS_KEY_FILE", ""),
+		TLSClientCAFile: getenv("TLS_CLIENT_CA_FILE", ""),
+
+		ResponseFormat: strings.ToLower(getenv("RESPONSE_FORMAT", "flat")),
+		AdminAPIToken:  getenv("ADMIN_API_TOKEN", ""),
+		RBACPolicyFile: getenv("RBAC_POLICY_FILE", ""),
+		RBACJWTSecret:  getenv("RBAC_JWT_SECRET", ""),
+
+		FeatureFlagsFile: getenv("FEATURE_FLAGS_FILE", ""),
+
+		MessageChecksumKey: getenv("MESSAGE_CHECKSUM_KEY", ""),
+
+		RateLimitBackend:   strings.ToLower(getenv("RATE_LIMIT_BACKEND", "memory")),
+		RateLimitKeyPrefix: getenv("RATE_LIMIT_KEY_PREFIX", "smsstore:ratelimit:"),
+		RedisAddr:          getenv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:      getenv("REDIS_PASSWORD", ""),
+
+		ChangeEventsTopic: getenv("CHANGE_EVENTS_TOPIC", "sms_change_events"),
+		DeadLetterTopic:   getenv("DEAD_LETTER_TOPIC", "sms_events_dlq"),
+		ResendTopic:       getenv("RESEND_TOPIC", "sms_resend"),
+
+		StatusTransitionMode: strings.ToLower(getenv("STATUS_TRANSITION_MODE", "reject")),
+
+		AlertWebhookURLs:     getenvList("ALERT_WEBHOOK_URLS", ""),
+		AlertSlackWebhookURL: getenv("ALERT_SLACK_WEBHOOK_URL", ""),
+
+		CanaryPhoneNumber: getenv("CANARY_PHONE_NUMBER", "canary"),
+
+		KafkaPriorityTopic:  getenv("KAFKA_PRIORITY_TOPIC", ""),
+		KafkaPriorityHeader: strings.ToLower(getenv("KAFKA_PRIORITY_HEADER", "x-priority")),
+
+		AlertPagerDutyRoutingKey: getenv("ALERT_PAGERDUTY_ROUTING_KEY", ""),
+		AlertPagerDutyURL:        getenv("ALERT_PAGERDUTY_URL", "https://events.pagerduty.com/v2/enqueue"),
+	}
+
+	var err error
+	if cfg.MaxInflightRequests, err = getenvInt("MAX_INFLIGHT_REQUESTS", 256); err != nil {
+		return nil, err
+	}
+	if cfg.LoadShedRetryAfter, err = getenvDuration("LOAD_SHED_RETRY_AFTER", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.RequestDeadlineMargin, err = getenvDuration("REQUEST_DEADLINE_MARGIN", 10*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.FirehoseRateLimit, err = getenvFloat("FIREHOSE_RATE_LIMIT", 5); err != nil {
+		return nil, err
+	}
+	if cfg.FirehoseBurst, err = getenvInt("FIREHOSE_BURST", 10); err != nil {
+		return nil, err
+	}
+	if cfg.RedisDB, err = getenvInt("REDIS_DB", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RedisTimeout, err = getenvDuration("REDIS_TIMEOUT", 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.TLSReloadInterval, err = getenvDuration("TLS_RELOAD_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RBACReloadInterval, err = getenvDuration("RBAC_RELOAD_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.AdminAllowedCIDRs, err = getenvPrefixes("ADMIN_ALLOWED_CIDRS"); err != nil {
+		return nil, err
+	}
+	if cfg.StartupMaxWait, err = getenvDuration("STARTUP_MAX_WAIT", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.StartupRetryBackoff, err = getenvDuration("STARTUP_RETRY_BACKOFF", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MongoQueryTimeout, err = getenvDuration("MONGO_QUERY_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MongoSlowQueryThreshold, err = getenvDuration("MONGO_SLOW_QUERY_THRESHOLD", 500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.MongoWriteConcerns, err = getenvMapping("MONGO_WRITE_CONCERN", "critical=majority"); err != nil {
+		return nil, err
+	}
+	if cfg.MongoReadPreferences, err = getenvMapping("MONGO_READ_PREFERENCE", "analytics=secondaryPreferred"); err != nil {
+		return nil, err
+	}
+	if cfg.TenantDatabases, err = getenvMapping("TENANT_DATABASES", ""); err != nil {
+		return nil, err
+	}
+	regionURIs, err := getenvMapping("MONGO_REGION_URIS", "")
+	if err != nil {
+		return nil, err
+	}
+	cfg.MongoRegionURIs = map[string]string{}
+	for region, uri := range regionURIs {
+		cfg.MongoRegionURIs[strings.ToUpper(region)] = uri
+	}
+	cfg.KafkaRegionHeader = strings.ToLower(getenv("KAFKA_REGION_HEADER", "region"))
+	countryRegions, err := getenvMapping("COUNTRY_REGIONS", "")
+	if err != nil {
+		return nil, err
+	}
+	cfg.CountryRegions = map[string]string{}
+	for country, region := range countryRegions {
+		cfg.CountryRegions[strings.ToUpper(country)] = strings.ToUpper(region)
+	}
+	cfg.PhoneDefaultCountry = strings.ToUpper(getenv("PHONE_DEFAULT_COUNTRY", ""))
+	cfg.MongoAnalyticsURI = getenv("MONGO_ANALYTICS_URI", "")
+	// MinBytes=1 returns as soon as any data is available, avoiding MaxWait latency on quiet topics
+	if cfg.KafkaMinBytes, err = getenvInt("KAFKA_MIN_BYTES", 1); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaMaxBytes, err = getenvInt("KAFKA_MAX_BYTES", 10e6); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaMaxWait, err = getenvDuration("KAFKA_MAX_WAIT", 500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaQueueCapacity, err = getenvInt("KAFKA_QUEUE_CAPACITY", 100); err != nil {
+		return nil, err
+	}
+	// Zero commits synchronously after every message
+	if cfg.KafkaCommitInterval, err = getenvDuration("KAFKA_COMMIT_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	cfg.KafkaStartOffset = strings.ToLower(getenv("KAFKA_START_OFFSET", "first"))
+	cfg.KafkaProducerCompression = strings.ToLower(getenv("KAFKA_PRODUCER_COMPRESSION", "none"))
+	if cfg.KafkaProducerBatchBytes, err = getenvInt("KAFKA_PRODUCER_BATCH_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	cfg.InstanceID = getenv("POD_NAME", hostname())
+	cfg.KafkaClientID = getenv("KAFKA_CLIENT_ID", "smsstore-"+cfg.InstanceID)
+	if cfg.KafkaSessionTimeout, err = getenvDuration("KAFKA_SESSION_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaRebalanceTimeout, err = getenvDuration("KAFKA_REBALANCE_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaHeartbeatInterval, err = getenvDuration("KAFKA_HEARTBEAT_INTERVAL", 3*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaJoinGroupBackoff, err = getenvDuration("KAFKA_JOIN_GROUP_BACKOFF", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaPriorityWorkers, err = getenvInt("KAFKA_PRIORITY_WORKERS", 4); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaPriorityMaxWait, err = getenvDuration("KAFKA_PRIORITY_MAX_WAIT", 50*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.PriorityLatencyTarget, err = getenvDuration("PRIORITY_LATENCY_TARGET", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.PromotionalRateLimit, err = getenvFloat("PROMOTIONAL_RATE_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.PromotionalBurst, err = getenvInt("PROMOTIONAL_BURST", 50); err != nil {
+		return nil, err
+	}
+	if cfg.PromotionalQueueCapacity, err = getenvInt("PROMOTIONAL_QUEUE_CAPACITY", 10000); err != nil {
+		return nil, err
+	}
+
+	dedupSeconds, err := getenvInt("DEDUP_WINDOW_SECONDS", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DedupWindow = time.Duration(dedupSeconds) * time.Second
+	if cfg.MaxMessageBytes, err = getenvInt("MAX_MESSAGE_BYTES", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MaxEventBytes, err = getenvInt("MAX_EVENT_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	cfg.MessageBodyEncoding = strings.ToLower(getenv("MESSAGE_BODY_ENCODING", "bson"))
+	if cfg.MessageBodyCompressionMinBytes, err = getenvInt("MESSAGE_BODY_COMPRESSION_MIN_BYTES", 512); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaHeaderMapping, err = getenvMapping("KAFKA_HEADER_MAPPING",
+		"idempotency_key=Idempotency-Key,tenant_id=X-Tenant-ID,trace_id=traceparent"); err != nil {
+		return nil, err
+	}
+
+	if cfg.UserCacheSize, err = getenvInt("USER_CACHE_SIZE", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.UserCacheTTL, err = getenvDuration("USER_CACHE_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.StatsCacheSize, err = getenvInt("STATS_CACHE_SIZE", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.StatsCacheTTL, err = getenvDuration("STATS_CACHE_TTL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.StatsCacheStale, err = getenvDuration("STATS_CACHE_STALE_WHILE_REVALIDATE", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookMaxAttempts, err = getenvInt("WEBHOOK_MAX_ATTEMPTS", 6); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookRetryBackoff, err = getenvDuration("WEBHOOK_RETRY_BACKOFF", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookTimeout, err = getenvDuration("WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookSubscriptionTTL, err = getenvDuration("WEBHOOK_SUBSCRIPTION_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.TenantConfigTTL, err = getenvDuration("TENANT_CONFIG_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.RetryOrchestratorInterval, err = getenvDuration("RETRY_ORCHESTRATOR_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ReconcileStatusURLs, err = getenvMapping("RECONCILE_STATUS_URLS", ""); err != nil {
+		return nil, err
+	}
+	staleAfter, err := getenvMapping("RECONCILE_STALE_AFTER", "default=2h")
+	if err != nil {
+		return nil, err
+	}
+	cfg.ReconcileStaleAfter = map[string]time.Duration{}
+	for provider, value := range staleAfter {
+		if cfg.ReconcileStaleAfter[provider], err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid RECONCILE_STALE_AFTER duration %q for %s: %w", value, provider, err)
+		}
+	}
+	if cfg.ReconcileMaxAge, err = getenvDuration("RECONCILE_MAX_AGE", 72*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ReconcileInterval, err = getenvDuration("RECONCILE_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.LogSampleRate, err = getenvInt("LOG_SAMPLE_RATE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.LogRateLimit, err = getenvFloat("LOG_RATE_LIMIT", 100); err != nil {
+		return nil, err
+	}
+	if cfg.LogDebug, err = getenvBool("LOG_DEBUG", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.RetentionDays, err = getenvInt("RETENTION_DAYS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RetentionInterval, err = getenvDuration("RETENTION_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SoftDeleteGracePeriod, err = getenvDuration("SOFT_DELETE_GRACE_PERIOD", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.StorageReportInterval, err = getenvDuration("STORAGE_REPORT_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.StorageReportTopUsers, err = getenvInt("STORAGE_REPORT_TOP_USERS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.UserMessageLimit, err = getenvInt("USER_MESSAGE_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.UserMessageLimitArchive, err = getenvBool("USER_MESSAGE_LIMIT_ARCHIVE", false); err != nil {
+		return nil, err
+	}
+	if cfg.HotTierDays, err = getenvInt("HOT_TIER_DAYS", 30); err != nil {
+		return nil, err
+	}
+	if cfg.TieringInterval, err = getenvDuration("TIERING_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.StatusCompactionInterval, err = getenvDuration("STATUS_COMPACTION_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.StatusCompactionMinStatuses, err = getenvInt("STATUS_COMPACTION_MIN_STATUSES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.StatusCompactionArchive, err = getenvBool("STATUS_COMPACTION_ARCHIVE", false); err != nil {
+		return nil, err
+	}
+	if cfg.JobWorkers, err = getenvInt("JOB_WORKERS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.JobPollInterval, err = getenvDuration("JOB_POLL_INTERVAL", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.JobLeaseDuration, err = getenvDuration("JOB_LEASE_DURATION", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ChangeEventsEnabled, err = getenvBool("CHANGE_EVENTS_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.DeadLetterEnabled, err = getenvBool("DEAD_LETTER_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.ForwardingRules, err = getenvForwardingRules("FORWARDING_RULES"); err != nil {
+		return nil, err
+	}
+	if cfg.DevMode, err = getenvBool("DEV_MODE", false); err != nil {
+		return nil, err
+	}
+	if cfg.DevProviderFailRate, err = getenvFloat("DEV_PROVIDER_FAIL_RATE", 0.1); err != nil {
+		return nil, err
+	}
+	if cfg.DevProviderMaxLatency, err = getenvDuration("DEV_PROVIDER_MAX_LATENCY", 2*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.FeatureFlags, err = getenvFeatureFlags("FEATURE_FLAGS"); err != nil {
+		return nil, err
+	}
+	if cfg.FeatureFlagsRefreshInterval, err = getenvDuration("FEATURE_FLAGS_REFRESH_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.ConsumerPauseErrorRate, err = getenvFloat("CONSUMER_PAUSE_ERROR_RATE", 0); err != nil {
+		return nil, err
+	}
+	if cfg.ConsumerPauseWindow, err = getenvDuration("CONSUMER_PAUSE_WINDOW", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ConsumerPauseMinEvents, err = getenvInt("CONSUMER_PAUSE_MIN_EVENTS", 20); err != nil {
+		return nil, err
+	}
+
+	if cfg.AnomalyEnabled, err = getenvBool("ANOMALY_DETECTION_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.AnomalyBaselineMinutes, err = getenvInt("ANOMALY_BASELINE_MINUTES", 60); err != nil {
+		return nil, err
+	}
+	if cfg.AnomalyZScoreThreshold, err = getenvFloat("ANOMALY_ZSCORE_THRESHOLD", 3); err != nil {
+		return nil, err
+	}
+	if cfg.AnomalyFailureRateThreshold, err = getenvFloat("ANOMALY_FAILURE_RATE_THRESHOLD", 0.2); err != nil {
+		return nil, err
+	}
+	if cfg.AnomalyMinBaselineVolume, err = getenvFloat("ANOMALY_MIN_BASELINE_VOLUME", 10); err != nil {
+		return nil, err
+	}
+	if cfg.AlertCooldown, err = getenvDuration("ALERT_COOLDOWN", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ShadowWrites, err = getenvBool("SHADOW_WRITES", false); err != nil {
+		return nil, err
+	}
+	if cfg.ShadowCompareInterval, err = getenvDuration("SHADOW_COMPARE_INTERVAL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ShadowCompareSample, err = getenvInt("SHADOW_COMPARE_SAMPLE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.ShadowCompareWindow, err = getenvDuration("SHADOW_COMPARE_WINDOW", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.CanaryInterval, err = getenvDuration("CANARY_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CanaryTimeout, err = getenvDuration("CANARY_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WatchdogInterval, err = getenvDuration("WATCHDOG_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ConsumerStallAfter, err = getenvDuration("CONSUMER_STALL_AFTER", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.DeadLetterGrowthThreshold, err = getenvInt("DEAD_LETTER_GROWTH_THRESHOLD", 100); err != nil {
+		return nil, err
+	}
+	if cfg.DeadLetterGrowthWindow, err = getenvDuration("DEAD_LETTER_GROWTH_WINDOW", time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.ProviderHealthWindow, err = getenvDuration("PROVIDER_HEALTH_WINDOW", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ProviderLatencyTarget, err = getenvDuration("PROVIDER_LATENCY_TARGET", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ProviderHealthMinSamples, err = getenvInt("PROVIDER_HEALTH_MIN_SAMPLES", 20); err != nil {
+		return nil, err
 	}
 
 	// Validate required fields
@@ -61,6 +1033,14 @@ func (c *Config) Validate() error {
 	if c.DBName == "" {
 		return errors.New("DB_NAME is required and cannot be empty")
 	}
+	if !databaseNamePattern.MatchString(c.DBName) {
+		return fmt.Errorf("DB_NAME %q is not a valid database name", c.DBName)
+	}
+	for tenant, name := range c.TenantDatabases {
+		if !databaseNamePattern.MatchString(name) {
+			return fmt.Errorf("TENANT_DATABASES: %q is not a valid database name for tenant %s", name, tenant)
+		}
+	}
 	if len(c.KafkaBrokers) == 0 {
 		return errors.New("at least one KAFKA_BROKERS is required")
 	}
@@ -73,5 +1053,410 @@ func (c *Config) Validate() error {
 	if c.ServerPort == "" {
 		return errors.New("SERVER_PORT is required and cannot be empty")
 	}
+	if c.AdminPort == c.ServerPort {
+		return errors.New("ADMIN_PORT must differ from SERVER_PORT (leave it empty to serve admin routes on SERVER_PORT)")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSClientCAFile != "" && !c.TLSEnabled() {
+		return errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if c.TLSEnabled() && c.TLSReloadInterval <= 0 {
+		return errors.New("TLS_RELOAD_INTERVAL must be positive")
+	}
+	if c.RBACEnabled() && c.RBACReloadInterval <= 0 {
+		return errors.New("RBAC_RELOAD_INTERVAL must be positive")
+	}
+	if c.RBACJWTSecret != "" && !c.RBACEnabled() {
+		return errors.New("RBAC_JWT_SECRET requires RBAC_POLICY_FILE")
+	}
+	if c.ResponseFormat != "flat" && c.ResponseFormat != "envelope" {
+		return errors.New("RESPONSE_FORMAT must be 'flat' or 'envelope'")
+	}
+	if c.MaxInflightRequests < 0 {
+		return errors.New("MAX_INFLIGHT_REQUESTS cannot be negative")
+	}
+	if c.LoadShedRetryAfter <= 0 {
+		return errors.New("LOAD_SHED_RETRY_AFTER must be positive")
+	}
+	if c.RequestDeadlineMargin < 0 {
+		return errors.New("REQUEST_DEADLINE_MARGIN cannot be negative")
+	}
+	if c.FirehoseRateLimit <= 0 {
+		return errors.New("FIREHOSE_RATE_LIMIT must be positive")
+	}
+	if c.FirehoseBurst < 1 {
+		return errors.New("FIREHOSE_BURST must be at least 1")
+	}
+	if c.RateLimitBackend != "memory" && c.RateLimitBackend != "redis" {
+		return errors.New("RATE_LIMIT_BACKEND must be 'memory' or 'redis'")
+	}
+	if c.RateLimitBackend == "redis" && c.RedisAddr == "" {
+		return errors.New("REDIS_ADDR is required when RATE_LIMIT_BACKEND=redis")
+	}
+	if c.RedisDB < 0 {
+		return errors.New("REDIS_DB cannot be negative")
+	}
+	if c.RedisTimeout <= 0 {
+		return errors.New("REDIS_TIMEOUT must be positive")
+	}
+	if c.StartupMaxWait <= 0 {
+		return errors.New("STARTUP_MAX_WAIT must be positive")
+	}
+	if c.StartupRetryBackoff <= 0 {
+		return errors.New("STARTUP_RETRY_BACKOFF must be positive")
+	}
+	if c.MongoQueryTimeout <= 0 {
+		return errors.New("MONGO_QUERY_TIMEOUT must be positive")
+	}
+	for class, concern := range c.MongoWriteConcerns {
+		if !MongoOperationClasses[class] {
+			return fmt.Errorf("MONGO_WRITE_CONCERN: unknown operation class %q, expected critical, analytics or default", class)
+		}
+		if n, err := strconv.Atoi(concern); concern != "majority" && (err != nil || n < 0) {
+			return fmt.Errorf("MONGO_WRITE_CONCERN: %s must be 'majority' or a node count, got %q", class, concern)
+		}
+	}
+	for class, preference := range c.MongoReadPreferences {
+		if !MongoOperationClasses[class] {
+			return fmt.Errorf("MONGO_READ_PREFERENCE: unknown operation class %q, expected critical, analytics or default", class)
+		}
+		if !mongoReadPreferences[preference] {
+			return fmt.Errorf("MONGO_READ_PREFERENCE: %s must be primary, primaryPreferred, secondary, secondaryPreferred or nearest, got %q", class, preference)
+		}
+	}
+	for region := range c.MongoRegionURIs {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("MONGO_REGION_URIS: region %q must be 2-8 letters", region)
+		}
+	}
+	if len(c.MongoRegionURIs) > 0 && c.KafkaRegionHeader == "" {
+		return errors.New("KAFKA_REGION_HEADER is required when MONGO_REGION_URIS is set")
+	}
+	for country, region := range c.CountryRegions {
+		if !countryPattern.MatchString(country) {
+			return fmt.Errorf("COUNTRY_REGIONS: country %q must be an ISO 3166-1 alpha-2 code", country)
+		}
+		if _, ok := c.MongoRegionURIs[region]; !ok {
+			return fmt.Errorf("COUNTRY_REGIONS: region %q of %s is not in MONGO_REGION_URIS", region, country)
+		}
+	}
+	if c.PhoneDefaultCountry != "" && !countryPattern.MatchString(c.PhoneDefaultCountry) {
+		return errors.New("PHONE_DEFAULT_COUNTRY must be an ISO 3166-1 alpha-2 code")
+	}
+	if c.MongoSlowQueryThreshold < 0 {
+		return errors.New("MONGO_SLOW_QUERY_THRESHOLD cannot be negative")
+	}
+	if c.KafkaMinBytes < 1 {
+		return errors.New("KAFKA_MIN_BYTES must be at least 1")
+	}
+	if c.KafkaMaxBytes < c.KafkaMinBytes {
+		return errors.New("KAFKA_MAX_BYTES must be greater than or equal to KAFKA_MIN_BYTES")
+	}
+	if c.KafkaMaxWait <= 0 {
+		return errors.New("KAFKA_MAX_WAIT must be positive")
+	}
+	if c.KafkaQueueCapacity < 1 {
+		return errors.New("KAFKA_QUEUE_CAPACITY must be at least 1")
+	}
+	if c.KafkaCommitInterval < 0 {
+		return errors.New("KAFKA_COMMIT_INTERVAL cannot be negative")
+	}
+	if c.KafkaStartOffset != "first" && c.KafkaStartOffset != "last" {
+		return errors.New("KAFKA_START_OFFSET must be 'first' or 'last'")
+	}
+	switch c.KafkaProducerCompression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return errors.New("KAFKA_PRODUCER_COMPRESSION must be none, gzip, snappy, lz4 or zstd")
+	}
+	if c.KafkaProducerBatchBytes < 1 {
+		return errors.New("KAFKA_PRODUCER_BATCH_BYTES must be at least 1")
+	}
+	if c.DedupWindow < 0 {
+		return errors.New("DEDUP_WINDOW_SECONDS cannot be negative")
+	}
+	if c.StatusTransitionMode != "reject" && c.StatusTransitionMode != "flag" && c.StatusTransitionMode != "off" {
+		return errors.New("STATUS_TRANSITION_MODE must be 'reject', 'flag' or 'off'")
+	}
+	for field := range c.KafkaHeaderMapping {
+		if !HeaderMappedFields[field] {
+			return fmt.Errorf("KAFKA_HEADER_MAPPING: unknown field %q (want idempotency_key, tenant_id or trace_id)", field)
+		}
+	}
+	if c.MaxMessageBytes < 0 {
+		return errors.New("MAX_MESSAGE_BYTES cannot be negative")
+	}
+	if c.MessageBodyEncoding != "bson" && c.MessageBodyEncoding != "zstd" {
+		return errors.New("MESSAGE_BODY_ENCODING must be 'bson' or 'zstd'")
+	}
+	if c.MessageBodyCompressionMinBytes < 1 {
+		return errors.New("MESSAGE_BODY_COMPRESSION_MIN_BYTES must be at least 1")
+	}
+	if c.MaxEventBytes < 0 {
+		return errors.New("MAX_EVENT_BYTES cannot be negative")
+	}
+	if c.LogSampleRate < 1 {
+		return errors.New("LOG_SAMPLE_RATE must be at least 1")
+	}
+	if c.LogRateLimit < 0 {
+		return errors.New("LOG_RATE_LIMIT cannot be negative")
+	}
+	if c.UserCacheSize < 0 {
+		return errors.New("USER_CACHE_SIZE cannot be negative")
+	}
+	if c.UserCacheSize > 0 && c.UserCacheTTL <= 0 {
+		return errors.New("USER_CACHE_TTL must be positive when the user cache is enabled")
+	}
+	if c.StatsCacheSize < 0 {
+		return errors.New("STATS_CACHE_SIZE cannot be negative")
+	}
+	if c.StatsCacheSize > 0 && c.StatsCacheTTL <= 0 {
+		return errors.New("STATS_CACHE_TTL must be positive when the stats cache is enabled")
+	}
+	if c.StatsCacheStale < 0 {
+		return errors.New("STATS_CACHE_STALE_WHILE_REVALIDATE cannot be negative")
+	}
+	if c.WebhookMaxAttempts < 1 {
+		return errors.New("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.WebhookRetryBackoff <= 0 {
+		return errors.New("WEBHOOK_RETRY_BACKOFF must be positive")
+	}
+	if c.WebhookTimeout <= 0 {
+		return errors.New("WEBHOOK_TIMEOUT must be positive")
+	}
+	if c.WebhookSubscriptionTTL <= 0 {
+		return errors.New("WEBHOOK_SUBSCRIPTION_TTL must be positive")
+	}
+	if c.TenantConfigTTL <= 0 {
+		return errors.New("TENANT_CONFIG_TTL must be positive")
+	}
+	if c.RetryOrchestratorInterval <= 0 {
+		return errors.New("RETRY_ORCHESTRATOR_INTERVAL must be positive")
+	}
+	if c.ResendTopic == "" {
+		return errors.New("RESEND_TOPIC is required and cannot be empty")
+	}
+	for provider, statusURL := range c.ReconcileStatusURLs {
+		parsed, err := url.Parse(statusURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || !strings.Contains(statusURL, "{id}") {
+			return fmt.Errorf("RECONCILE_STATUS_URLS: %s must be an http(s) URL with an {id} placeholder, got %q", provider, statusURL)
+		}
+	}
+	for provider, staleAfter := range c.ReconcileStaleAfter {
+		if staleAfter <= 0 || staleAfter >= c.ReconcileMaxAge {
+			return fmt.Errorf("RECONCILE_STALE_AFTER: %s must be positive and below RECONCILE_MAX_AGE", provider)
+		}
+	}
+	if c.ReconcileInterval <= 0 {
+		return errors.New("RECONCILE_INTERVAL must be positive")
+	}
+	if c.KafkaSessionTimeout <= 0 || c.KafkaRebalanceTimeout <= 0 || c.KafkaJoinGroupBackoff <= 0 {
+		return errors.New("KAFKA_SESSION_TIMEOUT, KAFKA_REBALANCE_TIMEOUT and KAFKA_JOIN_GROUP_BACKOFF must be positive")
+	}
+	// The broker expects several heartbeats per session before declaring a member dead
+	if c.KafkaHeartbeatInterval <= 0 || c.KafkaHeartbeatInterval*3 > c.KafkaSessionTimeout {
+		return errors.New("KAFKA_HEARTBEAT_INTERVAL must be positive and at most a third of KAFKA_SESSION_TIMEOUT")
+	}
+	// The workers also handle header-marked priority events on the main topic
+	if c.KafkaPriorityWorkers < 1 {
+		return errors.New("KAFKA_PRIORITY_WORKERS must be at least 1")
+	}
+	if c.KafkaPriorityTopic != "" {
+		if c.KafkaPriorityTopic == c.KafkaTopic {
+			return errors.New("KAFKA_PRIORITY_TOPIC must differ from KAFKA_TOPIC")
+		}
+		if c.KafkaPriorityMaxWait <= 0 {
+			return errors.New("KAFKA_PRIORITY_MAX_WAIT must be positive")
+		}
+	}
+	if c.PriorityLatencyTarget <= 0 {
+		return errors.New("PRIORITY_LATENCY_TARGET must be positive")
+	}
+	if c.PromotionalRateLimit < 0 {
+		return errors.New("PROMOTIONAL_RATE_LIMIT cannot be negative")
+	}
+	if c.PromotionalRateLimit > 0 && c.PromotionalBurst < 1 {
+		return errors.New("PROMOTIONAL_BURST must be at least 1")
+	}
+	if c.PromotionalQueueCapacity < 1 {
+		return errors.New("PROMOTIONAL_QUEUE_CAPACITY must be at least 1")
+	}
+	if c.RetentionDays < 0 {
+		return errors.New("RETENTION_DAYS cannot be negative")
+	}
+	if c.RetentionInterval <= 0 {
+		return errors.New("RETENTION_INTERVAL must be positive")
+	}
+	if c.SoftDeleteGracePeriod < 0 {
+		return errors.New("SOFT_DELETE_GRACE_PERIOD cannot be negative")
+	}
+	if c.StorageReportInterval <= 0 {
+		return errors.New("STORAGE_REPORT_INTERVAL must be positive")
+	}
+	if c.StorageReportTopUsers < 1 || c.StorageReportTopUsers > 100 {
+		return errors.New("STORAGE_REPORT_TOP_USERS must be between 1 and 100")
+	}
+	if c.UserMessageLimit < 0 {
+		return errors.New("USER_MESSAGE_LIMIT cannot be negative")
+	}
+	if c.HotTierDays < 0 {
+		return errors.New("HOT_TIER_DAYS cannot be negative")
+	}
+	if c.TieringInterval <= 0 {
+		return errors.New("TIERING_INTERVAL must be positive")
+	}
+	if c.StatusCompactionInterval < 0 {
+		return errors.New("STATUS_COMPACTION_INTERVAL cannot be negative")
+	}
+	if c.StatusCompactionMinStatuses < 3 {
+		return errors.New("STATUS_COMPACTION_MIN_STATUSES must be at least 3")
+	}
+	if c.JobWorkers < 1 {
+		return errors.New("JOB_WORKERS must be at least 1")
+	}
+	if c.JobPollInterval <= 0 {
+		return errors.New("JOB_POLL_INTERVAL must be positive")
+	}
+	if c.JobLeaseDuration < 10*time.Second {
+		return errors.New("JOB_LEASE_DURATION must be at least 10s")
+	}
+	if c.ChangeEventsEnabled && c.ChangeEventsTopic == "" {
+		return errors.New("CHANGE_EVENTS_TOPIC is required when CHANGE_EVENTS_ENABLED is true")
+	}
+	if c.DeadLetterEnabled && c.DeadLetterTopic == "" {
+		return errors.New("DEAD_LETTER_TOPIC is required when DEAD_LETTER_ENABLED is true")
+	}
+	for _, rule := range c.ForwardingRules {
+		for field := range rule.Match {
+			if !ForwardingFields[field] {
+				return fmt.Errorf("FORWARDING_RULES: unknown field %q for topic %s", field, rule.Topic)
+			}
+		}
+		if rule.Topic == c.KafkaTopic {
+			return fmt.Errorf("FORWARDING_RULES: cannot forward to the consumed topic %s", rule.Topic)
+		}
+	}
+	if c.DevMode {
+		if c.DevProviderFailRate < 0 || c.DevProviderFailRate > 1 {
+			return errors.New("DEV_PROVIDER_FAIL_RATE must be in [0, 1]")
+		}
+		if c.DevProviderMaxLatency < 0 {
+			return errors.New("DEV_PROVIDER_MAX_LATENCY cannot be negative")
+		}
+		if c.ChangeEventsEnabled {
+			return errors.New("CHANGE_EVENTS_ENABLED requires Kafka and cannot be used with DEV_MODE")
+		}
+		if c.DeadLetterEnabled {
+			return errors.New("DEAD_LETTER_ENABLED requires Kafka and cannot be used with DEV_MODE")
+		}
+		if len(c.ForwardingRules) > 0 {
+			return errors.New("FORWARDING_RULES requires Kafka and cannot be used with DEV_MODE")
+		}
+	}
+	if err := ValidateFeatureFlags(c.FeatureFlags); err != nil {
+		return fmt.Errorf("FEATURE_FLAGS: %w", err)
+	}
+	if c.FeatureFlagsRefreshInterval <= 0 {
+		return errors.New("FEATURE_FLAGS_REFRESH_INTERVAL must be positive")
+	}
+	if c.ConsumerPauseErrorRate < 0 || c.ConsumerPauseErrorRate > 1 {
+		return errors.New("CONSUMER_PAUSE_ERROR_RATE must be in [0, 1]")
+	}
+	if c.ConsumerPauseErrorRate > 0 {
+		if c.ConsumerPauseWindow < time.Second {
+			return errors.New("CONSUMER_PAUSE_WINDOW must be at least 1s")
+		}
+		if c.ConsumerPauseMinEvents < 1 {
+			return errors.New("CONSUMER_PAUSE_MIN_EVENTS must be at least 1")
+		}
+	}
+	if c.AnomalyEnabled {
+		if c.AnomalyBaselineMinutes < 5 {
+			return errors.New("ANOMALY_BASELINE_MINUTES must be at least 5")
+		}
+		if c.AnomalyZScoreThreshold <= 0 {
+			return errors.New("ANOMALY_ZSCORE_THRESHOLD must be positive")
+		}
+		if c.AnomalyFailureRateThreshold <= 0 || c.AnomalyFailureRateThreshold > 1 {
+			return errors.New("ANOMALY_FAILURE_RATE_THRESHOLD must be in (0, 1]")
+		}
+	}
+	if c.AlertCooldown < 0 {
+		return errors.New("ALERT_COOLDOWN cannot be negative")
+	}
+	if c.AlertPagerDutyRoutingKey != "" && c.AlertPagerDutyURL == "" {
+		return errors.New("ALERT_PAGERDUTY_URL is required when ALERT_PAGERDUTY_ROUTING_KEY is set")
+	}
+	if c.ShadowCompareInterval < 0 {
+		return errors.New("SHADOW_COMPARE_INTERVAL cannot be negative")
+	}
+	if c.ShadowCompareSample < 1 {
+		return errors.New("SHADOW_COMPARE_SAMPLE must be at least 1")
+	}
+	if c.ShadowCompareWindow <= 0 {
+		return errors.New("SHADOW_COMPARE_WINDOW must be positive")
+	}
+	if c.CanaryInterval < 0 {
+		return errors.New("CANARY_INTERVAL cannot be negative")
+	}
+	if c.CanaryInterval > 0 {
+		if c.CanaryTimeout <= 0 {
+			return errors.New("CANARY_TIMEOUT must be positive")
+		}
+		if strings.TrimSpace(c.CanaryPhoneNumber) == "" {
+			return errors.New("CANARY_PHONE_NUMBER is required when CANARY_INTERVAL is set")
+		}
+	}
+	if c.WatchdogInterval <= 0 {
+		return errors.New("WATCHDOG_INTERVAL must be positive")
+	}
+	if c.ConsumerStallAfter < 0 {
+		return errors.New("CONSUMER_STALL_AFTER cannot be negative")
+	}
+	if c.DeadLetterGrowthThreshold < 0 {
+		return errors.New("DEAD_LETTER_GROWTH_THRESHOLD cannot be negative")
+	}
+	if c.DeadLetterGrowthThreshold > 0 && c.DeadLetterGrowthWindow < c.WatchdogInterval {
+		return errors.New("DEAD_LETTER_GROWTH_WINDOW must be at least WATCHDOG_INTERVAL")
+	}
+	if c.ProviderHealthWindow < time.Minute {
+		return errors.New("PROVIDER_HEALTH_WINDOW must be at least 1m")
+	}
+	if c.ProviderLatencyTarget <= 0 {
+		return errors.New("PROVIDER_LATENCY_TARGET must be positive")
+	}
+	if c.ProviderHealthMinSamples < 0 {
+		return errors.New("PROVIDER_HEALTH_MIN_SAMPLES cannot be negative")
+	}
 	return nil
 }
+
+// TLSEnabled reports whether the HTTP server should terminate TLS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// AdminServerEnabled reports whether admin routes get their own listener
+func (c *Config) AdminServerEnabled() bool {
+	return c.AdminPort != ""
+}
+
+// ReconcileStaleAfterFor returns how long sends through provider may go
+// without a delivery report before their status is looked up.
+func (c *Config) ReconcileStaleAfterFor(provider string) time.Duration {
+	if staleAfter, ok := c.ReconcileStaleAfter[provider]; ok {
+		return staleAfter
+	}
+	if staleAfter, ok := c.ReconcileStaleAfter["default"]; ok {
+		return staleAfter
+	}
+	return 2 * time.Hour
+}
+
+// RBACEnabled reports whether routes are authorized by an RBAC policy.
+func (c *Config) RBACEnabled() bool {
+	return c.RBACPolicyFile != ""
+}
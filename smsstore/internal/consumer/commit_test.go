@@ -0,0 +1,246 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// These tests document the consumer's delivery semantics: offsets are only
+// committed after the write succeeds, so a crash anywhere before the commit
+// redelivers the message (at-least-once), and a crash between write and commit
+// stores it twice unless the dedup window suppresses the replay.
+
+// fakePartition is a single-partition log with a consumer-group committed offset.
+// Each "process lifetime" starts fetching from the committed offset, like a
+// restarted kafka.Reader.
+type fakePartition struct {
+	mu        sync.Mutex
+	log       [][]byte
+	committed int64
+	next      int64
+}
+
+func newFakePartition(payloads ...string) *fakePartition {
+	p := &fakePartition{}
+	for _, payload := range payloads {
+		p.log = append(p.log, []byte(payload))
+	}
+	return p
+}
+
+// restart simulates a new process joining the group.
+func (p *fakePartition) restart() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next = p.committed
+}
+
+func (p *fakePartition) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return kafka.Message{}, err
+	}
+	p.mu.Lock()
+	if p.next < int64(len(p.log)) {
+		msg := kafka.Message{Offset: p.next, Value: p.log[p.next]}
+		p.next++
+		p.mu.Unlock()
+		return msg, nil
+	}
+	p.mu.Unlock()
+	// Caught up: block like a real reader until shutdown
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (p *fakePartition) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range msgs {
+		if msg.Offset+1 > p.committed {
+			p.committed = msg.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (p *fakePartition) committedOffset() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.committed
+}
+
+// fakeStore records every write the handler makes.
+type fakeStore struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (s *fakeStore) write(payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = append(s.writes, string(payload))
+}
+
+func (s *fakeStore) count(payload string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, w := range s.writes {
+		if w == payload {
+			n++
+		}
+	}
+	return n
+}
+
+// runUntilIdle runs the consume loop until every message has been committed,
+// or until timeout.
+func runUntilIdle(t *testing.T, partition *fakePartition, handle func(ctx context.Context, msg kafka.Message) error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		(&Consumer{}).consume(ctx, partition, handle)
+		close(done)
+	}()
+	for partition.committedOffset() < int64(len(partition.log)) && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func useFastRetries(t *testing.T) {
+	t.Helper()
+	previous, previousMax := retryBackoff, maxRetryBackoff
+	retryBackoff, maxRetryBackoff = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { retryBackoff, maxRetryBackoff = previous, previousMax })
+}
+
+func TestCommitsOnlyAfterWriteSucceeds(t *testing.T) {
+	useFastRetries(t)
+	partition := newFakePartition("a", "b")
+	store := &fakeStore{}
+
+	failures := 2
+	runUntilIdle(t, partition, func(ctx context.Context, msg kafka.Message) error {
+		if string(msg.Value) == "a" && failures > 0 {
+			failures--
+			// Transient failure: nothing may be committed yet
+			if got := partition.committedOffset(); got != 0 {
+				t.Errorf("committed offset %d before write succeeded", got)
+			}
+			return errors.New("mongo unavailable")
+		}
+		store.write(msg.Value)
+		return nil
+	})
+
+	if got := partition.committedOffset(); got != 2 {
+		t.Fatalf("committed offset = %d, want 2", got)
+	}
+	for _, payload := range []string{"a", "b"} {
+		if got := store.count(payload); got != 1 {
+			t.Errorf("%q stored %d times, want 1", payload, got)
+		}
+	}
+}
+
+func TestCrashBetweenWriteAndCommitRedelivers(t *testing.T) {
+	partition := newFakePartition("a", "b")
+	store := &fakeStore{}
+
+	// First lifetime: "a" is written, then the process dies before committing.
+	ctx, crash := context.WithCancel(context.Background())
+	(&Consumer{}).consume(ctx, partition, func(ctx context.Context, msg kafka.Message) error {
+		store.write(msg.Value)
+		crash()
+		return nil
+	})
+	if got := partition.committedOffset(); got != 0 {
+		t.Fatalf("committed offset after crash = %d, want 0", got)
+	}
+
+	// Second lifetime resumes from the committed offset.
+	partition.restart()
+	runUntilIdle(t, partition, func(ctx context.Context, msg kafka.Message) error {
+		store.write(msg.Value)
+		return nil
+	})
+
+	// At-least-once: the replayed write is a duplicate. DEDUP_WINDOW_SECONDS
+	// suppresses these when the replay lands inside the window.
+	if got := store.count("a"); got != 2 {
+		t.Errorf("%q stored %d times, want 2 (redelivered after crash)", "a", got)
+	}
+	if got := store.count("b"); got != 1 {
+		t.Errorf("%q stored %d times, want 1", "b", got)
+	}
+	if got := partition.committedOffset(); got != 2 {
+		t.Errorf("committed offset = %d, want 2", got)
+	}
+}
+
+func TestCrashBeforeWriteDoesNotLoseMessage(t *testing.T) {
+	useFastRetries(t)
+	partition := newFakePartition("a")
+	store := &fakeStore{}
+
+	// First lifetime: the write keeps failing and the process shuts down mid-retry.
+	ctx, crash := context.WithCancel(context.Background())
+	attempts := 0
+	(&Consumer{}).consume(ctx, partition, func(ctx context.Context, msg kafka.Message) error {
+		if attempts++; attempts == 3 {
+			crash()
+		}
+		return errors.New("mongo unavailable")
+	})
+	if got := partition.committedOffset(); got != 0 {
+		t.Fatalf("committed offset after crash = %d, want 0", got)
+	}
+
+	partition.restart()
+	runUntilIdle(t, partition, func(ctx context.Context, msg kafka.Message) error {
+		store.write(msg.Value)
+		return nil
+	})
+	if got := store.count("a"); got != 1 {
+		t.Errorf("%q stored %d times, want 1", "a", got)
+	}
+}
+
+func TestInvalidEventIsCommittedWithoutRetry(t *testing.T) {
+	partition := newFakePartition("not json", "b")
+	store := &fakeStore{}
+
+	attempts := map[string]int{}
+	runUntilIdle(t, partition, func(ctx context.Context, msg kafka.Message) error {
+		attempts[string(msg.Value)]++
+		if string(msg.Value) == "not json" {
+			return fmt.Errorf("%w: bad payload", ErrInvalidEvent)
+		}
+		store.write(msg.Value)
+		return nil
+	})
+
+	if got := attempts["not json"]; got != 1 {
+		t.Errorf("invalid event attempted %d times, want 1", got)
+	}
+	if got := store.count("b"); got != 1 {
+		t.Errorf("%q stored %d times, want 1", "b", got)
+	}
+	if got := partition.committedOffset(); got != 2 {
+		t.Errorf("committed offset = %d, want 2", got)
+	}
+}
@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"smsstore/pkg/models"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+)
+
+// avroSchema mirrors the SmsEvent fields producers set.
+var avroSchema = avro.MustParse(`{
+	"type": "record",
+	"name": "SmsEvent",
+	"fields": [
+		{"name": "phoneNumber", "type": "string"},
+		{"name": "message", "type": "string"},
+		{"name": "status", "type": "string"},
+		{"name": "provider", "type": "string", "default": ""},
+		{"name": "providerMessageId", "type": "string", "default": ""},
+		{"name": "campaignId", "type": "string", "default": ""},
+		{"name": "templateId", "type": "string", "default": ""},
+		{"name": "countryCode", "type": "string", "default": ""},
+		{"name": "metadata", "type": {"type": "map", "values": "string"}, "default": {}},
+		{"name": "idempotencyKey", "type": "string", "default": ""},
+		{"name": "tenantId", "type": "string", "default": ""},
+		{"name": "category", "type": "string", "default": ""},
+		{"name": "senderId", "type": "string", "default": ""},
+		{"name": "traceId", "type": "string", "default": ""},
+		{"name": "attempt", "type": "int", "default": 0},
+		{"name": "providerLatencyMs", "type": "long", "default": 0}
+	]
+}`)
+
+type avroSmsEvent struct {
+	PhoneNumber       string            `avro:"phoneNumber"`
+	Message           string            `avro:"message"`
+	Status            string            `avro:"status"`
+	Provider          string            `avro:"provider"`
+	ProviderMessageID string            `avro:"providerMessageId"`
+	CampaignID        string            `avro:"campaignId"`
+	TemplateID        string            `avro:"templateId"`
+	CountryCode       string            `avro:"countryCode"`
+	Metadata          map[string]string `avro:"metadata"`
+	IdempotencyKey    string            `avro:"idempotencyKey"`
+	TenantID          string            `avro:"tenantId"`
+	Category          string            `avro:"category"`
+	SenderID          string            `avro:"senderId"`
+	TraceID           string            `avro:"traceId"`
+	Attempt           int               `avro:"attempt"`
+	ProviderLatencyMs int64             `avro:"providerLatencyMs"`
+}
+
+func (e avroSmsEvent) smsEvent() models.SmsEvent {
+	return models.SmsEvent{
+		SchemaVersion:     models.SchemaVersion,
+		PhoneNumber:       e.PhoneNumber,
+		Message:           e.Message,
+		Status:            e.Status,
+		Provider:          e.Provider,
+		ProviderMessageID: e.ProviderMessageID,
+		CampaignID:        e.CampaignID,
+		TemplateID:        e.TemplateID,
+		CountryCode:       e.CountryCode,
+		Metadata:          e.Metadata,
+		IdempotencyKey:    e.IdempotencyKey,
+		TenantID:          e.TenantID,
+		Category:          e.Category,
+		SenderID:          e.SenderID,
+		TraceID:           e.TraceID,
+		Attempt:           e.Attempt,
+		ProviderLatencyMs: e.ProviderLatencyMs,
+	}
+}
+
+var benchEvent = avroSmsEvent{
+	PhoneNumber:       "+15550001001",
+	Message:           "Your order OD-1001 has been shipped and will arrive by Friday. Track it in the app.",
+	Status:            "successful",
+	Provider:          "twilio",
+	ProviderMessageID: "SM9f3c2a1b0e8d7c6b5a4f3e2d1c0b9a8f",
+	CampaignID:        "order-updates",
+	TemplateID:        "shipped-v2",
+	CountryCode:       "US",
+	Metadata:          map[string]string{"order_id": "OD-1001", "merchant_id": "M-42"},
+	IdempotencyKey:    "send-7c1e4b2a",
+	TenantID:          "acme",
+	Category:          "transactional",
+	SenderID:          "MEESHO",
+	TraceID:           "4bf92f3577b34da6a3ce929d0e0e4736",
+	ProviderLatencyMs: 182,
+}
+
+// BenchmarkDecode compares the decode stage (JSON, the sms_events wire format
+// today) with decoding the same event from Avro, to size the gain of moving
+// producers to a binary schema. Run with:
+//
+//	go test -run '^$' -bench Decode -benchmem ./internal/consumer
+func BenchmarkDecode(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("json", func(b *testing.B) {
+		payload, err := json.Marshal(benchEvent.smsEvent())
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			env := &Envelope{Payload: payload}
+			if err := decode(ctx, env); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("avro", func(b *testing.B) {
+		payload, err := avro.Marshal(avroSchema, benchEvent)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			env := &Envelope{Payload: payload}
+			var decoded avroSmsEvent
+			if err := avro.Unmarshal(avroSchema, payload, &decoded); err != nil {
+				b.Fatal(err)
+			}
+			env.Event = decoded.smsEvent()
+		}
+	})
+}
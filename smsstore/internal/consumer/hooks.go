@@ -0,0 +1,124 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smsstore/internal/logsample"
+	"smsstore/internal/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Enricher is a custom processor run on every live event after the built-in
+// enrichment, e.g. to attach a merchant tier looked up from another service.
+// It sees the decoded, validated event and records what it adds in
+// env.Event.Metadata (stored with the message, under the same limits as
+// producer entries) or env.Attributes (for later hooks only). Returning
+// ErrSkip stops the event without storing it, and an error wrapping
+// ErrInvalidEvent rejects it.
+type Enricher interface {
+	Enrich(ctx context.Context, env *Envelope) error
+}
+
+// EnricherFunc adapts a function to Enricher.
+type EnricherFunc func(ctx context.Context, env *Envelope) error
+
+// Enrich calls f.
+func (f EnricherFunc) Enrich(ctx context.Context, env *Envelope) error {
+	return f(ctx, env)
+}
+
+// Hook registers an Enricher with the consumer.
+type Hook struct {
+	// Name identifies the hook in logs and metrics; it must be unique
+	Name     string
+	Enricher Enricher
+	// Order sorts hooks, lowest first; ties run in registration order
+	Order int
+	// Required hooks fail the event on error, so it is retried like a failed
+	// write. Errors of other hooks are logged and counted, and the event is
+	// stored without their enrichment.
+	Required bool
+	// Timeout bounds each call; zero leaves only the event's own deadline
+	Timeout time.Duration
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []Hook
+)
+
+// RegisterHook adds an enrichment hook to pipelines built afterwards. Call it
+// from an init function or from main before the consumer starts. It panics
+// if the hook has no name or enricher or its name is taken, as
+// misconfigured hooks are programming errors.
+func RegisterHook(hook Hook) {
+	if hook.Name == "" || hook.Enricher == nil {
+		panic("consumer: RegisterHook needs a name and an enricher")
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	for _, registered := range hooks {
+		if registered.Name == hook.Name {
+			panic(fmt.Sprintf("consumer: hook %q registered twice", hook.Name))
+		}
+	}
+	hooks = append(hooks, hook)
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Order < hooks[j].Order })
+}
+
+// Hooks returns the names of the registered hooks in the order they run.
+func Hooks() []string {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	names := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		names = append(names, hook.Name)
+	}
+	return names
+}
+
+// hookStages returns a pipeline stage per registered hook, named
+// "hook:<name>" so the stage metrics time each hook.
+func hookStages() []Stage {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	stages := make([]Stage, 0, len(hooks))
+	for _, hook := range hooks {
+		stages = append(stages, Stage{Name: "hook:" + hook.Name, Process: runHook(hook)})
+	}
+	return stages
+}
+
+func runHook(hook Hook) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		if hook.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+			defer cancel()
+		}
+		err := hook.Enricher.Enrich(ctx, env)
+		switch {
+		case err == nil:
+			metrics.EnrichmentHooks.WithLabelValues(hook.Name, "ok").Inc()
+			return nil
+		case errors.Is(err, ErrSkip):
+			metrics.EnrichmentHooks.WithLabelValues(hook.Name, "skipped").Inc()
+			return err
+		case errors.Is(err, ErrInvalidEvent):
+			metrics.EnrichmentHooks.WithLabelValues(hook.Name, "rejected").Inc()
+			logsample.Errorf("[HOOK] %s rejected event for %s: %v", hook.Name, env.Event.PhoneNumber, err)
+			return err
+		case hook.Required:
+			metrics.EnrichmentHooks.WithLabelValues(hook.Name, "failed").Inc()
+			logsample.Errorf("[HOOK] Required hook %s failed for %s: %v", hook.Name, env.Event.PhoneNumber, err)
+			return fmt.Errorf("hook %s: %w", hook.Name, err)
+		default:
+			metrics.EnrichmentHooks.WithLabelValues(hook.Name, "ignored").Inc()
+			logsample.Errorf("[HOOK] Hook %s failed for %s, storing without it: %v", hook.Name, env.Event.PhoneNumber, err)
+			return nil
+		}
+	}
+}
@@ -2,65 +2,310 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"smsstore/internal/anomaly"
+	"smsstore/internal/breaker"
 	"smsstore/internal/config"
+	"smsstore/internal/deadletter"
+	"smsstore/internal/logsample"
+	"smsstore/internal/metrics"
+	"smsstore/internal/quarantine"
+	"smsstore/internal/ratelimit"
 	"smsstore/internal/repository"
 	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
-// StartKafkaConsumer starts consuming messages from Kafka and stores them in MongoDB
-func StartKafkaConsumer(cfg *config.Config) {
+// Consumer stores SMS events from Kafka, the DEV_MODE bus or imports in a
+// MessageStore.
+type Consumer struct {
+	cfg *config.Config
+	// repo quarantines malformed events and knows the configured regions
+	repo  *repository.Repository
+	store repository.MessageStore
+	// bulk throttles the bulk lane; nil leaves it unthrottled
+	bulk ratelimit.Limiter
+}
+
+// New returns a consumer configured by cfg that stores events in store, which
+// is usually repo itself or wraps it.
+func New(cfg *config.Config, repo *repository.Repository, store repository.MessageStore) *Consumer {
+	return &Consumer{cfg: cfg, repo: repo, store: store, bulk: bulkLimiter(cfg)}
+}
+
+// StartKafka starts consuming messages from Kafka and stores them. Offsets
+// are committed only after a message has been handled, so delivery is
+// at-least-once: a crash between the write and the commit redelivers the
+// message on restart. With KAFKA_PRIORITY_TOPIC set the priority lane's
+// readers run alongside. Blocks until ctx is cancelled.
+func (c *Consumer) StartKafka(ctx context.Context) {
+	cfg := c.cfg
 	log.Println("========================================")
 	log.Println("Initializing Kafka Consumer")
 	log.Println("========================================")
 	log.Printf("Brokers: %v", cfg.KafkaBrokers)
 	log.Printf("Topic: %s", cfg.KafkaTopic)
 	log.Printf("Group ID: %s", cfg.KafkaGroupID)
+	log.Printf("Client ID: %s", cfg.KafkaClientID)
 
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  cfg.KafkaBrokers,
-		Topic:    cfg.KafkaTopic,
-		GroupID:  cfg.KafkaGroupID,
-		MinBytes: 10e3, // 10KB
-		MaxBytes: 10e6, // 10MB
-	})
+	log.Printf("Reader: minBytes=%d maxBytes=%d maxWait=%s queue=%d commitInterval=%s startOffset=%s",
+		cfg.KafkaMinBytes, cfg.KafkaMaxBytes, cfg.KafkaMaxWait, cfg.KafkaQueueCapacity, cfg.KafkaCommitInterval, cfg.KafkaStartOffset)
+
+	checkMaxBytes(ctx, cfg)
+
+	reader := kafka.NewReader(readerConfig(cfg))
 	defer reader.Close()
 
+	pipeline := c.Pipeline()
+	if names := Hooks(); len(names) > 0 {
+		log.Printf("Enrichment hooks: %v", names)
+	}
+
 	log.Printf("✓ Kafka consumer started successfully")
 	log.Printf("✓ Listening for messages on topic '%s'...", cfg.KafkaTopic)
 	log.Println("========================================")
 
+	if cfg.KafkaPriorityTopic != "" {
+		go c.startPriority(ctx, pipeline)
+	}
+	c.consumeLanes(ctx, reader, func(ctx context.Context, msg kafka.Message) error {
+		return process(ctx, pipeline, msg.Value, Headers(msg.Headers), recordSource(msg))
+	})
+	log.Println("Kafka consumer stopped")
+}
+
+// StartLocal consumes from an in-process source instead of Kafka, with the
+// same pipeline and commit semantics. Blocks until ctx is cancelled.
+func (c *Consumer) StartLocal(ctx context.Context, source MessageSource) {
+	pipeline := c.Pipeline()
+	log.Println("✓ Local consumer started (DEV_MODE, no Kafka)")
+	c.consumeLanes(ctx, source, func(ctx context.Context, msg kafka.Message) error {
+		return process(ctx, pipeline, msg.Value, Headers(msg.Headers), recordSource(msg))
+	})
+	log.Println("Local consumer stopped")
+}
+
+// MessageSource is the subset of *kafka.Reader the consume loop needs. The
+// DEV_MODE in-process bus implements it too.
+type MessageSource interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Backoff between attempts at a message that failed with a transient error.
+var (
+	retryBackoff    = time.Second
+	maxRetryBackoff = 30 * time.Second
+)
+
+// consume fetches messages one at a time and commits each only after handle
+// succeeds. Transient failures are retried in place rather than skipped,
+// because committing a later offset would implicitly commit the failed one.
+// Invalid events (ErrInvalidEvent) can never succeed and are committed, after
+// being copied to the dead-letter topic when one is configured; malformed ones
+// are quarantined too. While the
+// breaker has the consumer paused nothing is fetched or retried.
+func (c *Consumer) consume(ctx context.Context, source MessageSource, handle func(ctx context.Context, msg kafka.Message) error) {
 	for {
-		log.Println("[WAITING] Polling for new messages...")
-		msg, err := reader.ReadMessage(context.Background())
+		if breaker.Wait(ctx) != nil {
+			return
+		}
+		logsample.Debugf("[WAITING] Polling for new messages...")
+		msg, err := source.FetchMessage(ctx)
 		if err != nil {
-			log.Printf("[ERROR] Failed to read Kafka message: %v", err)
+			if ctx.Err() != nil {
+				return
+			}
+			logsample.Errorf("[ERROR] Failed to read Kafka message: %v", err)
 			continue
 		}
 
-		log.Printf("[RECEIVED] New message from partition %d, offset %d", msg.Partition, msg.Offset)
-		log.Printf("[RAW] Message: %s", string(msg.Value))
+		observeRecordSize(msg)
+		logsample.Debugf("[RECEIVED] New message from partition %d, offset %d", msg.Partition, msg.Offset)
+		logsample.Debugf("[RAW] Message: %s", string(msg.Value))
 
-		var smsEvent models.SmsEvent
-		if err := json.Unmarshal(msg.Value, &smsEvent); err != nil {
-			log.Printf("[ERROR] Failed to unmarshal SMS event: %v", err)
-			log.Printf("[ERROR] Raw payload: %s", string(msg.Value))
-			continue
+		if !c.handleWithRetry(ctx, msg, handle) {
+			// Shutting down mid-retry: leave the offset uncommitted so it is redelivered
+			return
 		}
+		if err := source.CommitMessages(ctx, msg); err != nil {
+			// A later commit covers this offset; until then a restart redelivers it
+			logsample.Errorf("[ERROR] Failed to commit partition %d offset %d: %v", msg.Partition, msg.Offset, err)
+		}
+
+		logsample.Debugf("----------------------------------------")
+	}
+}
 
-		log.Printf("[PROCESSING] SMS Event - Phone: %s, Status: %s", smsEvent.PhoneNumber, smsEvent.Status)
-		log.Printf("[PROCESSING] Message content: %s", smsEvent.Message)
+// observeRecordSize records msg's size. kafka-go has already decompressed
+// its batch, whichever codec the producer used.
+func observeRecordSize(msg kafka.Message) {
+	size := len(msg.Key) + len(msg.Value)
+	for _, header := range msg.Headers {
+		size += len(header.Key) + len(header.Value)
+	}
+	metrics.ConsumerDecompressedBytes.WithLabelValues(msg.Topic).Add(float64(size))
+	metrics.ConsumerRecordBytes.Observe(float64(size))
+}
 
-		// Store message in MongoDB with status
-		if err := repository.AddMessageToUser(smsEvent.PhoneNumber, smsEvent.Message, smsEvent.Status); err != nil {
-			log.Printf("[ERROR] Failed to store message in MongoDB: %v", err)
-			continue
+// handleWithRetry returns true once msg is done with (handled or invalid) and
+// false if ctx was cancelled first.
+func (c *Consumer) handleWithRetry(ctx context.Context, msg kafka.Message, handle func(ctx context.Context, msg kafka.Message) error) bool {
+	backoff := retryBackoff
+	for {
+		if breaker.Wait(ctx) != nil {
+			return false
+		}
+		err := handle(ctx, msg)
+		breaker.Record(ctx, err != nil)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrInvalidEvent) {
+			logsample.Errorf("[SKIPPED] Committing invalid event at partition %d offset %d: %v", msg.Partition, msg.Offset, err)
+			deadletter.Publish(ctx, msg, err)
+			if errors.Is(err, ErrMalformedEvent) {
+				quarantine.Record(ctx, c.repo, msg, err)
+			}
+			return true
 		}
+		logsample.Errorf("[RETRY] Partition %d offset %d failed, retrying in %s: %v", msg.Partition, msg.Offset, backoff, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// Process runs a raw SMS event payload and its record headers through the
+// standard pipeline. Errors are logged by the stages; the returned error
+// tells callers whether the event was handled.
+func (c *Consumer) Process(ctx context.Context, payload []byte, headers map[string]string) error {
+	return process(ctx, c.Pipeline(), payload, headers, nil)
+}
+
+// ProcessRecord is Process for a record read from Kafka outside the consume
+// loop (e.g. a backfill); the stored message records it as its source.
+func (c *Consumer) ProcessRecord(ctx context.Context, msg kafka.Message) error {
+	return process(ctx, c.Pipeline(), msg.Value, Headers(msg.Headers), recordSource(msg))
+}
+
+// recordSource is the provenance stored with a record's message.
+func recordSource(msg kafka.Message) *models.MessageSource {
+	return &models.MessageSource{
+		Topic:      msg.Topic,
+		Partition:  msg.Partition,
+		Offset:     msg.Offset,
+		IngestedAt: time.Now().UTC(),
+	}
+}
+
+// Headers flattens Kafka record headers into a map keyed by lower-cased name,
+// since header names are matched case-insensitively. The last value wins.
+func Headers(headers []kafka.Header) map[string]string {
+	flat := make(map[string]string, len(headers))
+	for _, header := range headers {
+		flat[strings.ToLower(header.Key)] = string(header.Value)
+	}
+	return flat
+}
+
+func process(ctx context.Context, pipeline *Pipeline, payload []byte, headers map[string]string, source *models.MessageSource) error {
+	start := time.Now()
+	defer func() { metrics.ConsumerProcessDuration.Observe(time.Since(start).Seconds()) }()
+
+	if source != nil {
+		ctx = repository.WithSource(ctx, source)
+	}
+	if _, err := pipeline.Run(ctx, payload, headers); err != nil {
+		anomaly.RecordError()
+		return err
+	}
+	return nil
+}
+
+func readerConfig(cfg *config.Config) kafka.ReaderConfig {
+	startOffset := kafka.FirstOffset
+	if cfg.KafkaStartOffset == "last" {
+		startOffset = kafka.LastOffset
+	}
+	return kafka.ReaderConfig{
+		Brokers:        cfg.KafkaBrokers,
+		Topic:          cfg.KafkaTopic,
+		GroupID:        cfg.KafkaGroupID,
+		MinBytes:       cfg.KafkaMinBytes,
+		MaxBytes:       cfg.KafkaMaxBytes,
+		MaxWait:        cfg.KafkaMaxWait,
+		QueueCapacity:  cfg.KafkaQueueCapacity,
+		CommitInterval: cfg.KafkaCommitInterval,
+		StartOffset:    startOffset,
+
+		SessionTimeout:    cfg.KafkaSessionTimeout,
+		RebalanceTimeout:  cfg.KafkaRebalanceTimeout,
+		HeartbeatInterval: cfg.KafkaHeartbeatInterval,
+		JoinGroupBackoff:  cfg.KafkaJoinGroupBackoff,
+		Dialer: &kafka.Dialer{
+			ClientID:  cfg.KafkaClientID,
+			Timeout:   10 * time.Second,
+			DualStack: true,
+		},
+	}
+}
 
-		log.Printf("[SUCCESS] ✓ Message stored for %s with status: %s", smsEvent.PhoneNumber, smsEvent.Status)
-		log.Println("----------------------------------------")
+// CheckTopic reports whether the brokers are reachable and serve the
+// consumer's topic, for waiting on Kafka at startup.
+func CheckTopic(ctx context.Context, cfg *config.Config) error {
+	client := &kafka.Client{Addr: kafka.TCP(cfg.KafkaBrokers...), Timeout: 10 * time.Second}
+	resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{cfg.KafkaTopic}})
+	if err != nil {
+		return err
+	}
+	for _, topic := range resp.Topics {
+		if topic.Name == cfg.KafkaTopic {
+			return topic.Error
+		}
+	}
+	return fmt.Errorf("topic '%s' not found", cfg.KafkaTopic)
+}
+
+// checkMaxBytes warns when the topic accepts records larger than
+// KAFKA_MAX_BYTES. The reader can never fetch such a record whole: it is
+// re-fetched forever and its partition stalls without an error, so the
+// mismatch is only visible up front. Failing to read the topic config is
+// logged and otherwise ignored.
+func checkMaxBytes(ctx context.Context, cfg *config.Config) {
+	client := &kafka.Client{Addr: kafka.TCP(cfg.KafkaBrokers...), Timeout: 10 * time.Second}
+	resp, err := client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: cfg.KafkaTopic,
+			ConfigNames:  []string{"max.message.bytes"},
+		}},
+	})
+	if err != nil {
+		log.Printf("[OVERSIZED] Could not read max.message.bytes of topic '%s': %v", cfg.KafkaTopic, err)
+		return
+	}
+	for _, resource := range resp.Resources {
+		for _, entry := range resource.ConfigEntries {
+			limit, err := strconv.Atoi(entry.ConfigValue)
+			if entry.ConfigName != "max.message.bytes" || err != nil {
+				continue
+			}
+			if limit > cfg.KafkaMaxBytes {
+				log.Printf("[OVERSIZED] WARNING: topic '%s' accepts records up to %d bytes but KAFKA_MAX_BYTES is %d; larger records will stall their partition",
+					cfg.KafkaTopic, limit, cfg.KafkaMaxBytes)
+			}
+		}
 	}
 }
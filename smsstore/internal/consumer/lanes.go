@@ -0,0 +1,300 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"smsstore/internal/breaker"
+	"smsstore/internal/config"
+	"smsstore/internal/logsample"
+	"smsstore/internal/metrics"
+	"smsstore/internal/ratelimit"
+	"smsstore/pkg/events"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Lanes events are consumed on. Priority events come from the priority
+// topic's dedicated readers or carry the priority header; promotional events
+// otherwise run on the throttled bulk lane; everything else is standard. On
+// the main topic each lane has its own workers (see consumeLanes).
+const (
+	LanePriority = "priority"
+	LaneStandard = "standard"
+	LaneBulk     = "bulk"
+)
+
+// AttributeLane is the envelope attribute holding the event's lane.
+const AttributeLane = "lane"
+
+type laneKey struct{}
+
+// withLane marks events handled under ctx as read on lane.
+func withLane(ctx context.Context, lane string) context.Context {
+	return context.WithValue(ctx, laneKey{}, lane)
+}
+
+func laneFrom(ctx context.Context) string {
+	lane, _ := ctx.Value(laneKey{}).(string)
+	return lane
+}
+
+// bulkLimiter is the shared promotional budget, or nil when
+// PROMOTIONAL_RATE_LIMIT leaves the bulk lane unthrottled.
+func bulkLimiter(cfg *config.Config) ratelimit.Limiter {
+	if cfg.PromotionalRateLimit <= 0 {
+		return nil
+	}
+	return ratelimit.New("promotional", cfg.PromotionalRateLimit, cfg.PromotionalBurst)
+}
+
+// startPriority runs KAFKA_PRIORITY_WORKERS readers on the priority topic,
+// each with its own consume loop so one slow event holds up only its
+// partitions. Blocks until ctx is cancelled.
+func (c *Consumer) startPriority(ctx context.Context, pipeline *Pipeline) {
+	cfg := c.cfg
+	readerCfg := readerConfig(cfg)
+	readerCfg.Topic = cfg.KafkaPriorityTopic
+	readerCfg.GroupID = cfg.KafkaGroupID + "-priority"
+	readerCfg.MinBytes = 1
+	readerCfg.MaxWait = cfg.KafkaPriorityMaxWait
+
+	log.Printf("✓ Priority lane: %d workers on topic '%s' (group %s, maxWait=%s, target=%s)",
+		cfg.KafkaPriorityWorkers, readerCfg.Topic, readerCfg.GroupID, readerCfg.MaxWait, cfg.PriorityLatencyTarget)
+
+	ctx = withLane(ctx, LanePriority)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.KafkaPriorityWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader := kafka.NewReader(readerCfg)
+			defer reader.Close()
+			c.consume(ctx, reader, func(ctx context.Context, msg kafka.Message) error {
+				return process(ctx, pipeline, msg.Value, Headers(msg.Headers), recordSource(msg))
+			})
+		}()
+	}
+	wg.Wait()
+	log.Println("Priority consumer stopped")
+}
+
+// assignLane records the event's lane: the one it was handled on, or for
+// events processed outside the lanes (Process, ProcessRecord) the one it would
+// have been handed to.
+func (c *Consumer) assignLane(ctx context.Context, env *Envelope) error {
+	lane := laneFrom(ctx)
+	if lane == "" {
+		lane = c.laneFor(env.Headers, env.Event.Category)
+	}
+	env.Attributes[AttributeLane] = lane
+	return nil
+}
+
+// laneFor returns the lane of an event with the given record headers and
+// category.
+func (c *Consumer) laneFor(headers map[string]string, category string) string {
+	switch {
+	case c.cfg.KafkaPriorityHeader != "" && strings.EqualFold(strings.TrimSpace(headers[c.cfg.KafkaPriorityHeader]), "high"):
+		return LanePriority
+	case strings.EqualFold(strings.TrimSpace(category), events.CategoryPromotional):
+		return LaneBulk
+	default:
+		return LaneStandard
+	}
+}
+
+// classify returns the lane of a record before it is decoded, peeking at the
+// payload's category. Payloads that don't decode go to the standard lane,
+// whose pipeline rejects them.
+func (c *Consumer) classify(msg kafka.Message) string {
+	var event struct {
+		Category string `json:"category"`
+	}
+	_ = json.Unmarshal(msg.Value, &event)
+	return c.laneFor(Headers(msg.Headers), event.Category)
+}
+
+// laneQueueCapacity is how many records the priority and standard lanes hold
+// ahead of their workers.
+const laneQueueCapacity = 100
+
+// consumeLanes is consume for the main topic with each record handed to its
+// lane (see classify), so a slow lane holds up only its own records:
+// priority events go to a pool of KAFKA_PRIORITY_WORKERS workers, each
+// partition's to the same worker so they keep their order; standard and bulk
+// events are handled one at a time in order, bulk ones once the promotional
+// rate limit allows. Offsets are committed per partition only once every
+// earlier record of the partition is done, so delivery stays at-least-once.
+// Fetching waits while a lane's queue is full, so a bulk backlog past
+// PROMOTIONAL_QUEUE_CAPACITY does hold up the topic. Blocks until ctx is
+// cancelled.
+func (c *Consumer) consumeLanes(ctx context.Context, source MessageSource, handle func(ctx context.Context, msg kafka.Message) error) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	commits := &commitTracker{partitions: map[int]*partitionProgress{}}
+	work := func(lane string, queue <-chan kafka.Message) {
+		defer wg.Done()
+		ctx := withLane(ctx, lane)
+		for {
+			var msg kafka.Message
+			select {
+			case <-ctx.Done():
+				return
+			case msg = <-queue:
+			}
+			if lane == LaneBulk && c.throttle(ctx) != nil {
+				return
+			}
+			if !c.handleWithRetry(ctx, msg, handle) {
+				// Shutting down mid-retry: leave the offset uncommitted so it is redelivered
+				return
+			}
+			commits.complete(ctx, source, msg)
+		}
+	}
+
+	priority := make([]chan kafka.Message, max(c.cfg.KafkaPriorityWorkers, 1))
+	for i := range priority {
+		priority[i] = make(chan kafka.Message, laneQueueCapacity)
+		wg.Add(1)
+		go work(LanePriority, priority[i])
+	}
+	standard := make(chan kafka.Message, laneQueueCapacity)
+	bulk := make(chan kafka.Message, max(c.cfg.PromotionalQueueCapacity, 1))
+	wg.Add(2)
+	go work(LaneStandard, standard)
+	go work(LaneBulk, bulk)
+
+	for {
+		if breaker.Wait(ctx) != nil {
+			return
+		}
+		logsample.Debugf("[WAITING] Polling for new messages...")
+		msg, err := source.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logsample.Errorf("[ERROR] Failed to read Kafka message: %v", err)
+			continue
+		}
+
+		observeRecordSize(msg)
+		lane := c.classify(msg)
+		logsample.Debugf("[RECEIVED] New message from partition %d, offset %d on the %s lane", msg.Partition, msg.Offset, lane)
+
+		queue := standard
+		switch lane {
+		case LanePriority:
+			queue = priority[msg.Partition%len(priority)]
+		case LaneBulk:
+			queue = bulk
+		}
+		commits.track(msg)
+		select {
+		case <-ctx.Done():
+			return
+		case queue <- msg:
+		}
+	}
+}
+
+// throttle waits for the promotional rate limit, if there is one.
+func (c *Consumer) throttle(ctx context.Context) error {
+	if c.bulk == nil {
+		return nil
+	}
+	start := time.Now()
+	for {
+		allowed, wait := c.bulk.Allow(ctx, "bulk")
+		if allowed {
+			metrics.BulkThrottleWait.Observe(time.Since(start).Seconds())
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// commitTracker commits a partition's offsets in order while its records
+// finish out of order on different lanes.
+type commitTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionProgress
+}
+
+// partitionProgress holds the offsets of a partition's records handed to
+// lanes and not yet committed, in fetch order, and which of them are done.
+type partitionProgress struct {
+	pending []int64
+	done    map[int64]bool
+}
+
+// track records msg as handed to a lane. Fetching an offset at or before one
+// still pending means the partition is being read again from its committed
+// offset (e.g. after a rebalance), so its progress starts over.
+func (t *commitTracker) track(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.partitions[msg.Partition]
+	if progress == nil || (len(progress.pending) > 0 && msg.Offset <= progress.pending[len(progress.pending)-1]) {
+		progress = &partitionProgress{done: map[int64]bool{}}
+		t.partitions[msg.Partition] = progress
+	}
+	progress.pending = append(progress.pending, msg.Offset)
+}
+
+// complete marks msg done and commits the partition up to the last record
+// before the first one still in progress. Commits are made under the lock so
+// a partition's offsets are committed in order.
+func (t *commitTracker) complete(ctx context.Context, source MessageSource, msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.partitions[msg.Partition]
+	if progress == nil {
+		return
+	}
+	progress.done[msg.Offset] = true
+	finished := 0
+	for finished < len(progress.pending) && progress.done[progress.pending[finished]] {
+		delete(progress.done, progress.pending[finished])
+		finished++
+	}
+	if finished == 0 {
+		return
+	}
+	last := kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: progress.pending[finished-1]}
+	progress.pending = progress.pending[finished:]
+	if err := source.CommitMessages(ctx, last); err != nil {
+		// A later commit covers this offset; until then a restart redelivers it
+		logsample.Errorf("[ERROR] Failed to commit partition %d offset %d: %v", last.Partition, last.Offset, err)
+	}
+}
+
+// laneLatency records how old the event was when the consumer finished with
+// it, counting priority events over PRIORITY_LATENCY_TARGET.
+func (c *Consumer) laneLatency(ctx context.Context, env *Envelope) error {
+	lane := env.Attributes[AttributeLane]
+	if lane == "" || env.Event.CreatedAt == nil || env.Headers[CanaryHeader] != "" {
+		return nil
+	}
+	latency := time.Since(*env.Event.CreatedAt)
+	if latency < 0 {
+		return nil
+	}
+	metrics.ConsumerLaneLatency.WithLabelValues(lane).Observe(latency.Seconds())
+	if lane == LanePriority && latency > c.cfg.PriorityLatencyTarget {
+		metrics.PriorityLatencyMisses.Inc()
+	}
+	return nil
+}
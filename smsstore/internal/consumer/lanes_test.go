@@ -0,0 +1,193 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"smsstore/internal/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const testPriorityHeader = "x-priority"
+
+func testLaneConsumer(bulk *gateLimiter) *Consumer {
+	c := &Consumer{cfg: &config.Config{
+		KafkaPriorityHeader:      testPriorityHeader,
+		KafkaPriorityWorkers:     2,
+		PromotionalQueueCapacity: 10,
+	}}
+	if bulk != nil {
+		c.bulk = bulk
+	}
+	return c
+}
+
+func TestClassify(t *testing.T) {
+	c := testLaneConsumer(nil)
+	tests := []struct {
+		name     string
+		priority string
+		payload  string
+		want     string
+	}{
+		{name: "transactional", payload: `{"category":"transactional"}`, want: LaneStandard},
+		{name: "no category", payload: `{"user_id":"u1"}`, want: LaneStandard},
+		{name: "promotional", payload: `{"category":"promotional"}`, want: LaneBulk},
+		{name: "promotional as sent", payload: `{"category":" Promotional "}`, want: LaneBulk},
+		{name: "header high", priority: "high", payload: `{"category":"transactional"}`, want: LanePriority},
+		// The header outranks the category: an urgent promotional send skips the throttle
+		{name: "header high on promotional", priority: " HIGH ", payload: `{"category":"promotional"}`, want: LanePriority},
+		{name: "header low", priority: "low", payload: `{"category":"promotional"}`, want: LaneBulk},
+		{name: "undecodable", payload: `not json`, want: LaneStandard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := kafka.Message{Value: []byte(tt.payload)}
+			if tt.priority != "" {
+				msg.Headers = []kafka.Header{{Key: testPriorityHeader, Value: []byte(tt.priority)}}
+			}
+			if got := c.classify(msg); got != tt.want {
+				t.Errorf("classify = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without a priority header configured nothing is promoted
+	c.cfg.KafkaPriorityHeader = ""
+	msg := kafka.Message{Value: []byte(`{}`), Headers: []kafka.Header{{Key: testPriorityHeader, Value: []byte("high")}}}
+	if got := c.classify(msg); got != LaneStandard {
+		t.Errorf("classify without KAFKA_PRIORITY_HEADER = %q, want %q", got, LaneStandard)
+	}
+}
+
+// gateLimiter refuses every request until opened.
+type gateLimiter struct {
+	mu   sync.Mutex
+	open bool
+}
+
+func (g *gateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.open, time.Millisecond
+}
+
+func (g *gateLimiter) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.open = true
+}
+
+// headedPartition is a fakePartition whose records mentioning "urgent" carry
+// the priority header.
+type headedPartition struct {
+	*fakePartition
+}
+
+func (p headedPartition) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	msg, err := p.fakePartition.FetchMessage(ctx)
+	if err == nil && bytes.Contains(msg.Value, []byte("urgent")) {
+		msg.Headers = []kafka.Header{{Key: testPriorityHeader, Value: []byte("high")}}
+	}
+	return msg, err
+}
+
+// A throttled bulk event must not hold up the standard and priority events
+// fetched after it, nor may their offsets be committed past it.
+func TestBulkThrottleDoesNotStallOtherLanes(t *testing.T) {
+	bulk := `{"category":"promotional"}`
+	standard := `{"category":"transactional"}`
+	priority := `{"category":"transactional","urgent":true}`
+	partition := newFakePartition(bulk, standard, priority)
+	gate := &gateLimiter{}
+	c := testLaneConsumer(gate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	store := &fakeStore{}
+	var mu sync.Mutex
+	lanes := map[string]string{}
+	done := make(chan struct{})
+	go func() {
+		c.consumeLanes(ctx, headedPartition{partition}, func(ctx context.Context, msg kafka.Message) error {
+			mu.Lock()
+			lanes[string(msg.Value)] = laneFrom(ctx)
+			mu.Unlock()
+			store.write(msg.Value)
+			return nil
+		})
+		close(done)
+	}()
+
+	for (store.count(standard) == 0 || store.count(priority) == 0) && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if store.count(standard) != 1 || store.count(priority) != 1 {
+		t.Fatal("standard and priority events waited behind the throttled bulk event")
+	}
+	if store.count(bulk) != 0 {
+		t.Fatal("bulk event handled while the limiter refused it")
+	}
+	if got := partition.committedOffset(); got != 0 {
+		t.Fatalf("committed offset = %d while offset 0 is throttled, want 0", got)
+	}
+
+	gate.release()
+	for partition.committedOffset() < int64(len(partition.log)) && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := partition.committedOffset(); got != int64(len(partition.log)) {
+		t.Errorf("committed offset = %d, want %d", got, len(partition.log))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{bulk: LaneBulk, standard: LaneStandard, priority: LanePriority}
+	for payload, lane := range want {
+		if lanes[payload] != lane {
+			t.Errorf("%s handled on the %q lane, want %q", payload, lanes[payload], lane)
+		}
+	}
+}
+
+func TestCommitTrackerCommitsInOrder(t *testing.T) {
+	partition := newFakePartition("a", "b", "c", "d")
+	tracker := &commitTracker{partitions: map[int]*partitionProgress{}}
+	ctx := context.Background()
+	for offset := int64(0); offset < 4; offset++ {
+		tracker.track(kafka.Message{Offset: offset})
+	}
+
+	steps := []struct {
+		complete int64
+		want     int64
+	}{
+		{complete: 2, want: 0},
+		{complete: 1, want: 0},
+		{complete: 0, want: 3},
+		{complete: 3, want: 4},
+	}
+	for _, step := range steps {
+		tracker.complete(ctx, partition, kafka.Message{Offset: step.complete})
+		if got := partition.committedOffset(); got != step.want {
+			t.Fatalf("after completing offset %d committed = %d, want %d", step.complete, got, step.want)
+		}
+	}
+
+	// A partition read again from an earlier offset starts over
+	tracker.track(kafka.Message{Offset: 5})
+	tracker.track(kafka.Message{Offset: 4})
+	tracker.complete(ctx, partition, kafka.Message{Offset: 5})
+	if got := partition.committedOffset(); got != 4 {
+		t.Fatalf("committed = %d after completing an offset dropped by the reset, want 4", got)
+	}
+	tracker.complete(ctx, partition, kafka.Message{Offset: 4})
+	if got := partition.committedOffset(); got != 5 {
+		t.Errorf("committed = %d, want 5", got)
+	}
+}
@@ -0,0 +1,93 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smsstore/internal/logsample"
+	"smsstore/pkg/models"
+	"time"
+)
+
+// ErrInvalidEvent marks events that can never be stored (malformed or failing
+// validation); retrying them is pointless, so the consumer commits past them.
+var ErrInvalidEvent = errors.New("consumer: invalid event")
+
+// ErrMalformedEvent marks invalid events whose payload could not be parsed at
+// all, which are quarantined as well as dead-lettered.
+var ErrMalformedEvent = fmt.Errorf("%w: malformed payload", ErrInvalidEvent)
+
+// CanaryHeader marks the synthetic events of the end-to-end canary (see
+// package canary). They are stored but not counted in rollups or published to
+// webhooks, forwarding rules and change events.
+const CanaryHeader = "x-smsstore-canary"
+
+// ErrSkip stops the pipeline without reporting a failure, e.g. for a suppressed duplicate.
+var ErrSkip = errors.New("consumer: skip remaining stages")
+
+// Envelope carries a single Kafka record through the pipeline. Stages fill it
+// in as they go.
+type Envelope struct {
+	Payload []byte
+	// Headers are the record's headers keyed by lower-cased name
+	Headers map[string]string
+	Event   models.SmsEvent
+	// DedupWindow is set by the dedup stage; zero stores unconditionally
+	DedupWindow time.Duration
+	Stored      *models.MessageWithStatus
+	Duplicate   bool
+	// Sampled is whether this message's routine log lines are written (see logsample)
+	Sampled bool
+	// Attributes lets middleware and stages hand data to later stages (e.g. a resolved tenant)
+	Attributes map[string]string
+}
+
+// Handler processes an envelope.
+type Handler func(ctx context.Context, env *Envelope) error
+
+// Stage is one named step of the pipeline.
+type Stage struct {
+	Name    string
+	Process Handler
+}
+
+// Middleware wraps every stage; stage is the name of the stage being wrapped.
+// Cross-cutting concerns (tracing, metrics, PII masking, tenant resolution)
+// belong here rather than in the consumer loop.
+type Middleware func(stage string, next Handler) Handler
+
+// Pipeline runs stages in order, each wrapped by the registered middleware.
+type Pipeline struct {
+	stages     []Stage
+	middleware []Middleware
+}
+
+// NewPipeline creates a pipeline from the given stages.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Use registers middleware. The first registered is the outermost.
+func (p *Pipeline) Use(middleware ...Middleware) *Pipeline {
+	p.middleware = append(p.middleware, middleware...)
+	return p
+}
+
+// Run pushes payload and its headers through every stage. A stage returning
+// ErrSkip ends the run early with a nil error; any other error aborts it.
+func (p *Pipeline) Run(ctx context.Context, payload []byte, headers map[string]string) (*Envelope, error) {
+	env := &Envelope{Payload: payload, Headers: headers, Sampled: logsample.Sample(), Attributes: map[string]string{}}
+	for _, stage := range p.stages {
+		handler := stage.Process
+		for i := len(p.middleware) - 1; i >= 0; i-- {
+			handler = p.middleware[i](stage.Name, handler)
+		}
+		if err := handler(ctx, env); err != nil {
+			if errors.Is(err, ErrSkip) {
+				return env, nil
+			}
+			return env, err
+		}
+	}
+	return env, nil
+}
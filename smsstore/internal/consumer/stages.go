@@ -0,0 +1,574 @@
+package consumer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"smsstore/internal/anomaly"
+	"smsstore/internal/changeevents"
+	"smsstore/internal/eventschema"
+	"smsstore/internal/features"
+	"smsstore/internal/forwarding"
+	"smsstore/internal/langdetect"
+	"smsstore/internal/logsample"
+	"smsstore/internal/metrics"
+	"smsstore/internal/phonecountry"
+	"smsstore/internal/providerhealth"
+	"smsstore/internal/repository"
+	"smsstore/internal/statusflow"
+	"smsstore/internal/webhooks"
+	"smsstore/pkg/events"
+	"smsstore/pkg/models"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Pipeline builds the standard size → decode → headers → country → region →
+// validate → enrich → hooks → metadata → lane → receipts → transitions →
+// latency → truncate → dedup → persist → rollup → notify → lane_latency
+// pipeline with stage metrics, where hooks are the registered enrichment hooks
+// in order.
+func (c *Consumer) Pipeline() *Pipeline {
+	cfg := c.cfg
+	stages := []Stage{
+		{Name: "size", Process: checkSize(cfg.MaxEventBytes)},
+		{Name: "decode", Process: decode},
+		{Name: "headers", Process: applyHeaders(cfg.KafkaHeaderMapping)},
+		{Name: "country", Process: deriveCountry(cfg.PhoneDefaultCountry)},
+		{Name: "region", Process: resolveRegion(cfg.KafkaRegionHeader, cfg.CountryRegions, c.repo.HasRegion)},
+		{Name: "validate", Process: validate},
+		{Name: "enrich", Process: enrich},
+	}
+	stages = append(stages, hookStages()...)
+	stages = append(stages,
+		// After the hooks, so metadata they add is held to the same limits
+		Stage{Name: "metadata", Process: sanitize},
+		Stage{Name: "lane", Process: c.assignLane},
+		Stage{Name: "receipts", Process: c.receipts},
+		Stage{Name: "transitions", Process: c.transitions(cfg.StatusTransitionMode)},
+		Stage{Name: "latency", Process: c.deliveryLatency},
+		Stage{Name: "truncate", Process: truncate(cfg.MaxMessageBytes)},
+		Stage{Name: "dedup", Process: dedup(cfg.DedupWindow)},
+		Stage{Name: "persist", Process: c.persist},
+		Stage{Name: "rollup", Process: c.rollup},
+		Stage{Name: "notify", Process: notify},
+		Stage{Name: "lane_latency", Process: c.laneLatency},
+	)
+	return NewPipeline(stages...).Use(stageMetrics, scopeContext)
+}
+
+// ImportPipeline builds the pipeline for historical records: the live
+// validation and persistence stages, without header mapping, read receipts,
+// status transition checks or the notify stage, so imports fire no change
+// events, webhooks, forwarded messages, anomaly or provider-health signals.
+// With dryRun the records are only validated.
+func (c *Consumer) ImportPipeline(dryRun bool) *Pipeline {
+	cfg := c.cfg
+	stages := []Stage{
+		{Name: "size", Process: checkSize(cfg.MaxEventBytes)},
+		{Name: "decode", Process: decode},
+		{Name: "country", Process: deriveCountry(cfg.PhoneDefaultCountry)},
+		{Name: "validate", Process: validate},
+		{Name: "history", Process: history},
+		{Name: "enrich", Process: enrich},
+		{Name: "metadata", Process: sanitize},
+		{Name: "truncate", Process: truncate(cfg.MaxMessageBytes)},
+	}
+	if !dryRun {
+		stages = append(stages,
+			Stage{Name: "dedup", Process: dedup(cfg.DedupWindow)},
+			Stage{Name: "persist", Process: c.persist},
+			Stage{Name: "rollup", Process: c.rollup},
+		)
+	}
+	return NewPipeline(stages...).Use(stageMetrics, scopeContext)
+}
+
+// checkSize rejects payloads over maxBytes before they are decoded, so an
+// oversized event is dead-lettered whole instead of being partly stored or
+// failing later against the BSON limit.
+func checkSize(maxBytes int) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		if maxBytes <= 0 || len(env.Payload) <= maxBytes {
+			return nil
+		}
+		metrics.OversizedEvents.WithLabelValues("payload", "rejected").Inc()
+		logsample.Errorf("[OVERSIZED] Rejecting event of %d bytes (MAX_EVENT_BYTES=%d)", len(env.Payload), maxBytes)
+		return fmt.Errorf("%w: event of %d bytes exceeds MAX_EVENT_BYTES=%d", ErrInvalidEvent, len(env.Payload), maxBytes)
+	}
+}
+
+func decode(ctx context.Context, env *Envelope) error {
+	if err := json.Unmarshal(env.Payload, &env.Event); err != nil {
+		logsample.Errorf("[ERROR] Failed to unmarshal SMS event: %v", err)
+		logsample.Errorf("[ERROR] Raw payload: %s", string(env.Payload))
+		return fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+
+	version := eventschema.Version(env.Event)
+	if version < 1 {
+		return fmt.Errorf("%w: invalid schema version %d", ErrInvalidEvent, version)
+	}
+	metrics.EventSchemaVersions.WithLabelValues(eventschema.Label(version)).Inc()
+	if version >= eventschema.CurrentVersion {
+		return nil
+	}
+
+	// Events from older producers are decoded again once upcast
+	payload, err := eventschema.Upcast(env.Payload, version)
+	if err == nil {
+		env.Event = models.SmsEvent{}
+		err = json.Unmarshal(payload, &env.Event)
+	}
+	if err != nil {
+		logsample.Errorf("[ERROR] Failed to upcast SMS event from schema version %d: %v", version, err)
+		return fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+	return nil
+}
+
+// applyHeaders fills event fields the payload left empty from the record
+// headers named in mapping (event field → header name).
+func applyHeaders(mapping map[string]string) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		for field, header := range mapping {
+			value := strings.TrimSpace(env.Headers[strings.ToLower(header)])
+			if value == "" {
+				continue
+			}
+			switch field {
+			case "idempotency_key":
+				if env.Event.IdempotencyKey == "" {
+					env.Event.IdempotencyKey = value
+				}
+			case "tenant_id":
+				if env.Event.TenantID == "" {
+					env.Event.TenantID = value
+				}
+			case "trace_id":
+				if env.Event.TraceID == "" {
+					env.Event.TraceID = traceID(value)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// AttributeRegion is the envelope attribute holding the event's
+// data-residency region; absent for the default cluster.
+const AttributeRegion = "region"
+
+// deriveCountry gives events without a countryCode the country of their
+// phone number (see phonecountry), so every stored message can be counted
+// and routed by country. National-format numbers get defaultCountry.
+func deriveCountry(defaultCountry string) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		env.Event.CountryCode = strings.ToUpper(strings.TrimSpace(env.Event.CountryCode))
+		if env.Event.CountryCode != "" {
+			return nil
+		}
+		env.Event.CountryCode = phonecountry.Derive(env.Event.PhoneNumber, defaultCountry)
+		result := "derived"
+		if env.Event.CountryCode == "" {
+			result = "unknown"
+		}
+		metrics.CountryDerivations.WithLabelValues(result).Inc()
+		return nil
+	}
+}
+
+// resolveRegion reads the event's data-residency region from the header
+// record header, else from countryRegions by the event's country. An event
+// naming a region without a configured cluster, as hasRegion reports, is
+// invalid: storing it anywhere else would break residency.
+func resolveRegion(header string, countryRegions map[string]string, hasRegion func(string) bool) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		region := strings.ToUpper(strings.TrimSpace(env.Headers[header]))
+		if region == "" {
+			region = countryRegions[env.Event.CountryCode]
+		}
+		if region == "" {
+			return nil
+		}
+		if !hasRegion(region) {
+			return fmt.Errorf("%w: no cluster configured for region %q", ErrInvalidEvent, region)
+		}
+		env.Attributes[AttributeRegion] = region
+		return nil
+	}
+}
+
+// traceID extracts the trace-id from a W3C traceparent
+// (version-traceid-parentid-flags); other values are used as-is.
+func traceID(value string) string {
+	parts := strings.Split(value, "-")
+	if len(parts) == 4 && len(parts[1]) == 32 {
+		return parts[1]
+	}
+	return value
+}
+
+// validate applies the event contract's rules (events.ValidateEvent), which
+// producers can check against before publishing.
+func validate(ctx context.Context, env *Envelope) error {
+	if err := events.ValidateEvent(env.Event); err != nil {
+		logsample.Errorf("[ERROR] Rejected SMS event for %q: %v", env.Event.PhoneNumber, err)
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if env.Event.CreatedAt != nil && env.Event.CreatedAt.After(time.Now().Add(maxClockSkew)) {
+		logsample.Errorf("[ERROR] Rejected SMS event for %s created in the future", env.Event.PhoneNumber)
+		return fmt.Errorf("%w: createdAt is in the future", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// history prepares an imported record. Read and click receipts update stored
+// messages rather than being messages, so they are rejected (set readAt on the
+// message instead). Records without an idempotency key get one derived from their
+// content, which makes re-running an interrupted import safe.
+func history(ctx context.Context, env *Envelope) error {
+	if isReadReceipt(env.Event) {
+		return fmt.Errorf("%w: read receipts can't be imported; set readAt on the message instead", ErrInvalidEvent)
+	}
+	if isClickReceipt(env.Event) {
+		return fmt.Errorf("%w: click receipts can't be imported", ErrInvalidEvent)
+	}
+	if strings.TrimSpace(env.Event.IdempotencyKey) == "" {
+		sum := sha256.Sum256(env.Payload)
+		env.Event.IdempotencyKey = "import:" + hex.EncodeToString(sum[:16])
+	}
+	return nil
+}
+
+// enrich normalizes fields so equivalent events are stored identically.
+func enrich(ctx context.Context, env *Envelope) error {
+	env.Event.PhoneNumber = strings.TrimSpace(env.Event.PhoneNumber)
+	env.Event.Status = strings.TrimSpace(env.Event.Status)
+	env.Event.CountryCode = strings.ToUpper(strings.TrimSpace(env.Event.CountryCode))
+	env.Event.IdempotencyKey = strings.TrimSpace(env.Event.IdempotencyKey)
+	env.Event.TenantID = strings.TrimSpace(env.Event.TenantID)
+	env.Event.Category = strings.ToLower(strings.TrimSpace(env.Event.Category))
+	env.Event.SenderID = strings.TrimSpace(env.Event.SenderID)
+	env.Event.Variant = strings.TrimSpace(env.Event.Variant)
+	env.Event.Language = strings.ToLower(strings.TrimSpace(env.Event.Language))
+	if env.Event.Language == "" {
+		env.Event.Language = langdetect.Detect(env.Event.Message)
+	}
+
+	if env.Sampled {
+		logsample.Infof("[PROCESSING] SMS Event - Phone: %s, Status: %s", env.Event.PhoneNumber, env.Event.Status)
+	}
+	logsample.Debugf("[PROCESSING] Message content: %s", env.Event.Message)
+	return nil
+}
+
+func isReadReceipt(event models.SmsEvent) bool {
+	return strings.EqualFold(strings.TrimSpace(event.Status), models.StatusRead)
+}
+
+func isClickReceipt(event models.SmsEvent) bool {
+	return strings.EqualFold(strings.TrimSpace(event.Status), models.StatusClicked)
+}
+
+// receipts applies read and click receipts to the messages they refer to. A
+// receipt is not a message of its own, so the remaining stages are skipped;
+// receipts for messages that aren't stored (yet) are dropped.
+func (c *Consumer) receipts(ctx context.Context, env *Envelope) error {
+	clicked := isClickReceipt(env.Event)
+	if !clicked && !isReadReceipt(env.Event) {
+		return nil
+	}
+	tag, kind, mark, event, reportedAt := "[READ]", "read", c.store.MarkMessagesRead, models.WebhookMessageRead, env.Event.ReadAt
+	if clicked {
+		tag, kind, mark, event, reportedAt = "[CLICK]", "clicked", c.store.MarkMessagesClicked, models.WebhookMessageClicked, env.Event.ClickedAt
+	}
+	at := time.Now().UTC()
+	if reportedAt != nil {
+		at = reportedAt.UTC()
+	}
+	userID, providerMessageID := env.Event.PhoneNumber, env.Event.ProviderMessageID
+	found, err := mark(ctx, userID, providerMessageID, at)
+	if err != nil {
+		logsample.Errorf("[ERROR] Failed to record %s receipt in MongoDB: %v", kind, err)
+		return err
+	}
+	if !found {
+		logsample.Infof("%s Dropped receipt for unknown message %s of %s", tag, providerMessageID, userID)
+		return ErrSkip
+	}
+
+	updated, err := c.store.SearchMessages(ctx, userID, repository.MessageFilter{ProviderMessageID: providerMessageID}, maxReceiptChangeEvents)
+	if err != nil {
+		// The receipt is stored; only the change events are lost
+		logsample.Errorf("[ERROR] Failed to load messages %s by %s: %v", kind, providerMessageID, err)
+		return ErrSkip
+	}
+	for i := range updated {
+		changeevents.PublishMessageChange(ctx, models.ChangeOpUpdate, userID, &updated[i].Message)
+	}
+	// One send is stored as several status events; subscribers get a single event
+	if len(updated) > 0 {
+		webhooks.Publish(ctx, event, userID, &updated[0].Message)
+	}
+	if env.Sampled {
+		logsample.Infof("%s ✓ Message %s of %s %s at %s", tag, providerMessageID, userID, kind, at.Format(time.RFC3339))
+	}
+	return ErrSkip
+}
+
+// transitions checks that a status event can follow the latest stored status
+// of the same send (see statusflow). Only events with a provider message ID
+// can be matched to a send. Depending on mode an invalid event is rejected as
+// ErrInvalidEvent, so the consumer dead-letters it, or stored flagged out of order.
+func (c *Consumer) transitions(mode string) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		if mode == "off" || env.Event.ProviderMessageID == "" {
+			return nil
+		}
+		userID, providerMessageID := env.Event.PhoneNumber, env.Event.ProviderMessageID
+		latest, err := c.store.SearchMessages(ctx, userID, repository.MessageFilter{ProviderMessageID: providerMessageID}, 1)
+		if err != nil {
+			logsample.Errorf("[ERROR] Failed to load stored status of %s: %v", providerMessageID, err)
+			return err
+		}
+		if len(latest) == 0 {
+			return nil
+		}
+		from, to := strings.ToLower(latest[0].Message.Status), strings.ToLower(env.Event.Status)
+		if statusflow.Allowed(from, to) {
+			return nil
+		}
+		if mode == "flag" {
+			metrics.StatusTransitionsInvalid.WithLabelValues(from, to, "flagged").Inc()
+			logsample.Infof("[TRANSITION] Storing %s of %s after %s flagged out of order", to, providerMessageID, from)
+			env.Event.OutOfOrder = true
+			return nil
+		}
+		metrics.StatusTransitionsInvalid.WithLabelValues(from, to, "rejected").Inc()
+		return fmt.Errorf("%w: status %s of %s can't follow %s", ErrInvalidEvent, to, providerMessageID, from)
+	}
+}
+
+// deliveryLatency records on a delivered event how long after the send's
+// first stored status it arrived. Delivery receipts often carry nothing but
+// the provider message ID, so they inherit the send's provider and country
+// code, which the latency is reported by.
+func (c *Consumer) deliveryLatency(ctx context.Context, env *Envelope) error {
+	if !strings.EqualFold(env.Event.Status, models.StatusDelivered) || env.Event.ProviderMessageID == "" {
+		return nil
+	}
+	userID, providerMessageID := env.Event.PhoneNumber, env.Event.ProviderMessageID
+	send, err := c.store.SearchMessages(ctx, userID, repository.MessageFilter{ProviderMessageID: providerMessageID}, maxReceiptChangeEvents)
+	if err != nil {
+		logsample.Errorf("[ERROR] Failed to load stored statuses of %s: %v", providerMessageID, err)
+		return err
+	}
+	if len(send) == 0 {
+		return nil
+	}
+	// Newest first, so the last is the submission
+	submitted := send[len(send)-1].Message
+	if env.Event.Provider == "" {
+		env.Event.Provider = submitted.Provider
+	}
+	if env.Event.CountryCode == "" {
+		env.Event.CountryCode = submitted.CountryCode
+	}
+
+	deliveredAt := time.Now().UTC()
+	if env.Event.CreatedAt != nil {
+		deliveredAt = env.Event.CreatedAt.UTC()
+	}
+	if latency := deliveredAt.Sub(submitted.CreatedAt); latency >= 0 {
+		env.Event.DeliveryLatencyMs = latency.Milliseconds()
+	}
+	return nil
+}
+
+// maxClockSkew is how far ahead of this replica's clock a createdAt may be.
+const maxClockSkew = time.Minute
+
+// maxReceiptChangeEvents bounds the change events one receipt can emit; a send
+// is normally stored as a handful of status events.
+const maxReceiptChangeEvents = 20
+
+// sanitize applies sanitizeMetadata to the event's metadata, as given by the
+// producer and added by hooks.
+func sanitize(ctx context.Context, env *Envelope) error {
+	env.Event.Metadata = sanitizeMetadata(env.Event.PhoneNumber, env.Event.Metadata)
+	return nil
+}
+
+// sanitizeMetadata drops entries that can't be stored or queried safely:
+// invalid keys, oversized values, and anything past the entry limit.
+func sanitizeMetadata(phoneNumber string, metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // deterministic choice of which entries survive the limit
+
+	clean := make(map[string]string, len(metadata))
+	for _, key := range keys {
+		value := metadata[key]
+		switch {
+		case !models.ValidMetadataKey(key):
+			logsample.Infof("[METADATA] Dropped invalid key %q for %s", key, phoneNumber)
+		case len(value) > models.MaxMetadataValueBytes:
+			logsample.Infof("[METADATA] Dropped oversized value for key %q for %s", key, phoneNumber)
+		case len(clean) >= models.MaxMetadataEntries:
+			logsample.Infof("[METADATA] Dropped key %q for %s: more than %d entries", key, phoneNumber, models.MaxMetadataEntries)
+		default:
+			clean[key] = value
+		}
+	}
+	if len(clean) == 0 {
+		return nil
+	}
+	return clean
+}
+
+// truncate caps the stored body at maxBytes, cutting on a UTF-8 boundary and
+// flagging the message so readers know it is incomplete.
+func truncate(maxBytes int) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		size := len(env.Event.Message)
+		if maxBytes <= 0 || size <= maxBytes {
+			return nil
+		}
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(env.Event.Message[cut]) {
+			cut--
+		}
+		env.Event.Message = env.Event.Message[:cut]
+		env.Event.Truncated = true
+		env.Event.OriginalBytes = size
+		metrics.MessagesTruncated.Inc()
+		logsample.Infof("[TRUNCATED] Message for %s cut from %d to %d bytes", env.Event.PhoneNumber, size, cut)
+		return nil
+	}
+}
+
+// dedup decides the suppression window. The duplicate check itself happens
+// atomically with the write in persist, so concurrent duplicates can't race.
+func dedup(window time.Duration) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		env.DedupWindow = window
+		return nil
+	}
+}
+
+// persist stores the event's message. A user document at the BSON size limit
+// is compacted (see repository.CompactUser) and the write retried once; a
+// message that still doesn't fit is invalid and goes to the dead-letter topic.
+func (c *Consumer) persist(ctx context.Context, env *Envelope) error {
+	event := env.Event
+	err := c.storeMessage(ctx, env)
+	if repository.IsDocumentTooLarge(err) {
+		moved, compactErr := c.store.CompactUser(ctx, event.PhoneNumber)
+		if compactErr != nil {
+			logsample.Errorf("[OVERSIZED] Failed to compact oversized document for %s: %v", event.PhoneNumber, compactErr)
+			return compactErr
+		}
+		metrics.OversizedEvents.WithLabelValues("document", "compacted").Inc()
+		logsample.Infof("[OVERSIZED] Compacted %d messages out of the oversized document for %s", moved, event.PhoneNumber)
+		if err = c.storeMessage(ctx, env); repository.IsDocumentTooLarge(err) {
+			metrics.OversizedEvents.WithLabelValues("document", "rejected").Inc()
+			return fmt.Errorf("%w: message too large to store: %v", ErrInvalidEvent, err)
+		}
+	}
+	if err != nil {
+		logsample.Errorf("[ERROR] Failed to store message in MongoDB: %v", err)
+		return err
+	}
+	if env.Duplicate {
+		metrics.DuplicateSuppressed.Inc()
+		switch {
+		case !env.Sampled:
+		case event.IdempotencyKey != "":
+			logsample.Infof("[DUPLICATE] Suppressed message for %s with idempotency key %s", event.PhoneNumber, event.IdempotencyKey)
+		default:
+			logsample.Infof("[DUPLICATE] Suppressed identical message for %s within %s", event.PhoneNumber, env.DedupWindow)
+		}
+		return ErrSkip
+	}
+	return nil
+}
+
+func (c *Consumer) storeMessage(ctx context.Context, env *Envelope) (err error) {
+	event := env.Event
+	switch {
+	case event.IdempotencyKey != "":
+		env.Stored, env.Duplicate, err = c.store.AddMessageToUserIdempotent(ctx, event)
+	case env.DedupWindow > 0:
+		env.Stored, env.Duplicate, err = c.store.AddMessageToUserDeduplicated(ctx, event, env.DedupWindow)
+	default:
+		env.Stored, err = c.store.AddMessageToUser(ctx, event)
+	}
+	return err
+}
+
+// rollup counts the stored message in its tenant's dashboard rollups, unless
+// the rollups feature is off for the tenant or it is a canary. The message is already stored, so
+// a failure is logged rather than retried, which would count it twice.
+func (c *Consumer) rollup(ctx context.Context, env *Envelope) error {
+	if env.Headers[CanaryHeader] != "" || !features.EnabledFor(features.Rollups, env.Stored.TenantID) {
+		return nil
+	}
+	if err := c.store.RecordRollup(ctx, env.Stored); err != nil {
+		logsample.Errorf("[ROLLUP] Failed to count message for %s in rollups: %v", env.Event.PhoneNumber, err)
+	}
+	return nil
+}
+
+func notify(ctx context.Context, env *Envelope) error {
+	if env.Headers[CanaryHeader] != "" {
+		return nil
+	}
+	changeevents.PublishMessageChange(ctx, models.ChangeOpInsert, env.Event.PhoneNumber, env.Stored)
+	webhooks.Publish(ctx, webhooks.EventForStatus(env.Event.Status), env.Event.PhoneNumber, env.Stored)
+	forwarding.Publish(ctx, env.Event.PhoneNumber, env.Stored)
+	anomaly.RecordEvent(env.Event.Status)
+	providerhealth.Record(env.Event.Provider, env.Event.Status, time.Duration(env.Event.ProviderLatencyMs)*time.Millisecond)
+	if env.Stored.DeliveryLatencyMs > 0 {
+		latency := time.Duration(env.Stored.DeliveryLatencyMs) * time.Millisecond
+		metrics.DeliveryLatency.WithLabelValues(env.Event.Provider, env.Event.CountryCode).Observe(latency.Seconds())
+	}
+
+	if env.Sampled {
+		logsample.Infof("[SUCCESS] ✓ Message stored for %s with status: %s", env.Event.PhoneNumber, env.Event.Status)
+	}
+	return nil
+}
+
+// scopeContext runs each stage against the database of the event's region
+// (once resolveRegion has set it) and tenant.
+func scopeContext(stage string, next Handler) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		if region := env.Attributes[AttributeRegion]; region != "" {
+			ctx = repository.WithRegion(ctx, region)
+		}
+		if env.Event.TenantID != "" {
+			ctx = repository.WithTenant(ctx, env.Event.TenantID)
+		}
+		return next(ctx, env)
+	}
+}
+
+// stageMetrics records how long each stage takes.
+func stageMetrics(stage string, next Handler) Handler {
+	return func(ctx context.Context, env *Envelope) error {
+		start := time.Now()
+		err := next(ctx, env)
+		metrics.ConsumerStageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
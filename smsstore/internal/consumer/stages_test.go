@@ -0,0 +1,106 @@
+package consumer
+
+import (
+	"context"
+	"maps"
+	"smsstore/internal/config"
+	"smsstore/pkg/models"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// The truncate stage cuts bodies to MAX_MESSAGE_BYTES without ever splitting
+// a multi-byte character, so stored bodies stay valid UTF-8.
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		maxBytes  int
+		want      string
+		truncated bool
+	}{
+		{name: "disabled", message: "hello world", maxBytes: 0, want: "hello world"},
+		{name: "within limit", message: "hello", maxBytes: 5, want: "hello"},
+		{name: "ascii", message: "hello world", maxBytes: 5, want: "hello", truncated: true},
+		// "é" is 2 bytes at offsets 1-2: a cut at 2 would split it
+		{name: "inside two-byte rune", message: "héllo", maxBytes: 2, want: "h", truncated: true},
+		{name: "after two-byte rune", message: "héllo", maxBytes: 3, want: "hé", truncated: true},
+		// "₹" is 3 bytes and "😀" 4, at offsets 0-2 and 3-6
+		{name: "inside three-byte rune", message: "₹😀", maxBytes: 2, want: "", truncated: true},
+		{name: "inside four-byte rune", message: "₹😀", maxBytes: 6, want: "₹", truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &Envelope{Event: models.SmsEvent{PhoneNumber: "+15550100", Message: tt.message}}
+			if err := truncate(tt.maxBytes)(context.Background(), env); err != nil {
+				t.Fatalf("truncate: %v", err)
+			}
+			if env.Event.Message != tt.want {
+				t.Errorf("message = %q, want %q", env.Event.Message, tt.want)
+			}
+			if !utf8.ValidString(env.Event.Message) {
+				t.Errorf("message %q is not valid UTF-8", env.Event.Message)
+			}
+			if env.Event.Truncated != tt.truncated {
+				t.Errorf("truncated = %t, want %t", env.Event.Truncated, tt.truncated)
+			}
+			if tt.truncated && env.Event.OriginalBytes != len(tt.message) {
+				t.Errorf("original bytes = %d, want %d", env.Event.OriginalBytes, len(tt.message))
+			}
+		})
+	}
+}
+
+// Hooks run before the metadata stage, so what they add to the event's
+// metadata is held to the limits applied to producer metadata.
+func TestHookMetadataSanitized(t *testing.T) {
+	hooksMu.Lock()
+	registered := hooks
+	hooks = nil
+	hooksMu.Unlock()
+	t.Cleanup(func() {
+		hooksMu.Lock()
+		hooks = registered
+		hooksMu.Unlock()
+	})
+	RegisterHook(Hook{Name: "merchant", Enricher: EnricherFunc(func(ctx context.Context, env *Envelope) error {
+		if env.Event.Metadata == nil {
+			env.Event.Metadata = map[string]string{}
+		}
+		env.Event.Metadata["merchant_tier"] = "gold"
+		env.Event.Metadata["bad key!"] = "dropped"
+		env.Event.Metadata["profile"] = strings.Repeat("x", models.MaxMetadataValueBytes+1)
+		return nil
+	})})
+
+	pipeline := New(&config.Config{}, nil, nil).Pipeline()
+	env := &Envelope{Event: models.SmsEvent{
+		PhoneNumber: "+15550100",
+		Metadata:    map[string]string{"order_id": "OD-1"},
+	}}
+	hooksSeen, sanitized := 0, false
+	for _, stage := range pipeline.stages {
+		switch {
+		case strings.HasPrefix(stage.Name, "hook:"):
+			if sanitized {
+				t.Fatalf("hook stage %s runs after the metadata stage", stage.Name)
+			}
+			hooksSeen++
+		case stage.Name == "metadata":
+			sanitized = true
+		default:
+			continue
+		}
+		if err := stage.Process(context.Background(), env); err != nil {
+			t.Fatalf("stage %s: %v", stage.Name, err)
+		}
+	}
+	if hooksSeen != 1 || !sanitized {
+		t.Fatalf("ran %d hook stages and metadata stage %t, want 1 and true", hooksSeen, sanitized)
+	}
+
+	if want := map[string]string{"order_id": "OD-1", "merchant_tier": "gold"}; !maps.Equal(env.Event.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", env.Event.Metadata, want)
+	}
+}
@@ -2,8 +2,10 @@ package db
 
 import (
 	"context"
+	"errors"
 	"log"
 	"smsstore/internal/config"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,64 +13,150 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var (
-	mongoClient *mongo.Client
-	initErr     error
-	once        sync.Once
-)
+// ErrUnknownRegion is returned for a region without a MONGO_REGION_URIS entry.
+var ErrUnknownRegion = errors.New("unknown data region")
 
-// GetClient returns a singleton MongoDB client initialized with app config.
-// Returns an error if the connection failed during initialization.
-// Subsequent calls will return the same error if initialization failed.
-func GetClient() (*mongo.Client, error) {
-	once.Do(func() {
-		cfg, err := config.LoadConfig()
-		if err != nil {
-			initErr = err
-			mongoClient = nil
-			return
-		}
-		mongoClient, initErr = connectDB(cfg.MongoURI)
-		if initErr != nil {
-			log.Printf("Failed to connect to MongoDB: %v", initErr)
-			mongoClient = nil
-		}
-	})
-	return mongoClient, initErr
+// Clients holds the MongoDB connections the service uses: the default
+// MONGO_URI cluster, one per data-residency region and the optional analytics
+// connection. Each connects on first use; a failed connection is retried on
+// the next call rather than remembered, so the service recovers once MongoDB
+// is reachable.
+type Clients struct {
+	primary *lazyClient
+	// analytics is nil without MONGO_ANALYTICS_URI
+	analytics *lazyClient
+	regions   map[string]*lazyClient
 }
 
-func connectDB(uri string) (*mongo.Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// New returns the clients for cfg's MongoDB settings without connecting.
+func New(cfg *config.Config) *Clients {
+	c := &Clients{
+		primary: &lazyClient{name: "MongoDB", uri: cfg.MongoURI},
+		regions: make(map[string]*lazyClient, len(cfg.MongoRegionURIs)),
+	}
+	if cfg.MongoAnalyticsURI != "" {
+		c.analytics = &lazyClient{name: "the MongoDB analytics URI", uri: cfg.MongoAnalyticsURI}
+	}
+	for region, uri := range cfg.MongoRegionURIs {
+		c.regions[region] = &lazyClient{name: "MongoDB region " + region, uri: uri}
+	}
+	return c
+}
 
-	clientOptions := options.Client().ApplyURI(uri)
+// Client returns the client for the default MONGO_URI cluster.
+func (c *Clients) Client() (*mongo.Client, error) {
+	return c.primary.get()
+}
 
-	client, err := mongo.Connect(ctx, clientOptions)
-	if err != nil {
-		return nil, err
+// RegionClient returns the client for a data-residency region; the empty
+// region is the default MONGO_URI cluster.
+func (c *Clients) RegionClient(region string) (*mongo.Client, error) {
+	if region == "" {
+		return c.primary.get()
+	}
+	client, ok := c.regions[region]
+	if !ok {
+		return nil, ErrUnknownRegion
 	}
+	return client.get()
+}
 
-	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
+// AnalyticsClient returns the client for analytics reads: the separate
+// MONGO_ANALYTICS_URI connection when that is set, otherwise the default one.
+func (c *Clients) AnalyticsClient() (*mongo.Client, error) {
+	if c.analytics == nil {
+		return c.primary.get()
 	}
+	return c.analytics.get()
+}
 
-	log.Println("MongoDB connected successfully")
+// Regions returns the configured data-residency regions, sorted, preceded by
+// the default region "".
+func (c *Clients) Regions() []string {
+	regions := []string{""}
+	for region := range c.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions[1:])
+	return regions
+}
+
+// HasRegion reports whether region is the default region or configured.
+func (c *Clients) HasRegion(region string) bool {
+	_, ok := c.regions[region]
+	return region == "" || ok
+}
+
+// Disconnect gracefully closes every connected client. Returns the error
+// from the default cluster; the others are only logged.
+func (c *Clients) Disconnect() error {
+	if c.analytics != nil {
+		c.analytics.disconnect()
+	}
+	for _, client := range c.regions {
+		client.disconnect()
+	}
+	return c.primary.disconnect()
+}
+
+// lazyClient connects to uri on first use.
+type lazyClient struct {
+	name string
+	uri  string
+
+	mu     sync.Mutex
+	client *mongo.Client
+}
+
+func (l *lazyClient) get() (*mongo.Client, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.client != nil {
+		return l.client, nil
+	}
+	client, err := connectDB(l.uri)
+	if err != nil {
+		log.Printf("Failed to connect to %s: %v", l.name, err)
+		return nil, err
+	}
+	l.client = client
 	return client, nil
 }
 
-// DisconnectMongo gracefully closes the shared client.
-func DisconnectMongo() error {
-	if mongoClient == nil {
+func (l *lazyClient) disconnect() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.client == nil {
 		return nil
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := mongoClient.Disconnect(ctx)
+	err := l.client.Disconnect(ctx)
+	l.client = nil
 	if err != nil {
-		log.Printf("Error disconnecting from MongoDB: %v", err)
+		log.Printf("Error disconnecting from %s: %v", l.name, err)
 		return err
 	}
-	log.Println("MongoDB disconnected successfully")
+	log.Printf("Disconnected from %s", l.name)
 	return nil
 }
+
+func connectDB(uri string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientOptions := options.Client().ApplyURI(uri)
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	log.Println("MongoDB connected successfully")
+	return client, nil
+}
@@ -0,0 +1,72 @@
+package deadletter
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/kafkawriter"
+	"smsstore/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to dead-lettered records, next to the original record's headers.
+const (
+	HeaderReason    = "x-dead-letter-reason"
+	HeaderTopic     = "x-dead-letter-topic"
+	HeaderPartition = "x-dead-letter-partition"
+	HeaderOffset    = "x-dead-letter-offset"
+)
+
+// writer is nil when dead-lettering is disabled, turning Publish into a no-op.
+var writer *kafka.Writer
+
+// Init configures the dead-letter writer from app config.
+// Must be called before the consumer starts.
+func Init(cfg *config.Config) {
+	if !cfg.DeadLetterEnabled {
+		log.Println("[DEAD-LETTER] Publisher disabled")
+		return
+	}
+	writer = kafkawriter.New(cfg, cfg.DeadLetterTopic, 50*time.Millisecond)
+	log.Printf("[DEAD-LETTER] Publishing invalid events to topic '%s'", cfg.DeadLetterTopic)
+}
+
+// Publish copies msg to the dead-letter topic with the reason it was rejected
+// and where it came from. Failures are logged and never propagated: the record
+// is committed past either way, as it can never be stored.
+func Publish(ctx context.Context, msg kafka.Message, reason error) {
+	if writer == nil {
+		return
+	}
+
+	headers := make([]kafka.Header, 0, len(msg.Headers)+4)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderReason, Value: []byte(reason.Error())},
+		kafka.Header{Key: HeaderTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := writer.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		metrics.DeadLetterEvents.WithLabelValues("failed").Inc()
+		log.Printf("[DEAD-LETTER] Failed to publish partition %d offset %d: %v", msg.Partition, msg.Offset, err)
+		return
+	}
+	metrics.DeadLetterEvents.WithLabelValues("published").Inc()
+}
+
+// Close flushes and closes the writer.
+func Close() error {
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
@@ -0,0 +1,52 @@
+package devmode
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// busCapacity bounds how many unconsumed events the bus holds before Publish blocks.
+const busCapacity = 1000
+
+// localBus is an in-process stand-in for the sms_events topic. Nothing is
+// persisted, so events still in flight are lost on restart.
+type localBus struct {
+	messages chan kafka.Message
+
+	mu   sync.Mutex
+	next int64
+}
+
+func newLocalBus() *localBus {
+	return &localBus{messages: make(chan kafka.Message, busCapacity)}
+}
+
+func (b *localBus) publish(ctx context.Context, payload []byte) error {
+	b.mu.Lock()
+	msg := kafka.Message{Offset: b.next, Value: payload}
+	b.next++
+	b.mu.Unlock()
+
+	select {
+	case b.messages <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *localBus) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-b.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+// CommitMessages is a no-op: a message leaves the bus once it is fetched.
+func (b *localBus) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return nil
+}
@@ -0,0 +1,210 @@
+package devmode
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"smsstore/internal/config"
+	"smsstore/internal/consumer"
+	"smsstore/internal/tenants"
+	"smsstore/pkg/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderName is recorded on events sent through the simulated provider.
+const ProviderName = "devsim"
+
+// ErrDisabled is returned by Send when DEV_MODE is off.
+var ErrDisabled = errors.New("dev mode is disabled")
+
+var (
+	bus        *localBus
+	failRate   float64
+	maxLatency time.Duration
+)
+
+// Init sets up the in-process bus and simulated provider when DEV_MODE is on.
+// Must be called before the consumer starts.
+func Init(cfg *config.Config) {
+	if !cfg.DevMode {
+		return
+	}
+	bus = newLocalBus()
+	failRate = cfg.DevProviderFailRate
+	maxLatency = cfg.DevProviderMaxLatency
+	log.Printf("[DEV] DEV_MODE enabled: in-process event bus, simulated provider (failRate=%.2f maxLatency=%s)",
+		failRate, maxLatency)
+}
+
+// Bus returns the in-process event source for the consumer, or nil when DEV_MODE is off.
+func Bus() consumer.MessageSource {
+	if bus == nil {
+		return nil
+	}
+	return bus
+}
+
+// ErrSenderNotAllowed is returned by Send when the tenant's configuration
+// doesn't allow the request's sender ID.
+var ErrSenderNotAllowed = errors.New("sender ID is not allowed for tenant")
+
+// QuotaExceededError is returned by Send when the tenant has used up its
+// daily quota.
+type QuotaExceededError struct {
+	TenantID   string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily quota exceeded for tenant %s", e.TenantID)
+}
+
+var (
+	quotaMu  sync.Mutex
+	quotaDay string
+	sent     = map[string]int{}
+)
+
+// Send simulates the sender's send path. The tenant's configuration is
+// applied first: sender IDs it doesn't allow are refused, promotional sends
+// inside its quiet hours are deferred until they end, and sends beyond its
+// daily quota are refused. Then the fake provider waits a random delay, fails
+// a configurable fraction of sends, and the resulting event is published on
+// the in-process bus for the consumer to store. The returned result matches
+// what the sms-sender API would say.
+func Send(ctx context.Context, req models.SmsRequest) (string, error) {
+	if bus == nil {
+		return "", ErrDisabled
+	}
+
+	tenant, err := tenants.Get(ctx, req.TenantID)
+	if err != nil {
+		return "", err
+	}
+	if !tenant.AllowsSender(req.SenderID) {
+		return "", ErrSenderNotAllowed
+	}
+
+	// Quiet hours are checked before quota so held messages don't count yet
+	now := time.Now()
+	if req.Category == "promotional" {
+		if releaseAt, ok := tenants.QuietHoursEnd(tenant.QuietHours, now); ok {
+			return deferUntil(ctx, req, releaseAt)
+		}
+	}
+	if err := consumeQuota(req.TenantID, tenant.DailyQuota, now); err != nil {
+		return "", err
+	}
+	return deliver(ctx, req)
+}
+
+// deferUntil records a deferred event and sends the request once releaseAt
+// has passed. Deferred sends are held in memory and lost on restart.
+func deferUntil(ctx context.Context, req models.SmsRequest, releaseAt time.Time) (string, error) {
+	event := newEvent(req)
+	event.Status = "deferred"
+	if err := publish(ctx, event); err != nil {
+		return "", err
+	}
+	time.AfterFunc(time.Until(releaseAt), func() {
+		if _, err := deliver(context.Background(), req); err != nil {
+			log.Printf("[DEV] Deferred send to %s failed: %v", req.PhoneNumber, err)
+		}
+	})
+	return fmt.Sprintf("Deferred until %s: Quiet hours in effect for tenant %s", releaseAt.UTC().Format(time.RFC3339), req.TenantID), nil
+}
+
+// consumeQuota counts a send against the tenant's quota for the current UTC
+// day, refusing it once quota sends were made. Zero quota is unlimited.
+func consumeQuota(tenantID string, quota int, now time.Time) error {
+	if quota == 0 {
+		return nil
+	}
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+
+	day := now.UTC().Format(time.DateOnly)
+	if day != quotaDay {
+		quotaDay, sent = day, map[string]int{}
+	}
+	if sent[tenantID] >= quota {
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return &QuotaExceededError{TenantID: tenantID, RetryAfter: midnight.Sub(now)}
+	}
+	sent[tenantID]++
+	return nil
+}
+
+// deliver runs a send through the simulated provider and publishes its outcome.
+func deliver(ctx context.Context, req models.SmsRequest) (string, error) {
+	event := newEvent(req)
+	started := time.Now()
+	var delay time.Duration
+	if maxLatency > 0 {
+		delay = time.Duration(mathrand.Int63n(int64(maxLatency)))
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	event.ProviderLatencyMs = time.Since(started).Milliseconds()
+
+	result := "SMS sent to " + req.PhoneNumber
+	if mathrand.Float64() < failRate {
+		event.Status = "unsuccessful"
+		result = "Failed to send SMS: simulated provider failure"
+	} else {
+		event.Status = "successful"
+		event.ProviderMessageID = "DEV" + randomID()
+	}
+	if err := publish(ctx, event); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+func newEvent(req models.SmsRequest) models.SmsEvent {
+	event := models.SmsEvent{
+		SchemaVersion: models.SchemaVersion,
+		PhoneNumber:   req.PhoneNumber,
+		Message:       req.Message,
+		Provider:      ProviderName,
+		CampaignID:    req.CampaignID,
+		TemplateID:    req.TemplateID,
+		CountryCode:   req.CountryCode,
+		Metadata:      req.Metadata,
+		TenantID:      req.TenantID,
+		Category:      req.Category,
+	}
+	if event.Category == "" {
+		event.Category = "transactional"
+	}
+	if !strings.EqualFold(req.SenderID, models.DefaultSenderID) {
+		event.SenderID = req.SenderID
+	}
+	return event
+}
+
+func publish(ctx context.Context, event models.SmsEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return bus.publish(ctx, payload)
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,54 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/mux"
+)
+
+// PprofPrefix is where the net/http/pprof handlers are mounted.
+const PprofPrefix = "/debug/pprof/"
+
+// MountPprof registers the standard pprof handlers on router under
+// PprofPrefix, wrapped in guard (e.g. admin auth). CPU profiles and traces run
+// for ?seconds=N, which must stay below the server's write timeout.
+func MountPprof(router *mux.Router, guard func(http.Handler) http.Handler) {
+	debug := router.PathPrefix(strings.TrimSuffix(PprofPrefix, "/")).Subrouter()
+	debug.Use(mux.MiddlewareFunc(guard))
+	debug.HandleFunc("/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/profile", pprof.Profile)
+	debug.HandleFunc("/symbol", pprof.Symbol)
+	debug.HandleFunc("/trace", pprof.Trace)
+	// Index also serves the named profiles (heap, goroutine, allocs, block, mutex, ...)
+	debug.PathPrefix("/").HandlerFunc(pprof.Index)
+}
+
+// DumpOnSignal logs a full goroutine dump whenever the process receives
+// SIGUSR1, without stopping it (unlike SIGQUIT, which dumps and exits).
+// Blocks until ctx is cancelled.
+func DumpOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			var dump bytes.Buffer
+			runtimepprof.Lookup("goroutine").WriteTo(&dump, 2)
+			log.Printf("[DIAGNOSTICS] SIGUSR1 goroutine dump (%d goroutines):\n%s", runtime.NumGoroutine(), dump.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package eventschema migrates SMS events written by older producers to the
+// current event schema. Producers stamp events with schemaVersion; events
+// without one are version 1. Each version has an upcaster that rewrites an
+// event of that version into the next, so an old event is migrated one step
+// at a time until it is current:
+//
+//	1 → 2  events predate categories; they are transactional
+//	2 → 3  the default sender is no longer sent as senderId "default"
+package eventschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+)
+
+// CurrentVersion is the schema version the consumer decodes into models.SmsEvent.
+const CurrentVersion = models.SchemaVersion
+
+const versionField = "schemaVersion"
+
+// An Upcaster rewrites the fields of an event of one schema version into the
+// next version.
+type Upcaster func(fields map[string]json.RawMessage) error
+
+// upcasters maps each version below CurrentVersion to its upcaster.
+var upcasters = map[int]Upcaster{
+	1: defaultCategory,
+	2: dropDefaultSender,
+}
+
+// Version returns the schema version of a decoded event; unversioned events
+// are version 1.
+func Version(event models.SmsEvent) int {
+	if event.SchemaVersion == 0 {
+		return 1
+	}
+	return event.SchemaVersion
+}
+
+// Label names a version for metrics. Versions newer than the current one are
+// grouped so producers can't grow the label set without bound.
+func Label(version int) string {
+	if version > CurrentVersion {
+		return "newer"
+	}
+	return strconv.Itoa(version)
+}
+
+// Upcast migrates payload, an event of the given version, to CurrentVersion.
+// Events already at (or beyond) the current version are returned unchanged;
+// newer producers are expected to only add fields.
+func Upcast(payload []byte, version int) ([]byte, error) {
+	if version >= CurrentVersion {
+		return payload, nil
+	}
+	if version < 1 {
+		return nil, fmt.Errorf("invalid schema version %d", version)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for ; version < CurrentVersion; version++ {
+		upcast, ok := upcasters[version]
+		if !ok {
+			return nil, fmt.Errorf("no upcaster from schema version %d", version)
+		}
+		if err := upcast(fields); err != nil {
+			return nil, fmt.Errorf("upcasting from schema version %d: %w", version, err)
+		}
+	}
+	fields[versionField] = json.RawMessage(fmt.Sprint(CurrentVersion))
+	return json.Marshal(fields)
+}
+
+// defaultCategory upcasts version 1: categories were introduced with version
+// 2, and everything sent before then was transactional.
+func defaultCategory(fields map[string]json.RawMessage) error {
+	var category string
+	if raw, ok := fields["category"]; ok {
+		if err := json.Unmarshal(raw, &category); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(category) == "" {
+		fields["category"] = json.RawMessage(`"transactional"`)
+	}
+	return nil
+}
+
+// dropDefaultSender upcasts version 2: those producers sent the placeholder
+// "default" for messages going out under the default sender, which version 3
+// leaves out.
+func dropDefaultSender(fields map[string]json.RawMessage) error {
+	raw, ok := fields["senderId"]
+	if !ok {
+		return nil
+	}
+	var senderID string
+	if err := json.Unmarshal(raw, &senderID); err != nil {
+		return err
+	}
+	if strings.EqualFold(strings.TrimSpace(senderID), models.DefaultSenderID) {
+		delete(fields, "senderId")
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// Package features turns features on and off at runtime, for everyone or per
+// tenant, so code can be deployed dark and enabled gradually. Each feature has
+// a default (config.Features), overridden in turn by FEATURE_FLAGS,
+// FEATURE_FLAGS_FILE and overrides set through the admin API; the highest
+// source that says anything about a tenant, or else about every tenant, wins.
+// Overrides live in Mongo: changes made through a replica apply to it
+// immediately and reach the others within FEATURE_FLAGS_REFRESH_INTERVAL.
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+)
+
+// Features gated by flags.
+const (
+	UserCache  = "user_cache"
+	Webhooks   = "webhooks"
+	Forwarding = "forwarding"
+	Rollups    = "rollups"
+	Timeseries = "timeseries"
+)
+
+var (
+	// ErrUnknownFeature is returned for names missing from config.Features.
+	ErrUnknownFeature = errors.New("unknown feature")
+	// ErrInvalidOverride wraps validation failures of an admin override.
+	ErrInvalidOverride = errors.New("invalid feature flag override")
+)
+
+var (
+	mu  sync.RWMutex
+	env map[string]config.FeatureFlag
+	// file and overrides are nil until loaded
+	file      map[string]config.FeatureFlag
+	overrides map[string]config.FeatureFlag
+
+	filePath    string
+	fileModTime time.Time
+
+	// store holds the admin overrides
+	store *repository.Repository
+)
+
+// Init applies FEATURE_FLAGS and loads FEATURE_FLAGS_FILE. It fails if the file
+// can't be loaded, so a misconfigured server never starts serving. Admin
+// overrides are loaded from repo by Refresh.
+func Init(cfg *config.Config, repo *repository.Repository) error {
+	mu.Lock()
+	env, filePath, store = cfg.FeatureFlags, cfg.FeatureFlagsFile, repo
+	mu.Unlock()
+	if filePath != "" {
+		if err := loadFile(); err != nil {
+			return fmt.Errorf("load feature flags: %w", err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Features)) {
+		if !EnabledFor(name, "") {
+			log.Printf("[FEATURES] %s is off", name)
+		}
+	}
+	return nil
+}
+
+// Watch refreshes the file and the admin overrides every interval. A failed
+// refresh keeps the previous flags. Blocks until ctx is cancelled.
+func Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		Refresh(ctx)
+	}
+}
+
+// Refresh re-reads FEATURE_FLAGS_FILE when it has changed and reloads the
+// admin overrides. Failures are logged and keep the previous flags.
+func Refresh(ctx context.Context) {
+	if filePath != "" {
+		info, err := os.Stat(filePath)
+		mu.RLock()
+		unchanged := err == nil && info.ModTime().Equal(fileModTime)
+		mu.RUnlock()
+		if !unchanged {
+			if err := loadFile(); err != nil {
+				log.Printf("[FEATURES] Reloading %s failed, keeping previous flags: %v", filePath, err)
+			} else {
+				log.Printf("[FEATURES] Reloaded %s", filePath)
+			}
+		}
+	}
+	if err := loadOverrides(ctx); err != nil {
+		log.Printf("[FEATURES] Loading overrides failed, keeping previous ones: %v", err)
+	}
+}
+
+func loadFile() error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var loaded map[string]config.FeatureFlag
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	if err := config.ValidateFeatureFlags(loaded); err != nil {
+		return err
+	}
+	mu.Lock()
+	file, fileModTime = loaded, info.ModTime()
+	mu.Unlock()
+	return nil
+}
+
+func loadOverrides(ctx context.Context) error {
+	stored, err := store.ListFeatureFlagOverrides(ctx)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string]config.FeatureFlag, len(stored))
+	for _, override := range stored {
+		if _, ok := config.Features[override.Name]; !ok {
+			// Set by a newer replica, or for a feature since removed
+			continue
+		}
+		loaded[override.Name] = config.FeatureFlag{Enabled: override.Enabled, Tenants: override.Tenants}
+	}
+	mu.Lock()
+	overrides = loaded
+	mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether a feature is on for the tenant of ctx (see
+// repository.WithTenant), or for everyone when ctx has no tenant.
+func Enabled(ctx context.Context, name string) bool {
+	return EnabledFor(name, repository.TenantFrom(ctx))
+}
+
+// EnabledFor reports whether a feature is on for a tenant; an empty tenantID
+// asks about every tenant. Unknown features are off.
+func EnabledFor(name string, tenantID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled(name, tenantID)
+}
+
+// enabled evaluates the sources from the highest down. Callers hold mu.
+func enabled(name string, tenantID string) bool {
+	for _, source := range []map[string]config.FeatureFlag{overrides, file, env} {
+		flag, ok := source[name]
+		if !ok {
+			continue
+		}
+		if on, ok := flag.Tenants[tenantID]; ok && tenantID != "" {
+			return on
+		}
+		if flag.Enabled != nil {
+			return *flag.Enabled
+		}
+	}
+	return config.Features[name]
+}
+
+// List returns every feature's effective state and sources, by name.
+func List() []models.FeatureFlagState {
+	mu.RLock()
+	defer mu.RUnlock()
+	states := make([]models.FeatureFlagState, 0, len(config.Features))
+	for _, name := range slices.Sorted(maps.Keys(config.Features)) {
+		state := models.FeatureFlagState{
+			Name:     name,
+			Default:  config.Features[name],
+			Enabled:  enabled(name, ""),
+			Env:      setting(env, name),
+			File:     setting(file, name),
+			Override: setting(overrides, name),
+		}
+		for _, source := range []map[string]config.FeatureFlag{overrides, file, env} {
+			for tenant := range source[name].Tenants {
+				if state.Tenants == nil {
+					state.Tenants = map[string]bool{}
+				}
+				state.Tenants[tenant] = enabled(name, tenant)
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+func setting(source map[string]config.FeatureFlag, name string) *models.FeatureFlagSetting {
+	flag, ok := source[name]
+	if !ok {
+		return nil
+	}
+	return &models.FeatureFlagSetting{Enabled: flag.Enabled, Tenants: flag.Tenants}
+}
+
+// SetOverride stores an admin override for a feature, replacing any previous
+// one, and applies it on this replica straight away.
+func SetOverride(ctx context.Context, name string, setting models.FeatureFlagSetting) (*models.FeatureFlagOverride, error) {
+	if _, ok := config.Features[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+	if setting.Enabled == nil && len(setting.Tenants) == 0 {
+		return nil, fmt.Errorf("%w: set enabled or tenants", ErrInvalidOverride)
+	}
+	flag := config.FeatureFlag{Enabled: setting.Enabled, Tenants: setting.Tenants}
+	if err := config.ValidateFeatureFlags(map[string]config.FeatureFlag{name: flag}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+	}
+	override := &models.FeatureFlagOverride{
+		Name:      name,
+		Enabled:   setting.Enabled,
+		Tenants:   setting.Tenants,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := store.SaveFeatureFlagOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	updated := maps.Clone(overrides)
+	if updated == nil {
+		updated = map[string]config.FeatureFlag{}
+	}
+	updated[name] = flag
+	overrides = updated
+	mu.Unlock()
+	log.Printf("[FEATURES] Override of %s set", name)
+	return override, nil
+}
+
+// DeleteOverride removes a feature's admin override, so FEATURE_FLAGS_FILE,
+// FEATURE_FLAGS and the default apply again. Returns false if none existed.
+func DeleteOverride(ctx context.Context, name string) (bool, error) {
+	if _, ok := config.Features[name]; !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+	deleted, err := store.DeleteFeatureFlagOverride(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	mu.Lock()
+	updated := maps.Clone(overrides)
+	delete(updated, name)
+	overrides = updated
+	mu.Unlock()
+	if deleted {
+		log.Printf("[FEATURES] Override of %s removed", name)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,139 @@
+// Package forwarding republishes stored messages that match FORWARDING_RULES
+// to downstream Kafka topics, so teams like fraud and CX can subscribe to the
+// messages they care about without running their own consumer.
+package forwarding
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"smsstore/internal/config"
+	"smsstore/internal/features"
+	"smsstore/internal/kafkawriter"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var (
+	rules []config.ForwardingRule
+	// writer is nil when no rules are configured, turning Publish into a no-op.
+	writer *kafka.Writer
+)
+
+// Init configures the forwarding rules and their writer from app config.
+// Must be called before the consumer starts.
+func Init(cfg *config.Config) {
+	if len(cfg.ForwardingRules) == 0 {
+		log.Println("[FORWARDING] No forwarding rules configured")
+		return
+	}
+	rules = cfg.ForwardingRules
+	// No Topic on the writer: each record names the topic of its rule
+	// Keyed by user so per-user ordering is preserved; topics are set per message
+	writer = kafkawriter.New(cfg, "", 50*time.Millisecond)
+	for _, rule := range rules {
+		log.Printf("[FORWARDING] Forwarding messages matching %v to topic '%s'", rule.Match, rule.Topic)
+	}
+}
+
+// Publish forwards a stored message to the topic of every rule it matches,
+// once per topic, unless the forwarding feature is off for its tenant. Failures
+// are logged and never propagated, so the write path is unaffected.
+func Publish(ctx context.Context, userID string, message *models.MessageWithStatus) {
+	if writer == nil || message == nil || !features.EnabledFor(features.Forwarding, message.TenantID) {
+		return
+	}
+	topics := topicsFor(message)
+	if len(topics) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(models.ForwardedMessage{
+		UserID:      userID,
+		Region:      repository.RegionFrom(ctx),
+		Message:     message,
+		ForwardedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("[FORWARDING] Failed to encode message for %s: %v", userID, err)
+		return
+	}
+
+	records := make([]kafka.Message, 0, len(topics))
+	for _, topic := range topics {
+		records = append(records, kafka.Message{Topic: topic, Key: []byte(userID), Value: payload})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = writer.WriteMessages(ctx, records...)
+	writeErrors, partial := err.(kafka.WriteErrors)
+	for i, topic := range topics {
+		switch {
+		case err == nil, partial && writeErrors[i] == nil:
+			metrics.ForwardedMessages.WithLabelValues(topic, "published").Inc()
+		default:
+			metrics.ForwardedMessages.WithLabelValues(topic, "failed").Inc()
+			log.Printf("[FORWARDING] Failed to forward message for %s to %s: %v", userID, topic, err)
+		}
+	}
+}
+
+// topicsFor returns the topics of the rules message matches, without duplicates.
+func topicsFor(message *models.MessageWithStatus) []string {
+	var topics []string
+	for _, rule := range rules {
+		if matches(rule, message) && !slices.Contains(topics, rule.Topic) {
+			topics = append(topics, rule.Topic)
+		}
+	}
+	return topics
+}
+
+// Close flushes and closes the writer.
+func Close() error {
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
+
+// matches reports whether every condition of rule holds for message. Values
+// are compared case-insensitively, since producers disagree on status casing.
+func matches(rule config.ForwardingRule, message *models.MessageWithStatus) bool {
+	for field, want := range rule.Match {
+		if !strings.EqualFold(fieldValue(message, field), want) {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldValue(message *models.MessageWithStatus, field string) string {
+	switch field {
+	case "status":
+		return message.Status
+	case "category":
+		return message.Category
+	case "tenant_id":
+		return message.TenantID
+	case "provider":
+		return message.Provider
+	case "country_code":
+		return message.CountryCode
+	case "campaign_id":
+		return message.CampaignID
+	case "template_id":
+		return message.TemplateID
+	case "sender_id":
+		return message.SenderID
+	}
+	return ""
+}
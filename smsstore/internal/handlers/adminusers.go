@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultUserListLimit = 100
+	maxUserListLimit     = 1000
+)
+
+// ListUsers returns user IDs with message counts and last activity.
+// Query params: updated_after (RFC3339), limit, cursor (next_cursor from the previous page).
+func (api *API) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var updatedAfter time.Time
+	if raw := query.Get("updated_after"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "updated_after must be an RFC3339 timestamp")
+			return
+		}
+		updatedAfter = parsed
+	}
+
+	limit := defaultUserListLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxUserListLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+
+	users, err := api.repo.ListUsers(r.Context(), updatedAfter, query.Get("cursor"), limit)
+	if err != nil {
+		serverError(w, r, "Failed to list users", err)
+		return
+	}
+
+	response := models.UserListResponse{
+		Users: users,
+		Count: len(users),
+	}
+	if len(users) == limit {
+		response.NextCursor = users[len(users)-1].UserID
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import "smsstore/internal/repository"
+
+// API serves the public message routes from a MessageStore, so they can be
+// exercised against a fake store and run on another backend, and the admin
+// routes from the repository.
+type API struct {
+	repo     *repository.Repository
+	messages repository.MessageStore
+}
+
+// NewAPI returns the route handlers backed by repo, serving messages from
+// messages, which is usually repo itself or wraps it.
+func NewAPI(repo *repository.Repository, messages repository.MessageStore) *API {
+	return &API{repo: repo, messages: messages}
+}
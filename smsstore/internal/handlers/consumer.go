@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/breaker"
+	"smsstore/internal/middleware"
+)
+
+// GetConsumerStatus reports whether this replica's consumer is paused by its
+// error-rate breaker, and its recent error counts.
+func GetConsumerStatus(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, r, http.StatusOK, breaker.Status())
+}
+
+// ResumeConsumer lets this replica's paused consumer fetch again. The events
+// it had not committed are redelivered, so fix whatever made them fail first.
+func ResumeConsumer(w http.ResponseWriter, r *http.Request) {
+	if !breaker.Resume() {
+		writeError(w, r, http.StatusConflict, "Consumer is not paused")
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, breaker.Status())
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// GetUserConversations lists the senders a user has received messages from,
+// each with its latest message and unread count, most recently active first.
+func (api *API) GetUserConversations(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	conversations, err := api.messages.ListConversations(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve conversations", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, models.ConversationsResponse{
+		UserID:        userID,
+		Conversations: conversations,
+		Count:         len(conversations),
+	})
+}
+
+// GetConversationMessages lists a user's messages from one sender. It accepts
+// the same query params as GetUserMessages; "default" selects messages sent
+// without a sender ID.
+func (api *API) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
+	pathVars := mux.Vars(r)
+	userID := pathVars["user_id"]
+
+	query, err := parseMessageQuery(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.SenderID = pathVars["sender_id"]
+	api.writeUserMessages(w, r, userID, query)
+}
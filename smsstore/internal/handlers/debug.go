@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Goroutines writes a dump of every goroutine's stack as plain text. With
+// ?debug=1 identical stacks are grouped with a count, which is easier to scan
+// for a stall (e.g. hundreds of goroutines parked in the same Mongo call).
+func Goroutines(w http.ResponseWriter, r *http.Request) {
+	level := 2
+	if r.URL.Query().Get("debug") == "1" {
+		level = 1
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Goroutine-Count", strconv.Itoa(runtime.NumGoroutine()))
+	if err := pprof.Lookup("goroutine").WriteTo(w, level); err != nil {
+		log.Printf("[DIAGNOSTICS] Failed to write goroutine dump: %v", err)
+	}
+}
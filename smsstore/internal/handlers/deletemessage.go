@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/changeevents"
+	"smsstore/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// DeleteMessage soft-deletes a single message. It stays restorable until the
+// purge job removes it after the grace period.
+func (api *API) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	pathVars := mux.Vars(r)
+	userID, messageID := pathVars["user_id"], pathVars["message_id"]
+
+	message, err := api.messages.SoftDeleteMessage(r.Context(), userID, messageID)
+	if err != nil {
+		serverError(w, r, "Failed to delete message", err)
+		return
+	}
+	if message == nil {
+		writeError(w, r, http.StatusNotFound, "Message not found")
+		return
+	}
+
+	changeevents.PublishMessageChange(r.Context(), models.ChangeOpDelete, userID, message)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreMessage undoes a soft delete. Change-event sinks see the message
+// inserted again.
+func (api *API) RestoreMessage(w http.ResponseWriter, r *http.Request) {
+	pathVars := mux.Vars(r)
+	userID, messageID := pathVars["user_id"], pathVars["message_id"]
+
+	message, err := api.messages.RestoreMessage(r.Context(), userID, messageID)
+	if err != nil {
+		serverError(w, r, "Failed to restore message", err)
+		return
+	}
+	if message == nil {
+		writeError(w, r, http.StatusNotFound, "Message not found")
+		return
+	}
+
+	changeevents.PublishMessageChange(r.Context(), models.ChangeOpInsert, userID, message)
+	w.WriteHeader(http.StatusNoContent)
+}
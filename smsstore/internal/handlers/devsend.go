@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"smsstore/internal/devmode"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+)
+
+// phoneNumberPattern matches the sms-sender's request validation.
+var phoneNumberPattern = regexp.MustCompile(`^\+?[1-9]\d{9,14}$`)
+
+// The sending tenant is named by a header, as on the sms-sender API.
+const (
+	tenantHeader  = "X-Tenant-ID"
+	defaultTenant = "default"
+)
+
+// DevSendSms accepts the sms-sender send request and routes it through the
+// DEV_MODE simulated provider, applying the tenant's configuration. Only
+// registered when DEV_MODE is on.
+func DevSendSms(w http.ResponseWriter, r *http.Request) {
+	var req models.SmsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !phoneNumberPattern.MatchString(req.PhoneNumber) {
+		writeError(w, r, http.StatusBadRequest, "Invalid phone number format")
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, r, http.StatusBadRequest, "Message is mandatory")
+		return
+	}
+
+	if req.Category != "" && req.Category != "transactional" && req.Category != "promotional" {
+		writeError(w, r, http.StatusBadRequest, "Category must be transactional or promotional")
+		return
+	}
+	req.TenantID = strings.TrimSpace(r.Header.Get(tenantHeader))
+	if req.TenantID == "" {
+		req.TenantID = defaultTenant
+	}
+
+	result, err := devmode.Send(r.Context(), req)
+	var quotaErr *devmode.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(quotaErr.RetryAfter.Seconds())+1))
+		middleware.WriteJSON(w, r, http.StatusTooManyRequests, models.SmsResponse{Result: "Failed: " + err.Error()})
+		return
+	case errors.Is(err, devmode.ErrSenderNotAllowed):
+		writeError(w, r, http.StatusForbidden, "Sender ID is not allowed for tenant "+req.TenantID)
+		return
+	case err != nil:
+		serverError(w, r, "Server error kindly try again later", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, models.SmsResponse{Result: result})
+}
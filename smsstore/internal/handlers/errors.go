@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"smsstore/internal/middleware"
+)
+
+// writeError sends a JSON error carrying the request ID, in the request's
+// response format. Server-side failures are logged with the same ID so
+// responses can be matched to logs.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	requestID := middleware.RequestIDFromContext(r.Context())
+	if status >= http.StatusInternalServerError {
+		log.Printf("[HTTP] [%s] %s %s -> %d: %s", requestID, r.Method, r.URL.Path, status, message)
+	}
+
+	middleware.WriteError(w, r, status, message)
+}
+
+// serverError logs the underlying error with the request ID and sends a 500
+// without leaking internal details to the client.
+func serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	log.Printf("[HTTP] [%s] %s %s -> %d: %s: %v", middleware.RequestIDFromContext(r.Context()),
+		r.Method, r.URL.Path, http.StatusInternalServerError, message, err)
+	middleware.WriteError(w, r, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smsstore/internal/features"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ListFeatureFlags returns every feature's effective state on this replica,
+// with what its default, FEATURE_FLAGS, FEATURE_FLAGS_FILE and admin override
+// say about it.
+func ListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, r, http.StatusOK, features.List())
+}
+
+// SetFeatureFlagOverride turns a feature on or off over the configured flags,
+// for every tenant and/or particular tenants, replacing any previous override.
+func SetFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["feature"]
+
+	var req models.FeatureFlagSetting
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	override, err := features.SetOverride(r.Context(), name, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, features.ErrUnknownFeature):
+			writeError(w, r, http.StatusNotFound, "Unknown feature")
+		case errors.Is(err, features.ErrInvalidOverride):
+			writeError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			serverError(w, r, "Failed to save feature flag override", err)
+		}
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, override)
+}
+
+// DeleteFeatureFlagOverride removes a feature's admin override so the
+// configured flags apply again.
+func DeleteFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	deleted, err := features.DeleteOverride(r.Context(), mux.Vars(r)["feature"])
+	if err != nil {
+		if errors.Is(err, features.ErrUnknownFeature) {
+			writeError(w, r, http.StatusNotFound, "Unknown feature")
+			return
+		}
+		serverError(w, r, "Failed to delete feature flag override", err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, http.StatusNotFound, "No override for feature")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+)
+
+const (
+	defaultFirehoseLimit = 500
+	maxFirehoseLimit     = 5000
+)
+
+// messageIDPattern matches the hex ObjectIDs used as message IDs and cursors.
+var messageIDPattern = regexp.MustCompile(`^[0-9a-f]{24}$`)
+
+// ListAllMessages is the admin firehose: every user's messages in insertion
+// order. Query params: since (next_cursor from the previous page; omit to
+// start from the beginning) and limit. Soft-deleted messages are included with
+// deleted_at so syncs can mirror deletions. Keep calling with next_cursor until
+// it is absent, then poll again later from the last cursor.
+func (api *API) ListAllMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	since := query.Get("since")
+	if since != "" && !messageIDPattern.MatchString(since) {
+		writeError(w, r, http.StatusBadRequest, "since must be a cursor returned by a previous page")
+		return
+	}
+
+	limit := defaultFirehoseLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxFirehoseLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 5000")
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := api.repo.ListAllMessages(r.Context(), since, limit)
+	if err != nil {
+		serverError(w, r, "Failed to list messages", err)
+		return
+	}
+
+	response := models.FirehoseResponse{
+		Messages: messages,
+		Count:    len(messages),
+	}
+	if len(messages) == limit {
+		response.NextCursor = messages[len(messages)-1].Message.MessageID
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, response)
+}
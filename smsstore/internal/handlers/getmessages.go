@@ -1,20 +1,109 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"smsstore/internal/middleware"
 	"smsstore/internal/repository"
 	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func GetUserMessages(w http.ResponseWriter, r *http.Request) {
+// selectableFields are the message fields clients may request via ?fields=.
+var selectableFields = map[string]bool{
+	"message_id": true,
+	"message":    true,
+	"status":     true,
+	"created_at": true,
+	"deleted_at": true,
+	"read_at":    true,
+	"clicked_at": true,
+
+	"provider":            true,
+	"provider_message_id": true,
+	"campaign_id":         true,
+	"template_id":         true,
+	"variant":             true,
+	"country_code":        true,
+	"truncated":           true,
+	"language":            true,
+	"metadata":            true,
+	"idempotency_key":     true,
+	"tenant_id":           true,
+	"trace_id":            true,
+	"category":            true,
+	"sender_id":           true,
+	"out_of_order":        true,
+	"checksum":            true,
+	"source":              true,
+}
+
+// GetUserMessages lists a user's messages. Optional query params:
+// from/to (RFC3339) restrict the listing to [from, to), status, provider,
+// campaign_id, template_id, variant, country_code, language, sender_id and metadata.<key> match exactly, sort=asc|desc orders
+// by insertion, and fields=a,b,c limits which message fields are returned.
+func (api *API) GetUserMessages(w http.ResponseWriter, r *http.Request) {
 	pathVars := mux.Vars(r)
 	userID := pathVars["user_id"]
-	messages, err := repository.GetUserMessages(userID)
+
+	query, err := parseMessageQuery(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	api.writeUserMessages(w, r, userID, query)
+}
+
+// writeUserMessages runs a message listing and renders it, projected when the
+// query selects fields. HEAD requests and conditional GETs (If-None-Match,
+// If-Modified-Since) first summarize the listing: HEAD answers with just its
+// X-Total-Count, Last-Modified and ETag, and either answers 304 if the listing
+// hasn't changed.
+func (api *API) writeUserMessages(w http.ResponseWriter, r *http.Request, userID string, query repository.MessageQuery) {
+	if r.Method == http.MethodHead || r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		summary, err := api.messages.GetUserMessagesSummary(r.Context(), userID, query.MessageFilter)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				writeError(w, r, http.StatusGatewayTimeout, "Timed out summarizing messages")
+				return
+			}
+			serverError(w, r, "Failed to summarize messages", err)
+			return
+		}
+		setListingValidators(w, summary)
+		if notModified(r, summary) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	messages, err := api.messages.GetUserMessages(r.Context(), userID, query)
 	if err != nil {
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, r, http.StatusGatewayTimeout, "Timed out retrieving messages")
+			return
+		}
+		serverError(w, r, "Failed to retrieve messages", err)
+		return
+	}
+
+	if len(query.Fields) > 0 {
+		middleware.WriteJSON(w, r, http.StatusOK, models.ProjectedApiResponse{
+			UserID:   userID,
+			Messages: projectFields(messages, query.Fields),
+			Count:    len(messages),
+		})
 		return
 	}
 
@@ -23,6 +112,206 @@ func GetUserMessages(w http.ResponseWriter, r *http.Request) {
 		Messages: messages,
 		Count:    len(messages),
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(apiResponse)
+	middleware.WriteJSON(w, r, http.StatusOK, apiResponse)
+}
+
+// listingETag is a weak validator of a listing: it changes whenever the count
+// or the version of the user's messages does.
+func listingETag(summary *models.MessagesSummary) string {
+	return fmt.Sprintf(`W/"%d-%s"`, summary.Count, summary.Version)
+}
+
+func setListingValidators(w http.ResponseWriter, summary *models.MessagesSummary) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(summary.Count))
+	w.Header().Set("ETag", listingETag(summary))
+	if !summary.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", summary.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates the request's preconditions against the listing.
+// If-None-Match takes precedence over If-Modified-Since, whose one-second
+// resolution can miss a change made in the second the client last looked.
+func notModified(r *http.Request, summary *models.MessagesSummary) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := strings.TrimPrefix(listingETag(summary), "W/")
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !summary.LastModified.Truncate(time.Second).After(since)
+}
+
+func parseMessageQuery(r *http.Request) (repository.MessageQuery, error) {
+	var query repository.MessageQuery
+	params := r.URL.Query()
+
+	filter, err := parseMessageFilter(r)
+	if err != nil {
+		return query, err
+	}
+	query.MessageFilter = filter
+
+	switch params.Get("sort") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, errors.New("sort must be asc or desc")
+	}
+
+	if raw := params.Get("fields"); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			if !selectableFields[field] {
+				return query, fmt.Errorf("unknown field %q", field)
+			}
+			query.Fields = append(query.Fields, field)
+		}
+	}
+	return query, nil
+}
+
+// parseMessageFilter reads the time-range and attribute filters shared by the
+// list, search and analytics endpoints.
+func parseMessageFilter(r *http.Request) (repository.MessageFilter, error) {
+	params := r.URL.Query()
+	filter := repository.MessageFilter{
+		Status:            params.Get("status"),
+		Provider:          params.Get("provider"),
+		ProviderMessageID: params.Get("provider_message_id"),
+		CampaignID:        params.Get("campaign_id"),
+		TemplateID:        params.Get("template_id"),
+		Variant:           params.Get("variant"),
+		CountryCode:       strings.ToUpper(params.Get("country_code")),
+		Language:          strings.ToLower(params.Get("language")),
+		SenderID:          params.Get("sender_id"),
+	}
+
+	for param, values := range params {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if !ok {
+			continue
+		}
+		if !models.ValidMetadataKey(key) {
+			return filter, fmt.Errorf("invalid metadata key %q", key)
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = map[string]string{}
+		}
+		filter.Metadata[key] = values[0]
+	}
+
+	if raw := params.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.New("from must be an RFC3339 timestamp")
+		}
+		filter.From = from
+	}
+	if raw := params.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.New("to must be an RFC3339 timestamp")
+		}
+		filter.To = to
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
+// projectFields renders only the requested fields of each message, leaving out
+// fields that are unset rather than emitting zero values.
+func projectFields(messages []models.MessageWithStatus, fields []string) []map[string]interface{} {
+	projected := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		item := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			switch field {
+			case "message_id":
+				if message.MessageID != "" {
+					item[field] = message.MessageID
+				}
+			case "message":
+				item[field] = message.Message
+			case "status":
+				item[field] = message.Status
+			case "created_at":
+				if !message.CreatedAt.IsZero() {
+					item[field] = message.CreatedAt
+				}
+			case "deleted_at":
+				if message.DeletedAt != nil {
+					item[field] = message.DeletedAt
+				}
+			case "read_at":
+				if message.ReadAt != nil {
+					item[field] = message.ReadAt
+				}
+			case "clicked_at":
+				if message.ClickedAt != nil {
+					item[field] = message.ClickedAt
+				}
+			case "provider":
+				setIfPresent(item, field, message.Provider)
+			case "provider_message_id":
+				setIfPresent(item, field, message.ProviderMessageID)
+			case "campaign_id":
+				setIfPresent(item, field, message.CampaignID)
+			case "template_id":
+				setIfPresent(item, field, message.TemplateID)
+			case "variant":
+				setIfPresent(item, field, message.Variant)
+			case "country_code":
+				setIfPresent(item, field, message.CountryCode)
+			case "language":
+				setIfPresent(item, field, message.Language)
+			case "idempotency_key":
+				setIfPresent(item, field, message.IdempotencyKey)
+			case "tenant_id":
+				setIfPresent(item, field, message.TenantID)
+			case "trace_id":
+				setIfPresent(item, field, message.TraceID)
+			case "category":
+				setIfPresent(item, field, message.Category)
+			case "sender_id":
+				setIfPresent(item, field, message.SenderID)
+			case "metadata":
+				if len(message.Metadata) > 0 {
+					item[field] = message.Metadata
+				}
+			case "out_of_order":
+				if message.OutOfOrder {
+					item[field] = true
+				}
+			case "source":
+				if message.Source != nil {
+					item[field] = message.Source
+				}
+			case "truncated":
+				if message.Truncated {
+					item[field] = true
+					item["original_bytes"] = message.OriginalBytes
+				}
+			}
+		}
+		projected = append(projected, item)
+	}
+	return projected
+}
+
+func setIfPresent(item map[string]interface{}, field string, value string) {
+	if value != "" {
+		item[field] = value
+	}
 }
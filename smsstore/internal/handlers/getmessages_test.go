@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// fakeStore serves one user's messages from memory. Methods the listing
+// doesn't use fall through to the nil MessageStore and panic.
+type fakeStore struct {
+	repository.MessageStore
+	userID   string
+	messages []models.MessageWithStatus
+	version  string
+
+	// queries records the queries GetUserMessages was called with
+	queries []repository.MessageQuery
+}
+
+func (s *fakeStore) GetUserMessages(ctx context.Context, userID string, query repository.MessageQuery) ([]models.MessageWithStatus, error) {
+	s.queries = append(s.queries, query)
+	if userID != s.userID {
+		return []models.MessageWithStatus{}, nil
+	}
+	var messages []models.MessageWithStatus
+	for _, message := range s.messages {
+		if query.Status == "" || message.Status == query.Status {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (s *fakeStore) GetUserMessagesSummary(ctx context.Context, userID string, filter repository.MessageFilter) (*models.MessagesSummary, error) {
+	summary := &models.MessagesSummary{Version: s.version}
+	if userID == s.userID {
+		summary.Count = len(s.messages)
+		summary.LastModified = s.messages[len(s.messages)-1].CreatedAt
+	}
+	return summary, nil
+}
+
+func newTestRouter(store repository.MessageStore) *mux.Router {
+	api := NewAPI(nil, store)
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/user/{user_id}/messages", api.GetUserMessages).Methods("GET", "HEAD")
+	return router
+}
+
+func TestGetUserMessages(t *testing.T) {
+	created := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		userID: "u1",
+		messages: []models.MessageWithStatus{
+			{MessageID: "m1", Message: "hello", Status: "successful", CreatedAt: created},
+			{MessageID: "m2", Message: "again", Status: "failed", CreatedAt: created.Add(time.Minute)},
+		},
+		version: "3",
+	}
+	router := newTestRouter(store)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/user/u1/messages?status=failed&sort=desc", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", recorder.Code, recorder.Body)
+	}
+	var response models.ApiResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.UserID != "u1" || response.Count != 1 || len(response.Messages) != 1 || response.Messages[0].MessageID != "m2" {
+		t.Errorf("response = %+v, want just m2", response)
+	}
+	if len(store.queries) != 1 || store.queries[0].Status != "failed" || !store.queries[0].Descending {
+		t.Errorf("queries = %+v, want one descending query for failed messages", store.queries)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/user/u1/messages?from=yesterday", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid from: status = %d, want 400", recorder.Code)
+	}
+}
+
+// HEAD and conditional GETs are answered from the summary; only a changed
+// listing is read in full.
+func TestGetUserMessagesConditional(t *testing.T) {
+	store := &fakeStore{
+		userID:   "u1",
+		messages: []models.MessageWithStatus{{MessageID: "m1", CreatedAt: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)}},
+		version:  "1",
+	}
+	router := newTestRouter(store)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/v1/user/u1/messages", nil))
+	etag := recorder.Header().Get("ETag")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("X-Total-Count") != "1" || etag != `W/"1-1"` {
+		t.Fatalf("HEAD = %d count=%q etag=%q, want 200 with count 1 and etag W/\"1-1\"",
+			recorder.Code, recorder.Header().Get("X-Total-Count"), etag)
+	}
+	if recorder.Body.Len() != 0 || len(store.queries) != 0 {
+		t.Errorf("HEAD read the listing: body %q after %d queries", recorder.Body, len(store.queries))
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/user/u1/messages", nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified || len(store.queries) != 0 {
+		t.Fatalf("unchanged listing: status = %d after %d queries, want 304 without reading it", recorder.Code, len(store.queries))
+	}
+
+	// An edit bumps the version, so the same count no longer matches
+	store.version = "2"
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || len(store.queries) != 1 {
+		t.Errorf("changed listing: status = %d after %d queries, want 200 after one", recorder.Code, len(store.queries))
+	}
+	if got := recorder.Header().Get("ETag"); got != `W/"1-2"` {
+		t.Errorf("ETag = %q, want W/\"1-2\"", got)
+	}
+}
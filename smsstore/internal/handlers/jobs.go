@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/jobs"
+	"smsstore/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// GetJob returns a background job's status, progress and result.
+func (api *API) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := jobs.Get(r.Context(), api.repo, mux.Vars(r)["job_id"])
+	if err != nil {
+		serverError(w, r, "Failed to retrieve job", err)
+		return
+	}
+	if job == nil {
+		writeError(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, job)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"smsstore/internal/logsample"
+	"smsstore/internal/middleware"
+)
+
+type loggingSettingsRequest struct {
+	Debug      *bool    `json:"debug"`
+	SampleRate *int     `json:"sample_rate"`
+	RateLimit  *float64 `json:"rate_limit"`
+}
+
+// GetLoggingSettings returns the consumer log sampling settings in effect.
+func GetLoggingSettings(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, r, http.StatusOK, logsample.Settings())
+}
+
+// UpdateLoggingSettings changes the log sampling settings on this replica
+// until restart. Omitted fields keep their current value.
+func UpdateLoggingSettings(w http.ResponseWriter, r *http.Request) {
+	var req loggingSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	settings := logsample.Settings()
+	if req.Debug != nil {
+		settings.Debug = *req.Debug
+	}
+	if req.SampleRate != nil {
+		settings.SampleRate = *req.SampleRate
+	}
+	if req.RateLimit != nil {
+		settings.RateLimit = *req.RateLimit
+	}
+	if settings.SampleRate < 1 || settings.RateLimit < 0 {
+		writeError(w, r, http.StatusBadRequest, "sample_rate must be at least 1 and rate_limit cannot be negative")
+		return
+	}
+
+	logsample.Apply(settings)
+	middleware.WriteJSON(w, r, http.StatusOK, settings)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+)
+
+// LookupMessages finds the messages, across all users, that carry the given
+// metadata, e.g. ?metadata.order_id=OD-1001 answers "what did we send for this
+// order?". At least one metadata.<key> param is required; the other message
+// filters and limit (1-1000, default 100) also apply. Newest first.
+func (api *API) LookupMessages(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseMessageFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(filter.Metadata) == 0 {
+		writeError(w, r, http.StatusBadRequest, "at least one metadata.<key> parameter is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	results, err := api.repo.SearchMessages(r.Context(), "", filter, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, r, http.StatusGatewayTimeout, "Timed out looking up messages")
+			return
+		}
+		serverError(w, r, "Failed to look up messages", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, models.SearchResponse{Results: results, Count: len(results)})
+}
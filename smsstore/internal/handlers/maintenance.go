@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smsstore/internal/maintenance"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// StartMaintenance enqueues an asynchronous maintenance operation:
+// rebuild_indexes, compact_user (requires user_id), recompute_stats or
+// verify_integrity (both for user_id if given, otherwise every user). It
+// responds 202 with the job; poll /v1/admin/jobs/{job_id} for progress.
+func (api *API) StartMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req models.MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	job, err := maintenance.Start(r.Context(), api.repo, req)
+	if err != nil {
+		if errors.Is(err, maintenance.ErrUnknownOperation) {
+			writeError(w, r, http.StatusBadRequest, "operation must be one of rebuild_indexes, compact_user, recompute_stats, verify_integrity")
+			return
+		}
+		if errors.Is(err, maintenance.ErrMissingUserID) || errors.Is(err, maintenance.ErrUnknownRegion) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		serverError(w, r, "Failed to enqueue maintenance job", err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/admin/jobs/"+job.ID)
+	middleware.WriteJSON(w, r, http.StatusAccepted, job)
+}
+
+// VerifyUserIntegrity checks a user's stored messages against the checksums
+// recorded at ingest and responds with the report. Mismatched messages were
+// changed or corrupted after they were stored.
+func (api *API) VerifyUserIntegrity(w http.ResponseWriter, r *http.Request) {
+	report, err := maintenance.VerifyUser(r.Context(), api.repo, mux.Vars(r)["user_id"])
+	if err != nil {
+		serverError(w, r, "Failed to verify messages", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, report)
+}
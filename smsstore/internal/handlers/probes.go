@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/canary"
+	"smsstore/internal/middleware"
+	"smsstore/internal/readiness"
+)
+
+// Liveness answers 200 whenever the process is serving HTTP, including while
+// it is still waiting for its dependencies. The canary's outcome is included
+// for detail; a failing canary doesn't fail the probe, as restarting the
+// process rarely fixes the pipeline.
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, r, http.StatusOK, map[string]interface{}{"status": "alive", "canary": canary.Status()})
+}
+
+// Readiness answers 200 once startup has finished and 503 before, with the
+// progress of each dependency the service is waiting for.
+func Readiness(w http.ResponseWriter, r *http.Request) {
+	status := readiness.Status()
+	code := http.StatusOK
+	if status.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	middleware.WriteJSON(w, r, code, status)
+}
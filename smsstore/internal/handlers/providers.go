@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/internal/providerhealth"
+)
+
+// GetProviderHealth returns each provider's rolling health score, computed from
+// the delivery outcomes and latencies on ingested status events, healthiest
+// first. Routing reads this ordering to shift traffic away from degraded providers.
+func GetProviderHealth(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, r, http.StatusOK, providerhealth.Snapshot())
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+)
+
+const (
+	defaultQuarantineLimit = 50
+	maxQuarantineLimit     = 1000
+)
+
+// ListQuarantinedEvents returns recently consumed records whose payload could
+// not be parsed, with their Kafka coordinates and parse error, newest first.
+// Query params: topic, limit (1-1000, default 50), and sample (1-1000) to
+// return that many records picked at random instead.
+func (api *API) ListQuarantinedEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	topic := query.Get("topic")
+
+	var (
+		events []models.QuarantinedEvent
+		err    error
+	)
+	if raw := query.Get("sample"); raw != "" {
+		size, parseErr := strconv.Atoi(raw)
+		if parseErr != nil || size < 1 || size > maxQuarantineLimit {
+			writeError(w, r, http.StatusBadRequest, "sample must be between 1 and 1000")
+			return
+		}
+		events, err = api.repo.SampleQuarantinedEvents(r.Context(), topic, size)
+	} else {
+		limit := defaultQuarantineLimit
+		if raw := query.Get("limit"); raw != "" {
+			parsed, parseErr := strconv.Atoi(raw)
+			if parseErr != nil || parsed < 1 || parsed > maxQuarantineLimit {
+				writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+				return
+			}
+			limit = parsed
+		}
+		events, err = api.repo.ListQuarantinedEvents(r.Context(), topic, int64(limit))
+	}
+	if err != nil {
+		serverError(w, r, "Failed to list quarantined events", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, map[string]interface{}{"events": events, "count": len(events)})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/internal/repository"
+	"smsstore/internal/storagereport"
+	"smsstore/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ListPurgeReports lists the stored monthly purge reports with their totals, newest first.
+func (api *API) ListPurgeReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := api.repo.ListPurgeReports(r.Context())
+	if err != nil {
+		serverError(w, r, "Failed to list purge reports", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, map[string]interface{}{"reports": reports, "count": len(reports)})
+}
+
+// GetPurgeReport returns the purge report for a month (YYYY-MM): the stored
+// report for an ended month, or a partial one built from the ledger so far for
+// the current month. With format=csv it is downloaded as one row per tenant,
+// source and category.
+func (api *API) GetPurgeReport(w http.ResponseWriter, r *http.Request) {
+	month := mux.Vars(r)["month"]
+	start, err := time.Parse(repository.ReportMonthFormat, month)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "month must be formatted YYYY-MM")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, r, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	now := time.Now().UTC()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if start.After(currentMonth) {
+		writeError(w, r, http.StatusNotFound, "No purge report for a future month")
+		return
+	}
+
+	report, err := api.repo.GetPurgeReport(r.Context(), month)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve purge report", err)
+		return
+	}
+	if report == nil {
+		// The reporter stores ended months shortly after they end; build in the meantime
+		report, err = api.repo.BuildPurgeReport(r.Context(), month)
+		if err != nil {
+			serverError(w, r, "Failed to build purge report", err)
+			return
+		}
+		if start.Equal(currentMonth) {
+			report.Partial = true
+		} else if err := api.repo.SavePurgeReport(r.Context(), report); err != nil {
+			serverError(w, r, "Failed to store purge report", err)
+			return
+		}
+	}
+
+	if format == "csv" {
+		writePurgeReportCSV(w, report)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, report)
+}
+
+func writePurgeReportCSV(w http.ResponseWriter, report *models.PurgeReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="purge-report-`+report.Month+`.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"month", "tenant_id", "source", "category", "count"})
+	for _, count := range report.Counts {
+		out.Write([]string{report.Month, count.TenantID, count.Source, count.Category, strconv.FormatInt(count.Count, 10)})
+	}
+	out.Flush()
+}
+
+// defaultStorageReportDays is how many days of storage reports are listed by default.
+const defaultStorageReportDays = 30
+
+// ListStorageReports lists the stored daily storage reports of the last days
+// (default 30, at most 366) with their totals, newest first.
+func (api *API) ListStorageReports(w http.ResponseWriter, r *http.Request) {
+	days := defaultStorageReportDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 366 {
+			writeError(w, r, http.StatusBadRequest, "days must be between 1 and 366")
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, 1-days).Format(repository.ReportDateFormat)
+	reports, err := api.repo.ListStorageReports(r.Context(), since)
+	if err != nil {
+		serverError(w, r, "Failed to list storage reports", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, map[string]interface{}{"reports": reports, "count": len(reports)})
+}
+
+// GetStorageReport returns the storage report stored for a day (YYYY-MM-DD).
+// With format=csv it is downloaded as one row per tenant.
+func (api *API) GetStorageReport(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if _, err := time.Parse(repository.ReportDateFormat, date); err != nil {
+		writeError(w, r, http.StatusBadRequest, "date must be formatted YYYY-MM-DD")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, r, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	report, err := api.repo.GetStorageReport(r.Context(), date)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve storage report", err)
+		return
+	}
+	if report == nil {
+		writeError(w, r, http.StatusNotFound, "No storage report for this date")
+		return
+	}
+
+	if format == "csv" {
+		writeStorageReportCSV(w, report)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, report)
+}
+
+// RebuildStorageReport enqueues a job rebuilding today's storage report and
+// responds 202 with it; poll /v1/admin/jobs/{job_id} for its outcome.
+func (api *API) RebuildStorageReport(w http.ResponseWriter, r *http.Request) {
+	job, err := storagereport.Rebuild(r.Context(), api.repo)
+	if err != nil {
+		serverError(w, r, "Failed to enqueue storage report job", err)
+		return
+	}
+	w.Header().Set("Location", "/v1/admin/jobs/"+job.ID)
+	middleware.WriteJSON(w, r, http.StatusAccepted, job)
+}
+
+func writeStorageReportCSV(w http.ResponseWriter, report *models.StorageReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="storage-report-`+report.Date+`.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "tenant_id", "messages", "message_bytes", "user_documents", "largest_user_id", "largest_document_bytes"})
+	for _, tenant := range report.Tenants {
+		largestUser, largestBytes := "", int64(0)
+		if len(tenant.LargestUsers) > 0 {
+			largestUser, largestBytes = tenant.LargestUsers[0].UserID, tenant.LargestUsers[0].DocumentBytes
+		}
+		out.Write([]string{report.Date, tenant.TenantID, strconv.FormatInt(tenant.Messages, 10), strconv.FormatInt(tenant.MessageBytes, 10),
+			strconv.FormatInt(tenant.UserDocuments, 10), largestUser, strconv.FormatInt(largestBytes, 10)})
+	}
+	out.Flush()
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"smsstore/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+type retentionOverrideRequest struct {
+	RetentionDays *int `json:"retention_days"`
+}
+
+// GetRetentionOverride returns the retention override configured for a user.
+func (api *API) GetRetentionOverride(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	override, err := api.repo.GetRetentionOverride(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve retention override", err)
+		return
+	}
+	if override == nil {
+		writeError(w, r, http.StatusNotFound, "No retention override for user")
+		return
+	}
+
+	middleware.WriteJSON(w, r, http.StatusOK, override)
+}
+
+// SetRetentionOverride creates or replaces a user's retention override.
+// A retention_days of 0 keeps the user's messages forever.
+func (api *API) SetRetentionOverride(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	var req retentionOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RetentionDays == nil || *req.RetentionDays < 0 {
+		writeError(w, r, http.StatusBadRequest, "retention_days is required and cannot be negative")
+		return
+	}
+
+	override, err := api.repo.SetRetentionOverride(r.Context(), userID, *req.RetentionDays)
+	if err != nil {
+		serverError(w, r, "Failed to save retention override", err)
+		return
+	}
+
+	middleware.WriteJSON(w, r, http.StatusOK, override)
+}
+
+// DeleteRetentionOverride removes a user's override so the global retention applies again.
+func (api *API) DeleteRetentionOverride(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	deleted, err := api.repo.DeleteRetentionOverride(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Failed to delete retention override", err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, http.StatusNotFound, "No retention override for user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
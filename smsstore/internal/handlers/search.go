@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// analyticsDimensions are the message fields analytics can group by.
+var analyticsDimensions = map[string]bool{
+	"status":       true,
+	"provider":     true,
+	"campaign_id":  true,
+	"template_id":  true,
+	"variant":      true,
+	"country_code": true,
+	"language":     true,
+}
+
+// SearchMessages finds messages across users, so it is only served with admin
+// credentials. Accepts the same filters as the message listing plus user_id
+// and limit (1-1000, default 100). At least one filter is required so a bare
+// request cannot scan every user.
+func (api *API) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseMessageFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" && filter.IsZero() {
+		writeError(w, r, http.StatusBadRequest, "at least one filter is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	results, err := api.messages.SearchMessages(r.Context(), userID, filter, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, r, http.StatusGatewayTimeout, "Timed out searching messages")
+			return
+		}
+		serverError(w, r, "Failed to search messages", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, models.SearchResponse{Results: results, Count: len(results)})
+}
+
+// Analytics metrics: message counts, delivery latency percentiles, or clicked sends.
+const (
+	metricCount           = "count"
+	metricDeliveryLatency = "delivery_latency"
+	metricClicks          = "clicks"
+)
+
+// GetMessageAnalytics counts messages grouped by group_by (status, provider,
+// campaign_id, template_id, variant, country_code or language; default status), accepting the
+// same filters as the message listing. With metric=delivery_latency each group
+// instead reports P50/P95/P99 delivery latency of its delivered messages
+// (default group_by provider); with metric=clicks it counts the sends that
+// were clicked (default group_by variant), e.g. for a campaign's A/B test.
+// Metadata filters are refused: counting other users' messages by a metadata
+// value would reveal what the admin-only lookup protects.
+func (api *API) GetMessageAnalytics(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseMessageFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(filter.Metadata) > 0 {
+		writeError(w, r, http.StatusBadRequest, "metadata filters are only accepted by the admin message lookup and search")
+		return
+	}
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = metricCount
+	}
+	if metric != metricCount && metric != metricDeliveryLatency && metric != metricClicks {
+		writeError(w, r, http.StatusBadRequest, "metric must be count, delivery_latency or clicks")
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "status"
+		switch metric {
+		case metricDeliveryLatency:
+			groupBy = "provider"
+		case metricClicks:
+			groupBy = "variant"
+		}
+	}
+	if !analyticsDimensions[groupBy] {
+		writeError(w, r, http.StatusBadRequest, "group_by must be one of status, provider, campaign_id, template_id, variant, country_code, language")
+		return
+	}
+
+	var buckets []models.AnalyticsBucket
+	switch metric {
+	case metricDeliveryLatency:
+		buckets, err = api.messages.DeliveryLatencyBy(r.Context(), groupBy, filter)
+	case metricClicks:
+		buckets, err = api.messages.CountClickedBy(r.Context(), groupBy, filter)
+	default:
+		buckets, err = api.messages.CountMessagesBy(r.Context(), groupBy, filter)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, r, http.StatusGatewayTimeout, "Timed out computing analytics")
+			return
+		}
+		serverError(w, r, "Failed to compute analytics", err)
+		return
+	}
+	total := 0
+	for _, bucket := range buckets {
+		total += bucket.Count
+	}
+	response := models.AnalyticsResponse{GroupBy: groupBy, Buckets: buckets, Total: total}
+	if metric != metricCount {
+		response.Metric = metric
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"smsstore/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// GetUserStats returns message counts by status for a user. With
+// precomputed=true it returns the last snapshot written by the recompute_stats
+// maintenance job instead of aggregating live.
+func (api *API) GetUserStats(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	if r.URL.Query().Get("precomputed") == "true" {
+		stats, err := api.messages.GetUserStatsSnapshot(r.Context(), userID)
+		if err != nil {
+			serverError(w, r, "Failed to retrieve stats snapshot", err)
+			return
+		}
+		if stats == nil {
+			writeError(w, r, http.StatusNotFound, "No precomputed stats for user")
+			return
+		}
+		middleware.WriteJSON(w, r, http.StatusOK, stats)
+		return
+	}
+
+	stats, err := api.messages.GetUserStats(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, "Failed to compute stats", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"smsstore/internal/notify"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sseKeepAlive keeps idle connections from being closed by proxies.
+const sseKeepAlive = 25 * time.Second
+
+// StreamUserMessages pushes a Server-Sent Event each time the user's messages change.
+// Clients re-fetch the messages endpoint on each event. Only mounted behind
+// RBAC or the admin token (see routes.SetupRoutes).
+func StreamUserMessages(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+	// The server's WriteTimeout would otherwise cut long-lived streams
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("[STREAM] Could not clear write deadline: %v", err)
+	}
+
+	events, unsubscribe := notify.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: messages-changed\ndata: %s\n\n", payload)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"smsstore/internal/masking"
+	"smsstore/internal/middleware"
+	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAuditReasonLength = 500
+	defaultAuditLimit    = 100
+	maxAuditLimit        = 1000
+)
+
+// GetSupportMessages lists a user's messages for support with every field
+// but the body, whose codes, account numbers and links are masked (see
+// masking.Body). It takes the filters and sort of GetUserMessages; fields=
+// is not supported.
+func (api *API) GetSupportMessages(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	messages, ok := api.supportMessages(w, r, userID)
+	if !ok {
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, models.SupportMessagesResponse{
+		UserID:   userID,
+		Messages: masking.Messages(messages),
+		Count:    len(messages),
+		Masked:   true,
+	})
+}
+
+// GetUnmaskedSupportMessages is GetSupportMessages without masking. The
+// caller must give a reason, and the access is written to the audit trail
+// before any message is returned; if it can't be recorded nothing is.
+func (api *API) GetUnmaskedSupportMessages(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+	if reason == "" || len(reason) > maxAuditReasonLength {
+		writeError(w, r, http.StatusBadRequest, "reason is required (at most 500 characters), e.g. the support ticket")
+		return
+	}
+	messages, ok := api.supportMessages(w, r, userID)
+	if !ok {
+		return
+	}
+
+	entry := &models.AuditEntry{
+		Actor:     middleware.CallerFromContext(r.Context()),
+		Action:    models.AuditActionUnmaskedMessages,
+		UserID:    userID,
+		Reason:    reason,
+		RequestID: middleware.RequestIDFromContext(r.Context()),
+		At:        time.Now().UTC(),
+	}
+	if entry.Actor == "" {
+		entry.Actor = "anonymous"
+	}
+	if err := api.repo.InsertAuditEntry(r.Context(), entry); err != nil {
+		serverError(w, r, "Failed to record audit entry", err)
+		return
+	}
+	log.Printf("[AUDIT] [%s] %s viewed unmasked messages of %s: %q", entry.RequestID, entry.Actor, userID, reason)
+
+	middleware.WriteJSON(w, r, http.StatusOK, models.SupportMessagesResponse{
+		UserID:   userID,
+		Messages: messages,
+		Count:    len(messages),
+	})
+}
+
+// supportMessages runs the listing for a support view, writing the error
+// response when it fails.
+func (api *API) supportMessages(w http.ResponseWriter, r *http.Request, userID string) ([]models.MessageWithStatus, bool) {
+	query, err := parseMessageQuery(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if len(query.Fields) > 0 {
+		writeError(w, r, http.StatusBadRequest, "fields is not supported by the support view")
+		return nil, false
+	}
+	messages, err := api.repo.GetUserMessages(r.Context(), userID, query)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve messages", err)
+		return nil, false
+	}
+	return messages, true
+}
+
+// ListAuditEntries returns the audit trail of unmasked data access, newest
+// first. Query params: user_id, limit (1-1000, default 100).
+func (api *API) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultAuditLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+	entries, err := api.repo.ListAuditEntries(r.Context(), query.Get("user_id"), int64(limit))
+	if err != nil {
+		serverError(w, r, "Failed to list audit entries", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, map[string]interface{}{"entries": entries, "count": len(entries)})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/internal/tenants"
+	"smsstore/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ListTenantConfigs returns every tenant's configuration.
+func (api *API) ListTenantConfigs(w http.ResponseWriter, r *http.Request) {
+	configs, err := api.repo.ListTenantConfigs(r.Context())
+	if err != nil {
+		serverError(w, r, "Failed to list tenant configurations", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, configs)
+}
+
+// GetTenantConfig returns a tenant's configuration.
+func (api *API) GetTenantConfig(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+	config, err := api.repo.GetTenantConfig(r.Context(), tenantID)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve tenant configuration", err)
+		return
+	}
+	if config == nil {
+		writeError(w, r, http.StatusNotFound, "No configuration for tenant")
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, config)
+}
+
+// SetTenantConfig creates or replaces a tenant's configuration. Settings left
+// out of the body fall back to the defaults.
+func SetTenantConfig(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+
+	var req models.TenantConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	config, err := tenants.Save(r.Context(), tenantID, req)
+	if err != nil {
+		if errors.Is(err, tenants.ErrInvalidConfig) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		serverError(w, r, "Failed to save tenant configuration", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, config)
+}
+
+// DeleteTenantConfig removes a tenant's configuration so the defaults apply again.
+func DeleteTenantConfig(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+	deleted, err := tenants.Delete(r.Context(), tenantID)
+	if err != nil {
+		serverError(w, r, "Failed to delete tenant configuration", err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, http.StatusNotFound, "No configuration for tenant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRetryPolicy returns a tenant's retry policy.
+func (api *API) GetRetryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+	config, err := api.repo.GetTenantConfig(r.Context(), tenantID)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve retry policy", err)
+		return
+	}
+	if config == nil || config.RetryPolicy == nil {
+		writeError(w, r, http.StatusNotFound, "No retry policy for tenant")
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, config.RetryPolicy)
+}
+
+// SetRetryPolicy creates or replaces a tenant's retry policy, leaving the rest
+// of its configuration unchanged.
+func SetRetryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+
+	var req models.RetryPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	policy, err := tenants.SaveRetryPolicy(r.Context(), tenantID, req)
+	if err != nil {
+		if errors.Is(err, tenants.ErrInvalidConfig) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		serverError(w, r, "Failed to save retry policy", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, policy)
+}
+
+// DeleteRetryPolicy stops resending a tenant's failed messages.
+func DeleteRetryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenant_id"]
+	deleted, err := tenants.DeleteRetryPolicy(r.Context(), tenantID)
+	if err != nil {
+		serverError(w, r, "Failed to delete retry policy", err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, http.StatusNotFound, "No retry policy for tenant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
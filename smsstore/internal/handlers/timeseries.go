@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"smsstore/internal/features"
+	"smsstore/internal/middleware"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeseriesRange = time.Hour
+	maxTimeseriesPoints    = 1000
+)
+
+// timeseriesIntervals are the intervals picked when none is given, the
+// smallest one that fits the range in maxTimeseriesPoints.
+var timeseriesIntervals = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// GetMessageTimeseries returns message counts by status, category, provider
+// and country per interval from the consumer's rollups, for tenant_id (else
+// the X-Tenant-ID tenant, else every tenant together). from and to default to the
+// last hour and are widened to whole intervals. interval (e.g. 5m, 1h, 24h)
+// defaults to the smallest that gives at most 1000 points; each point is summed
+// from the coarsest rollup granularity dividing it. 404s while the timeseries
+// feature is off for the tenant. Callers bound to a tenant only see their own.
+func (api *API) GetMessageTimeseries(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	tenantID := params.Get("tenant_id")
+	if bound := middleware.BoundTenant(r.Context()); bound != "" && tenantID != "" && tenantID != bound {
+		writeError(w, r, http.StatusForbidden, "These credentials are not valid for the requested tenant")
+		return
+	}
+	if tenantID == "" {
+		tenantID = strings.TrimSpace(r.Header.Get(middleware.TenantHeader))
+	}
+	if !features.EnabledFor(features.Timeseries, tenantID) {
+		writeError(w, r, http.StatusNotFound, "Not found")
+		return
+	}
+
+	to := time.Now().UTC()
+	if raw := params.Get("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "to must be an RFC3339 timestamp")
+			return
+		}
+		to = parsed.UTC()
+	}
+	from := to.Add(-defaultTimeseriesRange)
+	if raw := params.Get("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "from must be an RFC3339 timestamp")
+			return
+		}
+		from = parsed.UTC()
+	}
+	if !from.Before(to) {
+		writeError(w, r, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	var interval time.Duration
+	if raw := params.Get("interval"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < time.Minute || parsed%time.Minute != 0 {
+			writeError(w, r, http.StatusBadRequest, "interval must be a whole number of minutes, e.g. 5m or 1h")
+			return
+		}
+		interval = parsed
+	} else {
+		interval = timeseriesIntervals[len(timeseriesIntervals)-1]
+		for _, candidate := range timeseriesIntervals {
+			if to.Sub(from)/candidate <= maxTimeseriesPoints {
+				interval = candidate
+				break
+			}
+		}
+	}
+	from = from.Truncate(interval)
+	if aligned := to.Truncate(interval); aligned.Before(to) {
+		to = aligned.Add(interval)
+	}
+	if to.Sub(from)/interval > maxTimeseriesPoints {
+		writeError(w, r, http.StatusBadRequest, "too many points: use a larger interval or a shorter range")
+		return
+	}
+
+	granularity := models.GranularityMinute
+	for _, candidate := range []string{models.GranularityDay, models.GranularityHour} {
+		if period, _ := repository.RollupPeriod(candidate); interval%period == 0 {
+			granularity = candidate
+			break
+		}
+	}
+	rollups, err := api.messages.GetRollups(r.Context(), granularity, tenantID, from, to)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, r, http.StatusGatewayTimeout, "Timed out reading rollups")
+			return
+		}
+		serverError(w, r, "Failed to read rollups", err)
+		return
+	}
+
+	points := make([]models.TimeseriesPoint, 0, to.Sub(from)/interval)
+	for start := from; start.Before(to); start = start.Add(interval) {
+		points = append(points, models.TimeseriesPoint{Start: start})
+	}
+	for _, rollup := range rollups {
+		index := int(rollup.Start.Sub(from) / interval)
+		if index >= 0 && index < len(points) {
+			points[index].Add(rollup.RollupCounts)
+		}
+	}
+
+	middleware.WriteJSON(w, r, http.StatusOK, models.TimeseriesResponse{
+		TenantID: tenantID,
+		Interval: formatInterval(interval),
+		From:     from,
+		To:       to,
+		Points:   points,
+	})
+}
+
+// formatInterval renders whole-minute intervals without zero units, e.g. 1h
+// rather than 1h0m0s.
+func formatInterval(interval time.Duration) string {
+	formatted := strings.TrimSuffix(interval.String(), "0s")
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
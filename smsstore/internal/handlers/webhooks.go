@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smsstore/internal/middleware"
+	"smsstore/internal/webhooks"
+	"smsstore/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultDeliveryLogLimit = 50
+	maxDeliveryLogLimit     = 500
+)
+
+// CreateWebhookSubscription registers a URL for message events. The response
+// carries the signing secret; it is not returned again.
+func CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	subscription, err := webhooks.Subscribe(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidSubscription) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		serverError(w, r, "Failed to create webhook subscription", err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/webhook-subscriptions/"+subscription.ID)
+	middleware.WriteJSON(w, r, http.StatusCreated, subscription)
+}
+
+// ListWebhookSubscriptions returns every subscription, without secrets.
+func (api *API) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := api.repo.ListWebhookSubscriptions(r.Context())
+	if err != nil {
+		serverError(w, r, "Failed to list webhook subscriptions", err)
+		return
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, subscriptions)
+}
+
+// GetWebhookSubscription returns one subscription, without its secret.
+func (api *API) GetWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, err := api.repo.GetWebhookSubscription(r.Context(), mux.Vars(r)["subscription_id"])
+	if err != nil {
+		serverError(w, r, "Failed to retrieve webhook subscription", err)
+		return
+	}
+	if subscription == nil {
+		writeError(w, r, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	subscription.Secret = ""
+	middleware.WriteJSON(w, r, http.StatusOK, subscription)
+}
+
+// DeleteWebhookSubscription stops deliveries to a subscription.
+func DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	deleted, err := webhooks.Unsubscribe(r.Context(), mux.Vars(r)["subscription_id"])
+	if err != nil {
+		serverError(w, r, "Failed to delete webhook subscription", err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries returns a subscription's delivery attempts, newest
+// first. Query params: limit (default 50, max 500) and before (RFC3339; pass
+// the last attempted_at to page back).
+func (api *API) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultDeliveryLogLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDeliveryLogLimit {
+			writeError(w, r, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+	var before time.Time
+	if raw := query.Get("before"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "before must be an RFC3339 timestamp")
+			return
+		}
+		before = parsed
+	}
+
+	subscriptionID := mux.Vars(r)["subscription_id"]
+	subscription, err := api.repo.GetWebhookSubscription(r.Context(), subscriptionID)
+	if err != nil {
+		serverError(w, r, "Failed to retrieve webhook subscription", err)
+		return
+	}
+	if subscription == nil {
+		writeError(w, r, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+
+	deliveries, err := api.repo.ListWebhookDeliveries(r.Context(), subscriptionID, before, int64(limit))
+	if err != nil {
+		serverError(w, r, "Failed to list webhook deliveries", err)
+		return
+	}
+	middleware.WriteJSON(w, r, http.StatusOK, deliveries)
+}
@@ -0,0 +1,251 @@
+// Package importer loads historical SMS records from CSV or JSON Lines files
+// through the consumer's validation and persistence stages, for migrating data
+// from the legacy system.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"smsstore/internal/consumer"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported input formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// maxLineBytes bounds a single JSONL record.
+const maxLineBytes = 1 << 20
+
+// csvColumns are the SmsEvent JSON fields a CSV header may name, besides
+// metadata.<key> columns. Numeric fields are converted; everything else is a string.
+var csvColumns = map[string]bool{
+	"phoneNumber": true, "message": true, "status": true,
+	"provider": true, "providerMessageId": true, "campaignId": true, "templateId": true,
+	"countryCode": true, "language": true, "idempotencyKey": true, "tenantId": true,
+	"category": true, "senderId": true, "traceId": true, "createdAt": true, "readAt": true,
+	"attempt": true, "providerLatencyMs": true,
+}
+
+var numericColumns = map[string]bool{"attempt": true, "providerLatencyMs": true}
+
+// Options controls an import.
+type Options struct {
+	Format string
+	// DryRun validates records without storing them
+	DryRun bool
+	// Errors receives one JSON line per rejected record; nil discards them
+	Errors io.Writer
+	// OnProgress is called every ProgressInterval and once at the end
+	OnProgress       func(Progress)
+	ProgressInterval time.Duration
+}
+
+// Progress counts the records handled so far. Records are stored, skipped as
+// duplicates of stored ones (e.g. from a previous run), or rejected.
+type Progress struct {
+	Records    int           `json:"records"`
+	Stored     int           `json:"stored"`
+	Duplicates int           `json:"duplicates"`
+	Rejected   int           `json:"rejected"`
+	BytesRead  int64         `json:"bytes_read"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// Rejection is a line of the error report.
+type Rejection struct {
+	Line   int    `json:"line"`
+	Error  string `json:"error"`
+	Record string `json:"record"`
+}
+
+// FormatFromPath picks the format from a file extension, or "" if unknown.
+func FormatFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".csv"):
+		return FormatCSV
+	case strings.HasSuffix(path, ".jsonl"), strings.HasSuffix(path, ".ndjson"):
+		return FormatJSONL
+	}
+	return ""
+}
+
+// Run imports every record from r through c's import pipeline. Invalid records are reported and skipped;
+// any other failure (e.g. MongoDB unavailable) stops the import and is
+// returned with the progress so far. Records carry derived idempotency keys,
+// so re-running a stopped import does not store anything twice.
+func Run(ctx context.Context, c *consumer.Consumer, r io.Reader, opts Options) (Progress, error) {
+	counter := &countingReader{r: r}
+	var next func() (int, []byte, error)
+	switch opts.Format {
+	case FormatJSONL:
+		next = jsonlRecords(counter)
+	case FormatCSV:
+		var err error
+		if next, err = csvRecords(counter); err != nil {
+			return Progress{}, err
+		}
+	default:
+		return Progress{}, fmt.Errorf("unsupported format %q, expected %s or %s", opts.Format, FormatJSONL, FormatCSV)
+	}
+
+	pipeline := c.ImportPipeline(opts.DryRun)
+	var report *json.Encoder
+	if opts.Errors != nil {
+		report = json.NewEncoder(opts.Errors)
+	}
+
+	var progress Progress
+	started := time.Now()
+	lastReport := started
+	snapshot := func() Progress {
+		progress.BytesRead = counter.n
+		progress.Elapsed = time.Since(started)
+		return progress
+	}
+
+	for ctx.Err() == nil {
+		line, payload, err := next()
+		if err == io.EOF {
+			break
+		}
+		var env *consumer.Envelope
+		if err == nil {
+			env, err = pipeline.Run(ctx, payload, nil)
+		}
+		progress.Records++
+		switch {
+		case err == nil && env.Duplicate:
+			progress.Duplicates++
+		case err == nil:
+			progress.Stored++
+		case errors.Is(err, consumer.ErrInvalidEvent), errors.Is(err, errMalformed):
+			progress.Rejected++
+			if report != nil {
+				if err := report.Encode(Rejection{Line: line, Error: err.Error(), Record: string(payload)}); err != nil {
+					return snapshot(), fmt.Errorf("failed to write error report: %w", err)
+				}
+			}
+		default:
+			return snapshot(), fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if opts.OnProgress != nil && opts.ProgressInterval > 0 && time.Since(lastReport) >= opts.ProgressInterval {
+			opts.OnProgress(snapshot())
+			lastReport = time.Now()
+		}
+	}
+	if opts.OnProgress != nil {
+		opts.OnProgress(snapshot())
+	}
+	return snapshot(), ctx.Err()
+}
+
+// errMalformed marks records that could not be parsed from the file.
+var errMalformed = errors.New("malformed record")
+
+// jsonlRecords yields one payload per non-blank line.
+func jsonlRecords(r io.Reader) func() (int, []byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	line := 0
+	return func() (int, []byte, error) {
+		for scanner.Scan() {
+			line++
+			if record := bytes.TrimSpace(scanner.Bytes()); len(record) > 0 {
+				return line, append([]byte(nil), record...), nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return line + 1, nil, err
+		}
+		return line, nil, io.EOF
+	}
+}
+
+// csvRecords reads the header row and yields each following row as an
+// SmsEvent JSON payload. Empty cells are left out.
+func csvRecords(r io.Reader) (func() (int, []byte, error), error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !csvColumns[name] && !(strings.HasPrefix(name, "metadata.") && len(name) > len("metadata.")) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[i] = name
+	}
+
+	return func() (int, []byte, error) {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return parseErr.StartLine, nil, fmt.Errorf("%w: %v", errMalformed, err)
+			}
+			return 0, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) != len(columns) {
+			return line, []byte(strings.Join(row, ",")), fmt.Errorf("%w: expected %d columns, got %d", errMalformed, len(columns), len(row))
+		}
+
+		record := map[string]interface{}{}
+		metadata := map[string]string{}
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			name := columns[i]
+			switch {
+			case strings.HasPrefix(name, "metadata."):
+				metadata[strings.TrimPrefix(name, "metadata.")] = value
+			case numericColumns[name]:
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return line, []byte(strings.Join(row, ",")), fmt.Errorf("%w: %s must be an integer", errMalformed, name)
+				}
+				record[name] = n
+			default:
+				record[name] = value
+			}
+		}
+		if len(metadata) > 0 {
+			record["metadata"] = metadata
+		}
+		payload, err := json.Marshal(record)
+		return line, payload, err
+	}, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,94 @@
+// Package integrity computes and verifies the checksums stored with each
+// message body, so a dispute can show whether a stored message still reads as
+// it did when it was ingested.
+package integrity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"smsstore/internal/config"
+	"smsstore/pkg/models"
+	"strings"
+	"sync"
+)
+
+// Checksum algorithms, written as the "<algorithm>:" prefix of a stored checksum.
+const (
+	AlgorithmSHA256     = "sha256"
+	AlgorithmHMACSHA256 = "hmac-sha256"
+)
+
+// Verification outcomes for a single message.
+const (
+	ResultOK           = "ok"
+	ResultMismatch     = "mismatch"
+	ResultMissing      = "missing"
+	ResultUnverifiable = "unverifiable"
+)
+
+var (
+	mu  sync.RWMutex
+	key []byte
+)
+
+// Configure sets the service key from app config. With a key, checksums are
+// HMACs, which someone able to edit the database cannot recompute after
+// changing a body. Call once at startup.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	key = nil
+	if cfg.MessageChecksumKey != "" {
+		key = []byte(cfg.MessageChecksumKey)
+	}
+}
+
+// Checksum returns the checksum to store with a message body.
+func Checksum(body string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if key != nil {
+		return AlgorithmHMACSHA256 + ":" + digest(hmac.New(sha256.New, key), body)
+	}
+	return AlgorithmSHA256 + ":" + digest(sha256.New(), body)
+}
+
+// Verify recomputes a stored message's checksum and reports the outcome:
+// ResultMissing for messages stored before checksums were recorded and
+// ResultUnverifiable for HMAC checksums when no key is configured.
+func Verify(message models.MessageWithStatus) string {
+	if message.Checksum == "" {
+		return ResultMissing
+	}
+	algorithm, expected, ok := strings.Cut(message.Checksum, ":")
+	if !ok {
+		return ResultMismatch
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case AlgorithmSHA256:
+		h = sha256.New()
+	case AlgorithmHMACSHA256:
+		mu.RLock()
+		current := key
+		mu.RUnlock()
+		if current == nil {
+			return ResultUnverifiable
+		}
+		h = hmac.New(sha256.New, current)
+	default:
+		return ResultUnverifiable
+	}
+	if !hmac.Equal([]byte(digest(h, message.Message)), []byte(expected)) {
+		return ResultMismatch
+	}
+	return ResultOK
+}
+
+func digest(h hash.Hash, body string) string {
+	h.Write([]byte(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
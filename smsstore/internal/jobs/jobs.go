@@ -0,0 +1,161 @@
+// Package jobs runs persisted background work. Job state lives in Mongo, so any
+// replica can run a job, progress and results survive restarts, and a job
+// whose replica dies is picked up again once its lease expires.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProgressFunc reports how far a job has got; total is zero when unknown.
+type ProgressFunc func(done, total int)
+
+// Handler performs a job. The returned result is stored on the job either way.
+type Handler func(ctx context.Context, params map[string]string, progress ProgressFunc) (interface{}, error)
+
+// RetryPolicy controls how failed attempts are retried. The delay before
+// attempt n+1 is Backoff * 2^(n-1).
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// DefaultRetryPolicy suits idempotent maintenance-style jobs.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 30 * time.Second}
+
+// ErrUnknownType is returned when enqueueing a type nobody registered.
+var ErrUnknownType = errors.New("unknown job type")
+
+type definition struct {
+	handler Handler
+	policy  RetryPolicy
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]definition{}
+)
+
+// Register makes a job type runnable. Call it before Start.
+func Register(jobType string, policy RetryPolicy, handler Handler) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registry[jobType] = definition{handler: handler, policy: policy}
+}
+
+func lookup(jobType string) (definition, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	def, ok := registry[jobType]
+	return def, ok
+}
+
+func registeredTypes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for jobType := range registry {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+// Enqueue persists a pending job for a registered type in repo.
+func Enqueue(ctx context.Context, repo *repository.Repository, jobType string, params map[string]string) (*models.Job, error) {
+	def, ok := lookup(jobType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        jobType,
+		Params:      params,
+		Status:      models.JobPending,
+		MaxAttempts: def.policy.MaxAttempts,
+		CreatedAt:   now,
+		RunAfter:    now,
+	}
+	if err := repo.InsertJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// EnqueueOnce persists a pending job for a registered type under an ID made of
+// the type and key, so replicas enqueueing the same work (e.g. a daily job)
+// create a single job. Returns the job and whether this call created it; if
+// one already existed it is returned, whatever its status.
+func EnqueueOnce(ctx context.Context, repo *repository.Repository, jobType string, key string, params map[string]string) (*models.Job, bool, error) {
+	def, ok := lookup(jobType)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:          jobType + ":" + key,
+		Type:        jobType,
+		Params:      params,
+		Status:      models.JobPending,
+		MaxAttempts: def.policy.MaxAttempts,
+		CreatedAt:   now,
+		RunAfter:    now,
+	}
+	err := repo.InsertJob(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		existing, err := repo.GetJob(ctx, job.ID)
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+// Get returns a job by ID, or nil if it does not exist.
+func Get(ctx context.Context, repo *repository.Repository, id string) (*models.Job, error) {
+	return repo.GetJob(ctx, id)
+}
+
+type runKey struct{}
+
+// runInfo identifies the job attempt a handler's ctx belongs to.
+type runInfo struct {
+	id      string
+	attempt int
+}
+
+// ID returns the ID of the job a handler's ctx belongs to, or "" outside the runner.
+func ID(ctx context.Context) string {
+	info, _ := ctx.Value(runKey{}).(runInfo)
+	return info.id
+}
+
+// Attempt returns which attempt of its job a handler's ctx belongs to,
+// starting at 1, or 0 outside the runner.
+func Attempt(ctx context.Context) int {
+	info, _ := ctx.Value(runKey{}).(runInfo)
+	return info.attempt
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying; the job fails immediately.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+)
+
+// Start runs cfg.JobWorkers workers that claim and execute jobs of every
+// registered type. Blocks until ctx is cancelled and running jobs have
+// stopped; interrupted jobs are returned to the queue.
+func Start(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	owner := fmt.Sprintf("%s-%d", cfg.InstanceID, os.Getpid())
+	log.Printf("[JOBS] Runner started: owner=%s, workers=%d, lease=%s", owner, cfg.JobWorkers, cfg.JobLeaseDuration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.JobWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work(ctx, cfg, repo, owner)
+		}()
+	}
+	wg.Wait()
+	log.Println("[JOBS] Runner stopped")
+}
+
+func work(ctx context.Context, cfg *config.Config, repo *repository.Repository, owner string) {
+	for ctx.Err() == nil {
+		job, err := repo.ClaimJob(ctx, owner, registeredTypes(), cfg.JobLeaseDuration)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[JOBS] Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(cfg.JobPollInterval):
+			}
+			continue
+		}
+		run(ctx, cfg, repo, owner, job)
+	}
+}
+
+func run(ctx context.Context, cfg *config.Config, repo *repository.Repository, owner string, job *models.Job) {
+	def, ok := lookup(job.Type)
+	if !ok {
+		return
+	}
+	log.Printf("[JOBS] Running %s job %s (attempt %d/%d)", job.Type, job.ID, job.Attempts, job.MaxAttempts)
+
+	jobCtx, cancel := context.WithCancel(context.WithValue(ctx, runKey{}, runInfo{id: job.ID, attempt: job.Attempts}))
+	defer cancel()
+	go keepLease(jobCtx, cancel, repo, cfg.JobLeaseDuration, owner, job.ID)
+
+	progress := func(done, total int) {
+		if err := repo.UpdateJobProgress(jobCtx, job.ID, owner, models.JobProgress{Done: done, Total: total}); err != nil && jobCtx.Err() == nil {
+			log.Printf("[JOBS] Failed to record progress for job %s: %v", job.ID, err)
+		}
+	}
+	result, err := execute(jobCtx, def.handler, job.Params, progress)
+
+	// Outcomes are recorded even during shutdown, so don't use the cancelled ctx
+	finishCtx := context.Background()
+	switch {
+	case err == nil:
+		log.Printf("[JOBS] %s job %s succeeded", job.Type, job.ID)
+		err = repo.FinishJob(finishCtx, job.ID, owner, models.JobSucceeded, result, "")
+	case ctx.Err() != nil:
+		log.Printf("[JOBS] %s job %s interrupted by shutdown, requeueing", job.Type, job.ID)
+		err = repo.RescheduleJob(finishCtx, job.ID, owner, time.Now().UTC(), err.Error(), true)
+	case errors.As(err, &permanentError{}) || job.Attempts >= job.MaxAttempts:
+		log.Printf("[JOBS] %s job %s failed: %v", job.Type, job.ID, err)
+		err = repo.FinishJob(finishCtx, job.ID, owner, models.JobFailed, result, err.Error())
+	default:
+		delay := def.policy.Backoff << (job.Attempts - 1)
+		log.Printf("[JOBS] %s job %s failed, retrying in %s: %v", job.Type, job.ID, delay, err)
+		err = repo.RescheduleJob(finishCtx, job.ID, owner, time.Now().UTC().Add(delay), err.Error(), false)
+	}
+	if err != nil {
+		log.Printf("[JOBS] Failed to record outcome of job %s: %v", job.ID, err)
+	}
+}
+
+// execute runs a handler, converting a panic into a failed attempt.
+func execute(ctx context.Context, handler Handler, params map[string]string, progress ProgressFunc) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, params, progress)
+}
+
+// keepLease renews the job's lease until ctx ends. If another replica has
+// taken the job over, the local run is cancelled.
+func keepLease(ctx context.Context, cancel context.CancelFunc, repo *repository.Repository, lease time.Duration, owner string, id string) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		held, err := repo.RenewJobLease(ctx, id, owner, lease)
+		if err != nil {
+			log.Printf("[JOBS] Failed to renew lease on job %s: %v", id, err)
+			continue
+		}
+		if !held {
+			log.Printf("[JOBS] Lost lease on job %s, stopping it", id)
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package kafkawriter builds the Kafka writers the service produces with, so
+// every topic gets the same acknowledgement, partitioning, compression and
+// batching settings.
+package kafkawriter
+
+import (
+	"smsstore/internal/config"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// New returns a writer to topic that waits for all in-sync replicas, keys
+// records to partitions by hash, compresses batches with
+// KAFKA_PRODUCER_COMPRESSION and caps them at KAFKA_PRODUCER_BATCH_BYTES.
+// An empty topic leaves it to be set per message. batchTimeout is how long a
+// partial batch waits for more records; callers pick it by how
+// latency-sensitive their topic is.
+func New(cfg *config.Config, topic string, batchTimeout time.Duration) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(cfg.KafkaBrokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: batchTimeout,
+		BatchBytes:   int64(cfg.KafkaProducerBatchBytes),
+		Compression:  Codec(cfg.KafkaProducerCompression),
+	}
+}
+
+// Codec returns the compression codec named name (none, gzip, snappy, lz4 or
+// zstd); unknown names, rejected by config validation, mean none.
+func Codec(name string) kafka.Compression {
+	var codec kafka.Compression
+	if err := codec.UnmarshalText([]byte(name)); err != nil {
+		return 0
+	}
+	return codec
+}
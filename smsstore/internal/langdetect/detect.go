@@ -0,0 +1,61 @@
+// Package langdetect guesses the language of short message bodies from the
+// script they are written in. That only identifies the language for scripts a
+// single language we send in uses; everything else is Undetermined.
+package langdetect
+
+import "unicode"
+
+// Undetermined is returned when a body has no letters to go on, or is mostly
+// in a script several languages share (ISO 639-2 "und").
+const Undetermined = "und"
+
+// scripts maps writing systems to the one language we send in them, or to ""
+// for scripts shared by several: Latin (English, Hinglish, ...), Devanagari
+// (Hindi, Marathi, Nepali), Bengali (Bengali, Assamese) and Arabic (Urdu,
+// Kashmiri, Sindhi). Shared scripts still count, so a mostly Latin body with a
+// few Tamil letters is Undetermined rather than Tamil. Producers that know a
+// message's language should set it on the event.
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Latin, ""},
+	{unicode.Devanagari, ""},
+	{unicode.Bengali, ""},
+	{unicode.Arabic, ""},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Oriya, "or"},
+}
+
+// Detect returns the ISO 639-1 code of the language written in the script most
+// letters in text are in, or Undetermined.
+func Detect(text string) string {
+	counts := make([]int, len(scripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for i, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	best, bestCount := "", 0
+	for i, count := range counts {
+		if count > bestCount {
+			best, bestCount = scripts[i].language, count
+		}
+	}
+	if best == "" {
+		return Undetermined
+	}
+	return best
+}
@@ -0,0 +1,134 @@
+package logsample
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"smsstore/internal/config"
+	"smsstore/pkg/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Per-message logging on the consumer path goes through this package so it
+// can be thinned out in production: routine lines are sampled (1 in N
+// messages), errors are always eligible, and every level is capped at a
+// number of lines per second. Debug lines are off unless toggled on, at
+// startup or through the admin API.
+
+// Level is the severity of a log line.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+var levelNames = [...]string{"debug", "info", "error"}
+
+// limiter is a per-level token bucket that remembers how many lines it dropped.
+type limiter struct {
+	tokens  float64
+	last    time.Time
+	dropped int
+}
+
+var (
+	mu         sync.Mutex
+	debug      bool
+	sampleRate = 1
+	rateLimit  = 0.0 // lines per second per level; zero is unlimited
+	limiters   [len(levelNames)]limiter
+	sampled    atomic.Uint64
+)
+
+// Configure applies the sampling settings. Call once at startup.
+func Configure(cfg *config.Config) {
+	Apply(models.LoggingSettings{Debug: cfg.LogDebug, SampleRate: cfg.LogSampleRate, RateLimit: cfg.LogRateLimit})
+}
+
+// Settings returns the settings in effect.
+func Settings() models.LoggingSettings {
+	mu.Lock()
+	defer mu.Unlock()
+	return models.LoggingSettings{Debug: debug, SampleRate: sampleRate, RateLimit: rateLimit}
+}
+
+// Apply replaces the settings in effect. SampleRate must be at least 1.
+func Apply(settings models.LoggingSettings) {
+	mu.Lock()
+	defer mu.Unlock()
+	debug = settings.Debug
+	sampleRate = settings.SampleRate
+	rateLimit = settings.RateLimit
+	for i := range limiters {
+		limiters[i] = limiter{tokens: burst(), last: time.Now()}
+	}
+}
+
+// Sample reports whether routine lines for the next message should be
+// logged: true for 1 in every SampleRate calls, and always in debug mode.
+func Sample() bool {
+	mu.Lock()
+	rate, verbose := sampleRate, debug
+	mu.Unlock()
+	return verbose || rate <= 1 || sampled.Add(1)%uint64(rate) == 0
+}
+
+// Debugf logs only when debug logging is on.
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, format, args...)
+}
+
+// Infof logs a routine line. Callers gate it on Sample for per-message lines.
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, format, args...)
+}
+
+// Errorf logs an error line; errors are never sampled, only rate limited.
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, format, args...)
+}
+
+func logf(level Level, format string, args ...interface{}) {
+	mu.Lock()
+	if level == LevelDebug && !debug {
+		mu.Unlock()
+		return
+	}
+	allowed, dropped := take(level, time.Now())
+	mu.Unlock()
+	if !allowed {
+		return
+	}
+	if dropped > 0 {
+		log.Printf("[LOG] Rate limit dropped %d %s lines", dropped, levelNames[level])
+	}
+	log.Output(3, fmt.Sprintf(format, args...))
+}
+
+// take spends a token for level, returning how many lines were dropped since
+// the last allowed one. Callers hold mu.
+func take(level Level, now time.Time) (bool, int) {
+	if rateLimit <= 0 {
+		return true, 0
+	}
+	bucket := &limiters[level]
+	bucket.tokens = math.Min(burst(), bucket.tokens+now.Sub(bucket.last).Seconds()*rateLimit)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		bucket.dropped++
+		return false, 0
+	}
+	bucket.tokens--
+	dropped := bucket.dropped
+	bucket.dropped = 0
+	return true, dropped
+}
+
+// burst allows a second's worth of lines at once. Callers hold mu.
+func burst() float64 {
+	return math.Max(1, rateLimit)
+}
@@ -0,0 +1,126 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"smsstore/internal/jobs"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+)
+
+// Operations accepted by Start; each is also its job type.
+const (
+	OpRebuildIndexes  = "rebuild_indexes"
+	OpCompactUser     = "compact_user"
+	OpRecomputeStats  = "recompute_stats"
+	OpVerifyIntegrity = "verify_integrity"
+)
+
+// Validation errors returned by Start.
+var (
+	ErrUnknownOperation = errors.New("unknown maintenance operation")
+	ErrMissingUserID    = errors.New("user_id is required for compact_user")
+	ErrUnknownRegion    = errors.New("region has no configured cluster")
+)
+
+// statsPageSize is how many users a full stats recompute or integrity
+// verification lists per page.
+const statsPageSize = 500
+
+// RegisterJobs registers the maintenance job types with the job runner.
+// All operations are idempotent, so they use the default retry policy.
+// Each runs against repo's database for its region and tenant params.
+func RegisterJobs(repo *repository.Repository) {
+	jobs.Register(OpRebuildIndexes, jobs.DefaultRetryPolicy, inScope(func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		rebuilt, err := repo.RebuildIndexes(ctx)
+		return map[string]interface{}{"indexes": rebuilt}, err
+	}))
+	jobs.Register(OpCompactUser, jobs.DefaultRetryPolicy, inScope(func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		moved, err := repo.CompactUser(ctx, params["user_id"])
+		return map[string]int{"messages_moved": moved}, err
+	}))
+	jobs.Register(OpRecomputeStats, jobs.DefaultRetryPolicy, inScope(func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		users, err := recomputeStats(ctx, repo, params["user_id"], progress)
+		return map[string]int{"users": users}, err
+	}))
+	jobs.Register(OpVerifyIntegrity, jobs.DefaultRetryPolicy, inScope(func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		return verifyIntegrity(ctx, repo, params["user_id"], progress)
+	}))
+}
+
+// inScope runs handler against the database of the job's region and tenant
+// params: the region's cluster, and the tenant's own database if it has one.
+func inScope(handler jobs.Handler) jobs.Handler {
+	return func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		ctx = repository.WithTenant(repository.WithRegion(ctx, params["region"]), params["tenant_id"])
+		return handler(ctx, params, progress)
+	}
+}
+
+// Start validates and enqueues a maintenance operation on repo.
+func Start(ctx context.Context, repo *repository.Repository, req models.MaintenanceRequest) (*models.Job, error) {
+	params := map[string]string{}
+	switch req.Operation {
+	case OpRebuildIndexes:
+	case OpCompactUser:
+		if req.UserID == "" {
+			return nil, ErrMissingUserID
+		}
+		params["user_id"] = req.UserID
+	case OpRecomputeStats, OpVerifyIntegrity:
+		if req.UserID != "" {
+			params["user_id"] = req.UserID
+		}
+	default:
+		return nil, ErrUnknownOperation
+	}
+	if req.Region != "" {
+		region := strings.ToUpper(req.Region)
+		if !repo.HasRegion(region) {
+			return nil, ErrUnknownRegion
+		}
+		params["region"] = region
+	}
+	if req.TenantID != "" {
+		params["tenant_id"] = req.TenantID
+	}
+	return jobs.Enqueue(ctx, repo, req.Operation, params)
+}
+
+// recomputeStats refreshes the stats snapshot for one user, or for every user
+// when userID is empty. Returns the number of users processed.
+func recomputeStats(ctx context.Context, repo *repository.Repository, userID string, progress jobs.ProgressFunc) (int, error) {
+	if userID != "" {
+		return 1, snapshotUser(ctx, repo, userID)
+	}
+
+	processed := 0
+	cursor := ""
+	for {
+		users, err := repo.ListUsers(ctx, time.Time{}, cursor, statsPageSize)
+		if err != nil {
+			return processed, err
+		}
+		for _, user := range users {
+			if err := snapshotUser(ctx, repo, user.UserID); err != nil {
+				return processed, err
+			}
+			processed++
+		}
+		progress(processed, 0)
+		if len(users) < statsPageSize {
+			return processed, nil
+		}
+		cursor = users[len(users)-1].UserID
+	}
+}
+
+func snapshotUser(ctx context.Context, repo *repository.Repository, userID string) error {
+	stats, err := repo.GetUserStats(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return repo.SaveUserStatsSnapshot(ctx, stats)
+}
@@ -0,0 +1,64 @@
+// Package masking redacts the sensitive parts of message bodies for views
+// that don't need them, such as support: one-time codes, account and card
+// numbers, and links that may carry tokens. The rest of the text is kept so
+// the message stays recognizable.
+package masking
+
+import (
+	"regexp"
+	"smsstore/pkg/models"
+	"strings"
+)
+
+// minDigits is the shortest run of digits masked; shorter numbers (amounts,
+// dates, counts) are left readable.
+const minDigits = 4
+
+var (
+	// Digit runs, allowing the spaces and dashes of grouped card numbers
+	digitRun = regexp.MustCompile(`\d(?:[ -]?\d)+`)
+	link     = regexp.MustCompile(`(?i)\bhttps?://[^\s/?#]+[^\s]*`)
+	linkHost = regexp.MustCompile(`(?i)^https?://[^\s/?#]+`)
+)
+
+// Body masks a message body: links keep only their scheme and host, and every
+// digit of a run of at least four digits becomes '*'.
+func Body(body string) string {
+	body = link.ReplaceAllStringFunc(body, func(url string) string {
+		host := linkHost.FindString(url)
+		if host == url {
+			return url
+		}
+		return host + "/***"
+	})
+	return digitRun.ReplaceAllStringFunc(body, func(run string) string {
+		digits := 0
+		for _, r := range run {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minDigits {
+			return run
+		}
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return '*'
+			}
+			return r
+		}, run)
+	})
+}
+
+// Messages returns copies of messages with their bodies masked. The body
+// checksum is dropped too, since a short code could be recovered from it by
+// trying every value; every other field is kept.
+func Messages(messages []models.MessageWithStatus) []models.MessageWithStatus {
+	masked := make([]models.MessageWithStatus, len(messages))
+	for i, message := range messages {
+		message.Message = Body(message.Message)
+		message.Checksum = ""
+		masked[i] = message
+	}
+	return masked
+}
@@ -0,0 +1,395 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "smsstore"
+
+var (
+	// DuplicateSuppressed counts events dropped by the per-user dedup window.
+	DuplicateSuppressed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_suppressed_total",
+		Help:      "Messages not stored because an identical body was stored for the user within the dedup window.",
+	})
+
+	// MessagesTruncated counts bodies cut down to MAX_MESSAGE_BYTES before storage.
+	MessagesTruncated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_truncated_total",
+		Help:      "Messages whose body exceeded the maximum stored size and was truncated.",
+	})
+
+	// StatusTransitionsInvalid counts status events that can't follow the stored status of their send.
+	StatusTransitionsInvalid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "status_transitions_invalid_total",
+		Help:      "Status events arriving after a status they can't follow, by stored and incoming status and action (rejected or flagged).",
+	}, []string{"from", "to", "action"})
+
+	// EventSchemaVersions counts consumed events by the schema version their producer wrote.
+	EventSchemaVersions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_schema_versions_total",
+		Help:      "Consumed SMS events by producer schema version (\"newer\" for versions beyond the consumer's); older versions are upcast.",
+	}, []string{"version"})
+
+	// DeliveryLatency observes the time from a send's first stored status to
+	// its delivered status, for provider SLOs.
+	DeliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "delivery_latency_seconds",
+		Help:      "Time from a send's first stored status to its delivery receipt, by provider and country code.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"provider", "country"})
+
+	// IntegrityChecks counts message checksum verifications by outcome.
+	IntegrityChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "integrity_checks_total",
+		Help:      "Stored message checksums verified, by result (ok, mismatch, missing, unverifiable).",
+	}, []string{"result"})
+
+	// IPAllowlistRejected counts requests from addresses outside an allowlist.
+	IPAllowlistRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ip_allowlist_rejected_total",
+		Help:      "HTTP requests refused because the client address is outside the allowlist, by allowlist.",
+	}, []string{"allowlist"})
+
+	// AuthorizationDenied counts requests refused by the RBAC policy.
+	AuthorizationDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "authorization_denied_total",
+		Help:      "HTTP requests refused by the RBAC policy, by reason (unauthenticated, forbidden or tenant_mismatch).",
+	}, []string{"reason"})
+
+	// CountryDerivations counts events whose country was derived from the
+	// phone number, by whether the number gave one.
+	CountryDerivations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "country_derivations_total",
+		Help:      "Events without a countryCode, by whether a country was derived from the phone number (derived, unknown).",
+	}, []string{"result"})
+
+	// OversizedEvents counts events too large to store as they are.
+	OversizedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "oversized_events_total",
+		Help:      "Events too large to store as they are, by reason (payload or document) and outcome (compacted or rejected).",
+	}, []string{"reason", "outcome"})
+
+	// DeadLetterEvents counts invalid events copied to the dead-letter topic.
+	DeadLetterEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_letter_events_total",
+		Help:      "Invalid events copied to the dead-letter topic, by result: published or failed.",
+	}, []string{"result"})
+
+	// QuarantinedEvents counts unparseable records kept for inspection.
+	QuarantinedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quarantined_events_total",
+		Help:      "Records with an unparseable payload stored in the quarantine collection, by result: stored or failed.",
+	}, []string{"result"})
+
+	// ForwardedMessages counts stored messages republished by forwarding rules.
+	ForwardedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forwarded_messages_total",
+		Help:      "Stored messages republished to downstream topics by forwarding rules, by topic and result: published or failed.",
+	}, []string{"topic", "result"})
+
+	// OrchestratedRetries counts failed messages handled by tenant retry policies.
+	OrchestratedRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orchestrated_retries_total",
+		Help:      "Failed messages handled by tenant retry policies, by result: resent, failed (not handed to the sender) or exhausted.",
+	}, []string{"result"})
+
+	// ReconciledMessages counts stale sends looked up by status reconciliation.
+	ReconciledMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconciled_messages_total",
+		Help:      "Sends without a delivery report looked up in their provider's status API, by provider and result: the status stored (delivered, undelivered or failed), pending (not final yet), not_found or error.",
+	}, []string{"provider", "result"})
+
+	// ReconcileStaleMessages is how many stale sends the last reconciliation
+	// pass found per provider.
+	ReconcileStaleMessages = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconcile_stale_messages",
+		Help:      "Sends without a delivery report found by the last status reconciliation pass, by provider.",
+	}, []string{"provider"})
+
+	// HTTPInflightRequests is the number of requests currently being served.
+	HTTPInflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_inflight_requests",
+		Help:      "HTTP requests currently in flight (excluding streams).",
+	})
+
+	// HTTPRequestsShed counts requests rejected by the load-shedding middleware.
+	HTTPRequestsShed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_shed_total",
+		Help:      "HTTP requests rejected with 503 because the in-flight limit was reached.",
+	})
+
+	// HTTPDeadlinesExceeded counts requests answered with 504 because the
+	// deadline their caller set passed.
+	HTTPDeadlinesExceeded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_deadlines_exceeded_total",
+		Help:      "HTTP requests answered with 504 because the caller's X-Request-Deadline or X-Request-Timeout passed.",
+	})
+
+	// RateLimitFallbacks counts rate-limit checks decided locally because Redis was unreachable.
+	RateLimitFallbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_fallbacks_total",
+		Help:      "Rate-limit checks enforced per replica because the Redis backend failed.",
+	})
+)
+
+var (
+	// MongoOperationDuration tracks latency of each repository operation.
+	MongoOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Duration of repository operations against MongoDB.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	// VersionConflicts counts read-modify-write operations restarted because
+	// the user document changed between the read and the write.
+	VersionConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "version_conflicts_total",
+		Help:      "User document read-modify-writes restarted after a concurrent write, by operation.",
+	}, []string{"operation"})
+
+	// MongoOperationErrors counts failed repository operations by error kind.
+	MongoOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_errors_total",
+		Help:      "Failed repository operations against MongoDB.",
+	}, []string{"operation", "kind"})
+)
+
+var (
+	// HTTPRequestDuration tracks handler latency per route template.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	// ConsumerProcessDuration tracks time from receiving a Kafka message to finishing with it.
+	ConsumerProcessDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_process_duration_seconds",
+		Help:      "Time spent processing a single Kafka message.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// ConsumerDecompressedBytes counts the bytes of consumed records (key,
+	// value and headers) after decompression, by topic. Compare it with the
+	// broker's bytes-in to see what producers' compression saves.
+	ConsumerDecompressedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_decompressed_bytes_total",
+		Help:      "Bytes of consumed records (key, value and headers) after decompression, by topic.",
+	}, []string{"topic"})
+
+	// ConsumerRecordBytes tracks the decompressed size of consumed records.
+	ConsumerRecordBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_record_bytes",
+		Help:      "Decompressed size of consumed records (key, value and headers).",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+	})
+
+	// ConsumerLaneLatency is the age of events (since their createdAt) when
+	// the consumer finishes with them, by lane.
+	ConsumerLaneLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_lane_latency_seconds",
+		Help:      "Time from an event's createdAt to the consumer finishing with it, by lane (priority, standard, bulk).",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"lane"})
+
+	// PriorityLatencyMisses counts priority-lane events finished later than
+	// PRIORITY_LATENCY_TARGET.
+	PriorityLatencyMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "priority_latency_target_misses_total",
+		Help:      "Priority-lane events finished later than PRIORITY_LATENCY_TARGET after their createdAt.",
+	})
+
+	// BulkThrottleWait tracks how long bulk-lane events waited for the
+	// promotional rate limit.
+	BulkThrottleWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bulk_throttle_wait_seconds",
+		Help:      "Time promotional events waited for PROMOTIONAL_RATE_LIMIT before processing.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// EnrichmentHooks counts enrichment hook calls by hook and outcome; the
+	// stage metrics time them under stage "hook:<name>".
+	EnrichmentHooks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enrichment_hooks_total",
+		Help:      "Enrichment hook calls by hook and outcome (ok, skipped, rejected, failed, ignored).",
+	}, []string{"hook", "outcome"})
+
+	// CompressedBodyBytes counts the bytes of bodies stored compressed
+	// (MESSAGE_BODY_ENCODING=zstd), before and after compression.
+	CompressedBodyBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "compressed_body_bytes_total",
+		Help:      "Bytes of message bodies stored compressed, by form (original, stored).",
+	}, []string{"form"})
+
+	// BodyDecompressionErrors counts stored bodies that could not be
+	// decompressed and were returned empty.
+	BodyDecompressionErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "body_decompression_errors_total",
+		Help:      "Stored message bodies that failed to decompress on read.",
+	})
+
+	// ConsumerPaused is 1 while the consumer is paused by its error-rate breaker.
+	ConsumerPaused = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_paused",
+		Help:      "Whether the consumer is paused because too many events failed (1) or consuming (0).",
+	})
+
+	// ConsumerLag is the consumer group's lag on the events topic, as last
+	// read by the watchdog.
+	ConsumerLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_lag",
+		Help:      "Events on the topic after the consumer group's committed offsets.",
+	})
+
+	// ConsumerStageDuration tracks time spent in each consumer pipeline stage.
+	ConsumerStageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_stage_duration_seconds",
+		Help:      "Time spent in each consumer pipeline stage.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"stage"})
+
+	// MessagesMovedToCold counts messages shifted from the hot to the cold tier.
+	MessagesMovedToCold = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_moved_to_cold_total",
+		Help:      "Messages moved from the hot collection to the cold collection.",
+	})
+
+	// StatusesCompacted counts interim statuses dropped by status history compaction.
+	StatusesCompacted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "statuses_compacted_total",
+		Help:      "Interim status events dropped from send histories by status compaction.",
+	})
+
+	// ProviderHealthScore is each provider's current health score in [0, 1].
+	ProviderHealthScore = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_health_score",
+		Help:      "Rolling provider health score combining success rate and latency (1 = healthy).",
+	}, []string{"provider"})
+
+	// WebhookDeliveries counts webhook delivery attempts by outcome.
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by event type and result: success, retry or failed (given up).",
+	}, []string{"event", "result"})
+
+	// UserCacheRequests counts message listings served through the in-process user cache.
+	UserCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_cache_requests_total",
+		Help:      "Message listings by cache result: hit, miss, or shared (waited on another request's read).",
+	}, []string{"result"})
+
+	// UserCacheEntries is the number of listings currently held in the user cache.
+	UserCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "user_cache_entries",
+		Help:      "Message listings currently held in the in-process user cache.",
+	})
+
+	// StatsCacheRequests counts stats and analytics requests by route and cache result.
+	StatsCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stats_cache_requests_total",
+		Help:      "Stats and analytics requests by route and cache result: hit, stale (served while refreshing), miss or bypass.",
+	}, []string{"route", "result"})
+
+	// CanaryProbes counts end-to-end canary probes by result.
+	CanaryProbes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "canary_probes_total",
+		Help:      "End-to-end canary probes by result: success, timeout, publish_failed or error.",
+	}, []string{"result"})
+
+	// CanaryLatency tracks how long a canary event took from publish to stored.
+	CanaryLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "canary_latency_seconds",
+		Help:      "Time from publishing a canary event to finding it stored.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// CanaryLastSuccess is when the canary last succeeded, for staleness alerts.
+	CanaryLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "canary_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful end-to-end canary probe.",
+	})
+
+	// ShadowWrites counts message writes mirrored to the shadow backend.
+	ShadowWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_writes_total",
+		Help:      "Message writes mirrored to the shadow backend, by operation (insert, read, delete, restore) and result (ok or failed).",
+	}, []string{"operation", "result"})
+
+	// ShadowComparisons counts users compared between the primary and shadow backends.
+	ShadowComparisons = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_comparisons_total",
+		Help:      "Users whose recent messages were compared between the primary and shadow backends, by result: match, diverged or error.",
+	}, []string{"result"})
+
+	// ShadowDivergentMessages counts messages that differ between the backends.
+	ShadowDivergentMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_divergent_messages_total",
+		Help:      "Compared messages that differ between the backends, by kind: missing (primary only), extra (shadow only) or mismatched.",
+	}, []string{"kind"})
+
+	// StatsCacheEntries is the number of responses currently held in the stats cache.
+	StatsCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stats_cache_entries",
+		Help:      "Stats and analytics responses currently held in the in-process cache.",
+	})
+)
+
+// Handler exposes all registered metrics in Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminAuth requires "Authorization: Bearer <token>" on every request. An
+// empty token disables the check.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="smsstore-admin"`)
+				WriteError(w, r, http.StatusUnauthorized, "Admin credentials required")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithCaller(r.Context(), "ADMIN_API_TOKEN")))
+		})
+	}
+}
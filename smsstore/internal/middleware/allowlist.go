@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"smsstore/internal/metrics"
+	"strings"
+)
+
+// IPAllowlist admits only clients whose remote address falls in one of
+// prefixes, answering 403 otherwise; rejections are counted under name. The
+// remote address is used as-is: forwarded headers are client-controlled.
+// No prefixes admits everyone.
+func IPAllowlist(name string, prefixes []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(prefixes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allowed(prefixes, clientIP(r)) {
+				metrics.IPAllowlistRejected.WithLabelValues(name).Inc()
+				WriteError(w, r, http.StatusForbidden, "Client address is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowed(prefixes []netip.Prefix, ip string) bool {
+	// The zone of link-local addresses (fe80::1%eth0) is ignored
+	addr, err := netip.ParseAddr(strings.Split(ip, "%")[0])
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
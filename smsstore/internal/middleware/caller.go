@@ -0,0 +1,18 @@
+package middleware
+
+import "context"
+
+type callerKey struct{}
+
+// WithCaller records the name of the authenticated caller (an API key's name
+// or a token's subject) for handlers that audit who did what.
+func WithCaller(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, callerKey{}, name)
+}
+
+// CallerFromContext returns the authenticated caller's name, or an empty
+// string when the request was not authenticated.
+func CallerFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(callerKey{}).(string)
+	return name
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import "net/http"
+
+// ClientCert requires a verified client certificate on every request when
+// required is set (TLS_CLIENT_CA_FILE). The TLS handshake only verifies
+// certificates that are presented, so that the Kubernetes probes, which
+// carry none, can reach /healthz and /readyz in front of this middleware.
+func ClientCert(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				WriteError(w, r, http.StatusUnauthorized, "Client certificate required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers an upstream gateway sets to pass on what is left of its request
+// budget: an absolute deadline (RFC 3339 or Unix milliseconds), or a timeout
+// from receipt (a duration such as "250ms", or milliseconds).
+const (
+	RequestDeadlineHeader = "X-Request-Deadline"
+	RequestTimeoutHeader  = "X-Request-Timeout"
+)
+
+// Deadline bounds a request by the deadline its caller set, less margin to
+// write the response in. Repository calls made with the request context give
+// up when it passes, and the request is answered with 504 and diagnostics
+// listing the operations that ran. Requests with both headers use the earlier
+// deadline; requests with neither aren't bounded here.
+func Deadline(margin time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			deadline, source, err := requestDeadline(r, start)
+			if err != nil {
+				WriteError(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			if deadline.IsZero() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			deadline = deadline.Add(-margin)
+
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+			ctx, operations := repository.WithOperationLog(ctx)
+			r = r.WithContext(ctx)
+			budgetWriter := &deadlineWriter{ResponseWriter: w, ctx: ctx}
+			budgetWriter.timeout = func() {
+				metrics.HTTPDeadlinesExceeded.Inc()
+				WriteErrorDiagnostics(w, r, http.StatusGatewayTimeout, "Request deadline exceeded", models.DeadlineDiagnostics{
+					BudgetMs:   max(deadline.Sub(start).Milliseconds(), 0),
+					ElapsedMs:  time.Since(start).Milliseconds(),
+					Source:     source,
+					Operations: operations.Operations(),
+				})
+			}
+
+			if deadline.After(start) {
+				next.ServeHTTP(budgetWriter, r)
+			}
+			// The handler wrote nothing, or the budget was spent before it started
+			if !budgetWriter.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				budgetWriter.wroteHeader, budgetWriter.timedOut = true, true
+				budgetWriter.timeout()
+			}
+		})
+	}
+}
+
+// requestDeadline reads the caller's deadline from the request headers; zero
+// if it set none.
+func requestDeadline(r *http.Request, now time.Time) (deadline time.Time, source string, err error) {
+	if raw := strings.TrimSpace(r.Header.Get(RequestDeadlineHeader)); raw != "" {
+		if millis, parseErr := strconv.ParseInt(raw, 10, 64); parseErr == nil {
+			deadline = time.UnixMilli(millis)
+		} else if deadline, parseErr = time.Parse(time.RFC3339Nano, raw); parseErr != nil {
+			return time.Time{}, "", errors.New(RequestDeadlineHeader + " must be an RFC 3339 timestamp or Unix milliseconds")
+		}
+		source = RequestDeadlineHeader
+	}
+	if raw := strings.TrimSpace(r.Header.Get(RequestTimeoutHeader)); raw != "" {
+		timeout, parseErr := time.ParseDuration(raw)
+		if millis, intErr := strconv.ParseInt(raw, 10, 64); intErr == nil {
+			timeout, parseErr = time.Duration(millis)*time.Millisecond, nil
+		}
+		if parseErr != nil || timeout <= 0 {
+			return time.Time{}, "", errors.New(RequestTimeoutHeader + " must be a positive duration or milliseconds")
+		}
+		if timeoutDeadline := now.Add(timeout); deadline.IsZero() || timeoutDeadline.Before(deadline) {
+			deadline, source = timeoutDeadline, RequestTimeoutHeader
+		}
+	}
+	return deadline, source, nil
+}
+
+// deadlineWriter replaces a server error written once the deadline has passed,
+// typically the handler reporting its cancelled repository call, with the
+// deadline's 504, and drops what the handler writes after it.
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	timeout     func()
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		w.timeout()
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (flush, deadlines).
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *deadlineWriter) Flush() {
+	if w.timedOut {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"smsstore/internal/metrics"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LoadShed rejects requests with 503 once maxInflight requests are already
+// being served, so a slow Mongo cannot pile up goroutines until the process
+// runs out of memory. Long-lived streams, metrics scrapes and diagnostics
+// (needed most when the service is overloaded) are exempt.
+func LoadShed(maxInflight int, retryAfter time.Duration) func(http.Handler) http.Handler {
+	var inflight int64
+	retryAfterSeconds := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxInflight <= 0 || isExemptFromShedding(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			current := atomic.AddInt64(&inflight, 1)
+			metrics.HTTPInflightRequests.Inc()
+			defer func() {
+				atomic.AddInt64(&inflight, -1)
+				metrics.HTTPInflightRequests.Dec()
+			}()
+
+			if current > int64(maxInflight) {
+				metrics.HTTPRequestsShed.Inc()
+				w.Header().Set("Retry-After", retryAfterSeconds)
+				WriteError(w, r, http.StatusServiceUnavailable, "Server is overloaded, retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isExemptFromShedding(r *http.Request) bool {
+	return r.URL.Path == "/metrics" || strings.HasSuffix(r.URL.Path, "/stream") ||
+		strings.HasPrefix(r.URL.Path, "/debug/pprof/") || r.URL.Path == "/v1/admin/debug/goroutines"
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"smsstore/internal/ratelimit"
+	"strconv"
+)
+
+// RateLimit admits requests per client (by remote IP) as decided by limiter,
+// answering 429 with Retry-After beyond that.
+func RateLimit(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, wait := limiter.Allow(r.Context(), clientIP(r))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				WriteError(w, r, http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns handler panics into a structured 500 response instead of
+// dropping the connection. Must be installed after RequestID and ResponseFormat.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Let net/http abort the response as the handler intended
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			requestID := RequestIDFromContext(r.Context())
+			log.Printf("[PANIC] [%s] %s %s: %v\n%s", requestID, r.Method, r.URL.Path, rec, debug.Stack())
+
+			WriteError(w, r, http.StatusInternalServerError, "Internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"smsstore/internal/repository"
+	"strings"
+)
+
+// RegionHeader selects the data-residency region whose cluster a request
+// reads and writes messages in; without it the default cluster is used.
+const RegionHeader = "X-Region"
+
+// Region scopes each request's message data to the cluster of the region
+// named in RegionHeader, answering 400 for a region hasRegion doesn't know.
+func Region(hasRegion func(region string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			region := strings.ToUpper(strings.TrimSpace(r.Header.Get(RegionHeader)))
+			if region == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !hasRegion(region) {
+				WriteError(w, r, http.StatusBadRequest, "Unknown region "+region)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(repository.WithRegion(r.Context(), region)))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"smsstore/pkg/models"
+	"strings"
+)
+
+// ResponseFormatHeader lets a client choose the response shape per request.
+const ResponseFormatHeader = "X-Response-Format"
+
+type responseFormatKey struct{}
+
+// ResponseFormat selects the response shape for the request: the
+// X-Response-Format header wins, otherwise the configured default applies.
+func ResponseFormat(defaultFormat string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			format := defaultFormat
+			switch strings.ToLower(r.Header.Get(ResponseFormatHeader)) {
+			case models.ResponseFormatEnvelope:
+				format = models.ResponseFormatEnvelope
+			case models.ResponseFormatFlat:
+				format = models.ResponseFormatFlat
+			}
+			ctx := context.WithValue(r.Context(), responseFormatKey{}, format)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func responseFormatFromContext(ctx context.Context) string {
+	if format, ok := ctx.Value(responseFormatKey{}).(string); ok {
+		return format
+	}
+	return models.ResponseFormatFlat
+}
+
+// WriteJSON writes a successful payload in the request's response format.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	var body interface{} = data
+	if responseFormatFromContext(r.Context()) == models.ResponseFormatEnvelope {
+		body = models.Envelope{
+			Data:   data,
+			Meta:   responseMeta(r),
+			Errors: []models.APIError{},
+		}
+	}
+	writeBody(w, status, body)
+}
+
+// WriteError writes an error in the request's response format.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	WriteErrorDiagnostics(w, r, status, message, nil)
+}
+
+// WriteErrorDiagnostics writes an error with diagnostics, which go under
+// diagnostics in the flat format and meta.diagnostics in the envelope.
+func WriteErrorDiagnostics(w http.ResponseWriter, r *http.Request, status int, message string, diagnostics interface{}) {
+	var body interface{} = models.ErrorResponse{
+		Error:       message,
+		RequestID:   RequestIDFromContext(r.Context()),
+		Diagnostics: diagnostics,
+	}
+	if responseFormatFromContext(r.Context()) == models.ResponseFormatEnvelope {
+		meta := responseMeta(r)
+		if diagnostics != nil {
+			meta["diagnostics"] = diagnostics
+		}
+		body = models.Envelope{
+			Data:   nil,
+			Meta:   meta,
+			Errors: []models.APIError{{Status: status, Message: message}},
+		}
+	}
+	writeBody(w, status, body)
+}
+
+func responseMeta(r *http.Request) map[string]interface{} {
+	meta := map[string]interface{}{}
+	if requestID := RequestIDFromContext(r.Context()); requestID != "" {
+		meta["request_id"] = requestID
+	}
+	return meta
+}
+
+func writeBody(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
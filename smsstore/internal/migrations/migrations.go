@@ -0,0 +1,276 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is a single, idempotent schema change. Versions must be unique and
+// increasing; once released a migration must never be edited, only superseded.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+}
+
+// registry lists every migration in version order.
+var registry = []Migration{
+	{
+		Version:     1,
+		Description: "index messages.created_at for the retention janitor",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("smsdata"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "messages.created_at", Value: 1}},
+				Options: options.Index().SetName("messages_created_at"),
+			})
+		},
+	},
+	{
+		Version:     2,
+		Description: "index smsdata.updated_at for admin user listings",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("smsdata"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "updated_at", Value: 1}},
+				Options: options.Index().SetName("updated_at"),
+			})
+		},
+	},
+	{
+		Version:     3,
+		Description: "index smsdata_cold messages.created_at for the retention janitor",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// The cold tier is deliberately left without the hot tier's query indexes
+			return createIndexes(ctx, database.Collection("smsdata_cold"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "messages.created_at", Value: 1}},
+				Options: options.Index().SetName("messages_created_at"),
+			})
+		},
+	},
+	{
+		Version:     4,
+		Description: "index message campaign, template and provider message IDs",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("smsdata"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "messages.campaign_id", Value: 1}},
+					Options: options.Index().SetName("messages_campaign_id").SetSparse(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "messages.template_id", Value: 1}},
+					Options: options.Index().SetName("messages_template_id").SetSparse(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "messages.provider_message_id", Value: 1}},
+					Options: options.Index().SetName("messages_provider_message_id").SetSparse(true),
+				},
+			)
+		},
+	},
+	{
+		Version:     5,
+		Description: "index compacted messages by user and creation time",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("messages"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("created_at"),
+				},
+			)
+		},
+	},
+	{
+		Version:     6,
+		Description: "index jobs for claiming and expire finished jobs after 7 days",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("jobs"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "run_after", Value: 1}},
+					Options: options.Index().SetName("type_status_run_after"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "finished_at", Value: 1}},
+					Options: options.Index().SetName("finished_at_ttl").SetExpireAfterSeconds(7 * 24 * 60 * 60),
+				},
+			)
+		},
+	},
+	{
+		Version:     7,
+		Description: "index embedded message IDs for the firehose cursor",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"smsdata", "smsdata_cold"} {
+				err := createIndexes(ctx, database.Collection(name), mongo.IndexModel{
+					Keys:    bson.D{{Key: "messages.message_id", Value: 1}},
+					Options: options.Index().SetName("messages_message_id"),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     8,
+		Description: "wildcard-index message metadata for search",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"smsdata", "smsdata_cold"} {
+				err := createIndexes(ctx, database.Collection(name), mongo.IndexModel{
+					Keys:    bson.D{{Key: "messages.metadata.$**", Value: 1}},
+					Options: options.Index().SetName("messages_metadata_wildcard"),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return createIndexes(ctx, database.Collection("messages"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "metadata.$**", Value: 1}},
+				Options: options.Index().SetName("metadata_wildcard"),
+			})
+		},
+	},
+	{
+		Version:     9,
+		Description: "index webhook delivery log by subscription and expire it after 30 days",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("webhook_deliveries"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "subscription_id", Value: 1}, {Key: "attempted_at", Value: -1}},
+					Options: options.Index().SetName("subscription_id_attempted_at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "attempted_at", Value: 1}},
+					Options: options.Index().SetName("attempted_at_ttl").SetExpireAfterSeconds(30 * 24 * 60 * 60),
+				},
+			)
+		},
+	},
+	{
+		Version:     10,
+		Description: "unique index on the purge ledger's month, source, tenant and category",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("purge_ledger"), mongo.IndexModel{
+				Keys: bson.D{
+					{Key: "month", Value: 1}, {Key: "source", Value: 1},
+					{Key: "tenant_id", Value: 1}, {Key: "category", Value: 1},
+				},
+				Options: options.Index().SetName("month_source_tenant_id_category").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     11,
+		Description: "index smsdata messages by tenant, status and creation time for the retry orchestrator",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("smsdata"), mongo.IndexModel{
+				Keys: bson.D{
+					{Key: "messages.tenant_id", Value: 1}, {Key: "messages.status", Value: 1},
+					{Key: "messages.created_at", Value: 1},
+				},
+				Options: options.Index().SetName("messages_tenant_id_status_created_at"),
+			})
+		},
+	},
+	{
+		Version:     12,
+		Description: "index message rollups by granularity, tenant and start and expire fine-grained ones",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("message_rollups"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "granularity", Value: 1}, {Key: "tenant_id", Value: 1}, {Key: "start", Value: 1}},
+					Options: options.Index().SetName("granularity_tenant_id_start"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+				},
+			)
+		},
+	},
+	{
+		Version:     13,
+		Description: "create the capped quarantine collection for unparseable payloads",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			names, err := database.ListCollectionNames(ctx, bson.M{"name": "quarantine"})
+			if err != nil || len(names) > 0 {
+				return err
+			}
+			// The 1000 most recent payloads, within 64MB
+			return database.CreateCollection(ctx, "quarantine",
+				options.CreateCollection().SetCapped(true).SetSizeInBytes(64<<20).SetMaxDocuments(1000))
+		},
+	},
+	{
+		Version:     14,
+		Description: "index shadow messages by user for comparisons and read receipts",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("messages_shadow"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "provider_message_id", Value: 1}},
+					Options: options.Index().SetName("user_id_provider_message_id"),
+				},
+			)
+		},
+	},
+	{
+		Version:     15,
+		Description: "index archived status events by user for purges",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("status_archive"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				},
+			)
+		},
+	},
+	{
+		Version:     16,
+		Description: "index the audit trail by time and by user",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database.Collection("audit_log"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "at", Value: -1}},
+					Options: options.Index().SetName("at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "at", Value: -1}},
+					Options: options.Index().SetName("user_id_at"),
+				},
+			)
+		},
+	},
+	{
+		Version:     17,
+		Description: "index message country codes for per-country analytics",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			err := createIndexes(ctx, database.Collection("smsdata"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "messages.country_code", Value: 1}, {Key: "messages.created_at", Value: 1}},
+				Options: options.Index().SetName("messages_country_code_created_at"),
+			})
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, database.Collection("messages"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "country_code", Value: 1}, {Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("country_code_created_at"),
+			})
+		},
+	},
+}
+
+func createIndexes(ctx context.Context, collection *mongo.Collection, models ...mongo.IndexModel) error {
+	_, err := collection.Indexes().CreateMany(ctx, models)
+	return err
+}
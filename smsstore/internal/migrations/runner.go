@@ -0,0 +1,187 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "migrations"
+
+	// lockTTL bounds how long a crashed replica can block others from migrating.
+	// The holder renews the lock every lockRenewInterval, so migrations may run
+	// for longer.
+	lockTTL           = 2 * time.Minute
+	lockRenewInterval = 30 * time.Second
+	lockPollInterval  = 2 * time.Second
+)
+
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Run applies every pending migration in version order. Replicas starting at the
+// same time serialize on a lock document so each migration runs exactly once.
+// Should the lock be lost (e.g. the holder stalled past lockTTL), ctx is
+// cancelled for the running migration rather than letting two replicas migrate
+// at once.
+func Run(ctx context.Context, database *mongo.Database) error {
+	if err := validateRegistry(); err != nil {
+		return err
+	}
+
+	owner := lockOwner()
+	if err := acquireLock(ctx, database, owner); err != nil {
+		return err
+	}
+	defer releaseLock(database, owner)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go renewLock(ctx, cancel, database, owner)
+
+	applied, err := appliedVersions(ctx, database)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, migration := range registry {
+		if applied[migration.Version] {
+			continue
+		}
+		log.Printf("[MIGRATIONS] Applying %d: %s", migration.Version, migration.Description)
+		if err := migration.Up(ctx, database); err != nil {
+			if cause := context.Cause(ctx); cause != nil && ctx.Err() != nil {
+				err = cause
+			}
+			return fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+		record := appliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		}
+		if _, err := database.Collection(migrationsCollection).InsertOne(ctx, record); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+	}
+	log.Println("[MIGRATIONS] Schema is up to date")
+	return nil
+}
+
+func validateRegistry() error {
+	sorted := sort.SliceIsSorted(registry, func(i, j int) bool {
+		return registry[i].Version < registry[j].Version
+	})
+	if !sorted {
+		return errors.New("migrations registry is not in version order")
+	}
+	for i := 1; i < len(registry); i++ {
+		if registry[i].Version == registry[i-1].Version {
+			return fmt.Errorf("duplicate migration version %d", registry[i].Version)
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, database *mongo.Database) (map[int]bool, error) {
+	cursor, err := database.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []appliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+	return applied, nil
+}
+
+// acquireLock blocks until this process holds the migration lock or ctx expires.
+// An expired lock left behind by a crashed replica is taken over.
+func acquireLock(ctx context.Context, database *mongo.Database, owner string) error {
+	collection := database.Collection(lockCollection)
+	for {
+		now := time.Now().UTC()
+		filter := bson.M{
+			"_id": lockID,
+			"$or": bson.A{
+				bson.M{"expires_at": bson.M{"$lt": now}},
+				bson.M{"owner": owner},
+			},
+		}
+		update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(lockTTL)}}
+		_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return nil
+		}
+		// A duplicate key means another replica holds an unexpired lock
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		log.Printf("[MIGRATIONS] Waiting for migration lock held by another replica...")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for migration lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// renewLock extends the lock held by owner every lockRenewInterval until ctx
+// is done, cancelling ctx if the lock turns out to be held by someone else.
+func renewLock(ctx context.Context, cancel context.CancelCauseFunc, database *mongo.Database, owner string) {
+	ticker := time.NewTicker(lockRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		update := bson.M{"$set": bson.M{"expires_at": time.Now().UTC().Add(lockTTL)}}
+		result, err := database.Collection(lockCollection).UpdateOne(ctx, bson.M{"_id": lockID, "owner": owner}, update)
+		if err != nil {
+			// Retried on the next tick; the lock outlives a few failed renewals
+			log.Printf("[MIGRATIONS] Failed to renew migration lock: %v", err)
+			continue
+		}
+		if result.MatchedCount == 0 {
+			cancel(errors.New("migration lock lost to another replica"))
+			return
+		}
+	}
+}
+
+func releaseLock(database *mongo.Database, owner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := database.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner}); err != nil {
+		log.Printf("[MIGRATIONS] Failed to release migration lock: %v", err)
+	}
+}
+
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
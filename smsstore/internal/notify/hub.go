@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event signals that a user's stored messages changed.
+type Event struct {
+	UserID    string    `json:"user_id"`
+	Operation string    `json:"op"`
+	At        time.Time `json:"at"`
+}
+
+// subscriberBuffer bounds how far a slow subscriber can fall behind before events are dropped.
+const subscriberBuffer = 16
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[string]map[chan Event]struct{}{}
+	listeners   []func(Event)
+)
+
+// Subscribe registers for events about a single user. The returned function
+// must be called to unsubscribe; it closes the channel.
+func Subscribe(userID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	mu.Lock()
+	if subscribers[userID] == nil {
+		subscribers[userID] = map[chan Event]struct{}{}
+	}
+	subscribers[userID][ch] = struct{}{}
+	mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			mu.Lock()
+			delete(subscribers[userID], ch)
+			if len(subscribers[userID]) == 0 {
+				delete(subscribers, userID)
+			}
+			mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// AddListener registers a callback invoked for every event regardless of user,
+// e.g. for cache invalidation. Listeners run synchronously and must be fast.
+func AddListener(listener func(Event)) {
+	mu.Lock()
+	listeners = append(listeners, listener)
+	mu.Unlock()
+}
+
+// Publish fans an event out to listeners and the user's subscribers.
+// Subscribers whose buffer is full miss the event rather than blocking the publisher.
+func Publish(event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+	for ch := range subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("[NOTIFY] Dropping event for slow subscriber of %s", event.UserID)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package providerhealth
+
+import (
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/pkg/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthyScore is the score below which a provider is reported as degraded.
+const healthyScore = 0.8
+
+// Only the sender's status for each send attempt is counted. Delivery receipts
+// (delivered, failed, undelivered) follow a send already counted, so counting
+// them too would count the same message twice.
+var (
+	successStatuses = map[string]bool{
+		"successful": true,
+	}
+	failureStatuses = map[string]bool{
+		"unsuccessful": true,
+		"retrying":     true, // transient provider failure, queued for retry
+	}
+)
+
+// bucket holds one minute of outcomes for a provider.
+type bucket struct {
+	minute       int64
+	total        int64
+	failures     int64
+	latencySum   time.Duration
+	latencyCount int64
+}
+
+var (
+	mu            sync.Mutex
+	providers     = map[string][]bucket{}
+	window        = 15 * time.Minute
+	latencyTarget = 2 * time.Second
+	minSamples    = 20
+	now           = time.Now
+)
+
+// Configure applies the scoring settings. Call once at startup.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	window = cfg.ProviderHealthWindow
+	latencyTarget = cfg.ProviderLatencyTarget
+	minSamples = cfg.ProviderHealthMinSamples
+}
+
+// Record counts a send attempt's outcome for a provider. Events without a
+// provider, receipts, and statuses that say nothing about the provider (e.g.
+// blocked) are ignored.
+func Record(provider, status string, latency time.Duration) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	status = strings.ToLower(status)
+	failed := failureStatuses[status]
+	if provider == "" || (!failed && !successStatuses[status]) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	minute := now().Unix() / 60
+	buckets := prune(providers[provider], minute)
+	if len(buckets) == 0 || buckets[len(buckets)-1].minute != minute {
+		buckets = append(buckets, bucket{minute: minute})
+	}
+	b := &buckets[len(buckets)-1]
+	b.total++
+	if failed {
+		b.failures++
+	}
+	if latency > 0 {
+		b.latencySum += latency
+		b.latencyCount++
+	}
+	providers[provider] = buckets
+	metrics.ProviderHealthScore.WithLabelValues(provider).Set(score(buckets).Score)
+}
+
+// Snapshot returns every known provider's health, healthiest first.
+func Snapshot() models.ProviderHealthResponse {
+	mu.Lock()
+	defer mu.Unlock()
+	minute := now().Unix() / 60
+	result := models.ProviderHealthResponse{Window: window.String(), Providers: []models.ProviderHealth{}}
+	for provider, buckets := range providers {
+		buckets = prune(buckets, minute)
+		providers[provider] = buckets
+		health := score(buckets)
+		health.Provider = provider
+		result.Providers = append(result.Providers, health)
+	}
+	sort.Slice(result.Providers, func(i, j int) bool {
+		if result.Providers[i].Score != result.Providers[j].Score {
+			return result.Providers[i].Score > result.Providers[j].Score
+		}
+		return result.Providers[i].Provider < result.Providers[j].Provider
+	})
+	return result
+}
+
+// prune drops buckets that have fallen out of the window. Callers hold mu.
+func prune(buckets []bucket, minute int64) []bucket {
+	oldest := minute - int64(window/time.Minute) + 1
+	i := 0
+	for i < len(buckets) && buckets[i].minute < oldest {
+		i++
+	}
+	return buckets[i:]
+}
+
+// score combines success rate with a latency penalty: a provider slower on
+// average than the latency target loses score in proportion. Callers hold mu.
+func score(buckets []bucket) models.ProviderHealth {
+	var total, failures, latencyCount int64
+	var latencySum time.Duration
+	for _, b := range buckets {
+		total += b.total
+		failures += b.failures
+		latencySum += b.latencySum
+		latencyCount += b.latencyCount
+	}
+
+	health := models.ProviderHealth{Samples: total, Score: 1, Healthy: true, SuccessRate: 1}
+	if total > 0 {
+		health.SuccessRate = float64(total-failures) / float64(total)
+	}
+	latencyFactor := 1.0
+	if latencyCount > 0 {
+		avg := latencySum / time.Duration(latencyCount)
+		health.AvgLatencyMs = float64(avg) / float64(time.Millisecond)
+		if avg > latencyTarget {
+			latencyFactor = float64(latencyTarget) / float64(avg)
+		}
+	}
+	if total < int64(minSamples) {
+		return health
+	}
+	health.Score = health.SuccessRate * latencyFactor
+	health.Healthy = health.Score >= healthyScore
+	return health
+}
@@ -0,0 +1,51 @@
+// Package quarantine keeps the most recent consumed records whose payload could
+// not be parsed, with their Kafka coordinates, so engineers can see what
+// producers are sending wrong through the admin API instead of Kafka tooling.
+// Unlike the dead-letter topic it is always on and only holds a capped sample.
+package quarantine
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Record stores msg and why it could not be parsed in repo. Failures are logged
+// and never propagated: the record is committed past either way.
+func Record(ctx context.Context, repo *repository.Repository, msg kafka.Message, reason error) {
+	event := &models.QuarantinedEvent{
+		ID:            primitive.NewObjectID().Hex(),
+		Topic:         msg.Topic,
+		Partition:     msg.Partition,
+		Offset:        msg.Offset,
+		Key:           string(msg.Key),
+		Payload:       string(msg.Value),
+		Error:         reason.Error(),
+		QuarantinedAt: time.Now().UTC(),
+	}
+	if !utf8.Valid(msg.Value) {
+		event.Payload = base64.StdEncoding.EncodeToString(msg.Value)
+		event.PayloadEncoding = "base64"
+	}
+	if len(msg.Headers) > 0 {
+		event.Headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			event.Headers[header.Key] = string(header.Value)
+		}
+	}
+
+	if err := repo.InsertQuarantinedEvent(ctx, event); err != nil {
+		metrics.QuarantinedEvents.WithLabelValues("failed").Inc()
+		log.Printf("[QUARANTINE] Failed to quarantine partition %d offset %d: %v", msg.Partition, msg.Offset, err)
+		return
+	}
+	metrics.QuarantinedEvents.WithLabelValues("stored").Inc()
+}
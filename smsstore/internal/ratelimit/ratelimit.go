@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"smsstore/internal/config"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiters are keyed by client (the middleware uses the remote IP). The memory
+// backend counts per process, so N replicas behind a load balancer admit N
+// times the configured rate; the redis backend shares one budget per client
+// across replicas and falls back to the memory backend while Redis is down.
+
+// Limiter decides whether the next request for key is allowed, and if not how
+// long until it would be.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration)
+}
+
+// idleBucketTTL is how long an unused client bucket is kept before being dropped.
+const idleBucketTTL = 10 * time.Minute
+
+var (
+	mu          sync.Mutex
+	backend     = "memory"
+	redisClient *redis.Client
+	keyPrefix   = "smsstore:ratelimit:"
+)
+
+// Configure selects the backend for limiters created afterwards. Call once at
+// startup, before routes are set up.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	backend = cfg.RateLimitBackend
+	keyPrefix = cfg.RateLimitKeyPrefix
+	if backend == "redis" && redisClient == nil {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisAddr,
+			Password:     cfg.RedisPassword,
+			DB:           cfg.RedisDB,
+			DialTimeout:  cfg.RedisTimeout,
+			ReadTimeout:  cfg.RedisTimeout,
+			WriteTimeout: cfg.RedisTimeout,
+		})
+		log.Printf("[RATELIMIT] Using Redis at %s for distributed rate limits", cfg.RedisAddr)
+	}
+}
+
+// New returns a limiter named name (distinct names keep separate budgets)
+// allowing rate requests per second per key with bursts of up to burst.
+func New(name string, rate float64, burst int) Limiter {
+	mu.Lock()
+	defer mu.Unlock()
+	local := NewMemory(rate, burst)
+	if backend != "redis" {
+		return local
+	}
+	return newRedisLimiter(redisClient, keyPrefix+name+":", rate, burst, local)
+}
+
+// Close releases the Redis connection pool, if any.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if redisClient == nil {
+		return nil
+	}
+	err := redisClient.Close()
+	redisClient = nil
+	return err
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// memoryLimiter is a per-process token bucket per key.
+type memoryLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemory returns an in-process token-bucket limiter.
+func NewMemory(rate float64, burst int) Limiter {
+	return &memoryLimiter{rate: rate, burst: burst, buckets: map[string]*tokenBucket{}, lastSweep: time.Now()}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleBucketTTL {
+		for k, bucket := range l.buckets {
+			if now.Sub(bucket.last) > idleBucketTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(l.burst), bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"smsstore/internal/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm: the key holds the
+// theoretical arrival time (TAT) of the next request in microseconds. A request
+// is allowed unless it arrives more than burst emission intervals before the
+// TAT. Redis' own clock is used so replica clock skew doesn't matter.
+//
+// KEYS[1] key; ARGV[1] emission interval (µs); ARGV[2] burst.
+// Returns {allowed, retry after (µs)}.
+var gcraScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+  tat = now
+end
+local newTat = tat + emission
+local allowAt = newTat - burst * emission
+if now < allowAt then
+  return {0, math.ceil(allowAt - now)}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', newTat), 'PX', math.ceil((newTat - now) / 1000))
+return {1, 0}
+`)
+
+type redisLimiter struct {
+	client   *redis.Client
+	prefix   string
+	emission int64 // µs between requests at the sustained rate
+	burst    int
+	fallback Limiter
+	degraded atomic.Bool // logs only the transitions, not every failed request
+}
+
+func newRedisLimiter(client *redis.Client, prefix string, rate float64, burst int, fallback Limiter) *redisLimiter {
+	return &redisLimiter{
+		client:   client,
+		prefix:   prefix,
+		emission: int64(math.Max(1, math.Round(float64(time.Second/time.Microsecond)/rate))),
+		burst:    burst,
+		fallback: fallback,
+	}
+}
+
+// Allow checks the shared budget in Redis. While Redis is unreachable each
+// replica enforces the limit on its own, which is looser but never fails open.
+func (l *redisLimiter) Allow(ctx context.Context, key string) (bool, time.Duration) {
+	res, err := gcraScript.Run(ctx, l.client, []string{l.prefix + key}, l.emission, l.burst).Int64Slice()
+	if err != nil || len(res) != 2 {
+		metrics.RateLimitFallbacks.Inc()
+		if !l.degraded.Swap(true) {
+			log.Printf("[RATELIMIT] Redis unavailable, enforcing limits per replica: %v", err)
+		}
+		return l.fallback.Allow(ctx, key)
+	}
+	if l.degraded.Swap(false) {
+		log.Printf("[RATELIMIT] Redis reachable again, limits are shared across replicas")
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond
+}
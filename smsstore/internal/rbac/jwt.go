@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// jwtClockSkew is how far ahead of ours an issuer's clock may run before its
+// fresh tokens (nbf, iat) are refused.
+const jwtClockSkew = 30 * time.Second
+
+// looksLikeJWT tells compact JWTs (header.payload.signature) from API keys.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// verifyJWT checks an HS256 token's signature and its exp (required), nbf and
+// iat claims, and returns the caller it names: sub, with the roles from
+// rolesClaim (a list of strings or a space-separated string) and the tenant
+// from tenantClaim.
+func verifyJWT(token string, secret []byte, rolesClaim, tenantClaim string, now time.Time) (principal, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return principal{}, err
+	}
+	// Only the algorithm we hold a key for; never "none"
+	if header.Alg != "HS256" {
+		return principal{}, errors.New("unsupported JWT algorithm")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return principal{}, errors.New("malformed JWT signature")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return principal{}, errors.New("invalid JWT signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return principal{}, err
+	}
+	// A token without exp would be valid forever
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return principal{}, errors.New("JWT has no exp claim")
+	}
+	if !now.Before(time.Unix(int64(exp), 0)) {
+		return principal{}, errors.New("JWT has expired")
+	}
+	if !reached(claims, "nbf", now) {
+		return principal{}, errors.New("JWT is not valid yet")
+	}
+	if !reached(claims, "iat", now) {
+		return principal{}, errors.New("JWT is issued in the future")
+	}
+
+	caller := principal{}
+	caller.Name, _ = claims["sub"].(string)
+	if tenant, ok := claims[tenantClaim].(string); ok {
+		caller.Tenant = strings.TrimSpace(tenant)
+	}
+	switch roles := claims[rolesClaim].(type) {
+	case string:
+		caller.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				caller.Roles = append(caller.Roles, role)
+			}
+		}
+	}
+	return caller, nil
+}
+
+// reached reports whether the time claim, if present, is no later than now
+// allowing for jwtClockSkew. A claim that isn't a number is never reached.
+func reached(claims map[string]interface{}, claim string, now time.Time) bool {
+	raw, present := claims[claim]
+	if !present {
+		return true
+	}
+	value, ok := raw.(float64)
+	return ok && !now.Add(jwtClockSkew).Before(time.Unix(int64(value), 0))
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed JWT")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errors.New("malformed JWT")
+	}
+	return nil
+}
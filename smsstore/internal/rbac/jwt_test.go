@@ -0,0 +1,173 @@
+package rbac
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+// signJWT builds a compact JWT with the given header and claims, signed with
+// HS256 under secret whatever alg the header names.
+func signJWT(t *testing.T, header, claims map[string]interface{}, secret []byte) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signed := segment(header) + "." + segment(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	valid := func(extra map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{"sub": "checkout", "exp": now.Add(time.Hour).Unix()}
+		for key, value := range extra {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantErr    string
+		wantRoles  []string
+		wantTenant string
+	}{
+		{
+			name:      "roles as list",
+			token:     signJWT(t, hs256, valid(map[string]interface{}{"roles": []string{"reader", "sender"}}), testSecret),
+			wantRoles: []string{"reader", "sender"},
+		},
+		{
+			name:      "roles as space-separated string",
+			token:     signJWT(t, hs256, valid(map[string]interface{}{"roles": "reader sender"}), testSecret),
+			wantRoles: []string{"reader", "sender"},
+		},
+		{
+			name:       "tenant claim",
+			token:      signJWT(t, hs256, valid(map[string]interface{}{"roles": "reader", "tenant_id": " acme "}), testSecret),
+			wantRoles:  []string{"reader"},
+			wantTenant: "acme",
+		},
+		{
+			name:    "alg none",
+			token:   signJWT(t, map[string]interface{}{"alg": "none"}, valid(nil), testSecret),
+			wantErr: "unsupported JWT algorithm",
+		},
+		{
+			name:    "alg none without signature",
+			token:   unsigned(t, map[string]interface{}{"alg": "none"}, valid(nil)),
+			wantErr: "unsupported JWT algorithm",
+		},
+		{
+			name:    "other algorithm",
+			token:   signJWT(t, map[string]interface{}{"alg": "HS512"}, valid(nil), testSecret),
+			wantErr: "unsupported JWT algorithm",
+		},
+		{
+			name:    "wrong secret",
+			token:   signJWT(t, hs256, valid(nil), []byte("other-secret")),
+			wantErr: "invalid JWT signature",
+		},
+		{
+			name:    "tampered claims",
+			token:   tamper(t, signJWT(t, hs256, valid(nil), testSecret), valid(map[string]interface{}{"roles": "admin"})),
+			wantErr: "invalid JWT signature",
+		},
+		{
+			name:    "missing exp",
+			token:   signJWT(t, hs256, map[string]interface{}{"sub": "checkout"}, testSecret),
+			wantErr: "JWT has no exp claim",
+		},
+		{
+			name:    "exp not a number",
+			token:   signJWT(t, hs256, map[string]interface{}{"sub": "checkout", "exp": "tomorrow"}, testSecret),
+			wantErr: "JWT has no exp claim",
+		},
+		{
+			name:    "expired",
+			token:   signJWT(t, hs256, valid(map[string]interface{}{"exp": now.Add(-time.Second).Unix()}), testSecret),
+			wantErr: "JWT has expired",
+		},
+		{
+			name:    "expires now",
+			token:   signJWT(t, hs256, valid(map[string]interface{}{"exp": now.Unix()}), testSecret),
+			wantErr: "JWT has expired",
+		},
+		{
+			name:  "nbf within skew",
+			token: signJWT(t, hs256, valid(map[string]interface{}{"nbf": now.Add(jwtClockSkew).Unix()}), testSecret),
+		},
+		{
+			name:    "nbf beyond skew",
+			token:   signJWT(t, hs256, valid(map[string]interface{}{"nbf": now.Add(jwtClockSkew + time.Second).Unix()}), testSecret),
+			wantErr: "JWT is not valid yet",
+		},
+		{
+			name:  "iat within skew",
+			token: signJWT(t, hs256, valid(map[string]interface{}{"iat": now.Add(jwtClockSkew).Unix()}), testSecret),
+		},
+		{
+			name:    "iat beyond skew",
+			token:   signJWT(t, hs256, valid(map[string]interface{}{"iat": now.Add(time.Minute).Unix()}), testSecret),
+			wantErr: "JWT is issued in the future",
+		},
+		{
+			name:    "iat not a number",
+			token:   signJWT(t, hs256, valid(map[string]interface{}{"iat": "now"}), testSecret),
+			wantErr: "JWT is issued in the future",
+		},
+		{
+			name:    "malformed header",
+			token:   "!!!.e30.c2ln",
+			wantErr: "malformed JWT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := verifyJWT(tt.token, testSecret, "roles", "tenant_id", now)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyJWT: %v", err)
+			}
+			if caller.Name != "checkout" || !slices.Equal(caller.Roles, tt.wantRoles) || caller.Tenant != tt.wantTenant {
+				t.Errorf("caller = %+v, want checkout with roles %v and tenant %q", caller, tt.wantRoles, tt.wantTenant)
+			}
+		})
+	}
+}
+
+// unsigned builds a token with an empty signature, as alg=none tokens have.
+func unsigned(t *testing.T, header, claims map[string]interface{}) string {
+	t.Helper()
+	token := signJWT(t, header, claims, testSecret)
+	return token[:strings.LastIndex(token, ".")+1]
+}
+
+// tamper swaps a signed token's claims, keeping its signature.
+func tamper(t *testing.T, token string, claims map[string]interface{}) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	forged := strings.Split(signJWT(t, map[string]interface{}{}, claims, testSecret), ".")
+	return parts[0] + "." + forged[1] + "." + parts[2]
+}
@@ -0,0 +1,171 @@
+package rbac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Roles used by the shipped policies. Policies may define others; RoleAdmin
+// is allowed on every route whatever the rules say.
+const (
+	RoleReader  = "reader"
+	RoleSender  = "sender"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// policyFile is the JSON layout of RBAC_POLICY_FILE.
+type policyFile struct {
+	// Keys are API keys by SHA-256 (hex), so the file holds no secrets
+	Keys []struct {
+		Name   string   `json:"name"`
+		SHA256 string   `json:"sha256"`
+		Roles  []string `json:"roles"`
+		// Tenant, when set, binds the key to that tenant's data
+		Tenant string `json:"tenant"`
+	} `json:"keys"`
+	// JWTRolesClaim names the claim carrying a token's roles (default "roles")
+	JWTRolesClaim string `json:"jwt_roles_claim"`
+	// JWTTenantClaim names the claim binding a token to a tenant (default
+	// "tenant_id")
+	JWTTenantClaim string `json:"jwt_tenant_claim"`
+	Rules          []rule `json:"rules"`
+	// DefaultRoles apply to routes no rule matches (default admin only)
+	DefaultRoles []string `json:"default_roles"`
+}
+
+// rule grants the listed roles access to a route. Path is a route template as
+// registered (e.g. /v1/user/{user_id}/messages) or a prefix ending in /*;
+// no methods means all. Public rules need no credentials.
+type rule struct {
+	Methods []string `json:"methods"`
+	Path    string   `json:"path"`
+	Roles   []string `json:"roles"`
+	Public  bool     `json:"public"`
+}
+
+// principal is an authenticated caller. Tenant, when set, is the only tenant
+// the caller may act for.
+type principal struct {
+	Name   string
+	Roles  []string
+	Tenant string
+}
+
+type policy struct {
+	keys         map[[sha256.Size]byte]principal
+	rolesClaim   string
+	tenantClaim  string
+	rules        []rule
+	defaultRules rule
+}
+
+// loadPolicy reads and validates a policy file. adminToken, when set, is
+// accepted as an admin key.
+func loadPolicy(path, adminToken string) (*policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file policyFile
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	p := &policy{
+		keys:         map[[sha256.Size]byte]principal{},
+		rolesClaim:   file.JWTRolesClaim,
+		tenantClaim:  file.JWTTenantClaim,
+		rules:        file.Rules,
+		defaultRules: rule{Roles: file.DefaultRoles},
+	}
+	if p.rolesClaim == "" {
+		p.rolesClaim = "roles"
+	}
+	if p.tenantClaim == "" {
+		p.tenantClaim = "tenant_id"
+	}
+	if len(p.defaultRules.Roles) == 0 {
+		p.defaultRules.Roles = []string{RoleAdmin}
+	}
+
+	for i, key := range file.Keys {
+		digest, err := hex.DecodeString(key.SHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("key %d (%s): sha256 must be 64 hex characters", i, key.Name)
+		}
+		if len(key.Roles) == 0 {
+			return nil, fmt.Errorf("key %d (%s): roles must not be empty", i, key.Name)
+		}
+		p.keys[[sha256.Size]byte(digest)] = principal{Name: key.Name, Roles: key.Roles, Tenant: strings.TrimSpace(key.Tenant)}
+	}
+	if adminToken != "" {
+		p.keys[sha256.Sum256([]byte(adminToken))] = principal{Name: "ADMIN_API_TOKEN", Roles: []string{RoleAdmin}}
+	}
+
+	for i := range p.rules {
+		r := &p.rules[i]
+		if !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("rule %d: path must start with /", i)
+		}
+		if !r.Public && len(r.Roles) == 0 {
+			return nil, fmt.Errorf("rule %d (%s): roles must not be empty unless the rule is public", i, r.Path)
+		}
+		for j, method := range r.Methods {
+			r.Methods[j] = strings.ToUpper(method)
+		}
+	}
+	return p, nil
+}
+
+// match returns the first rule covering method and the route template, or
+// the default rule. HEAD requests are covered by the rules for GET, as they
+// reveal the same data.
+func (p *policy) match(method, template string) rule {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	for _, r := range p.rules {
+		if len(r.Methods) > 0 && !slices.Contains(r.Methods, method) {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+			if strings.HasPrefix(template, prefix) {
+				return r
+			}
+		} else if r.Path == template {
+			return r
+		}
+	}
+	return p.defaultRules
+}
+
+// permits reports whether caller holds one of the rule's roles.
+func (r rule) permits(caller principal) bool {
+	for _, role := range caller.Roles {
+		if role == RoleAdmin || slices.Contains(r.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+var errUnknownKey = errors.New("unknown API key")
+
+func (p *policy) lookupKey(key string) (principal, error) {
+	caller, ok := p.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return principal{}, errUnknownKey
+	}
+	return caller, nil
+}
@@ -0,0 +1,144 @@
+package rbac
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writePolicy writes a policy file holding the given JSON and loads it.
+func writePolicy(t *testing.T, raw string, adminToken string) *policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	p, err := loadPolicy(path, adminToken)
+	if err != nil {
+		t.Fatalf("loadPolicy: %v", err)
+	}
+	return p
+}
+
+func keyDigest(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+func TestPolicyMatch(t *testing.T) {
+	p := writePolicy(t, `{
+		"rules": [
+			{"path": "/metrics", "public": true},
+			{"methods": ["get"], "path": "/v1/user/*", "roles": ["reader"]},
+			{"methods": ["DELETE"], "path": "/v1/user/{user_id}/messages/{message_id}", "roles": ["support"]},
+			{"path": "/v1/analytics/messages", "roles": ["analyst"]}
+		],
+		"default_roles": ["operator"]
+	}`, "")
+
+	tests := []struct {
+		name      string
+		method    string
+		template  string
+		wantRoles []string
+		wantOpen  bool
+	}{
+		{name: "public rule", method: http.MethodGet, template: "/metrics", wantOpen: true},
+		{name: "prefix", method: http.MethodGet, template: "/v1/user/{user_id}/messages", wantRoles: []string{"reader"}},
+		{name: "prefix nested", method: http.MethodGet, template: "/v1/user/{user_id}/conversations/{sender_id}/messages", wantRoles: []string{"reader"}},
+		// HEAD reveals what GET does, so GET's rules apply
+		{name: "head maps to get", method: http.MethodHead, template: "/v1/user/{user_id}/messages", wantRoles: []string{"reader"}},
+		{name: "method skips rule", method: http.MethodDelete, template: "/v1/user/{user_id}/messages/{message_id}", wantRoles: []string{"support"}},
+		{name: "no methods means all", method: http.MethodPost, template: "/v1/analytics/messages", wantRoles: []string{"analyst"}},
+		{name: "exact path only", method: http.MethodGet, template: "/v1/analytics/timeseries", wantRoles: []string{"operator"}},
+		{name: "unmatched method falls to default", method: http.MethodPost, template: "/v1/user/{user_id}/messages/{message_id}/restore", wantRoles: []string{"operator"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.match(tt.method, tt.template)
+			if got.Public != tt.wantOpen {
+				t.Fatalf("public = %t, want %t", got.Public, tt.wantOpen)
+			}
+			if !slices.Equal(got.Roles, tt.wantRoles) {
+				t.Errorf("roles = %v, want %v", got.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestPolicyDefaultsToAdmin(t *testing.T) {
+	p := writePolicy(t, `{"rules": []}`, "")
+	required := p.match(http.MethodGet, "/v1/user/{user_id}/messages")
+	if len(required.Roles) != 1 || required.Roles[0] != RoleAdmin {
+		t.Fatalf("default roles = %v, want admin only", required.Roles)
+	}
+	if required.permits(principal{Roles: []string{RoleReader}}) {
+		t.Error("reader permitted by the admin-only default")
+	}
+	if !required.permits(principal{Roles: []string{RoleAdmin}}) {
+		t.Error("admin refused")
+	}
+}
+
+func TestPolicyKeys(t *testing.T) {
+	p := writePolicy(t, `{
+		"keys": [
+			{"name": "dashboard", "sha256": "`+keyDigest("dashboard-key")+`", "roles": ["reader"]},
+			{"name": "acme-portal", "sha256": "`+keyDigest("acme-key")+`", "roles": ["reader"], "tenant": " acme "}
+		]
+	}`, "admin-token")
+
+	tests := []struct {
+		key        string
+		wantName   string
+		wantTenant string
+		wantErr    bool
+	}{
+		{key: "dashboard-key", wantName: "dashboard"},
+		{key: "acme-key", wantName: "acme-portal", wantTenant: "acme"},
+		{key: "admin-token", wantName: "ADMIN_API_TOKEN"},
+		{key: "unknown-key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			caller, err := p.lookupKey(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("lookupKey(%q) = %+v, want an error", tt.key, caller)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lookupKey: %v", err)
+			}
+			if caller.Name != tt.wantName || caller.Tenant != tt.wantTenant {
+				t.Errorf("caller = %+v, want %s with tenant %q", caller, tt.wantName, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":      `{"rulez": []}`,
+		"short digest":       `{"keys": [{"name": "k", "sha256": "abc", "roles": ["reader"]}]}`,
+		"key without roles":  `{"keys": [{"name": "k", "sha256": "` + keyDigest("k") + `", "roles": []}]}`,
+		"relative path":      `{"rules": [{"path": "v1/user/*", "roles": ["reader"]}]}`,
+		"rule without roles": `{"rules": [{"path": "/v1/user/*"}]}`,
+	}
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+				t.Fatalf("write policy: %v", err)
+			}
+			if _, err := loadPolicy(path, ""); err == nil {
+				t.Fatal("loadPolicy accepted an invalid policy")
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"smsstore/internal/middleware"
+	"smsstore/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// useTestPolicy installs p and the test JWT secret for Authorize, restoring
+// the previous ones when the test ends.
+func useTestPolicy(t *testing.T, p *policy) {
+	t.Helper()
+	mu.Lock()
+	previous, previousSecret := current, jwtSecret
+	current, jwtSecret = p, testSecret
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		current, jwtSecret = previous, previousSecret
+		mu.Unlock()
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	useTestPolicy(t, writePolicy(t, `{
+		"keys": [
+			{"name": "dashboard", "sha256": "`+keyDigest("dashboard-key")+`", "roles": ["reader"]},
+			{"name": "acme-portal", "sha256": "`+keyDigest("acme-key")+`", "roles": ["reader"], "tenant": "acme"}
+		],
+		"rules": [
+			{"path": "/metrics", "public": true},
+			{"methods": ["GET"], "path": "/v1/user/*", "roles": ["reader"]}
+		]
+	}`, "admin-token"))
+
+	// The handler reports who it ran as and for which tenant
+	router := mux.NewRouter()
+	router.Use(Authorize)
+	seen := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"caller": middleware.CallerFromContext(r.Context()),
+			"tenant": repository.TenantFrom(r.Context()),
+			"header": r.Header.Get(middleware.TenantHeader),
+		})
+	}
+	router.HandleFunc("/metrics", seen).Methods("GET")
+	router.HandleFunc("/v1/user/{user_id}/messages", seen).Methods("GET", "DELETE")
+	router.HandleFunc("/v1/admin/users", seen).Methods("GET")
+
+	readerToken := signJWT(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{
+		"sub": "support-bot", "roles": "reader", "tenant_id": "globex", "exp": time.Now().Add(time.Hour).Unix(),
+	}, testSecret)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		bearer       string
+		tenantHeader string
+		wantStatus   int
+		wantCaller   string
+		wantTenant   string
+	}{
+		{name: "public route without credentials", method: "GET", path: "/metrics", wantStatus: http.StatusOK},
+		{name: "no credentials", method: "GET", path: "/v1/user/u1/messages", wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", method: "GET", path: "/v1/user/u1/messages", bearer: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "permitted key", method: "GET", path: "/v1/user/u1/messages", bearer: "dashboard-key", wantStatus: http.StatusOK, wantCaller: "dashboard"},
+		{name: "role not permitted", method: "DELETE", path: "/v1/user/u1/messages", bearer: "dashboard-key", wantStatus: http.StatusForbidden},
+		{name: "default rule is admin only", method: "GET", path: "/v1/admin/users", bearer: "dashboard-key", wantStatus: http.StatusForbidden},
+		{name: "admin token", method: "GET", path: "/v1/admin/users", bearer: "admin-token", wantStatus: http.StatusOK, wantCaller: "ADMIN_API_TOKEN"},
+		{name: "unbound caller keeps header", method: "GET", path: "/v1/user/u1/messages", bearer: "dashboard-key", tenantHeader: "initech", wantStatus: http.StatusOK, wantCaller: "dashboard"},
+		{name: "bound key without header", method: "GET", path: "/v1/user/u1/messages", bearer: "acme-key", wantStatus: http.StatusOK, wantCaller: "acme-portal", wantTenant: "acme"},
+		{name: "bound key with own tenant", method: "GET", path: "/v1/user/u1/messages", bearer: "acme-key", tenantHeader: "acme", wantStatus: http.StatusOK, wantCaller: "acme-portal", wantTenant: "acme"},
+		{name: "bound key with other tenant", method: "GET", path: "/v1/user/u1/messages", bearer: "acme-key", tenantHeader: "globex", wantStatus: http.StatusForbidden},
+		{name: "bound token", method: "GET", path: "/v1/user/u1/messages", bearer: readerToken, wantStatus: http.StatusOK, wantCaller: "support-bot", wantTenant: "globex"},
+		{name: "bound token with other tenant", method: "GET", path: "/v1/user/u1/messages", bearer: readerToken, tenantHeader: "acme", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.tenantHeader != "" {
+				req.Header.Set(middleware.TenantHeader, tt.tenantHeader)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got map[string]string
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got["caller"] != tt.wantCaller {
+				t.Errorf("caller = %q, want %q", got["caller"], tt.wantCaller)
+			}
+			// Bound callers are scoped to their tenant, header included, so
+			// handlers reading either agree
+			if tt.wantTenant != "" && (got["tenant"] != tt.wantTenant || got["header"] != tt.wantTenant) {
+				t.Errorf("tenant = %q (header %q), want %q", got["tenant"], got["header"], tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package readiness tracks startup progress for the readiness probe. The
+// service starts serving probes before its dependencies are reachable and
+// waits for them with backoff, so an orchestrator starting MongoDB or Kafka
+// after the app sees a pod that is not ready yet rather than one that crashes.
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+)
+
+// maxBackoff caps the delay between attempts at a dependency.
+const maxBackoff = 30 * time.Second
+
+var (
+	mu           sync.Mutex
+	ready        bool
+	dependencies []*models.DependencyStatus
+)
+
+// Wait calls check until it succeeds, backing off from backoff (doubling, up
+// to 30s) between attempts and recording progress under name. Returns the
+// last error once maxWait has passed, or ctx's error if it is cancelled first.
+func Wait(ctx context.Context, name string, maxWait, backoff time.Duration, check func(ctx context.Context) error) error {
+	dependency := &models.DependencyStatus{Name: name, Since: time.Now().UTC()}
+	mu.Lock()
+	dependencies = append(dependencies, dependency)
+	mu.Unlock()
+
+	deadline := time.Now().Add(maxWait)
+	for {
+		err := check(ctx)
+
+		mu.Lock()
+		dependency.Attempts++
+		dependency.LastError = ""
+		if err != nil {
+			dependency.LastError = err.Error()
+		} else {
+			dependency.Ready = true
+		}
+		attempts := dependency.Attempts
+		mu.Unlock()
+
+		if err == nil {
+			if attempts > 1 {
+				log.Printf("[STARTUP] %s is reachable after %d attempts", name, attempts)
+			}
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("%s not reachable after %s (%d attempts): %w", name, maxWait, attempts, err)
+		}
+		log.Printf("[STARTUP] Waiting for %s (attempt %d, retrying in %s): %v", name, attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// SetReady marks startup as finished. Call once every dependency has been
+// waited for and the service is processing work.
+func SetReady() {
+	mu.Lock()
+	defer mu.Unlock()
+	ready = true
+}
+
+// Status returns whether startup has finished and each dependency's progress.
+func Status() models.ReadinessStatus {
+	mu.Lock()
+	defer mu.Unlock()
+	status := models.ReadinessStatus{Status: "starting", Dependencies: make([]models.DependencyStatus, 0, len(dependencies))}
+	if ready {
+		status.Status = "ready"
+	}
+	for _, dependency := range dependencies {
+		status.Dependencies = append(status.Dependencies, *dependency)
+	}
+	return status
+}
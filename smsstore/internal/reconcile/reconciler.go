@@ -0,0 +1,219 @@
+// Package reconcile catches up on lost delivery reports. Sends that have been
+// sent for longer than their provider's RECONCILE_STALE_AFTER without a final
+// status are looked up in the provider's status API, and a final status found
+// there is published to KAFKA_TOPIC as the send's next event, so it is stored,
+// forwarded and delivered to webhooks like a late delivery report.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"smsstore/internal/config"
+	"smsstore/internal/kafkawriter"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/events"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// usersPerBatch bounds how many user documents a single pass reads per
+// provider and database.
+const usersPerBatch = 200
+
+// Statuses are the statuses of sends the provider accepted and has not
+// reported on since.
+var Statuses = []string{"sent", "successful"}
+
+// finalStatuses maps the final statuses of provider status APIs to the ones
+// stored; anything else means the provider doesn't know the outcome yet.
+var finalStatuses = map[string]string{
+	"delivered":   "delivered",
+	"undelivered": "undelivered",
+	"expired":     "undelivered",
+	"failed":      "failed",
+	"rejected":    "failed",
+}
+
+// errNotFound is returned by lookUp when the provider doesn't know the message.
+var errNotFound = errors.New("message not found")
+
+// Start periodically reconciles the stale sends of every provider with a
+// status URL. Every replica runs it; marking a message before looking it up
+// keeps two replicas from looking it up twice. Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if len(cfg.ReconcileStatusURLs) == 0 {
+		log.Println("[RECONCILE] Status reconciliation disabled (no RECONCILE_STATUS_URLS)")
+		return
+	}
+	if cfg.DevMode {
+		log.Println("[RECONCILE] Status reconciliation disabled (DEV_MODE's simulated provider has no status API)")
+		return
+	}
+
+	writer := kafkawriter.New(cfg, cfg.KafkaTopic, 50*time.Millisecond)
+	defer writer.Close()
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	log.Printf("[RECONCILE] Status reconciliation started: interval=%s, max age=%s", cfg.ReconcileInterval, cfg.ReconcileMaxAge)
+	ticker := time.NewTicker(cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		runOnce(ctx, cfg, repo, client, writer)
+		select {
+		case <-ctx.Done():
+			log.Println("[RECONCILE] Status reconciliation stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, cfg *config.Config, repo *repository.Repository, client *http.Client, writer *kafka.Writer) {
+	for provider, statusURL := range cfg.ReconcileStatusURLs {
+		now := time.Now().UTC()
+		since := now.Add(-cfg.ReconcileMaxAge)
+		before := now.Add(-cfg.ReconcileStaleAfterFor(provider))
+		found := 0
+		for _, scope := range repo.Scopes() {
+			found += reconcileScope(scope.Context(ctx), cfg, repo, client, writer, scope, provider, statusURL, since, before)
+		}
+		metrics.ReconcileStaleMessages.WithLabelValues(provider).Set(float64(found))
+	}
+}
+
+// reconcileScope looks up the provider's stale sends in one scope and returns
+// how many it found.
+func reconcileScope(ctx context.Context, cfg *config.Config, repo *repository.Repository, client *http.Client, writer *kafka.Writer, scope repository.Scope,
+	provider string, statusURL string, since time.Time, before time.Time) int {
+	stale, err := repo.FindStaleSends(ctx, provider, Statuses, since, before, usersPerBatch)
+	if err != nil {
+		log.Printf("[RECONCILE] Failed to find stale %s sends%s: %v", provider, scope.Label(), err)
+		return 0
+	}
+
+	// A send's sent and successful events are looked up once
+	seen := map[string]bool{}
+	for _, send := range stale {
+		if ctx.Err() != nil {
+			break
+		}
+		message := send.Message
+		key := send.UserID + "/" + repository.SendKey(message)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		claimed, err := repo.MarkMessageReconciled(ctx, send.UserID, message.MessageID, before)
+		if err != nil {
+			log.Printf("[RECONCILE] Failed to mark message %s of %s: %v", message.MessageID, send.UserID, err)
+			continue
+		}
+		if !claimed {
+			// Looked up by another replica in the meantime
+			continue
+		}
+
+		status, err := lookUp(ctx, client, statusURL, message.ProviderMessageID)
+		if errors.Is(err, errNotFound) {
+			metrics.ReconciledMessages.WithLabelValues(provider, "not_found").Inc()
+			continue
+		}
+		if err != nil {
+			metrics.ReconciledMessages.WithLabelValues(provider, "error").Inc()
+			log.Printf("[RECONCILE] Failed to look up %s message %s: %v", provider, message.ProviderMessageID, err)
+			continue
+		}
+		final, ok := finalStatuses[status]
+		if !ok {
+			metrics.ReconciledMessages.WithLabelValues(provider, "pending").Inc()
+			continue
+		}
+		if err := publish(ctx, cfg, writer, scope, send, final); err != nil {
+			metrics.ReconciledMessages.WithLabelValues(provider, "error").Inc()
+			log.Printf("[RECONCILE] Failed to publish status %s of message %s of %s: %v", final, message.MessageID, send.UserID, err)
+			continue
+		}
+		metrics.ReconciledMessages.WithLabelValues(provider, final).Inc()
+		log.Printf("[RECONCILE] Message %s of %s reconciled as %s", message.MessageID, send.UserID, final)
+	}
+	return len(seen)
+}
+
+// lookUp returns the provider's status of a message, lower-cased, from its
+// status API: statusURL with {id} replaced by the provider message ID,
+// answering {"status": "..."}.
+func lookUp(ctx context.Context, client *http.Client, statusURL string, providerMessageID string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.ReplaceAll(statusURL, "{id}", url.PathEscape(providerMessageID)), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return "", errNotFound
+	}
+	if response.StatusCode/100 != 2 {
+		return "", fmt.Errorf("status API answered %s", response.Status)
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding status API response: %w", err)
+	}
+	return strings.ToLower(strings.TrimSpace(body.Status)), nil
+}
+
+// publish stores status as the send's next event, through the consumer. The
+// idempotency key makes a status published twice (e.g. by a pass that failed
+// after publishing) stored once.
+func publish(ctx context.Context, cfg *config.Config, writer *kafka.Writer, scope repository.Scope, send repository.StaleSend, status string) error {
+	message := send.Message
+	payload, err := json.Marshal(models.SmsEvent{
+		SchemaVersion:     events.SchemaVersion,
+		PhoneNumber:       send.UserID,
+		Message:           message.Message,
+		Status:            status,
+		Provider:          message.Provider,
+		ProviderMessageID: message.ProviderMessageID,
+		CampaignID:        message.CampaignID,
+		TemplateID:        message.TemplateID,
+		Variant:           message.Variant,
+		CountryCode:       message.CountryCode,
+		Metadata:          message.Metadata,
+		IdempotencyKey:    "reconcile:" + message.ProviderMessageID + ":" + status,
+		TenantID:          message.TenantID,
+		Category:          message.Category,
+		SenderID:          message.SenderID,
+		SendID:            repository.SendKey(message),
+		Attempt:           message.Attempt,
+	})
+	if err != nil {
+		return err
+	}
+
+	record := kafka.Message{Key: []byte(send.UserID), Value: payload}
+	// The consumer stores the event in the database it was found in
+	if cfg.KafkaRegionHeader != "" {
+		record.Headers = []kafka.Header{{Key: cfg.KafkaRegionHeader, Value: []byte(scope.Region)}}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return writer.WriteMessages(ctx, record)
+}
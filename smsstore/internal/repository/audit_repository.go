@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// auditCollection holds the audit trail of access to unmasked user data. It
+// stays in the application database whatever the user's region or tenant, so
+// the whole trail can be reviewed in one place.
+const auditCollection = "audit_log"
+
+// InsertAuditEntry appends an entry to the audit trail, giving it an ID.
+func (r *Repository) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) (err error) {
+	defer r.observe(ctx, "InsertAuditEntry", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, auditCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	entry.ID = primitive.NewObjectID().Hex()
+	_, err = collection.InsertOne(ctx, entry)
+	return err
+}
+
+// ListAuditEntries returns up to limit audit entries, newest first,
+// optionally only those about userID.
+func (r *Repository) ListAuditEntries(ctx context.Context, userID string, limit int64) (_ []models.AuditEntry, err error) {
+	defer r.observe(ctx, "ListAuditEntries", time.Now(), &err)
+	collection, err := r.getCollection(ctx, auditCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"smsstore/internal/config"
+	"smsstore/internal/db"
+	"smsstore/pkg/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// These benchmarks run against a real MongoDB to catch storage regressions
+// before release:
+//
+//	go test -run '^$' -bench . ./internal/repository
+//
+// A throwaway mongo container is started through Docker (dockertest) on first
+// use; set BENCH_MONGO_URI to use an existing server instead. The database
+// they write to is dropped before each benchmark. Without either, they skip.
+
+const benchMongoImage = "8.0"
+
+var (
+	mongoOnce     sync.Once
+	mongoErr      error
+	mongoPool     *dockertest.Pool
+	mongoResource *dockertest.Resource
+	// bench is the repository on the benchmark server
+	bench *Repository
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if mongoResource != nil {
+		if err := mongoPool.Purge(mongoResource); err != nil {
+			log.Printf("Failed to remove benchmark mongo container: %v", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// benchDatabase connects the repository to the benchmark server and returns
+// its database, emptied.
+func benchDatabase(b *testing.B) *mongo.Database {
+	b.Helper()
+	mongoOnce.Do(func() { mongoErr = startMongo() })
+	if mongoErr != nil {
+		b.Skipf("no MongoDB for benchmarks: %v", mongoErr)
+	}
+	database, err := bench.Database(Scope{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := database.Drop(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	return database
+}
+
+func startMongo() error {
+	uri := os.Getenv("BENCH_MONGO_URI")
+	if uri == "" {
+		pool, err := dockertest.NewPool("")
+		if err != nil {
+			return err
+		}
+		if err := pool.Client.Ping(); err != nil {
+			return err
+		}
+		resource, err := pool.Run("mongo", benchMongoImage, nil)
+		if err != nil {
+			return err
+		}
+		resource.Expire(600) // reaped even if the run is killed
+		mongoPool, mongoResource = pool, resource
+		uri = "mongodb://localhost:" + resource.GetPort("27017/tcp")
+
+		pool.MaxWait = time.Minute
+		err = pool.Retry(func() error {
+			client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+			if err != nil {
+				return err
+			}
+			defer client.Disconnect(context.Background())
+			return client.Ping(context.Background(), nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The repository is configured from the same environment as the service
+	os.Setenv("MONGO_URI", uri)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	bench = New(cfg, db.New(cfg))
+	_, err = bench.clients.Client()
+	return err
+}
+
+func benchEvent(user int) models.SmsEvent {
+	return models.SmsEvent{
+		PhoneNumber: fmt.Sprintf("+1555%07d", user),
+		Message:     "Your order OD-1001 has been shipped and will arrive by Friday.",
+		Status:      "successful",
+		Provider:    "twilio",
+		CampaignID:  "bench",
+		CountryCode: "US",
+		Metadata:    map[string]string{"order_id": "OD-1001"},
+	}
+}
+
+// benchUsers spreads inserts over enough users that documents stay small.
+const benchUsers = 1000
+
+// BenchmarkInsert compares storing messages one event at a time, as the
+// consumer does, with pushing them to a user in batches. ns/op is per message.
+func BenchmarkInsert(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("AddMessageToUser", func(b *testing.B) {
+		benchDatabase(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := bench.AddMessageToUser(ctx, benchEvent(i%benchUsers)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, batch := range []int{10, 100} {
+		b.Run(fmt.Sprintf("BulkPush/batch=%d", batch), func(b *testing.B) {
+			database := benchDatabase(b)
+			collection := database.Collection(smsDataCollection)
+			b.ResetTimer()
+			for i := 0; i < b.N; i += batch {
+				event := benchEvent((i / batch) % benchUsers)
+				messages := make([]models.MessageWithStatus, 0, batch)
+				for j := 0; j < batch && i+j < b.N; j++ {
+					messages = append(messages, newMessage(event))
+				}
+				_, err := collection.UpdateOne(ctx,
+					bson.M{"_id": event.PhoneNumber},
+					bson.M{"$push": bson.M{"messages": bson.M{"$each": messages}}},
+					options.Update().SetUpsert(true))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetUserMessages compares listing a user whose messages are
+// embedded in the user document with one whose messages were compacted into
+// the per-message collection.
+func BenchmarkGetUserMessages(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{100, 1000, 5000} {
+		for _, layout := range []string{"embedded", "compacted"} {
+			b.Run(fmt.Sprintf("%s/messages=%d", layout, size), func(b *testing.B) {
+				database := benchDatabase(b)
+				event := benchEvent(0)
+				seedUser(b, database.Collection(smsDataCollection), event, size)
+				if layout == "compacted" {
+					if _, err := bench.CompactUser(ctx, event.PhoneNumber); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					messages, err := bench.GetUserMessages(ctx, event.PhoneNumber, MessageQuery{Descending: true})
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(messages) != size {
+						b.Fatalf("listed %d messages, want %d", len(messages), size)
+					}
+				}
+			})
+		}
+	}
+}
+
+// seedUser stores count messages for the event's user in one update.
+func seedUser(b *testing.B, collection *mongo.Collection, event models.SmsEvent, count int) {
+	b.Helper()
+	messages := make([]models.MessageWithStatus, count)
+	for i := range messages {
+		messages[i] = newMessage(event)
+	}
+	_, err := collection.UpdateOne(context.Background(),
+		bson.M{"_id": event.PhoneNumber},
+		bson.M{"$push": bson.M{"messages": bson.M{"$each": messages}}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"log"
+	"slices"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/pkg/models"
+
+	"github.com/klauspost/compress/zstd"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Message bodies are stored as BSON strings, or with MESSAGE_BODY_ENCODING=zstd
+// as compressed binary in body_zstd when they are long enough for it to pay
+// off: promotional campaigns send the same long text to many users. Only the
+// write path looks at the setting; every read path restores compressed bodies,
+// whatever the setting is now.
+
+// bodyZstdField is where compressed bodies are stored, in place of "message".
+const bodyZstdField = "body_zstd"
+
+var (
+	// EncodeAll and DecodeAll are safe for concurrent use
+	bodyEncoder, _ = zstd.NewWriter(nil)
+	bodyDecoder, _ = zstd.NewReader(nil)
+)
+
+// bodyCompressionFrom returns the shortest body stored compressed under cfg;
+// zero stores every body as a string.
+func bodyCompressionFrom(cfg *config.Config) int {
+	if cfg.MessageBodyEncoding == "zstd" {
+		return cfg.MessageBodyCompressionMinBytes
+	}
+	return 0
+}
+
+// compressedBody returns body compressed, or nil if it should be stored as
+// a string.
+func (r *Repository) compressedBody(body string) []byte {
+	if r.compressBodiesFrom == 0 || len(body) < r.compressBodiesFrom {
+		return nil
+	}
+	compressed := bodyEncoder.EncodeAll([]byte(body), nil)
+	if len(compressed) >= len(body) {
+		return nil
+	}
+	metrics.CompressedBodyBytes.WithLabelValues("original").Add(float64(len(body)))
+	metrics.CompressedBodyBytes.WithLabelValues("stored").Add(float64(len(compressed)))
+	return compressed
+}
+
+// storedForm is message as written to MongoDB, with its body compressed when
+// the encoding calls for it. message itself, returned to callers, is unchanged.
+func (r *Repository) storedForm(message models.MessageWithStatus) models.MessageWithStatus {
+	if compressed := r.compressedBody(message.Message); compressed != nil {
+		message.BodyZstd = compressed
+		message.Message = ""
+	}
+	return message
+}
+
+// sameBody matches an embedded message ($elemMatch) whose body is body,
+// stored either way.
+func (r *Repository) sameBody(body string) bson.M {
+	if compressed := r.compressedBody(body); compressed != nil {
+		return bson.M{"$or": bson.A{bson.M{"message": body}, bson.M{bodyZstdField: compressed}}}
+	}
+	return bson.M{"message": body}
+}
+
+// inflate restores the bodies of messages stored compressed, in place. A body
+// that can't be decompressed is left empty and logged, rather than failing
+// the whole read.
+func inflate(messages []models.MessageWithStatus) []models.MessageWithStatus {
+	for i := range messages {
+		inflateMessage(&messages[i])
+	}
+	return messages
+}
+
+func inflateMessage(message *models.MessageWithStatus) {
+	if len(message.BodyZstd) == 0 {
+		return
+	}
+	body, err := bodyDecoder.DecodeAll(message.BodyZstd, nil)
+	if err != nil {
+		log.Printf("[BODIES] Failed to decompress body of message %s: %v", message.MessageID, err)
+		metrics.BodyDecompressionErrors.Inc()
+	} else {
+		message.Message = string(body)
+	}
+	message.BodyZstd = nil
+}
+
+// inflateResults is inflate for search and firehose results.
+func inflateResults(results []models.SearchResult) []models.SearchResult {
+	for i := range results {
+		inflateMessage(&results[i].Message)
+	}
+	return results
+}
+
+// withStoredBody adds body_zstd to a field projection asking for the body.
+func withStoredBody(fields []string) []string {
+	if slices.Contains(fields, "message") && !slices.Contains(fields, bodyZstdField) {
+		return append(append([]string{}, fields...), bodyZstdField)
+	}
+	return fields
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Compacted messages live one document per message in the messages collection,
+// keyed by message ID. Users are compacted on demand (see CompactUser) to shrink
+// oversized user documents; new messages still land in the embedded array, and
+// every read, update and purge also covers this collection.
+
+// messageDocument is a compacted message.
+type messageDocument struct {
+	ID                       string `bson:"_id"`
+	UserID                   string `bson:"user_id"`
+	models.MessageWithStatus `bson:",inline"`
+}
+
+// findCompactedMessages returns a user's compacted messages matching query.
+func findCompactedMessages(ctx context.Context, collection *mongo.Collection, userID string, query MessageQuery) ([]models.MessageWithStatus, error) {
+	filter := query.MessageFilter.elementMatch("")
+	filter["user_id"] = userID
+
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: direction}, {Key: "_id", Value: direction}})
+	if len(query.Fields) > 0 {
+		projection := bson.M{"_id": 0}
+		for _, field := range query.Fields {
+			projection[field] = 1
+		}
+		opts.SetProjection(projection)
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var documents []messageDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	messages := make([]models.MessageWithStatus, 0, len(documents))
+	for _, document := range documents {
+		messages = append(messages, document.MessageWithStatus)
+	}
+	return inflate(messages), nil
+}
+
+// summarizeCompactedMessages summarizes a user's compacted messages.
+func summarizeCompactedMessages(ctx context.Context, collection *mongo.Collection, userID string, filter MessageFilter) (messagesSummaryDocument, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": nil,
+			// The listing's conditions, applied to each document as $$m
+			"count": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$let": bson.M{"vars": bson.M{"m": "$$ROOT"}, "in": messageConditions(filter)}}, 1, 0,
+			}}},
+			"documents":     bson.M{"$sum": 1},
+			"version":       bson.M{"$sum": bson.M{"$ifNull": bson.A{"$" + versionField, 0}}},
+			"last_modified": bson.M{"$max": bson.M{"$max": bson.A{"$" + modifiedField, lastChange("$$ROOT")}}},
+		}}},
+	}
+	return summarize(ctx, collection, pipeline)
+}
+
+type embeddedMessages struct {
+	Version  int64                      `bson:"version"`
+	Messages []models.MessageWithStatus `bson:"messages"`
+}
+
+// CompactUser moves a user's embedded messages (from both tiers) into the
+// per-message collection. Messages are inserted before being pulled from the
+// array, and re-inserting an already moved message is ignored, so an
+// interrupted compaction can simply be rerun. The pull only applies if the
+// user document is unchanged since it was read; otherwise the inserted copies
+// are deleted and the tier is compacted again. Returns the number of messages moved.
+func (r *Repository) CompactUser(ctx context.Context, userID string) (_ int, err error) {
+	defer r.observe(ctx, "CompactUser", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classCritical)
+	if err != nil {
+		return 0, err
+	}
+	compacted, err := r.getCollectionFor(ctx, classCritical, messagesCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	moved := 0
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		err := retryOnConflict(ctx, "CompactUser", func() error {
+			count, err := compactTier(ctx, collection, compacted, userID)
+			moved += count
+			return err
+		})
+		if err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// compactTier moves a user's embedded messages in one tier into the
+// per-message collection, or returns errVersionConflict after deleting the
+// copies if the user document changed meanwhile.
+func compactTier(ctx context.Context, collection, compacted *mongo.Collection, userID string) (int, error) {
+	var user embeddedMessages
+	opts := options.FindOne().SetProjection(bson.M{"messages": 1, versionField: 1})
+	err := collection.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments || (err == nil && len(user.Messages) == 0) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return moveToCompacted(ctx, collection, compacted, userID, user)
+}
+
+// moveToCompacted copies user's messages into the per-message collection and
+// pulls them from collection, or returns errVersionConflict after deleting the
+// copies if the user document changed since user was read.
+func moveToCompacted(ctx context.Context, collection, compacted *mongo.Collection, userID string, user embeddedMessages) (int, error) {
+	documents := make([]interface{}, 0, len(user.Messages))
+	ids := make([]string, 0, len(user.Messages))
+	documentIDs := make([]string, 0, len(user.Messages))
+	for _, message := range user.Messages {
+		if message.MessageID == "" {
+			// Messages stored before IDs existed get one now
+			message.MessageID = primitive.NewObjectID().Hex()
+		} else {
+			ids = append(ids, message.MessageID)
+		}
+		documentIDs = append(documentIDs, message.MessageID)
+		documents = append(documents, messageDocument{ID: message.MessageID, UserID: userID, MessageWithStatus: message})
+	}
+	_, err := compacted.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeyErrors(err) {
+		return 0, err
+	}
+
+	// Only pull what was copied; messages appended meanwhile stay embedded
+	pull := bson.M{"$or": bson.A{
+		bson.M{"message_id": bson.M{"$in": ids}},
+		bson.M{"message_id": bson.M{"$exists": false}},
+	}}
+	result, err := collection.UpdateOne(ctx, atVersion(userID, user.Version), versioned(bson.M{"$pull": bson.M{"messages": pull}}))
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount > 0 {
+		return len(documents), nil
+	}
+
+	// The copies are stale (or the user is gone); the originals are still embedded
+	if _, err := compacted.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": documentIDs}, "user_id": userID}); err != nil {
+		return 0, err
+	}
+	return 0, errVersionConflict
+}
+
+// onlyDuplicateKeyErrors reports whether every write error in a bulk insert is
+// a duplicate key, i.e. the documents were already there.
+func onlyDuplicateKeyErrors(err error) bool {
+	bulkErr, ok := err.(mongo.BulkWriteException)
+	if !ok || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+// purgeCopies deletes the copies of purged messages kept outside the tiers
+// (shadow writes and archived status events), selected by the filter used on
+// the compacted collection.
+func (r *Repository) purgeCopies(ctx context.Context, filter bson.M) error {
+	if err := r.purgeShadow(ctx, filter); err != nil {
+		return err
+	}
+	return r.purgeStatusArchive(ctx, filter)
+}
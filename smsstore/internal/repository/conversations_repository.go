@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type conversationCount struct {
+	SenderID string                   `bson:"_id"`
+	Count    int                      `bson:"count"`
+	Unread   []string                 `bson:"unread"`
+	Last     models.MessageWithStatus `bson:"last"`
+}
+
+// ListConversations groups a user's visible messages by sender across both
+// tiers and the compacted collection, with each sender's latest message and
+// count of delivered messages without a read receipt. Messages without a sender are grouped under
+// models.DefaultSenderID. Conversations are ordered by latest message, newest first.
+func (r *Repository) ListConversations(ctx context.Context, userID string) (_ []models.Conversation, err error) {
+	defer r.observe(ctx, "ListConversations", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classAnalytics)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": userID}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: bson.M{"messages.deleted_at": bson.M{"$exists": false}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$messages"}}},
+	}
+	pipeline = append(pipeline, groupBySender()...)
+	var counts []conversationCount
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var tierCounts []conversationCount
+		if err := cursor.All(ctx, &tierCounts); err != nil {
+			return nil, err
+		}
+		counts = append(counts, tierCounts...)
+	}
+
+	compacted, err := r.getCollectionFor(ctx, classAnalytics, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := compacted.Aggregate(ctx, append(mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": bson.M{"$exists": false}}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "user_id": 0}}},
+	}, groupBySender()...))
+	if err != nil {
+		return nil, err
+	}
+	var compactedCounts []conversationCount
+	if err := cursor.All(ctx, &compactedCounts); err != nil {
+		return nil, err
+	}
+	counts = append(counts, compactedCounts...)
+
+	bySender := map[string]*models.Conversation{}
+	unread := map[string]map[string]bool{}
+	for _, count := range counts {
+		conversation, ok := bySender[count.SenderID]
+		if !ok {
+			conversation = &models.Conversation{SenderID: count.SenderID, LastMessage: count.Last}
+			bySender[count.SenderID] = conversation
+			unread[count.SenderID] = map[string]bool{}
+		}
+		conversation.MessageCount += count.Count
+		addUnread(unread[count.SenderID], count.Unread)
+		if count.Last.CreatedAt.After(conversation.LastMessage.CreatedAt) {
+			conversation.LastMessage = count.Last
+		}
+	}
+	conversations := make([]models.Conversation, 0, len(bySender))
+	for senderID, conversation := range bySender {
+		conversation.UnreadCount = len(unread[senderID])
+		conversations = append(conversations, *conversation)
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		a, b := conversations[i].LastMessage, conversations[j].LastMessage
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.MessageID > b.MessageID
+	})
+	return conversations, nil
+}
+
+// groupBySender groups message documents by sender, keeping the latest message.
+func groupBySender() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "message_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$ifNull": bson.A{"$sender_id", models.DefaultSenderID}},
+			"count":  bson.M{"$sum": 1},
+			"unread": bson.M{"$addToSet": unreadSend("")},
+			"last":   bson.M{"$first": "$$ROOT"},
+		}}},
+	}
+}
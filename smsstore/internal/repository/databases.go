@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// regionalCollections hold users' messages and per-user data, which live in
+// the cluster of the user's data-residency region, in the database of their
+// tenant when it has its own. Everything else (jobs, webhooks, tenant
+// configs, ledgers) stays in the application database on the default cluster.
+var regionalCollections = map[string]bool{
+	smsDataCollection:       true,
+	coldDataCollection:      true,
+	messagesCollection:      true,
+	shadowCollection:        true,
+	statusArchiveCollection: true,
+	userStatsCollection:     true,
+}
+
+type regionKey struct{}
+
+type tenantKey struct{}
+
+// WithRegion returns a context whose repository operations on message data
+// use the cluster of region; "" is the default cluster.
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// RegionFrom returns the data-residency region set by WithRegion, or "".
+func RegionFrom(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
+
+// WithTenant returns a context whose repository operations on message data
+// use the tenant's own database when it has one, and the application
+// database otherwise.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant set by WithTenant, or "", whether or not the
+// tenant has its own database.
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// Scope is one database holding message data: the application database or a
+// tenant's own database, on the default cluster or a region's.
+type Scope struct {
+	Region string
+	// Tenant is set only for tenants with their own database
+	Tenant string
+}
+
+// Scopes lists every database holding message data, for background work
+// (migrations, retention, tiering, change streams) that must cover them all.
+func (r *Repository) Scopes() []Scope {
+	tenants := make([]string, 0, len(r.tenantDatabases))
+	for tenant := range r.tenantDatabases {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	var scopes []Scope
+	for _, region := range r.clients.Regions() {
+		scopes = append(scopes, Scope{Region: region})
+		for _, tenant := range tenants {
+			scopes = append(scopes, Scope{Region: region, Tenant: tenant})
+		}
+	}
+	return scopes
+}
+
+// ScopeFrom returns the scope ctx's message data operations use.
+func (r *Repository) ScopeFrom(ctx context.Context) Scope {
+	scope := Scope{Region: RegionFrom(ctx)}
+	if tenant, _ := ctx.Value(tenantKey{}).(string); r.tenantDatabases[tenant] != "" {
+		scope.Tenant = tenant
+	}
+	return scope
+}
+
+// Context returns ctx scoped to the scope's database.
+func (s Scope) Context(ctx context.Context) context.Context {
+	return WithTenant(WithRegion(ctx, s.Region), s.Tenant)
+}
+
+// Database returns a handle to scope's database; Scope{} is the application
+// database on the default cluster.
+func (r *Repository) Database(scope Scope) (*mongo.Database, error) {
+	client, err := r.clients.RegionClient(scope.Region)
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(r.databaseName(scope)), nil
+}
+
+// Label is appended to log lines about the scope's database; empty for the
+// application database on the default cluster.
+func (s Scope) Label() string {
+	label := ""
+	if s.Tenant != "" {
+		label += " for tenant " + s.Tenant
+	}
+	if s.Region != "" {
+		label += " in region " + s.Region
+	}
+	return label
+}
+
+// databaseName returns the name of scope's database: the tenant's own, or
+// DB_NAME.
+func (r *Repository) databaseName(scope Scope) string {
+	if name := r.tenantDatabases[scope.Tenant]; name != "" {
+		return name
+	}
+	return r.appDatabase
+}
+
+// databaseFor returns the database holding collection name for ctx's scope.
+// Analytics reads on the default cluster go through the analytics client.
+func (r *Repository) databaseFor(ctx context.Context, class string, name string) (*mongo.Database, error) {
+	scope := r.ScopeFrom(ctx)
+	if !regionalCollections[name] {
+		scope = Scope{}
+	}
+	if scope.Region == "" && class == classAnalytics {
+		client, err := r.clients.AnalyticsClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.Database(r.databaseName(scope)), nil
+	}
+	return r.Database(scope)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"smsstore/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// A user's document in the hot tier, which carries the query indexes, holds
+// at most USER_MESSAGE_LIMIT messages, so bot-like numbers can't grow it (and
+// its index entries) without bound. By default the write that adds a message
+// drops the oldest ones beyond the limit, with $push and $slice. With
+// USER_MESSAGE_LIMIT_ARCHIVE they are moved to the per-message collection
+// instead, where listings, purges and retention still cover them.
+
+// pushMessage is the update clause appending stored to a user's messages,
+// in its stored form, dropping the oldest beyond the limit in the same write
+// unless they are archived.
+func (r *Repository) pushMessage(stored models.MessageWithStatus) bson.M {
+	push := bson.M{"$each": bson.A{r.storedForm(stored)}}
+	if r.userMessageLimit > 0 && !r.archiveEvicted {
+		push["$slice"] = -r.userMessageLimit
+	}
+	return bson.M{"messages": push}
+}
+
+// enforceMessageLimit archives the user's messages beyond the limit after a
+// message was added, when evicted messages are archived. The message is
+// already stored, so a failure is logged rather than returned: the next
+// message the user receives tries again.
+func (r *Repository) enforceMessageLimit(ctx context.Context, hot *mongo.Collection, userID string) {
+	if r.userMessageLimit <= 0 || !r.archiveEvicted {
+		return
+	}
+	if _, err := r.archiveOverflow(ctx, hot, userID); err != nil {
+		log.Printf("[LIMIT] Archiving messages of %s beyond %d failed: %v", userID, r.userMessageLimit, err)
+	}
+}
+
+// archiveOverflow moves a user's oldest messages beyond the limit from the hot
+// tier to the per-message collection. Returns how many were moved.
+func (r *Repository) archiveOverflow(ctx context.Context, hot *mongo.Collection, userID string) (int, error) {
+	compacted, err := r.getCollectionFor(ctx, classCritical, messagesCollection)
+	if err != nil {
+		return 0, err
+	}
+	archived := 0
+	err = retryOnConflict(ctx, "ArchiveOverflow", func() error {
+		count, err := r.archiveOldest(ctx, hot, compacted, userID)
+		archived += count
+		return err
+	})
+	return archived, err
+}
+
+// archiveOldest reads the messages beyond the limit, which are the first ones
+// pushed, and moves them. Messages stored before IDs existed can't be pulled
+// selectively, so a user with any among them is compacted entirely.
+func (r *Repository) archiveOldest(ctx context.Context, hot, compacted *mongo.Collection, userID string) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id": userID,
+			fmt.Sprintf("messages.%d", r.userMessageLimit): bson.M{"$exists": true},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			versionField: 1,
+			"messages": bson.M{"$slice": bson.A{
+				"$messages", bson.M{"$subtract": bson.A{bson.M{"$size": "$messages"}, r.userMessageLimit}},
+			}},
+		}}},
+	}
+	cursor, err := hot.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var users []embeddedMessages
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 || len(users[0].Messages) == 0 {
+		return 0, nil
+	}
+	for _, message := range users[0].Messages {
+		if message.MessageID == "" {
+			return compactTier(ctx, hot, compacted, userID)
+		}
+	}
+	return moveToCompacted(ctx, hot, compacted, userID, users[0])
+}
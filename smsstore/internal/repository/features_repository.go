@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const featureFlagsCollection = "feature_flags"
+
+// SaveFeatureFlagOverride creates or replaces a feature's admin override.
+func (r *Repository) SaveFeatureFlagOverride(ctx context.Context, override *models.FeatureFlagOverride) (err error) {
+	defer r.observe(ctx, "SaveFeatureFlagOverride", time.Now(), &err)
+	collection, err := r.getCollection(ctx, featureFlagsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": override.Name}, override, opts)
+	return err
+}
+
+// ListFeatureFlagOverrides returns every admin override.
+func (r *Repository) ListFeatureFlagOverrides(ctx context.Context) (_ []models.FeatureFlagOverride, err error) {
+	defer r.observe(ctx, "ListFeatureFlagOverrides", time.Now(), &err)
+	collection, err := r.getCollection(ctx, featureFlagsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	overrides := []models.FeatureFlagOverride{}
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// DeleteFeatureFlagOverride removes a feature's admin override. Returns false
+// if none existed.
+func (r *Repository) DeleteFeatureFlagOverride(ctx context.Context, name string) (_ bool, err error) {
+	defer r.observe(ctx, "DeleteFeatureFlagOverride", time.Now(), &err)
+	collection, err := r.getCollection(ctx, featureFlagsCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
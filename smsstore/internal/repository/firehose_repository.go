@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// firehoseSettleDelay holds back the newest messages. Message IDs are minted
+// by each consumer replica, so IDs from the last few seconds can still be
+// committed out of order; a cursor past them could skip a late arrival.
+const firehoseSettleDelay = 10 * time.Second
+
+// ListAllMessages returns up to limit messages across all users with a
+// message_id greater than afterID, in message_id (insertion) order, including
+// soft-deleted ones. Messages stored before message IDs existed are not listed.
+func (r *Repository) ListAllMessages(ctx context.Context, afterID string, limit int) (_ []models.SearchResult, err error) {
+	defer r.observe(ctx, "ListAllMessages", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	idRange := bson.M{"$lt": primitive.NewObjectIDFromTimestamp(time.Now().Add(-firehoseSettleDelay)).Hex()}
+	if afterID != "" {
+		idRange["$gt"] = afterID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"messages.message_id": idRange}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: bson.M{"messages.message_id": idRange}}},
+		{{Key: "$sort", Value: bson.D{{Key: "messages.message_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	results := []models.SearchResult{}
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var tierResults []models.SearchResult
+		if err := cursor.All(ctx, &tierResults); err != nil {
+			return nil, err
+		}
+		results = append(results, tierResults...)
+	}
+
+	cursor, err := compacted.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": idRange}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": "$user_id", "messages": "$$ROOT"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var compactedResults []models.SearchResult
+	if err := cursor.All(ctx, &compactedResults); err != nil {
+		return nil, err
+	}
+	results = append(results, compactedResults...)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Message.MessageID < results[j].Message.MessageID
+	})
+	// A message being moved between tiers can briefly be in two places
+	unique := results[:0]
+	for i, result := range results {
+		if i > 0 && result.Message.MessageID == results[i-1].Message.MessageID {
+			continue
+		}
+		unique = append(unique, result)
+	}
+	if len(unique) > limit {
+		unique = unique[:limit]
+	}
+	return inflateResults(unique), nil
+}
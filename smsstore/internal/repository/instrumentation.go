@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"smsstore/internal/metrics"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// observe records the duration and outcome of a repository operation, and
+// adds it to ctx's operation log if it has one.
+// Use as: defer observe(ctx, "OpName", time.Now(), &err)
+func (r *Repository) observe(ctx context.Context, operation string, start time.Time, errp *error) {
+	elapsed := time.Since(start)
+	metrics.MongoOperationDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+
+	err := *errp
+	if operations, ok := ctx.Value(operationLogKey{}).(*OperationLog); ok {
+		operations.add(operation, start, elapsed, err)
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		metrics.MongoOperationErrors.WithLabelValues(operation, errorKind(err)).Inc()
+	}
+	if r.slowQueryThreshold > 0 && elapsed >= r.slowQueryThreshold {
+		log.Printf("[SLOW-QUERY] %s took %s (threshold %s, err=%v)", operation, elapsed, r.slowQueryThreshold, err)
+	}
+}
+
+type operationLogKey struct{}
+
+// OperationLog lists the repository operations run with a context, so a
+// request that ran out of time can report where the time went.
+type OperationLog struct {
+	mu         sync.Mutex
+	started    time.Time
+	operations []models.OperationTiming
+}
+
+// WithOperationLog returns a context whose repository operations are recorded
+// in the returned log.
+func WithOperationLog(ctx context.Context) (context.Context, *OperationLog) {
+	operations := &OperationLog{started: time.Now()}
+	return context.WithValue(ctx, operationLogKey{}, operations), operations
+}
+
+func (l *OperationLog) add(operation string, start time.Time, elapsed time.Duration, err error) {
+	timing := models.OperationTiming{
+		Operation:  operation,
+		StartMs:    start.Sub(l.started).Milliseconds(),
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		timing.Error = errorKind(err)
+	}
+	l.mu.Lock()
+	l.operations = append(l.operations, timing)
+	l.mu.Unlock()
+}
+
+// Operations returns the operations finished so far, in the order they finished.
+func (l *OperationLog) Operations() []models.OperationTiming {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]models.OperationTiming{}, l.operations...)
+}
+
+// documentTooLargeCodes are the server errors for a document over the 16MB
+// BSON limit: BSONObjectTooLarge and an update growing a document past it.
+var documentTooLargeCodes = []int{10334, 17419}
+
+// IsDocumentTooLarge reports whether err is a write rejected because the
+// resulting document would exceed MongoDB's BSON size limit, e.g. a user
+// document whose embedded message array has grown too large.
+func IsDocumentTooLarge(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	for _, code := range documentTooLargeCodes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case mongo.IsDuplicateKeyError(err):
+		return "duplicate_key"
+	case IsDocumentTooLarge(err):
+		return "document_too_large"
+	case mongo.IsNetworkError(err):
+		return "network"
+	default:
+		return "other"
+	}
+}
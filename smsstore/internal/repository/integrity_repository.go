@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetStoredMessages returns every message stored for a user across all tiers,
+// including soft-deleted ones, in insertion order. Unlike GetUserMessages it
+// reads the documents as stored, for checks that must see everything.
+func (r *Repository) GetStoredMessages(ctx context.Context, userID string) (_ []models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "GetStoredMessages", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var sources [][]models.MessageWithStatus
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		var document embeddedMessages
+		err := collection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"messages": 1})).Decode(&document)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		sources = append(sources, document.Messages)
+	}
+
+	cursor, err := compacted.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var documents []messageDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	compactedMessages := make([]models.MessageWithStatus, 0, len(documents))
+	for _, document := range documents {
+		compactedMessages = append(compactedMessages, document.MessageWithStatus)
+	}
+	sources = append(sources, compactedMessages)
+
+	return inflate(mergeTiers(false, sources...)), nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const jobsCollection = "jobs"
+
+// InsertJob persists a new job.
+func (r *Repository) InsertJob(ctx context.Context, job *models.Job) (err error) {
+	defer r.observe(ctx, "InsertJob", time.Now(), &err)
+	collection, err := r.getCollection(ctx, jobsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.InsertOne(ctx, job)
+	return err
+}
+
+// GetJob returns a job by ID, or nil if it does not exist.
+func (r *Repository) GetJob(ctx context.Context, id string) (_ *models.Job, err error) {
+	defer r.observe(ctx, "GetJob", time.Now(), &err)
+	collection, err := r.getCollection(ctx, jobsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var job models.Job
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimJob atomically takes the oldest runnable job of one of types: a pending
+// job that is due, or a running job whose owner's lease has expired. Returns
+// nil when there is nothing to run.
+func (r *Repository) ClaimJob(ctx context.Context, owner string, types []string, lease time.Duration) (_ *models.Job, err error) {
+	defer r.observe(ctx, "ClaimJob", time.Now(), &err)
+	collection, err := r.getCollection(ctx, jobsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"type": bson.M{"$in": types},
+		"$or": bson.A{
+			bson.M{"status": models.JobPending, "run_after": bson.M{"$lte": now}},
+			bson.M{"status": models.JobRunning, "lease_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobRunning,
+			"owner":       owner,
+			"lease_until": now.Add(lease),
+			"started_at":  now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_after", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RenewJobLease extends the lease on a job this owner is running. Returns
+// false if the job was taken over by another owner.
+func (r *Repository) RenewJobLease(ctx context.Context, id string, owner string, lease time.Duration) (_ bool, err error) {
+	defer r.observe(ctx, "RenewJobLease", time.Now(), &err)
+	return r.updateOwnedJob(ctx, id, owner, bson.M{"$set": bson.M{"lease_until": time.Now().UTC().Add(lease)}})
+}
+
+// UpdateJobProgress records progress on a job this owner is running.
+func (r *Repository) UpdateJobProgress(ctx context.Context, id string, owner string, progress models.JobProgress) (err error) {
+	defer r.observe(ctx, "UpdateJobProgress", time.Now(), &err)
+	_, err = r.updateOwnedJob(ctx, id, owner, bson.M{"$set": bson.M{"progress": progress}})
+	return err
+}
+
+// FinishJob records a job's final outcome.
+func (r *Repository) FinishJob(ctx context.Context, id string, owner string, status string, result interface{}, errMessage string) (err error) {
+	defer r.observe(ctx, "FinishJob", time.Now(), &err)
+	now := time.Now().UTC()
+	update := bson.M{
+		"$set":   bson.M{"status": status, "result": result, "error": errMessage, "finished_at": now},
+		"$unset": bson.M{"lease_until": ""},
+	}
+	_, err = r.updateOwnedJob(ctx, id, owner, update)
+	return err
+}
+
+// RescheduleJob returns a job to pending so it runs again after runAfter.
+// refundAttempt un-counts the current attempt, e.g. when it was interrupted
+// by shutdown rather than failing.
+func (r *Repository) RescheduleJob(ctx context.Context, id string, owner string, runAfter time.Time, errMessage string, refundAttempt bool) (err error) {
+	defer r.observe(ctx, "RescheduleJob", time.Now(), &err)
+	update := bson.M{
+		"$set":   bson.M{"status": models.JobPending, "run_after": runAfter, "error": errMessage},
+		"$unset": bson.M{"lease_until": "", "owner": ""},
+	}
+	if refundAttempt {
+		update["$inc"] = bson.M{"attempts": -1}
+	}
+	_, err = r.updateOwnedJob(ctx, id, owner, update)
+	return err
+}
+
+func (r *Repository) updateOwnedJob(ctx context.Context, id string, owner string, update bson.M) (bool, error) {
+	collection, err := r.getCollection(ctx, jobsCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "owner": owner, "status": models.JobRunning}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
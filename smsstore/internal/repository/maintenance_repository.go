@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const userStatsCollection = "user_stats"
+
+// rebuildSuffix names the stand-in index kept while an index rebuilds, and
+// the key it adds to tell the two apart: MongoDB refuses a second index on the
+// same keys.
+const rebuildSuffix = "_rebuild"
+
+// RebuildIndexes recreates every secondary index on the message collections
+// from its current definition. A stand-in index on the same keys is built
+// first and dropped last, so queries keep an index throughout; the stand-in
+// enforces no unique constraint, so uniqueness goes unchecked while each
+// unique index rebuilds. Returns the names of the rebuilt indexes.
+func (r *Repository) RebuildIndexes(ctx context.Context) (_ []string, err error) {
+	defer r.observe(ctx, "RebuildIndexes", time.Now(), &err)
+	database, err := r.Database(r.ScopeFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	rebuilt := []string{}
+	for _, name := range []string{smsDataCollection, coldDataCollection, messagesCollection} {
+		collection := database.Collection(name)
+		cursor, err := collection.Indexes().List(ctx)
+		if err != nil {
+			return rebuilt, err
+		}
+		// bson.D, since the order of a compound index's keys matters
+		var specs []bson.D
+		if err := cursor.All(ctx, &specs); err != nil {
+			return rebuilt, err
+		}
+
+		for _, spec := range specs {
+			indexName, _ := spec.Map()["name"].(string)
+			if indexName == "_id_" || strings.HasSuffix(indexName, rebuildSuffix) {
+				continue
+			}
+			// Server-managed fields are not accepted by createIndexes
+			spec = withoutFields(spec, "v", "ns")
+
+			if err := createIndex(ctx, database, name, standInIndex(spec, indexName)); err != nil {
+				return rebuilt, err
+			}
+			if _, err := collection.Indexes().DropOne(ctx, indexName); err != nil {
+				return rebuilt, err
+			}
+			if err := createIndex(ctx, database, name, spec); err != nil {
+				return rebuilt, err
+			}
+			if _, err := collection.Indexes().DropOne(ctx, indexName+rebuildSuffix); err != nil {
+				return rebuilt, err
+			}
+			rebuilt = append(rebuilt, name+"."+indexName)
+		}
+	}
+	return rebuilt, nil
+}
+
+func createIndex(ctx context.Context, database *mongo.Database, collection string, spec bson.D) error {
+	command := bson.D{{Key: "createIndexes", Value: collection}, {Key: "indexes", Value: bson.A{spec}}}
+	return database.RunCommand(ctx, command).Err()
+}
+
+// standInIndex returns the spec of the index standing in for the named one
+// while it rebuilds: the same keys plus one, serving the same queries, without
+// the unique constraint or TTL. Retried rebuilds find it already built.
+func standInIndex(spec bson.D, indexName string) bson.D {
+	standIn := withoutFields(spec, "unique", "expireAfterSeconds")
+	for i, field := range standIn {
+		switch field.Key {
+		case "name":
+			standIn[i].Value = indexName + rebuildSuffix
+		case "key":
+			keys, _ := field.Value.(bson.D)
+			standIn[i].Value = append(append(bson.D{}, keys...), bson.E{Key: rebuildSuffix, Value: 1})
+		}
+	}
+	return standIn
+}
+
+func withoutFields(doc bson.D, keys ...string) bson.D {
+	kept := bson.D{}
+	for _, field := range doc {
+		drop := false
+		for _, key := range keys {
+			if field.Key == key {
+				drop = true
+			}
+		}
+		if !drop {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// SaveUserStatsSnapshot stores precomputed stats for a user, replacing any previous snapshot.
+func (r *Repository) SaveUserStatsSnapshot(ctx context.Context, stats *models.UserStats) (err error) {
+	defer r.observe(ctx, "SaveUserStatsSnapshot", time.Now(), &err)
+	collection, err := r.getCollection(ctx, userStatsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	computedAt := time.Now().UTC()
+	snapshot := *stats
+	snapshot.ComputedAt = &computedAt
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": stats.UserID}, snapshot, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetUserStatsSnapshot returns the last precomputed stats for a user, or nil if none exist.
+func (r *Repository) GetUserStatsSnapshot(ctx context.Context, userID string) (_ *models.UserStats, err error) {
+	defer r.observe(ctx, "GetUserStatsSnapshot", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classAnalytics, userStatsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var stats models.UserStats
+	if err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&stats); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"smsstore/internal/changeevents"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Every permanent removal of messages is counted in the purge ledger, one
+// document per month, source, tenant and category, from which the monthly
+// purge reports are built. Messages are counted just before each removal, so
+// a message restored in between is still counted. Each removed message also
+// gets a delete change event.
+const (
+	purgeLedgerCollection  = "purge_ledger"
+	purgeReportsCollection = "purge_reports"
+)
+
+// ReportMonthFormat is the layout of purge report months.
+const ReportMonthFormat = "2006-01"
+
+type purgeGroup struct {
+	Key struct {
+		TenantID string `bson:"tenant_id"`
+		Category string `bson:"category"`
+	} `bson:"_id"`
+	Count int64 `bson:"count"`
+}
+
+// groupPurged counts message documents by tenant and category.
+var groupPurged = bson.D{{Key: "$group", Value: bson.M{
+	"_id": bson.M{
+		"tenant_id": bson.M{"$ifNull": bson.A{"$tenant_id", ""}},
+		"category":  bson.M{"$ifNull": bson.A{"$category", ""}},
+	},
+	"count": bson.M{"$sum": 1},
+}}}
+
+// tierPurged matches the embedded messages matching element in the user
+// documents matching filter, each with the user_id of its document.
+func tierPurged(filter bson.M, element bson.M) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{"$messages", bson.M{"user_id": "$_id"}}}}}},
+		{{Key: "$match", Value: element}},
+	}
+}
+
+// countTierPurge counts the embedded messages matching element in the user
+// documents matching filter.
+func countTierPurge(ctx context.Context, collection *mongo.Collection, filter bson.M, element bson.M) ([]purgeGroup, error) {
+	return aggregatePurge(ctx, collection, append(tierPurged(filter, element), groupPurged))
+}
+
+// countCompactedPurge counts the compacted messages matching filter.
+func countCompactedPurge(ctx context.Context, collection *mongo.Collection, filter bson.M) ([]purgeGroup, error) {
+	return aggregatePurge(ctx, collection, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		groupPurged,
+	})
+}
+
+type purgedMessage struct {
+	UserID    string `bson:"user_id"`
+	MessageID string `bson:"message_id"`
+}
+
+// listPurged returns the messages matched by pipeline so delete change events
+// can be published once they are removed; nil when change events are off.
+func listPurged(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]purgedMessage, error) {
+	if !changeevents.Enabled() {
+		return nil, nil
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"_id": 0, "user_id": 1, "message_id": 1}}})
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var purged []purgedMessage
+	if err := cursor.All(ctx, &purged); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+func publishPurged(ctx context.Context, purged []purgedMessage) {
+	for _, message := range purged {
+		changeevents.PublishMessageRemoval(ctx, message.UserID, message.MessageID)
+	}
+}
+
+func aggregatePurge(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]purgeGroup, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []purgeGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// purgeTier pulls the embedded messages matching element from the user
+// documents matching filter and records them under source. Returns the number
+// of documents modified.
+func (r *Repository) purgeTier(ctx context.Context, collection *mongo.Collection, source string, filter bson.M, element bson.M) (int64, error) {
+	groups, err := countTierPurge(ctx, collection, filter, element)
+	if err != nil || len(groups) == 0 {
+		return 0, err
+	}
+	purged, err := listPurged(ctx, collection, tierPurged(filter, element))
+	if err != nil {
+		return 0, err
+	}
+	result, err := collection.UpdateMany(ctx, filter, versioned(bson.M{"$pull": bson.M{"messages": element}}))
+	if err != nil {
+		return 0, err
+	}
+	publishPurged(ctx, purged)
+	return result.ModifiedCount, r.recordPurges(ctx, source, groups)
+}
+
+// purgeCompacted deletes the compacted messages matching filter and records
+// them under source. Returns the number of messages deleted.
+func (r *Repository) purgeCompacted(ctx context.Context, collection *mongo.Collection, source string, filter bson.M) (int64, error) {
+	groups, err := countCompactedPurge(ctx, collection, filter)
+	if err != nil || len(groups) == 0 {
+		return 0, err
+	}
+	purged, err := listPurged(ctx, collection, mongo.Pipeline{{{Key: "$match", Value: filter}}})
+	if err != nil {
+		return 0, err
+	}
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	publishPurged(ctx, purged)
+	return result.DeletedCount, r.recordPurges(ctx, source, groups)
+}
+
+// recordPurges adds removed message counts to the current month's ledger.
+func (r *Repository) recordPurges(ctx context.Context, source string, groups []purgeGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ledger, err := r.getCollectionFor(ctx, classCritical, purgeLedgerCollection)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	month := now.Format(ReportMonthFormat)
+	writes := make([]mongo.WriteModel, 0, len(groups))
+	for _, group := range groups {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"month": month, "source": source, "tenant_id": group.Key.TenantID, "category": group.Key.Category}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": group.Count}, "$set": bson.M{"updated_at": now}}).
+			SetUpsert(true))
+	}
+	_, err = ledger.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// BuildPurgeReport summarizes the purge ledger for month (YYYY-MM). Tenants
+// are ordered by ID, counts by tenant, source and category.
+func (r *Repository) BuildPurgeReport(ctx context.Context, month string) (_ *models.PurgeReport, err error) {
+	defer r.observe(ctx, "BuildPurgeReport", time.Now(), &err)
+	ledger, err := r.getCollectionFor(ctx, classAnalytics, purgeLedgerCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cursor, err := ledger.Find(ctx, bson.M{"month": month})
+	if err != nil {
+		return nil, err
+	}
+	counts := []models.PurgeCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Category < b.Category
+	})
+
+	report := &models.PurgeReport{Month: month, Tenants: []models.TenantPurges{}, Counts: counts, GeneratedAt: time.Now().UTC()}
+	for _, count := range counts {
+		report.Total += count.Count
+		last := len(report.Tenants) - 1
+		if last < 0 || report.Tenants[last].TenantID != count.TenantID {
+			report.Tenants = append(report.Tenants, models.TenantPurges{
+				TenantID:   count.TenantID,
+				BySource:   map[string]int64{},
+				ByCategory: map[string]int64{},
+			})
+			last++
+		}
+		tenant := &report.Tenants[last]
+		tenant.Total += count.Count
+		tenant.BySource[count.Source] += count.Count
+		tenant.ByCategory[count.Category] += count.Count
+	}
+	return report, nil
+}
+
+// SavePurgeReport stores a report, replacing any earlier one for its month.
+func (r *Repository) SavePurgeReport(ctx context.Context, report *models.PurgeReport) (err error) {
+	defer r.observe(ctx, "SavePurgeReport", time.Now(), &err)
+	collection, err := r.getCollection(ctx, purgeReportsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": report.Month}, report, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetPurgeReport returns the stored report for month, or nil if none exists.
+func (r *Repository) GetPurgeReport(ctx context.Context, month string) (_ *models.PurgeReport, err error) {
+	defer r.observe(ctx, "GetPurgeReport", time.Now(), &err)
+	collection, err := r.getCollection(ctx, purgeReportsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var report models.PurgeReport
+	if err := collection.FindOne(ctx, bson.M{"_id": month}).Decode(&report); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListPurgeReports returns the stored reports without their counts, newest month first.
+func (r *Repository) ListPurgeReports(ctx context.Context) (_ []models.PurgeReport, err error) {
+	defer r.observe(ctx, "ListPurgeReports", time.Now(), &err)
+	collection, err := r.getCollection(ctx, purgeReportsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"tenants": 0, "counts": 0})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	reports := []models.PurgeReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
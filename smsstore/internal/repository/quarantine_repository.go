@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QuarantineCollection is capped (see migration 13), so it keeps only the most
+// recent unparseable payloads.
+const QuarantineCollection = "quarantine"
+
+// InsertQuarantinedEvent stores an unparseable record. The oldest entry is
+// dropped once the collection is full.
+func (r *Repository) InsertQuarantinedEvent(ctx context.Context, event *models.QuarantinedEvent) (err error) {
+	defer r.observe(ctx, "InsertQuarantinedEvent", time.Now(), &err)
+	collection, err := r.getCollection(ctx, QuarantineCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.InsertOne(ctx, event)
+	return err
+}
+
+// ListQuarantinedEvents returns up to limit quarantined records, newest first,
+// optionally only those consumed from topic.
+func (r *Repository) ListQuarantinedEvents(ctx context.Context, topic string, limit int64) (_ []models.QuarantinedEvent, err error) {
+	defer r.observe(ctx, "ListQuarantinedEvents", time.Now(), &err)
+	collection, err := r.getCollection(ctx, QuarantineCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if topic != "" {
+		filter["topic"] = topic
+	}
+	// Capped collections keep insertion order
+	opts := options.Find().SetSort(bson.D{{Key: "$natural", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	events := []models.QuarantinedEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// SampleQuarantinedEvents returns up to size quarantined records picked at
+// random, optionally only those consumed from topic, so a flood of one broken
+// producer doesn't hide the others.
+func (r *Repository) SampleQuarantinedEvents(ctx context.Context, topic string, size int) (_ []models.QuarantinedEvent, err error) {
+	defer r.observe(ctx, "SampleQuarantinedEvents", time.Now(), &err)
+	collection, err := r.getCollection(ctx, QuarantineCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipeline := bson.A{}
+	if topic != "" {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"topic": topic}})
+	}
+	pipeline = append(pipeline, bson.M{"$sample": bson.M{"size": size}})
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	events := []models.QuarantinedEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
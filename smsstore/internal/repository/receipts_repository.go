@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MarkMessagesRead records a read receipt on every message of the user with
+// the given provider message ID (the sent and delivered events of one send are
+// stored separately), in whichever tier holds them. Repeated receipts keep the
+// first read time. Returns false if no such message exists.
+func (r *Repository) MarkMessagesRead(ctx context.Context, userID string, providerMessageID string, readAt time.Time) (_ bool, err error) {
+	defer r.observe(ctx, "MarkMessagesRead", time.Now(), &err)
+	return r.markReceipt(ctx, userID, providerMessageID, "read_at", readAt)
+}
+
+// MarkMessagesClicked records a click receipt the way MarkMessagesRead records
+// read receipts, keeping the first click time.
+func (r *Repository) MarkMessagesClicked(ctx context.Context, userID string, providerMessageID string, clickedAt time.Time) (_ bool, err error) {
+	defer r.observe(ctx, "MarkMessagesClicked", time.Now(), &err)
+	return r.markReceipt(ctx, userID, providerMessageID, "clicked_at", clickedAt)
+}
+
+// markReceipt sets field to at on the user's messages with the provider
+// message ID that don't have it yet, versioning the documents it changes so
+// the listing validators move with the receipt.
+func (r *Repository) markReceipt(ctx context.Context, userID string, providerMessageID string, field string, at time.Time) (bool, error) {
+	hot, cold, err := r.tierCollectionsFor(ctx, classCritical)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": userID, "messages.provider_message_id": providerMessageID}
+	update := versioned(bson.M{"$set": bson.M{"messages.$[m]." + field: at}})
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"m.provider_message_id": providerMessageID, "m." + field: bson.M{"$exists": false}},
+	}})
+
+	found := false
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		result, err := collection.UpdateOne(ctx, filter, update, opts)
+		if err != nil {
+			return false, err
+		}
+		found = found || result.MatchedCount > 0
+	}
+
+	compacted, err := r.getCollectionFor(ctx, classCritical, messagesCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := compacted.UpdateMany(ctx,
+		bson.M{"user_id": userID, "provider_message_id": providerMessageID},
+		bson.A{
+			versionedStage(bson.M{"$eq": bson.A{bson.M{"$type": "$" + field}, "missing"}}),
+			bson.M{"$set": bson.M{field: bson.M{"$ifNull": bson.A{"$" + field, at}}}},
+		})
+	if err != nil {
+		return false, err
+	}
+	return found || result.MatchedCount > 0, nil
+}
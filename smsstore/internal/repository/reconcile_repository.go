@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"smsstore/internal/statusflow"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StaleSend is a message the provider accepted whose send has no final
+// status yet, most likely because its delivery report was lost.
+type StaleSend struct {
+	UserID  string
+	Message models.MessageWithStatus
+}
+
+// FindStaleSends returns the messages sent through provider after since and
+// no later than before with one of statuses, whose send has no final status
+// stored and that status reconciliation has not looked up since before, from
+// up to batchSize users. Only the hot tier is read, like FindRetryCandidates.
+func (r *Repository) FindStaleSends(ctx context.Context, provider string, statuses []string, since time.Time, before time.Time, batchSize int) (_ []StaleSend, err error) {
+	defer r.observe(ctx, "FindStaleSends", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"messages": bson.M{"$elemMatch": bson.M{
+			"provider":            provider,
+			"status":              bson.M{"$in": statuses},
+			"created_at":          bson.M{"$gt": since, "$lte": before},
+			"provider_message_id": bson.M{"$exists": true},
+			"deleted_at":          bson.M{"$exists": false},
+			"$or": bson.A{
+				bson.M{"reconciled_at": bson.M{"$exists": false}},
+				bson.M{"reconciled_at": bson.M{"$lte": before}},
+			},
+		}}}},
+		bson.M{"$limit": batchSize},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var users []models.UserData
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	var stale []StaleSend
+	for _, user := range users {
+		inflate(user.Messages)
+		settled := map[string]bool{}
+		for _, message := range user.Messages {
+			if statusflow.Final(message.Status) {
+				settled[SendKey(message)] = true
+			}
+		}
+		for _, message := range user.Messages {
+			if message.Provider != provider || message.ProviderMessageID == "" || message.DeletedAt != nil ||
+				!slices.Contains(statuses, message.Status) || settled[SendKey(message)] ||
+				!message.CreatedAt.After(since) || message.CreatedAt.After(before) ||
+				(message.ReconciledAt != nil && message.ReconciledAt.After(before)) {
+				continue
+			}
+			stale = append(stale, StaleSend{UserID: user.ID, Message: message})
+		}
+	}
+	return stale, nil
+}
+
+// MarkMessageReconciled records that status reconciliation is looking up a
+// stale message. Only one call succeeds per message until before passes the
+// time it records, so replicas never look up the same message at once;
+// returns false if another replica got there first or the message no longer
+// exists. The claim shows in listings, so it is versioned like any other write.
+func (r *Repository) MarkMessageReconciled(ctx context.Context, userID string, messageID string, before time.Time) (_ bool, err error) {
+	defer r.observe(ctx, "MarkMessageReconciled", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	unclaimed := bson.A{
+		bson.M{"reconciled_at": bson.M{"$exists": false}},
+		bson.M{"reconciled_at": bson.M{"$lte": before}},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"m.message_id": messageID, "$or": bson.A{
+			bson.M{"m.reconciled_at": bson.M{"$exists": false}},
+			bson.M{"m.reconciled_at": bson.M{"$lte": before}},
+		}},
+	}})
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "messages": bson.M{"$elemMatch": bson.M{"message_id": messageID, "$or": unclaimed}}},
+		versioned(bson.M{"$set": bson.M{"messages.$[m].reconciled_at": time.Now().UTC()}}), opts)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"smsstore/internal/config"
+	"smsstore/internal/db"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository runs storage operations on a set of MongoDB clients, with the
+// settings it was built with from app config. The service builds one at
+// startup and hands it to everything that stores or reads data.
+type Repository struct {
+	clients *db.Clients
+
+	// appDatabase is DB_NAME, the application database on every cluster.
+	appDatabase string
+	// tenantDatabases maps tenants with their own database (TENANT_DATABASES)
+	// to its name.
+	tenantDatabases map[string]string
+
+	// queryTimeout bounds a single Mongo operation on top of the caller's context.
+	queryTimeout time.Duration
+	// slowQueryThreshold logs operations slower than this; zero disables the log.
+	slowQueryThreshold time.Duration
+	// classOptions holds the collection options per operation class; classes
+	// without an entry use the client defaults.
+	classOptions map[string]*options.CollectionOptions
+	// userMessageLimit is USER_MESSAGE_LIMIT; zero is unlimited.
+	userMessageLimit int
+	// archiveEvicted is USER_MESSAGE_LIMIT_ARCHIVE.
+	archiveEvicted bool
+	// compressBodiesFrom is the shortest body stored compressed; zero stores
+	// every body as a string.
+	compressBodiesFrom int
+}
+
+// New returns a Repository on clients, configured from cfg.
+func New(cfg *config.Config, clients *db.Clients) *Repository {
+	return &Repository{
+		clients:            clients,
+		appDatabase:        cfg.DBName,
+		tenantDatabases:    cfg.TenantDatabases,
+		queryTimeout:       cfg.MongoQueryTimeout,
+		slowQueryThreshold: cfg.MongoSlowQueryThreshold,
+		classOptions:       operationClasses(cfg),
+		userMessageLimit:   cfg.UserMessageLimit,
+		archiveEvicted:     cfg.UserMessageLimitArchive,
+		compressBodiesFrom: bodyCompressionFrom(cfg),
+	}
+}
+
+// HasRegion reports whether region is a configured data-residency region;
+// "" is the default cluster.
+func (r *Repository) HasRegion(region string) bool {
+	return r.clients.HasRegion(region)
+}
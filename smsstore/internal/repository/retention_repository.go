@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetRetentionOverride creates or replaces the retention override for a user.
+func (r *Repository) SetRetentionOverride(ctx context.Context, userID string, retentionDays int) (_ *models.RetentionOverride, err error) {
+	defer r.observe(ctx, "SetRetentionOverride", time.Now(), &err)
+	collection, err := r.getCollection(ctx, retentionOverrideCollName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	override := models.RetentionOverride{
+		UserID:        userID,
+		RetentionDays: retentionDays,
+		UpdatedAt:     time.Now().UTC(),
+	}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": userID}, override, opts); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// GetRetentionOverride returns the override for a user, or nil if none is set.
+func (r *Repository) GetRetentionOverride(ctx context.Context, userID string) (_ *models.RetentionOverride, err error) {
+	defer r.observe(ctx, "GetRetentionOverride", time.Now(), &err)
+	collection, err := r.getCollection(ctx, retentionOverrideCollName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var override models.RetentionOverride
+	err = collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&override)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &override, nil
+}
+
+// DeleteRetentionOverride removes a user's override. Returns false if none existed.
+func (r *Repository) DeleteRetentionOverride(ctx context.Context, userID string) (_ bool, err error) {
+	defer r.observe(ctx, "DeleteRetentionOverride", time.Now(), &err)
+	collection, err := r.getCollection(ctx, retentionOverrideCollName)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ListRetentionOverrides returns every configured override.
+func (r *Repository) ListRetentionOverrides(ctx context.Context) (_ []models.RetentionOverride, err error) {
+	defer r.observe(ctx, "ListRetentionOverrides", time.Now(), &err)
+	collection, err := r.getCollection(ctx, retentionOverrideCollName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	overrides := []models.RetentionOverride{}
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// PurgeMessagesBefore removes messages created before cutoff from every user
+// except those listed in excludeUsers, in both tiers and the compacted
+// collection, recording them in the purge ledger. Messages of the tenants in
+// excludeTenants are kept. Returns the number of documents modified or deleted.
+func (r *Repository) PurgeMessagesBefore(ctx context.Context, cutoff time.Time, excludeUsers, excludeTenants []string) (_ int64, err error) {
+	defer r.observe(ctx, "PurgeMessagesBefore", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	filter := bson.M{"messages.created_at": bson.M{"$lt": cutoff}}
+	if len(excludeUsers) > 0 {
+		filter["_id"] = bson.M{"$nin": excludeUsers}
+	}
+	element := bson.M{"created_at": bson.M{"$lt": cutoff}}
+	if len(excludeTenants) > 0 {
+		element["tenant_id"] = bson.M{"$nin": excludeTenants}
+	}
+	var modified int64
+	for _, collection := range []*mongo.Collection{cold, hot} {
+		tierModified, err := r.purgeTier(ctx, collection, models.PurgeSourceRetention, filter, element)
+		modified += tierModified
+		if err != nil {
+			return modified, err
+		}
+	}
+
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return modified, err
+	}
+	compactedFilter := bson.M{"created_at": bson.M{"$lt": cutoff}}
+	if len(excludeUsers) > 0 {
+		compactedFilter["user_id"] = bson.M{"$nin": excludeUsers}
+	}
+	if len(excludeTenants) > 0 {
+		compactedFilter["tenant_id"] = bson.M{"$nin": excludeTenants}
+	}
+	deleted, err := r.purgeCompacted(ctx, compacted, models.PurgeSourceRetention, compactedFilter)
+	if err != nil {
+		return modified + deleted, err
+	}
+	return modified + deleted, r.purgeCopies(ctx, compactedFilter)
+}
+
+// PurgeTenantMessagesBefore removes one tenant's messages created before
+// cutoff from every user except those listed in excludeUsers, in both tiers
+// and the compacted collection, recording them in the purge ledger. Returns
+// the number of documents modified or deleted.
+func (r *Repository) PurgeTenantMessagesBefore(ctx context.Context, tenantID string, cutoff time.Time, excludeUsers []string) (_ int64, err error) {
+	defer r.observe(ctx, "PurgeTenantMessagesBefore", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	element := bson.M{"tenant_id": tenantID, "created_at": bson.M{"$lt": cutoff}}
+	filter := bson.M{"messages": bson.M{"$elemMatch": element}}
+	if len(excludeUsers) > 0 {
+		filter["_id"] = bson.M{"$nin": excludeUsers}
+	}
+	var modified int64
+	for _, collection := range []*mongo.Collection{cold, hot} {
+		tierModified, err := r.purgeTier(ctx, collection, models.PurgeSourceRetention, filter, element)
+		modified += tierModified
+		if err != nil {
+			return modified, err
+		}
+	}
+
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return modified, err
+	}
+	compactedFilter := bson.M{"tenant_id": tenantID, "created_at": bson.M{"$lt": cutoff}}
+	if len(excludeUsers) > 0 {
+		compactedFilter["user_id"] = bson.M{"$nin": excludeUsers}
+	}
+	deleted, err := r.purgeCompacted(ctx, compacted, models.PurgeSourceRetention, compactedFilter)
+	if err != nil {
+		return modified + deleted, err
+	}
+	return modified + deleted, r.purgeCopies(ctx, compactedFilter)
+}
+
+// PurgeUserMessagesBefore removes a single user's messages created before
+// cutoff from both tiers and the compacted collection, recording them in the
+// purge ledger.
+func (r *Repository) PurgeUserMessagesBefore(ctx context.Context, userID string, cutoff time.Time) (_ bool, err error) {
+	defer r.observe(ctx, "PurgeUserMessagesBefore", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	element := bson.M{"created_at": bson.M{"$lt": cutoff}}
+	modified := false
+	for _, collection := range []*mongo.Collection{cold, hot} {
+		tierModified, err := r.purgeTier(ctx, collection, models.PurgeSourceRetention, bson.M{"_id": userID}, element)
+		if err != nil {
+			return false, err
+		}
+		modified = modified || tierModified > 0
+	}
+
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return false, err
+	}
+	compactedFilter := bson.M{"user_id": userID, "created_at": bson.M{"$lt": cutoff}}
+	deleted, err := r.purgeCompacted(ctx, compacted, models.PurgeSourceRetention, compactedFilter)
+	if err != nil {
+		return false, err
+	}
+	if err := r.purgeCopies(ctx, compactedFilter); err != nil {
+		return false, err
+	}
+	return modified || deleted > 0, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RetryCandidate is a failed message the retry orchestrator may resend.
+type RetryCandidate struct {
+	UserID  string
+	Message models.MessageWithStatus
+	// Resends counts the earlier resends of the message's send
+	Resends int
+}
+
+// SendKey identifies the send a message belongs to: its send ID, or for
+// messages stored without one, its own message ID. Resends use the key as
+// their send ID, so their messages share it.
+func SendKey(message models.MessageWithStatus) string {
+	if message.SendID != "" {
+		return message.SendID
+	}
+	return message.MessageID
+}
+
+// FindRetryCandidates returns the tenant's messages with one of statuses that
+// failed after since and no later than before and have not been handled by the
+// retry orchestrator, from up to batchSize users. Only the hot tier is read:
+// failures old enough to have moved to the cold tier are past resending.
+func (r *Repository) FindRetryCandidates(ctx context.Context, tenantID string, statuses []string, since time.Time, before time.Time, batchSize int) (_ []RetryCandidate, err error) {
+	defer r.observe(ctx, "FindRetryCandidates", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"messages": bson.M{"$elemMatch": bson.M{
+			"tenant_id":   tenantID,
+			"status":      bson.M{"$in": statuses},
+			"created_at":  bson.M{"$gt": since, "$lte": before},
+			"retry_state": bson.M{"$exists": false},
+			"deleted_at":  bson.M{"$exists": false},
+		}}}},
+		bson.M{"$limit": batchSize},
+		// Every failure of the tenant is kept, as the handled ones count the
+		// resends already made of each send
+		bson.M{"$project": bson.M{"messages": bson.M{"$filter": bson.M{
+			"input": "$messages",
+			"cond": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$$this.tenant_id", tenantID}},
+				bson.M{"$in": bson.A{"$$this.status", statuses}},
+			}},
+		}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var users []models.UserData
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	var candidates []RetryCandidate
+	for _, user := range users {
+		inflate(user.Messages)
+		resends := map[string]int{}
+		for _, message := range user.Messages {
+			if message.RetryState == models.RetryStateResent {
+				resends[SendKey(message)]++
+			}
+		}
+		for _, message := range user.Messages {
+			if message.RetryState != "" || message.DeletedAt != nil || !slices.Contains(statuses, message.Status) ||
+				!message.CreatedAt.After(since) || message.CreatedAt.After(before) {
+				continue
+			}
+			candidates = append(candidates, RetryCandidate{UserID: user.ID, Message: message, Resends: resends[SendKey(message)]})
+		}
+	}
+	return candidates, nil
+}
+
+// MarkMessageRetry records that the retry orchestrator handled a failed
+// message, with the resend it made (zero for none). Only the first call for a
+// message succeeds, so replicas never resend the same failure twice; returns
+// false if the message was already handled or no longer exists.
+func (r *Repository) MarkMessageRetry(ctx context.Context, userID string, messageID string, state string, attempt int) (_ bool, err error) {
+	defer r.observe(ctx, "MarkMessageRetry", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	set := bson.M{"messages.$[m].retry_state": state, "messages.$[m].retried_at": time.Now().UTC()}
+	if attempt > 0 {
+		set["messages.$[m].retry_attempt"] = attempt
+	}
+	unhandled := bson.M{"message_id": messageID, "retry_state": bson.M{"$exists": false}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"m.message_id": messageID, "m.retry_state": bson.M{"$exists": false}},
+	}})
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "messages": bson.M{"$elemMatch": unhandled}},
+		versioned(bson.M{"$set": set}), opts)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClearMessageRetry undoes MarkMessageRetry, for a resend that could not be
+// handed to the sender, so the next run picks the message up again.
+func (r *Repository) ClearMessageRetry(ctx context.Context, userID string, messageID string) (err error) {
+	defer r.observe(ctx, "ClearMessageRetry", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"m.message_id": messageID},
+	}})
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "messages.message_id": messageID},
+		versioned(bson.M{"$unset": bson.M{
+			"messages.$[m].retry_state":   "",
+			"messages.$[m].retry_attempt": "",
+			"messages.$[m].retried_at":    "",
+		}}), opts)
+	return err
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rollupsCollection = "message_rollups"
+
+// rollupGranularities are the periods every stored message is counted in, with
+// how long their rollups are kept (zero keeps them forever).
+var rollupGranularities = []struct {
+	name      string
+	period    time.Duration
+	retention time.Duration
+}{
+	{models.GranularityMinute, time.Minute, 48 * time.Hour},
+	{models.GranularityHour, time.Hour, 90 * 24 * time.Hour},
+	{models.GranularityDay, 24 * time.Hour, 0},
+}
+
+// RollupPeriod returns the length of a rollup granularity, or false if there
+// is no such granularity.
+func RollupPeriod(granularity string) (time.Duration, bool) {
+	for _, g := range rollupGranularities {
+		if g.name == granularity {
+			return g.period, true
+		}
+	}
+	return 0, false
+}
+
+// rollupKeys makes field values safe as document keys.
+var rollupKeys = strings.NewReplacer(".", "_", "$", "_")
+
+func rollupKey(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return rollupKeys.Replace(value)
+}
+
+// RecordRollup counts a stored message in the minute, hour and day rollups of
+// its tenant, by the time it was created. Rollups are shared by every region
+// and tenant database, so they live in the application database.
+func (r *Repository) RecordRollup(ctx context.Context, message *models.MessageWithStatus) (err error) {
+	defer r.observe(ctx, "RecordRollup", time.Now(), &err)
+	collection, err := r.getCollection(ctx, rollupsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(rollupGranularities))
+	for _, g := range rollupGranularities {
+		start := message.CreatedAt.UTC().Truncate(g.period)
+		insert := bson.M{"granularity": g.name, "tenant_id": message.TenantID, "start": start}
+		if g.retention > 0 {
+			insert["expires_at"] = start.Add(g.retention)
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": g.name + ":" + message.TenantID + ":" + start.Format(time.RFC3339)}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{
+					"total":                               1,
+					"status." + rollupKey(message.Status): 1,
+					"category." + rollupKey(message.Category):   1,
+					"provider." + rollupKey(message.Provider):   1,
+					"country." + rollupKey(message.CountryCode): 1,
+				},
+				"$setOnInsert": insert,
+			}).
+			SetUpsert(true))
+	}
+	_, err = collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// GetRollups returns the rollups of a granularity starting in [from, to),
+// ordered by start. An empty tenantID returns every tenant's rollups.
+func (r *Repository) GetRollups(ctx context.Context, granularity string, tenantID string, from time.Time, to time.Time) (_ []models.MessageRollup, err error) {
+	defer r.observe(ctx, "GetRollups", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classAnalytics, rollupsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"granularity": granularity, "start": bson.M{"$gte": from, "$lt": to}}
+	if tenantID != "" {
+		filter["tenant_id"] = tenantID
+	}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	rollups := []models.MessageRollup{}
+	if err := cursor.All(ctx, &rollups); err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
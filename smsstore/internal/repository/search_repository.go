@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SearchMessages finds visible messages matching filter across users (or a
+// single user when userID is set), newest first, from both tiers and the
+// compacted collection.
+func (r *Repository) SearchMessages(ctx context.Context, userID string, filter MessageFilter, limit int) (_ []models.SearchResult, err error) {
+	defer r.observe(ctx, "SearchMessages", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// Narrow to candidate documents first so the multikey indexes apply
+	match := bson.M{"messages": bson.M{"$elemMatch": filter.elementMatch("")}}
+	if userID != "" {
+		match["_id"] = userID
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: filter.elementMatch("messages.")}},
+		{{Key: "$sort", Value: bson.D{{Key: "messages.created_at", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	results := []models.SearchResult{}
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var tierResults []models.SearchResult
+		if err := cursor.All(ctx, &tierResults); err != nil {
+			return nil, err
+		}
+		results = append(results, tierResults...)
+	}
+
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	compactedMatch := filter.elementMatch("")
+	if userID != "" {
+		compactedMatch["user_id"] = userID
+	}
+	cursor, err := compacted.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: compactedMatch}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		// Reshape to match the unwound tier documents
+		{{Key: "$project", Value: bson.M{"_id": "$user_id", "messages": "$$ROOT"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var compactedResults []models.SearchResult
+	if err := cursor.All(ctx, &compactedResults); err != nil {
+		return nil, err
+	}
+	results = append(results, compactedResults...)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Message.CreatedAt.After(results[j].Message.CreatedAt)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return inflateResults(results), nil
+}
+
+// CountMessagesBy counts visible messages matching filter grouped by the
+// stored message field groupBy, across both tiers and the compacted collection.
+// Messages without the field are counted under an empty key.
+func (r *Repository) CountMessagesBy(ctx context.Context, groupBy string, filter MessageFilter) (_ []models.AnalyticsBucket, err error) {
+	defer r.observe(ctx, "CountMessagesBy", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classAnalytics)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"messages": bson.M{"$elemMatch": filter.elementMatch("")}}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: filter.elementMatch("messages.")}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$messages." + groupBy, ""}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	compacted, err := r.getCollectionFor(ctx, classAnalytics, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	compactedPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.elementMatch("")}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$" + groupBy, ""}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	counts := map[string]int{}
+	sources := []struct {
+		collection *mongo.Collection
+		pipeline   mongo.Pipeline
+	}{{hot, pipeline}, {cold, pipeline}, {compacted, compactedPipeline}}
+	for _, source := range sources {
+		cursor, err := source.collection.Aggregate(ctx, source.pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var sourceBuckets []models.AnalyticsBucket
+		if err := cursor.All(ctx, &sourceBuckets); err != nil {
+			return nil, err
+		}
+		for _, bucket := range sourceBuckets {
+			counts[bucket.Key] += bucket.Count
+		}
+	}
+
+	buckets := make([]models.AnalyticsBucket, 0, len(counts))
+	for key, count := range counts {
+		buckets = append(buckets, models.AnalyticsBucket{Key: key, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	return buckets, nil
+}
+
+// latencyPercentiles are the percentiles DeliveryLatencyBy reports.
+var latencyPercentiles = bson.A{0.5, 0.95, 0.99}
+
+// DeliveryLatencyBy summarizes the delivery latency of delivered messages
+// matching filter, grouped by the stored message field groupBy, across both
+// tiers and the compacted collection. The tiers are unioned into a single
+// aggregation so percentiles cover all of them; Mongo computes them
+// approximately. Messages without the field are grouped under an empty key.
+func (r *Repository) DeliveryLatencyBy(ctx context.Context, groupBy string, filter MessageFilter) (_ []models.AnalyticsBucket, err error) {
+	defer r.observe(ctx, "DeliveryLatencyBy", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classAnalytics)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollectionFor(ctx, classAnalytics, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	embeddedMatch := filter.elementMatch("")
+	embeddedMatch["delivery_latency_ms"] = bson.M{"$exists": true}
+	unwoundMatch := filter.elementMatch("messages.")
+	unwoundMatch["messages.delivery_latency_ms"] = bson.M{"$exists": true}
+	tierPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"messages": bson.M{"$elemMatch": embeddedMatch}}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: unwoundMatch}},
+		{{Key: "$project", Value: bson.M{
+			"key":     bson.M{"$ifNull": bson.A{"$messages." + groupBy, ""}},
+			"latency": "$messages.delivery_latency_ms",
+		}}},
+	}
+	compactedPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: embeddedMatch}},
+		{{Key: "$project", Value: bson.M{
+			"key":     bson.M{"$ifNull": bson.A{"$" + groupBy, ""}},
+			"latency": "$delivery_latency_ms",
+		}}},
+	}
+
+	pipeline := append(mongo.Pipeline{}, tierPipeline...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": cold.Name(), "pipeline": tierPipeline}}},
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": compacted.Name(), "pipeline": compactedPipeline}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":   "$key",
+			"count": bson.M{"$sum": 1},
+			"latency": bson.M{"$percentile": bson.M{
+				"input":  "$latency",
+				"p":      latencyPercentiles,
+				"method": "approximate",
+			}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	)
+
+	cursor, err := hot.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Key     string    `bson:"_id"`
+		Count   int       `bson:"count"`
+		Latency []float64 `bson:"latency"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]models.AnalyticsBucket, 0, len(groups))
+	for _, group := range groups {
+		bucket := models.AnalyticsBucket{Key: group.Key, Count: group.Count}
+		if len(group.Latency) == len(latencyPercentiles) {
+			bucket.DeliveryLatencyMs = &models.LatencyPercentiles{P50: group.Latency[0], P95: group.Latency[1], P99: group.Latency[2]}
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// CountClickedBy counts the sends matching filter a click receipt was recorded
+// for, grouped by the stored message field groupBy, across both tiers and the
+// compacted collection. Every stored status of a clicked send carries the
+// click, so sends are counted once by provider message ID. Messages without
+// the field are counted under an empty key.
+func (r *Repository) CountClickedBy(ctx context.Context, groupBy string, filter MessageFilter) (_ []models.AnalyticsBucket, err error) {
+	defer r.observe(ctx, "CountClickedBy", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classAnalytics)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollectionFor(ctx, classAnalytics, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	embeddedMatch := filter.elementMatch("")
+	embeddedMatch["clicked_at"] = bson.M{"$exists": true}
+	unwoundMatch := filter.elementMatch("messages.")
+	unwoundMatch["messages.clicked_at"] = bson.M{"$exists": true}
+	tierPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"messages": bson.M{"$elemMatch": embeddedMatch}}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: unwoundMatch}},
+		{{Key: "$project", Value: bson.M{
+			"key":  bson.M{"$ifNull": bson.A{"$messages." + groupBy, ""}},
+			"user": "$_id",
+			"send": "$messages.provider_message_id",
+		}}},
+	}
+	compactedPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: embeddedMatch}},
+		{{Key: "$project", Value: bson.M{
+			"key":  bson.M{"$ifNull": bson.A{"$" + groupBy, ""}},
+			"user": "$user_id",
+			"send": "$provider_message_id",
+		}}},
+	}
+
+	pipeline := append(mongo.Pipeline{}, tierPipeline...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": cold.Name(), "pipeline": tierPipeline}}},
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": compacted.Name(), "pipeline": compactedPipeline}}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"key": "$key", "user": "$user", "send": "$send"}}}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$_id.key", "count": bson.M{"$sum": 1}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	)
+
+	cursor, err := hot.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []models.AnalyticsBucket{}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The shadow collection is the storage a migration is evaluated against: one
+// document per message, shaped like the compacted collection, written
+// alongside every message write while SHADOW_WRITES is on (see package
+// shadow). Nothing serves reads from it; purges remove from it what they
+// remove from the primary storage, without ledger entries, as it only holds
+// copies.
+const shadowCollection = "messages_shadow"
+
+// PutShadowMessage writes a user's message to the shadow collection as the
+// primary storage holds it, replacing any earlier copy.
+func (r *Repository) PutShadowMessage(ctx context.Context, userID string, message models.MessageWithStatus) (err error) {
+	defer r.observe(ctx, "PutShadowMessage", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, shadowCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	document := messageDocument{ID: message.MessageID, UserID: userID, MessageWithStatus: message}
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": message.MessageID}, document, options.Replace().SetUpsert(true))
+	return err
+}
+
+// MarkShadowMessagesRead applies a read receipt to the shadow collection the
+// way MarkMessagesRead does to compacted messages.
+func (r *Repository) MarkShadowMessagesRead(ctx context.Context, userID string, providerMessageID string, readAt time.Time) (err error) {
+	defer r.observe(ctx, "MarkShadowMessagesRead", time.Now(), &err)
+	return r.markShadowReceipt(ctx, userID, providerMessageID, "read_at", readAt)
+}
+
+// MarkShadowMessagesClicked is MarkShadowMessagesRead for click receipts.
+func (r *Repository) MarkShadowMessagesClicked(ctx context.Context, userID string, providerMessageID string, clickedAt time.Time) (err error) {
+	defer r.observe(ctx, "MarkShadowMessagesClicked", time.Now(), &err)
+	return r.markShadowReceipt(ctx, userID, providerMessageID, "clicked_at", clickedAt)
+}
+
+func (r *Repository) markShadowReceipt(ctx context.Context, userID string, providerMessageID string, field string, at time.Time) error {
+	collection, err := r.getCollectionFor(ctx, classCritical, shadowCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "provider_message_id": providerMessageID},
+		bson.A{bson.M{"$set": bson.M{field: bson.M{"$ifNull": bson.A{"$" + field, at}}}}})
+	return err
+}
+
+// GetShadowMessages returns a user's visible shadow messages created in
+// [from, to), oldest first.
+func (r *Repository) GetShadowMessages(ctx context.Context, userID string, from time.Time, to time.Time) (_ []models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "GetShadowMessages", time.Now(), &err)
+	collection, err := r.getCollection(ctx, shadowCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return findCompactedMessages(ctx, collection, userID, MessageQuery{MessageFilter: MessageFilter{From: from, To: to}})
+}
+
+// SampleRecentUsers returns up to size users, picked at random, whose messages
+// in the hot tier changed since since.
+func (r *Repository) SampleRecentUsers(ctx context.Context, since time.Time, size int) (_ []string, err error) {
+	defer r.observe(ctx, "SampleRecentUsers", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classAnalytics, smsDataCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"updated_at": bson.M{"$gte": since}}}},
+		{{Key: "$sample", Value: bson.M{"size": size}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var users []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// purgeShadow deletes the shadow copies of purged messages, selected by the
+// filter used on the compacted collection.
+func (r *Repository) purgeShadow(ctx context.Context, filter bson.M) error {
+	collection, err := r.getCollection(ctx, shadowCollection)
+	if err != nil {
+		return err
+	}
+	_, err = collection.DeleteMany(ctx, filter)
+	return err
+}
@@ -2,61 +2,619 @@ package repository
 
 import (
 	"context"
-	"smsstore/internal/db"
+	"fmt"
+	"smsstore/internal/config"
+	"smsstore/internal/integrity"
 	"smsstore/pkg/models"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
-func AddMessageToUser(phoneNumber string, message string, status string) error {
-	client, err := db.GetClient()
+const (
+	smsDataCollection         = "smsdata"
+	coldDataCollection        = "smsdata_cold"
+	messagesCollection        = "messages"
+	retentionOverrideCollName = "retention_overrides"
+)
+
+// withTimeout derives a per-query context. The caller's cancellation (e.g. a
+// disconnected HTTP client) still aborts the query before the timeout elapses.
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+// Operation classes select the write concern and read preference an
+// operation runs with (MONGO_WRITE_CONCERN, MONGO_READ_PREFERENCE).
+const (
+	// classCritical is message persistence: ingest, receipts, tier moves and compaction
+	classCritical = "critical"
+	// classAnalytics is aggregate reads that tolerate replication lag; on the
+	// default cluster they use the MONGO_ANALYTICS_URI connection when set
+	classAnalytics = "analytics"
+	classDefault   = "default"
+)
+
+// operationClasses builds the collection options per operation class; classes
+// without an entry use the client defaults.
+func operationClasses(cfg *config.Config) map[string]*options.CollectionOptions {
+	configured := map[string]*options.CollectionOptions{}
+	for class := range config.MongoOperationClasses {
+		opts := options.Collection()
+		set := false
+		if concern, ok := cfg.MongoWriteConcerns[class]; ok {
+			if concern == "majority" {
+				opts.SetWriteConcern(writeconcern.Majority())
+			} else {
+				n, _ := strconv.Atoi(concern) // validated by config
+				opts.SetWriteConcern(&writeconcern.WriteConcern{W: n})
+			}
+			set = true
+		}
+		if preference, ok := cfg.MongoReadPreferences[class]; ok {
+			mode, _ := readpref.ModeFromString(preference) // validated by config
+			pref, err := readpref.New(mode)
+			if err == nil {
+				opts.SetReadPreference(pref)
+				set = true
+			}
+		}
+		if set {
+			configured[class] = opts
+		}
+	}
+	return configured
+}
+
+// getCollection returns a handle to the named collection in the application database.
+func (r *Repository) getCollection(ctx context.Context, name string) (*mongo.Collection, error) {
+	return r.getCollectionFor(ctx, classDefault, name)
+}
+
+// getCollectionFor returns a handle to the named collection configured for an
+// operation class, in the cluster of ctx's region for message data.
+func (r *Repository) getCollectionFor(ctx context.Context, class string, name string) (*mongo.Collection, error) {
+	database, err := r.databaseFor(ctx, class, name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts, ok := r.classOptions[class]; ok {
+		return database.Collection(name, opts), nil
 	}
-	collection := client.Database("smsstore").Collection("smsdata")
+	return database.Collection(name), nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+type sourceKey struct{}
+
+// WithSource returns a context whose stored messages record source as their
+// provenance.
+func WithSource(ctx context.Context, source *models.MessageSource) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the provenance set by WithSource, or nil.
+func SourceFrom(ctx context.Context) *models.MessageSource {
+	source, _ := ctx.Value(sourceKey{}).(*models.MessageSource)
+	return source
+}
 
-	filter := bson.M{"_id": phoneNumber}
-	update := bson.M{
-		"$push": bson.M{
-			"messages": bson.M{
-				"message": message,
-				"status":  status,
-			},
-		},
+// newMessage builds a message document for an event with a fresh, time-ordered message ID.
+func newMessage(event models.SmsEvent) models.MessageWithStatus {
+	return models.MessageWithStatus{
+		MessageID:         primitive.NewObjectID().Hex(),
+		Message:           event.Message,
+		Status:            event.Status,
+		CreatedAt:         createdAt(event),
+		ReadAt:            event.ReadAt,
+		Provider:          event.Provider,
+		ProviderMessageID: event.ProviderMessageID,
+		CampaignID:        event.CampaignID,
+		TemplateID:        event.TemplateID,
+		Variant:           event.Variant,
+		CountryCode:       event.CountryCode,
+		Attempt:           event.Attempt,
+		Language:          event.Language,
+		Metadata:          event.Metadata,
+		IdempotencyKey:    event.IdempotencyKey,
+		TenantID:          event.TenantID,
+		Category:          event.Category,
+		SenderID:          event.SenderID,
+		TraceID:           event.TraceID,
+		SendID:            event.SendID,
+		Truncated:         event.Truncated,
+		OriginalBytes:     event.OriginalBytes,
+		OutOfOrder:        event.OutOfOrder,
+		DeliveryLatencyMs: event.DeliveryLatencyMs,
+		Checksum:          integrity.Checksum(event.Message),
 	}
+}
+
+// createdAt is the event's own timestamp for imported history, otherwise now.
+func createdAt(event models.SmsEvent) time.Time {
+	if event.CreatedAt != nil {
+		return event.CreatedAt.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// AddMessageToUser appends an event's message to the user's document, creating
+// it if needed, and returns the stored message.
+func (r *Repository) AddMessageToUser(ctx context.Context, event models.SmsEvent) (_ *models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "AddMessageToUser", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	stored := newMessage(event)
+	stored.Source = SourceFrom(ctx)
+	filter := bson.M{"_id": event.PhoneNumber}
+	update := versioned(bson.M{
+		"$push": r.pushMessage(stored),
+		"$max":  bson.M{"updated_at": stored.CreatedAt},
+	})
 
 	// Upsert option creates the user if they don't exist
 	opts := options.Update().SetUpsert(true)
-	_, err = collection.UpdateOne(ctx, filter, update, opts)
+	if _, err = collection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return nil, err
+	}
+	r.enforceMessageLimit(ctx, collection, event.PhoneNumber)
 
-	return err
+	return &stored, nil
 }
 
-func GetUserMessages(phoneNumber string) ([]models.MessageWithStatus, error) {
-	client, err := db.GetClient()
+// AddMessageToUserDeduplicated stores an event's message unless an identical
+// body was stored for the same user within window. The check and the write are
+// a single atomic update, so concurrent duplicates cannot both be stored.
+// Returns duplicate=true (and a nil message) when the message was suppressed.
+func (r *Repository) AddMessageToUserDeduplicated(ctx context.Context, event models.SmsEvent, window time.Duration) (_ *models.MessageWithStatus, _ bool, err error) {
+	defer r.observe(ctx, "AddMessageToUserDeduplicated", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	stored := newMessage(event)
+	stored.Source = SourceFrom(ctx)
+	identical := r.sameBody(event.Message)
+	identical["created_at"] = bson.M{"$gte": stored.CreatedAt.Add(-window)}
+	filter := bson.M{
+		"_id":      event.PhoneNumber,
+		"messages": bson.M{"$not": bson.M{"$elemMatch": identical}},
+	}
+	update := versioned(bson.M{
+		"$push": r.pushMessage(stored),
+		"$max":  bson.M{"updated_at": stored.CreatedAt},
+	})
+
+	_, err = collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// The user exists but the filter excluded it, so the upsert tried to
+		// insert a second document with the same _id: that's a duplicate.
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	r.enforceMessageLimit(ctx, collection, event.PhoneNumber)
+	return &stored, false, nil
+}
+
+// AddMessageToUserIdempotent stores an event's message unless a message with
+// the same idempotency key is already stored for the user. Like the dedup
+// write, the check and the write are a single atomic update. Only the hot tier
+// is checked: producer retries arrive long before messages are tiered.
+// Returns duplicate=true (and a nil message) when the message was suppressed.
+func (r *Repository) AddMessageToUserIdempotent(ctx context.Context, event models.SmsEvent) (_ *models.MessageWithStatus, _ bool, err error) {
+	defer r.observe(ctx, "AddMessageToUserIdempotent", time.Now(), &err)
+	collection, err := r.getCollectionFor(ctx, classCritical, smsDataCollection)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	stored := newMessage(event)
+	stored.Source = SourceFrom(ctx)
+	filter := bson.M{
+		"_id":                      event.PhoneNumber,
+		"messages.idempotency_key": bson.M{"$ne": event.IdempotencyKey},
+	}
+	update := versioned(bson.M{
+		"$push": r.pushMessage(stored),
+		"$max":  bson.M{"updated_at": stored.CreatedAt},
+	})
+
+	_, err = collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// Same as AddMessageToUserDeduplicated: a filtered-out existing user
+		// makes the upsert collide on _id
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	r.enforceMessageLimit(ctx, collection, event.PhoneNumber)
+	return &stored, false, nil
+}
+
+// MessageFilter matches messages by exact attribute values. Empty fields match anything.
+type MessageFilter struct {
+	Status            string
+	Provider          string
+	ProviderMessageID string
+	CampaignID        string
+	TemplateID        string
+	Variant           string
+	CountryCode       string
+	Language          string
+	// SenderID matches messages from one sender; models.DefaultSenderID
+	// matches messages stored without one
+	SenderID string
+	// Metadata matches producer-supplied metadata entries exactly; keys must be
+	// valid metadata keys (see models.ValidMetadataKey)
+	Metadata map[string]string
+	// From is inclusive, To is exclusive
+	From time.Time
+	To   time.Time
+}
+
+// attributes returns the set attribute filters keyed by stored field name.
+func (f MessageFilter) attributes() bson.M {
+	attributes := bson.M{}
+	for field, value := range map[string]string{
+		"status":              f.Status,
+		"provider":            f.Provider,
+		"provider_message_id": f.ProviderMessageID,
+		"campaign_id":         f.CampaignID,
+		"template_id":         f.TemplateID,
+		"variant":             f.Variant,
+		"country_code":        f.CountryCode,
+		"language":            f.Language,
+	} {
+		if value != "" {
+			attributes[field] = value
+		}
+	}
+	if f.SenderID == models.DefaultSenderID {
+		attributes["sender_id"] = nil
+	} else if f.SenderID != "" {
+		attributes["sender_id"] = f.SenderID
+	}
+	for key, value := range f.Metadata {
+		attributes["metadata."+key] = value
+	}
+	return attributes
+}
+
+// IsZero reports whether the filter matches every visible message.
+func (f MessageFilter) IsZero() bool {
+	return len(f.attributes()) == 0 && f.From.IsZero() && f.To.IsZero()
+}
+
+// elementMatch is the query form of the filter for a single visible embedded
+// message, suitable for $elemMatch or, prefixed, for unwound documents.
+func (f MessageFilter) elementMatch(prefix string) bson.M {
+	match := bson.M{prefix + "deleted_at": bson.M{"$exists": false}}
+	for field, value := range f.attributes() {
+		match[prefix+field] = value
+	}
+	createdAt := bson.M{}
+	if !f.From.IsZero() {
+		createdAt["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		createdAt["$lt"] = f.To
+	}
+	if len(createdAt) > 0 {
+		match[prefix+"created_at"] = createdAt
+	}
+	return match
+}
+
+// MessageQuery narrows a user's message listing. Zero values mean unbounded.
+type MessageQuery struct {
+	MessageFilter
+	// Descending returns newest messages first instead of insertion order
+	Descending bool
+	// Fields limits each returned message to these stored fields; empty returns all
+	Fields []string
+}
+
+// GetUserMessages returns a user's visible (not soft-deleted) messages matching
+// the query from both storage tiers and the compacted collection. Filtering
+// happens server-side so only the window is transferred.
+func (r *Repository) GetUserMessages(ctx context.Context, phoneNumber string, query MessageQuery) (_ []models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "GetUserMessages", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
 	if err != nil {
 		return nil, err
 	}
-	collection := client.Database("smsstore").Collection("smsdata")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := r.withTimeout(ctx)
 	defer cancel()
 
-	filter := bson.M{"_id": phoneNumber}
-	var userData models.UserData
-	err = collection.FindOne(ctx, filter).Decode(&userData)
+	// Sources are merged by creation time, so it must survive projection
+	if len(query.Fields) > 0 && !containsString(query.Fields, "created_at") {
+		query.Fields = append(append([]string{}, query.Fields...), "created_at")
+	}
+	query.Fields = withStoredBody(query.Fields)
+
+	hotMessages, err := queryUserMessages(ctx, hot, phoneNumber, query)
+	if err != nil {
+		return nil, err
+	}
+	coldMessages, err := queryUserMessages(ctx, cold, phoneNumber, query)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			// User not found - return empty slice instead of error
-			return []models.MessageWithStatus{}, nil
+		return nil, err
+	}
+	compactedMessages, err := findCompactedMessages(ctx, compacted, phoneNumber, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeTiers(query.Descending, hotMessages, coldMessages, compactedMessages), nil
+}
+
+// GetUserMessagesSummary counts the messages GetUserMessages would list for
+// filter and versions the user's messages, without transferring them: the
+// summary's version combines the tiers' user document versions and the
+// compacted messages' versions, so any write to any of them changes it. A
+// message caught mid-move between sources may be counted twice for a moment.
+func (r *Repository) GetUserMessagesSummary(ctx context.Context, phoneNumber string, filter MessageFilter) (_ *models.MessagesSummary, err error) {
+	defer r.observe(ctx, "GetUserMessagesSummary", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	summary := &models.MessagesSummary{}
+	var versions []string
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		document, err := summarizeUserMessages(ctx, collection, phoneNumber, filter)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, document.add(summary))
+	}
+	document, err := summarizeCompactedMessages(ctx, compacted, phoneNumber, filter)
+	if err != nil {
+		return nil, err
+	}
+	versions = append(versions, document.add(summary))
+	summary.Version = strings.Join(versions, ".")
+	return summary, nil
+}
+
+// lastChange is the latest time a message at path was created, read, clicked,
+// deleted, retried or reconciled. It dates documents last written before modified_at was
+// stamped on every write.
+func lastChange(path string) bson.M {
+	return bson.M{"$max": bson.A{
+		bson.M{"$max": path + ".created_at"},
+		bson.M{"$max": path + ".read_at"},
+		bson.M{"$max": path + ".clicked_at"},
+		bson.M{"$max": path + ".deleted_at"},
+		bson.M{"$max": path + ".retried_at"},
+		bson.M{"$max": path + ".reconciled_at"},
+	}}
+}
+
+// messagesSummaryDocument summarizes one source of a user's messages: how
+// many match, how many documents hold them and the sum of their versions
+// (one user document per tier, one per message compacted), and when any of
+// them was last written.
+type messagesSummaryDocument struct {
+	Count        int       `bson:"count"`
+	Documents    int64     `bson:"documents"`
+	Version      int64     `bson:"version"`
+	LastModified time.Time `bson:"last_modified"`
+}
+
+// add counts a source's summary into summary and returns the source's part of
+// the summary's version. Removing a document changes the document count even
+// where the version sum is made up by another write.
+func (d messagesSummaryDocument) add(summary *models.MessagesSummary) string {
+	summary.Count += d.Count
+	if d.LastModified.After(summary.LastModified) {
+		summary.LastModified = d.LastModified
+	}
+	return fmt.Sprintf("%d.%d", d.Documents, d.Version)
+}
+
+// summarizeUserMessages summarizes a tier's user document.
+func summarizeUserMessages(ctx context.Context, collection *mongo.Collection, phoneNumber string, filter MessageFilter) (messagesSummaryDocument, error) {
+	messages := bson.M{"$ifNull": bson.A{"$messages", bson.A{}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": phoneNumber}}},
+		{{Key: "$project", Value: bson.M{
+			"count": bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": messages,
+				"as":    "m",
+				"cond":  messageConditions(filter),
+			}}},
+			"documents":     bson.M{"$literal": 1},
+			"version":       bson.M{"$ifNull": bson.A{"$" + versionField, 0}},
+			"last_modified": bson.M{"$max": bson.A{"$" + modifiedField, lastChange("$messages")}},
+		}}},
+	}
+	return summarize(ctx, collection, pipeline)
+}
+
+// summarize runs a summary pipeline, returning an empty summary if it matches
+// nothing.
+func summarize(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) (messagesSummaryDocument, error) {
+	var document messagesSummaryDocument
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return document, err
+	}
+	var results []messagesSummaryDocument
+	if err := cursor.All(ctx, &results); err != nil {
+		return document, err
+	}
+	if len(results) > 0 {
+		document = results[0]
+	}
+	return document, nil
+}
+
+// queryUserMessages runs the message listing against a single tier.
+func queryUserMessages(ctx context.Context, collection *mongo.Collection, phoneNumber string, query MessageQuery) ([]models.MessageWithStatus, error) {
+	var messages interface{} = bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$messages", bson.A{}}},
+		"as":    "m",
+		"cond":  messageConditions(query.MessageFilter),
+	}}
+	if query.Descending {
+		messages = bson.M{"$reverseArray": messages}
+	}
+	if len(query.Fields) > 0 {
+		projected := bson.M{}
+		for _, field := range query.Fields {
+			projected[field] = "$$m." + field
+		}
+		messages = bson.M{"$map": bson.M{"input": messages, "as": "m", "in": projected}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": phoneNumber}}},
+		{{Key: "$project", Value: bson.M{"messages": messages}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []models.UserData
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 || results[0].Messages == nil {
+		// User not found - return empty slice instead of error
+		return []models.MessageWithStatus{}, nil
+	}
+	return inflate(results[0].Messages), nil
+}
+
+// messageConditions builds the $filter condition applied to each embedded message ($$m).
+func messageConditions(filter MessageFilter) bson.M {
+	conditions := bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$$m.deleted_at"}, "missing"}},
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, bson.M{"$gte": bson.A{"$$m.created_at", filter.From}})
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, bson.M{"$lt": bson.A{"$$m.created_at", filter.To}})
+	}
+	for field, value := range filter.attributes() {
+		if value == nil {
+			// A missing field is not equal to null in expressions
+			conditions = append(conditions, bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$$m." + field, nil}}, nil}})
+			continue
 		}
+		conditions = append(conditions, bson.M{"$eq": bson.A{"$$m." + field, value}})
+	}
+	return bson.M{"$and": conditions}
+}
+
+// WatchUserMessages opens a change stream on the messages collection.
+// If resumeAfter is set the stream continues after that event.
+func (r *Repository) WatchUserMessages(ctx context.Context, resumeAfter bson.Raw) (_ *mongo.ChangeStream, err error) {
+	defer r.observe(ctx, "WatchUserMessages", time.Now(), &err)
+	collection, err := r.getCollection(ctx, smsDataCollection)
+	if err != nil {
 		return nil, err
 	}
-	return userData.Messages, nil
+
+	opts := options.ChangeStream()
+	if resumeAfter != nil {
+		opts.SetResumeAfter(resumeAfter)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	return collection.Watch(ctx, pipeline, opts)
+}
+
+// DeleteUser removes a user's documents and all of their messages from both
+// tiers and the compacted collection, recording the messages in the purge
+// ledger. Returns false if the user did not exist.
+func (r *Repository) DeleteUser(ctx context.Context, phoneNumber string) (_ bool, err error) {
+	defer r.observe(ctx, "DeleteUser", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	deleted := false
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		groups, err := countTierPurge(ctx, collection, bson.M{"_id": phoneNumber}, bson.M{})
+		if err != nil {
+			return false, err
+		}
+		purged, err := listPurged(ctx, collection, tierPurged(bson.M{"_id": phoneNumber}, bson.M{}))
+		if err != nil {
+			return false, err
+		}
+		result, err := collection.DeleteOne(ctx, bson.M{"_id": phoneNumber})
+		if err != nil {
+			return false, err
+		}
+		publishPurged(ctx, purged)
+		if err := r.recordPurges(ctx, models.PurgeSourceUserDeletion, groups); err != nil {
+			return false, err
+		}
+		deleted = deleted || result.DeletedCount > 0
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return false, err
+	}
+	compactedDeleted, err := r.purgeCompacted(ctx, compacted, models.PurgeSourceUserDeletion, bson.M{"user_id": phoneNumber})
+	if err != nil {
+		return false, err
+	}
+	if err := r.purgeCopies(ctx, bson.M{"user_id": phoneNumber}); err != nil {
+		return false, err
+	}
+	return deleted || compactedDeleted > 0, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SoftDeleteMessage flags a message as deleted so it is hidden from default
+// listings until it is restored or purged. Deleting an already deleted message
+// keeps the original deletion time. Returns nil if the message does not exist.
+func (r *Repository) SoftDeleteMessage(ctx context.Context, userID string, messageID string) (_ *models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "SoftDeleteMessage", time.Now(), &err)
+	now := time.Now().UTC()
+	update := bson.M{"$set": bson.M{"messages.$[m].deleted_at": now}}
+	arrayFilter := bson.M{"m.message_id": messageID, "m.deleted_at": bson.M{"$exists": false}}
+	compactedUpdate := bson.A{
+		versionedStage(bson.M{"$eq": bson.A{bson.M{"$type": "$deleted_at"}, "missing"}}),
+		bson.M{"$set": bson.M{"deleted_at": bson.M{"$ifNull": bson.A{"$deleted_at", now}}}},
+	}
+	return r.updateMessage(ctx, userID, messageID, update, arrayFilter, compactedUpdate)
+}
+
+// RestoreMessage clears a message's deletion flag. Returns nil if the message does not exist.
+func (r *Repository) RestoreMessage(ctx context.Context, userID string, messageID string) (_ *models.MessageWithStatus, err error) {
+	defer r.observe(ctx, "RestoreMessage", time.Now(), &err)
+	update := bson.M{"$unset": bson.M{"messages.$[m].deleted_at": ""}}
+	arrayFilter := bson.M{"m.message_id": messageID}
+	compactedUpdate := versioned(bson.M{"$unset": bson.M{"deleted_at": ""}})
+	return r.updateMessage(ctx, userID, messageID, update, arrayFilter, compactedUpdate)
+}
+
+// updateMessage applies update to the array element selected by arrayFilter
+// (bound as "m") in whichever tier holds the message, or compactedUpdate if it
+// has been compacted, and returns the message as stored afterwards.
+func (r *Repository) updateMessage(ctx context.Context, userID string, messageID string, update bson.M, arrayFilter bson.M, compactedUpdate interface{}) (*models.MessageWithStatus, error) {
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		message, err := updateTierMessage(ctx, collection, userID, messageID, update, arrayFilter)
+		if err != nil || message != nil {
+			return message, err
+		}
+	}
+
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	var document messageDocument
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = compacted.FindOneAndUpdate(ctx, bson.M{"_id": messageID, "user_id": userID}, compactedUpdate, opts).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	inflateMessage(&document.MessageWithStatus)
+	return &document.MessageWithStatus, nil
+}
+
+func updateTierMessage(ctx context.Context, collection *mongo.Collection, userID string, messageID string, update bson.M, arrayFilter bson.M) (*models.MessageWithStatus, error) {
+
+	opts := options.FindOneAndUpdate().
+		SetArrayFilters(options.ArrayFilters{Filters: []interface{}{arrayFilter}}).
+		SetProjection(bson.M{"messages": bson.M{"$elemMatch": bson.M{"message_id": messageID}}}).
+		SetReturnDocument(options.After)
+	filter := bson.M{"_id": userID, "messages.message_id": messageID}
+
+	var userData models.UserData
+	err := collection.FindOneAndUpdate(ctx, filter, versioned(update), opts).Decode(&userData)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(userData.Messages) == 0 {
+		return nil, nil
+	}
+	inflateMessage(&userData.Messages[0])
+	return &userData.Messages[0], nil
+}
+
+// PurgeDeletedMessagesBefore permanently removes messages soft-deleted before
+// cutoff from both tiers and the compacted collection, recording them in the
+// purge ledger. Returns the number of documents modified or deleted.
+func (r *Repository) PurgeDeletedMessagesBefore(ctx context.Context, cutoff time.Time) (_ int64, err error) {
+	defer r.observe(ctx, "PurgeDeletedMessagesBefore", time.Now(), &err)
+	hot, cold, err := r.tierCollections(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	filter := bson.M{"messages.deleted_at": bson.M{"$lt": cutoff}}
+	element := bson.M{"deleted_at": bson.M{"$lt": cutoff}}
+	var modified int64
+	for _, collection := range []*mongo.Collection{cold, hot} {
+		tierModified, err := r.purgeTier(ctx, collection, models.PurgeSourceDeletion, filter, element)
+		modified += tierModified
+		if err != nil {
+			return modified, err
+		}
+	}
+	compacted, err := r.getCollection(ctx, messagesCollection)
+	if err != nil {
+		return modified, err
+	}
+	compactedFilter := bson.M{"deleted_at": bson.M{"$lt": cutoff}}
+	deleted, err := r.purgeCompacted(ctx, compacted, models.PurgeSourceDeletion, compactedFilter)
+	if err != nil {
+		return modified + deleted, err
+	}
+	return modified + deleted, r.purgeCopies(ctx, compactedFilter)
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"smsstore/internal/langdetect"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type statusCount struct {
+	Key struct {
+		Status   string `bson:"status"`
+		Language string `bson:"language"`
+	} `bson:"_id"`
+	Count  int        `bson:"count"`
+	Unread []string   `bson:"unread"`
+	First  *time.Time `bson:"first"`
+	Last   *time.Time `bson:"last"`
+}
+
+// unreadSend evaluates to the send a message document belongs to when it is
+// a delivered message without a read receipt, otherwise to null. A send is
+// stored as one document per status event, so unread messages are counted by
+// collecting these into a set. path is the prefix of the message fields.
+func unreadSend(path string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$" + path + "status", models.StatusDelivered}},
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + path + "read_at", nil}}, nil}},
+		}},
+		bson.M{"$ifNull": bson.A{"$" + path + "provider_message_id", "$" + path + "message_id"}},
+		nil,
+	}}
+}
+
+// addUnread adds the sends collected by unreadSend to unread.
+func addUnread(unread map[string]bool, sends []string) {
+	for _, send := range sends {
+		if send != "" {
+			unread[send] = true
+		}
+	}
+}
+
+// GetUserStats counts a user's visible messages by status and by language, and
+// the delivered messages without a read receipt, across both tiers and the
+// compacted collection.
+// Messages stored before language detection are counted under "und".
+func (r *Repository) GetUserStats(ctx context.Context, userID string) (_ *models.UserStats, err error) {
+	defer r.observe(ctx, "GetUserStats", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classAnalytics)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": userID}}},
+		{{Key: "$unwind", Value: "$messages"}},
+		{{Key: "$match", Value: bson.M{"messages.deleted_at": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"status":   "$messages.status",
+				"language": bson.M{"$ifNull": bson.A{"$messages.language", langdetect.Undetermined}},
+			},
+			"count":  bson.M{"$sum": 1},
+			"unread": bson.M{"$addToSet": unreadSend("messages.")},
+			"first":  bson.M{"$min": "$messages.created_at"},
+			"last":   bson.M{"$max": "$messages.created_at"},
+		}}},
+	}
+	var counts []statusCount
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var tierCounts []statusCount
+		if err := cursor.All(ctx, &tierCounts); err != nil {
+			return nil, err
+		}
+		counts = append(counts, tierCounts...)
+	}
+
+	compacted, err := r.getCollectionFor(ctx, classAnalytics, messagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := compacted.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"status":   "$status",
+				"language": bson.M{"$ifNull": bson.A{"$language", langdetect.Undetermined}},
+			},
+			"count":  bson.M{"$sum": 1},
+			"unread": bson.M{"$addToSet": unreadSend("")},
+			"first":  bson.M{"$min": "$created_at"},
+			"last":   bson.M{"$max": "$created_at"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var compactedCounts []statusCount
+	if err := cursor.All(ctx, &compactedCounts); err != nil {
+		return nil, err
+	}
+	counts = append(counts, compactedCounts...)
+
+	stats := &models.UserStats{UserID: userID, ByStatus: map[string]int{}, ByLanguage: map[string]int{}}
+	unread := map[string]bool{}
+	for _, count := range counts {
+		stats.Total += count.Count
+		addUnread(unread, count.Unread)
+		stats.ByStatus[count.Key.Status] += count.Count
+		stats.ByLanguage[count.Key.Language] += count.Count
+		if count.First != nil && (stats.FirstMessage == nil || count.First.Before(*stats.FirstMessage)) {
+			stats.FirstMessage = count.First
+		}
+		if count.Last != nil && (stats.LatestMessage == nil || count.Last.After(*stats.LatestMessage)) {
+			stats.LatestMessage = count.Last
+		}
+	}
+	stats.UnreadCount = len(unread)
+	return stats, nil
+}
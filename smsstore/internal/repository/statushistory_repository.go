@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"smsstore/internal/statusflow"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// statusArchiveCollection keeps the status events dropped by status history
+// compaction when archiving is on, one document per message in the compacted
+// collection's shape. Nothing reads it back; purges cover it like the tiers.
+const statusArchiveCollection = "status_archive"
+
+// CompactStatusHistories thins out the status histories of a user's sends
+// (messages sharing a provider message ID) with at least minStatuses stored
+// statuses, keeping the statuses statusflow.Keep selects and dropping interim
+// repeats from every tier. With archive the dropped messages are first copied
+// to the status archive. Soft-deleted messages and messages without an ID
+// are left alone. Dropping is by message ID, so a rerun after a failure only
+// finishes the job. Returns the number of messages dropped.
+func (r *Repository) CompactStatusHistories(ctx context.Context, userID string, minStatuses int, archive bool) (_ int, err error) {
+	defer r.observe(ctx, "CompactStatusHistories", time.Now(), &err)
+	messages, err := r.GetStoredMessages(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	dropped := droppedStatuses(messages, minStatuses)
+	if len(dropped) == 0 {
+		return 0, nil
+	}
+
+	hot, cold, err := r.tierCollectionsFor(ctx, classCritical)
+	if err != nil {
+		return 0, err
+	}
+	compacted, err := r.getCollectionFor(ctx, classCritical, messagesCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	ids := make([]string, 0, len(dropped))
+	for _, message := range dropped {
+		ids = append(ids, message.MessageID)
+	}
+	if archive {
+		if err := r.archiveStatuses(ctx, userID, dropped); err != nil {
+			return 0, err
+		}
+	}
+	pull := versioned(bson.M{"$pull": bson.M{"messages": bson.M{"message_id": bson.M{"$in": ids}}}})
+	for _, collection := range []*mongo.Collection{hot, cold} {
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, pull); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := compacted.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID}); err != nil {
+		return 0, err
+	}
+	return len(dropped), nil
+}
+
+// droppedStatuses groups messages (oldest first) by send and returns the ones
+// compaction drops from histories of at least minStatuses statuses.
+func droppedStatuses(messages []models.MessageWithStatus, minStatuses int) []models.MessageWithStatus {
+	sends := map[string][]models.MessageWithStatus{}
+	var order []string
+	for _, message := range messages {
+		if message.ProviderMessageID == "" || message.DeletedAt != nil {
+			continue
+		}
+		if _, seen := sends[message.ProviderMessageID]; !seen {
+			order = append(order, message.ProviderMessageID)
+		}
+		sends[message.ProviderMessageID] = append(sends[message.ProviderMessageID], message)
+	}
+
+	var dropped []models.MessageWithStatus
+	for _, providerMessageID := range order {
+		history := sends[providerMessageID]
+		if len(history) < minStatuses {
+			continue
+		}
+		statuses := make([]string, len(history))
+		for i, message := range history {
+			statuses[i] = strings.ToLower(message.Status)
+		}
+		for i, keep := range statusflow.Keep(statuses) {
+			if !keep && history[i].MessageID != "" {
+				dropped = append(dropped, history[i])
+			}
+		}
+	}
+	return dropped
+}
+
+// archiveStatuses copies dropped status events to the status archive;
+// copies left by an earlier attempt are kept.
+func (r *Repository) archiveStatuses(ctx context.Context, userID string, messages []models.MessageWithStatus) error {
+	collection, err := r.getCollection(ctx, statusArchiveCollection)
+	if err != nil {
+		return err
+	}
+	documents := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		documents = append(documents, messageDocument{ID: message.MessageID, UserID: userID, MessageWithStatus: message})
+	}
+	_, err = collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeyErrors(err) {
+		return err
+	}
+	return nil
+}
+
+// purgeStatusArchive deletes the archived status events of purged messages,
+// selected by the filter used on the compacted collection.
+func (r *Repository) purgeStatusArchive(ctx context.Context, filter bson.M) error {
+	collection, err := r.getCollection(ctx, statusArchiveCollection)
+	if err != nil {
+		return err
+	}
+	_, err = collection.DeleteMany(ctx, filter)
+	return err
+}
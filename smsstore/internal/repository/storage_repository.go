@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const storageReportsCollection = "storage_reports"
+
+// ReportDateFormat is the layout of storage report dates.
+const ReportDateFormat = "2006-01-02"
+
+// storageScanTimeout bounds each aggregation over a whole message collection.
+const storageScanTimeout = 30 * time.Minute
+
+// namespaceNotFound is the server error code for a collection that doesn't exist.
+const namespaceNotFound = 26
+
+type storageGroup struct {
+	TenantID      string               `bson:"_id"`
+	Messages      int64                `bson:"messages"`
+	MessageBytes  int64                `bson:"message_bytes"`
+	UserDocuments int64                `bson:"user_documents"`
+	LargestUsers  []models.UserStorage `bson:"largest_users"`
+}
+
+// BuildStorageReport measures storage usage in every database holding message
+// data: the size of each collection from $collStats, and each tenant's share
+// of the message collections from the BSON size of its messages, with its
+// topUsers largest user documents. The message collections are scanned in
+// full on the analytics client, so this is meant for the nightly reporter.
+func (r *Repository) BuildStorageReport(ctx context.Context, date string, topUsers int) (_ *models.StorageReport, err error) {
+	defer r.observe(ctx, "BuildStorageReport", time.Now(), &err)
+
+	report := &models.StorageReport{Date: date, Collections: []models.CollectionStorage{}, Tenants: []models.TenantStorage{}}
+	tenants := map[string]*models.TenantStorage{}
+	for _, scope := range r.Scopes() {
+		scopeCtx := scope.Context(ctx)
+
+		names := make([]string, 0, len(regionalCollections))
+		for name := range regionalCollections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			usage, err := r.collectionStorage(scopeCtx, name)
+			if err != nil {
+				return nil, err
+			}
+			if usage == nil {
+				continue
+			}
+			usage.Region = scope.Region
+			usage.Database = r.databaseName(scope)
+			report.Collections = append(report.Collections, *usage)
+			report.Documents += usage.Documents
+			report.DataBytes += usage.DataBytes
+			report.StorageBytes += usage.StorageBytes
+			report.IndexBytes += usage.IndexBytes
+		}
+
+		for _, name := range []string{smsDataCollection, coldDataCollection, messagesCollection} {
+			groups, err := r.tenantStorage(scopeCtx, name, topUsers)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range groups {
+				tenant := tenants[group.TenantID]
+				if tenant == nil {
+					tenant = &models.TenantStorage{TenantID: group.TenantID, ByCollection: map[string]int64{}, LargestUsers: []models.UserStorage{}}
+					tenants[group.TenantID] = tenant
+				}
+				tenant.Messages += group.Messages
+				tenant.MessageBytes += group.MessageBytes
+				tenant.UserDocuments += group.UserDocuments
+				tenant.ByCollection[name] += group.MessageBytes
+				for _, user := range group.LargestUsers {
+					user.Region = scope.Region
+					user.Collection = name
+					tenant.LargestUsers = append(tenant.LargestUsers, user)
+				}
+			}
+		}
+	}
+
+	for _, tenant := range tenants {
+		sort.SliceStable(tenant.LargestUsers, func(i, j int) bool {
+			return tenant.LargestUsers[i].DocumentBytes > tenant.LargestUsers[j].DocumentBytes
+		})
+		if len(tenant.LargestUsers) > topUsers {
+			tenant.LargestUsers = tenant.LargestUsers[:topUsers]
+		}
+		report.Tenants = append(report.Tenants, *tenant)
+	}
+	sort.Slice(report.Tenants, func(i, j int) bool {
+		return report.Tenants[i].MessageBytes > report.Tenants[j].MessageBytes
+	})
+	report.GeneratedAt = time.Now().UTC()
+	return report, nil
+}
+
+// collectionStorage returns the size of collection name in ctx's scope,
+// summed across shards, or nil if the collection doesn't exist.
+func (r *Repository) collectionStorage(ctx context.Context, name string) (*models.CollectionStorage, error) {
+	collection, err := r.getCollectionFor(ctx, classAnalytics, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$collStats", Value: bson.M{"storageStats": bson.M{}}}},
+	})
+	if err != nil {
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) && serverErr.HasErrorCode(namespaceNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var shards []struct {
+		StorageStats struct {
+			Count          int64 `bson:"count"`
+			Size           int64 `bson:"size"`
+			StorageSize    int64 `bson:"storageSize"`
+			TotalIndexSize int64 `bson:"totalIndexSize"`
+		} `bson:"storageStats"`
+	}
+	if err := cursor.All(ctx, &shards); err != nil {
+		return nil, err
+	}
+	usage := &models.CollectionStorage{Collection: name}
+	for _, shard := range shards {
+		usage.Documents += shard.StorageStats.Count
+		usage.DataBytes += shard.StorageStats.Size
+		usage.StorageBytes += shard.StorageStats.StorageSize
+		usage.IndexBytes += shard.StorageStats.TotalIndexSize
+	}
+	return usage, nil
+}
+
+// tenantStorage groups the messages of collection name in ctx's scope by
+// tenant. For the tiers, where messages are embedded in user documents, each
+// group also counts the user documents holding the tenant's messages and
+// lists the topUsers largest of them.
+func (r *Repository) tenantStorage(ctx context.Context, name string, topUsers int) ([]storageGroup, error) {
+	collection, err := r.getCollectionFor(ctx, classAnalytics, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageScanTimeout)
+	defer cancel()
+
+	tenantID := func(field string) bson.M { return bson.M{"$ifNull": bson.A{field, ""}} }
+	var pipeline mongo.Pipeline
+	if name == messagesCollection {
+		pipeline = mongo.Pipeline{
+			{{Key: "$group", Value: bson.M{
+				"_id":           tenantID("$tenant_id"),
+				"messages":      bson.M{"$sum": 1},
+				"message_bytes": bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}},
+			}}},
+		}
+	} else {
+		pipeline = mongo.Pipeline{
+			{{Key: "$project", Value: bson.M{"messages": 1, "document_bytes": bson.M{"$bsonSize": "$$ROOT"}}}},
+			{{Key: "$unwind", Value: "$messages"}},
+			{{Key: "$group", Value: bson.M{
+				"_id":            bson.M{"tenant_id": tenantID("$messages.tenant_id"), "user_id": "$_id"},
+				"document_bytes": bson.M{"$first": "$document_bytes"},
+				"messages":       bson.M{"$sum": 1},
+				"message_bytes":  bson.M{"$sum": bson.M{"$bsonSize": "$messages"}},
+			}}},
+			{{Key: "$group", Value: bson.M{
+				"_id":            "$_id.tenant_id",
+				"messages":       bson.M{"$sum": "$messages"},
+				"message_bytes":  bson.M{"$sum": "$message_bytes"},
+				"user_documents": bson.M{"$sum": 1},
+				"largest_users": bson.M{"$topN": bson.M{
+					"n":      topUsers,
+					"sortBy": bson.M{"document_bytes": -1},
+					"output": bson.M{
+						"user_id":        "$_id.user_id",
+						"document_bytes": "$document_bytes",
+						"messages":       "$messages",
+						"message_bytes":  "$message_bytes",
+					},
+				}},
+			}}},
+		}
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	var groups []storageGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// SaveStorageReport stores a report, replacing any earlier one for its date.
+func (r *Repository) SaveStorageReport(ctx context.Context, report *models.StorageReport) (err error) {
+	defer r.observe(ctx, "SaveStorageReport", time.Now(), &err)
+	collection, err := r.getCollection(ctx, storageReportsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": report.Date}, report, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetStorageReport returns the stored report for date, or nil if none exists.
+func (r *Repository) GetStorageReport(ctx context.Context, date string) (_ *models.StorageReport, err error) {
+	defer r.observe(ctx, "GetStorageReport", time.Now(), &err)
+	collection, err := r.getCollection(ctx, storageReportsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var report models.StorageReport
+	if err := collection.FindOne(ctx, bson.M{"_id": date}).Decode(&report); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListStorageReports returns the stored reports from since on without their
+// collections and tenants, newest date first.
+func (r *Repository) ListStorageReports(ctx context.Context, since string) (_ []models.StorageReport, err error) {
+	defer r.observe(ctx, "ListStorageReports", time.Now(), &err)
+	collection, err := r.getCollection(ctx, storageReportsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"collections": 0, "tenants": 0})
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	reports := []models.StorageReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+)
+
+// MessageStore is the message storage the consumer and the public API are
+// built on. Repository implements it; tests and alternative backends can
+// supply their own.
+type MessageStore interface {
+	AddMessageToUser(ctx context.Context, event models.SmsEvent) (*models.MessageWithStatus, error)
+	AddMessageToUserIdempotent(ctx context.Context, event models.SmsEvent) (*models.MessageWithStatus, bool, error)
+	AddMessageToUserDeduplicated(ctx context.Context, event models.SmsEvent, window time.Duration) (*models.MessageWithStatus, bool, error)
+	MarkMessagesRead(ctx context.Context, userID string, providerMessageID string, readAt time.Time) (bool, error)
+	MarkMessagesClicked(ctx context.Context, userID string, providerMessageID string, clickedAt time.Time) (bool, error)
+	CompactUser(ctx context.Context, userID string) (int, error)
+	RecordRollup(ctx context.Context, message *models.MessageWithStatus) error
+
+	GetUserMessages(ctx context.Context, userID string, query MessageQuery) ([]models.MessageWithStatus, error)
+	GetUserMessagesSummary(ctx context.Context, userID string, filter MessageFilter) (*models.MessagesSummary, error)
+	SearchMessages(ctx context.Context, userID string, filter MessageFilter, limit int) ([]models.SearchResult, error)
+	SoftDeleteMessage(ctx context.Context, userID string, messageID string) (*models.MessageWithStatus, error)
+	RestoreMessage(ctx context.Context, userID string, messageID string) (*models.MessageWithStatus, error)
+	ListConversations(ctx context.Context, userID string) ([]models.Conversation, error)
+
+	GetUserStats(ctx context.Context, userID string) (*models.UserStats, error)
+	GetUserStatsSnapshot(ctx context.Context, userID string) (*models.UserStats, error)
+	CountMessagesBy(ctx context.Context, groupBy string, filter MessageFilter) ([]models.AnalyticsBucket, error)
+	DeliveryLatencyBy(ctx context.Context, groupBy string, filter MessageFilter) ([]models.AnalyticsBucket, error)
+	CountClickedBy(ctx context.Context, groupBy string, filter MessageFilter) ([]models.AnalyticsBucket, error)
+	GetRollups(ctx context.Context, granularity string, tenantID string, from time.Time, to time.Time) ([]models.MessageRollup, error)
+}
+
+var _ MessageStore = (*Repository)(nil)
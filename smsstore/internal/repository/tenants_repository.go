@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const tenantConfigsCollection = "tenant_configs"
+
+// SaveTenantConfig creates or replaces a tenant's configuration.
+func (r *Repository) SaveTenantConfig(ctx context.Context, config *models.TenantConfig) (err error) {
+	defer r.observe(ctx, "SaveTenantConfig", time.Now(), &err)
+	collection, err := r.getCollection(ctx, tenantConfigsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	_, err = collection.ReplaceOne(ctx, bson.M{"_id": config.TenantID}, config, opts)
+	return err
+}
+
+// GetTenantConfig returns a tenant's configuration, or nil if none is set.
+func (r *Repository) GetTenantConfig(ctx context.Context, tenantID string) (_ *models.TenantConfig, err error) {
+	defer r.observe(ctx, "GetTenantConfig", time.Now(), &err)
+	collection, err := r.getCollection(ctx, tenantConfigsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var config models.TenantConfig
+	if err := collection.FindOne(ctx, bson.M{"_id": tenantID}).Decode(&config); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ListTenantConfigs returns every tenant's configuration, ordered by tenant ID.
+func (r *Repository) ListTenantConfigs(ctx context.Context) (_ []models.TenantConfig, err error) {
+	defer r.observe(ctx, "ListTenantConfigs", time.Now(), &err)
+	collection, err := r.getCollection(ctx, tenantConfigsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	configs := []models.TenantConfig{}
+	if err := cursor.All(ctx, &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// DeleteTenantConfig removes a tenant's configuration. Returns false if none existed.
+func (r *Repository) DeleteTenantConfig(ctx context.Context, tenantID string) (_ bool, err error) {
+	defer r.observe(ctx, "DeleteTenantConfig", time.Now(), &err)
+	collection, err := r.getCollection(ctx, tenantConfigsCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": tenantID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
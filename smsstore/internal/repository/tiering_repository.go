@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Messages live in one of two collections with the same per-user document
+// shape: the hot tier (smsdata) holds recent messages and carries the query
+// indexes, the cold tier (smsdata_cold) holds everything older than the hot
+// window with minimal indexes. Reads and purges cover both tiers; new messages
+// are always written to the hot tier.
+
+// tierCollections returns the hot and cold collections.
+func (r *Repository) tierCollections(ctx context.Context) (hot *mongo.Collection, cold *mongo.Collection, err error) {
+	return r.tierCollectionsFor(ctx, classDefault)
+}
+
+// tierCollectionsFor returns both tiers configured for an operation class.
+func (r *Repository) tierCollectionsFor(ctx context.Context, class string) (hot *mongo.Collection, cold *mongo.Collection, err error) {
+	if hot, err = r.getCollectionFor(ctx, class, smsDataCollection); err != nil {
+		return nil, nil, err
+	}
+	if cold, err = r.getCollectionFor(ctx, class, coldDataCollection); err != nil {
+		return nil, nil, err
+	}
+	return hot, cold, nil
+}
+
+// mergeTiers combines per-source listings into one ordered by creation time. A
+// message caught mid-move can be present in two sources for a moment, so
+// repeated message IDs are dropped.
+func mergeTiers(descending bool, sources ...[]models.MessageWithStatus) []models.MessageWithStatus {
+	merged := []models.MessageWithStatus{}
+	seen := map[string]struct{}{}
+	for _, source := range sources {
+		for _, message := range source {
+			if message.MessageID != "" {
+				if _, dup := seen[message.MessageID]; dup {
+					continue
+				}
+				seen[message.MessageID] = struct{}{}
+			}
+			merged = append(merged, message)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if descending {
+			return merged[i].CreatedAt.After(merged[j].CreatedAt)
+		}
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+	return merged
+}
+
+type tierBatch struct {
+	UserID   string     `bson:"_id"`
+	Version  int64      `bson:"version"`
+	Messages []bson.Raw `bson:"messages"`
+}
+
+// MoveMessagesToCold moves up to batchSize users' messages created before cutoff
+// from the hot tier to the cold tier. Each user's messages are copied to the
+// cold tier before being pulled from the hot tier, so a failure in between
+// leaves a duplicate that the next run resolves rather than losing data. The
+// pull only applies if the hot document is unchanged since it was read;
+// otherwise the copy is undone and the user's messages are read again.
+// Returns the number of messages moved; zero means nothing is left to move.
+func (r *Repository) MoveMessagesToCold(ctx context.Context, cutoff time.Time, batchSize int) (_ int, err error) {
+	defer r.observe(ctx, "MoveMessagesToCold", time.Now(), &err)
+	hot, cold, err := r.tierCollectionsFor(ctx, classCritical)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	batches, err := findTierBatches(ctx, hot, bson.M{"messages.created_at": bson.M{"$lt": cutoff}}, cutoff, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, batch := range batches {
+		err := retryOnConflict(ctx, "MoveMessagesToCold", func() error {
+			count, err := moveUserToCold(ctx, hot, cold, batch, cutoff)
+			moved += count
+			if !errors.Is(err, errVersionConflict) {
+				return err
+			}
+			fresh, readErr := findTierBatches(ctx, hot, bson.M{"_id": batch.UserID, "messages.created_at": bson.M{"$lt": cutoff}}, cutoff, 1)
+			if readErr != nil {
+				return readErr
+			}
+			batch = tierBatch{UserID: batch.UserID}
+			if len(fresh) > 0 {
+				batch = fresh[0]
+			}
+			return err
+		})
+		if err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// findTierBatches reads, for up to limit users matching match, the version and
+// the messages created before cutoff.
+func findTierBatches(ctx context.Context, hot *mongo.Collection, match bson.M, cutoff time.Time, limit int) ([]tierBatch, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{
+			versionField: 1,
+			"messages": bson.M{"$filter": bson.M{
+				"input": "$messages",
+				"as":    "m",
+				"cond":  bson.M{"$lt": bson.A{"$$m.created_at", cutoff}},
+			}},
+		}}},
+	}
+	cursor, err := hot.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var batches []tierBatch
+	if err := cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// moveUserToCold copies a user's batch to the cold tier and pulls it from the
+// hot tier. If the hot document changed since the batch was read the copy is
+// removed again and errVersionConflict returned.
+func moveUserToCold(ctx context.Context, hot, cold *mongo.Collection, batch tierBatch, cutoff time.Time) (int, error) {
+	if len(batch.Messages) == 0 {
+		return 0, nil
+	}
+	// Raw documents keep every stored field and make $addToSet idempotent on retry
+	copyUpdate := versioned(bson.M{"$addToSet": bson.M{"messages": bson.M{"$each": batch.Messages}}})
+	if _, err := cold.UpdateOne(ctx, bson.M{"_id": batch.UserID}, copyUpdate, options.Update().SetUpsert(true)); err != nil {
+		return 0, err
+	}
+	pullUpdate := versioned(bson.M{"$pull": bson.M{"messages": bson.M{"created_at": bson.M{"$lt": cutoff}}}})
+	result, err := hot.UpdateOne(ctx, atVersion(batch.UserID, batch.Version), pullUpdate)
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount > 0 {
+		return len(batch.Messages), nil
+	}
+
+	// The copies are stale (or the user is gone); they are still in the hot tier
+	undo := versioned(bson.M{"$pull": bson.M{"messages": bson.M{"$in": batch.Messages}}})
+	if _, err := cold.UpdateOne(ctx, bson.M{"_id": batch.UserID}, undo); err != nil {
+		return 0, err
+	}
+	if _, err := cold.DeleteOne(ctx, bson.M{"_id": batch.UserID, "messages": bson.M{"$size": 0}}); err != nil {
+		return 0, err
+	}
+	return 0, errVersionConflict
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListUsers returns user summaries ordered by user ID, starting after the
+// afterUserID cursor. A zero updatedAfter disables the activity filter.
+func (r *Repository) ListUsers(ctx context.Context, updatedAfter time.Time, afterUserID string, limit int) (_ []models.UserSummary, err error) {
+	defer r.observe(ctx, "ListUsers", time.Now(), &err)
+	collection, err := r.getCollection(ctx, smsDataCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	match := bson.M{}
+	if !updatedAfter.IsZero() {
+		match["updated_at"] = bson.M{"$gt": updatedAfter}
+	}
+	if afterUserID != "" {
+		match["_id"] = bson.M{"$gt": afterUserID}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		// Fold in the cold tier; it has at most one document per user
+		{{Key: "$lookup", Value: bson.M{"from": coldDataCollection, "localField": "_id", "foreignField": "_id", "as": "cold"}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$cold", "preserveNullAndEmptyArrays": true}}},
+		// and the compacted collection, summarized server-side
+		{{Key: "$lookup", Value: bson.M{
+			"from": messagesCollection,
+			"let":  bson.M{"user": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$user_id", "$$user"}}}},
+				bson.M{"$group": bson.M{"_id": nil, "count": bson.M{"$sum": 1}, "last": bson.M{"$max": "$created_at"}}},
+			},
+			"as": "compacted",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$compacted", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$project", Value: bson.M{
+			"message_count": bson.M{"$add": bson.A{
+				bson.M{"$size": bson.M{"$ifNull": bson.A{"$messages", bson.A{}}}},
+				bson.M{"$size": bson.M{"$ifNull": bson.A{"$cold.messages", bson.A{}}}},
+				bson.M{"$ifNull": bson.A{"$compacted.count", 0}},
+			}},
+			"last_activity": bson.M{"$max": bson.A{
+				bson.M{"$max": "$messages.created_at"},
+				bson.M{"$max": "$cold.messages.created_at"},
+				"$compacted.last",
+			}},
+			"updated_at": 1,
+		}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	users := []models.UserSummary{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
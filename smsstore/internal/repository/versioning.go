@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"smsstore/internal/metrics"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// User documents (both tiers) carry a version that every write increments.
+// Single atomic updates don't need it, but operations that read a user's
+// messages and then write based on what they read (tier moves, compaction)
+// make their final write conditional on the version they read. When another
+// replica wrote in between - a new message, a receipt, a deletion, a purge -
+// the operation undoes its partial work and starts over, so the concurrent
+// write is never lost. The version, and the modified_at time stamped with it,
+// are also what listing validators (ETag, Last-Modified) are built from, so
+// per-message documents in the compacted collection carry both too.
+
+const (
+	versionField  = "version"
+	modifiedField = "modified_at"
+)
+
+// maxVersionAttempts bounds how often a read-modify-write is tried per user.
+const maxVersionAttempts = 5
+
+// versionBackoff is the first pause after a conflict; it doubles, with jitter.
+const versionBackoff = 10 * time.Millisecond
+
+// errVersionConflict reports that a user document changed since it was read.
+var errVersionConflict = errors.New("repository: user document changed concurrently")
+
+// versioned adds the version increment and modification time to a document
+// update.
+func versioned(update bson.M) bson.M {
+	update["$inc"] = bson.M{versionField: 1}
+	update["$currentDate"] = bson.M{modifiedField: true}
+	return update
+}
+
+// versionedStage is versioned for pipeline updates: a stage, to run before
+// the ones making the change, that increments the version and stamps the
+// modification time if changed (an expression over the document as it was)
+// holds.
+func versionedStage(changed interface{}) bson.M {
+	return bson.M{"$set": bson.M{
+		versionField:  bson.M{"$cond": bson.A{changed, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + versionField, 0}}, 1}}, "$" + versionField}},
+		modifiedField: bson.M{"$cond": bson.A{changed, "$$NOW", "$" + modifiedField}},
+	}}
+}
+
+// atVersion matches a user document only while it is still at version, as
+// read earlier. Documents written before versioning have no version (zero).
+func atVersion(userID string, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": userID, versionField: bson.M{"$exists": false}}
+	}
+	return bson.M{"_id": userID, versionField: version}
+}
+
+// retryOnConflict runs attempt until it finishes without a version conflict,
+// up to maxVersionAttempts times, backing off between attempts.
+func retryOnConflict(ctx context.Context, operation string, attempt func() error) error {
+	backoff := versionBackoff
+	for i := 1; ; i++ {
+		err := attempt()
+		if !errors.Is(err, errVersionConflict) {
+			return err
+		}
+		metrics.VersionConflicts.WithLabelValues(operation).Inc()
+		if i == maxVersionAttempts {
+			return fmt.Errorf("%s: %w (%d attempts)", operation, err, i)
+		}
+		// Jitter keeps replicas retrying the same user from colliding again
+		pause := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pause):
+		}
+		backoff *= 2
+	}
+}
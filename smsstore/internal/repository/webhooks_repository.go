@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"smsstore/pkg/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	webhookSubscriptionsCollection = "webhook_subscriptions"
+	webhookDeliveriesCollection    = "webhook_deliveries"
+)
+
+// InsertWebhookSubscription persists a new subscription.
+func (r *Repository) InsertWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) (err error) {
+	defer r.observe(ctx, "InsertWebhookSubscription", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookSubscriptionsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.InsertOne(ctx, subscription)
+	return err
+}
+
+// GetWebhookSubscription returns a subscription by ID, or nil if it does not exist.
+func (r *Repository) GetWebhookSubscription(ctx context.Context, id string) (_ *models.WebhookSubscription, err error) {
+	defer r.observe(ctx, "GetWebhookSubscription", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookSubscriptionsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var subscription models.WebhookSubscription
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListWebhookSubscriptions returns every subscription, oldest first.
+func (r *Repository) ListWebhookSubscriptions(ctx context.Context) (_ []models.WebhookSubscription, err error) {
+	defer r.observe(ctx, "ListWebhookSubscriptions", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookSubscriptionsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := []models.WebhookSubscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription removes a subscription. Its delivery log is kept
+// until it expires. Returns false if the subscription did not exist.
+func (r *Repository) DeleteWebhookSubscription(ctx context.Context, id string) (_ bool, err error) {
+	defer r.observe(ctx, "DeleteWebhookSubscription", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookSubscriptionsCollection)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// InsertWebhookDelivery appends an attempt to a subscription's delivery log.
+func (r *Repository) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (err error) {
+	defer r.observe(ctx, "InsertWebhookDelivery", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookDeliveriesCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err = collection.InsertOne(ctx, delivery)
+	return err
+}
+
+// ListWebhookDeliveries returns a subscription's most recent delivery attempts,
+// newest first. A non-zero before pages back from that time.
+func (r *Repository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, before time.Time, limit int64) (_ []models.WebhookDelivery, err error) {
+	defer r.observe(ctx, "ListWebhookDeliveries", time.Now(), &err)
+	collection, err := r.getCollection(ctx, webhookDeliveriesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"subscription_id": subscriptionID}
+	if !before.IsZero() {
+		filter["attempted_at"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "attempted_at", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
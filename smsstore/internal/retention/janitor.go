@@ -0,0 +1,119 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"smsstore/internal/tenants"
+	"smsstore/pkg/models"
+	"sort"
+	"time"
+)
+
+// StartJanitor periodically deletes messages older than the configured retention.
+// Messages of tenants configured with their own retention are purged using
+// the tenant's period, and users with a retention override using their own
+// period, instead of the global one. Blocks until ctx is cancelled.
+func StartJanitor(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if cfg.RetentionDays == 0 {
+		log.Println("[RETENTION] Janitor disabled (RETENTION_DAYS=0)")
+		return
+	}
+
+	log.Printf("[RETENTION] Janitor started: retention=%dd, interval=%s", cfg.RetentionDays, cfg.RetentionInterval)
+	ticker := time.NewTicker(cfg.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		runOnce(ctx, repo, cfg.RetentionDays)
+		select {
+		case <-ctx.Done():
+			log.Println("[RETENTION] Janitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, repo *repository.Repository, globalDays int) {
+	now := time.Now().UTC()
+
+	overrides, err := repo.ListRetentionOverrides(ctx)
+	if err != nil {
+		// Without the override list we could delete VIP history early, so skip this run.
+		log.Printf("[RETENTION] Failed to load retention overrides, skipping run: %v", err)
+		return
+	}
+
+	configs, err := tenants.Snapshot(ctx)
+	if err != nil {
+		// Likewise for tenants keeping their messages longer than the global period
+		log.Printf("[RETENTION] Failed to load tenant configurations, skipping run: %v", err)
+		return
+	}
+
+	excluded := make([]string, 0, len(overrides))
+	for _, override := range overrides {
+		excluded = append(excluded, override.UserID)
+	}
+	var tenantIDs []string
+	for tenantID, config := range configs {
+		if config.RetentionDays != nil {
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+	}
+	sort.Strings(tenantIDs)
+
+	// Residency regions and tenants with their own database keep their
+	// messages apart
+	for _, scope := range repo.Scopes() {
+		purgeScope(scope.Context(ctx), repo, now, globalDays, excluded, tenantIDs, configs, overrides)
+	}
+}
+
+// purgeScope applies the global, tenant and user retention periods to the
+// messages in ctx's database.
+func purgeScope(ctx context.Context, repo *repository.Repository, now time.Time, globalDays int, excluded, tenantIDs []string, configs map[string]models.TenantConfig, overrides []models.RetentionOverride) {
+	label := repo.ScopeFrom(ctx).Label()
+	modified, err := repo.PurgeMessagesBefore(ctx, cutoff(now, globalDays), excluded, tenantIDs)
+	if err != nil {
+		log.Printf("[RETENTION] Global purge failed%s: %v", label, err)
+	} else if modified > 0 {
+		log.Printf("[RETENTION] Purged expired messages from %d documents%s", modified, label)
+	}
+
+	for _, tenantID := range tenantIDs {
+		days := *configs[tenantID].RetentionDays
+		if days == 0 {
+			continue
+		}
+		modified, err := repo.PurgeTenantMessagesBefore(ctx, tenantID, cutoff(now, days), excluded)
+		if err != nil {
+			log.Printf("[RETENTION] Purge failed for tenant %s%s: %v", tenantID, label, err)
+			continue
+		}
+		if modified > 0 {
+			log.Printf("[RETENTION] Purged expired messages of tenant %s from %d documents%s (tenant: %dd)", tenantID, modified, label, days)
+		}
+	}
+
+	for _, override := range overrides {
+		if override.RetentionDays == 0 {
+			// Zero means keep forever
+			continue
+		}
+		purged, err := repo.PurgeUserMessagesBefore(ctx, override.UserID, cutoff(now, override.RetentionDays))
+		if err != nil {
+			log.Printf("[RETENTION] Purge failed for %s%s: %v", override.UserID, label, err)
+			continue
+		}
+		if purged {
+			log.Printf("[RETENTION] Purged expired messages for %s%s (override: %dd)", override.UserID, label, override.RetentionDays)
+		}
+	}
+}
+
+func cutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
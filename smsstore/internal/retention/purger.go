@@ -0,0 +1,36 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"time"
+)
+
+// StartPurger permanently removes soft-deleted messages once their grace period
+// has elapsed. Blocks until ctx is cancelled.
+func StartPurger(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	log.Printf("[PURGE] Purger started: grace=%s, interval=%s", cfg.SoftDeleteGracePeriod, cfg.RetentionInterval)
+	ticker := time.NewTicker(cfg.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().UTC().Add(-cfg.SoftDeleteGracePeriod)
+		for _, scope := range repo.Scopes() {
+			modified, err := repo.PurgeDeletedMessagesBefore(scope.Context(ctx), cutoff)
+			if err != nil {
+				log.Printf("[PURGE] Purge of soft-deleted messages failed%s: %v", scope.Label(), err)
+			} else if modified > 0 {
+				log.Printf("[PURGE] Purged soft-deleted messages from %d documents%s", modified, scope.Label())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("[PURGE] Purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"time"
+)
+
+// StartReporter stores the purge report for each calendar month once it has
+// ended, checking every RETENTION_INTERVAL. Replicas racing to store the same
+// report write identical documents. Blocks until ctx is cancelled.
+func StartReporter(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	log.Printf("[PURGE-REPORT] Reporter started: interval=%s", cfg.RetentionInterval)
+	ticker := time.NewTicker(cfg.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		reportPreviousMonth(ctx, repo)
+		select {
+		case <-ctx.Done():
+			log.Println("[PURGE-REPORT] Reporter stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reportPreviousMonth(ctx context.Context, repo *repository.Repository) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format(repository.ReportMonthFormat)
+
+	existing, err := repo.GetPurgeReport(ctx, month)
+	if err != nil {
+		log.Printf("[PURGE-REPORT] Failed to check report for %s: %v", month, err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+	report, err := repo.BuildPurgeReport(ctx, month)
+	if err != nil {
+		log.Printf("[PURGE-REPORT] Failed to build report for %s: %v", month, err)
+		return
+	}
+	if err := repo.SavePurgeReport(ctx, report); err != nil {
+		log.Printf("[PURGE-REPORT] Failed to store report for %s: %v", month, err)
+		return
+	}
+	log.Printf("[PURGE-REPORT] Stored report for %s: %d messages purged across %d tenants", month, report.Total, len(report.Tenants))
+}
@@ -0,0 +1,151 @@
+// Package retries resends failed messages under their tenant's retry policy.
+// The orchestrator marks each failure it handles on the message document and
+// hands the resend to the sender through RESEND_TOPIC; the resend's own events
+// come back through the consumer like any other send.
+package retries
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/kafkawriter"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/internal/tenants"
+	"smsstore/pkg/models"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// usersPerBatch bounds how many user documents a single pass reads per tenant.
+const usersPerBatch = 200
+
+// Statuses are the final statuses a retry policy resends. Blocked and rejected
+// sends are not retried: resending them would fail the same way.
+var Statuses = []string{"failed", "unsuccessful", "undelivered"}
+
+// StartOrchestrator periodically resends the failed messages that are due
+// under their tenant's retry policy. Every replica runs it; marking a message
+// before publishing its resend keeps two replicas from resending it twice.
+// Blocks until ctx is cancelled.
+func StartOrchestrator(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if cfg.DevMode {
+		log.Println("[RETRIES] Orchestrator disabled (DEV_MODE has no sender to resend through)")
+		return
+	}
+
+	writer := kafkawriter.New(cfg, cfg.ResendTopic, 50*time.Millisecond)
+	defer writer.Close()
+
+	log.Printf("[RETRIES] Orchestrator started: topic=%s, interval=%s", cfg.ResendTopic, cfg.RetryOrchestratorInterval)
+	ticker := time.NewTicker(cfg.RetryOrchestratorInterval)
+	defer ticker.Stop()
+
+	for {
+		runOnce(ctx, repo, writer)
+		select {
+		case <-ctx.Done():
+			log.Println("[RETRIES] Orchestrator stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, repo *repository.Repository, writer *kafka.Writer) {
+	configs, err := tenants.Snapshot(ctx)
+	if err != nil {
+		log.Printf("[RETRIES] Failed to load tenant configurations: %v", err)
+		return
+	}
+	for tenantID, tenantConfig := range configs {
+		if tenantConfig.RetryPolicy == nil {
+			continue
+		}
+		for _, scope := range repo.Scopes() {
+			if scope.Tenant != "" && scope.Tenant != tenantID {
+				continue
+			}
+			resendDue(scope.Context(ctx), repo, writer, tenantID, *tenantConfig.RetryPolicy)
+		}
+	}
+}
+
+// resendDue handles the tenant's failures that are due in one scope.
+func resendDue(ctx context.Context, repo *repository.Repository, writer *kafka.Writer, tenantID string, policy models.RetryPolicy) {
+	due := time.Now().UTC().Add(-time.Duration(policy.AfterMinutes) * time.Minute)
+	candidates, err := repo.FindRetryCandidates(ctx, tenantID, Statuses, policy.CreatedAt, due, usersPerBatch)
+	if err != nil {
+		log.Printf("[RETRIES] Failed to find failed messages of %s%s: %v", tenantID, repo.ScopeFrom(ctx).Label(), err)
+		return
+	}
+
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		message := candidate.Message
+		// A truncated body can't be sent again as it was
+		if candidate.Resends >= policy.MaxAttempts || message.Truncated {
+			if _, err := repo.MarkMessageRetry(ctx, candidate.UserID, message.MessageID, models.RetryStateExhausted, 0); err != nil {
+				log.Printf("[RETRIES] Failed to mark message %s of %s exhausted: %v", message.MessageID, candidate.UserID, err)
+				continue
+			}
+			metrics.OrchestratedRetries.WithLabelValues("exhausted").Inc()
+			continue
+		}
+
+		resend := candidate.Resends + 1
+		claimed, err := repo.MarkMessageRetry(ctx, candidate.UserID, message.MessageID, models.RetryStateResent, resend)
+		if err != nil {
+			log.Printf("[RETRIES] Failed to mark message %s of %s for resending: %v", message.MessageID, candidate.UserID, err)
+			continue
+		}
+		if !claimed {
+			// Handled by another replica in the meantime
+			continue
+		}
+		if err := publish(ctx, writer, candidate, policy); err != nil {
+			metrics.OrchestratedRetries.WithLabelValues("failed").Inc()
+			log.Printf("[RETRIES] Failed to resend message %s of %s: %v", message.MessageID, candidate.UserID, err)
+			if err := repo.ClearMessageRetry(ctx, candidate.UserID, message.MessageID); err != nil {
+				log.Printf("[RETRIES] Failed to unmark message %s of %s, it will not be resent: %v", message.MessageID, candidate.UserID, err)
+			}
+			continue
+		}
+		metrics.OrchestratedRetries.WithLabelValues("resent").Inc()
+		log.Printf("[RETRIES] Resending message %s of %s (resend %d of %d)", message.MessageID, candidate.UserID, resend, policy.MaxAttempts)
+	}
+}
+
+// publish hands a resend to the sender, as the next provider attempt of the
+// failed send.
+func publish(ctx context.Context, writer *kafka.Writer, candidate repository.RetryCandidate, policy models.RetryPolicy) error {
+	message := candidate.Message
+	payload, err := json.Marshal(models.ResendRequest{
+		ID:       repository.SendKey(message),
+		TenantID: message.TenantID,
+		Request: models.ResendBody{
+			PhoneNumber: candidate.UserID,
+			Message:     message.Message,
+			CampaignID:  message.CampaignID,
+			TemplateID:  message.TemplateID,
+			CountryCode: message.CountryCode,
+			Metadata:    message.Metadata,
+			SenderID:    message.SenderID,
+			Category:    message.Category,
+		},
+		Attempt:      max(message.Attempt, 1) + 1,
+		DueAtEpochMs: time.Now().UnixMilli(),
+		Provider:     policy.Provider,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return writer.WriteMessages(ctx, kafka.Message{Key: []byte(candidate.UserID), Value: payload})
+}
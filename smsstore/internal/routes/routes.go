@@ -1,15 +1,177 @@
 package routes
 
 import (
+	"net/http"
+	"smsstore/internal/config"
+	"smsstore/internal/diagnostics"
 	"smsstore/internal/handlers"
+	"smsstore/internal/metrics"
+	"smsstore/internal/middleware"
+	"smsstore/internal/ratelimit"
+	"smsstore/internal/rbac"
+	"smsstore/internal/repository"
+	"smsstore/internal/statscache"
 
 	"github.com/gorilla/mux"
 )
 
-// SetupRoutes initializes and configures HTTP routes.
+// SetupRoutes initializes and configures the public HTTP routes, served by
+// api from repo. When no ADMIN_PORT is configured the admin and diagnostic routes are
+// mounted here as well; otherwise they are served by SetupAdminRoutes on the
+// internal port.
 // Returns an error if route setup fails (unlikely but possible for future validation).
-func SetupRoutes() (*mux.Router, error) {
+func SetupRoutes(cfg *config.Config, repo *repository.Repository, api *handlers.API) (*mux.Router, error) {
 	router := mux.NewRouter()
-	router.HandleFunc("/v1/user/{user_id}/messages", handlers.GetUserMessages).Methods("GET")
+	router.Use(
+		middleware.RequestID,
+		middleware.ResponseFormat(cfg.ResponseFormat),
+		middleware.Recover,
+		middleware.Metrics,
+		middleware.ClientCert(cfg.TLSClientCAFile != ""),
+		middleware.LoadShed(cfg.MaxInflightRequests, cfg.LoadShedRetryAfter),
+		middleware.Deadline(cfg.RequestDeadlineMargin),
+		middleware.Region(repo.HasRegion),
+		middleware.Tenant,
+	)
+	if cfg.RBACEnabled() {
+		router.Use(rbac.Authorize)
+	}
+
+	router.HandleFunc("/v1/user/{user_id}/messages", api.GetUserMessages).Methods("GET", "HEAD")
+	router.Handle("/v1/user/{user_id}/stats", statscache.Handler(api.GetUserStats)).Methods("GET")
+	router.HandleFunc("/v1/user/{user_id}/conversations", api.GetUserConversations).Methods("GET")
+	router.HandleFunc("/v1/user/{user_id}/conversations/{sender_id}/messages", api.GetConversationMessages).Methods("GET", "HEAD")
+	router.Handle("/v1/analytics/messages", statscache.Handler(api.GetMessageAnalytics)).Methods("GET")
+	router.Handle("/v1/analytics/timeseries", statscache.Handler(api.GetMessageTimeseries)).Methods("GET")
+	// A stream pushes every change to a user's messages for as long as it stays
+	// open, so it always needs credentials: the RBAC policy's, else the admin
+	// token. Without either it isn't served.
+	switch {
+	case cfg.RBACEnabled():
+		router.HandleFunc("/v1/user/{user_id}/messages/stream", handlers.StreamUserMessages).Methods("GET")
+	case cfg.AdminAPIToken != "":
+		router.Handle("/v1/user/{user_id}/messages/stream",
+			middleware.AdminAuth(cfg.AdminAPIToken)(http.HandlerFunc(handlers.StreamUserMessages))).Methods("GET")
+	}
+	router.HandleFunc("/v1/user/{user_id}/messages/{message_id}", api.DeleteMessage).Methods("DELETE")
+	router.HandleFunc("/v1/user/{user_id}/messages/{message_id}/restore", api.RestoreMessage).Methods("POST")
+
+	if cfg.DevMode {
+		// Same contract as the sms-sender API, backed by the simulated provider
+		router.HandleFunc("/v1/sms/send", handlers.DevSendSms).Methods("POST")
+	}
+
+	if !cfg.AdminServerEnabled() {
+		mountAdminRoutes(router, cfg, api, middleware.IPAllowlist("admin", cfg.AdminAllowedCIDRs))
+	}
 	return router, nil
-}
\ No newline at end of file
+}
+
+// SetupAdminRoutes configures the routes served on the internal ADMIN_PORT:
+// metrics, the cross-user firehose, lookup and search, webhook subscriptions,
+// /v1/admin and pprof. Nothing here should be reachable from the public
+// listener.
+func SetupAdminRoutes(cfg *config.Config, repo *repository.Repository, api *handlers.API) (*mux.Router, error) {
+	router := mux.NewRouter()
+	// No load shedding: operators need these routes most when the public API is overloaded
+	router.Use(
+		middleware.RequestID,
+		middleware.ResponseFormat(cfg.ResponseFormat),
+		middleware.Recover,
+		middleware.Metrics,
+		middleware.ClientCert(cfg.TLSClientCAFile != ""),
+		// The allowlist covers the whole port, ahead of any authentication
+		middleware.IPAllowlist("admin", cfg.AdminAllowedCIDRs),
+		middleware.Deadline(cfg.RequestDeadlineMargin),
+		middleware.Region(repo.HasRegion),
+		middleware.Tenant,
+	)
+	if cfg.RBACEnabled() {
+		router.Use(rbac.Authorize)
+	}
+	mountAdminRoutes(router, cfg, api, passthrough)
+	return router, nil
+}
+
+// mountAdminRoutes registers the admin routes on router, each wrapped in
+// restrict (the admin allowlist when they share the public port).
+func mountAdminRoutes(router *mux.Router, cfg *config.Config, api *handlers.API, restrict func(http.Handler) http.Handler) {
+	router.Handle("/metrics", restrict(metrics.Handler())).Methods("GET")
+
+	auth := middleware.AdminAuth(cfg.AdminAPIToken)
+	if cfg.RBACEnabled() {
+		// The RBAC policy already authorizes every route on the router
+		auth = passthrough
+	}
+	adminAuth := func(next http.Handler) http.Handler { return restrict(auth(next)) }
+	firehose := router.Path("/v1/messages").Subrouter()
+	firehose.Use(adminAuth, middleware.RateLimit(ratelimit.New("firehose", cfg.FirehoseRateLimit, cfg.FirehoseBurst)))
+	firehose.Methods("GET").HandlerFunc(api.ListAllMessages)
+	// Cross-user lookups expose other users' messages, so they need admin credentials too
+	router.Handle("/v1/messages/lookup", adminAuth(http.HandlerFunc(api.LookupMessages))).Methods("GET")
+	router.Handle("/v1/search/messages", adminAuth(http.HandlerFunc(api.SearchMessages))).Methods("GET")
+
+	// Subscriptions receive other users' messages, so they are managed with admin credentials
+	subscriptions := router.PathPrefix("/v1/webhook-subscriptions").Subrouter()
+	subscriptions.Use(adminAuth)
+	subscriptions.HandleFunc("", handlers.CreateWebhookSubscription).Methods("POST")
+	subscriptions.HandleFunc("", api.ListWebhookSubscriptions).Methods("GET")
+	subscriptions.HandleFunc("/{subscription_id}", api.GetWebhookSubscription).Methods("GET")
+	subscriptions.HandleFunc("/{subscription_id}", handlers.DeleteWebhookSubscription).Methods("DELETE")
+	subscriptions.HandleFunc("/{subscription_id}/deliveries", api.ListWebhookDeliveries).Methods("GET")
+
+	admin := router.PathPrefix("/v1/admin").Subrouter()
+	admin.Use(adminAuth)
+	admin.HandleFunc("/users", api.ListUsers).Methods("GET")
+	admin.HandleFunc("/retention/{user_id}", api.GetRetentionOverride).Methods("GET")
+	admin.HandleFunc("/retention/{user_id}", api.SetRetentionOverride).Methods("PUT")
+	admin.HandleFunc("/retention/{user_id}", api.DeleteRetentionOverride).Methods("DELETE")
+	admin.HandleFunc("/tenants", api.ListTenantConfigs).Methods("GET")
+	admin.HandleFunc("/tenants/{tenant_id}/config", api.GetTenantConfig).Methods("GET")
+	admin.HandleFunc("/tenants/{tenant_id}/config", handlers.SetTenantConfig).Methods("PUT")
+	admin.HandleFunc("/tenants/{tenant_id}/config", handlers.DeleteTenantConfig).Methods("DELETE")
+	admin.HandleFunc("/tenants/{tenant_id}/retry-policy", api.GetRetryPolicy).Methods("GET")
+	admin.HandleFunc("/tenants/{tenant_id}/retry-policy", handlers.SetRetryPolicy).Methods("PUT")
+	admin.HandleFunc("/tenants/{tenant_id}/retry-policy", handlers.DeleteRetryPolicy).Methods("DELETE")
+	admin.HandleFunc("/reports/purges", api.ListPurgeReports).Methods("GET")
+	admin.HandleFunc("/reports/purges/{month}", api.GetPurgeReport).Methods("GET")
+	admin.HandleFunc("/reports/storage", api.ListStorageReports).Methods("GET")
+	admin.HandleFunc("/reports/storage", api.RebuildStorageReport).Methods("POST")
+	admin.HandleFunc("/reports/storage/{date}", api.GetStorageReport).Methods("GET")
+	admin.HandleFunc("/maintenance", api.StartMaintenance).Methods("POST")
+	admin.HandleFunc("/jobs/{job_id}", api.GetJob).Methods("GET")
+	admin.HandleFunc("/integrity/{user_id}", api.VerifyUserIntegrity).Methods("GET")
+	admin.HandleFunc("/providers", handlers.GetProviderHealth).Methods("GET")
+	admin.HandleFunc("/consumer", handlers.GetConsumerStatus).Methods("GET")
+	admin.HandleFunc("/consumer/resume", handlers.ResumeConsumer).Methods("POST")
+	admin.HandleFunc("/quarantine", api.ListQuarantinedEvents).Methods("GET")
+	admin.HandleFunc("/features", handlers.ListFeatureFlags).Methods("GET")
+	admin.HandleFunc("/features/{feature}", handlers.SetFeatureFlagOverride).Methods("PUT")
+	admin.HandleFunc("/features/{feature}", handlers.DeleteFeatureFlagOverride).Methods("DELETE")
+	admin.HandleFunc("/logging", handlers.GetLoggingSettings).Methods("GET")
+	admin.HandleFunc("/logging", handlers.UpdateLoggingSettings).Methods("PUT")
+	admin.HandleFunc("/debug/goroutines", handlers.Goroutines).Methods("GET")
+	// Grant the support role the masked view in the RBAC policy; the unmasked
+	// one is audited and stays admin-only unless a rule says otherwise
+	admin.HandleFunc("/support/users/{user_id}/messages", api.GetSupportMessages).Methods("GET")
+	admin.HandleFunc("/support/users/{user_id}/messages/unmasked", api.GetUnmaskedSupportMessages).Methods("GET")
+	admin.HandleFunc("/audit", api.ListAuditEntries).Methods("GET")
+
+	// Profiles reveal internals, so they sit behind admin auth as well
+	diagnostics.MountPprof(router, adminAuth)
+}
+
+// WithProbes serves the Kubernetes liveness (/healthz) and readiness
+// (/readyz) probes in front of handler, outside its middleware, so probes
+// are never shed, rate limited or asked for credentials.
+func WithProbes(handler http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handlers.Liveness)
+	mux.HandleFunc("/readyz", handlers.Readiness)
+	mux.Handle("/", handler)
+	return mux
+}
+
+func passthrough(next http.Handler) http.Handler {
+	return next
+}
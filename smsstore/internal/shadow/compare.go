@@ -0,0 +1,160 @@
+package shadow
+
+import (
+	"context"
+	"log"
+	"maps"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"time"
+)
+
+// settleDelay keeps messages written in the last moments, whose shadow write
+// may still be in flight, out of a comparison.
+const settleDelay = time.Minute
+
+// divergence counts how a user's primary and shadow messages differ.
+type divergence struct {
+	missing    int // in the primary storage only
+	extra      int // in the shadow backend only
+	mismatched int // in both, with different contents
+	examples   []string
+}
+
+func (d divergence) diverged() bool {
+	return d.missing+d.extra+d.mismatched > 0
+}
+
+// StartComparer compares a sample of SHADOW_COMPARE_SAMPLE recently active
+// users every SHADOW_COMPARE_INTERVAL, over the messages they received within
+// SHADOW_COMPARE_WINDOW. Messages from before the comparer started, which
+// shadow writes may have missed, are left out. Blocks until ctx is cancelled.
+func StartComparer(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if !cfg.ShadowWrites || cfg.ShadowCompareInterval <= 0 {
+		return
+	}
+	started := time.Now()
+	log.Printf("[SHADOW] Comparer started: interval=%s sample=%d window=%s",
+		cfg.ShadowCompareInterval, cfg.ShadowCompareSample, cfg.ShadowCompareWindow)
+
+	ticker := time.NewTicker(cfg.ShadowCompareInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[SHADOW] Comparer stopped")
+			return
+		case <-ticker.C:
+		}
+		to := time.Now().Add(-settleDelay)
+		from := to.Add(-cfg.ShadowCompareWindow)
+		if from.Before(started) {
+			from = started
+		}
+		if !to.After(from) {
+			continue
+		}
+		compare(ctx, repo, cfg.ShadowCompareSample, from, to)
+	}
+}
+
+// compare diffs the messages created in [from, to) of up to sample users.
+func compare(ctx context.Context, repo *repository.Repository, sample int, from time.Time, to time.Time) {
+	users, err := repo.SampleRecentUsers(ctx, from, sample)
+	if err != nil {
+		log.Printf("[SHADOW] Sampling users failed: %v", err)
+		return
+	}
+	compared, diverged := 0, 0
+	for _, userID := range users {
+		primary, err := repo.GetUserMessages(ctx, userID, repository.MessageQuery{
+			MessageFilter: repository.MessageFilter{From: from, To: to},
+		})
+		if err != nil {
+			metrics.ShadowComparisons.WithLabelValues("error").Inc()
+			log.Printf("[SHADOW] Reading %s from the primary storage failed: %v", userID, err)
+			continue
+		}
+		shadowed, err := repo.GetShadowMessages(ctx, userID, from, to)
+		if err != nil {
+			metrics.ShadowComparisons.WithLabelValues("error").Inc()
+			log.Printf("[SHADOW] Reading %s from the shadow backend failed: %v", userID, err)
+			continue
+		}
+
+		compared++
+		result := diff(primary, shadowed)
+		metrics.ShadowDivergentMessages.WithLabelValues("missing").Add(float64(result.missing))
+		metrics.ShadowDivergentMessages.WithLabelValues("extra").Add(float64(result.extra))
+		metrics.ShadowDivergentMessages.WithLabelValues("mismatched").Add(float64(result.mismatched))
+		if !result.diverged() {
+			metrics.ShadowComparisons.WithLabelValues("match").Inc()
+			continue
+		}
+		diverged++
+		metrics.ShadowComparisons.WithLabelValues("diverged").Inc()
+		log.Printf("[SHADOW] %s diverged: missing=%d extra=%d mismatched=%d e.g. %v",
+			userID, result.missing, result.extra, result.mismatched, result.examples)
+	}
+	log.Printf("[SHADOW] Compared %d users: %d diverged", compared, diverged)
+}
+
+// diff matches messages by ID. Messages stored before IDs existed can't be
+// matched and are skipped.
+func diff(primary, shadowed []models.MessageWithStatus) divergence {
+	var result divergence
+	note := func(messageID string) {
+		if len(result.examples) < 5 {
+			result.examples = append(result.examples, messageID)
+		}
+	}
+
+	byID := make(map[string]models.MessageWithStatus, len(shadowed))
+	for _, message := range shadowed {
+		byID[message.MessageID] = message
+	}
+	for _, message := range primary {
+		if message.MessageID == "" {
+			continue
+		}
+		copied, ok := byID[message.MessageID]
+		delete(byID, message.MessageID)
+		switch {
+		case !ok:
+			result.missing++
+			note(message.MessageID)
+		case !sameMessage(message, copied):
+			result.mismatched++
+			note(message.MessageID)
+		}
+	}
+	for messageID := range byID {
+		result.extra++
+		note(messageID)
+	}
+	return result
+}
+
+// sameMessage compares the fields written through the message store. Fields
+// that background jobs change, such as the retry state, are not mirrored and
+// so not compared.
+func sameMessage(a, b models.MessageWithStatus) bool {
+	return a.Message == b.Message &&
+		a.Status == b.Status &&
+		a.CreatedAt.Equal(b.CreatedAt) &&
+		sameTime(a.ReadAt, b.ReadAt) &&
+		sameTime(a.ClickedAt, b.ClickedAt) &&
+		a.ProviderMessageID == b.ProviderMessageID &&
+		a.TenantID == b.TenantID &&
+		a.Checksum == b.Checksum &&
+		maps.Equal(a.Metadata, b.Metadata)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,101 @@
+// Package shadow supports moving message storage to a new backend without a
+// leap of faith. With SHADOW_WRITES on, Store copies every message write the
+// primary storage accepts to the shadow backend (the repository's
+// messages_shadow collection), and the comparer periodically samples recently
+// active users and diffs their recent messages between the two, counting
+// divergence in metrics. Reads are still served by the primary storage only;
+// once divergence stays at zero, reads can be cut over.
+package shadow
+
+import (
+	"context"
+	"smsstore/internal/logsample"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"time"
+)
+
+// Store is a repository.MessageStore that mirrors message writes to the
+// shadow backend. A failed shadow write is logged and counted but never fails
+// the primary write.
+type Store struct {
+	repository.MessageStore
+
+	// Shadow holds the shadow backend
+	Shadow *repository.Repository
+}
+
+func (s Store) AddMessageToUser(ctx context.Context, event models.SmsEvent) (*models.MessageWithStatus, error) {
+	stored, err := s.MessageStore.AddMessageToUser(ctx, event)
+	if err == nil {
+		s.mirrorMessage(ctx, "insert", event.PhoneNumber, stored)
+	}
+	return stored, err
+}
+
+func (s Store) AddMessageToUserIdempotent(ctx context.Context, event models.SmsEvent) (*models.MessageWithStatus, bool, error) {
+	stored, duplicate, err := s.MessageStore.AddMessageToUserIdempotent(ctx, event)
+	if err == nil {
+		s.mirrorMessage(ctx, "insert", event.PhoneNumber, stored)
+	}
+	return stored, duplicate, err
+}
+
+func (s Store) AddMessageToUserDeduplicated(ctx context.Context, event models.SmsEvent, window time.Duration) (*models.MessageWithStatus, bool, error) {
+	stored, duplicate, err := s.MessageStore.AddMessageToUserDeduplicated(ctx, event, window)
+	if err == nil {
+		s.mirrorMessage(ctx, "insert", event.PhoneNumber, stored)
+	}
+	return stored, duplicate, err
+}
+
+func (s Store) MarkMessagesRead(ctx context.Context, userID string, providerMessageID string, readAt time.Time) (bool, error) {
+	found, err := s.MessageStore.MarkMessagesRead(ctx, userID, providerMessageID, readAt)
+	if err == nil && found {
+		mirror("read", s.Shadow.MarkShadowMessagesRead(ctx, userID, providerMessageID, readAt))
+	}
+	return found, err
+}
+
+func (s Store) MarkMessagesClicked(ctx context.Context, userID string, providerMessageID string, clickedAt time.Time) (bool, error) {
+	found, err := s.MessageStore.MarkMessagesClicked(ctx, userID, providerMessageID, clickedAt)
+	if err == nil && found {
+		mirror("click", s.Shadow.MarkShadowMessagesClicked(ctx, userID, providerMessageID, clickedAt))
+	}
+	return found, err
+}
+
+func (s Store) SoftDeleteMessage(ctx context.Context, userID string, messageID string) (*models.MessageWithStatus, error) {
+	message, err := s.MessageStore.SoftDeleteMessage(ctx, userID, messageID)
+	if err == nil {
+		s.mirrorMessage(ctx, "delete", userID, message)
+	}
+	return message, err
+}
+
+func (s Store) RestoreMessage(ctx context.Context, userID string, messageID string) (*models.MessageWithStatus, error) {
+	message, err := s.MessageStore.RestoreMessage(ctx, userID, messageID)
+	if err == nil {
+		s.mirrorMessage(ctx, "restore", userID, message)
+	}
+	return message, err
+}
+
+// mirrorMessage copies a message as the primary storage returned it; nil
+// (a suppressed duplicate or a missing message) was not written.
+func (s Store) mirrorMessage(ctx context.Context, operation string, userID string, message *models.MessageWithStatus) {
+	if message == nil || message.MessageID == "" {
+		return
+	}
+	mirror(operation, s.Shadow.PutShadowMessage(ctx, userID, *message))
+}
+
+func mirror(operation string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "failed"
+		logsample.Errorf("[SHADOW] Shadow %s failed: %v", operation, err)
+	}
+	metrics.ShadowWrites.WithLabelValues(operation, result).Inc()
+}
@@ -0,0 +1,219 @@
+package statscache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net/http"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/internal/middleware"
+	"smsstore/internal/repository"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The cache holds successful stats and analytics responses in process, keyed
+// by path, query, region, tenant and response format. A response is served
+// from the cache while fresh (STATS_CACHE_TTL). For
+// STATS_CACHE_STALE_WHILE_REVALIDATE after that it is still served, and the
+// first request to see it stale refreshes it in the background. Responses
+// carry X-Cache (HIT, STALE, MISS or BYPASS) and, when cached, Age. Requests
+// with Cache-Control: no-cache skip the cache but still refresh it.
+
+// CacheHeader reports how the cache answered a request.
+const CacheHeader = "X-Cache"
+
+// Cache results, as sent in CacheHeader and counted in metrics.
+const (
+	resultHit    = "hit"
+	resultStale  = "stale"
+	resultMiss   = "miss"
+	resultBypass = "bypass"
+)
+
+// entry is one cached response.
+type entry struct {
+	key         string
+	contentType string
+	body        []byte
+	storedAt    time.Time
+	// refreshing is set while a request refreshes the stale entry
+	refreshing bool
+}
+
+var (
+	mu      sync.Mutex
+	maxSize = 0 // zero disables the cache
+	ttl     = time.Minute
+	stale   = 5 * time.Minute
+	order   = list.New()                 // front is most recently used
+	entries = map[string]*list.Element{} // by key
+	now     = time.Now
+)
+
+// Configure applies the cache settings. Call once at startup.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	maxSize = cfg.StatsCacheSize
+	ttl = cfg.StatsCacheTTL
+	stale = cfg.StatsCacheStale
+}
+
+// Handler serves a stats or analytics handler through the cache.
+func Handler(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		enabled := maxSize > 0
+		mu.Unlock()
+		if !enabled {
+			next(w, r)
+			return
+		}
+
+		route := routeTemplate(r)
+		key := cacheKey(r)
+		if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+			metrics.StatsCacheRequests.WithLabelValues(route, resultBypass).Inc()
+			w.Header().Set(CacheHeader, strings.ToUpper(resultBypass))
+			serve(w, r, next, key)
+			return
+		}
+
+		cached, result, refresh := lookup(key)
+		if cached == nil {
+			metrics.StatsCacheRequests.WithLabelValues(route, resultMiss).Inc()
+			w.Header().Set(CacheHeader, strings.ToUpper(resultMiss))
+			serve(w, r, next, key)
+			return
+		}
+		metrics.StatsCacheRequests.WithLabelValues(route, result).Inc()
+		if refresh {
+			// The refresh must not fail because this request finished first
+			go serve(discard{header: http.Header{}}, r.Clone(context.WithoutCancel(r.Context())), next, key)
+		}
+		w.Header().Set(CacheHeader, strings.ToUpper(result))
+		w.Header().Set("Age", strconv.Itoa(int(now().Sub(cached.storedAt)/time.Second)))
+		w.Header().Set("Content-Type", cached.contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(cached.body)
+	})
+}
+
+// serve runs the handler, writing through to w, and caches a 200 response.
+func serve(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, key string) {
+	recorder := &recorder{ResponseWriter: w, status: http.StatusOK}
+	next(recorder, r)
+	if recorder.status != http.StatusOK {
+		release(key)
+		return
+	}
+	store(key, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+}
+
+// cacheKey identifies a response: the request's path and query (whose
+// parameters Encode sorts), and the context that scopes its data and shape.
+func cacheKey(r *http.Request) string {
+	return strings.Join([]string{
+		r.URL.Path,
+		r.URL.Query().Encode(),
+		repository.RegionFrom(r.Context()),
+		strings.TrimSpace(r.Header.Get(middleware.TenantHeader)),
+		strings.ToLower(r.Header.Get(middleware.ResponseFormatHeader)),
+	}, "|")
+}
+
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// lookup returns the cached response for key and whether it is fresh or stale;
+// refresh is true for the one request that should refresh a stale response.
+// Expired responses are dropped.
+func lookup(key string) (cached *entry, result string, refresh bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	element, ok := entries[key]
+	if !ok {
+		return nil, "", false
+	}
+	cached = element.Value.(*entry)
+	age := now().Sub(cached.storedAt)
+	if age >= ttl+stale {
+		remove(element)
+		return nil, "", false
+	}
+	order.MoveToFront(element)
+	if age < ttl {
+		return cached, resultHit, false
+	}
+	refresh = !cached.refreshing
+	cached.refreshing = true
+	return cached, resultStale, refresh
+}
+
+// store caches a response, replacing any previous one for key.
+func store(key, contentType string, body []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	if element, ok := entries[key]; ok {
+		remove(element)
+	}
+	entries[key] = order.PushFront(&entry{key: key, contentType: contentType, body: body, storedAt: now()})
+	for order.Len() > maxSize {
+		remove(order.Back())
+	}
+	metrics.StatsCacheEntries.Set(float64(order.Len()))
+}
+
+// release lets a later request retry a refresh that failed.
+func release(key string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if element, ok := entries[key]; ok {
+		element.Value.(*entry).refreshing = false
+	}
+}
+
+// remove unlinks an element. Callers hold mu.
+func remove(element *list.Element) {
+	order.Remove(element)
+	delete(entries, element.Value.(*entry).key)
+	metrics.StatsCacheEntries.Set(float64(order.Len()))
+}
+
+// recorder copies the response it writes through, so it can be cached.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// discard is the writer of a background refresh, which has no client.
+type discard struct {
+	header http.Header
+}
+
+func (d discard) Header() http.Header         { return d.header }
+func (d discard) Write(b []byte) (int, error) { return len(b), nil }
+func (d discard) WriteHeader(int)             {}
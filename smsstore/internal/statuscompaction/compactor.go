@@ -0,0 +1,83 @@
+// Package statuscompaction thins out the status histories of sends. Providers
+// that emit many delivery reports leave dozens of interim statuses per send,
+// each a stored message; compaction keeps the first and last status and each
+// phase change (see statusflow.Keep) to bound user document size.
+package statuscompaction
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"time"
+)
+
+// usersPerPage is how many active users a run lists at a time.
+const usersPerPage = 500
+
+// Start compacts the status histories of users active since the previous run
+// every STATUS_COMPACTION_INTERVAL; the first run covers one interval back.
+// Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if cfg.StatusCompactionInterval == 0 {
+		log.Println("[STATUS-COMPACTION] Compactor disabled (STATUS_COMPACTION_INTERVAL=0)")
+		return
+	}
+
+	log.Printf("[STATUS-COMPACTION] Compactor started: interval=%s min_statuses=%d archive=%t",
+		cfg.StatusCompactionInterval, cfg.StatusCompactionMinStatuses, cfg.StatusCompactionArchive)
+	ticker := time.NewTicker(cfg.StatusCompactionInterval)
+	defer ticker.Stop()
+
+	since := time.Now().UTC().Add(-cfg.StatusCompactionInterval)
+	for {
+		started := time.Now().UTC()
+		complete := true
+		for _, scope := range repo.Scopes() {
+			complete = runOnce(scope.Context(ctx), cfg, repo, since) && complete
+		}
+		// A failed run is covered again by the next one
+		if complete {
+			since = started
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("[STATUS-COMPACTION] Compactor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce compacts every user updated after since and reports whether it got
+// through all of them.
+func runOnce(ctx context.Context, cfg *config.Config, repo *repository.Repository, since time.Time) bool {
+	total, users := 0, 0
+	cursor := ""
+	for ctx.Err() == nil {
+		page, err := repo.ListUsers(ctx, since, cursor, usersPerPage)
+		if err != nil {
+			log.Printf("[STATUS-COMPACTION] Failed to list users active since %s%s: %v", since.Format(time.RFC3339), repo.ScopeFrom(ctx).Label(), err)
+			return false
+		}
+		for _, user := range page {
+			dropped, err := repo.CompactStatusHistories(ctx, user.UserID, cfg.StatusCompactionMinStatuses, cfg.StatusCompactionArchive)
+			total += dropped
+			metrics.StatusesCompacted.Add(float64(dropped))
+			if err != nil {
+				log.Printf("[STATUS-COMPACTION] Failed to compact statuses of %s%s: %v", user.UserID, repo.ScopeFrom(ctx).Label(), err)
+				return false
+			}
+			users++
+		}
+		if len(page) < usersPerPage {
+			break
+		}
+		cursor = page[len(page)-1].UserID
+	}
+	if total > 0 {
+		log.Printf("[STATUS-COMPACTION] Dropped %d interim statuses across %d active users%s", total, users, repo.ScopeFrom(ctx).Label())
+	}
+	return ctx.Err() == nil
+}
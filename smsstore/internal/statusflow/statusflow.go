@@ -0,0 +1,81 @@
+// Package statusflow defines the order in which the status events of one send
+// may be stored. A send moves from pending (queued, deferred, retrying) to
+// accepted by the provider (sent, successful) to a final outcome (delivered,
+// failed, ...); it never moves back, and nothing follows a final outcome.
+package statusflow
+
+type phase int
+
+const (
+	pending phase = iota
+	accepted
+	final
+)
+
+var phases = map[string]phase{
+	"queued":   pending,
+	"pending":  pending,
+	"deferred": pending,
+	"retrying": pending,
+
+	"sent":       accepted,
+	"successful": accepted,
+
+	"delivered":    final,
+	"undelivered":  final,
+	"failed":       final,
+	"unsuccessful": final,
+	"blocked":      final,
+	"rejected":     final,
+}
+
+// Allowed reports whether a send whose latest stored status is from may next
+// store status to. Repeats of the same status are allowed (redelivery is the
+// dedup stage's concern), and statuses outside the lifecycle are never checked.
+func Allowed(from, to string) bool {
+	if from == to {
+		return true
+	}
+	fromPhase, known := phases[from]
+	if !known {
+		return true
+	}
+	toPhase, known := phases[to]
+	if !known {
+		return true
+	}
+	if fromPhase == final {
+		return false
+	}
+	return toPhase >= fromPhase
+}
+
+// Final reports whether status is a final outcome of a send, after which
+// nothing more is stored for it.
+func Final(status string) bool {
+	statusPhase, known := phases[status]
+	return known && statusPhase == final
+}
+
+// Keep reports which statuses of one send's history (oldest first) status
+// compaction keeps: the first, the last, and each that enters a later phase
+// of the lifecycle. Interim repeats within a phase are dropped; statuses
+// outside the lifecycle are kept whenever they differ from the one before.
+func Keep(history []string) []bool {
+	keep := make([]bool, len(history))
+	for i, status := range history {
+		if i == 0 || i == len(history)-1 {
+			keep[i] = true
+			continue
+		}
+		prev := history[i-1]
+		prevPhase, prevKnown := phases[prev]
+		statusPhase, known := phases[status]
+		if !known || !prevKnown {
+			keep[i] = status != prev
+			continue
+		}
+		keep[i] = statusPhase > prevPhase
+	}
+	return keep
+}
@@ -0,0 +1,89 @@
+// Package storagereport measures storage usage per collection and tenant for
+// capacity planning and chargeback. Reports are built by a job, since they
+// scan every message collection, and stored once per UTC day.
+package storagereport
+
+import (
+	"context"
+	"errors"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/jobs"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"time"
+)
+
+// JobType is the job building and storing a storage report.
+const JobType = "storage_report"
+
+// RegisterJob registers the storage report job type with the job runner. The
+// job stores the report for its date param, replacing any earlier one.
+func RegisterJob(cfg *config.Config, repo *repository.Repository) {
+	jobs.Register(JobType, jobs.DefaultRetryPolicy, func(ctx context.Context, params map[string]string, progress jobs.ProgressFunc) (interface{}, error) {
+		date := params["date"]
+		if _, err := time.Parse(repository.ReportDateFormat, date); err != nil {
+			return nil, jobs.Permanent(errors.New("date must be formatted YYYY-MM-DD"))
+		}
+		report, err := repo.BuildStorageReport(ctx, date, cfg.StorageReportTopUsers)
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.SaveStorageReport(ctx, report); err != nil {
+			return nil, err
+		}
+		log.Printf("[STORAGE-REPORT] Stored report for %s: %d bytes stored across %d collections and %d tenants",
+			date, report.StorageBytes, len(report.Collections), len(report.Tenants))
+		return map[string]interface{}{"date": date, "storage_bytes": report.StorageBytes, "tenants": len(report.Tenants)}, nil
+	})
+}
+
+// Rebuild enqueues a job rebuilding today's report, e.g. after a bulk import
+// or purge, without waiting for tomorrow's.
+func Rebuild(ctx context.Context, repo *repository.Repository) (*models.Job, error) {
+	return jobs.Enqueue(ctx, repo, JobType, map[string]string{"date": today()})
+}
+
+// Start enqueues the job storing each day's report once that day has no
+// report, checking every STORAGE_REPORT_INTERVAL. Replicas enqueue the same
+// job, so each day's report is built once; if that job fails for good, the
+// day's report can still be built with Rebuild. Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	log.Printf("[STORAGE-REPORT] Reporter started: interval=%s", cfg.StorageReportInterval)
+	ticker := time.NewTicker(cfg.StorageReportInterval)
+	defer ticker.Stop()
+
+	for {
+		reportToday(ctx, repo)
+		select {
+		case <-ctx.Done():
+			log.Println("[STORAGE-REPORT] Reporter stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reportToday(ctx context.Context, repo *repository.Repository) {
+	date := today()
+	existing, err := repo.GetStorageReport(ctx, date)
+	if err != nil {
+		log.Printf("[STORAGE-REPORT] Failed to check report for %s: %v", date, err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+	job, created, err := jobs.EnqueueOnce(ctx, repo, JobType, date, map[string]string{"date": date})
+	if err != nil {
+		log.Printf("[STORAGE-REPORT] Failed to enqueue report for %s: %v", date, err)
+		return
+	}
+	if created {
+		log.Printf("[STORAGE-REPORT] Enqueued job %s building report for %s", job.ID, date)
+	}
+}
+
+func today() string {
+	return time.Now().UTC().Format(repository.ReportDateFormat)
+}
@@ -0,0 +1,237 @@
+// Package tenants serves per-tenant settings to the send path, the retention
+// janitor, webhook delivery and the retry orchestrator. Configurations live in Mongo; each replica
+// reads them through an in-memory snapshot that changes made through it
+// refresh immediately and other replicas pick up within TENANT_CONFIG_TTL.
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"smsstore/internal/config"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"strings"
+	"sync"
+	"time"
+
+	// Quiet-hour timezones must resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+var clockPattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// Bounds of a retry policy: resends wait at most a week and a send is resent
+// at most MaxRetryAttempts times.
+const (
+	MaxRetryAfterMinutes = 7 * 24 * 60
+	MaxRetryAttempts     = 10
+)
+
+var (
+	mu       sync.Mutex
+	cacheTTL = 30 * time.Second
+	snapshot map[string]models.TenantConfig
+	loadedAt time.Time
+	// store holds the configurations
+	store *repository.Repository
+)
+
+// Configure applies the snapshot TTL and the repository configurations are
+// stored in. Call once at startup.
+func Configure(cfg *config.Config, repo *repository.Repository) {
+	mu.Lock()
+	cacheTTL = cfg.TenantConfigTTL
+	store = repo
+	mu.Unlock()
+}
+
+// ErrInvalidConfig wraps validation failures of a tenant configuration.
+var ErrInvalidConfig = errors.New("invalid tenant config")
+
+// Save validates and stores a tenant's configuration, replacing any previous one.
+func Save(ctx context.Context, tenantID string, cfg models.TenantConfig) (*models.TenantConfig, error) {
+	cfg.TenantID = tenantID
+	if err := normalize(&cfg); err != nil {
+		return nil, err
+	}
+	cfg.UpdatedAt = time.Now().UTC()
+	if err := store.SaveTenantConfig(ctx, &cfg); err != nil {
+		return nil, err
+	}
+	Invalidate()
+	log.Printf("[TENANTS] Configuration of %s updated", tenantID)
+	return &cfg, nil
+}
+
+// Delete removes a tenant's configuration so the defaults apply again.
+// Returns false if none existed.
+func Delete(ctx context.Context, tenantID string) (bool, error) {
+	deleted, err := store.DeleteTenantConfig(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	Invalidate()
+	return deleted, nil
+}
+
+// SaveRetryPolicy sets a tenant's retry policy, keeping the rest of its
+// configuration. Replacing a policy keeps its CreatedAt, so failures it already
+// covered stay covered.
+func SaveRetryPolicy(ctx context.Context, tenantID string, policy models.RetryPolicy) (*models.RetryPolicy, error) {
+	current, err := store.GetTenantConfig(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		current = &models.TenantConfig{}
+	}
+	policy.CreatedAt = time.Time{}
+	if current.RetryPolicy != nil {
+		policy.CreatedAt = current.RetryPolicy.CreatedAt
+	}
+	current.RetryPolicy = &policy
+	saved, err := Save(ctx, tenantID, *current)
+	if err != nil {
+		return nil, err
+	}
+	return saved.RetryPolicy, nil
+}
+
+// DeleteRetryPolicy stops resending a tenant's failed messages. Returns false
+// if the tenant had no retry policy.
+func DeleteRetryPolicy(ctx context.Context, tenantID string) (bool, error) {
+	current, err := store.GetTenantConfig(ctx, tenantID)
+	if err != nil || current == nil || current.RetryPolicy == nil {
+		return false, err
+	}
+	current.RetryPolicy = nil
+	if _, err := Save(ctx, tenantID, *current); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Invalidate drops the cached snapshot so the next read reloads it.
+func Invalidate() {
+	mu.Lock()
+	loadedAt = time.Time{}
+	mu.Unlock()
+}
+
+// Snapshot returns every tenant's configuration by tenant ID, reloading them
+// once the cached copy is older than the TTL. The map is shared and must not
+// be modified.
+func Snapshot(ctx context.Context) (map[string]models.TenantConfig, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if time.Since(loadedAt) > cacheTTL {
+		configs, err := store.ListTenantConfigs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		loaded := make(map[string]models.TenantConfig, len(configs))
+		for _, cfg := range configs {
+			loaded[cfg.TenantID] = cfg
+		}
+		snapshot, loadedAt = loaded, time.Now()
+	}
+	return snapshot, nil
+}
+
+// Get returns a tenant's configuration; tenants without one get the zero
+// configuration, under which every default applies.
+func Get(ctx context.Context, tenantID string) (models.TenantConfig, error) {
+	configs, err := Snapshot(ctx)
+	if err != nil {
+		return models.TenantConfig{}, err
+	}
+	cfg, ok := configs[tenantID]
+	if !ok {
+		return models.TenantConfig{TenantID: tenantID}, nil
+	}
+	return cfg, nil
+}
+
+// QuietHoursEnd returns when the quiet hours containing now end, or false if
+// now is outside them.
+func QuietHoursEnd(window *models.QuietHours, now time.Time) (time.Time, bool) {
+	if window == nil || window.Start == window.End {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		// Validated on save; an unknown zone can only come from a newer replica
+		location = time.UTC
+	}
+	local := now.In(location)
+	clock := local.Format("15:04")
+	inside := clock >= window.Start && clock < window.End
+	if window.Start > window.End {
+		inside = clock >= window.Start || clock < window.End
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	end, _ := time.Parse("15:04", window.End)
+	release := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, location)
+	if !release.After(local) {
+		// e.g. 23:00 in a 21:00-09:00 window ends tomorrow
+		release = release.AddDate(0, 0, 1)
+	}
+	return release, true
+}
+
+func normalize(cfg *models.TenantConfig) error {
+	if strings.TrimSpace(cfg.TenantID) == "" {
+		return fmt.Errorf("%w: tenant ID is required", ErrInvalidConfig)
+	}
+	if cfg.RetentionDays != nil && *cfg.RetentionDays < 0 {
+		return fmt.Errorf("%w: retention_days cannot be negative", ErrInvalidConfig)
+	}
+	if cfg.DailyQuota < 0 {
+		return fmt.Errorf("%w: daily_quota cannot be negative", ErrInvalidConfig)
+	}
+
+	var senders []string
+	for _, sender := range cfg.AllowedSenders {
+		sender = strings.TrimSpace(sender)
+		if sender == "" {
+			return fmt.Errorf("%w: allowed_senders cannot contain empty sender IDs", ErrInvalidConfig)
+		}
+		if !slices.Contains(senders, sender) {
+			senders = append(senders, sender)
+		}
+	}
+	cfg.AllowedSenders = senders
+
+	if window := cfg.QuietHours; window != nil {
+		if !clockPattern.MatchString(window.Start) || !clockPattern.MatchString(window.End) {
+			return fmt.Errorf("%w: quiet_hours start and end must be HH:MM", ErrInvalidConfig)
+		}
+		if window.Timezone == "" {
+			window.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(window.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown quiet_hours timezone %q", ErrInvalidConfig, window.Timezone)
+		}
+	}
+
+	if policy := cfg.RetryPolicy; policy != nil {
+		if policy.AfterMinutes < 1 || policy.AfterMinutes > MaxRetryAfterMinutes {
+			return fmt.Errorf("%w: retry_policy after_minutes must be between 1 and %d", ErrInvalidConfig, MaxRetryAfterMinutes)
+		}
+		if policy.MaxAttempts < 1 || policy.MaxAttempts > MaxRetryAttempts {
+			return fmt.Errorf("%w: retry_policy max_attempts must be between 1 and %d", ErrInvalidConfig, MaxRetryAttempts)
+		}
+		policy.Provider = strings.ToLower(strings.TrimSpace(policy.Provider))
+		if policy.CreatedAt.IsZero() {
+			policy.CreatedAt = time.Now().UTC()
+		}
+	}
+	return nil
+}
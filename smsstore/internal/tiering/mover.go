@@ -0,0 +1,60 @@
+package tiering
+
+import (
+	"context"
+	"log"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"time"
+)
+
+// usersPerBatch bounds how many user documents a single move pass rewrites.
+const usersPerBatch = 100
+
+// StartMover periodically moves messages older than the hot window into the
+// cold collection. Blocks until ctx is cancelled.
+func StartMover(ctx context.Context, cfg *config.Config, repo *repository.Repository) {
+	if cfg.HotTierDays == 0 {
+		log.Println("[TIERING] Mover disabled (HOT_TIER_DAYS=0)")
+		return
+	}
+
+	log.Printf("[TIERING] Mover started: hot=%dd, interval=%s", cfg.HotTierDays, cfg.TieringInterval)
+	ticker := time.NewTicker(cfg.TieringInterval)
+	defer ticker.Stop()
+
+	for {
+		// Residency regions and tenants with their own database keep their
+		// messages apart
+		for _, scope := range repo.Scopes() {
+			runOnce(scope.Context(ctx), repo, cfg.HotTierDays)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("[TIERING] Mover stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, repo *repository.Repository, hotDays int) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -hotDays)
+	total := 0
+	for ctx.Err() == nil {
+		moved, err := repo.MoveMessagesToCold(ctx, cutoff, usersPerBatch)
+		total += moved
+		metrics.MessagesMovedToCold.Add(float64(moved))
+		if err != nil {
+			log.Printf("[TIERING] Move to cold tier failed after %d messages%s: %v", total, repo.ScopeFrom(ctx).Label(), err)
+			return
+		}
+		if moved == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("[TIERING] Moved %d messages older than %s to the cold tier%s", total, cutoff.Format(time.RFC3339), repo.ScopeFrom(ctx).Label())
+	}
+}
@@ -0,0 +1,186 @@
+package usercache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"smsstore/internal/config"
+	"smsstore/internal/features"
+	"smsstore/internal/metrics"
+	"smsstore/internal/notify"
+	"smsstore/internal/repository"
+	"smsstore/pkg/models"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// The cache holds recent message listings for hot users in process, keyed by
+// user and query. Concurrent misses for the same key share one Mongo read via
+// singleflight. Entries are dropped when the change stream reports a write for
+// the user; the TTL bounds staleness if the stream lags or is unavailable.
+
+// entry is one cached listing.
+type entry struct {
+	key      string
+	userID   string
+	messages []models.MessageWithStatus
+	expires  time.Time
+}
+
+// load counts invalidations seen while reads of a user are in flight.
+type load struct {
+	readers      int
+	invalidation uint64
+}
+
+var (
+	mu      sync.Mutex
+	maxSize = 0 // zero disables the cache
+	ttl     = 30 * time.Second
+	order   = list.New()                            // front is most recently used
+	entries = map[string]*list.Element{}            // by key
+	byUser  = map[string]map[string]*list.Element{} // by user, then key
+	// loading tracks users with reads in flight, so a read that raced a write isn't cached
+	loading = map[string]*load{}
+	group   singleflight.Group
+	now     = time.Now
+)
+
+// Configure applies the cache settings and subscribes to change notifications
+// for invalidation. Call once at startup.
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	maxSize = cfg.UserCacheSize
+	ttl = cfg.UserCacheTTL
+	mu.Unlock()
+	if cfg.UserCacheSize > 0 {
+		notify.AddListener(func(event notify.Event) { Invalidate(event.UserID) })
+	}
+}
+
+// Store is a repository.MessageStore whose GetUserMessages goes through the
+// cache.
+type Store struct {
+	repository.MessageStore
+
+	// Repository resolves the database each listing is read from
+	Repository *repository.Repository
+}
+
+// GetUserMessages is the wrapped store's GetUserMessages behind the cache,
+// unless the user_cache feature is off for ctx's tenant. The returned slice may
+// be shared with other callers and must not be modified.
+func (s Store) GetUserMessages(ctx context.Context, userID string, query repository.MessageQuery) ([]models.MessageWithStatus, error) {
+	mu.Lock()
+	enabled := maxSize > 0
+	mu.Unlock()
+	if !enabled || !features.Enabled(ctx, features.UserCache) {
+		return s.MessageStore.GetUserMessages(ctx, userID, query)
+	}
+
+	// fmt prints maps in key order, so equal queries produce equal keys
+	scope := s.Repository.ScopeFrom(ctx)
+	key := fmt.Sprintf("%s|%s|%s|%+v", scope.Region, scope.Tenant, userID, query)
+	if messages, ok := lookup(key); ok {
+		metrics.UserCacheRequests.WithLabelValues("hit").Inc()
+		return messages, nil
+	}
+
+	// The shared read must not fail because the caller that started it went away
+	loadCtx := context.WithoutCancel(ctx)
+	result, err, shared := group.Do(key, func() (interface{}, error) {
+		started := beginLoad(userID)
+		messages, err := s.MessageStore.GetUserMessages(loadCtx, userID, query)
+		endLoad(userID, key, started, messages, err)
+		return messages, err
+	})
+	if shared {
+		metrics.UserCacheRequests.WithLabelValues("shared").Inc()
+	} else {
+		metrics.UserCacheRequests.WithLabelValues("miss").Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.([]models.MessageWithStatus), nil
+}
+
+// Invalidate drops every cached listing for a user.
+func Invalidate(userID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if pending := loading[userID]; pending != nil {
+		pending.invalidation++
+	}
+	for _, element := range byUser[userID] {
+		remove(element)
+	}
+}
+
+func lookup(key string) ([]models.MessageWithStatus, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	element, ok := entries[key]
+	if !ok {
+		return nil, false
+	}
+	cached := element.Value.(*entry)
+	if now().After(cached.expires) {
+		remove(element)
+		return nil, false
+	}
+	order.MoveToFront(element)
+	return cached.messages, true
+}
+
+func beginLoad(userID string) uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+	pending := loading[userID]
+	if pending == nil {
+		pending = &load{}
+		loading[userID] = pending
+	}
+	pending.readers++
+	return pending.invalidation
+}
+
+// endLoad caches a successful read unless the user was invalidated since it began.
+func endLoad(userID, key string, started uint64, messages []models.MessageWithStatus, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	pending := loading[userID]
+	if pending.readers--; pending.readers == 0 {
+		delete(loading, userID)
+	}
+	if err != nil || pending.invalidation != started {
+		return
+	}
+	if element, ok := entries[key]; ok {
+		remove(element)
+	}
+	element := order.PushFront(&entry{key: key, userID: userID, messages: messages, expires: now().Add(ttl)})
+	entries[key] = element
+	if byUser[userID] == nil {
+		byUser[userID] = map[string]*list.Element{}
+	}
+	byUser[userID][key] = element
+	for order.Len() > maxSize {
+		remove(order.Back())
+	}
+	metrics.UserCacheEntries.Set(float64(order.Len()))
+}
+
+// remove unlinks an element from every index. Callers hold mu.
+func remove(element *list.Element) {
+	cached := element.Value.(*entry)
+	order.Remove(element)
+	delete(entries, cached.key)
+	delete(byUser[cached.userID], cached.key)
+	if len(byUser[cached.userID]) == 0 {
+		delete(byUser, cached.userID)
+	}
+	metrics.UserCacheEntries.Set(float64(order.Len()))
+}
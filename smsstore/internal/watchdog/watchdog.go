@@ -0,0 +1,245 @@
+// Package watchdog alerts on failures no request or event would surface: a
+// consumer group that stopped committing while events wait for it, and a
+// dead-letter topic filling up. Both are read from Kafka, so every replica
+// sees the whole group and the checks don't depend on which partitions this
+// replica was assigned.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"smsstore/internal/alerting"
+	"smsstore/internal/breaker"
+	"smsstore/internal/config"
+	"smsstore/internal/metrics"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// sample is the dead-letter topic's total end offset at a point in time.
+type sample struct {
+	at     time.Time
+	offset int64
+}
+
+// watchdog holds what one check needs from the previous ones.
+type watchdog struct {
+	cfg      *config.Config
+	client   *kafka.Client
+	notifier *alerting.Notifier
+
+	// committed is the group's summed committed offsets when they last moved
+	committed    int64
+	lastProgress time.Time
+	// deadLetters covers DeadLetterGrowthWindow, oldest first
+	deadLetters []sample
+}
+
+// Start checks the consumer group and dead-letter topic every
+// WATCHDOG_INTERVAL and fires alerts on a stalled consumer or dead-letter
+// growth. Blocks until ctx is cancelled.
+func Start(ctx context.Context, cfg *config.Config) {
+	stallCheck := cfg.ConsumerStallAfter > 0
+	growthCheck := cfg.DeadLetterEnabled && cfg.DeadLetterGrowthThreshold > 0
+	if !stallCheck && !growthCheck {
+		log.Println("[WATCHDOG] Disabled")
+		return
+	}
+	log.Printf("[WATCHDOG] Started: stallAfter=%s deadLetterGrowth=%d/%s", cfg.ConsumerStallAfter, cfg.DeadLetterGrowthThreshold, cfg.DeadLetterGrowthWindow)
+
+	w := &watchdog{
+		cfg:          cfg,
+		client:       &kafka.Client{Addr: kafka.TCP(cfg.KafkaBrokers...), Timeout: 10 * time.Second},
+		notifier:     alerting.NewNotifier(cfg),
+		committed:    -1,
+		lastProgress: time.Now(),
+	}
+	ticker := time.NewTicker(cfg.WatchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[WATCHDOG] Stopped")
+			return
+		case <-ticker.C:
+		}
+		if stallCheck {
+			if err := w.checkStall(ctx, time.Now()); err != nil {
+				log.Printf("[WATCHDOG] Consumer check failed: %v", err)
+			}
+		}
+		if growthCheck {
+			if err := w.checkDeadLetters(ctx, time.Now()); err != nil {
+				log.Printf("[WATCHDOG] Dead-letter check failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkStall alerts when the group has lag but its committed offsets haven't
+// moved for CONSUMER_STALL_AFTER. A consumer paused by its breaker has already
+// alerted and waits for an admin, so it isn't reported again.
+func (w *watchdog) checkStall(ctx context.Context, now time.Time) error {
+	committed, lag, err := w.groupOffsets(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.ConsumerLag.Set(float64(lag))
+	if committed != w.committed || lag == 0 {
+		w.committed, w.lastProgress = committed, now
+		return nil
+	}
+	stalledFor := now.Sub(w.lastProgress)
+	if stalledFor < w.cfg.ConsumerStallAfter || breaker.Status().Paused {
+		return nil
+	}
+	w.notifier.Fire(ctx, alerting.Alert{
+		Name:     "consumer_stalled",
+		Severity: "critical",
+		Summary:  fmt.Sprintf("No event consumed from %s in %s while %d wait", w.cfg.KafkaTopic, stalledFor.Round(time.Second), lag),
+		Details: map[string]string{
+			"topic": w.cfg.KafkaTopic,
+			"group": w.cfg.KafkaGroupID,
+			"lag":   fmt.Sprintf("%d", lag),
+			"since": w.lastProgress.UTC().Format(time.RFC3339),
+		},
+	})
+	return nil
+}
+
+// checkDeadLetters alerts when the dead-letter topic grew by more than
+// DEAD_LETTER_GROWTH_THRESHOLD within DEAD_LETTER_GROWTH_WINDOW. Until a full
+// window has been observed, growth since the watchdog started is compared.
+func (w *watchdog) checkDeadLetters(ctx context.Context, now time.Time) error {
+	ends, err := w.endOffsets(ctx, w.cfg.DeadLetterTopic)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, offset := range ends {
+		total += offset
+	}
+
+	cutoff := now.Add(-w.cfg.DeadLetterGrowthWindow)
+	for len(w.deadLetters) > 1 && !w.deadLetters[1].at.After(cutoff) {
+		w.deadLetters = w.deadLetters[1:]
+	}
+	w.deadLetters = append(w.deadLetters, sample{at: now, offset: total})
+	oldest := w.deadLetters[0]
+	growth := total - oldest.offset
+	if growth <= int64(w.cfg.DeadLetterGrowthThreshold) {
+		return nil
+	}
+	w.notifier.Fire(ctx, alerting.Alert{
+		Name:     "dead_letter_growth",
+		Severity: "critical",
+		Summary:  fmt.Sprintf("%d events reached %s in %s", growth, w.cfg.DeadLetterTopic, now.Sub(oldest.at).Round(time.Minute)),
+		Details: map[string]string{
+			"topic":     w.cfg.DeadLetterTopic,
+			"growth":    fmt.Sprintf("%d", growth),
+			"threshold": fmt.Sprintf("%d", w.cfg.DeadLetterGrowthThreshold),
+			"since":     oldest.at.UTC().Format(time.RFC3339),
+		},
+	})
+	return nil
+}
+
+// groupOffsets returns the consumer group's committed offsets summed over the
+// topic's partitions, and its lag: the events after them. Partitions the group
+// never committed count from their first offset.
+func (w *watchdog) groupOffsets(ctx context.Context) (committed int64, lag int64, err error) {
+	partitions, err := w.partitions(ctx, w.cfg.KafkaTopic)
+	if err != nil {
+		return 0, 0, err
+	}
+	offsets, err := w.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{
+		w.cfg.KafkaTopic: offsetRequests(partitions),
+	}})
+	if err != nil {
+		return 0, 0, err
+	}
+	fetched, err := w.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: w.cfg.KafkaGroupID,
+		Topics:  map[string][]int{w.cfg.KafkaTopic: partitions},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if fetched.Error != nil {
+		return 0, 0, fetched.Error
+	}
+
+	commits := map[int]int64{}
+	for _, partition := range fetched.Topics[w.cfg.KafkaTopic] {
+		if partition.Error != nil {
+			return 0, 0, partition.Error
+		}
+		commits[partition.Partition] = partition.CommittedOffset
+	}
+	for _, partition := range offsets.Topics[w.cfg.KafkaTopic] {
+		if partition.Error != nil {
+			return 0, 0, partition.Error
+		}
+		position, ok := commits[partition.Partition]
+		if !ok || position < partition.FirstOffset {
+			position = partition.FirstOffset
+		}
+		committed += position
+		lag += max(partition.LastOffset-position, 0)
+	}
+	return committed, lag, nil
+}
+
+// endOffsets returns the next offset of each of a topic's partitions.
+func (w *watchdog) endOffsets(ctx context.Context, topic string) (map[int]int64, error) {
+	partitions, err := w.partitions(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := w.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{
+		topic: offsetRequests(partitions),
+	}})
+	if err != nil {
+		return nil, err
+	}
+	ends := make(map[int]int64, len(partitions))
+	for _, partition := range offsets.Topics[topic] {
+		if partition.Error != nil {
+			return nil, partition.Error
+		}
+		ends[partition.Partition] = partition.LastOffset
+	}
+	return ends, nil
+}
+
+func (w *watchdog) partitions(ctx context.Context, topic string) ([]int, error) {
+	resp, err := w.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, metadata := range resp.Topics {
+		if metadata.Name != topic {
+			continue
+		}
+		if metadata.Error != nil {
+			return nil, metadata.Error
+		}
+		partitions := make([]int, 0, len(metadata.Partitions))
+		for _, partition := range metadata.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+		return partitions, nil
+	}
+	return nil, fmt.Errorf("topic '%s' not found", topic)
+}
+
+// offsetRequests asks for the first and last offset of each partition.
+func offsetRequests(partitions []int) []kafka.OffsetRequest {
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for _, partition := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(partition), kafka.LastOffsetOf(partition))
+	}
+	return requests
+}
@@ -0,0 +1,352 @@
+// Package webhooks delivers message events to subscribed URLs. Matching events
+// are enqueued as jobs, so deliveries survive restarts and are retried with
+// backoff by whichever replica claims them; every attempt is recorded in the
+// subscription's delivery log.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"smsstore/internal/config"
+	"smsstore/internal/features"
+	"smsstore/internal/jobs"
+	"smsstore/internal/metrics"
+	"smsstore/internal/repository"
+	"smsstore/internal/tenants"
+	"smsstore/pkg/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobType is the job runner type of a single webhook delivery.
+const JobType = "webhook_delivery"
+
+// Signature headers sent with every delivery. The signature is
+// "v1=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Events lists the event types subscriptions can ask for.
+var Events = []string{models.WebhookMessageSent, models.WebhookMessageDelivered, models.WebhookMessageFailed, models.WebhookMessageRead, models.WebhookMessageClicked}
+
+// A successful send only means the provider accepted the message; delivery is
+// reported by the receipt that follows
+var statusEvents = map[string]string{
+	"successful":   models.WebhookMessageSent,
+	"delivered":    models.WebhookMessageDelivered,
+	"unsuccessful": models.WebhookMessageFailed,
+	"failed":       models.WebhookMessageFailed,
+	"undelivered":  models.WebhookMessageFailed,
+	"blocked":      models.WebhookMessageFailed,
+}
+
+var (
+	mu            sync.Mutex
+	cacheTTL      = 30 * time.Second
+	maxAttempts   = 1
+	subscriptions []models.WebhookSubscription
+	loadedAt      time.Time
+	client        = &http.Client{Timeout: 10 * time.Second}
+
+	// generation is bumped by Invalidate, so a reload that started before it is discarded
+	generation int
+
+	// store holds the subscriptions and delivery log, and queues deliveries
+	store *repository.Repository
+)
+
+// Configure applies the delivery settings and registers the delivery job type,
+// keeping subscriptions and deliveries in repo. Call once at startup, before
+// the job runner starts.
+func Configure(cfg *config.Config, repo *repository.Repository) {
+	mu.Lock()
+	store = repo
+	cacheTTL = cfg.WebhookSubscriptionTTL
+	maxAttempts = cfg.WebhookMaxAttempts
+	client = &http.Client{Timeout: cfg.WebhookTimeout}
+	mu.Unlock()
+	jobs.Register(JobType, jobs.RetryPolicy{MaxAttempts: cfg.WebhookMaxAttempts, Backoff: cfg.WebhookRetryBackoff}, deliver)
+}
+
+// EventForStatus maps a stored message status to its webhook event type, or
+// "" when the status has none (e.g. "retrying").
+func EventForStatus(status string) string {
+	return statusEvents[status]
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ErrInvalidSubscription wraps validation failures of a subscription request.
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+// Subscribe validates and stores a subscription, generating its secret when
+// the request has none.
+func Subscribe(ctx context.Context, req models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidSubscription)
+	}
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: events must list at least one of %s", ErrInvalidSubscription, strings.Join(Events, ", "))
+	}
+	var events []string
+	for _, event := range req.Events {
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalidSubscription, event, strings.Join(Events, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	category := strings.ToLower(strings.TrimSpace(req.Category))
+	if category != "" && category != "transactional" && category != "promotional" {
+		return nil, fmt.Errorf("%w: category must be transactional or promotional", ErrInvalidSubscription)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = NewSecret(); err != nil {
+			return nil, err
+		}
+	}
+	subscription := &models.WebhookSubscription{
+		ID:        primitive.NewObjectID().Hex(),
+		URL:       req.URL,
+		Events:    events,
+		TenantID:  strings.TrimSpace(req.TenantID),
+		Category:  category,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := store.InsertWebhookSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	Invalidate()
+	log.Printf("[WEBHOOKS] Subscription %s created for %v -> %s", subscription.ID, events, target.Host)
+	return subscription, nil
+}
+
+// Unsubscribe deletes a subscription; pending deliveries to it are dropped
+// when they next run. Returns false if it did not exist.
+func Unsubscribe(ctx context.Context, id string) (bool, error) {
+	deleted, err := store.DeleteWebhookSubscription(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	Invalidate()
+	return deleted, nil
+}
+
+// Invalidate drops the cached subscriptions so changes made through this
+// replica apply immediately; other replicas pick them up within the TTL.
+func Invalidate() {
+	mu.Lock()
+	loadedAt = time.Time{}
+	generation++
+	mu.Unlock()
+}
+
+// Publish enqueues a delivery of eventType for message to every matching
+// subscription, unless the message's tenant has webhooks turned off or the
+// webhooks feature is off for it. Failures are logged and never propagated, so
+// the write path is unaffected.
+func Publish(ctx context.Context, eventType string, userID string, message *models.MessageWithStatus) {
+	if eventType == "" || message == nil || !features.EnabledFor(features.Webhooks, message.TenantID) {
+		return
+	}
+	if message.TenantID != "" {
+		tenant, err := tenants.Get(ctx, message.TenantID)
+		if err != nil {
+			log.Printf("[WEBHOOKS] Failed to load configuration of tenant %s: %v", message.TenantID, err)
+		} else if !tenant.WebhooksAllowed() {
+			return
+		}
+	}
+	matching, err := match(ctx, eventType, message)
+	if err != nil {
+		log.Printf("[WEBHOOKS] Failed to load subscriptions: %v", err)
+		return
+	}
+	if len(matching) == 0 {
+		return
+	}
+
+	event := models.WebhookEvent{
+		ID:         primitive.NewObjectID().Hex(),
+		Type:       eventType,
+		UserID:     userID,
+		Message:    message,
+		OccurredAt: time.Now().UTC(),
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[WEBHOOKS] Failed to encode %s event for %s: %v", eventType, userID, err)
+		return
+	}
+	for _, subscription := range matching {
+		params := map[string]string{
+			"subscription_id": subscription.ID,
+			"event_id":        event.ID,
+			"event_type":      eventType,
+			"payload":         string(payload),
+		}
+		if _, err := jobs.Enqueue(ctx, store, JobType, params); err != nil {
+			log.Printf("[WEBHOOKS] Failed to enqueue %s delivery to subscription %s: %v", eventType, subscription.ID, err)
+		}
+	}
+}
+
+// match returns the subscriptions wanting eventType for message, reloading
+// them once the cached copy is older than the TTL. The reload runs without
+// holding mu, so a slow MongoDB doesn't stall deliveries and other lookups.
+func match(ctx context.Context, eventType string, message *models.MessageWithStatus) ([]models.WebhookSubscription, error) {
+	mu.Lock()
+	current, stale, loadedGeneration := subscriptions, time.Since(loadedAt) > cacheTTL, generation
+	mu.Unlock()
+	if stale {
+		loaded, err := store.ListWebhookSubscriptions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		if generation == loadedGeneration {
+			subscriptions, loadedAt = loaded, time.Now()
+		}
+		mu.Unlock()
+		current = loaded
+	}
+
+	var matching []models.WebhookSubscription
+	for _, subscription := range current {
+		if !slices.Contains(subscription.Events, eventType) {
+			continue
+		}
+		if subscription.TenantID != "" && subscription.TenantID != message.TenantID {
+			continue
+		}
+		if subscription.Category != "" && subscription.Category != message.Category {
+			continue
+		}
+		matching = append(matching, subscription)
+	}
+	return matching, nil
+}
+
+// deliver is the job handler for one delivery. 2xx responses succeed; 4xx
+// responses other than 408 and 429 are not retried.
+func deliver(ctx context.Context, params map[string]string, _ jobs.ProgressFunc) (interface{}, error) {
+	subscription, err := store.GetWebhookSubscription(ctx, params["subscription_id"])
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, jobs.Permanent(errors.New("subscription was deleted"))
+	}
+
+	mu.Lock()
+	httpClient, attempts := client, maxAttempts
+	mu.Unlock()
+
+	attempt := jobs.Attempt(ctx)
+	started := time.Now()
+	statusCode, err := post(ctx, httpClient, subscription, params)
+	record := &models.WebhookDelivery{
+		ID:             primitive.NewObjectID().Hex(),
+		SubscriptionID: subscription.ID,
+		EventID:        params["event_id"],
+		EventType:      params["event_type"],
+		Attempt:        attempt,
+		StatusCode:     statusCode,
+		Succeeded:      err == nil,
+		DurationMs:     time.Since(started).Milliseconds(),
+		JobID:          jobs.ID(ctx),
+		AttemptedAt:    started.UTC(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	// The log is best-effort; a failed write must not cause a redelivery
+	if logErr := store.InsertWebhookDelivery(context.WithoutCancel(ctx), record); logErr != nil {
+		log.Printf("[WEBHOOKS] Failed to record delivery to subscription %s: %v", subscription.ID, logErr)
+	}
+
+	result := map[string]interface{}{"status_code": statusCode}
+	switch {
+	case err == nil:
+		metrics.WebhookDeliveries.WithLabelValues(params["event_type"], "success").Inc()
+		return result, nil
+	case statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests:
+		metrics.WebhookDeliveries.WithLabelValues(params["event_type"], "failed").Inc()
+		return result, jobs.Permanent(err)
+	case attempt >= attempts:
+		metrics.WebhookDeliveries.WithLabelValues(params["event_type"], "failed").Inc()
+	default:
+		metrics.WebhookDeliveries.WithLabelValues(params["event_type"], "retry").Inc()
+	}
+	return result, err
+}
+
+// post sends the signed payload and returns the response status, or 0 if no
+// response was received.
+func post(ctx context.Context, httpClient *http.Client, subscription *models.WebhookSubscription, params map[string]string) (int, error) {
+	body := []byte(params["payload"])
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, params["event_id"])
+	req.Header.Set(HeaderEvent, params["event_type"])
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s responded with status %d", subscription.URL, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the signature header value for a delivery body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
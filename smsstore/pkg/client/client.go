@@ -0,0 +1,299 @@
+// Package client is a typed Go client for the SMS store API and the SMS
+// sender's send endpoint, for internal services that would otherwise
+// hand-roll HTTP calls.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"smsstore/pkg/models"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 2
+	defaultBackoff    = 200 * time.Millisecond
+)
+
+// Client talks to the SMS store (reads) and, optionally, the SMS sender (sends).
+// It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	senderURL  string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	headers    http.Header
+}
+
+// Option customizes a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client (10s timeout).
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithSenderURL sets the base URL of the SMS sender service used by SendSMS.
+func WithSenderURL(senderURL string) Option {
+	return func(c *Client) { c.senderURL = strings.TrimRight(senderURL, "/") }
+}
+
+// WithRetries sets how many times retryable failures are retried and the
+// initial backoff, which doubles on each attempt.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithHeader adds a header to every request, e.g. credentials.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers.Add(key, value) }
+}
+
+// New creates a client for the SMS store at baseURL (e.g. http://smsstore:8081).
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		headers:    http.Header{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned for non-2xx responses.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("smsstore: %d %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("smsstore: %d %s", e.StatusCode, e.Message)
+}
+
+// GetMessagesOptions filters and shapes a message listing. Zero values are omitted.
+type GetMessagesOptions struct {
+	From        time.Time
+	To          time.Time
+	Descending  bool
+	Status      string
+	Provider    string
+	CampaignID  string
+	TemplateID  string
+	CountryCode string
+	Language    string
+	SenderID    string
+	// Metadata matches messages carrying all of these metadata entries
+	Metadata map[string]string
+}
+
+// GetMessages lists a user's messages.
+func (c *Client) GetMessages(ctx context.Context, userID string, opts *GetMessagesOptions) (*models.ApiResponse, error) {
+	query := url.Values{}
+	if opts != nil {
+		if !opts.From.IsZero() {
+			query.Set("from", opts.From.Format(time.RFC3339))
+		}
+		if !opts.To.IsZero() {
+			query.Set("to", opts.To.Format(time.RFC3339))
+		}
+		if opts.Descending {
+			query.Set("sort", "desc")
+		}
+		for key, value := range map[string]string{
+			"status":       opts.Status,
+			"provider":     opts.Provider,
+			"campaign_id":  opts.CampaignID,
+			"template_id":  opts.TemplateID,
+			"country_code": opts.CountryCode,
+			"language":     opts.Language,
+			"sender_id":    opts.SenderID,
+		} {
+			if value != "" {
+				query.Set(key, value)
+			}
+		}
+		for key, value := range opts.Metadata {
+			query.Set("metadata."+key, value)
+		}
+	}
+
+	var response models.ApiResponse
+	endpoint := c.baseURL + "/v1/user/" + url.PathEscape(userID) + "/messages"
+	if err := c.do(ctx, http.MethodGet, endpoint, query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetStats returns message counts by status for a user.
+func (c *Client) GetStats(ctx context.Context, userID string) (*models.UserStats, error) {
+	var stats models.UserStats
+	endpoint := c.baseURL + "/v1/user/" + url.PathEscape(userID) + "/stats"
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, nil, &stats, true); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetConversations lists the senders a user has received messages from, most
+// recently active first. Use GetMessagesOptions.SenderID to list one conversation.
+func (c *Client) GetConversations(ctx context.Context, userID string) (*models.ConversationsResponse, error) {
+	var response models.ConversationsResponse
+	endpoint := c.baseURL + "/v1/user/" + url.PathEscape(userID) + "/conversations"
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SendSMSRequest mirrors the sender's POST /v1/sms/send body.
+type SendSMSRequest struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Message     string `json:"message"`
+	CampaignID  string `json:"campaignId,omitempty"`
+	TemplateID  string `json:"templateId,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	// Metadata is stored with the message and searchable via metadata.<key>
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SendSMSResponse mirrors the sender's response body.
+type SendSMSResponse struct {
+	Result    string `json:"result"`
+	MessageID string `json:"messageId,omitempty"`
+	// Status is the outcome of a SendSMSAndWait; "pending" if the sender's wait
+	// timed out
+	Status string `json:"status,omitempty"`
+}
+
+// SendSMS submits a message through the SMS sender. Requires WithSenderURL.
+// Sends are not idempotent, so only responses that guarantee the request was
+// not processed (429, 503) are retried.
+func (c *Client) SendSMS(ctx context.Context, req SendSMSRequest) (*SendSMSResponse, error) {
+	return c.sendSMS(ctx, req, nil)
+}
+
+// SendSMSAndWait is SendSMS with wait=true: the sender answers once the send
+// has an outcome, after any provider retries, or as "pending" when its wait
+// times out. The default HTTP client's 10s timeout is shorter than the
+// sender's default 30s wait; pass a longer one with WithHTTPClient.
+func (c *Client) SendSMSAndWait(ctx context.Context, req SendSMSRequest) (*SendSMSResponse, error) {
+	return c.sendSMS(ctx, req, url.Values{"wait": {"true"}})
+}
+
+func (c *Client) sendSMS(ctx context.Context, req SendSMSRequest, query url.Values) (*SendSMSResponse, error) {
+	if c.senderURL == "" {
+		return nil, errors.New("smsstore: SendSMS requires WithSenderURL")
+	}
+	var response SendSMSResponse
+	if err := c.do(ctx, http.MethodPost, c.senderURL+"/v1/sms/send", query, req, &response, false); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// do performs a request with retries. idempotent requests are also retried on
+// transport errors and 5xx responses.
+func (c *Client) do(ctx context.Context, method, endpoint string, query url.Values, body, out interface{}, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, endpoint, payload, out)
+		if err == nil || attempt >= c.maxRetries || !retryable(err, idempotent) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, endpoint string, payload []byte, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	// Always decode the flat shape regardless of the server's default format
+	req.Header.Set("X-Response-Format", models.ResponseFormatFlat)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		var errorBody models.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errorBody) == nil && errorBody.Error != "" {
+			apiErr.Message = errorBody.Error
+		} else {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true
+		}
+		return idempotent && apiErr.StatusCode >= 500
+	}
+	// Transport error: the request may have reached the server
+	return idempotent
+}
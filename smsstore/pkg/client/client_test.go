@@ -0,0 +1,249 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"smsstore/pkg/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetMessagesEncodesRequest(t *testing.T) {
+	from := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("method = %s, want GET", r.Method)
+		}
+		// User IDs are path-escaped, not spliced into the path
+		if got, want := r.URL.EscapedPath(), "/v1/user/acme%2F42/messages"; got != want {
+			t.Errorf("path = %s, want %s", got, want)
+		}
+		want := url.Values{
+			"from":           {"2026-10-14T09:00:00Z"},
+			"to":             {"2026-10-14T10:00:00Z"},
+			"sort":           {"desc"},
+			"status":         {"successful"},
+			"country_code":   {"IN"},
+			"metadata.order": {"A-1"},
+		}
+		if got := r.URL.Query(); got.Encode() != want.Encode() {
+			t.Errorf("query = %s, want %s", got.Encode(), want.Encode())
+		}
+		for header, want := range map[string]string{
+			"Accept":            "application/json",
+			"X-Response-Format": models.ResponseFormatFlat,
+			"Authorization":     "Bearer key",
+		} {
+			if got := r.Header.Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			UserID:   "acme/42",
+			Messages: []models.MessageWithStatus{{MessageID: "m1", Message: "hi", Status: "successful"}},
+			Count:    1,
+		})
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", WithHeader("Authorization", "Bearer key"))
+	response, err := c.GetMessages(context.Background(), "acme/42", &GetMessagesOptions{
+		From:        from,
+		To:          to,
+		Descending:  true,
+		Status:      "successful",
+		CountryCode: "IN",
+		Metadata:    map[string]string{"order": "A-1"},
+	})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if response.Count != 1 || len(response.Messages) != 1 || response.Messages[0].MessageID != "m1" {
+		t.Errorf("response = %+v, want the one message", response)
+	}
+}
+
+// Listings have no cursor: callers page through a range by [from, to) windows,
+// so consecutive windows must neither drop nor repeat a message.
+func TestGetMessagesPagesByWindow(t *testing.T) {
+	start := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	var stored []models.MessageWithStatus
+	for i, offset := range []time.Duration{0, 30 * time.Minute, time.Hour, 150 * time.Minute, 3 * time.Hour} {
+		stored = append(stored, models.MessageWithStatus{
+			MessageID: string(rune('a' + i)),
+			CreatedAt: start.Add(offset),
+		})
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+		if err != nil {
+			t.Errorf("from: %v", err)
+		}
+		to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+		if err != nil {
+			t.Errorf("to: %v", err)
+		}
+		response := models.ApiResponse{UserID: "u1", Messages: []models.MessageWithStatus{}}
+		for _, message := range stored {
+			if !message.CreatedAt.Before(from) && message.CreatedAt.Before(to) {
+				response.Messages = append(response.Messages, message)
+			}
+		}
+		response.Count = len(response.Messages)
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	var got []string
+	for from := start; from.Before(start.Add(4 * time.Hour)); from = from.Add(time.Hour) {
+		page, err := c.GetMessages(context.Background(), "u1", &GetMessagesOptions{From: from, To: from.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("GetMessages from %s: %v", from, err)
+		}
+		for _, message := range page.Messages {
+			got = append(got, message.MessageID)
+		}
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("paged messages = %v, want %v", got, want)
+	}
+}
+
+func TestSendSMSEncodesRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		wait     bool
+		wantWait string
+	}{
+		{name: "send", wantWait: ""},
+		{name: "send and wait", wait: true, wantWait: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/sms/send" {
+					t.Errorf("request = %s %s, want POST /v1/sms/send", r.Method, r.URL.Path)
+				}
+				if got := r.URL.Query().Get("wait"); got != tt.wantWait {
+					t.Errorf("wait = %q, want %q", got, tt.wantWait)
+				}
+				if got := r.Header.Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", got)
+				}
+				var body map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				if body["phoneNumber"] != "+919876543210" || body["message"] != "hi" || body["campaignId"] != "diwali" {
+					t.Errorf("body = %v", body)
+				}
+				// Unset optional fields are left out rather than sent empty
+				if _, ok := body["templateId"]; ok {
+					t.Errorf("body = %v, want no templateId", body)
+				}
+				json.NewEncoder(w).Encode(SendSMSResponse{Result: "Success", MessageID: "m1", Status: "successful"})
+			}))
+			defer server.Close()
+
+			c := New("http://smsstore.invalid", WithSenderURL(server.URL+"/"))
+			req := SendSMSRequest{PhoneNumber: "+919876543210", Message: "hi", CampaignID: "diwali"}
+			send := c.SendSMS
+			if tt.wait {
+				send = c.SendSMSAndWait
+			}
+			response, err := send(context.Background(), req)
+			if err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if response.MessageID != "m1" || response.Status != "successful" {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
+
+func TestSendSMSRequiresSenderURL(t *testing.T) {
+	if _, err := New("http://smsstore.invalid").SendSMS(context.Background(), SendSMSRequest{}); err == nil {
+		t.Fatal("SendSMS without WithSenderURL succeeded")
+	}
+}
+
+// Non-2xx responses become *APIError; reads are retried on 5xx, sends only on
+// the statuses that guarantee the send was not processed.
+func TestErrorStatusMapping(t *testing.T) {
+	tests := []struct {
+		name         string
+		send         bool
+		status       int
+		body         string
+		wantMessage  string
+		wantAttempts int32
+	}{
+		{name: "not found", status: http.StatusNotFound, body: `{"error":"User not found"}`, wantMessage: "User not found", wantAttempts: 1},
+		{name: "bad request send", send: true, status: http.StatusBadRequest, body: `{"error":"Invalid phone number format"}`, wantMessage: "Invalid phone number format", wantAttempts: 1},
+		{name: "read server error", status: http.StatusInternalServerError, body: `{"error":"Failed to get stats"}`, wantMessage: "Failed to get stats", wantAttempts: 3},
+		{name: "send server error", send: true, status: http.StatusInternalServerError, body: `{"error":"boom"}`, wantMessage: "boom", wantAttempts: 1},
+		{name: "send unavailable", send: true, status: http.StatusServiceUnavailable, body: `{"error":"overloaded"}`, wantMessage: "overloaded", wantAttempts: 3},
+		{name: "send rate limited", send: true, status: http.StatusTooManyRequests, body: `{"error":"slow down"}`, wantMessage: "slow down", wantAttempts: 3},
+		{name: "body without error", status: http.StatusBadGateway, body: "<html>bad gateway</html>", wantMessage: "Bad Gateway", wantAttempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.Header().Set("X-Request-ID", "req-1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := New(server.URL, WithSenderURL(server.URL), WithRetries(2, time.Millisecond))
+			var err error
+			if tt.send {
+				_, err = c.SendSMS(context.Background(), SendSMSRequest{PhoneNumber: "+919876543210", Message: "hi"})
+			} else {
+				_, err = c.GetStats(context.Background(), "u1")
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || apiErr.RequestID != "req-1" {
+				t.Errorf("err = %+v, want status %d message %q request req-1", apiErr, tt.status, tt.wantMessage)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryRecovers(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(models.UserStats{UserID: "u1", Total: 3})
+	}))
+	defer server.Close()
+
+	stats, err := New(server.URL, WithRetries(2, time.Millisecond)).GetStats(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Total != 3 || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("stats = %+v after %d attempts, want total 3 after 2", stats, attempts)
+	}
+}
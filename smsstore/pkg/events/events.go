@@ -0,0 +1,93 @@
+// Package events is the contract between the services that publish SMS events
+// and the smsstore consumer: the event type, its JSON schema and a validator
+// that applies the same rules the consumer does. Producers written in Go can
+// build and check events with it directly; others can validate against
+// Schema().
+package events
+
+import "time"
+
+// SchemaVersion is the event schema version SmsEvent implements. Events from
+// older producers are upcast to it when consumed.
+const SchemaVersion = 3
+
+// StatusRead marks a read receipt. Receipt events update the messages sharing
+// their ProviderMessageID instead of being stored as messages themselves.
+const StatusRead = "read"
+
+// StatusClicked marks a click receipt: the recipient opened a link in the
+// message. Like read receipts, they update the messages sharing their
+// ProviderMessageID.
+const StatusClicked = "clicked"
+
+// StatusDelivered is the delivery receipt a send's delivery latency is measured to.
+const StatusDelivered = "delivered"
+
+// Categories a sender accepts.
+const (
+	CategoryTransactional = "transactional"
+	CategoryPromotional   = "promotional"
+)
+
+type SmsEvent struct {
+	// SchemaVersion is the schema version the producer wrote; zero for
+	// producers that predate versioning (version 1)
+	SchemaVersion int `json:"schemaVersion,omitempty" bson:"schemaVersion,omitempty"`
+
+	PhoneNumber string `json:"phoneNumber" bson:"phoneNumber"`
+	Message     string `json:"message" bson:"message"`
+	Status      string `json:"status" bson:"status"`
+
+	// Delivery metadata set by the sender; all optional
+	Provider          string `json:"provider,omitempty" bson:"provider,omitempty"`
+	ProviderMessageID string `json:"providerMessageId,omitempty" bson:"providerMessageId,omitempty"`
+	CampaignID        string `json:"campaignId,omitempty" bson:"campaignId,omitempty"`
+	TemplateID        string `json:"templateId,omitempty" bson:"templateId,omitempty"`
+	// Variant is the template variant of the campaign's A/B test the recipient was assigned
+	Variant     string `json:"variant,omitempty" bson:"variant,omitempty"`
+	CountryCode string `json:"countryCode,omitempty" bson:"countryCode,omitempty"`
+
+	// Metadata is a free-form bag for producer references (order_id, merchant_id, ...)
+	Metadata map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"`
+
+	// Language is an ISO 639-1 code; detected from Message's script at ingest
+	// when not set, which leaves "und" for shared scripts such as Latin
+	Language string `json:"language,omitempty" bson:"language,omitempty"`
+
+	// IdempotencyKey identifies a logical send across producer retries; events
+	// repeating a stored key for the same user are dropped
+	IdempotencyKey string `json:"idempotencyKey,omitempty" bson:"idempotencyKey,omitempty"`
+	TenantID       string `json:"tenantId,omitempty" bson:"tenantId,omitempty"`
+	// Category is "transactional" or "promotional", as given to the sender
+	Category string `json:"category,omitempty" bson:"category,omitempty"`
+	// SenderID is the sender ID or shortcode the message went out under; empty
+	// for the default sender
+	SenderID string `json:"senderId,omitempty" bson:"senderId,omitempty"`
+	// TraceID is the W3C trace-id of the producing request
+	TraceID string `json:"traceId,omitempty" bson:"traceId,omitempty"`
+	// SendID is shared by every status event, retry and resend of one send
+	SendID string `json:"sendId,omitempty" bson:"sendId,omitempty"`
+
+	// ReadAt is when a read receipt says the recipient read the message; defaults to ingest time.
+	// Imported history may also set it on the message itself.
+	ReadAt *time.Time `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	// ClickedAt is when a click receipt says the recipient opened a link; defaults to ingest time
+	ClickedAt *time.Time `json:"clickedAt,omitempty" bson:"clickedAt,omitempty"`
+
+	// CreatedAt backdates imported history; live events are stamped at ingest
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+
+	// Attempt is the provider send attempt the event reports; zero means the first
+	Attempt int `json:"attempt,omitempty" bson:"attempt,omitempty"`
+
+	// ProviderLatencyMs is how long the provider took to accept the send
+	ProviderLatencyMs int64 `json:"providerLatencyMs,omitempty" bson:"providerLatencyMs,omitempty"`
+
+	// Set by the consumer when Message was cut to MAX_MESSAGE_BYTES; never read from producers
+	Truncated     bool `json:"-" bson:"-"`
+	OriginalBytes int  `json:"-" bson:"-"`
+	// Set by the consumer when the status can't follow the send's stored status
+	OutOfOrder bool `json:"-" bson:"-"`
+	// Set by the consumer on delivered events: time since the send's first stored status
+	DeliveryLatencyMs int64 `json:"-" bson:"-"`
+}
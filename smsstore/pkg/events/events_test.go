@@ -0,0 +1,129 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// These tests pin the event contract. testdata/valid holds payloads producers
+// may publish, each with the event it decodes to re-encoded in a .golden file;
+// testdata/invalid holds payloads the consumer rejects, each with the error in
+// a .golden file. A change that alters any of them changes what producers must
+// send, so it needs their sign-off. Regenerate the golden files with
+//
+//	go test ./pkg/events -update
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestSchemaCoversSmsEvent(t *testing.T) {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(Schema(), &doc); err != nil {
+		t.Fatalf("schema.json is not valid JSON: %v", err)
+	}
+
+	var fields []string
+	eventType := reflect.TypeOf(SmsEvent{})
+	for i := 0; i < eventType.NumField(); i++ {
+		name, _, _ := strings.Cut(eventType.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, names := range []struct {
+		what string
+		got  []string
+	}{
+		{"schema.json", sortedKeys(doc.Properties)},
+		{"the validator", sortedKeys(properties)},
+	} {
+		if !reflect.DeepEqual(names.got, fields) {
+			t.Errorf("%s has properties %v, SmsEvent has %v", names.what, names.got, fields)
+		}
+	}
+}
+
+func TestValidEvents(t *testing.T) {
+	for _, path := range payloads(t, "valid") {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			payload := read(t, path)
+			if err := Validate(payload); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			var event SmsEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			encoded, err := json.MarshalIndent(event, "", "  ")
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			golden(t, path, append(encoded, '\n'))
+		})
+	}
+}
+
+func TestInvalidEvents(t *testing.T) {
+	for _, path := range payloads(t, "invalid") {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := Validate(read(t, path))
+			if !errors.Is(err, ErrInvalidEvent) {
+				t.Fatalf("Validate = %v, want ErrInvalidEvent", err)
+			}
+			golden(t, path, []byte(err.Error()+"\n"))
+		})
+	}
+}
+
+func payloads(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", dir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no payloads in testdata/%s: %v", dir, err)
+	}
+	return paths
+}
+
+func read(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// golden compares got with the .golden file next to path, or rewrites it with
+// -update.
+func golden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	goldenPath := strings.TrimSuffix(path, ".json") + ".golden"
+	if *update {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if want := read(t, goldenPath); string(got) != string(want) {
+		t.Errorf("%s changed:\ngot:\n%s\nwant:\n%s", goldenPath, got, want)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
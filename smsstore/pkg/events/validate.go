@@ -0,0 +1,143 @@
+package events
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed schema.json
+var schema []byte
+
+// Schema returns the JSON schema (draft 2020-12) of SmsEvent payloads.
+func Schema() []byte {
+	return append([]byte(nil), schema...)
+}
+
+// ErrInvalidEvent wraps every problem Validate reports.
+var ErrInvalidEvent = errors.New("invalid SMS event")
+
+// kind is the JSON type a property must have.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInteger
+	kindTime
+	kindStringMap
+)
+
+// mismatches describes a property of the wrong kind.
+var mismatches = map[kind]string{
+	kindString:    "must be a string",
+	kindInteger:   "must be an integer",
+	kindTime:      "must be an RFC 3339 timestamp",
+	kindStringMap: "must be an object of strings",
+}
+
+// properties lists the schema's properties by JSON name.
+var properties = map[string]kind{
+	"schemaVersion":     kindInteger,
+	"phoneNumber":       kindString,
+	"message":           kindString,
+	"status":            kindString,
+	"provider":          kindString,
+	"providerMessageId": kindString,
+	"campaignId":        kindString,
+	"templateId":        kindString,
+	"variant":           kindString,
+	"countryCode":       kindString,
+	"metadata":          kindStringMap,
+	"language":          kindString,
+	"idempotencyKey":    kindString,
+	"tenantId":          kindString,
+	"category":          kindString,
+	"senderId":          kindString,
+	"traceId":           kindString,
+	"sendId":            kindString,
+	"readAt":            kindTime,
+	"clickedAt":         kindTime,
+	"createdAt":         kindTime,
+	"attempt":           kindInteger,
+	"providerLatencyMs": kindInteger,
+}
+
+// Validate checks a payload against the schema: that it is a JSON object,
+// that the properties it sets have the right types, and that the event passes
+// ValidateEvent. Unknown properties are allowed, as the consumer ignores them,
+// and null properties count as absent, as the sender writes unset fields as
+// null. Errors wrap ErrInvalidEvent; properties of the wrong type are all
+// reported.
+func Validate(payload []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return fmt.Errorf("%w: payload must be a JSON object", ErrInvalidEvent)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		want, ok := properties[name]
+		if !ok || string(fields[name]) == "null" {
+			continue
+		}
+		if !isKind(fields[name], want) {
+			problems = append(problems, name+" "+mismatches[want])
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, strings.Join(problems, "; "))
+	}
+
+	var event SmsEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if raw, ok := fields["schemaVersion"]; ok && string(raw) != "null" && event.SchemaVersion < 1 {
+		return fmt.Errorf("%w: schemaVersion must be at least 1", ErrInvalidEvent)
+	}
+	if err := ValidateEvent(event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	return nil
+}
+
+// isKind reports whether raw, which isn't null, decodes into the Go type of a kind.
+func isKind(raw json.RawMessage, want kind) bool {
+	var target any
+	switch want {
+	case kindString:
+		target = new(string)
+	case kindInteger:
+		target = new(int64)
+	case kindTime:
+		target = new(time.Time)
+	case kindStringMap:
+		target = new(map[string]string)
+	}
+	return json.Unmarshal(raw, target) == nil
+}
+
+// ValidateEvent applies the rules the consumer rejects a decoded event by: a
+// phone number is required, and read and click receipts must name the provider
+// message they report on.
+func ValidateEvent(event SmsEvent) error {
+	if strings.TrimSpace(event.PhoneNumber) == "" {
+		return errors.New("phoneNumber is required")
+	}
+	if strings.EqualFold(strings.TrimSpace(event.Status), StatusRead) && strings.TrimSpace(event.ProviderMessageID) == "" {
+		return errors.New("providerMessageId is required for read receipts")
+	}
+	if strings.EqualFold(strings.TrimSpace(event.Status), StatusClicked) && strings.TrimSpace(event.ProviderMessageID) == "" {
+		return errors.New("providerMessageId is required for click receipts")
+	}
+	return nil
+}
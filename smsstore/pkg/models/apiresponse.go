@@ -5,3 +5,19 @@ type ApiResponse struct {
 	Messages []MessageWithStatus `json:"messages"`
 	Count    int                 `json:"count"`
 }
+
+// ProjectedApiResponse is returned when the client selects a subset of message fields.
+type ProjectedApiResponse struct {
+	UserID   string                   `json:"user_id"`
+	Messages []map[string]interface{} `json:"messages"`
+	Count    int                      `json:"count"`
+}
+
+// SupportMessagesResponse is a user's messages as shown to support. Masked
+// reports whether message bodies were masked.
+type SupportMessagesResponse struct {
+	UserID   string              `json:"user_id"`
+	Messages []MessageWithStatus `json:"messages"`
+	Count    int                 `json:"count"`
+	Masked   bool                `json:"masked"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AuditEntry records an access to unmasked user data: who, to what, why and
+// from which request.
+type AuditEntry struct {
+	ID        string    `bson:"_id" json:"id"`
+	Actor     string    `bson:"actor" json:"actor"`
+	Action    string    `bson:"action" json:"action"`
+	UserID    string    `bson:"user_id" json:"user_id"`
+	Reason    string    `bson:"reason" json:"reason"`
+	RequestID string    `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	At        time.Time `bson:"at" json:"at"`
+}
+
+// Audited actions.
+const (
+	AuditActionUnmaskedMessages = "view_unmasked_messages"
+)
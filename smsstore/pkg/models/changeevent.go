@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Change event operations
+const (
+	ChangeOpInsert = "insert"
+	ChangeOpUpdate = "update"
+	ChangeOpDelete = "delete"
+)
+
+// ChangeEvent is a normalized CDC-style record describing a change to a stored message.
+// It is the contract for downstream warehouse sinks and must only evolve additively.
+// Deletes of soft-deleted messages carry the message, with deleted_at, in After;
+// permanent removals (retention, purges, user deletion) carry only MessageID.
+type ChangeEvent struct {
+	EventID    string             `json:"event_id"`
+	Operation  string             `json:"op"`
+	Entity     string             `json:"entity"`
+	UserID     string             `json:"user_id"`
+	MessageID  string             `json:"message_id,omitempty"`
+	After      *MessageWithStatus `json:"after,omitempty"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ConsumerPauseStatus reports whether the consumer is paused by its error-rate
+// breaker, and the failures that tripped it.
+type ConsumerPauseStatus struct {
+	Paused bool `json:"paused"`
+	// ErrorRateThreshold is zero when pausing is disabled
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	Window             string  `json:"window"`
+	// Events and failures handled within the window; while paused, those
+	// that tripped the breaker
+	Events   int        `json:"events"`
+	Failures int        `json:"failures"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// DefaultSenderID groups messages sent without a sender ID or shortcode.
+const DefaultSenderID = "default"
+
+// Conversation summarizes a user's messages from one sender.
+type Conversation struct {
+	SenderID     string            `json:"sender_id"`
+	MessageCount int               `json:"message_count"`
+	UnreadCount  int               `json:"unread_count"`
+	LastMessage  MessageWithStatus `json:"last_message"`
+}
+
+// ConversationsResponse lists a user's conversations, most recently active first.
+type ConversationsResponse struct {
+	UserID        string         `json:"user_id"`
+	Conversations []Conversation `json:"conversations"`
+	Count         int            `json:"count"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// DeadlineDiagnostics explains a request that ran out of the time its caller
+// gave it: the budget, how long it ran, and the repository operations that
+// finished in that time. Operations still running when the deadline passed
+// are cut short and listed with a timeout error.
+type DeadlineDiagnostics struct {
+	BudgetMs   int64             `json:"budget_ms"`
+	ElapsedMs  int64             `json:"elapsed_ms"`
+	Source     string            `json:"source"`
+	Operations []OperationTiming `json:"operations"`
+}
+
+// OperationTiming is one repository operation run for a request, timed from
+// the start of the request.
+type OperationTiming struct {
+	Operation  string `json:"operation"`
+	StartMs    int64  `json:"start_ms"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
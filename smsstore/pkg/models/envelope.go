@@ -0,0 +1,20 @@
+package models
+
+// Response formats selectable via config or the X-Response-Format header.
+const (
+	ResponseFormatFlat     = "flat"
+	ResponseFormatEnvelope = "envelope"
+)
+
+// Envelope is the standardized response shape: payload under data, request
+// metadata under meta, and any failures under errors.
+type Envelope struct {
+	Data   interface{}            `json:"data"`
+	Meta   map[string]interface{} `json:"meta"`
+	Errors []APIError             `json:"errors"`
+}
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
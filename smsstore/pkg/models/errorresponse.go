@@ -0,0 +1,8 @@
+package models
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+	// Diagnostics details some errors, e.g. DeadlineDiagnostics on a 504
+	Diagnostics interface{} `json:"diagnostics,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// FeatureFlagOverride turns a feature on or off over FEATURE_FLAGS and
+// FEATURE_FLAGS_FILE, set through the admin API. Enabled applies to every
+// tenant (nil leaves the lower settings in place); Tenants to particular ones.
+type FeatureFlagOverride struct {
+	Name      string          `bson:"_id" json:"name"`
+	Enabled   *bool           `bson:"enabled,omitempty" json:"enabled,omitempty"`
+	Tenants   map[string]bool `bson:"tenants,omitempty" json:"tenants,omitempty"`
+	UpdatedAt time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// FeatureFlagSetting is what one source says about a feature.
+type FeatureFlagSetting struct {
+	Enabled *bool           `json:"enabled,omitempty"`
+	Tenants map[string]bool `json:"tenants,omitempty"`
+}
+
+// FeatureFlagState is a feature's effective state and where it comes from.
+// Enabled applies to tenants not listed in Tenants.
+type FeatureFlagState struct {
+	Name     string              `json:"name"`
+	Default  bool                `json:"default"`
+	Enabled  bool                `json:"enabled"`
+	Tenants  map[string]bool     `json:"tenants,omitempty"`
+	Env      *FeatureFlagSetting `json:"env,omitempty"`
+	File     *FeatureFlagSetting `json:"file,omitempty"`
+	Override *FeatureFlagSetting `json:"override,omitempty"`
+}
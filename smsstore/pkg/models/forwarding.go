@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ForwardedMessage is the record FORWARDING_RULES publish to downstream topics
+// for a stored message. It must only evolve additively.
+type ForwardedMessage struct {
+	UserID      string             `json:"user_id"`
+	Region      string             `json:"region,omitempty"`
+	Message     *MessageWithStatus `json:"message"`
+	ForwardedAt time.Time          `json:"forwarded_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// IntegrityMismatch identifies a message whose body no longer matches the
+// checksum recorded when it was stored.
+type IntegrityMismatch struct {
+	UserID    string    `json:"user_id" bson:"user_id"`
+	MessageID string    `json:"message_id,omitempty" bson:"message_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	Checksum  string    `json:"checksum" bson:"checksum"`
+}
+
+// IntegrityReport summarizes a checksum verification run. Missing counts
+// messages stored before checksums were recorded; Unverifiable counts keyed
+// checksums that can't be checked without the service key.
+type IntegrityReport struct {
+	Users        int                 `json:"users" bson:"users"`
+	Messages     int                 `json:"messages" bson:"messages"`
+	Verified     int                 `json:"verified" bson:"verified"`
+	Missing      int                 `json:"missing" bson:"missing"`
+	Unverifiable int                 `json:"unverifiable" bson:"unverifiable"`
+	Mismatched   int                 `json:"mismatched" bson:"mismatched"`
+	Mismatches   []IntegrityMismatch `json:"mismatches" bson:"mismatches"`
+	// Truncated is set when more mismatches were found than are listed
+	Truncated bool `json:"truncated,omitempty" bson:"truncated,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is a persisted unit of background work and its outcome.
+type Job struct {
+	ID          string            `json:"id" bson:"_id"`
+	Type        string            `json:"type" bson:"type"`
+	Params      map[string]string `json:"params,omitempty" bson:"params,omitempty"`
+	Status      string            `json:"status" bson:"status"`
+	Attempts    int               `json:"attempts" bson:"attempts"`
+	MaxAttempts int               `json:"max_attempts" bson:"max_attempts"`
+	Progress    *JobProgress      `json:"progress,omitempty" bson:"progress,omitempty"`
+	Result      interface{}       `json:"result,omitempty" bson:"result,omitempty"`
+	Error       string            `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at" bson:"created_at"`
+	// RunAfter delays a pending job, e.g. between retries
+	RunAfter   time.Time  `json:"run_after" bson:"run_after"`
+	StartedAt  *time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	// Owner and LeaseUntil identify the replica running the job
+	Owner      string     `json:"owner,omitempty" bson:"owner,omitempty"`
+	LeaseUntil *time.Time `json:"lease_until,omitempty" bson:"lease_until,omitempty"`
+}
+
+// JobProgress is reported by long-running jobs; Total is zero when unknown.
+type JobProgress struct {
+	Done  int `json:"done" bson:"done"`
+	Total int `json:"total,omitempty" bson:"total,omitempty"`
+}
+
+// MaintenanceRequest starts a maintenance operation.
+type MaintenanceRequest struct {
+	Operation string `json:"operation"`
+	UserID    string `json:"user_id,omitempty"`
+	// Region runs the operation against a data-residency region's cluster
+	Region string `json:"region,omitempty"`
+	// TenantID runs the operation against the tenant's own database, if it has one
+	TenantID string `json:"tenant_id,omitempty"`
+}
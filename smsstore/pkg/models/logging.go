@@ -0,0 +1,11 @@
+package models
+
+// LoggingSettings controls consumer-path logging; see GET/PUT /v1/admin/logging.
+type LoggingSettings struct {
+	// Debug enables per-message debug lines such as raw payloads and message bodies
+	Debug bool `json:"debug"`
+	// SampleRate logs routine lines for 1 in SampleRate messages; 1 logs every message
+	SampleRate int `json:"sample_rate"`
+	// RateLimit caps lines per second for each level; 0 is unlimited
+	RateLimit float64 `json:"rate_limit"`
+}
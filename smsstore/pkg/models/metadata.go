@@ -0,0 +1,18 @@
+package models
+
+import "regexp"
+
+// Limits on producer-supplied message metadata, so a misbehaving producer
+// can't bloat documents or the metadata wildcard indexes.
+const (
+	MaxMetadataEntries    = 20
+	MaxMetadataValueBytes = 256
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// ValidMetadataKey reports whether key may be stored and queried. Keys are
+// used in field paths, so dots and $ are not allowed.
+func ValidMetadataKey(key string) bool {
+	return metadataKeyPattern.MatchString(key)
+}
@@ -0,0 +1,17 @@
+package models
+
+// ProviderHealth is a provider's rolling delivery health.
+type ProviderHealth struct {
+	Provider     string  `json:"provider"`
+	Score        float64 `json:"score"`
+	Healthy      bool    `json:"healthy"`
+	Samples      int64   `json:"samples"`
+	SuccessRate  float64 `json:"success_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// ProviderHealthResponse lists providers from healthiest to least healthy.
+type ProviderHealthResponse struct {
+	Window    string           `json:"window"`
+	Providers []ProviderHealth `json:"providers"`
+}
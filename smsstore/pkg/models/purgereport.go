@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Sources of permanently removed messages, as recorded in the purge ledger.
+const (
+	// PurgeSourceRetention is the retention janitor removing expired messages
+	PurgeSourceRetention = "retention"
+	// PurgeSourceDeletion is the purger removing messages soft-deleted through
+	// the API once their grace period has passed
+	PurgeSourceDeletion = "deletion"
+	// PurgeSourceUserDeletion is a user deleted with all of their messages
+	PurgeSourceUserDeletion = "user_deletion"
+)
+
+// PurgeCount is the number of messages of one tenant and category removed by
+// one source. Empty tenant and category mean the messages had none.
+type PurgeCount struct {
+	TenantID string `json:"tenant_id" bson:"tenant_id"`
+	Source   string `json:"source" bson:"source"`
+	Category string `json:"category" bson:"category"`
+	Count    int64  `json:"count" bson:"count"`
+}
+
+// TenantPurges totals a tenant's purged messages for the report month.
+type TenantPurges struct {
+	TenantID   string           `json:"tenant_id" bson:"tenant_id"`
+	Total      int64            `json:"total" bson:"total"`
+	BySource   map[string]int64 `json:"by_source" bson:"by_source"`
+	ByCategory map[string]int64 `json:"by_category" bson:"by_category"`
+}
+
+// PurgeReport summarizes the messages permanently removed in a calendar month (UTC).
+type PurgeReport struct {
+	// Month is formatted YYYY-MM
+	Month       string         `json:"month" bson:"_id"`
+	Total       int64          `json:"total" bson:"total"`
+	Tenants     []TenantPurges `json:"tenants" bson:"tenants"`
+	Counts      []PurgeCount   `json:"counts" bson:"counts"`
+	GeneratedAt time.Time      `json:"generated_at" bson:"generated_at"`
+	// Partial is set on reports for the current month, which are built on
+	// request and not stored
+	Partial bool `json:"partial,omitempty" bson:"-"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// QuarantinedEvent is a consumed record whose payload could not be parsed,
+// kept with its Kafka coordinates for inspection.
+type QuarantinedEvent struct {
+	ID        string            `bson:"_id" json:"id"`
+	Topic     string            `bson:"topic" json:"topic"`
+	Partition int               `bson:"partition" json:"partition"`
+	Offset    int64             `bson:"offset" json:"offset"`
+	Key       string            `bson:"key,omitempty" json:"key,omitempty"`
+	Headers   map[string]string `bson:"headers,omitempty" json:"headers,omitempty"`
+	Payload   string            `bson:"payload" json:"payload"`
+	// PayloadEncoding is "base64" for payloads that aren't valid UTF-8, else empty
+	PayloadEncoding string    `bson:"payload_encoding,omitempty" json:"payload_encoding,omitempty"`
+	Error           string    `bson:"error" json:"error"`
+	QuarantinedAt   time.Time `bson:"quarantined_at" json:"quarantined_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DependencyStatus is the startup progress of one dependency the service waits for.
+type DependencyStatus struct {
+	Name      string    `json:"name"`
+	Ready     bool      `json:"ready"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// ReadinessStatus is reported by the readiness probe. Status is "starting"
+// until every dependency is reachable and startup (including migrations) has
+// finished, then "ready".
+type ReadinessStatus struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// CanaryStatus is the outcome of the end-to-end canary, reported by the
+// liveness probe. Status is "disabled", "pending" until the first probe
+// finishes, then "ok" or "failing".
+type CanaryStatus struct {
+	Status      string     `json:"status"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	// LatencyMs is the last successful probe's publish-to-stored time
+	LatencyMs           int64  `json:"latency_ms,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
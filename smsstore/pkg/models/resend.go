@@ -0,0 +1,26 @@
+package models
+
+// ResendRequest asks the sender to send a failed message again. It matches the
+// sender's own retry record (SmsRetry), so its retry listener can consume it
+// from RESEND_TOPIC.
+type ResendRequest struct {
+	// ID becomes the send ID of the resend's events
+	ID           string     `json:"id"`
+	TenantID     string     `json:"tenantId,omitempty"`
+	Request      ResendBody `json:"request"`
+	Attempt      int        `json:"attempt"`
+	DueAtEpochMs int64      `json:"dueAtEpochMs"`
+	Provider     string     `json:"provider,omitempty"`
+}
+
+// ResendBody is the send request of a ResendRequest (the sender's SmsRequest).
+type ResendBody struct {
+	PhoneNumber string            `json:"phoneNumber"`
+	Message     string            `json:"message"`
+	CampaignID  string            `json:"campaignId,omitempty"`
+	TemplateID  string            `json:"templateId,omitempty"`
+	CountryCode string            `json:"countryCode,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	SenderID    string            `json:"senderId,omitempty"`
+	Category    string            `json:"category,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RetentionOverride replaces the global retention period for a single user.
+type RetentionOverride struct {
+	UserID        string    `bson:"_id" json:"user_id"`
+	RetentionDays int       `bson:"retention_days" json:"retention_days"`
+	UpdatedAt     time.Time `bson:"updated_at" json:"updated_at"`
+}
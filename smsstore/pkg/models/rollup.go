@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Rollup granularities, from finest to coarsest.
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// RollupCounts counts the messages stored in a period, in total and by
+// status, category, provider and country. Messages without a category,
+// provider or country count under "unknown"; periods rolled up before
+// countries were counted have no Country.
+type RollupCounts struct {
+	Total    int            `bson:"total" json:"total"`
+	Status   map[string]int `bson:"status,omitempty" json:"status,omitempty"`
+	Category map[string]int `bson:"category,omitempty" json:"category,omitempty"`
+	Provider map[string]int `bson:"provider,omitempty" json:"provider,omitempty"`
+	Country  map[string]int `bson:"country,omitempty" json:"country,omitempty"`
+}
+
+// Add adds other's counts to c.
+func (c *RollupCounts) Add(other RollupCounts) {
+	c.Total += other.Total
+	c.Status = addCounts(c.Status, other.Status)
+	c.Category = addCounts(c.Category, other.Category)
+	c.Provider = addCounts(c.Provider, other.Provider)
+	c.Country = addCounts(c.Country, other.Country)
+}
+
+func addCounts(into map[string]int, from map[string]int) map[string]int {
+	if len(from) == 0 {
+		return into
+	}
+	if into == nil {
+		into = make(map[string]int, len(from))
+	}
+	for key, count := range from {
+		into[key] += count
+	}
+	return into
+}
+
+// MessageRollup is one tenant's counts for the minute, hour or day starting
+// at Start, kept up to date by the consumer as messages are stored.
+type MessageRollup struct {
+	Granularity  string    `bson:"granularity"`
+	TenantID     string    `bson:"tenant_id"`
+	Start        time.Time `bson:"start"`
+	RollupCounts `bson:",inline"`
+}
+
+// TimeseriesPoint is the counts of one interval of a timeseries.
+type TimeseriesPoint struct {
+	Start time.Time `json:"start"`
+	RollupCounts
+}
+
+// TimeseriesResponse is the body of /v1/analytics/timeseries.
+type TimeseriesResponse struct {
+	// TenantID is empty for the counts of every tenant together
+	TenantID string            `json:"tenant_id,omitempty"`
+	Interval string            `json:"interval"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Points   []TimeseriesPoint `json:"points"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// SearchResult is a message matched by a cross-user search.
+type SearchResult struct {
+	UserID  string            `json:"user_id" bson:"_id"`
+	Message MessageWithStatus `json:"message" bson:"messages"`
+}
+
+// SearchResponse is returned by the message search endpoint.
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Count   int            `json:"count"`
+}
+
+// AnalyticsBucket is the message count for one value of the grouped dimension.
+type AnalyticsBucket struct {
+	Key   string `json:"key" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+	// DeliveryLatencyMs is set for metric=delivery_latency, where Count is the
+	// number of delivered messages with a recorded latency
+	DeliveryLatencyMs *LatencyPercentiles `json:"delivery_latency_ms,omitempty" bson:"delivery_latency_ms,omitempty"`
+}
+
+// LatencyPercentiles summarizes a latency distribution in milliseconds.
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50" bson:"p50"`
+	P95 float64 `json:"p95" bson:"p95"`
+	P99 float64 `json:"p99" bson:"p99"`
+}
+
+// AnalyticsResponse is returned by the message analytics endpoint.
+type AnalyticsResponse struct {
+	Metric  string            `json:"metric,omitempty"`
+	GroupBy string            `json:"group_by"`
+	Buckets []AnalyticsBucket `json:"buckets"`
+	Total   int               `json:"total"`
+}
+
+// FirehoseResponse is one page of the all-messages feed, in insertion order.
+type FirehoseResponse struct {
+	Messages   []SearchResult `json:"messages"`
+	Count      int            `json:"count"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
@@ -1,7 +1,18 @@
 package models
 
-type SmsEvent struct {
-	PhoneNumber string `json:"phoneNumber" bson:"phoneNumber"`
-	Message     string `json:"message" bson:"message"`
-	Status	  string `json:"status" bson:"status"`
-}
\ No newline at end of file
+import "smsstore/pkg/events"
+
+// SmsEvent is the event producers publish; package events defines it along
+// with its schema.
+type SmsEvent = events.SmsEvent
+
+const (
+	// SchemaVersion is the event schema version SmsEvent implements.
+	SchemaVersion = events.SchemaVersion
+	// StatusRead marks a read receipt.
+	StatusRead = events.StatusRead
+	// StatusClicked marks a click receipt.
+	StatusClicked = events.StatusClicked
+	// StatusDelivered is the delivery receipt a send's delivery latency is measured to.
+	StatusDelivered = events.StatusDelivered
+)
@@ -0,0 +1,21 @@
+package models
+
+// SmsRequest mirrors the sms-sender send API request body.
+type SmsRequest struct {
+	PhoneNumber string            `json:"phoneNumber"`
+	Message     string            `json:"message"`
+	CampaignID  string            `json:"campaignId,omitempty"`
+	TemplateID  string            `json:"templateId,omitempty"`
+	CountryCode string            `json:"countryCode,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	SenderID    string            `json:"senderId,omitempty"`
+	// Category is "transactional" (the default) or "promotional"
+	Category string `json:"category,omitempty"`
+	// TenantID comes from the X-Tenant-ID header, not the body
+	TenantID string `json:"-"`
+}
+
+// SmsResponse mirrors the sms-sender send API response body.
+type SmsResponse struct {
+	Result string `json:"result"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// CollectionStorage is the size of one collection holding message data, as
+// reported by collStats. Region and Database identify the database it is in.
+type CollectionStorage struct {
+	Region     string `json:"region,omitempty" bson:"region,omitempty"`
+	Database   string `json:"database" bson:"database"`
+	Collection string `json:"collection" bson:"collection"`
+	Documents  int64  `json:"documents" bson:"documents"`
+	// DataBytes is the uncompressed size of the documents, StorageBytes the
+	// space allocated to them on disk and IndexBytes that of all indexes
+	DataBytes    int64 `json:"data_bytes" bson:"data_bytes"`
+	StorageBytes int64 `json:"storage_bytes" bson:"storage_bytes"`
+	IndexBytes   int64 `json:"index_bytes" bson:"index_bytes"`
+}
+
+// UserStorage is one of a tenant's largest user documents. Messages and
+// MessageBytes count only the tenant's messages in it.
+type UserStorage struct {
+	UserID        string `json:"user_id" bson:"user_id"`
+	Region        string `json:"region,omitempty" bson:"region,omitempty"`
+	Collection    string `json:"collection" bson:"collection"`
+	DocumentBytes int64  `json:"document_bytes" bson:"document_bytes"`
+	Messages      int64  `json:"messages" bson:"messages"`
+	MessageBytes  int64  `json:"message_bytes" bson:"message_bytes"`
+}
+
+// TenantStorage totals a tenant's stored messages across tiers and databases.
+// Empty tenant means the messages had none.
+type TenantStorage struct {
+	TenantID string `json:"tenant_id" bson:"tenant_id"`
+	Messages int64  `json:"messages" bson:"messages"`
+	// MessageBytes is the uncompressed BSON size of the messages, the share
+	// of DataBytes attributable to the tenant
+	MessageBytes int64 `json:"message_bytes" bson:"message_bytes"`
+	// UserDocuments counts the hot and cold user documents holding any of
+	// the tenant's messages; a user with messages in both tiers counts twice
+	UserDocuments int64            `json:"user_documents" bson:"user_documents"`
+	ByCollection  map[string]int64 `json:"by_collection" bson:"by_collection"`
+	LargestUsers  []UserStorage    `json:"largest_users" bson:"largest_users"`
+}
+
+// StorageReport is a snapshot of storage usage on a calendar day (UTC).
+type StorageReport struct {
+	// Date is formatted YYYY-MM-DD
+	Date         string              `json:"date" bson:"_id"`
+	Documents    int64               `json:"documents" bson:"documents"`
+	DataBytes    int64               `json:"data_bytes" bson:"data_bytes"`
+	StorageBytes int64               `json:"storage_bytes" bson:"storage_bytes"`
+	IndexBytes   int64               `json:"index_bytes" bson:"index_bytes"`
+	Collections  []CollectionStorage `json:"collections" bson:"collections"`
+	Tenants      []TenantStorage     `json:"tenants" bson:"tenants"`
+	GeneratedAt  time.Time           `json:"generated_at" bson:"generated_at"`
+}
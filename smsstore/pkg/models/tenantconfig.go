@@ -0,0 +1,62 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// TenantConfig holds a tenant's settings. Unset settings fall back to the
+// service-wide defaults.
+type TenantConfig struct {
+	TenantID string `bson:"_id" json:"tenant_id"`
+	// RetentionDays replaces RETENTION_DAYS for the tenant's messages; 0 keeps
+	// them forever. Per-user retention overrides still take precedence.
+	RetentionDays *int `bson:"retention_days,omitempty" json:"retention_days,omitempty"`
+	// DailyQuota caps the tenant's sends per UTC day; 0 is unlimited
+	DailyQuota int `bson:"daily_quota,omitempty" json:"daily_quota,omitempty"`
+	// AllowedSenders restricts the sender IDs the tenant may send under; empty
+	// allows any. The default sender is always allowed.
+	AllowedSenders []string `bson:"allowed_senders,omitempty" json:"allowed_senders,omitempty"`
+	// QuietHours defers the tenant's promotional sends inside the window
+	QuietHours *QuietHours `bson:"quiet_hours,omitempty" json:"quiet_hours,omitempty"`
+	// WebhooksEnabled turns webhook deliveries for the tenant's messages off
+	// when false
+	WebhooksEnabled *bool `bson:"webhooks_enabled,omitempty" json:"webhooks_enabled,omitempty"`
+	// RetryPolicy resends the tenant's failed messages; nil never resends them
+	RetryPolicy *RetryPolicy `bson:"retry_policy,omitempty" json:"retry_policy,omitempty"`
+	UpdatedAt   time.Time    `bson:"updated_at" json:"updated_at"`
+}
+
+// RetryPolicy has the retry orchestrator resend a failed message AfterMinutes
+// after it failed, up to MaxAttempts times per send.
+type RetryPolicy struct {
+	AfterMinutes int `bson:"after_minutes" json:"after_minutes"`
+	MaxAttempts  int `bson:"max_attempts" json:"max_attempts"`
+	// Provider asks the sender to resend through another provider; empty uses
+	// the default one
+	Provider string `bson:"provider,omitempty" json:"provider,omitempty"`
+	// CreatedAt is when the policy was first set; messages that failed before
+	// it are never resent
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// QuietHours is a daily [Start, End) window (HH:MM) in Timezone; a window
+// with Start after End crosses midnight.
+type QuietHours struct {
+	Start    string `bson:"start" json:"start"`
+	End      string `bson:"end" json:"end"`
+	Timezone string `bson:"timezone" json:"timezone"`
+}
+
+// AllowsSender reports whether the tenant may send under senderID.
+func (c TenantConfig) AllowsSender(senderID string) bool {
+	if senderID == "" || senderID == DefaultSenderID || len(c.AllowedSenders) == 0 {
+		return true
+	}
+	return slices.Contains(c.AllowedSenders, senderID)
+}
+
+// WebhooksAllowed reports whether the tenant's messages are delivered to webhooks.
+func (c TenantConfig) WebhooksAllowed() bool {
+	return c.WebhooksEnabled == nil || *c.WebhooksEnabled
+}
@@ -1,11 +1,90 @@
 package models
 
+import "time"
+
 type MessageWithStatus struct {
-	Message string `bson:"message" json:"message"`
-	Status  string `bson:"status" json:"status"`
+	MessageID string     `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	Message   string     `bson:"message" json:"message"`
+	Status    string     `bson:"status" json:"status"`
+	CreatedAt time.Time  `bson:"created_at,omitempty" json:"created_at"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// ReadAt is set by read receipts from channels that report them (WhatsApp, RCS)
+	ReadAt *time.Time `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	// ClickedAt is set by click receipts, when the recipient first opened a link in the message
+	ClickedAt *time.Time `bson:"clicked_at,omitempty" json:"clicked_at,omitempty"`
+
+	Provider          string `bson:"provider,omitempty" json:"provider,omitempty"`
+	ProviderMessageID string `bson:"provider_message_id,omitempty" json:"provider_message_id,omitempty"`
+	CampaignID        string `bson:"campaign_id,omitempty" json:"campaign_id,omitempty"`
+	TemplateID        string `bson:"template_id,omitempty" json:"template_id,omitempty"`
+	Variant           string `bson:"variant,omitempty" json:"variant,omitempty"`
+	CountryCode       string `bson:"country_code,omitempty" json:"country_code,omitempty"`
+	Attempt           int    `bson:"attempt,omitempty" json:"attempt,omitempty"`
+	Language          string `bson:"language,omitempty" json:"language,omitempty"`
+
+	Metadata map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+	TenantID       string `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Category       string `bson:"category,omitempty" json:"category,omitempty"`
+	SenderID       string `bson:"sender_id,omitempty" json:"sender_id,omitempty"`
+	TraceID        string `bson:"trace_id,omitempty" json:"trace_id,omitempty"`
+	SendID         string `bson:"send_id,omitempty" json:"send_id,omitempty"`
+
+	// Truncated is set when the body exceeded the storage limit; OriginalBytes is its size before truncation
+	Truncated     bool `bson:"truncated,omitempty" json:"truncated,omitempty"`
+	OriginalBytes int  `bson:"original_bytes,omitempty" json:"original_bytes,omitempty"`
+	// OutOfOrder is set when the status arrived after one it can't follow (STATUS_TRANSITION_MODE=flag)
+	OutOfOrder bool `bson:"out_of_order,omitempty" json:"out_of_order,omitempty"`
+	// DeliveryLatencyMs is set on delivered messages: milliseconds from the send's first stored status
+	DeliveryLatencyMs int64 `bson:"delivery_latency_ms,omitempty" json:"delivery_latency_ms,omitempty"`
+	// Checksum is "<algorithm>:<hex digest>" of the stored body, recorded at ingest
+	Checksum string `bson:"checksum,omitempty" json:"checksum,omitempty"`
+	// BodyZstd is the body compressed with zstd when it was stored that way
+	// (MESSAGE_BODY_ENCODING=zstd), leaving Message empty in storage. The
+	// repository restores Message when reading, so callers never see it.
+	BodyZstd []byte `bson:"body_zstd,omitempty" json:"-"`
+	// RetryState is set on failed messages the retry orchestrator has handled:
+	// RetryStateResent (RetryAttempt is which resend of the send it made) or
+	// RetryStateExhausted
+	RetryState   string     `bson:"retry_state,omitempty" json:"retry_state,omitempty"`
+	RetryAttempt int        `bson:"retry_attempt,omitempty" json:"retry_attempt,omitempty"`
+	RetriedAt    *time.Time `bson:"retried_at,omitempty" json:"retried_at,omitempty"`
+	// ReconciledAt is when status reconciliation last looked up a send that
+	// had no delivery report in its provider's status API
+	ReconciledAt *time.Time `bson:"reconciled_at,omitempty" json:"reconciled_at,omitempty"`
+	// Source is the Kafka record the message was consumed from; unset for
+	// imported history and messages stored before it was recorded
+	Source *MessageSource `bson:"source,omitempty" json:"source,omitempty"`
 }
 
+// MessageSource is where a stored message came from: its record's topic,
+// partition and offset, and when the consumer ingested it. smsctl backfill
+// replays a window of these.
+type MessageSource struct {
+	Topic      string    `bson:"topic" json:"topic"`
+	Partition  int       `bson:"partition" json:"partition"`
+	Offset     int64     `bson:"offset" json:"offset"`
+	IngestedAt time.Time `bson:"ingested_at" json:"ingested_at"`
+}
+
+// Retry states of a failed message.
+const (
+	RetryStateResent    = "resent"
+	RetryStateExhausted = "exhausted"
+)
+
 type UserData struct {
 	ID       string              `bson:"_id" json:"id"`
 	Messages []MessageWithStatus `bson:"messages" json:"messages"`
 }
+
+// MessagesSummary describes a user's message listing without its messages:
+// how many match, an opaque version that changes with every write to any of
+// the user's messages, and when any of them last changed (zero when the user
+// has none).
+type MessagesSummary struct {
+	Count        int
+	Version      string
+	LastModified time.Time
+}
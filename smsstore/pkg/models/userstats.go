@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserStats summarizes a user's visible (not soft-deleted) messages.
+type UserStats struct {
+	UserID string `json:"user_id" bson:"_id"`
+	Total  int    `json:"total" bson:"total"`
+	// UnreadCount is the number of visible delivered messages without a read receipt
+	UnreadCount   int            `json:"unread_count" bson:"unread_count"`
+	ByStatus      map[string]int `json:"by_status" bson:"by_status"`
+	ByLanguage    map[string]int `json:"by_language" bson:"by_language"`
+	FirstMessage  *time.Time     `json:"first_message_at,omitempty" bson:"first_message_at,omitempty"`
+	LatestMessage *time.Time     `json:"last_message_at,omitempty" bson:"last_message_at,omitempty"`
+	// ComputedAt is set on precomputed snapshots only
+	ComputedAt *time.Time `json:"computed_at,omitempty" bson:"computed_at,omitempty"`
+}
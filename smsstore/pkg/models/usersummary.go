@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserSummary describes a user's stored data volume for admin listings.
+type UserSummary struct {
+	UserID       string     `bson:"_id" json:"user_id"`
+	MessageCount int        `bson:"message_count" json:"message_count"`
+	LastActivity *time.Time `bson:"last_activity,omitempty" json:"last_activity,omitempty"`
+	UpdatedAt    *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+type UserListResponse struct {
+	Users      []UserSummary `json:"users"`
+	Count      int           `json:"count"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
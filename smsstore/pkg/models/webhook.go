@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Webhook event types
+const (
+	WebhookMessageSent      = "message.sent"
+	WebhookMessageDelivered = "message.delivered"
+	WebhookMessageFailed    = "message.failed"
+	WebhookMessageRead      = "message.read"
+	WebhookMessageClicked   = "message.clicked"
+)
+
+// WebhookSubscription registers a URL for message events, optionally narrowed
+// to one tenant and/or message category. Secret signs every delivery; it is
+// only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID        string    `bson:"_id" json:"id"`
+	URL       string    `bson:"url" json:"url"`
+	Events    []string  `bson:"events" json:"events"`
+	TenantID  string    `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Category  string    `bson:"category,omitempty" json:"category,omitempty"`
+	Secret    string    `bson:"secret" json:"secret,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// WebhookSubscriptionRequest creates a subscription. A secret is generated when none is given.
+type WebhookSubscriptionRequest struct {
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Secret   string   `json:"secret,omitempty"`
+}
+
+// WebhookEvent is the body POSTed to subscribers. ID is stable across retries
+// of the same delivery so receivers can deduplicate.
+type WebhookEvent struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	UserID     string             `json:"user_id"`
+	Message    *MessageWithStatus `json:"message"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a subscription.
+type WebhookDelivery struct {
+	ID             string    `bson:"_id" json:"id"`
+	SubscriptionID string    `bson:"subscription_id" json:"subscription_id"`
+	JobID          string    `bson:"job_id" json:"job_id"`
+	EventID        string    `bson:"event_id" json:"event_id"`
+	EventType      string    `bson:"event_type" json:"event_type"`
+	Attempt        int       `bson:"attempt" json:"attempt"`
+	StatusCode     int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error          string    `bson:"error,omitempty" json:"error,omitempty"`
+	Succeeded      bool      `bson:"succeeded" json:"succeeded"`
+	DurationMs     int64     `bson:"duration_ms" json:"duration_ms"`
+	AttemptedAt    time.Time `bson:"attempted_at" json:"attempted_at"`
+}